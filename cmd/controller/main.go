@@ -39,8 +39,10 @@ func main() {
 		op.AMIProvider,
 		op.SecurityGroupProvider,
 		op.SubnetProvider,
+		op.CostLimitProvider,
 	)
 	lo.Must0(op.AddHealthzCheck("cloud-provider", awsCloudProvider.LivenessProbe))
+	lo.Must0(op.AddHealthzCheck("spot-advisor", op.SpotAdvisorProvider.LivenessProbe))
 	cloudProvider := metrics.Decorate(awsCloudProvider)
 
 	op.
@@ -55,6 +57,7 @@ func main() {
 		WithControllers(ctx, controllers.NewControllers(
 			ctx,
 			op.Session,
+			op.EC2API,
 			op.Clock,
 			op.GetClient(),
 			op.EventRecorder,
@@ -65,8 +68,14 @@ func main() {
 			op.InstanceProfileProvider,
 			op.InstanceProvider,
 			op.PricingProvider,
+			op.SpotAdvisorProvider,
+			op.CostLimitProvider,
 			op.AMIProvider,
 			op.LaunchTemplateProvider,
+			op.InstanceTypesProvider,
+			op.CapacityReservationProvider,
+			op.MetricsExporter,
+			op.Dependencies,
 		)...).
 		WithWebhooks(ctx, webhooks.NewWebhooks()...).
 		Start(ctx)
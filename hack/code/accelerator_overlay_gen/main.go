@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/yaml"
+)
+
+const fileFormat = `
+%s
+package instancetype
+
+// GENERATED FILE. DO NOT EDIT DIRECTLY.
+// Update hack/code/accelerator_overlay_gen/accelerators.yaml and re-generate to edit
+
+var (
+	AcceleratorOverlay = map[string]AcceleratorInfo{
+		%s
+	}
+)
+`
+
+// accelerator is an entry of the maintained YAML
+type accelerator struct {
+	Name         string `json:"name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Count        int64  `json:"count,omitempty"`
+	MemoryMiB    int64  `json:"memoryMiB,omitempty"`
+}
+
+type Options struct {
+	input  string
+	output string
+}
+
+func NewOptions() *Options {
+	o := &Options{}
+	flag.StringVar(&o.input, "input", "hack/code/accelerator_overlay_gen/accelerators.yaml", "The maintained YAML of accelerator data to generate from.")
+	flag.StringVar(&o.output, "output", "pkg/providers/instancetype/zz_generated.accelerators.go", "The destination for the generated go file.")
+	flag.Parse()
+	return o
+}
+
+func main() {
+	opts := NewOptions()
+	accelerators, err := parse(lo.Must(os.ReadFile(opts.input)))
+	if err != nil {
+		log.Fatalf("parsing %s, %s", opts.input, err)
+	}
+	license := lo.Must(os.ReadFile("hack/boilerplate.go.txt"))
+	generated, err := generate(license, accelerators)
+	if err != nil {
+		log.Fatalf("generating %s, %s", opts.output, err)
+	}
+	lo.Must0(os.WriteFile(opts.output, generated, 0644))
+}
+
+// parse reads the maintained YAML, rejecting unknown fields and negative values so that typos don't silently drop data
+func parse(data []byte) (map[string]accelerator, error) {
+	accelerators := map[string]accelerator{}
+	if err := yaml.UnmarshalStrict(data, &accelerators); err != nil {
+		return nil, err
+	}
+	for instanceType, a := range accelerators {
+		if a.Count < 0 || a.MemoryMiB < 0 {
+			return nil, fmt.Errorf("instance type %s, count and memoryMiB must not be negative", instanceType)
+		}
+	}
+	return accelerators, nil
+}
+
+// generate renders the accelerators as a go file, sorted by instance type so that the output is stable
+func generate(license []byte, accelerators map[string]accelerator) ([]byte, error) {
+	instanceTypes := lo.Keys(accelerators)
+	sort.Strings(instanceTypes)
+	var body string
+	for _, instanceType := range instanceTypes {
+		a := accelerators[instanceType]
+		body += fmt.Sprintf("\t%q: {Name: %q, Manufacturer: %q, Count: %d, MemoryMiB: %d},\n", instanceType, a.Name, a.Manufacturer, a.Count, a.MemoryMiB)
+	}
+	return format.Source([]byte(fmt.Sprintf(fileFormat, license, body)))
+}
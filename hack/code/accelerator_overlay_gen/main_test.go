@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAcceleratorOverlayGen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AcceleratorOverlayGen")
+}
+
+var _ = Describe("AcceleratorOverlayGen", func() {
+	var license []byte
+	BeforeEach(func() {
+		var err error
+		license, err = os.ReadFile("../../boilerplate.go.txt")
+		Expect(err).ToNot(HaveOccurred())
+	})
+	It("should generate the overlay from the YAML fixture", func() {
+		accelerators, err := parse(readFile("testdata/accelerators.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(accelerators).To(Equal(map[string]accelerator{
+			"trn1.32xlarge": {Name: "Inferentia", Manufacturer: "AWS", Count: 16, MemoryMiB: 32768},
+			"inf9.xlarge":   {Count: 2},
+		}))
+		generated, err := generate(license, accelerators)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(generated)).To(Equal(string(readFile("testdata/zz_generated.accelerators.go.golden"))))
+	})
+	It("should match the generated overlay that's checked in", func() {
+		accelerators, err := parse(readFile("accelerators.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		generated, err := generate(license, accelerators)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(generated)).To(Equal(string(readFile("../../../pkg/providers/instancetype/zz_generated.accelerators.go"))))
+	})
+	It("should reject unknown fields", func() {
+		_, err := parse([]byte("trn1.2xlarge:\n  neurons: 1\n"))
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject negative values", func() {
+		_, err := parse([]byte("trn1.2xlarge:\n  count: -1\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func readFile(name string) []byte {
+	GinkgoHelper()
+	data, err := os.ReadFile(name)
+	Expect(err).ToNot(HaveOccurred())
+	return data
+}
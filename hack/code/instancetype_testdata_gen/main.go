@@ -118,13 +118,20 @@ func getInstanceTypeInfo(info *ec2.InstanceTypeInfo) string {
 	fmt.Fprintf(src, "BurstablePerformanceSupported: aws.Bool(%t),\n", lo.FromPtr(info.BurstablePerformanceSupported))
 	fmt.Fprintf(src, "BareMetal: aws.Bool(%t),\n", lo.FromPtr(info.BareMetal))
 	fmt.Fprintf(src, "Hypervisor: aws.String(\"%s\"),\n", lo.FromPtr(info.Hypervisor))
+	fmt.Fprintf(src, "DedicatedHostsSupported: aws.Bool(%t),\n", lo.FromPtr(info.DedicatedHostsSupported))
 	fmt.Fprintf(src, "ProcessorInfo: &ec2.ProcessorInfo{\n")
 	fmt.Fprintf(src, "Manufacturer: aws.String(\"%s\"),\n", lo.FromPtr(info.ProcessorInfo.Manufacturer))
 	fmt.Fprintf(src, "SupportedArchitectures: aws.StringSlice([]string{%s}),\n", getStringSliceData(info.ProcessorInfo.SupportedArchitectures))
+	if info.ProcessorInfo.SustainedClockSpeedInGhz != nil {
+		fmt.Fprintf(src, "SustainedClockSpeedInGhz: aws.Float64(%v),\n", lo.FromPtr(info.ProcessorInfo.SustainedClockSpeedInGhz))
+	}
 	fmt.Fprintf(src, "},\n")
 	fmt.Fprintf(src, "VCpuInfo: &ec2.VCpuInfo{\n")
 	fmt.Fprintf(src, "DefaultCores: aws.Int64(%d),\n", lo.FromPtr(info.VCpuInfo.DefaultCores))
 	fmt.Fprintf(src, "DefaultVCpus: aws.Int64(%d),\n", lo.FromPtr(info.VCpuInfo.DefaultVCpus))
+	if len(info.VCpuInfo.ValidThreadsPerCore) > 0 {
+		fmt.Fprintf(src, "ValidThreadsPerCore: aws.Int64Slice([]int64{%s}),\n", strings.Join(lo.Map(info.VCpuInfo.ValidThreadsPerCore, func(t *int64, _ int) string { return fmt.Sprint(lo.FromPtr(t)) }), ", "))
+	}
 	fmt.Fprintf(src, "},\n")
 	fmt.Fprintf(src, "MemoryInfo: &ec2.MemoryInfo{\n")
 	fmt.Fprintf(src, "SizeInMiB: aws.Int64(%d),\n", lo.FromPtr(info.MemoryInfo.SizeInMiB))
@@ -178,6 +185,8 @@ func getNetworkCardInfo(info *ec2.NetworkCardInfo) string {
 	fmt.Fprintf(src, "{\n")
 	fmt.Fprintf(src, "NetworkCardIndex: aws.Int64(%d),\n", lo.FromPtr(info.NetworkCardIndex))
 	fmt.Fprintf(src, "MaximumNetworkInterfaces: aws.Int64(%d),\n", lo.FromPtr(info.MaximumNetworkInterfaces))
+	fmt.Fprintf(src, "BaselineBandwidthInGbps: aws.Float64(%g),\n", lo.FromPtr(info.BaselineBandwidthInGbps))
+	fmt.Fprintf(src, "PeakBandwidthInGbps: aws.Float64(%g),\n", lo.FromPtr(info.PeakBandwidthInGbps))
 	fmt.Fprintf(src, "},\n")
 	return src.String()
 }
@@ -32,6 +32,7 @@ import (
 	ec22 "github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/pricing"
@@ -108,8 +109,8 @@ func main() {
 	// record prices for each region we are interested in
 	for _, region := range getAWSRegions(opts.partition) {
 		log.Println("fetching for", region)
-		pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(sess, region), ec2, region)
-		controller := controllerspricing.NewController(pricingProvider)
+		pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(sess, region), ec2, region, clock.RealClock{})
+		controller := controllerspricing.NewController(clock.RealClock{}, pricingProvider)
 		_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{}})
 		if err != nil {
 			log.Fatalf("failed to initialize pricing provider %s", err)
@@ -42,6 +42,14 @@ vpcLimits() {
   checkForUpdates "${GENERATED_FILE}"
 }
 
+accelerators() {
+  GENERATED_FILE="pkg/providers/instancetype/zz_generated.accelerators.go"
+
+  go run hack/code/accelerator_overlay_gen/main.go --input hack/code/accelerator_overlay_gen/accelerators.yaml --output "${GENERATED_FILE}"
+
+  checkForUpdates "${GENERATED_FILE}"
+}
+
 instanceTypeTestData() {
   GENERATED_FILE="pkg/fake/zz_generated.describe_instance_types.go"
 
@@ -107,6 +115,8 @@ echo "Updating pricing..."
 pricing
 echo "Updating VPC limits..."
 vpcLimits
+echo "Updating accelerator overlay..."
+accelerators
 echo "Updating instance type data..."
 instanceTypeTestData
 echo "Finished codegen"
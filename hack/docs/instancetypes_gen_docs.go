@@ -89,6 +89,8 @@ func main() {
 		ClusterName:     lo.ToPtr("docs-gen"),
 		ClusterEndpoint: lo.ToPtr("https://docs-gen.aws"),
 		IsolatedVPC:     lo.ToPtr(true), // disable pricing lookup
+		// include the opt-in network bandwidth resource so that it's documented for each instance type
+		NetworkBandwidthResource: lo.ToPtr(true),
 	}))
 
 	ctx, op := operator.NewOperator(ctx, &coreoperator.Operator{
@@ -96,7 +98,7 @@ func main() {
 		KubernetesInterface: kubernetes.NewForConfigOrDie(&rest.Config{}),
 	})
 	cp := awscloudprovider.New(op.InstanceTypesProvider, op.InstanceProvider,
-		op.EventRecorder, op.GetClient(), op.AMIProvider, op.SecurityGroupProvider, op.SubnetProvider)
+		op.EventRecorder, op.GetClient(), op.AMIProvider, op.SecurityGroupProvider, op.SubnetProvider, op.CostLimitProvider)
 
 	instanceTypes, err := cp.GetInstanceTypes(ctx, nil)
 	if err != nil {
@@ -124,7 +126,9 @@ description: >
 below are the resources available with some assumptions and after the instance overhead has been subtracted:
 - `+"`blockDeviceMappings` are not configured"+`
 - `+"`aws-eni-limited-pod-density` is assumed to be `true`"+`
-- `+"`amiFamily` is set to the default of `AL2`")
+- `+"`amiFamily` is set to the default of `AL2`"+`
+- `+"`karpenter.k8s.aws/network-bandwidth` is only reported when the `--network-bandwidth-resource` setting is enabled"+`
+- `+"labels gated behind the `--enabled-labels` setting are not listed")
 
 	// generate a map of family -> instance types along with some other sorted lists.  The sorted lists ensure we
 	// generate consistent docs every run.
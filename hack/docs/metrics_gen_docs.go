@@ -283,6 +283,7 @@ func getIdentMapping(identName string) (string, error) {
 		"disruptionSubsystem":     "disruption",
 		"consistencySubsystem":    "consistency",
 		"batcherSubsystem":        "cloudprovider_batcher",
+		"awsSubsystem":            "aws",
 		"cloudProviderSubsystem":  "cloudprovider",
 		"stateSubsystem":          "cluster_state",
 	}
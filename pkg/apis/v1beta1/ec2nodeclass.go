@@ -16,7 +16,10 @@ package v1beta1
 
 import (
 	"fmt"
+	"path"
+	"sort"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -45,14 +48,20 @@ type EC2NodeClassSpec struct {
 	// AssociatePublicIPAddress controls if public IP addresses are assigned to instances that are launched with the nodeclass.
 	// +optional
 	AssociatePublicIPAddress *bool `json:"associatePublicIPAddress,omitempty"`
+	// IPv6 assigns IPv6 prefixes or addresses to the primary network interface of instances that are launched with the
+	// nodeclass. The subnets must have IPv6 CIDR blocks.
+	// +kubebuilder:validation:XValidation:message="prefixCount and addressCount are mutually exclusive",rule="!(has(self.prefixCount) && has(self.addressCount))"
+	// +optional
+	IPv6 *IPv6 `json:"ipv6,omitempty"`
 	// AMISelectorTerms is a list of or ami selector terms. The terms are ORed.
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'ssmParameter']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.ssmParameter))"
 	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name) || has(x.owner)))"
+	// +kubebuilder:validation:XValidation:message="'ssmParameter' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.ssmParameter) && (has(x.id) || has(x.tags) || has(x.name) || has(x.owner)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +optional
 	AMISelectorTerms []AMISelectorTerm `json:"amiSelectorTerms,omitempty" hash:"ignore"`
 	// AMIFamily is the AMI family that instances use.
-	// +kubebuilder:validation:Enum:={AL2,AL2023,Bottlerocket,Ubuntu,Custom,Windows2019,Windows2022}
+	// +kubebuilder:validation:Enum:={AL2,AL2023,Bottlerocket,Ubuntu,Custom,Windows2019,Windows2022,Windows2025}
 	// +required
 	AMIFamily *string `json:"amiFamily"`
 	// UserData to be applied to the provisioned nodes.
@@ -82,7 +91,10 @@ type EC2NodeClassSpec struct {
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/managed-by",rule="self.all(k, k !='karpenter.sh/managed-by')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodeclaim",rule="self.all(k, k !='karpenter.sh/nodeclaim')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/taints",rule="self.all(k, k !='karpenter.sh/taints')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/taints-truncated",rule="self.all(k, k !='karpenter.sh/taints-truncated')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/termination-behavior",rule="self.all(k, k !='karpenter.k8s.aws/termination-behavior')"
 	// +optional
 	Tags map[string]string `json:"tags,omitempty"`
 	// BlockDeviceMappings to be applied to provisioned nodes.
@@ -90,9 +102,36 @@ type EC2NodeClassSpec struct {
 	// +kubebuilder:validation:MaxItems:=50
 	// +optional
 	BlockDeviceMappings []*BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
+	// BlockDeviceDefaults are applied to the EBS volumes of every block device mapping that doesn't set them, including
+	// the AMI family's default block device mappings when blockDeviceMappings is omitted.
+	// +optional
+	BlockDeviceDefaults *BlockDeviceDefaults `json:"blockDeviceDefaults,omitempty"`
 	// InstanceStorePolicy specifies how to handle instance-store disks.
 	// +optional
 	InstanceStorePolicy *InstanceStorePolicy `json:"instanceStorePolicy,omitempty"`
+	// InstanceStore configures how the instance store disks are assembled when the InstanceStorePolicy is RAID0.
+	// If omitted, the disks are striped and formatted with xfs.
+	// +optional
+	InstanceStore *InstanceStore `json:"instanceStore,omitempty"`
+	// VMMemoryOverheadPercent is the fraction of an instance type's memory, such as "0.075", that is subtracted from its
+	// capacity to account for the memory consumed by the hypervisor and the operating system.
+	// If omitted, the operator's vm-memory-overhead-percent is used.
+	// +kubebuilder:validation:Pattern:="^0(\\.[0-9]+)?$"
+	// +optional
+	VMMemoryOverheadPercent *string `json:"vmMemoryOverheadPercent,omitempty" hash:"ignore"`
+	// MaxPodsOverrides sets the maximum number of pods for instance types matching an instance type glob, such as "t3.*"
+	// or "m5.large". When several globs match an instance type, the longest glob is used. Instance types that don't match
+	// any glob use the kubelet's maxPods, or the AMI family's default when it isn't set.
+	// +kubebuilder:validation:XValidation:message="maxPodsOverrides keys can't be empty",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:XValidation:message="maxPodsOverrides values must be non-negative",rule="self.all(k, self[k] >= 0)"
+	// +kubebuilder:validation:MaxProperties:=50
+	// +optional
+	MaxPodsOverrides map[string]int32 `json:"maxPodsOverrides,omitempty"`
+	// ReservedENIs is the number of ENIs that are left out of max-pods and kube-reserved, such as the trunk ENI of
+	// security groups for pods. If omitted, the operator's reserved-enis is used.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	ReservedENIs *int32 `json:"reservedENIs,omitempty"`
 	// DetailedMonitoring controls if detailed monitoring is enabled for instances that are launched
 	// +optional
 	DetailedMonitoring *bool `json:"detailedMonitoring,omitempty"`
@@ -117,6 +156,111 @@ type EC2NodeClassSpec struct {
 	// https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFleet.html
 	// +optional
 	Context *string `json:"context,omitempty"`
+	// OutpostARN is the ARN of the AWS Outpost that instances are launched onto. Only subnets on the Outpost are
+	// selected, only instance types that are available on the Outpost are launched, and instances are always launched
+	// as on-demand since Outposts don't support spot.
+	// +kubebuilder:validation:Pattern:="^arn:[^:]+:outposts:[^:]+:[0-9]{12}:outpost/op-[0-9a-z]+$"
+	// +optional
+	OutpostARN *string `json:"outpostARN,omitempty"`
+	// Placement configures the placement group that instances are launched into.
+	// If the placement group uses the cluster strategy, each launch is constrained to a single availability zone.
+	// +optional
+	Placement *Placement `json:"placement,omitempty"`
+	// Tenancy of the instances launched with this EC2NodeClass. Host tenancy is limited to instance types that support
+	// Dedicated Hosts, and spot capacity is only launched with default tenancy. If omitted, instances run on shared hardware.
+	// +kubebuilder:validation:Enum:={default,dedicated,host}
+	// +optional
+	Tenancy *string `json:"tenancy,omitempty"`
+	// HostPlacement selects the Dedicated Hosts that instances are launched onto when using host tenancy.
+	// If omitted, instances are launched onto any available Dedicated Host with auto-placement enabled.
+	// +optional
+	HostPlacement *HostPlacement `json:"hostPlacement,omitempty"`
+	// CapacityReservationSelectorTerms is a list of or capacity reservation selector terms. The terms are ORed.
+	// Active Capacity Blocks for ML that are selected are offered with the capacity-block capacity type, in the zone
+	// and for the instance type that they reserve.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in capacityReservationSelectorTerms",rule="!self.all(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	CapacityReservationSelectorTerms []CapacityReservationSelectorTerm `json:"capacityReservationSelectorTerms,omitempty" hash:"ignore"`
+	// TerminationBehavior controls whether instances are terminated or stopped when Karpenter deprovisions their nodes.
+	// Stopped instances are tagged and are no longer managed by Karpenter. Spot instances can't be stopped, so they're
+	// always terminated. If omitted, instances are terminated.
+	// +optional
+	TerminationBehavior *TerminationBehavior `json:"terminationBehavior,omitempty" hash:"ignore"`
+	// OnDemandOptions configures how EC2 Fleet chooses between instance types for on-demand launches.
+	// If omitted, the operator's on-demand allocation strategy is used.
+	// +optional
+	OnDemandOptions *OnDemandOptions `json:"onDemandOptions,omitempty" hash:"ignore"`
+	// SubnetSelectionPolicy controls how Karpenter chooses between selected subnets that share an availability zone.
+	// If omitted, the subnet with the most available IP addresses is chosen.
+	// +optional
+	SubnetSelectionPolicy *SubnetSelectionPolicy `json:"subnetSelectionPolicy,omitempty" hash:"ignore"`
+	// AMIStabilization holds newly resolved AMIs before they are adopted in the EC2NodeClass status, so that nodes are
+	// not drifted as soon as a new AMI is published. Until the pending AMIs are adopted, nodes continue to launch with
+	// the AMIs in the status. Adoption can be forced by annotating the EC2NodeClass with karpenter.k8s.aws/force-ami-adoption: "true".
+	// If omitted, newly resolved AMIs are adopted immediately.
+	// +optional
+	AMIStabilization *AMIStabilization `json:"amiStabilization,omitempty" hash:"ignore"`
+}
+
+// OnDemandOptions defines the allocation strategy used by Karpenter to launch on-demand instances.
+// +kubebuilder:validation:XValidation:message="instanceTypePriorities must be specified when allocationStrategy is prioritized",rule="!has(self.allocationStrategy) || self.allocationStrategy != 'prioritized' || (has(self.instanceTypePriorities) && size(self.instanceTypePriorities) > 0)"
+type OnDemandOptions struct {
+	// AllocationStrategy is the EC2 Fleet on-demand allocation strategy.
+	// +kubebuilder:validation:Enum:={lowest-price,prioritized}
+	// +optional
+	AllocationStrategy *string `json:"allocationStrategy,omitempty"`
+	// InstanceTypePriorities is an ordered list of instance type globs, such as "m5.*" or "c6i.2xlarge", used with the
+	// prioritized allocation strategy. Instance types matching an earlier glob are preferred over those matching a later one.
+	// Instance types matching the same glob, or no glob, are ordered by price.
+	// +kubebuilder:validation:XValidation:message="instance type priorities can't be empty",rule="self.all(x, x != '')"
+	// +kubebuilder:validation:MaxItems:=50
+	// +optional
+	InstanceTypePriorities []string `json:"instanceTypePriorities,omitempty"`
+}
+
+// AMIStabilization defines when newly resolved AMIs are adopted by an EC2NodeClass.
+type AMIStabilization struct {
+	// Window is how long a newly resolved set of AMIs must remain unchanged before it is adopted.
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +kubebuilder:validation:Type:="string"
+	// +required
+	Window metav1.Duration `json:"window"`
+	// AllowedHoursUTC are the hours of the day, in UTC, during which AMIs that have completed the stabilization window
+	// may be adopted. If omitted, AMIs may be adopted at any hour.
+	// +kubebuilder:validation:XValidation:message="allowedHoursUTC must be between 0 and 23",rule="self.all(x, x >= 0 && x <= 23)"
+	// +kubebuilder:validation:MaxItems:=24
+	// +optional
+	AllowedHoursUTC []int32 `json:"allowedHoursUTC,omitempty"`
+}
+
+// Placement defines the EC2 placement group used by Karpenter to launch nodes.
+type Placement struct {
+	// GroupName is the name of an existing placement group
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=255
+	// +required
+	GroupName string `json:"groupName"`
+	// PartitionNumber is the partition of a partition placement group to launch instances into.
+	// If omitted, EC2 distributes instances across the partitions.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=7
+	// +optional
+	PartitionNumber *int64 `json:"partitionNumber,omitempty"`
+}
+
+// HostPlacement defines the Dedicated Hosts used by Karpenter to launch nodes with host tenancy.
+// +kubebuilder:validation:XValidation:message="must specify exactly one of ['hostID', 'hostResourceGroupARN']",rule="has(self.hostID) != has(self.hostResourceGroupARN)"
+type HostPlacement struct {
+	// HostID is the ID of the Dedicated Host to launch instances onto
+	// +kubebuilder:validation:Pattern:="h-[0-9a-z]+"
+	// +optional
+	HostID *string `json:"hostID,omitempty"`
+	// HostResourceGroupARN is the ARN of the host resource group to launch instances into
+	// +kubebuilder:validation:Pattern:="^arn:[^:]+:resource-groups:"
+	// +optional
+	HostResourceGroupARN *string `json:"hostResourceGroupARN,omitempty"`
 }
 
 // SubnetSelectorTerm defines selection logic for a subnet used by Karpenter to launch nodes.
@@ -149,9 +293,28 @@ type SecurityGroupSelectorTerm struct {
 	ID string `json:"id,omitempty"`
 	// Name is the security group name in EC2.
 	// This value is the name field, which is different from the name tag.
+	// The name may contain the wildcards '*' and '?' to select every security group whose name matches, e.g.
+	// 'eksctl-my-cluster-nodegroup-*-SG'.
+	// +kubebuilder:validation:XValidation:message="name cannot be empty",rule="self != ''"
+	// +optional
 	Name string `json:"name,omitempty"`
 }
 
+// CapacityReservationSelectorTerm defines selection logic for a capacity reservation used by Karpenter to launch nodes.
+// If multiple fields are used for selection, the requirements are ANDed.
+type CapacityReservationSelectorTerm struct {
+	// Tags is a map of key/value tags used to select capacity reservations
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the capacity reservation id in EC2
+	// +kubebuilder:validation:Pattern="cr-[0-9a-z]+"
+	// +optional
+	ID string `json:"id,omitempty"`
+}
+
 // AMISelectorTerm defines selection logic for an ami used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
 type AMISelectorTerm struct {
@@ -170,9 +333,16 @@ type AMISelectorTerm struct {
 	// +optional
 	Name string `json:"name,omitempty"`
 	// Owner is the owner for the ami.
-	// You can specify a combination of AWS account IDs, "self", "amazon", and "aws-marketplace"
+	// You can specify a combination of AWS account IDs, "self", "amazon", and "aws-marketplace".
+	// Terms with a name or tags and no owner are restricted to the operator's default owners, and "*" searches
+	// the AMIs of every owner, including public AMIs.
 	// +optional
 	Owner string `json:"owner,omitempty"`
+	// SSMParameter is the name of an SSM parameter whose value is an ami id. The parameter is resolved on every
+	// reconcile, so promoting a new ami is done by updating the parameter.
+	// +kubebuilder:validation:MaxLength:=2048
+	// +optional
+	SSMParameter string `json:"ssmParameter,omitempty"`
 }
 
 // MetadataOptions contains parameters for specifying the exposure of the
@@ -303,8 +473,33 @@ type BlockDevice struct {
 	VolumeType *string `json:"volumeType,omitempty"`
 }
 
+// BlockDeviceDefaults are the EBS volume settings that block device mappings fall back to. IOPS and throughput are only
+// applied to volumes whose type supports them, and the KMS key is only applied to encrypted volumes.
+// +kubebuilder:validation:XValidation:message="iops is only supported with the gp3, io1 and io2 volume types",rule="has(self.iops) ? (has(self.volumeType) && self.volumeType in ['gp3', 'io1', 'io2']) : true"
+// +kubebuilder:validation:XValidation:message="throughput is only supported with the gp3 volume type",rule="has(self.throughput) ? (has(self.volumeType) && self.volumeType == 'gp3') : true"
+type BlockDeviceDefaults struct {
+	// VolumeType of the block devices that don't set one.
+	// +kubebuilder:validation:Enum:={standard,io1,io2,gp2,sc1,st1,gp3}
+	// +optional
+	VolumeType *string `json:"volumeType,omitempty"`
+	// IOPS provisioned for gp3, io1 and io2 block devices that don't set it.
+	// +optional
+	IOPS *int64 `json:"iops,omitempty"`
+	// Throughput in MiB/s provisioned for gp3 block devices that don't set it.
+	// +kubebuilder:validation:Minimum:=125
+	// +kubebuilder:validation:Maximum:=1000
+	// +optional
+	Throughput *int64 `json:"throughput,omitempty"`
+	// Encrypted indicates whether block devices that don't set it are encrypted.
+	// +optional
+	Encrypted *bool `json:"encrypted,omitempty"`
+	// KMSKeyID (ARN) of the KMS key that encrypted block devices without one are encrypted with.
+	// +optional
+	KMSKeyID *string `json:"kmsKeyID,omitempty"`
+}
+
 // InstanceStorePolicy enumerates options for configuring instance store disks.
-// +kubebuilder:validation:Enum={RAID0}
+// +kubebuilder:validation:Enum={RAID0,RAID10}
 type InstanceStorePolicy string
 
 const (
@@ -313,6 +508,79 @@ const (
 	// ephemeral storage for more and faster node ephemeral-storage. The node's ephemeral storage can be shared among
 	// pods that request ephemeral storage and container images that are downloaded to the node.
 	InstanceStorePolicyRAID0 InstanceStorePolicy = "RAID0"
+
+	// InstanceStorePolicyRAID10 configures a RAID-10 array that stripes and mirrors all ephemeral NVMe instance storage
+	// disks, which is used for the containerd and kubelet state directories like InstanceStorePolicyRAID0. The array
+	// survives the loss of a disk, but only half of the disks' capacity is usable. Mirroring needs at least two disks,
+	// so instance types with a single instance store disk aren't launched with it.
+	InstanceStorePolicyRAID10 InstanceStorePolicy = "RAID10"
+)
+
+// InstanceStore configures the array that the instance store disks of an instance are assembled into
+type InstanceStore struct {
+	// RAIDLevel of the array. RAID0 stripes data across the disks, and RAID1 mirrors it onto every disk so that the array
+	// is only as large as the smallest disk. RAID1 needs at least two disks, so instance types with a single instance
+	// store disk aren't launched with it.
+	// +kubebuilder:validation:Enum:={RAID0,RAID1}
+	// +optional
+	RAIDLevel *RAIDLevel `json:"raidLevel,omitempty"`
+	// Filesystem that the array is formatted with.
+	// +kubebuilder:validation:Enum:={xfs,ext4}
+	// +optional
+	Filesystem *string `json:"filesystem,omitempty"`
+	// ReportUsableCapacity, if true, reduces the ephemeral-storage capacity of instance types by the estimated overhead
+	// of the filesystem so that it reflects the space available to pods.
+	// +optional
+	ReportUsableCapacity *bool `json:"reportUsableCapacity,omitempty"`
+}
+
+// RAIDLevel enumerates the RAID levels that instance store disks can be assembled with
+type RAIDLevel string
+
+const (
+	RAIDLevelRAID0 RAIDLevel = "RAID0"
+	RAIDLevelRAID1 RAIDLevel = "RAID1"
+
+	InstanceStoreFilesystemXFS  = "xfs"
+	InstanceStoreFilesystemExt4 = "ext4"
+)
+
+// IPv6 configures the IPv6 prefixes or addresses that are assigned to the primary network interface of an instance
+type IPv6 struct {
+	// PrefixCount is the number of /80 IPv6 prefixes that are delegated to the primary network interface. Pod IPs are
+	// assigned from delegated prefixes, so max-pods and kube-reserved are calculated as they are with IPv4 prefix
+	// delegation.
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	PrefixCount *int64 `json:"prefixCount,omitempty"`
+	// AddressCount is the number of IPv6 addresses that are assigned to the primary network interface.
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	AddressCount *int64 `json:"addressCount,omitempty"`
+}
+
+// TerminationBehavior enumerates the actions taken on an instance when its node is deprovisioned.
+// +kubebuilder:validation:Enum={Terminate,Stop}
+type TerminationBehavior string
+
+const (
+	// TerminationBehaviorTerminate terminates the instance, deleting any volumes marked for deletion on termination.
+	TerminationBehaviorTerminate TerminationBehavior = "Terminate"
+	// TerminationBehaviorStop stops the instance so that its volumes can be inspected or attached elsewhere. The
+	// instance is tagged with TagTerminationBehavior and must be cleaned up outside of Karpenter.
+	TerminationBehaviorStop TerminationBehavior = "Stop"
+)
+
+// SubnetSelectionPolicy enumerates the ways of choosing a subnet among the selected subnets in an availability zone.
+// +kubebuilder:validation:Enum={MostAvailableIPs,Balanced}
+type SubnetSelectionPolicy string
+
+const (
+	// SubnetSelectionPolicyMostAvailableIPs launches into the subnet with the most available IP addresses in each zone.
+	SubnetSelectionPolicyMostAvailableIPs SubnetSelectionPolicy = "MostAvailableIPs"
+	// SubnetSelectionPolicyBalanced spreads launches across the subnets in each zone, choosing each subnet with a
+	// probability proportional to its available IP addresses.
+	SubnetSelectionPolicyBalanced SubnetSelectionPolicy = "Balanced"
 )
 
 // EC2NodeClass is the Schema for the EC2NodeClass API
@@ -325,6 +593,9 @@ type EC2NodeClass struct {
 
 	// +kubebuilder:validation:XValidation:message="amiSelectorTerms is required when amiFamily == 'Custom'",rule="self.amiFamily == 'Custom' ? self.amiSelectorTerms.size() != 0 : true"
 	// +kubebuilder:validation:XValidation:message="must specify exactly one of ['role', 'instanceProfile']",rule="(has(self.role) && !has(self.instanceProfile)) || (!has(self.role) && has(self.instanceProfile))"
+	// +kubebuilder:validation:XValidation:message="hostPlacement may only be specified with host tenancy",rule="has(self.hostPlacement) ? (has(self.tenancy) && self.tenancy == 'host') : true"
+	// +kubebuilder:validation:XValidation:message="instanceStore may only be specified with the RAID0 instanceStorePolicy",rule="has(self.instanceStore) ? (has(self.instanceStorePolicy) && self.instanceStorePolicy == 'RAID0') : true"
+	// +kubebuilder:validation:XValidation:message="the RAID10 instanceStorePolicy isn't supported with Windows AMI families",rule="has(self.instanceStorePolicy) && self.instanceStorePolicy == 'RAID10' ? !self.amiFamily.startsWith('Windows') : true"
 	// +kubebuilder:validation:XValidation:message="changing from 'instanceProfile' to 'role' is not supported. You must delete and recreate this node class if you want to change this.",rule="(has(oldSelf.role) && has(self.role)) || (has(oldSelf.instanceProfile) && has(self.instanceProfile))"
 	Spec   EC2NodeClassSpec   `json:"spec,omitempty"`
 	Status EC2NodeClassStatus `json:"status,omitempty"`
@@ -334,7 +605,8 @@ type EC2NodeClass struct {
 // 1. A field changes its default value for an existing field that is already hashed
 // 2. A field is added to the hash calculation with an already-set value
 // 3. A field is removed from the hash calculations
-const EC2NodeClassHashVersion = "v2"
+// v3 folds the hashes of the resolved subnet and security group IDs into the drift hash.
+const EC2NodeClassHashVersion = "v3"
 
 func (in *EC2NodeClass) Hash() string {
 	return fmt.Sprint(lo.Must(hashstructure.Hash(in.Spec, hashstructure.FormatV2, &hashstructure.HashOptions{
@@ -344,10 +616,71 @@ func (in *EC2NodeClass) Hash() string {
 	})))
 }
 
+// DriftHash is the hash that NodeClaims are checked against for static drift. It folds the hashes of the resolved
+// subnet and security group IDs, once the status controller has recorded them, into the hash of the spec, so that
+// NodeClaims launched before subnets or security groups were changed out-of-band are drifted.
+func (in *EC2NodeClass) DriftHash() string {
+	if !in.HasResolvedHashes() {
+		return in.Hash()
+	}
+	return fmt.Sprint(lo.Must(hashstructure.Hash([]string{
+		in.Hash(),
+		in.Annotations[AnnotationEC2NodeClassSubnetsHash],
+		in.Annotations[AnnotationEC2NodeClassSecurityGroupsHash],
+	}, hashstructure.FormatV2, nil)))
+}
+
+// HasResolvedHashes returns whether the hashes of both the resolved subnet and security group IDs have been recorded
+func (in *EC2NodeClass) HasResolvedHashes() bool {
+	_, subnets := in.Annotations[AnnotationEC2NodeClassSubnetsHash]
+	_, securityGroups := in.Annotations[AnnotationEC2NodeClassSecurityGroupsHash]
+	return subnets && securityGroups
+}
+
+// ResolvedIDsHash hashes resolved subnet or security group IDs. Only the sorted IDs are hashed, so that the order that
+// EC2 returns them in and changes to their other fields, e.g. free IP addresses, don't drift NodeClaims.
+func ResolvedIDsHash(ids []string) string {
+	sorted := lo.Uniq(ids)
+	sort.Strings(sorted)
+	return fmt.Sprint(lo.Must(hashstructure.Hash(sorted, hashstructure.FormatV2, nil)))
+}
+
+// MaxPodsOverride returns the max-pods override for the instance type, if one of the EC2NodeClass's instance type globs
+// matches it. The longest matching glob takes precedence, with ties broken alphabetically.
+func (in *EC2NodeClass) MaxPodsOverride(instanceType string) (int32, bool) {
+	globs := lo.Keys(in.Spec.MaxPodsOverrides)
+	sort.Slice(globs, func(i, j int) bool {
+		if len(globs[i]) != len(globs[j]) {
+			return len(globs[i]) > len(globs[j])
+		}
+		return globs[i] < globs[j]
+	})
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, instanceType); matched {
+			return in.Spec.MaxPodsOverrides[glob], true
+		}
+	}
+	return 0, false
+}
+
+// CapacityBlockReservation returns the capacity block that the EC2NodeClass resolved for the instance type in the zone.
+// When several capacity blocks reserve the same instance type in the same zone, the one that sorts first is returned.
+func (in *EC2NodeClass) CapacityBlockReservation(instanceType, zone string) (CapacityReservation, bool) {
+	return lo.Find(in.Status.CapacityReservations, func(cr CapacityReservation) bool {
+		return cr.ReservationType == ec2.CapacityReservationTypeCapacityBlock && cr.InstanceType == instanceType && cr.AvailabilityZone == zone
+	})
+}
+
 func (in *EC2NodeClass) InstanceProfileName(clusterName, region string) string {
 	return fmt.Sprintf("%s_%d", clusterName, lo.Must(hashstructure.Hash(fmt.Sprintf("%s%s", region, in.Name), hashstructure.FormatV2, nil)))
 }
 
+// SharedInstanceProfileName is the name of the instance profile that every EC2NodeClass in the region with the same
+// role shares when shared instance profiles are enabled
+func (in *EC2NodeClass) SharedInstanceProfileName(clusterName, region string) string {
+	return fmt.Sprintf("%s_%d", clusterName, lo.Must(hashstructure.Hash(fmt.Sprintf("%srole/%s", region, in.Spec.Role), hashstructure.FormatV2, nil)))
+}
+
 func (in *EC2NodeClass) InstanceProfileRole() string {
 	return in.Spec.Role
 }
@@ -360,6 +693,17 @@ func (in *EC2NodeClass) InstanceProfileTags(clusterName string) map[string]strin
 	})
 }
 
+// SharedInstanceProfileTags are the tags of a shared instance profile. The tags of the EC2NodeClass aren't included,
+// since the EC2NodeClasses sharing the instance profile could set different values for them. The EC2NodeClass tag,
+// which the controller policy requires on instance profiles, is valued with the role rather than any one EC2NodeClass.
+func (in *EC2NodeClass) SharedInstanceProfileTags(clusterName string) map[string]string {
+	return map[string]string{
+		fmt.Sprintf("kubernetes.io/cluster/%s", clusterName): "owned",
+		corev1beta1.ManagedByAnnotationKey:                   clusterName,
+		LabelNodeClass:                                       fmt.Sprintf("role/%s", in.Spec.Role),
+	}
+}
+
 // EC2NodeClassList contains a list of EC2NodeClass
 // +kubebuilder:object:root=true
 type EC2NodeClassList struct {
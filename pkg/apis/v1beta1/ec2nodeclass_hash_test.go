@@ -15,10 +15,13 @@ limitations under the License.
 package v1beta1_test
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
@@ -136,7 +139,9 @@ var _ = Describe("Hash", func() {
 		Entry("DetailedMonitoring", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{DetailedMonitoring: aws.Bool(true)}}),
 		Entry("AMIFamily", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AMIFamily: aws.String(v1beta1.AMIFamilyBottlerocket)}}),
 		Entry("InstanceStorePolicy", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{InstanceStorePolicy: lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)}}),
+		Entry("InstanceStore", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{InstanceStore: &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}}}),
 		Entry("AssociatePublicIPAddress", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AssociatePublicIPAddress: lo.ToPtr(true)}}),
+		Entry("IPv6", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{IPv6: &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](1)}}}),
 		Entry("MetadataOptions HTTPEndpoint", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPEndpoint: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPProtocolIPv6", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPProtocolIPv6: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPPutResponseHopLimit", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPPutResponseHopLimit: lo.ToPtr(int64(10))}}}),
@@ -150,6 +155,12 @@ var _ = Describe("Hash", func() {
 		Entry("BlockDeviceMapping SnapshotID", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{BlockDeviceMappings: []*v1beta1.BlockDeviceMapping{{EBS: &v1beta1.BlockDevice{SnapshotID: lo.ToPtr("test")}}}}}),
 		Entry("BlockDeviceMapping Throughput", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{BlockDeviceMappings: []*v1beta1.BlockDeviceMapping{{EBS: &v1beta1.BlockDevice{Throughput: lo.ToPtr(int64(10))}}}}}),
 		Entry("BlockDeviceMapping VolumeType", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{BlockDeviceMappings: []*v1beta1.BlockDeviceMapping{{EBS: &v1beta1.BlockDevice{VolumeType: lo.ToPtr("io1")}}}}}),
+		Entry("Tenancy", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{Tenancy: aws.String("dedicated")}}),
+		Entry("HostPlacement", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{HostPlacement: &v1beta1.HostPlacement{HostID: aws.String("h-0123456789abcdef0")}}}),
+		Entry("OutpostARN", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{OutpostARN: aws.String("arn:aws:outposts:us-west-2:123456789012:outpost/op-0123456789abcdef0")}}),
+		Entry("BlockDeviceDefaults VolumeType", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{BlockDeviceDefaults: &v1beta1.BlockDeviceDefaults{VolumeType: lo.ToPtr("gp3")}}}),
+		Entry("BlockDeviceDefaults IOPS", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{BlockDeviceDefaults: &v1beta1.BlockDeviceDefaults{IOPS: lo.ToPtr(int64(4000))}}}),
+		Entry("BlockDeviceDefaults Throughput", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{BlockDeviceDefaults: &v1beta1.BlockDeviceDefaults{Throughput: lo.ToPtr(int64(250))}}}),
 	)
 	// We create a separate test for updating blockDeviceMapping volumeSize, since resource.Quantity is a struct, and mergo.WithSliceDeepCopy
 	// doesn't work well with unexported fields, like the ones that are present in resource.Quantity
@@ -198,6 +209,8 @@ var _ = Describe("Hash", func() {
 				Tags: map[string]string{"ami-test-key": "ami-test-value"},
 			},
 		}
+		nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyBalanced)
+		nodeClass.Spec.AMIStabilization = &v1beta1.AMIStabilization{Window: metav1.Duration{Duration: time.Hour}}
 		updatedHash := nodeClass.Hash()
 		Expect(hash).To(Equal(updatedHash))
 	})
@@ -207,4 +220,36 @@ var _ = Describe("Hash", func() {
 		})
 		Expect(nodeClass.Hash()).To(Equal(otherNodeClass.Hash()))
 	})
+	Context("DriftHash", func() {
+		It("should match the hash until the resolved subnets and security groups are recorded", func() {
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationEC2NodeClassSubnetsHash: v1beta1.ResolvedIDsHash([]string{"subnet-1"})}
+			Expect(nodeClass.HasResolvedHashes()).To(BeFalse())
+			Expect(nodeClass.DriftHash()).To(Equal(nodeClass.Hash()))
+
+			nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassSecurityGroupsHash] = v1beta1.ResolvedIDsHash([]string{"sg-1"})
+			Expect(nodeClass.HasResolvedHashes()).To(BeTrue())
+			Expect(nodeClass.DriftHash()).ToNot(Equal(nodeClass.Hash()))
+		})
+		It("should change when the resolved subnets or security groups change", func() {
+			nodeClass.Annotations = map[string]string{
+				v1beta1.AnnotationEC2NodeClassSubnetsHash:        v1beta1.ResolvedIDsHash([]string{"subnet-1"}),
+				v1beta1.AnnotationEC2NodeClassSecurityGroupsHash: v1beta1.ResolvedIDsHash([]string{"sg-1"}),
+			}
+			hash := nodeClass.DriftHash()
+			nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassSubnetsHash] = v1beta1.ResolvedIDsHash([]string{"subnet-1", "subnet-2"})
+			subnetHash := nodeClass.DriftHash()
+			Expect(subnetHash).ToNot(Equal(hash))
+			nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassSecurityGroupsHash] = v1beta1.ResolvedIDsHash([]string{"sg-2"})
+			Expect(nodeClass.DriftHash()).ToNot(Equal(subnetHash))
+		})
+		It("should hash resolved IDs regardless of their order or duplicates", func() {
+			Expect(v1beta1.ResolvedIDsHash([]string{"subnet-1", "subnet-2", "subnet-3"})).To(Equal(v1beta1.ResolvedIDsHash([]string{"subnet-3", "subnet-1", "subnet-2", "subnet-1"})))
+			Expect(v1beta1.ResolvedIDsHash([]string{"subnet-1", "subnet-2"})).ToNot(Equal(v1beta1.ResolvedIDsHash([]string{"subnet-1"})))
+		})
+		It("should not reorder the IDs that it's given", func() {
+			ids := []string{"subnet-2", "subnet-1"}
+			v1beta1.ResolvedIDsHash(ids)
+			Expect(ids).To(Equal([]string{"subnet-2", "subnet-1"}))
+		})
+	})
 })
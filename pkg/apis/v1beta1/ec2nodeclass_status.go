@@ -15,6 +15,10 @@ limitations under the License.
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 )
 
@@ -26,8 +30,43 @@ type Subnet struct {
 	// The associated availability zone
 	// +required
 	Zone string `json:"zone"`
+	// The associated availability zone ID
+	// +optional
+	ZoneID string `json:"zoneID,omitempty"`
+	// VPCID is the ID of the VPC that the subnet is in
+	// +optional
+	VPCID string `json:"vpcID,omitempty"`
+	// ZoneType is the type of the zone that the subnet is in: availability-zone, local-zone or
+	// wavelength-zone
+	// +optional
+	ZoneType string `json:"zoneType,omitempty"`
+	// NetworkBorderGroup is the network border group of the zone that the subnet is in
+	// +optional
+	NetworkBorderGroup string `json:"networkBorderGroup,omitempty"`
+	// AvailableIPAddressCount is the number of free IPv4 addresses in the subnet when it was last resolved
+	// +optional
+	AvailableIPAddressCount int64 `json:"availableIPAddressCount"`
+	// ClusterTag reports whether the subnet carries the kubernetes.io/cluster/<cluster-name> tag. It's only set when
+	// subnet cluster tagging is enabled.
+	// +kubebuilder:validation:Enum:={Present,Tagged,WouldTag,Failed}
+	// +optional
+	ClusterTag SubnetClusterTag `json:"clusterTag,omitempty"`
 }
 
+// SubnetClusterTag is the state of a subnet's kubernetes.io/cluster/<cluster-name> tag
+type SubnetClusterTag string
+
+const (
+	// SubnetClusterTagPresent means that the subnet already carried the tag
+	SubnetClusterTagPresent SubnetClusterTag = "Present"
+	// SubnetClusterTagTagged means that Karpenter added the tag to the subnet
+	SubnetClusterTagTagged SubnetClusterTag = "Tagged"
+	// SubnetClusterTagWouldTag means that Karpenter would have added the tag if it wasn't running in dry-run mode
+	SubnetClusterTagWouldTag SubnetClusterTag = "WouldTag"
+	// SubnetClusterTagFailed means that Karpenter wasn't permitted to tag the subnet
+	SubnetClusterTagFailed SubnetClusterTag = "Failed"
+)
+
 // SecurityGroup contains resolved SecurityGroup selector values utilized for node launch
 type SecurityGroup struct {
 	// ID of the security group
@@ -36,6 +75,26 @@ type SecurityGroup struct {
 	// Name of the security group
 	// +optional
 	Name string `json:"name,omitempty"`
+	// VPCID is the ID of the VPC that the security group is in
+	// +optional
+	VPCID string `json:"vpcID,omitempty"`
+}
+
+// CapacityReservation contains resolved CapacityReservation selector values utilized for node launch
+type CapacityReservation struct {
+	// ID of the capacity reservation
+	// +required
+	ID string `json:"id"`
+	// InstanceType is the instance type that the capacity reservation reserves
+	// +required
+	InstanceType string `json:"instanceType"`
+	// AvailabilityZone is the availability zone that the capacity reservation reserves capacity in
+	// +required
+	AvailabilityZone string `json:"availabilityZone"`
+	// ReservationType is the type of the capacity reservation: default or capacity-block
+	// +kubebuilder:validation:Enum:={default,capacity-block}
+	// +optional
+	ReservationType string `json:"reservationType,omitempty"`
 }
 
 // AMI contains resolved AMI selector values utilized for node launch
@@ -46,9 +105,22 @@ type AMI struct {
 	// Name of the AMI
 	// +optional
 	Name string `json:"name,omitempty"`
+	// Owner is the ID of the AWS account that owns the AMI
+	// +optional
+	Owner string `json:"owner,omitempty"`
 	// Requirements of the AMI to be utilized on an instance type
 	// +required
 	Requirements []corev1beta1.NodeSelectorRequirementWithMinValues `json:"requirements"`
+	// RootDeviceName is the device name of the AMI's root volume
+	// +optional
+	RootDeviceName string `json:"rootDeviceName,omitempty"`
+	// RootSnapshotSize is the size of the snapshot backing the AMI's root volume. Instances can't be launched
+	// from the AMI with a smaller root volume.
+	// +optional
+	RootSnapshotSize *resource.Quantity `json:"rootSnapshotSize,omitempty"`
+	// DeprecationTime is when the AMI is deprecated, after which it's no longer returned to selectors by name or tags
+	// +optional
+	DeprecationTime *metav1.Time `json:"deprecationTime,omitempty"`
 }
 
 // EC2NodeClassStatus contains the resolved state of the EC2NodeClass
@@ -61,11 +133,75 @@ type EC2NodeClassStatus struct {
 	// cluster under the SecurityGroups selectors.
 	// +optional
 	SecurityGroups []SecurityGroup `json:"securityGroups,omitempty"`
+	// CapacityReservations contains the current, active Capacity Reservation values that are available to the
+	// cluster under the capacity reservation selectors.
+	// +optional
+	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty"`
 	// AMI contains the current AMI values that are available to the
 	// cluster under the AMI selectors.
 	// +optional
 	AMIs []AMI `json:"amis,omitempty"`
+	// PendingAMIs contains newly resolved AMI values that are being held by the AMI stabilization
+	// window before they replace AMIs.
+	// +optional
+	PendingAMIs []AMI `json:"pendingAMIs,omitempty"`
+	// PendingAMIsDetectionTime is the time at which PendingAMIs were first resolved
+	// +optional
+	PendingAMIsDetectionTime *metav1.Time `json:"pendingAMIsDetectionTime,omitempty"`
 	// InstanceProfile contains the resolved instance profile for the role
 	// +optional
 	InstanceProfile string `json:"instanceProfile,omitempty"`
+	// LastRefresh is the value of the karpenter.k8s.aws/refresh annotation that was last handled
+	// +optional
+	LastRefresh string `json:"lastRefresh,omitempty"`
+	// Conditions contains signals for health and readiness
+	// +optional
+	Conditions apis.Conditions `json:"conditions,omitempty"`
+}
+
+var (
+	// ConditionTypeSubnetsReady is set to false when no subnets are resolved, or when every resolved subnet has fewer
+	// free IP addresses than the subnet-free-ip-threshold
+	ConditionTypeSubnetsReady apis.ConditionType = "SubnetsReady"
+	// ConditionTypeAMIsReady is set to false when no AMIs are resolved, when an SSM parameter in the AMI selector
+	// terms is missing or doesn't contain an AMI ID, or when none of the resolved AMIs have an allowed owner
+	ConditionTypeAMIsReady apis.ConditionType = "AMIsReady"
+	// ConditionTypeSecurityGroupsReady is set to false when no security groups are resolved, when more security
+	// groups are resolved than can be attached to an instance's network interface, or when a resolved security group
+	// isn't in the VPC of the resolved subnets
+	ConditionTypeSecurityGroupsReady apis.ConditionType = "SecurityGroupsReady"
+	// ConditionTypeBlockDeviceTooSmall is set when the root volume in the block device mappings is smaller than the
+	// root snapshot of one of the resolved AMIs
+	ConditionTypeBlockDeviceTooSmall apis.ConditionType = "BlockDeviceTooSmall"
+	// ConditionTypePublicAMISearch is set when an AMI selector term sets the wildcard owner, so that its name or tags
+	// can match public AMIs published by any account
+	ConditionTypePublicAMISearch apis.ConditionType = "PublicAMISearch"
+	// ConditionTypeDisallowedAMIs is set when resolved AMIs are dropped because their owner isn't in the
+	// allowed-ami-owners
+	ConditionTypeDisallowedAMIs apis.ConditionType = "DisallowedAMIs"
+	// ConditionTypeAMIsDeprecating is set when one of the AMIs in the status is deprecated, or will be within the
+	// ami-deprecation-window
+	ConditionTypeAMIsDeprecating apis.ConditionType = "AMIsDeprecating"
+	// ConditionTypeInstanceProfileMismatch is set when the instance profile doesn't have the instance-profile-path, or
+	// its role doesn't have the instance-profile-permissions-boundary
+	ConditionTypeInstanceProfileMismatch apis.ConditionType = "InstanceProfileMismatch"
+	// ConditionTypeInterruptionQueueUnhealthy is set when a NodePool that allows spot uses the EC2NodeClass and the
+	// interruption-queue can't be reached
+	ConditionTypeInterruptionQueueUnhealthy apis.ConditionType = "InterruptionQueueUnhealthy"
+	// ConditionTypeInterruptionQueueReady is set to false when interruption-queue-required is enabled, a NodePool that
+	// allows spot uses the EC2NodeClass, and the interruption-queue isn't set or can't be reached
+	ConditionTypeInterruptionQueueReady apis.ConditionType = "InterruptionQueueReady"
+)
+
+func (in *EC2NodeClass) StatusConditions() apis.ConditionManager {
+	return apis.NewLivingConditionSet(ConditionTypeSubnetsReady, ConditionTypeAMIsReady, ConditionTypeSecurityGroupsReady,
+		ConditionTypeInterruptionQueueReady).Manage(in)
+}
+
+func (in *EC2NodeClass) GetConditions() apis.Conditions {
+	return in.Status.Conditions
+}
+
+func (in *EC2NodeClass) SetConditions(conditions apis.Conditions) {
+	in.Status.Conditions = conditions
 }
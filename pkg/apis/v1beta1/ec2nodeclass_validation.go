@@ -17,6 +17,7 @@ package v1beta1
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -26,15 +27,17 @@ import (
 )
 
 const (
-	subnetSelectorTermsPath        = "subnetSelectorTerms"
-	securityGroupSelectorTermsPath = "securityGroupSelectorTerms"
-	amiSelectorTermsPath           = "amiSelectorTerms"
-	amiFamilyPath                  = "amiFamily"
-	tagsPath                       = "tags"
-	metadataOptionsPath            = "metadataOptions"
-	blockDeviceMappingsPath        = "blockDeviceMappings"
-	rolePath                       = "role"
-	instanceProfilePath            = "instanceProfile"
+	subnetSelectorTermsPath              = "subnetSelectorTerms"
+	securityGroupSelectorTermsPath       = "securityGroupSelectorTerms"
+	capacityReservationSelectorTermsPath = "capacityReservationSelectorTerms"
+	amiSelectorTermsPath                 = "amiSelectorTerms"
+	amiFamilyPath                        = "amiFamily"
+	tagsPath                             = "tags"
+	metadataOptionsPath                  = "metadataOptions"
+	blockDeviceMappingsPath              = "blockDeviceMappings"
+	rolePath                             = "role"
+	instanceProfilePath                  = "instanceProfile"
+	onDemandOptionsPath                  = "onDemandOptions"
 )
 
 var (
@@ -76,11 +79,13 @@ func (in *EC2NodeClassSpec) validate(_ context.Context) (errs *apis.FieldError)
 	return errs.Also(
 		in.validateSubnetSelectorTerms().ViaField(subnetSelectorTermsPath),
 		in.validateSecurityGroupSelectorTerms().ViaField(securityGroupSelectorTermsPath),
+		in.validateCapacityReservationSelectorTerms().ViaField(capacityReservationSelectorTermsPath),
 		in.validateAMISelectorTerms().ViaField(amiSelectorTermsPath),
 		in.validateMetadataOptions().ViaField(metadataOptionsPath),
 		in.validateAMIFamily().ViaField(amiFamilyPath),
 		in.validateBlockDeviceMappings().ViaField(blockDeviceMappingsPath),
 		in.validateTags().ViaField(tagsPath),
+		in.validateOnDemandOptions().ViaField(onDemandOptionsPath),
 	)
 }
 
@@ -127,6 +132,23 @@ func (in *SecurityGroupSelectorTerm) validate() (errs *apis.FieldError) {
 	return errs
 }
 
+func (in *EC2NodeClassSpec) validateCapacityReservationSelectorTerms() (errs *apis.FieldError) {
+	for i, term := range in.CapacityReservationSelectorTerms {
+		errs = errs.Also(term.validate()).ViaIndex(i)
+	}
+	return errs
+}
+
+func (in *CapacityReservationSelectorTerm) validate() (errs *apis.FieldError) {
+	errs = errs.Also(validateTags(in.Tags).ViaField("tags"))
+	if len(in.Tags) == 0 && in.ID == "" {
+		errs = errs.Also(apis.ErrGeneric("expected at least one, got none", "tags", "id"))
+	} else if in.ID != "" && len(in.Tags) > 0 {
+		errs = errs.Also(apis.ErrGeneric(`"id" is mutually exclusive, cannot be set with a combination of other fields in`))
+	}
+	return errs
+}
+
 func (in *EC2NodeClassSpec) validateAMISelectorTerms() (errs *apis.FieldError) {
 	for _, term := range in.AMISelectorTerms {
 		errs = errs.Also(term.validate())
@@ -137,10 +159,12 @@ func (in *EC2NodeClassSpec) validateAMISelectorTerms() (errs *apis.FieldError) {
 //nolint:gocyclo
 func (in *AMISelectorTerm) validate() (errs *apis.FieldError) {
 	errs = errs.Also(validateTags(in.Tags).ViaField("tags"))
-	if len(in.Tags) == 0 && in.ID == "" && in.Name == "" {
-		errs = errs.Also(apis.ErrGeneric("expect at least one, got none", "tags", "id", "name"))
+	if len(in.Tags) == 0 && in.ID == "" && in.Name == "" && in.SSMParameter == "" {
+		errs = errs.Also(apis.ErrGeneric("expect at least one, got none", "tags", "id", "name", "ssmParameter"))
 	} else if in.ID != "" && (len(in.Tags) > 0 || in.Name != "" || in.Owner != "") {
 		errs = errs.Also(apis.ErrGeneric(`"id" is mutually exclusive, cannot be set with a combination of other fields in`))
+	} else if in.SSMParameter != "" && (len(in.Tags) > 0 || in.ID != "" || in.Name != "" || in.Owner != "") {
+		errs = errs.Also(apis.ErrGeneric(`"ssmParameter" is mutually exclusive, cannot be set with a combination of other fields in`))
 	}
 	return errs
 }
@@ -210,6 +234,19 @@ func (in *EC2NodeClassSpec) validateStringEnum(value, field string, validValues
 	return apis.ErrInvalidValue(fmt.Sprintf("%s not in %v", value, strings.Join(validValues, ", ")), field)
 }
 
+// validateOnDemandOptions rejects instance type priorities that aren't valid globs, since they would never match an instance type
+func (in *EC2NodeClassSpec) validateOnDemandOptions() (errs *apis.FieldError) {
+	if in.OnDemandOptions == nil {
+		return nil
+	}
+	for i, pattern := range in.OnDemandOptions.InstanceTypePriorities {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = errs.Also(apis.ErrInvalidArrayValue(fmt.Sprintf("%s is not a valid glob, %s", pattern, err), "instanceTypePriorities", i))
+		}
+	}
+	return errs
+}
+
 func (in *EC2NodeClassSpec) validateBlockDeviceMappings() (errs *apis.FieldError) {
 	numRootVolume := 0
 	for i, blockDeviceMapping := range in.BlockDeviceMappings {
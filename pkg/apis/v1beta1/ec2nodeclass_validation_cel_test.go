@@ -15,9 +15,12 @@ limitations under the License.
 package v1beta1_test
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
@@ -91,6 +94,18 @@ var _ = Describe("CEL/Validation", func() {
 				"karpenter.sh/nodeclaim": "test",
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"karpenter.sh/taints": "test",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"karpenter.sh/taints-truncated": "test",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				v1beta1.TagTerminationBehavior: "test",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
 		})
 	})
 	Context("SubnetSelectorTerms", func() {
@@ -191,6 +206,53 @@ var _ = Describe("CEL/Validation", func() {
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
 		})
 	})
+	Context("CapacityReservationSelectorTerms", func() {
+		It("should succeed with a valid capacity reservation selector on tags", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					Tags: map[string]string{
+						"test": "testvalue",
+					},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a valid capacity reservation selector on id", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					ID: "cr-12345749",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when a capacity reservation selector term has no values", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when a capacity reservation selector term has a tag map key that is empty", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					Tags: map[string]string{
+						"test": "",
+					},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when specifying id with tags", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					ID: "cr-12345749",
+					Tags: map[string]string{
+						"test": "testvalue",
+					},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
 	Context("SecurityGroupSelectorTerms", func() {
 		It("should succeed with a valid security group selector on tags", func() {
 			nc.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
@@ -218,6 +280,22 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Succeed())
 		})
+		It("should succeed with a security group selector on a wildcard name", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+				{
+					Name: "eksctl-my-cluster-nodegroup-*-SG",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when a security group selector term has an empty name", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+				{
+					Name: "",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 		It("should fail when security group selector terms is set to nil", func() {
 			nc.Spec.SecurityGroupSelectorTerms = nil
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
@@ -364,6 +442,23 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Succeed())
 		})
+		It("should succeed with a valid ami selector on ssmParameter", func() {
+			nc.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
+				{
+					SSMParameter: "/my-org/eks/al2023/ami-id",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when an ami selector term has ssmParameter with other fields", func() {
+			nc.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
+				{
+					SSMParameter: "/my-org/eks/al2023/ami-id",
+					Owner:        "self",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 		It("should fail when a ami selector term has no values", func() {
 			nc.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
 				{},
@@ -653,4 +748,240 @@ var _ = Describe("CEL/Validation", func() {
 			Expect(env.Client.Update(ctx, nc)).ToNot(Succeed())
 		})
 	})
+	Context("TerminationBehavior", func() {
+		It("should succeed when termination behavior is Terminate", func() {
+			nc.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehaviorTerminate)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed when termination behavior is Stop", func() {
+			nc.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehaviorStop)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when termination behavior is not a known value", func() {
+			nc.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehavior("Hibernate"))
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("OnDemandOptions", func() {
+		It("should succeed with the lowest-price allocation strategy", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{AllocationStrategy: lo.ToPtr("lowest-price")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with the prioritized allocation strategy and instance type priorities", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+				AllocationStrategy:     lo.ToPtr("prioritized"),
+				InstanceTypePriorities: []string{"m5.*", "c6i.2xlarge"},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with the prioritized allocation strategy and no instance type priorities", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{AllocationStrategy: lo.ToPtr("prioritized")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an unknown allocation strategy", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{AllocationStrategy: lo.ToPtr("capacity-optimized")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an empty instance type priority", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+				AllocationStrategy:     lo.ToPtr("prioritized"),
+				InstanceTypePriorities: []string{""},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("Tenancy", func() {
+		It("should succeed with dedicated tenancy", func() {
+			nc.Spec.Tenancy = lo.ToPtr("dedicated")
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unknown tenancy", func() {
+			nc.Spec.Tenancy = lo.ToPtr("shared")
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed with a host ID and host tenancy", func() {
+			nc.Spec.Tenancy = lo.ToPtr("host")
+			nc.Spec.HostPlacement = &v1beta1.HostPlacement{HostID: lo.ToPtr("h-0123456789abcdef0")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a host resource group and host tenancy", func() {
+			nc.Spec.Tenancy = lo.ToPtr("host")
+			nc.Spec.HostPlacement = &v1beta1.HostPlacement{HostResourceGroupARN: lo.ToPtr("arn:aws:resource-groups:us-west-2:123456789012:group/test")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with host placement and dedicated tenancy", func() {
+			nc.Spec.Tenancy = lo.ToPtr("dedicated")
+			nc.Spec.HostPlacement = &v1beta1.HostPlacement{HostID: lo.ToPtr("h-0123456789abcdef0")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with host placement and no tenancy", func() {
+			nc.Spec.HostPlacement = &v1beta1.HostPlacement{HostID: lo.ToPtr("h-0123456789abcdef0")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when specifying both a host ID and a host resource group", func() {
+			nc.Spec.Tenancy = lo.ToPtr("host")
+			nc.Spec.HostPlacement = &v1beta1.HostPlacement{
+				HostID:               lo.ToPtr("h-0123456789abcdef0"),
+				HostResourceGroupARN: lo.ToPtr("arn:aws:resource-groups:us-west-2:123456789012:group/test"),
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when host placement is empty", func() {
+			nc.Spec.Tenancy = lo.ToPtr("host")
+			nc.Spec.HostPlacement = &v1beta1.HostPlacement{}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("InstanceStore", func() {
+		It("should succeed with a mirrored ext4 array and the RAID0 instance store policy", func() {
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			nc.Spec.InstanceStore = &v1beta1.InstanceStore{
+				RAIDLevel:            lo.ToPtr(v1beta1.RAIDLevelRAID1),
+				Filesystem:           lo.ToPtr(v1beta1.InstanceStoreFilesystemExt4),
+				ReportUsableCapacity: lo.ToPtr(true),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail without an instance store policy", func() {
+			nc.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an unknown RAID level", func() {
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			nc.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevel("RAID5"))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an unknown filesystem", func() {
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			nc.Spec.InstanceStore = &v1beta1.InstanceStore{Filesystem: lo.ToPtr("btrfs")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed with the RAID10 instance store policy", func() {
+			nc.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID10)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with the RAID10 instance store policy and a Windows AMI family", func() {
+			nc.Spec.AMIFamily = &v1beta1.AMIFamilyWindows2022
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID10)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("BlockDeviceDefaults", func() {
+		It("should succeed with iops and throughput for gp3", func() {
+			nc.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{VolumeType: lo.ToPtr("gp3"), IOPS: lo.ToPtr(int64(4000)), Throughput: lo.ToPtr(int64(250))}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with iops for io2", func() {
+			nc.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{VolumeType: lo.ToPtr("io2"), IOPS: lo.ToPtr(int64(10000))}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with iops for gp2", func() {
+			nc.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{VolumeType: lo.ToPtr("gp2"), IOPS: lo.ToPtr(int64(4000))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with iops and no volume type", func() {
+			nc.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{IOPS: lo.ToPtr(int64(4000))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with throughput for io1", func() {
+			nc.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{VolumeType: lo.ToPtr("io1"), Throughput: lo.ToPtr(int64(250))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with throughput outside of the gp3 range", func() {
+			nc.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{VolumeType: lo.ToPtr("gp3"), Throughput: lo.ToPtr(int64(2000))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("OutpostARN", func() {
+		It("should succeed with an outpost ARN", func() {
+			nc.Spec.OutpostARN = lo.ToPtr("arn:aws:outposts:us-west-2:123456789012:outpost/op-0123456789abcdef0")
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an ARN that isn't an outpost's", func() {
+			nc.Spec.OutpostARN = lo.ToPtr("arn:aws:ec2:us-west-2:123456789012:subnet/subnet-0123456789abcdef0")
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("AMIStabilization", func() {
+		It("should succeed with a window and allowed hours", func() {
+			nc.Spec.AMIStabilization = &v1beta1.AMIStabilization{
+				Window:          metav1.Duration{Duration: 24 * time.Hour},
+				AllowedHoursUTC: []int32{0, 12, 23},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an allowed hour outside of the day", func() {
+			nc.Spec.AMIStabilization = &v1beta1.AMIStabilization{
+				Window:          metav1.Duration{Duration: time.Hour},
+				AllowedHoursUTC: []int32{24},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with a negative allowed hour", func() {
+			nc.Spec.AMIStabilization = &v1beta1.AMIStabilization{
+				Window:          metav1.Duration{Duration: time.Hour},
+				AllowedHoursUTC: []int32{-1},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("VMMemoryOverheadPercent", func() {
+		It("should succeed with a fraction between 0 and 1", func() {
+			nc.Spec.VMMemoryOverheadPercent = lo.ToPtr("0.075")
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with no overhead", func() {
+			nc.Spec.VMMemoryOverheadPercent = lo.ToPtr("0")
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with a negative overhead", func() {
+			nc.Spec.VMMemoryOverheadPercent = lo.ToPtr("-0.1")
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an overhead of 1 or more", func() {
+			nc.Spec.VMMemoryOverheadPercent = lo.ToPtr("1")
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an overhead that isn't a number", func() {
+			nc.Spec.VMMemoryOverheadPercent = lo.ToPtr("7.5%")
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("IPv6", func() {
+		It("should succeed with a prefix count", func() {
+			nc.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](1)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with an address count", func() {
+			nc.Spec.IPv6 = &v1beta1.IPv6{AddressCount: lo.ToPtr[int64](4)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with both a prefix count and an address count", func() {
+			nc.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](1), AddressCount: lo.ToPtr[int64](4)}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with a prefix count less than one", func() {
+			nc.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](0)}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an address count less than one", func() {
+			nc.Spec.IPv6 = &v1beta1.IPv6{AddressCount: lo.ToPtr[int64](0)}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("MaxPodsOverrides", func() {
+		It("should succeed with instance type globs", func() {
+			nc.Spec.MaxPodsOverrides = map[string]int32{"t3.*": 17, "m5.large": 29}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an empty instance type glob", func() {
+			nc.Spec.MaxPodsOverrides = map[string]int32{"": 17}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with a negative max pods", func() {
+			nc.Spec.MaxPodsOverrides = map[string]int32{"t3.*": -1}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
 })
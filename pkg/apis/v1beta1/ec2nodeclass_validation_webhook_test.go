@@ -103,6 +103,18 @@ var _ = Describe("Webhook/Validation", func() {
 				"karpenter.sh/nodeclaim": "test",
 			}
 			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"karpenter.sh/taints": "test",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"karpenter.sh/taints-truncated": "test",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				v1beta1.TagTerminationBehavior: "test",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
 		})
 	})
 	Context("SubnetSelectorTerms", func() {
@@ -203,6 +215,53 @@ var _ = Describe("Webhook/Validation", func() {
 			Expect(nc.Validate(ctx)).ToNot(Succeed())
 		})
 	})
+	Context("CapacityReservationSelectorTerms", func() {
+		It("should succeed with a valid capacity reservation selector on tags", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					Tags: map[string]string{
+						"test": "testvalue",
+					},
+				},
+			}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should succeed with a valid capacity reservation selector on id", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					ID: "cr-12345749",
+				},
+			}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should fail when a capacity reservation selector term has no values", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{},
+			}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail when a capacity reservation selector term has a tag map key that is empty", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					Tags: map[string]string{
+						"test": "",
+					},
+				},
+			}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail when specifying id with tags", func() {
+			nc.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+				{
+					ID: "cr-12345749",
+					Tags: map[string]string{
+						"test": "testvalue",
+					},
+				},
+			}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+	})
 	Context("SecurityGroupSelectorTerms", func() {
 		It("should succeed with a valid security group selector on tags", func() {
 			nc.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
@@ -376,6 +435,23 @@ var _ = Describe("Webhook/Validation", func() {
 			}
 			Expect(nc.Validate(ctx)).To(Succeed())
 		})
+		It("should succeed with a valid ami selector on ssmParameter", func() {
+			nc.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
+				{
+					SSMParameter: "/my-org/eks/al2023/ami-id",
+				},
+			}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should fail when an ami selector term has ssmParameter with other fields", func() {
+			nc.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
+				{
+					SSMParameter: "/my-org/eks/al2023/ami-id",
+					Name:         "testname",
+				},
+			}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
 		It("should fail when a ami selector term has no values", func() {
 			nc.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
 				{},
@@ -492,6 +568,29 @@ var _ = Describe("Webhook/Validation", func() {
 			Expect(nodeClass.Validate(ctx)).To(Not(Succeed()))
 		})
 	})
+	Context("OnDemandOptions", func() {
+		It("should succeed with valid instance type priorities", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+				AllocationStrategy:     aws.String("prioritized"),
+				InstanceTypePriorities: []string{"m5.*", "c[56]i.2xlarge", "r6?.large"},
+			}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should fail when an instance type priority isn't a valid glob", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+				AllocationStrategy:     aws.String("prioritized"),
+				InstanceTypePriorities: []string{"m5.*", "c[56i.2xlarge"},
+			}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail when an instance type priority ends in an escape", func() {
+			nc.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+				AllocationStrategy:     aws.String("prioritized"),
+				InstanceTypePriorities: []string{`m5.\`},
+			}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+	})
 	Context("Role Immutability", func() {
 		It("should fail when updating the role", func() {
 			nc.Spec.Role = "test-role"
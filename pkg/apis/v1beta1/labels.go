@@ -18,8 +18,10 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 )
@@ -33,11 +35,19 @@ func init() {
 		LabelInstanceFamily,
 		LabelInstanceGeneration,
 		LabelInstanceSize,
+		LabelInstanceBareMetal,
 		LabelInstanceLocalNVME,
+		LabelInstanceLocalNVMECount,
+		LabelInstanceLocalNVMEDiskSize,
 		LabelInstanceCPU,
 		LabelInstanceCPUManufacturer,
+		LabelInstanceCPUSustainedClockSpeedMhz,
+		LabelInstanceSMTSupported,
 		LabelInstanceMemory,
 		LabelInstanceNetworkBandwidth,
+		LabelInstanceNetworkCards,
+		LabelInstanceNetworkCardsBandwidth,
+		LabelInstanceEFANetworkCards,
 		LabelInstanceGPUName,
 		LabelInstanceGPUManufacturer,
 		LabelInstanceGPUCount,
@@ -45,12 +55,17 @@ func init() {
 		LabelInstanceAcceleratorName,
 		LabelInstanceAcceleratorManufacturer,
 		LabelInstanceAcceleratorCount,
+		LabelInstanceAcceleratorMemory,
+		LabelTopologyZoneID,
+		LabelInstanceStorePolicy,
 		v1.LabelWindowsBuild,
 	)
 }
 
 const (
 	TerminationFinalizer = Group + "/termination"
+	// CapacityTypeCapacityBlock is the karpenter.sh/capacity-type of instances launched into Capacity Blocks for ML
+	CapacityTypeCapacityBlock = "capacity-block"
 )
 
 var (
@@ -73,6 +88,9 @@ var (
 		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(v1beta1.ManagedByAnnotationKey))),
 		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(LabelNodeClass))),
 		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(TagNodeClaim))),
+		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(TagTaints))),
+		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(TagTaintsTruncated))),
+		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(TagTerminationBehavior))),
 	}
 	AMIFamilyBottlerocket                      = "Bottlerocket"
 	AMIFamilyAL2                               = "AL2"
@@ -80,12 +98,15 @@ var (
 	AMIFamilyUbuntu                            = "Ubuntu"
 	AMIFamilyWindows2019                       = "Windows2019"
 	AMIFamilyWindows2022                       = "Windows2022"
+	AMIFamilyWindows2025                       = "Windows2025"
 	AMIFamilyCustom                            = "Custom"
 	Windows2019                                = "2019"
 	Windows2022                                = "2022"
+	Windows2025                                = "2025"
 	WindowsCore                                = "Core"
 	Windows2019Build                           = "10.0.17763"
 	Windows2022Build                           = "10.0.20348"
+	Windows2025Build                           = "10.0.26100"
 	ResourceNVIDIAGPU          v1.ResourceName = "nvidia.com/gpu"
 	ResourceAMDGPU             v1.ResourceName = "amd.com/gpu"
 	ResourceAWSNeuron          v1.ResourceName = "aws.amazon.com/neuron"
@@ -93,20 +114,36 @@ var (
 	ResourceAWSPodENI          v1.ResourceName = "vpc.amazonaws.com/pod-eni"
 	ResourcePrivateIPv4Address v1.ResourceName = "vpc.amazonaws.com/PrivateIPv4Address"
 	ResourceEFA                v1.ResourceName = "vpc.amazonaws.com/efa"
+	ResourceNetworkBandwidth   v1.ResourceName = Group + "/network-bandwidth"
 
 	LabelNodeClass = Group + "/ec2nodeclass"
 
+	// LabelTopologyZoneID is the ID of the node's availability zone (e.g. use1-az1). Unlike zone names, zone IDs refer to
+	// the same physical location in every account.
+	LabelTopologyZoneID = "topology.k8s.aws/zone-id"
+	// LabelInstanceStorePolicy is the instance store policy that the node's instance store disks were assembled with.
+	// It isn't set on nodes whose EC2NodeClass has no policy, or whose AMI family doesn't apply it.
+	LabelInstanceStorePolicy = Group + "/instance-store-policy"
+
 	LabelInstanceHypervisor                   = Group + "/instance-hypervisor"
 	LabelInstanceEncryptionInTransitSupported = Group + "/instance-encryption-in-transit-supported"
 	LabelInstanceCategory                     = Group + "/instance-category"
 	LabelInstanceFamily                       = Group + "/instance-family"
 	LabelInstanceGeneration                   = Group + "/instance-generation"
 	LabelInstanceLocalNVME                    = Group + "/instance-local-nvme"
+	LabelInstanceLocalNVMECount               = Group + "/instance-local-nvme-count"
+	LabelInstanceLocalNVMEDiskSize            = Group + "/instance-local-nvme-disk-size"
 	LabelInstanceSize                         = Group + "/instance-size"
+	LabelInstanceBareMetal                    = Group + "/instance-bare-metal"
 	LabelInstanceCPU                          = Group + "/instance-cpu"
 	LabelInstanceCPUManufacturer              = Group + "/instance-cpu-manufacturer"
+	LabelInstanceCPUSustainedClockSpeedMhz    = Group + "/instance-cpu-sustained-clock-speed-mhz"
+	LabelInstanceSMTSupported                 = Group + "/instance-smt-supported"
 	LabelInstanceMemory                       = Group + "/instance-memory"
 	LabelInstanceNetworkBandwidth             = Group + "/instance-network-bandwidth"
+	LabelInstanceNetworkCards                 = Group + "/instance-network-cards"
+	LabelInstanceNetworkCardsBandwidth        = Group + "/instance-network-cards-bandwidth"
+	LabelInstanceEFANetworkCards              = Group + "/instance-efa-network-cards"
 	LabelInstanceGPUName                      = Group + "/instance-gpu-name"
 	LabelInstanceGPUManufacturer              = Group + "/instance-gpu-manufacturer"
 	LabelInstanceGPUCount                     = Group + "/instance-gpu-count"
@@ -114,10 +151,151 @@ var (
 	LabelInstanceAcceleratorName              = Group + "/instance-accelerator-name"
 	LabelInstanceAcceleratorManufacturer      = Group + "/instance-accelerator-manufacturer"
 	LabelInstanceAcceleratorCount             = Group + "/instance-accelerator-count"
+	LabelInstanceAcceleratorMemory            = Group + "/instance-accelerator-memory"
 	AnnotationEC2NodeClassHash                = Group + "/ec2nodeclass-hash"
 	AnnotationEC2NodeClassHashVersion         = Group + "/ec2nodeclass-hash-version"
-	AnnotationInstanceTagged                  = Group + "/tagged"
+	// AnnotationEC2NodeClassSubnetsHash and AnnotationEC2NodeClassSecurityGroupsHash record the hashes of the subnet and
+	// security group IDs that an EC2NodeClass resolves to, which are folded into its drift hash.
+	AnnotationEC2NodeClassSubnetsHash        = Group + "/ec2nodeclass-subnets-hash"
+	AnnotationEC2NodeClassSecurityGroupsHash = Group + "/ec2nodeclass-securitygroups-hash"
+	AnnotationInstanceTagged                 = Group + "/tagged"
+	// AnnotationClusterAutoscalerMirrored records the annotations of a Node that were mirrored to or from their
+	// cluster-autoscaler equivalents, so that they're removed when the annotation they were mirrored from is.
+	AnnotationClusterAutoscalerMirrored = Group + "/cluster-autoscaler-mirrored"
+	// AnnotationFleetID, AnnotationLaunchTemplateName and AnnotationLaunchTemplateVersion record the CreateFleet request
+	// and launch template that a NodeClaim's instance was launched with, and AnnotationSpotInstanceRequestID records the
+	// spot instance request of a spot instance. They're set once, so that they can be joined against billing and usage
+	// reports for as long as the NodeClaim exists.
+	AnnotationFleetID               = Group + "/fleet-id"
+	AnnotationLaunchTemplateName    = Group + "/launch-template-name"
+	AnnotationLaunchTemplateVersion = Group + "/launch-template-version"
+	AnnotationSpotInstanceRequestID = Group + "/spot-instance-request-id"
+	// AnnotationForceAMIAdoption, when set to "true" on an EC2NodeClass, adopts newly resolved AMIs immediately rather
+	// than holding them for the EC2NodeClass's AMI stabilization window.
+	AnnotationForceAMIAdoption = Group + "/force-ami-adoption"
+	// AnnotationRefresh, when set on an EC2NodeClass to a value that differs from its status.lastRefresh, drops the
+	// cached instance types, offerings, subnets and security groups and refetches pricing.
+	AnnotationRefresh = Group + "/refresh"
+	// AnnotationMigrationNodeClass and AnnotationMigrationPercentage, when set on a NodePool, launch the given percentage
+	// (0-100) of its new NodeClaims from the named EC2NodeClass rather than the one that its template references.
+	// AnnotationLaunchedNodeClass records the EC2NodeClass that a NodeClaim was launched from.
+	AnnotationMigrationNodeClass  = Group + "/migration-ec2nodeclass"
+	AnnotationMigrationPercentage = Group + "/migration-percentage"
+	AnnotationLaunchedNodeClass   = Group + "/launched-ec2nodeclass"
+	// AnnotationInstanceProfile records the instance profile that a NodeClaim's instance was launched with, so that a
+	// shared instance profile isn't deleted while instances still use it.
+	AnnotationInstanceProfile = Group + "/instance-profile"
+	// AnnotationSharedInstanceProfile records the shared instance profile that an EC2NodeClass last used, so that the
+	// shared instance profile of its previous role is deleted once its role changes and nothing else uses it.
+	AnnotationSharedInstanceProfile = Group + "/shared-instance-profile"
+	// AnnotationEphemeralStorageModel records whether the ephemeral-storage overhead of a NodeClaim was computed against
+	// the instance store array ("instance-store") or the EBS volume ("ebs") that its ephemeral storage is on.
+	AnnotationEphemeralStorageModel = Group + "/ephemeral-storage-model"
+	// AnnotationLimitHourlyCost, when set on a NodePool, limits the hourly cost in dollars of its running instances.
+	// Launches that would exceed the limit are rejected.
+	AnnotationLimitHourlyCost = Group + "/limit-hourly-cost"
 
 	TagNodeClaim = v1beta1.Group + "/nodeclaim"
 	TagName      = "Name"
+	// TagTaints holds the NodeClaim's taints serialized as a comma-separated list of key=value:Effect entries.
+	// TagTaintsTruncated is "true" when taints were dropped from TagTaints to fit within the tag value length limit.
+	TagTaints          = v1beta1.Group + "/taints"
+	TagTaintsTruncated = v1beta1.Group + "/taints-truncated"
+	// TagTerminationBehavior is set on instances that Karpenter stopped rather than terminated. Karpenter no longer
+	// considers these instances when listing or getting instances.
+	TagTerminationBehavior = Group + "/termination-behavior"
+	// TagCreatedBy marks EBS snapshots and images that were created for the cluster by tooling that opts them into
+	// snapshot garbage collection. Only resources carrying this tag are ever considered for snapshot garbage collection.
+	TagCreatedBy = Group + "/created-by"
 )
+
+// gatedMinorReleases is the number of minor releases that a gated label is only emitted when enabled for
+const gatedMinorReleases = 2
+
+// InstanceTypeLabel describes a label that instance types are labeled with from their EC2 instance type info.
+// Strict admission policies and older core versions can reject NodeClaims with requirement keys that they don't
+// recognize, so new labels are registered as gated and are only emitted when they're listed in enabled-labels. A
+// gated label is emitted by default once it has been released for two minor releases. Any label can be turned off
+// with disabled-labels.
+type InstanceTypeLabel struct {
+	Key string
+	// Since is the release that the label was first emitted in. Labels that predate the registry are recorded as
+	// v0.36.0.
+	Since string
+	Gated bool
+	// Required labels are relied on when launching instances, like to pick between AMI variants, so they're always
+	// emitted and can't be disabled
+	Required bool
+}
+
+// Emitted returns whether instance types are labeled with the label by the running release, given the labels that
+// are explicitly enabled and disabled
+func (l InstanceTypeLabel) Emitted(release string, enabled, disabled []string) bool {
+	if l.Required {
+		return true
+	}
+	if lo.Contains(disabled, l.Key) {
+		return false
+	}
+	return !l.Gated || l.Promoted(release) || lo.Contains(enabled, l.Key)
+}
+
+// Promoted returns whether the label has been released for long enough by the running release that it's emitted by
+// default even if it's gated. Releases that aren't versioned, like development builds, never promote labels.
+func (l InstanceTypeLabel) Promoted(release string) bool {
+	current, err := version.ParseGeneric(release)
+	if err != nil {
+		return false
+	}
+	since := version.MustParseGeneric(l.Since)
+	return current.AtLeast(version.MajorMinor(since.Major(), since.Minor()+gatedMinorReleases))
+}
+
+// InstanceTypeLabels is the registry of labels that instance types are labeled with from their EC2 instance type
+// info. New labels must be added here to be emitted.
+var InstanceTypeLabels = []InstanceTypeLabel{
+	{Key: LabelInstanceHypervisor, Since: "v0.36.0"},
+	{Key: LabelInstanceEncryptionInTransitSupported, Since: "v0.36.0"},
+	{Key: LabelInstanceCategory, Since: "v0.36.0"},
+	{Key: LabelInstanceFamily, Since: "v0.36.0"},
+	{Key: LabelInstanceGeneration, Since: "v0.36.0"},
+	{Key: LabelInstanceSize, Since: "v0.36.0"},
+	{Key: LabelInstanceLocalNVME, Since: "v0.36.0"},
+	{Key: LabelInstanceCPU, Since: "v0.36.0"},
+	{Key: LabelInstanceCPUManufacturer, Since: "v0.36.0"},
+	{Key: LabelInstanceMemory, Since: "v0.36.0", Required: true},
+	{Key: LabelInstanceNetworkBandwidth, Since: "v0.36.0"},
+	{Key: LabelInstanceGPUName, Since: "v0.36.0"},
+	{Key: LabelInstanceGPUManufacturer, Since: "v0.36.0"},
+	{Key: LabelInstanceGPUCount, Since: "v0.36.0", Required: true},
+	{Key: LabelInstanceGPUMemory, Since: "v0.36.0"},
+	{Key: LabelInstanceAcceleratorName, Since: "v0.36.0"},
+	{Key: LabelInstanceAcceleratorManufacturer, Since: "v0.36.0"},
+	{Key: LabelInstanceAcceleratorCount, Since: "v0.36.0", Required: true},
+	{Key: LabelInstanceAcceleratorMemory, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceBareMetal, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceLocalNVMECount, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceLocalNVMEDiskSize, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceCPUSustainedClockSpeedMhz, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceSMTSupported, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceNetworkCards, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceNetworkCardsBandwidth, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceEFANetworkCards, Since: "v0.37.0", Gated: true},
+	{Key: LabelTopologyZoneID, Since: "v0.37.0", Gated: true},
+	{Key: LabelInstanceStorePolicy, Since: "v0.37.0", Gated: true},
+}
+
+// EmittedInstanceTypeLabels returns the keys of the registered labels that instance types are labeled with by the
+// running release, given the labels that are explicitly enabled and disabled
+func EmittedInstanceTypeLabels(release string, enabled, disabled []string) []string {
+	return lo.FilterMap(InstanceTypeLabels, func(l InstanceTypeLabel, _ int) (string, bool) {
+		return l.Key, l.Emitted(release, enabled, disabled)
+	})
+}
+
+// InstanceTypeLabelEmitted returns whether instance types are labeled with the label by the running release, given the
+// labels that are explicitly enabled and disabled. Labels that aren't registered are always emitted.
+func InstanceTypeLabelEmitted(key, release string, enabled, disabled []string) bool {
+	label, ok := lo.Find(InstanceTypeLabels, func(l InstanceTypeLabel) bool { return l.Key == key })
+	return !ok || label.Emitted(release, enabled, disabled)
+}
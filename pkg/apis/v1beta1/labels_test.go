@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1_test
+
+import (
+	"github.com/samber/lo"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InstanceTypeLabels", func() {
+	It("should only register well known labels once", func() {
+		keys := lo.Map(v1beta1.InstanceTypeLabels, func(l v1beta1.InstanceTypeLabel, _ int) string { return l.Key })
+		Expect(keys).To(HaveLen(len(lo.Uniq(keys))))
+		for _, key := range keys {
+			Expect(corev1beta1.WellKnownLabels.Has(key)).To(BeTrue(), key)
+		}
+	})
+	It("should record the release that each label was introduced in", func() {
+		for _, label := range v1beta1.InstanceTypeLabels {
+			Expect(label.Since).To(MatchRegexp(`^v[0-9]+\.[0-9]+\.[0-9]+$`), label.Key)
+		}
+	})
+	It("should emit every label that isn't gated by default", func() {
+		Expect(v1beta1.EmittedInstanceTypeLabels("", nil, nil)).To(ConsistOf(lo.FilterMap(v1beta1.InstanceTypeLabels, func(l v1beta1.InstanceTypeLabel, _ int) (string, bool) {
+			return l.Key, !l.Gated
+		})))
+	})
+	It("should gate the labels introduced since the registry was added", func() {
+		for _, label := range v1beta1.InstanceTypeLabels {
+			Expect(label.Gated).To(Equal(label.Since != "v0.36.0"), label.Key)
+		}
+		Expect(v1beta1.EmittedInstanceTypeLabels("", nil, nil)).ToNot(ContainElements(v1beta1.LabelTopologyZoneID, v1beta1.LabelInstanceStorePolicy, v1beta1.LabelInstanceBareMetal))
+	})
+	It("should not emit disabled labels", func() {
+		emitted := v1beta1.EmittedInstanceTypeLabels("", nil, []string{v1beta1.LabelInstanceHypervisor})
+		Expect(emitted).ToNot(ContainElement(v1beta1.LabelInstanceHypervisor))
+		Expect(emitted).To(HaveLen(len(v1beta1.EmittedInstanceTypeLabels("", nil, nil)) - 1))
+	})
+	It("should only emit gated labels once they're enabled", func() {
+		label := v1beta1.InstanceTypeLabel{Key: v1beta1.Group + "/instance-flex", Since: "v0.37.0", Gated: true}
+		Expect(label.Emitted("0.37.0", nil, nil)).To(BeFalse())
+		Expect(label.Emitted("0.37.0", []string{label.Key}, nil)).To(BeTrue())
+		Expect(label.Emitted("0.37.0", []string{label.Key}, []string{label.Key})).To(BeFalse())
+	})
+	DescribeTable("should emit gated labels by default two minor releases after they were introduced",
+		func(release string, promoted bool) {
+			label := v1beta1.InstanceTypeLabel{Key: v1beta1.Group + "/instance-flex", Since: "v0.37.0", Gated: true}
+			Expect(label.Promoted(release)).To(Equal(promoted))
+			Expect(label.Emitted(release, nil, nil)).To(Equal(promoted))
+			Expect(label.Emitted(release, nil, []string{label.Key})).To(BeFalse())
+		},
+		Entry("the release it was introduced in", "0.37.0", false),
+		Entry("a patch of the next minor release", "v0.38.4", false),
+		Entry("two minor releases later", "0.39.0", true),
+		Entry("a build after two minor releases", "0.39.0-12-g1a2b3c4", true),
+		Entry("a later major release", "1.0.0", true),
+		Entry("a development build", "unspecified", false),
+		Entry("an unversioned build", "", false),
+	)
+	It("should always emit required labels", func() {
+		label := v1beta1.InstanceTypeLabel{Key: v1beta1.LabelInstanceGPUCount, Since: "v0.36.0", Required: true}
+		Expect(label.Emitted("", nil, []string{label.Key})).To(BeTrue())
+	})
+	It("should emit labels that aren't registered", func() {
+		Expect(v1beta1.InstanceTypeLabelEmitted("node.kubernetes.io/instance-type", "", nil, nil)).To(BeTrue())
+		Expect(v1beta1.InstanceTypeLabelEmitted(v1beta1.LabelTopologyZoneID, "", nil, nil)).To(BeFalse())
+		Expect(v1beta1.InstanceTypeLabelEmitted(v1beta1.LabelTopologyZoneID, "", []string{v1beta1.LabelTopologyZoneID}, nil)).To(BeTrue())
+	})
+})
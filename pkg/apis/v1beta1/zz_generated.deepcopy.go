@@ -20,6 +20,7 @@ package v1beta1
 
 import (
 	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/pkg/apis"
 	apisv1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 )
 
@@ -33,6 +34,15 @@ func (in *AMI) DeepCopyInto(out *AMI) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RootSnapshotSize != nil {
+		in, out := &in.RootSnapshotSize, &out.RootSnapshotSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DeprecationTime != nil {
+		in, out := &in.DeprecationTime, &out.DeprecationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMI.
@@ -67,6 +77,27 @@ func (in *AMISelectorTerm) DeepCopy() *AMISelectorTerm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AMIStabilization) DeepCopyInto(out *AMIStabilization) {
+	*out = *in
+	out.Window = in.Window
+	if in.AllowedHoursUTC != nil {
+		in, out := &in.AllowedHoursUTC, &out.AllowedHoursUTC
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMIStabilization.
+func (in *AMIStabilization) DeepCopy() *AMIStabilization {
+	if in == nil {
+		return nil
+	}
+	out := new(AMIStabilization)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlockDevice) DeepCopyInto(out *BlockDevice) {
 	*out = *in
@@ -122,6 +153,46 @@ func (in *BlockDevice) DeepCopy() *BlockDevice {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockDeviceDefaults) DeepCopyInto(out *BlockDeviceDefaults) {
+	*out = *in
+	if in.VolumeType != nil {
+		in, out := &in.VolumeType, &out.VolumeType
+		*out = new(string)
+		**out = **in
+	}
+	if in.IOPS != nil {
+		in, out := &in.IOPS, &out.IOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Throughput != nil {
+		in, out := &in.Throughput, &out.Throughput
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Encrypted != nil {
+		in, out := &in.Encrypted, &out.Encrypted
+		*out = new(bool)
+		**out = **in
+	}
+	if in.KMSKeyID != nil {
+		in, out := &in.KMSKeyID, &out.KMSKeyID
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockDeviceDefaults.
+func (in *BlockDeviceDefaults) DeepCopy() *BlockDeviceDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockDeviceDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlockDeviceMapping) DeepCopyInto(out *BlockDeviceMapping) {
 	*out = *in
@@ -147,6 +218,43 @@ func (in *BlockDeviceMapping) DeepCopy() *BlockDeviceMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
+func (in *CapacityReservation) DeepCopy() *CapacityReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservationSelectorTerm) DeepCopyInto(out *CapacityReservationSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservationSelectorTerm.
+func (in *CapacityReservationSelectorTerm) DeepCopy() *CapacityReservationSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservationSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EC2NodeClass) DeepCopyInto(out *EC2NodeClass) {
 	*out = *in
@@ -228,6 +336,11 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.IPv6 != nil {
+		in, out := &in.IPv6, &out.IPv6
+		*out = new(IPv6)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AMISelectorTerms != nil {
 		in, out := &in.AMISelectorTerms, &out.AMISelectorTerms
 		*out = make([]AMISelectorTerm, len(*in))
@@ -268,11 +381,38 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			}
 		}
 	}
+	if in.BlockDeviceDefaults != nil {
+		in, out := &in.BlockDeviceDefaults, &out.BlockDeviceDefaults
+		*out = new(BlockDeviceDefaults)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.InstanceStorePolicy != nil {
 		in, out := &in.InstanceStorePolicy, &out.InstanceStorePolicy
 		*out = new(InstanceStorePolicy)
 		**out = **in
 	}
+	if in.InstanceStore != nil {
+		in, out := &in.InstanceStore, &out.InstanceStore
+		*out = new(InstanceStore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VMMemoryOverheadPercent != nil {
+		in, out := &in.VMMemoryOverheadPercent, &out.VMMemoryOverheadPercent
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxPodsOverrides != nil {
+		in, out := &in.MaxPodsOverrides, &out.MaxPodsOverrides
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ReservedENIs != nil {
+		in, out := &in.ReservedENIs, &out.ReservedENIs
+		*out = new(int32)
+		**out = **in
+	}
 	if in.DetailedMonitoring != nil {
 		in, out := &in.DetailedMonitoring, &out.DetailedMonitoring
 		*out = new(bool)
@@ -288,6 +428,53 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.OutpostARN != nil {
+		in, out := &in.OutpostARN, &out.OutpostARN
+		*out = new(string)
+		**out = **in
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(Placement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenancy != nil {
+		in, out := &in.Tenancy, &out.Tenancy
+		*out = new(string)
+		**out = **in
+	}
+	if in.HostPlacement != nil {
+		in, out := &in.HostPlacement, &out.HostPlacement
+		*out = new(HostPlacement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityReservationSelectorTerms != nil {
+		in, out := &in.CapacityReservationSelectorTerms, &out.CapacityReservationSelectorTerms
+		*out = make([]CapacityReservationSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TerminationBehavior != nil {
+		in, out := &in.TerminationBehavior, &out.TerminationBehavior
+		*out = new(TerminationBehavior)
+		**out = **in
+	}
+	if in.OnDemandOptions != nil {
+		in, out := &in.OnDemandOptions, &out.OnDemandOptions
+		*out = new(OnDemandOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SubnetSelectionPolicy != nil {
+		in, out := &in.SubnetSelectionPolicy, &out.SubnetSelectionPolicy
+		*out = new(SubnetSelectionPolicy)
+		**out = **in
+	}
+	if in.AMIStabilization != nil {
+		in, out := &in.AMIStabilization, &out.AMIStabilization
+		*out = new(AMIStabilization)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EC2NodeClassSpec.
@@ -313,6 +500,11 @@ func (in *EC2NodeClassStatus) DeepCopyInto(out *EC2NodeClassStatus) {
 		*out = make([]SecurityGroup, len(*in))
 		copy(*out, *in)
 	}
+	if in.CapacityReservations != nil {
+		in, out := &in.CapacityReservations, &out.CapacityReservations
+		*out = make([]CapacityReservation, len(*in))
+		copy(*out, *in)
+	}
 	if in.AMIs != nil {
 		in, out := &in.AMIs, &out.AMIs
 		*out = make([]AMI, len(*in))
@@ -320,6 +512,24 @@ func (in *EC2NodeClassStatus) DeepCopyInto(out *EC2NodeClassStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PendingAMIs != nil {
+		in, out := &in.PendingAMIs, &out.PendingAMIs
+		*out = make([]AMI, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingAMIsDetectionTime != nil {
+		in, out := &in.PendingAMIsDetectionTime, &out.PendingAMIsDetectionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apis.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EC2NodeClassStatus.
@@ -332,6 +542,86 @@ func (in *EC2NodeClassStatus) DeepCopy() *EC2NodeClassStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPlacement) DeepCopyInto(out *HostPlacement) {
+	*out = *in
+	if in.HostID != nil {
+		in, out := &in.HostID, &out.HostID
+		*out = new(string)
+		**out = **in
+	}
+	if in.HostResourceGroupARN != nil {
+		in, out := &in.HostResourceGroupARN, &out.HostResourceGroupARN
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPlacement.
+func (in *HostPlacement) DeepCopy() *HostPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPv6) DeepCopyInto(out *IPv6) {
+	*out = *in
+	if in.PrefixCount != nil {
+		in, out := &in.PrefixCount, &out.PrefixCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.AddressCount != nil {
+		in, out := &in.AddressCount, &out.AddressCount
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPv6.
+func (in *IPv6) DeepCopy() *IPv6 {
+	if in == nil {
+		return nil
+	}
+	out := new(IPv6)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceStore) DeepCopyInto(out *InstanceStore) {
+	*out = *in
+	if in.RAIDLevel != nil {
+		in, out := &in.RAIDLevel, &out.RAIDLevel
+		*out = new(RAIDLevel)
+		**out = **in
+	}
+	if in.Filesystem != nil {
+		in, out := &in.Filesystem, &out.Filesystem
+		*out = new(string)
+		**out = **in
+	}
+	if in.ReportUsableCapacity != nil {
+		in, out := &in.ReportUsableCapacity, &out.ReportUsableCapacity
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStore.
+func (in *InstanceStore) DeepCopy() *InstanceStore {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataOptions) DeepCopyInto(out *MetadataOptions) {
 	*out = *in
@@ -367,6 +657,51 @@ func (in *MetadataOptions) DeepCopy() *MetadataOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnDemandOptions) DeepCopyInto(out *OnDemandOptions) {
+	*out = *in
+	if in.AllocationStrategy != nil {
+		in, out := &in.AllocationStrategy, &out.AllocationStrategy
+		*out = new(string)
+		**out = **in
+	}
+	if in.InstanceTypePriorities != nil {
+		in, out := &in.InstanceTypePriorities, &out.InstanceTypePriorities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnDemandOptions.
+func (in *OnDemandOptions) DeepCopy() *OnDemandOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(OnDemandOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Placement) DeepCopyInto(out *Placement) {
+	*out = *in
+	if in.PartitionNumber != nil {
+		in, out := &in.PartitionNumber, &out.PartitionNumber
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Placement.
+func (in *Placement) DeepCopy() *Placement {
+	if in == nil {
+		return nil
+	}
+	out := new(Placement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroup) DeepCopyInto(out *SecurityGroup) {
 	*out = *in
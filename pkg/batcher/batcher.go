@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mitchellh/hashstructure/v2"
@@ -183,10 +184,24 @@ func (b *Batcher[T, U]) waitForIdle() {
 }
 
 func (b *Batcher[T, U]) runCalls(requests []*request[T, U]) {
+	// Requests that were canceled while waiting for the batch are dropped rather than executed on behalf of a caller
+	// that has gone away
+	requests = lo.Filter(requests, func(req *request[T, U], _ int) bool {
+		if err := req.ctx.Err(); err != nil {
+			req.requestor <- Result[U]{Err: err}
+			return false
+		}
+		return true
+	})
+	if len(requests) == 0 {
+		return
+	}
 	// Measure the size of the request batch
 	batchSize.With(prometheus.Labels{batcherNameLabel: b.options.Name}).Observe(float64(len(requests)))
+	ctx, cancel := batchContext(requests)
+	defer cancel()
 	requestIdx := 0
-	for _, result := range b.options.BatchExecutor(requests[0].ctx, lo.Map(requests, func(req *request[T, U], _ int) *T { return req.input })) {
+	for _, result := range b.options.BatchExecutor(ctx, lo.Map(requests, func(req *request[T, U], _ int) *T { return req.input })) {
 		requests[requestIdx].requestor <- result
 		requestIdx++
 	}
@@ -195,3 +210,24 @@ func (b *Batcher[T, U]) runCalls(requests []*request[T, U]) {
 		requests[requestIdx].requestor <- Result[U]{Err: fmt.Errorf("error making call")}
 	}
 }
+
+// batchContext returns a context carrying the values of the first request that is only canceled once every request in
+// the batch has been canceled. This prevents a single caller going away from failing the call for the rest of the batch.
+func batchContext[T input, U output](requests []*request[T, U]) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(requests[0].ctx))
+	remaining := atomic.Int64{}
+	remaining.Store(int64(len(requests)))
+	stops := lo.Map(requests, func(req *request[T, U], _ int) func() bool {
+		return context.AfterFunc(req.ctx, func() {
+			if remaining.Add(-1) == 0 {
+				cancel()
+			}
+		})
+	})
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
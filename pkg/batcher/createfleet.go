@@ -30,6 +30,8 @@ type CreateFleetBatcher struct {
 }
 
 func NewCreateFleetBatcher(ctx context.Context, ec2api ec2iface.EC2API) *CreateFleetBatcher {
+	// Requests are hashed in full, so launches carrying their own client token and NodeClaim tag are never batched
+	// together. A batch can't be retried idempotently with the token of any one of its requests.
 	options := Options[ec2.CreateFleetInput, ec2.CreateFleetOutput]{
 		Name:          "create_fleet",
 		IdleTimeout:   35 * time.Millisecond,
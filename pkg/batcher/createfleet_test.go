@@ -15,6 +15,8 @@ limitations under the License.
 package batcher_test
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,6 +24,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/batcher"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -81,6 +84,85 @@ var _ = Describe("CreateFleet Batching", func() {
 		call := fakeEC2API.CreateFleetBehavior.CalledWithInput.Pop()
 		Expect(*call.TargetCapacitySpecification.TotalTargetCapacity).To(BeNumerically("==", 5))
 	})
+	It("should not batch inputs that carry different client tokens", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				nodeClaimName := fmt.Sprintf("nodeclaim-%d", i)
+				rsp, err := cfb.CreateFleet(ctx, &ec2.CreateFleetInput{
+					ClientToken: aws.String(fmt.Sprintf("%s-0", nodeClaimName)),
+					LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+						{
+							LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+								LaunchTemplateName: aws.String("my-template"),
+							},
+							Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+								{
+									AvailabilityZone: aws.String("us-east-1"),
+								},
+							},
+						},
+					},
+					TagSpecifications: []*ec2.TagSpecification{
+						{
+							ResourceType: aws.String(ec2.ResourceTypeInstance),
+							Tags: []*ec2.Tag{
+								{Key: aws.String("karpenter.sh/nodepool"), Value: aws.String("default")},
+								{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String(nodeClaimName)},
+							},
+						},
+					},
+					TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
+						TotalTargetCapacity: aws.Int64(1),
+					},
+				})
+				Expect(err).To(BeNil())
+				Expect(rsp.Instances).To(HaveLen(1))
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(fakeEC2API.CreateFleetBehavior.CalledWithInput.Len()).To(BeNumerically("==", 5))
+		fakeEC2API.CreateFleetBehavior.CalledWithInput.ForEach(func(input *ec2.CreateFleetInput) {
+			Expect(*input.TargetCapacitySpecification.TotalTargetCapacity).To(BeNumerically("==", 1))
+			Expect(input.ClientToken).ToNot(BeNil())
+			Expect(input.TagSpecifications[0].Tags).To(ContainElement(&ec2.Tag{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String(strings.TrimSuffix(aws.StringValue(input.ClientToken), "-0"))}))
+		})
+	})
+	It("should send a single input unchanged", func() {
+		input := &ec2.CreateFleetInput{
+			ClientToken: aws.String("nodeclaim-0"),
+			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+				{
+					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+						LaunchTemplateName: aws.String("my-template"),
+					},
+					Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+						{
+							AvailabilityZone: aws.String("us-east-1"),
+						},
+					},
+				},
+			},
+			TagSpecifications: []*ec2.TagSpecification{
+				{
+					ResourceType: aws.String(ec2.ResourceTypeInstance),
+					Tags:         []*ec2.Tag{{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String("nodeclaim")}},
+				},
+			},
+			TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: aws.Int64(1),
+			},
+		}
+		_, err := cfb.CreateFleet(ctx, input)
+		Expect(err).To(BeNil())
+
+		Expect(fakeEC2API.CreateFleetBehavior.CalledWithInput.Len()).To(BeNumerically("==", 1))
+		Expect(fakeEC2API.CreateFleetBehavior.CalledWithInput.Pop()).To(Equal(input))
+	})
 	It("should batch different inputs into multiple calls", func() {
 		east1input := &ec2.CreateFleetInput{
 			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
@@ -89,6 +89,84 @@ var _ = Describe("Batcher", func() {
 			Eventually(fakeBatcher.completedBatches.Load, time.Second*3).Should(BeNumerically("==", 300))
 		})
 	})
+	Context("Cancellation", func() {
+		var executed chan []*string
+		var batchCtxs chan context.Context
+		var release chan struct{}
+		var b *batcher.Batcher[string, string]
+
+		BeforeEach(func() {
+			executed = make(chan []*string, 10)
+			batchCtxs = make(chan context.Context, 10)
+			release = make(chan struct{})
+			b = batcher.NewBatcher(cancelCtx, batcher.Options[string, string]{
+				Name:              "cancellation",
+				IdleTimeout:       100 * time.Millisecond,
+				MaxTimeout:        1 * time.Second,
+				MaxRequestWorkers: 10,
+				RequestHasher:     batcher.OneBucketHasher[string],
+				BatchExecutor: func(ctx context.Context, items []*string) []batcher.Result[string] {
+					executed <- items
+					batchCtxs <- ctx
+					select {
+					case <-ctx.Done():
+					case <-release:
+					}
+					return lo.Map(items, func(i *string, _ int) batcher.Result[string] {
+						return batcher.Result[string]{Output: i, Err: ctx.Err()}
+					})
+				},
+			})
+		})
+		It("should answer requests that are canceled before the batch runs without executing them", func() {
+			canceledCtx, cancelRequest := context.WithCancel(ctx)
+			cancelRequest()
+			results := make(chan batcher.Result[string], 2)
+			go func() { results <- b.Add(canceledCtx, lo.ToPtr("canceled")) }()
+			go func() { results <- b.Add(ctx, lo.ToPtr("live")) }()
+
+			var items []*string
+			Eventually(executed).Should(Receive(&items))
+			Expect(items).To(HaveLen(1))
+			Expect(*items[0]).To(Equal("live"))
+			close(release)
+
+			var first, second batcher.Result[string]
+			Eventually(results).Should(Receive(&first))
+			Eventually(results).Should(Receive(&second))
+			Expect([]error{first.Err, second.Err}).To(ConsistOf(MatchError(context.Canceled), BeNil()))
+		})
+		It("should not cancel the batch when a single caller is canceled", func() {
+			requestCtx, cancelRequest := context.WithCancel(ctx)
+			results := make(chan batcher.Result[string], 2)
+			go func() { results <- b.Add(requestCtx, lo.ToPtr("a")) }()
+			go func() { results <- b.Add(ctx, lo.ToPtr("b")) }()
+
+			var batchCtx context.Context
+			Eventually(batchCtxs).Should(Receive(&batchCtx))
+			cancelRequest()
+			Consistently(batchCtx.Done(), time.Second).ShouldNot(BeClosed())
+			close(release)
+
+			for i := 0; i < 2; i++ {
+				var result batcher.Result[string]
+				Eventually(results).Should(Receive(&result))
+				Expect(result.Err).ToNot(HaveOccurred())
+			}
+		})
+		It("should cancel the batch once every caller is canceled", func() {
+			requestCtx, cancelRequest := context.WithCancel(ctx)
+			otherCtx, cancelOther := context.WithCancel(ctx)
+			go func() { b.Add(requestCtx, lo.ToPtr("a")) }()
+			go func() { b.Add(otherCtx, lo.ToPtr("b")) }()
+
+			var batchCtx context.Context
+			Eventually(batchCtxs).Should(Receive(&batchCtx))
+			cancelRequest()
+			cancelOther()
+			Eventually(batchCtx.Done()).Should(BeClosed())
+		})
+	})
 	Context("Metrics", func() {
 		It("should create a batch_size metric when a batch is run", func() {
 			// This batcher will get canceled at the end of the test run
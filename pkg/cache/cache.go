@@ -27,10 +27,31 @@ const (
 	// UnavailableOfferingsTTL is the time before offerings that were marked as unavailable
 	// are removed from the cache and are available for launch again
 	UnavailableOfferingsTTL = 3 * time.Minute
+	// UnavailableOfferingsBackoffWindow is how long after an unavailable offering is available again that another
+	// failure doubles the time it's marked as unavailable for
+	UnavailableOfferingsBackoffWindow = 10 * time.Minute
+	// UnavailableOfferingsMaxTTL caps how long an offering that keeps failing is marked as unavailable for
+	UnavailableOfferingsMaxTTL = time.Hour
 	// InstanceTypesAndZonesTTL is the time before we refresh instance types and zones at EC2
 	InstanceTypesAndZonesTTL = 5 * time.Minute
 	// InstanceProfileTTL is the time before we refresh checking instance profile existence at IAM
 	InstanceProfileTTL = 15 * time.Minute
+	// InflightLaunchTTL is the time that we remember a NodeClaim whose CreateFleet request timed out, so that a
+	// retried launch first checks whether EC2 already created the instance. This matches the NodeClaim registration TTL.
+	InflightLaunchTTL = 15 * time.Minute
+	// LaunchAttemptTTL is the time that we remember how many times a NodeClaim's launch was attempted, which keys the
+	// CreateFleet client token. It's kept long after the launch so that a later attempt doesn't reuse an earlier token.
+	LaunchAttemptTTL = 24 * time.Hour
+	// InstanceDescriptionTTL is the time that sweeps of the cluster's instances reuse an instance's full description
+	// before describing it again, which is how long it can take a sweep to see tags that were added outside of Karpenter
+	InstanceDescriptionTTL = 15 * time.Minute
+	// SpotFallbackZoneTTL is the time that we remember the zone where a NodePool's spot launch failed with insufficient
+	// capacity, so that its on-demand fallback prefers that zone. This matches how long the spot offerings are first
+	// marked as unavailable for.
+	SpotFallbackZoneTTL = UnavailableOfferingsTTL
+	// RiskyOfferingTTL is the time that an offering isn't launched after one of its spot instances received a rebalance
+	// recommendation, which is kept short since the recommendation only signals an elevated risk of interruption
+	RiskyOfferingTTL = time.Minute
 )
 
 const (
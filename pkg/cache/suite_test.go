@@ -0,0 +1,182 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+)
+
+var ctx context.Context
+var unavailableOfferings *cache.UnavailableOfferings
+
+func TestCache(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cache")
+}
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	unavailableOfferings = cache.NewUnavailableOfferings()
+})
+
+var _ = Describe("UnavailableOfferings", func() {
+	fleetErr := func(code, instanceType, zone string) *ec2.CreateFleetError {
+		return &ec2.CreateFleetError{
+			ErrorCode: aws.String(code),
+			LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+				Overrides: &ec2.FleetLaunchTemplateOverrides{InstanceType: aws.String(instanceType), AvailabilityZone: aws.String(zone)},
+			},
+		}
+	}
+	expectUnavailableFor := func(instanceType, zone, capacityType string, ttl time.Duration) {
+		GinkgoHelper()
+		until, ok := unavailableOfferings.UnavailableUntil(instanceType, zone, capacityType)
+		Expect(ok).To(BeTrue())
+		Expect(until).To(BeTemporally("~", time.Now().Add(ttl), ttl/10))
+	}
+
+	It("should mark on-demand offerings with insufficient capacity unavailable for the on-demand TTL", func() {
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("InsufficientInstanceCapacity", "p4d.24xlarge", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand)
+		expectUnavailableFor("p4d.24xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, 15*time.Minute)
+	})
+	It("should mark spot offerings with unfulfillable or insufficient capacity unavailable for the spot TTL", func() {
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("InsufficientInstanceCapacity", "m5.large", "test-zone-1b"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 3*time.Minute)
+		expectUnavailableFor("m5.large", "test-zone-1b", corev1beta1.CapacityTypeSpot, 3*time.Minute)
+	})
+	It("should mark offerings with an exhausted capacity reservation unavailable for the reservation TTL", func() {
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("ReservationCapacityExceeded", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, time.Minute)
+	})
+	It("should mark offerings unavailable for the default TTL for other reasons", func() {
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("VcpuLimitExceeded", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand)
+		unavailableOfferings.MarkUnavailable(ctx, "SpotInterruptionKind", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, cache.UnavailableOfferingsTTL)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, cache.UnavailableOfferingsTTL)
+	})
+	It("should use the configured TTLs", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			OnDemandInsufficientCapacityTTL: lo.ToPtr(2 * time.Hour),
+			SpotUnfulfillableCapacityTTL:    lo.ToPtr(time.Minute),
+		}))
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("InsufficientInstanceCapacity", "p4d.24xlarge", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("p4d.24xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, 2*time.Hour)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, time.Minute)
+	})
+	It("should double the TTL each time an offering fails again after becoming available", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotUnfulfillableCapacityTTL: lo.ToPtr(100 * time.Millisecond)}))
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 100*time.Millisecond)
+		Eventually(func() bool {
+			return unavailableOfferings.IsUnavailable("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		}).Should(BeFalse())
+
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 200*time.Millisecond)
+		Eventually(func() bool {
+			return unavailableOfferings.IsUnavailable("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		}).Should(BeFalse())
+
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 400*time.Millisecond)
+	})
+	It("should not double the TTL when an offering that is still unavailable fails again", func() {
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 3*time.Minute)
+	})
+	It("should not carry the backoff over once an offering is deleted", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotUnfulfillableCapacityTTL: lo.ToPtr(100 * time.Millisecond)}))
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.Delete("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 100*time.Millisecond)
+	})
+	It("should mark risky offerings unavailable for the risky offering TTL without counting towards the backoff", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotUnfulfillableCapacityTTL: lo.ToPtr(100 * time.Millisecond)}))
+		unavailableOfferings.MarkRisky(ctx, "RebalanceRecommendationKind", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, cache.RiskyOfferingTTL)
+
+		unavailableOfferings.Delete("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkRisky(ctx, "RebalanceRecommendationKind", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 100*time.Millisecond)
+	})
+	It("should not shorten how long an offering is unavailable when marking it risky", func() {
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkRisky(ctx, "RebalanceRecommendationKind", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		expectUnavailableFor("m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot, 3*time.Minute)
+	})
+	It("should increment the sequence number every time an offering is marked unavailable", func() {
+		seqNum := unavailableOfferings.SeqNum()
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("UnfulfillableCapacity", "m5.large", "test-zone-1a"), corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("InsufficientInstanceCapacity", "p4d.24xlarge", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand)
+		Expect(unavailableOfferings.SeqNum()).To(Equal(seqNum + 3))
+	})
+	Context("Scopes", func() {
+		reservation := cache.ReservationScope("cr-0123456789abcdef0")
+		It("should not mark an offering unavailable in a reservation when it's unavailable outside of it", func() {
+			unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("InsufficientInstanceCapacity", "p5.48xlarge", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand)
+			Expect(unavailableOfferings.IsUnavailable("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)).To(BeTrue())
+			Expect(unavailableOfferings.IsUnavailable("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, reservation)).To(BeFalse())
+		})
+		It("should not mark an offering unavailable outside of a reservation when the reservation is exhausted", func() {
+			unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("ReservationCapacityExceeded", "p5.48xlarge", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand, reservation)
+			Expect(unavailableOfferings.IsUnavailable("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, reservation)).To(BeTrue())
+			Expect(unavailableOfferings.IsUnavailable("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)).To(BeFalse())
+			Expect(unavailableOfferings.IsUnavailable("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, cache.ReservationScope("cr-other"))).To(BeFalse())
+		})
+		It("should use the default scope when none is given", func() {
+			unavailableOfferings.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)
+			Expect(unavailableOfferings.IsUnavailable("m5.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, cache.UnavailableOfferingsScopeDefault)).To(BeTrue())
+			unavailableOfferings.Delete("m5.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, cache.UnavailableOfferingsScopeDefault)
+			Expect(unavailableOfferings.IsUnavailable("m5.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)).To(BeFalse())
+		})
+		It("should back off each scope separately", func() {
+			unavailableOfferings.MarkUnavailable(ctx, "ReservationCapacityExceeded", "p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)
+			unavailableOfferings.Delete("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)
+			unavailableOfferings.MarkUnavailable(ctx, "ReservationCapacityExceeded", "p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, reservation)
+			until, ok := unavailableOfferings.UnavailableUntil("p5.48xlarge", "test-zone-1a", corev1beta1.CapacityTypeOnDemand, reservation)
+			Expect(ok).To(BeTrue())
+			Expect(until).To(BeTemporally("~", time.Now().Add(time.Minute), 6*time.Second))
+		})
+		It("should increment the sequence numbers of the scope and of every scope", func() {
+			seqNum, defaultSeqNum, reservationSeqNum := unavailableOfferings.SeqNum(), unavailableOfferings.SeqNum(cache.UnavailableOfferingsScopeDefault), unavailableOfferings.SeqNum(reservation)
+			unavailableOfferings.MarkUnavailableForFleetErr(ctx, fleetErr("ReservationCapacityExceeded", "p5.48xlarge", "test-zone-1a"), corev1beta1.CapacityTypeOnDemand, reservation)
+			Expect(unavailableOfferings.SeqNum()).To(Equal(seqNum + 1))
+			Expect(unavailableOfferings.SeqNum(reservation)).To(Equal(reservationSeqNum + 1))
+			Expect(unavailableOfferings.SeqNum(cache.UnavailableOfferingsScopeDefault)).To(Equal(defaultSeqNum))
+		})
+	})
+})
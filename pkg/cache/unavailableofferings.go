@@ -17,68 +17,205 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
 	"knative.dev/pkg/logging"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	insufficientInstanceCapacityErrorCode = "InsufficientInstanceCapacity"
+	unfulfillableCapacityErrorCode        = "UnfulfillableCapacity"
+	reservationCapacityExceededErrorCode  = "ReservationCapacityExceeded"
+
+	// UnavailableOfferingsScopeDefault is the scope of offerings that aren't launched into a capacity reservation, which
+	// is used when no scope is given
+	UnavailableOfferingsScopeDefault = "default"
 )
 
+// ReservationScope is the scope of offerings launched into the capacity reservation. Capacity shortages of an offering
+// outside of the reservation don't affect launches into it, and the reservation running out doesn't affect launches
+// outside of it.
+func ReservationScope(id string) string {
+	return fmt.Sprintf("reservation:%s", id)
+}
+
 // UnavailableOfferings stores any offerings that return ICE (insufficient capacity errors) when
 // attempting to launch the capacity. These offerings are ignored as long as they are in the cache on
-// GetInstanceTypes responses
+// GetInstanceTypes responses. Offerings are tracked separately in each scope, and the methods that take an optional
+// scope use UnavailableOfferingsScopeDefault without one.
 type UnavailableOfferings struct {
-	// key: <capacityType>:<instanceType>:<zone>, value: struct{}{}
-	cache  *cache.Cache
-	SeqNum uint64
+	// key: [<scope>:]<capacityType>:<instanceType>:<zone>, value: <scope>
+	cache *cache.Cache
+	// failures counts the consecutive times that each offering has been marked unavailable, keyed the same as cache.
+	// A count outlives its offering's entry by the backoff window, so an offering that fails again soon after it's
+	// available is marked unavailable for longer.
+	failures *cache.Cache
+	mu       sync.Mutex
+	// seqNum changes with the offerings of every scope, and seqNums with the offerings of each scope
+	seqNum  uint64
+	seqNums sync.Map
 }
 
 func NewUnavailableOfferings() *UnavailableOfferings {
 	uo := &UnavailableOfferings{
-		cache:  cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
-		SeqNum: 0,
+		cache:    cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
+		failures: cache.New(UnavailableOfferingsTTL+UnavailableOfferingsBackoffWindow, DefaultCleanupInterval),
 	}
-	uo.cache.OnEvicted(func(_ string, _ interface{}) {
-		atomic.AddUint64(&uo.SeqNum, 1)
+	uo.cache.OnEvicted(func(_ string, scope interface{}) {
+		uo.changed(scope.(string))
 	})
 	return uo
 }
 
+// SeqNum returns a number that changes whenever an offering of the scope is marked unavailable or becomes available
+// again. Without a scope, it changes with the offerings of every scope.
+func (u *UnavailableOfferings) SeqNum(scope ...string) uint64 {
+	if len(scope) == 0 {
+		return atomic.LoadUint64(&u.seqNum)
+	}
+	seqNum, ok := u.seqNums.Load(scope[0])
+	if !ok {
+		return 0
+	}
+	return atomic.LoadUint64(seqNum.(*uint64))
+}
+
 // IsUnavailable returns true if the offering appears in the cache
-func (u *UnavailableOfferings) IsUnavailable(instanceType, zone, capacityType string) bool {
-	_, found := u.cache.Get(u.key(instanceType, zone, capacityType))
+func (u *UnavailableOfferings) IsUnavailable(instanceType, zone, capacityType string, scope ...string) bool {
+	_, found := u.cache.Get(u.key(instanceType, zone, capacityType, scopeOf(scope)))
 	return found
 }
 
-// MarkUnavailable communicates recently observed temporary capacity shortages in the provided offerings
-func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, unavailableReason, instanceType, zone, capacityType string) {
+// UnavailableUntil returns when the offering becomes available again. The second return value is false if the offering
+// isn't unavailable.
+func (u *UnavailableOfferings) UnavailableUntil(instanceType, zone, capacityType string, scope ...string) (time.Time, bool) {
+	_, expiration, found := u.cache.GetWithExpiration(u.key(instanceType, zone, capacityType, scopeOf(scope)))
+	return expiration, found
+}
+
+// MarkUnavailable communicates recently observed temporary capacity shortages in the provided offerings. The offering
+// is unavailable for the TTL of the reason, doubled for each time it was marked unavailable again within the backoff
+// window after becoming available, up to UnavailableOfferingsMaxTTL.
+func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, unavailableReason, instanceType, zone, capacityType string, scope ...string) {
+	offeringScope := scopeOf(scope)
+	key := u.key(instanceType, zone, capacityType, offeringScope)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	failures := 1
+	if count, ok := u.failures.Get(key); ok {
+		failures = count.(int)
+		// An offering that's still unavailable is being reported by a launch that was already in flight, which doesn't
+		// say anything new about how long the shortage lasts
+		if !u.IsUnavailable(instanceType, zone, capacityType, offeringScope) {
+			failures++
+		}
+	}
+	base := unavailableTTL(ctx, unavailableReason, capacityType)
+	ttl := base
+	for i := 1; i < failures && ttl < UnavailableOfferingsMaxTTL; i++ {
+		ttl *= 2
+	}
+	// The cap only limits the backoff, a longer TTL that's configured for the reason is still respected
+	ttl = lo.Max([]time.Duration{base, lo.Min([]time.Duration{ttl, UnavailableOfferingsMaxTTL})})
 	// even if the key is already in the cache, we still need to call Set to extend the cached entry's TTL
 	logging.FromContext(ctx).With(
 		"reason", unavailableReason,
 		"instance-type", instanceType,
 		"zone", zone,
 		"capacity-type", capacityType,
-		"ttl", UnavailableOfferingsTTL).Debugf("removing offering from offerings")
-	u.cache.SetDefault(u.key(instanceType, zone, capacityType), struct{}{})
-	atomic.AddUint64(&u.SeqNum, 1)
+		"scope", offeringScope,
+		"failures", failures,
+		"ttl", ttl).Debugf("removing offering from offerings")
+	u.cache.Set(key, offeringScope, ttl)
+	u.failures.Set(key, failures, ttl+UnavailableOfferingsBackoffWindow)
+	u.changed(offeringScope)
 }
 
-func (u *UnavailableOfferings) MarkUnavailableForFleetErr(ctx context.Context, fleetErr *ec2.CreateFleetError, capacityType string) {
+// MarkRisky keeps an offering with an elevated risk of interruption from being launched for RiskyOfferingTTL. Unlike
+// MarkUnavailable, it doesn't count towards the backoff and never shortens how long an offering is already unavailable.
+func (u *UnavailableOfferings) MarkRisky(ctx context.Context, reason, instanceType, zone, capacityType string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if until, ok := u.UnavailableUntil(instanceType, zone, capacityType); ok && until.After(time.Now().Add(RiskyOfferingTTL)) {
+		return
+	}
+	logging.FromContext(ctx).With(
+		"reason", reason,
+		"instance-type", instanceType,
+		"zone", zone,
+		"capacity-type", capacityType,
+		"ttl", RiskyOfferingTTL).Debugf("removing risky offering from offerings")
+	u.cache.Set(u.key(instanceType, zone, capacityType, UnavailableOfferingsScopeDefault), UnavailableOfferingsScopeDefault, RiskyOfferingTTL)
+	u.changed(UnavailableOfferingsScopeDefault)
+}
+
+func (u *UnavailableOfferings) MarkUnavailableForFleetErr(ctx context.Context, fleetErr *ec2.CreateFleetError, capacityType string, scope ...string) {
 	instanceType := aws.StringValue(fleetErr.LaunchTemplateAndOverrides.Overrides.InstanceType)
 	zone := aws.StringValue(fleetErr.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)
-	u.MarkUnavailable(ctx, aws.StringValue(fleetErr.ErrorCode), instanceType, zone, capacityType)
+	u.MarkUnavailable(ctx, aws.StringValue(fleetErr.ErrorCode), instanceType, zone, capacityType, scope...)
+}
+
+func (u *UnavailableOfferings) Delete(instanceType string, zone string, capacityType string, scope ...string) {
+	u.cache.Delete(u.key(instanceType, zone, capacityType, scopeOf(scope)))
+	u.failures.Delete(u.key(instanceType, zone, capacityType, scopeOf(scope)))
 }
 
-func (u *UnavailableOfferings) Delete(instanceType string, zone string, capacityType string) {
-	u.cache.Delete(u.key(instanceType, zone, capacityType))
+// Len returns the number of offerings that are currently unavailable
+func (u *UnavailableOfferings) Len() int {
+	return len(u.cache.Items())
 }
 
 func (u *UnavailableOfferings) Flush() {
 	u.cache.Flush()
+	u.failures.Flush()
+}
+
+// changed moves the sequence numbers of the scope and of every scope on
+func (u *UnavailableOfferings) changed(scope string) {
+	seqNum, _ := u.seqNums.LoadOrStore(scope, new(uint64))
+	atomic.AddUint64(seqNum.(*uint64), 1)
+	atomic.AddUint64(&u.seqNum, 1)
+}
+
+// key returns the cache key for all offerings in the cache. Offerings in the default scope aren't prefixed with it.
+func (u *UnavailableOfferings) key(instanceType string, zone string, capacityType string, scope string) string {
+	if scope == UnavailableOfferingsScopeDefault {
+		return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
+	}
+	return fmt.Sprintf("%s:%s:%s:%s", scope, capacityType, instanceType, zone)
 }
 
-// key returns the cache key for all offerings in the cache
-func (u *UnavailableOfferings) key(instanceType string, zone string, capacityType string) string {
-	return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
+// scopeOf returns the scope of the optional scope argument of a method
+func scopeOf(scope []string) string {
+	if len(scope) == 0 || scope[0] == "" {
+		return UnavailableOfferingsScopeDefault
+	}
+	return scope[0]
+}
+
+// unavailableTTL returns how long an offering is unavailable after a single failure for the reason. On-demand capacity
+// shortages tend to persist far longer than spot ones, while an exhausted capacity reservation frees up as soon as an
+// instance in it terminates.
+func unavailableTTL(ctx context.Context, unavailableReason, capacityType string) time.Duration {
+	opts := options.FromContext(ctx)
+	switch {
+	case unavailableReason == reservationCapacityExceededErrorCode:
+		return opts.ReservationCapacityExceededTTL
+	case capacityType == corev1beta1.CapacityTypeSpot && (unavailableReason == unfulfillableCapacityErrorCode || unavailableReason == insufficientInstanceCapacityErrorCode):
+		return opts.SpotUnfulfillableCapacityTTL
+	case capacityType == corev1beta1.CapacityTypeOnDemand && unavailableReason == insufficientInstanceCapacityErrorCode:
+		return opts.OnDemandInsufficientCapacityTTL
+	default:
+		return UnavailableOfferingsTTL
+	}
 }
@@ -18,12 +18,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -32,6 +34,7 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"github.com/samber/lo"
@@ -43,6 +46,7 @@ import (
 
 	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
@@ -53,6 +57,9 @@ import (
 
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
 
+// nodeClaimDeletionPollInterval is how often the NodeClaim of a launch is re-read to find out whether it was deleted
+const nodeClaimDeletionPollInterval = time.Second
+
 type CloudProvider struct {
 	kubeClient client.Client
 	recorder   events.Recorder
@@ -62,10 +69,12 @@ type CloudProvider struct {
 	amiProvider           amifamily.Provider
 	securityGroupProvider securitygroup.Provider
 	subnetProvider        subnet.Provider
+	costLimitProvider     costlimit.Provider
 }
 
 func New(instanceTypeProvider instancetype.Provider, instanceProvider instance.Provider, recorder events.Recorder,
-	kubeClient client.Client, amiProvider amifamily.Provider, securityGroupProvider securitygroup.Provider, subnetProvider subnet.Provider) *CloudProvider {
+	kubeClient client.Client, amiProvider amifamily.Provider, securityGroupProvider securitygroup.Provider, subnetProvider subnet.Provider,
+	costLimitProvider costlimit.Provider) *CloudProvider {
 	return &CloudProvider{
 		instanceTypeProvider:  instanceTypeProvider,
 		instanceProvider:      instanceProvider,
@@ -73,13 +82,18 @@ func New(instanceTypeProvider instancetype.Provider, instanceProvider instance.P
 		amiProvider:           amiProvider,
 		securityGroupProvider: securityGroupProvider,
 		subnetProvider:        subnetProvider,
+		costLimitProvider:     costLimitProvider,
 		recorder:              recorder,
 	}
 }
 
 // Create a NodeClaim given the constraints.
 func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (*corev1beta1.NodeClaim, error) {
-	nodeClass, err := c.resolveNodeClassFromNodeClaim(ctx, nodeClaim)
+	nodeClassName, err := c.resolveLaunchNodeClassName(ctx, nodeClaim)
+	if err != nil {
+		return nil, fmt.Errorf("resolving node class migration, %w", err)
+	}
+	nodeClass, err := c.resolveNodeClass(ctx, nodeClassName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.recorder.Publish(cloudproviderevents.NodeClaimFailedToResolveNodeClass(nodeClaim))
@@ -87,6 +101,10 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1beta1.NodeC
 		// We treat a failure to resolve the NodeClass as an ICE since this means there is no capacity possibilities for this NodeClaim
 		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("resolving node class, %w", err))
 	}
+	// Launches with security groups from a different VPC than the subnets would be rejected by EC2
+	if cond := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady); cond != nil && cond.IsFalse() && cond.Reason == securitygroup.NotInSubnetVPCReason {
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("resolving node class, %s", cond.Message))
+	}
 	instanceTypes, err := c.resolveInstanceTypes(ctx, nodeClaim, nodeClass)
 	if err != nil {
 		return nil, fmt.Errorf("resolving instance types, %w", err)
@@ -94,23 +112,116 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1beta1.NodeC
 	if len(instanceTypes) == 0 {
 		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all requested instance types were unavailable during launch"))
 	}
-	instance, err := c.instanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+	launchCtx, cancel := c.cancelOnDeletion(ctx, nodeClaim)
+	instance, err := c.instanceProvider.Create(launchCtx, nodeClass, nodeClaim, instanceTypes)
+	cancel()
 	if err != nil {
+		c.publishFleetErrors(nodeClaim, err)
 		return nil, fmt.Errorf("creating instance, %w", err)
 	}
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
 		return i.Name == instance.Type
 	})
-	nc := c.instanceToNodeClaim(instance, instanceType)
-	nc.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
-		v1beta1.AnnotationEC2NodeClassHash:        nodeClass.Hash(),
+	nc := c.instanceToNodeClaim(ctx, instance, instanceType)
+	// EC2 doesn't report the zone ID of instances, so it's taken from the subnet the instance was launched into
+	var zoneID string
+	if subnet, ok := lo.Find(nodeClass.Status.Subnets, func(s v1beta1.Subnet) bool { return s.ID == instance.SubnetID }); ok {
+		zoneID = subnet.ZoneID
+	}
+	if zoneID != "" && instancetype.LabelEmitted(ctx, v1beta1.LabelTopologyZoneID) {
+		nc.Labels[v1beta1.LabelTopologyZoneID] = zoneID
+	}
+	nc.Labels = lo.Assign(nc.Labels, extraNodeLabels(ctx, instance, nc.Labels, zoneID))
+	nc.Annotations = lo.Assign(nodeClass.Annotations, launchAnnotations(instance), map[string]string{
+		v1beta1.AnnotationEC2NodeClassHash:        nodeClass.DriftHash(),
 		v1beta1.AnnotationEC2NodeClassHashVersion: v1beta1.EC2NodeClassHashVersion,
-	})
+		v1beta1.AnnotationLaunchedNodeClass:       nodeClass.Name,
+	}, lo.OmitByValues(map[string]string{
+		v1beta1.AnnotationInstanceProfile: lo.FromPtrOr(nodeClass.Spec.InstanceProfile, nodeClass.Status.InstanceProfile),
+	}, []string{""}))
+	if model, err := c.instanceTypeProvider.EphemeralStorageModel(ctx, nodeClass, instance.Type); err != nil {
+		logging.FromContext(ctx).With("instance-type", instance.Type).Errorf("resolving ephemeral storage model, %s", err)
+	} else {
+		nc.Annotations[v1beta1.AnnotationEphemeralStorageModel] = model
+	}
 	return nc, nil
 }
 
+// cancelOnDeletion returns a context that's canceled once the NodeClaim is deleted, so that the launch for a NodeClaim
+// deleted mid-provisioning is aborted, or the instance it launched is terminated, rather than left behind
+func (c *CloudProvider) cancelOnDeletion(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(nodeClaimDeletionPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			latest := &corev1beta1.NodeClaim{}
+			if err := c.kubeClient.Get(ctx, client.ObjectKeyFromObject(nodeClaim), latest); err != nil {
+				if !errors.IsNotFound(err) {
+					continue
+				}
+			} else if latest.UID == nodeClaim.UID && latest.DeletionTimestamp.IsZero() {
+				continue
+			}
+			logging.FromContext(ctx).Infof("canceling launch for deleted nodeclaim")
+			cancel()
+			return
+		}
+	}()
+	return ctx, cancel
+}
+
+// resolveLaunchNodeClassName returns the name of the EC2NodeClass that the NodeClaim launches from, which is the one
+// that it references unless its NodePool is migrating some of its launches to another EC2NodeClass
+func (c *CloudProvider) resolveLaunchNodeClassName(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (string, error) {
+	nodePoolName, ok := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	if !ok {
+		return nodeClaim.Spec.NodeClassRef.Name, nil
+	}
+	nodePool := &corev1beta1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		if errors.IsNotFound(err) {
+			return nodeClaim.Spec.NodeClassRef.Name, nil
+		}
+		return "", err
+	}
+	m, err := nodePoolMigration(nodePool)
+	if err != nil {
+		return "", err
+	}
+	if m == nil || nodePool.Spec.Template.Spec.NodeClassRef == nil {
+		return nodeClaim.Spec.NodeClassRef.Name, nil
+	}
+	return m.nodeClassName(nodePool, nodeClaim.UID), nil
+}
+
+// publishFleetErrors publishes the instance types and zones that EC2 rejected when a fleet request fails to launch
+func (c *CloudProvider) publishFleetErrors(nodeClaim *corev1beta1.NodeClaim, err error) {
+	if fleetErrs := instance.FleetErrors(err); len(fleetErrs) > 0 {
+		c.recorder.Publish(cloudproviderevents.NodeClaimFleetErrors(nodeClaim, fleetErrs))
+	}
+}
+
+// launchAnnotations returns the identifiers of the request that launched the instance that are known, which are
+// recorded on the NodeClaim for joining against billing and usage reports
+func launchAnnotations(i *instance.Instance) map[string]string {
+	return lo.OmitByValues(map[string]string{
+		v1beta1.AnnotationFleetID:               i.FleetID,
+		v1beta1.AnnotationLaunchTemplateName:    i.LaunchTemplateName,
+		v1beta1.AnnotationLaunchTemplateVersion: i.LaunchTemplateVersion,
+		v1beta1.AnnotationSpotInstanceRequestID: i.SpotInstanceRequestID,
+	}, []string{""})
+}
+
+// List is only used to sweep for instances that no longer have a NodeClaim, or NodeClaims that no longer have an
+// instance, so it reads the instances with the lighter Sweep rather than describing each of them in full
 func (c *CloudProvider) List(ctx context.Context) ([]*corev1beta1.NodeClaim, error) {
-	instances, err := c.instanceProvider.List(ctx)
+	instances, err := c.instanceProvider.Sweep(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("listing instances, %w", err)
 	}
@@ -120,7 +231,7 @@ func (c *CloudProvider) List(ctx context.Context) ([]*corev1beta1.NodeClaim, err
 		if err != nil {
 			return nil, fmt.Errorf("resolving instance type, %w", err)
 		}
-		nodeClaims = append(nodeClaims, c.instanceToNodeClaim(instance, instanceType))
+		nodeClaims = append(nodeClaims, c.instanceToNodeClaim(ctx, instance, instanceType))
 	}
 	return nodeClaims, nil
 }
@@ -135,11 +246,14 @@ func (c *CloudProvider) Get(ctx context.Context, providerID string) (*corev1beta
 	if err != nil {
 		return nil, fmt.Errorf("getting instance, %w", err)
 	}
+	if options.FromContext(ctx).RequirePrivateDNSName && instance.PrivateDNSName == "" {
+		logging.FromContext(ctx).Warnf("instance has no private DNS name, enable the enableDnsHostnames attribute of %s or set require-private-dns-name to false if node names don't depend on it", instance.VPCID)
+	}
 	instanceType, err := c.resolveInstanceTypeFromInstance(ctx, instance)
 	if err != nil {
 		return nil, fmt.Errorf("resolving instance type, %w", err)
 	}
-	return c.instanceToNodeClaim(instance, instanceType), nil
+	return c.instanceToNodeClaim(ctx, instance, instanceType), nil
 }
 
 func (c *CloudProvider) LivenessProbe(req *http.Request) error {
@@ -164,9 +278,12 @@ func (c *CloudProvider) GetInstanceTypes(ctx context.Context, nodePool *corev1be
 	// TODO, break this coupling
 	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
 	if err != nil {
+		if instancetype.IsNoSubnetZones(err) {
+			c.recorder.Publish(cloudproviderevents.NodePoolNoSubnetZones(nodePool, err))
+		}
 		return nil, err
 	}
-	return instanceTypes, nil
+	return c.costLimitProvider.MarkUnavailable(ctx, nodePool.Name, instanceTypes), nil
 }
 
 func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) error {
@@ -177,7 +294,40 @@ func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *corev1beta1.NodeC
 		return fmt.Errorf("getting instance ID, %w", err)
 	}
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("id", id))
-	return c.instanceProvider.Delete(ctx, id)
+	terminationBehavior, err := c.resolveTerminationBehavior(ctx, nodeClaim)
+	if err != nil {
+		return fmt.Errorf("resolving termination behavior, %w", err)
+	}
+	if terminationBehavior == v1beta1.TerminationBehaviorStop {
+		err = c.instanceProvider.Stop(ctx, id)
+	} else {
+		err = c.instanceProvider.Delete(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	// Garbage collected instances have no NodeClaim to record the event against
+	if nodeClaim.UID != "" {
+		c.recorder.Publish(cloudproviderevents.NodeClaimInstanceDeleted(nodeClaim, id))
+	}
+	return nil
+}
+
+// resolveTerminationBehavior returns the termination behavior of the NodeClaim's EC2NodeClass. NodeClaims without a
+// resolvable EC2NodeClass, such as those for leaked instances, are terminated.
+func (c *CloudProvider) resolveTerminationBehavior(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (v1beta1.TerminationBehavior, error) {
+	if nodeClaim.Spec.NodeClassRef == nil {
+		return v1beta1.TerminationBehaviorTerminate, nil
+	}
+	// Spot instances are launched with one-time requests, which can't be stopped
+	if nodeClaim.Labels[corev1beta1.CapacityTypeLabelKey] == corev1beta1.CapacityTypeSpot {
+		return v1beta1.TerminationBehaviorTerminate, nil
+	}
+	nodeClass, err := c.resolveNodeClassFromNodeClaim(ctx, nodeClaim)
+	if err != nil {
+		return v1beta1.TerminationBehaviorTerminate, client.IgnoreNotFound(err)
+	}
+	return lo.FromPtrOr(nodeClass.Spec.TerminationBehavior, v1beta1.TerminationBehaviorTerminate), nil
 }
 
 func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (cloudprovider.DriftReason, error) {
@@ -193,7 +343,16 @@ func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *corev1beta1.No
 	if nodePool.Spec.Template.Spec.NodeClassRef == nil {
 		return "", nil
 	}
-	nodeClass, err := c.resolveNodeClassFromNodePool(ctx, nodePool)
+	if isNodeClassMigrationDrifted(nodeClaim, nodePool) {
+		return NodeClassMigrationDrift, nil
+	}
+	// NodeClaims are compared against the EC2NodeClass that they were launched from, which differs from the one that
+	// their NodePool references while the NodePool is migrating
+	nodeClassName := nodePool.Spec.Template.Spec.NodeClassRef.Name
+	if name, ok := nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass]; ok {
+		nodeClassName = name
+	}
+	nodeClass, err := c.resolveNodeClass(ctx, nodeClassName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.recorder.Publish(cloudproviderevents.NodePoolFailedToResolveNodeClass(nodePool))
@@ -223,22 +382,16 @@ func (c *CloudProvider) GetSupportedNodeClasses() []schema.GroupVersionKind {
 }
 
 func (c *CloudProvider) resolveNodeClassFromNodeClaim(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (*v1beta1.EC2NodeClass, error) {
-	nodeClass := &v1beta1.EC2NodeClass{}
-	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
-		return nil, err
-	}
-	// For the purposes of NodeClass CloudProvider resolution, we treat deleting NodeClasses as NotFound
-	if !nodeClass.DeletionTimestamp.IsZero() {
-		// For the purposes of NodeClass CloudProvider resolution, we treat deleting NodeClasses as NotFound,
-		// but we return a different error message to be clearer to users
-		return nil, newTerminatingNodeClassError(nodeClass.Name)
-	}
-	return nodeClass, nil
+	return c.resolveNodeClass(ctx, utils.NodeClassName(nodeClaim))
 }
 
 func (c *CloudProvider) resolveNodeClassFromNodePool(ctx context.Context, nodePool *corev1beta1.NodePool) (*v1beta1.EC2NodeClass, error) {
+	return c.resolveNodeClass(ctx, nodePool.Spec.Template.Spec.NodeClassRef.Name)
+}
+
+func (c *CloudProvider) resolveNodeClass(ctx context.Context, name string) (*v1beta1.EC2NodeClass, error) {
 	nodeClass := &v1beta1.EC2NodeClass{}
-	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: name}, nodeClass); err != nil {
 		return nil, err
 	}
 	if !nodeClass.DeletionTimestamp.IsZero() {
@@ -270,7 +423,10 @@ func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, ins
 	}
 	instanceTypes, err := c.GetInstanceTypes(ctx, nodePool)
 	if err != nil {
-		// If we can't resolve the NodePool, we fall back to not getting instance type info
+		// If we can't resolve the NodePool, or its subnets no longer exist, we fall back to not getting instance type info
+		if instancetype.IsNoSubnetZones(err) {
+			return nil, nil
+		}
 		return nil, client.IgnoreNotFound(fmt.Errorf("resolving nodeclass, %w", err))
 	}
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
@@ -290,13 +446,18 @@ func (c *CloudProvider) resolveNodePoolFromInstance(ctx context.Context, instanc
 	return nil, errors.NewNotFound(schema.GroupResource{Group: corev1beta1.Group, Resource: "nodepools"}, "")
 }
 
-func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *cloudprovider.InstanceType) *corev1beta1.NodeClaim {
+func (c *CloudProvider) instanceToNodeClaim(ctx context.Context, i *instance.Instance, instanceType *cloudprovider.InstanceType) *corev1beta1.NodeClaim {
 	nodeClaim := &corev1beta1.NodeClaim{}
 	labels := map[string]string{}
 	annotations := map[string]string{}
 
 	if instanceType != nil {
 		for key, req := range instanceType.Requirements {
+			// Gated and disabled labels are still requirements of the instance type, so that NodePools can select on
+			// them, but nodes aren't labeled with them
+			if !instancetype.LabelEmitted(ctx, key) {
+				continue
+			}
 			if req.Len() == 1 {
 				labels[key] = req.Values()[0]
 			}
@@ -323,6 +484,7 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	if v, ok := i.Tags[corev1beta1.ManagedByAnnotationKey]; ok {
 		annotations[corev1beta1.ManagedByAnnotationKey] = v
 	}
+	annotations = lo.Assign(annotations, launchAnnotations(i))
 	nodeClaim.Labels = labels
 	nodeClaim.Annotations = annotations
 	nodeClaim.CreationTimestamp = metav1.Time{Time: i.LaunchTime}
@@ -335,6 +497,32 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	return nodeClaim
 }
 
+// extraNodeLabels renders the extra-node-labels of a launched instance. Labels that don't render a valid label value are
+// left out rather than failing the launch, since the instance has already been launched.
+func extraNodeLabels(ctx context.Context, i *instance.Instance, labels map[string]string, zoneID string) map[string]string {
+	data := options.NodeLabelTemplateData{
+		InstanceID:   i.ID,
+		Region:       labels[v1.LabelTopologyRegion],
+		ZoneID:       zoneID,
+		InstanceType: i.Type,
+	}
+	extra := map[string]string{}
+	for key, value := range options.FromContext(ctx).ExtraNodeLabels {
+		rendered, err := options.RenderNodeLabel(value, data)
+		if err == nil {
+			if errs := validation.IsValidLabelValue(rendered); len(errs) > 0 {
+				err = fmt.Errorf("%q is not a valid label value, %s", rendered, strings.Join(errs, ", "))
+			}
+		}
+		if err != nil {
+			logging.FromContext(ctx).With("label", key, "id", i.ID).Errorf("rendering extra node label, %s", err)
+			continue
+		}
+		extra[key] = rendered
+	}
+	return extra
+}
+
 // newTerminatingNodeClassError returns a NotFound error for handling by
 func newTerminatingNodeClassError(name string) *errors.StatusError {
 	qualifiedResource := schema.GroupResource{Group: corev1beta1.Group, Resource: "ec2nodeclasses"}
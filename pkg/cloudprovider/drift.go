@@ -39,6 +39,9 @@ const (
 	SubnetDrift        cloudprovider.DriftReason = "SubnetDrift"
 	SecurityGroupDrift cloudprovider.DriftReason = "SecurityGroupDrift"
 	NodeClassDrift     cloudprovider.DriftReason = "NodeClassDrift"
+	// NodeClassMigrationDrift is reported for NodeClaims launched from an EC2NodeClass that new NodeClaims of their
+	// NodePool no longer launch from
+	NodeClassMigrationDrift cloudprovider.DriftReason = "NodeClassMigrationDrift"
 )
 
 func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodePool *corev1beta1.NodePool, nodeClass *v1beta1.EC2NodeClass) (cloudprovider.DriftReason, error) {
@@ -80,9 +83,11 @@ func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *corev1beta1
 	if !found {
 		return "", fmt.Errorf(`finding node instance type "%s"`, nodeClaim.Labels[v1.LabelInstanceTypeStable])
 	}
-	amis, err := c.amiProvider.Get(ctx, nodeClass, &amifamily.Options{})
-	if err != nil {
-		return "", fmt.Errorf("getting amis, %w", err)
+	amis, pinned := amifamily.PinnedAMIs(nodeClass)
+	if !pinned {
+		if amis, err = c.amiProvider.Get(ctx, nodeClass, &amifamily.Options{}); err != nil {
+			return "", fmt.Errorf("getting amis, %w", err)
+		}
 	}
 	if len(amis) == 0 {
 		return "", fmt.Errorf("no amis exist given constraints")
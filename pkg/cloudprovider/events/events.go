@@ -15,10 +15,19 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 
 	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/events"
+
+	awsv1beta1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 )
 
 func NodePoolFailedToResolveNodeClass(nodePool *v1beta1.NodePool) events.Event {
@@ -30,6 +39,17 @@ func NodePoolFailedToResolveNodeClass(nodePool *v1beta1.NodePool) events.Event {
 	}
 }
 
+// NodePoolNoSubnetZones warns that the NodePool can't launch anything because its EC2NodeClass has no subnets
+func NodePoolNoSubnetZones(nodePool *v1beta1.NodePool, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           v1.EventTypeWarning,
+		Reason:         "NoSubnetZones",
+		Message:        fmt.Sprintf("Failed resolving instance types, %s", err),
+		DedupeValues:   []string{string(nodePool.UID)},
+	}
+}
+
 func NodeClaimFailedToResolveNodeClass(nodeClaim *v1beta1.NodeClaim) events.Event {
 	return events.Event{
 		InvolvedObject: nodeClaim,
@@ -38,3 +58,69 @@ func NodeClaimFailedToResolveNodeClass(nodeClaim *v1beta1.NodeClaim) events.Even
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+// NodeClaimInstanceDeleted carries the launch identifiers recorded on the NodeClaim, so that they can still be joined
+// against billing and usage reports once the NodeClaim is gone
+func NodeClaimInstanceDeleted(nodeClaim *v1beta1.NodeClaim, id string) events.Event {
+	launch := lo.MapToSlice(lo.PickByKeys(nodeClaim.Annotations, []string{
+		awsv1beta1.AnnotationFleetID,
+		awsv1beta1.AnnotationLaunchTemplateName,
+		awsv1beta1.AnnotationLaunchTemplateVersion,
+		awsv1beta1.AnnotationSpotInstanceRequestID,
+	}), func(k, v string) string { return fmt.Sprintf("%s=%s", k, v) })
+	sort.Strings(launch)
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "InstanceDeleted",
+		Message:        strings.TrimSpace(fmt.Sprintf("Deleted instance %s %s", id, strings.Join(launch, " "))),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+// maxFleetErrorOfferings bounds the instance types and zones listed for each fleet error so that the event message stays
+// readable when a request with many overrides fails
+const maxFleetErrorOfferings = 10
+
+// NodeClaimFleetErrors lists the distinct errors of a failed fleet request along with the instance types and zones
+// that EC2 rejected with each of them
+func NodeClaimFleetErrors(nodeClaim *v1beta1.NodeClaim, fleetErrs []*ec2.CreateFleetError) events.Event {
+	offerings := map[string][]string{}
+	for _, err := range fleetErrs {
+		reason := aws.StringValue(err.ErrorCode)
+		if msg := aws.StringValue(err.ErrorMessage); msg != "" {
+			reason = fmt.Sprintf("%s: %s", reason, msg)
+		}
+		offerings[reason] = append(offerings[reason], fleetErrorOffering(err))
+	}
+	reasons := lo.MapToSlice(offerings, func(reason string, o []string) string {
+		o = lo.Compact(lo.Uniq(o))
+		sort.Strings(o)
+		if len(o) == 0 {
+			return reason
+		}
+		if len(o) > maxFleetErrorOfferings {
+			o = append(o[:maxFleetErrorOfferings], fmt.Sprintf("and %d more", len(o)-maxFleetErrorOfferings))
+		}
+		return fmt.Sprintf("%s (%s)", reason, strings.Join(o, ", "))
+	})
+	sort.Strings(reasons)
+	message := fmt.Sprintf("Fleet request failed, %s", strings.Join(reasons, "; "))
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "FleetErrors",
+		Message:        message,
+		DedupeValues:   []string{string(nodeClaim.UID), message},
+	}
+}
+
+// fleetErrorOffering returns the instance type and zone of the launch template override that a fleet error is for
+func fleetErrorOffering(err *ec2.CreateFleetError) string {
+	if err.LaunchTemplateAndOverrides == nil || err.LaunchTemplateAndOverrides.Overrides == nil {
+		return ""
+	}
+	return strings.Trim(fmt.Sprintf("%s/%s",
+		aws.StringValue(err.LaunchTemplateAndOverrides.Overrides.InstanceType),
+		aws.StringValue(err.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)), "/")
+}
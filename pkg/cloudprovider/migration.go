@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// migration moves the launches of a NodePool from the EC2NodeClass that its template references to the target one. The
+// NodeClaims that launch from the target are chosen from a hash of their UIDs, so raising the percentage only moves
+// launches to the target.
+type migration struct {
+	target     string
+	percentage int
+}
+
+// nodePoolMigration returns the migration that is configured on the NodePool, if any
+func nodePoolMigration(nodePool *corev1beta1.NodePool) (*migration, error) {
+	name, ok := nodePool.Annotations[v1beta1.AnnotationMigrationNodeClass]
+	if !ok || name == "" {
+		return nil, nil
+	}
+	percentage, err := strconv.Atoi(nodePool.Annotations[v1beta1.AnnotationMigrationPercentage])
+	if err != nil || percentage < 0 || percentage > 100 {
+		return nil, fmt.Errorf("%s must be an integer between 0 and 100, got %q", v1beta1.AnnotationMigrationPercentage, nodePool.Annotations[v1beta1.AnnotationMigrationPercentage])
+	}
+	return &migration{target: name, percentage: percentage}, nil
+}
+
+// nodeClassName returns the EC2NodeClass that the NodeClaim with the UID launches from
+func (m *migration) nodeClassName(nodePool *corev1beta1.NodePool, uid types.UID) string {
+	if migrationCohort(uid) < m.percentage {
+		return m.target
+	}
+	return nodePool.Spec.Template.Spec.NodeClassRef.Name
+}
+
+// nodeClassNames returns the EC2NodeClasses that new NodeClaims of the NodePool may launch from
+func (m *migration) nodeClassNames(nodePool *corev1beta1.NodePool) sets.Set[string] {
+	names := sets.New[string]()
+	if m.percentage < 100 {
+		names.Insert(nodePool.Spec.Template.Spec.NodeClassRef.Name)
+	}
+	if m.percentage > 0 {
+		names.Insert(m.target)
+	}
+	return names
+}
+
+// isNodeClassMigrationDrifted returns true if new NodeClaims of the NodePool no longer launch from the EC2NodeClass that
+// the NodeClaim was launched from. NodeClaims that don't record their EC2NodeClass are left to the other drift checks.
+func isNodeClassMigrationDrifted(nodeClaim *corev1beta1.NodeClaim, nodePool *corev1beta1.NodePool) bool {
+	launched, ok := nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass]
+	if !ok {
+		return false
+	}
+	m, err := nodePoolMigration(nodePool)
+	if err != nil {
+		return false
+	}
+	if m == nil {
+		return launched != nodePool.Spec.Template.Spec.NodeClassRef.Name
+	}
+	return !m.nodeClassNames(nodePool).Has(launched)
+}
+
+// migrationCohort deterministically maps a NodeClaim UID to a number in [0, 100)
+func migrationCohort(uid types.UID) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(uid))
+	return int(h.Sum32() % 100)
+}
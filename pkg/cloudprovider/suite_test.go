@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -25,12 +26,14 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 
 	clock "k8s.io/utils/clock/testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/imdario/mergo"
@@ -41,7 +44,12 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	corecloudproivder "sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -82,8 +90,8 @@ var _ = BeforeSuite(func() {
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = clock.NewFakeClock(time.Now())
 	recorder = events.NewRecorder(&record.FakeRecorder{})
-	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider)
+	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, awsEnv.EventRecorder,
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider, awsEnv.CostLimitProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, recorder, cloudProvider, cluster)
 })
@@ -155,6 +163,42 @@ var _ = Describe("CloudProvider", func() {
 		Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(cloudProviderNodeClaim).To(BeNil())
 	})
+	It("should return an ICE error when the security groups aren't in the VPC of the subnets", func() {
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeSecurityGroupsReady, securitygroup.NotInSubnetVPCReason,
+			"SecurityGroups [sg-test1] not in VPC of selected subnets vpc-test1")
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("sg-test1"))
+		Expect(cloudProviderNodeClaim).To(BeNil())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+	})
+	It("should abort the launch when the NodeClaim is deleted mid-launch", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		instanceProvider := instance.NewDefaultProvider(ctx, "", awsEnv.EC2API, awsEnv.UnavailableOfferingsCache, awsEnv.InstanceTypesProvider,
+			awsEnv.SubnetProvider, &deletingLaunchTemplateProvider{Provider: awsEnv.LaunchTemplateProvider, nodeClaim: nodeClaim},
+			awsEnv.PlacementGroupProvider, awsEnv.SpotAdvisorProvider, awsEnv.CostLimitProvider, awsEnv.InflightLaunchCache, awsEnv.LaunchAttemptCache, awsEnv.SpotFallbackZoneCache, awsEnv.InstanceDescriptionCache, fakeClock, awsEnv.EventRecorder, nil)
+		cp := cloudprovider.New(awsEnv.InstanceTypesProvider, instanceProvider, awsEnv.EventRecorder,
+			env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider, awsEnv.CostLimitProvider)
+		_, err := cp.Create(ctx, nodeClaim)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+	})
+	It("should not launch when more security groups are selected than can be attached to an instance", func() {
+		var securityGroups []*ec2.SecurityGroup
+		for i := 0; i < 6; i++ {
+			securityGroups = append(securityGroups, &ec2.SecurityGroup{
+				GroupId:   aws.String(fmt.Sprintf("sg-test%d", i)),
+				GroupName: aws.String(fmt.Sprintf("eksctl-test-cluster-nodegroup-ng%d-SG", i)),
+			})
+		}
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: securityGroups})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).To(HaveOccurred())
+		Expect(cloudProviderNodeClaim).To(BeNil())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+	})
 	It("should set ImageID in the status field of the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
@@ -179,6 +223,146 @@ var _ = Describe("CloudProvider", func() {
 		Expect(ok).To(BeTrue())
 		Expect(v).To(Equal(v1beta1.EC2NodeClassHashVersion))
 	})
+	It("should annotate the nodeClaim with an EBS ephemeral storage model without an instance store policy", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEphemeralStorageModel, instancetype.EphemeralStorageModelEBS))
+	})
+	It("should annotate the nodeClaim with an instance store ephemeral storage model with the RAID0 instance store policy", func() {
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+		nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"g4dn.8xlarge"}}},
+		}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEphemeralStorageModel, instancetype.EphemeralStorageModelInstanceStore))
+	})
+	It("should annotate the nodeClaim with the instance profile that it was launched with", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationInstanceProfile, "test-profile"))
+	})
+	It("should annotate the nodeClaim with an instance store ephemeral storage model when the local NVMe label is disabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{DisabledLabels: []string{v1beta1.LabelInstanceLocalNVME}}))
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+		nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"g4dn.8xlarge"}}},
+		}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProviderNodeClaim.Labels).ToNot(HaveKey(v1beta1.LabelInstanceLocalNVME))
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEphemeralStorageModel, instancetype.EphemeralStorageModelInstanceStore))
+	})
+	Context("Launch Annotations", func() {
+		It("should annotate the fleet and launch template of an on-demand launch", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationFleetID, HavePrefix("fleet-")))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationLaunchTemplateName, HavePrefix("karpenter.k8s.aws/")))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationLaunchTemplateVersion, "$Latest"))
+			Expect(cloudProviderNodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationSpotInstanceRequestID))
+		})
+		It("should annotate the fleet, launch template and spot instance request of a spot launch", func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue(corev1beta1.CapacityTypeLabelKey, corev1beta1.CapacityTypeSpot))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationFleetID, HavePrefix("fleet-")))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationLaunchTemplateName, HavePrefix("karpenter.k8s.aws/")))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationLaunchTemplateVersion, "$Latest"))
+
+			// The spot instance request is only known once the instance is described
+			described, err := cloudProvider.Get(ctx, cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(described.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSpotInstanceRequestID, Not(BeEmpty())))
+		})
+		It("should include the launch annotations in the event published when the instance is deleted", func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			described, err := cloudProvider.Get(ctx, cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, cloudProviderNodeClaim.Annotations, lo.PickByKeys(described.Annotations, []string{v1beta1.AnnotationSpotInstanceRequestID}))
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EventRecorder.Calls("InstanceDeleted")).To(Equal(1))
+			id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EventRecorder.DetectedEvent(fmt.Sprintf("Deleted instance %s %s=%s %s=%s %s=%s %s=%s", id,
+				v1beta1.AnnotationFleetID, nodeClaim.Annotations[v1beta1.AnnotationFleetID],
+				v1beta1.AnnotationLaunchTemplateName, nodeClaim.Annotations[v1beta1.AnnotationLaunchTemplateName],
+				v1beta1.AnnotationLaunchTemplateVersion, nodeClaim.Annotations[v1beta1.AnnotationLaunchTemplateVersion],
+				v1beta1.AnnotationSpotInstanceRequestID, nodeClaim.Annotations[v1beta1.AnnotationSpotInstanceRequestID],
+			))).To(BeTrue())
+		})
+	})
+	Context("Fleet Errors", func() {
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.xlarge"}}},
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1a", "test-zone-1b"}}},
+			}
+		})
+		It("should publish the instance types and zones that were rejected with insufficient capacity", func() {
+			awsEnv.EC2API.InsufficientCapacityPools.Set([]fake.CapacityPool{
+				{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: "m5.xlarge", Zone: "test-zone-1a"},
+				{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: "m5.xlarge", Zone: "test-zone-1b"},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(cloudProviderNodeClaim).To(BeNil())
+			Expect(awsEnv.EventRecorder.Calls("FleetErrors")).To(Equal(1))
+			Expect(awsEnv.EventRecorder.DetectedEvent("Fleet request failed, InsufficientInstanceCapacity (m5.xlarge/test-zone-1a, m5.xlarge/test-zone-1b)")).To(BeTrue())
+		})
+		It("should publish each distinct fleet error with its message", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Output.Set(&ec2.CreateFleetOutput{
+				Errors: []*ec2.CreateFleetError{
+					{
+						ErrorCode:    aws.String("UnfulfillableCapacity"),
+						ErrorMessage: aws.String("Unable to fulfill capacity due to your request configuration."),
+						LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+							Overrides: &ec2.FleetLaunchTemplateOverrides{InstanceType: aws.String("m5.xlarge"), AvailabilityZone: aws.String("test-zone-1b")},
+						},
+					},
+					{
+						ErrorCode:    aws.String("InvalidParameterValue"),
+						ErrorMessage: aws.String("Invalid subnet."),
+						LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+							Overrides: &ec2.FleetLaunchTemplateOverrides{InstanceType: aws.String("m5.xlarge"), AvailabilityZone: aws.String("test-zone-1a")},
+						},
+					},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeFalse())
+			Expect(awsEnv.EventRecorder.DetectedEvent("Fleet request failed, " +
+				"InvalidParameterValue: Invalid subnet. (m5.xlarge/test-zone-1a); " +
+				"UnfulfillableCapacity: Unable to fulfill capacity due to your request configuration. (m5.xlarge/test-zone-1b)")).To(BeTrue())
+		})
+		It("should not publish fleet errors when the fleet request itself fails", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Error.Set(fmt.Errorf("failed"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(awsEnv.EventRecorder.Calls("FleetErrors")).To(Equal(0))
+		})
+	})
 	Context("EC2 Context", func() {
 		contextID := "context-1234"
 		It("should set context on the CreateFleet request if specified on the NodePool", func() {
@@ -631,6 +815,30 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
 		})
+		It("should not return drifted while newly resolved AMIs are held by AMI stabilization", func() {
+			pinnedAMIID := fake.ImageID()
+			instance.ImageId = aws.String(pinnedAMIID)
+			anyArch := []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpExists}},
+			}
+			nodeClass.Spec.AMIStabilization = &v1beta1.AMIStabilization{Window: metav1.Duration{Duration: time.Hour}}
+			nodeClass.Status.AMIs = []v1beta1.AMI{{ID: pinnedAMIID, Requirements: anyArch}}
+			nodeClass.Status.PendingAMIs = []v1beta1.AMI{{ID: armAMIID, Requirements: anyArch}, {ID: amdAMIID, Requirements: anyArch}}
+			nodeClass.Status.PendingAMIsDetectionTime = &metav1.Time{Time: time.Now()}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+
+			// Once the pending AMIs are adopted, the instance is drifted
+			nodeClass.Status.AMIs = nodeClass.Status.PendingAMIs
+			nodeClass.Status.PendingAMIs = nil
+			nodeClass.Status.PendingAMIsDetectionTime = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+		})
 		It("should return drifted if there are multiple drift reasons", func() {
 			// Instance is a reference to what we return in the GetInstances call
 			instance.ImageId = aws.String(fake.ImageID())
@@ -730,6 +938,67 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
 		})
+		Context("NodeClass Migration", func() {
+			var targetNodeClass *v1beta1.EC2NodeClass
+			BeforeEach(func() {
+				targetNodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: nodeClass.Spec})
+				targetNodeClass.Annotations = lo.Assign(targetNodeClass.Annotations, map[string]string{
+					v1beta1.AnnotationEC2NodeClassHash:        targetNodeClass.Hash(),
+					v1beta1.AnnotationEC2NodeClassHashVersion: v1beta1.EC2NodeClassHashVersion,
+				})
+				nodePool.Annotations = lo.Assign(nodePool.Annotations, map[string]string{
+					v1beta1.AnnotationMigrationNodeClass:  targetNodeClass.Name,
+					v1beta1.AnnotationMigrationPercentage: "50",
+				})
+				ExpectApplied(ctx, env.Client, nodePool, targetNodeClass)
+			})
+			It("should not drift either cohort while the migration is in progress", func() {
+				for _, name := range []string{nodeClass.Name, targetNodeClass.Name} {
+					nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass] = name
+					isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(isDrifted).To(BeEmpty())
+				}
+			})
+			It("should compare NodeClaims against the EC2NodeClass that they were launched from", func() {
+				targetNodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash] = "abcdefghijkl"
+				ExpectApplied(ctx, env.Client, targetNodeClass)
+				nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass] = nodeClass.Name
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+
+				nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass] = targetNodeClass.Name
+				isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.NodeClassDrift))
+			})
+			DescribeTable("should drift NodeClaims launched from an EC2NodeClass that new NodeClaims no longer launch from",
+				func(percentage string, referencedDrift, targetDrift corecloudproivder.DriftReason) {
+					nodePool.Annotations[v1beta1.AnnotationMigrationPercentage] = percentage
+					ExpectApplied(ctx, env.Client, nodePool)
+					nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass] = nodeClass.Name
+					isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(isDrifted).To(Equal(referencedDrift))
+
+					nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass] = targetNodeClass.Name
+					isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(isDrifted).To(Equal(targetDrift))
+				},
+				Entry("when the migration completes", "100", cloudprovider.NodeClassMigrationDrift, corecloudproivder.DriftReason("")),
+				Entry("when the migration is rolled back", "0", corecloudproivder.DriftReason(""), cloudprovider.NodeClassMigrationDrift),
+			)
+			It("should drift NodeClaims launched from the target once the migration is removed", func() {
+				delete(nodePool.Annotations, v1beta1.AnnotationMigrationNodeClass)
+				ExpectApplied(ctx, env.Client, nodePool)
+				nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass] = targetNodeClass.Name
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.NodeClassMigrationDrift))
+			})
+		})
 		Context("Static Drift Detection", func() {
 			BeforeEach(func() {
 				nodeClass = &v1beta1.EC2NodeClass{
@@ -820,6 +1089,25 @@ var _ = Describe("CloudProvider", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(isDrifted).To(Equal(cloudprovider.NodeClassDrift))
 			})
+			It("should return drifted when the resolved security groups change", func() {
+				nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
+					v1beta1.AnnotationEC2NodeClassSubnetsHash:        v1beta1.ResolvedIDsHash([]string{"subnet-test1"}),
+					v1beta1.AnnotationEC2NodeClassSecurityGroupsHash: v1beta1.ResolvedIDsHash([]string{validSecurityGroup}),
+				})
+				nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash] = nodeClass.DriftHash()
+				nodeClaim.Annotations[v1beta1.AnnotationEC2NodeClassHash] = nodeClass.DriftHash()
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+
+				nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassSecurityGroupsHash] = v1beta1.ResolvedIDsHash([]string{validSecurityGroup, "sg-test2"})
+				nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash] = nodeClass.DriftHash()
+				ExpectApplied(ctx, env.Client, nodeClass)
+				isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.NodeClassDrift))
+			})
 			DescribeTable("should not return drifted if dynamic fields are updated",
 				func(changes v1beta1.EC2NodeClass) {
 					ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -957,13 +1245,52 @@ var _ = Describe("CloudProvider", func() {
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod1, pod2)
 			ExpectScheduled(ctx, env.Client, pod1)
 			ExpectScheduled(ctx, env.Client, pod2)
-			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
-			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-2"))
-			// Provision for another pod that should now use the other subnet since we've consumed some from the first launch.
+			// The first launch consumes an IP from test-subnet-2, which leaves both subnets with 10 available IPs, so the
+			// second launch goes to the subnet with the lower ID
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(2))
+			Expect(fake.SubnetsFromFleetRequest(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop())).To(ConsistOf("test-subnet-1"))
+			Expect(fake.SubnetsFromFleetRequest(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop())).To(ConsistOf("test-subnet-2"))
+			// Provision for another pod that should now use test-subnet-2 since the second launch consumed from test-subnet-1
 			pod3 := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1a"}})
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod3)
 			ExpectScheduled(ctx, env.Client, pod3)
-			createFleetInput = awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-2"))
+		})
+		It("should only launch instances into subnets in the zone IDs required by the NodePool", func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("use1-az4"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1b"), AvailabilityZoneId: aws.String("use1-az1"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
+			}})
+			nodeClass.Status.Subnets = []v1beta1.Subnet{
+				{ID: "test-subnet-1", Zone: "test-zone-1a", ZoneID: "use1-az4"},
+				{ID: "test-subnet-2", Zone: "test-zone-1b", ZoneID: "use1-az1"},
+			}
+			nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1beta1.LabelTopologyZoneID, Operator: v1.NodeSelectorOpIn, Values: []string{"use1-az1"}},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelTopologyZoneID, "use1-az1"))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-2"))
+		})
+		It("should launch instances into subnets in the zone ID selected by a pod", func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("use1-az4"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1b"), AvailabilityZoneId: aws.String("use1-az1"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
+			}})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1beta1.LabelTopologyZoneID: "use1-az4"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
 			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-1"))
 		})
 		It("should update in-flight IPs when a CreateFleet error occurs", func() {
@@ -1024,6 +1351,13 @@ var _ = Describe("CloudProvider", func() {
 			createFleetInput = awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
 			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-2"))
 		})
+		It("should publish an event when the NodeClass of a NodePool selects 0 subnets", func() {
+			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "nothing"}}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			_, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(instancetype.IsNoSubnetZones(err)).To(BeTrue())
+			Expect(awsEnv.EventRecorder.Calls("NoSubnetZones")).To(Equal(1))
+		})
 		It("should launch instances with an alternate NodePool when a NodeClass selects 0 subnets, security groups, or amis", func() {
 			misconfiguredNodeClass := test.EC2NodeClass(v1beta1.EC2NodeClass{
 				Spec: v1beta1.EC2NodeClassSpec{
@@ -1064,6 +1398,210 @@ var _ = Describe("CloudProvider", func() {
 			ExpectScheduled(ctx, env.Client, pod)
 		})
 	})
+	Context("Termination Behavior", func() {
+		It("should terminate the instance by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			// Deletion is driven from the NodeClaim in the API server, which references its EC2NodeClass
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(awsEnv.EC2API.StopInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should stop the instance when the EC2NodeClass sets the Stop termination behavior", func() {
+			nodeClass.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehaviorStop)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+			Expect(awsEnv.EC2API.StopInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+
+			id, err := utils.ParseInstanceID(cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			raw, ok := awsEnv.EC2API.Instances.Load(id)
+			Expect(ok).To(BeTrue())
+			instance := raw.(*ec2.Instance)
+			Expect(aws.StringValue(instance.State.Name)).To(Equal(ec2.InstanceStateNameStopping))
+			Expect(instance.Tags).To(ContainElement(&ec2.Tag{Key: aws.String(v1beta1.TagTerminationBehavior), Value: aws.String(string(v1beta1.TerminationBehaviorStop))}))
+
+			// Stopped instances are no longer considered to be managed by Karpenter
+			_, err = cloudProvider.Get(ctx, cloudProviderNodeClaim.Status.ProviderID)
+			Expect(corecloudproivder.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			nodeClaims, err := cloudProvider.List(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeClaims).To(BeEmpty())
+		})
+		It("should terminate spot instances when the EC2NodeClass sets the Stop termination behavior", func() {
+			nodeClass.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehaviorStop)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{corev1beta1.CapacityTypeLabelKey: corev1beta1.CapacityTypeSpot})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(awsEnv.EC2API.StopInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should keep managing the instance when it can't be stopped", func() {
+			nodeClass.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehaviorStop)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			awsEnv.EC2API.StopInstancesBehavior.Error.Set(awserr.New("UnsupportedOperation", "The instance can't be stopped.", nil))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+
+			_, err = cloudProvider.Get(ctx, cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should terminate the instance when the EC2NodeClass no longer exists", func() {
+			nodeClass.Spec.TerminationBehavior = lo.ToPtr(v1beta1.TerminationBehaviorStop)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectDeleted(ctx, env.Client, nodeClass)
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(awsEnv.EC2API.StopInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
+	Context("NodeClass Migration", func() {
+		var targetNodeClass *v1beta1.EC2NodeClass
+		// launch creates NodeClaims with fixed UIDs and returns the EC2NodeClass that each was launched from
+		launch := func(count int) []string {
+			GinkgoHelper()
+			var names []string
+			for i := 0; i < count; i++ {
+				nc := nodeClaim.DeepCopy()
+				nc.UID = types.UID(fmt.Sprintf("nodeclaim-%d", i))
+				cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nc)
+				Expect(err).ToNot(HaveOccurred())
+				names = append(names, cloudProviderNodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass])
+			}
+			return names
+		}
+		BeforeEach(func() {
+			targetNodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AMIFamily: lo.ToPtr(v1beta1.AMIFamilyBottlerocket)}})
+			nodePool.Annotations = lo.Assign(nodePool.Annotations, map[string]string{
+				v1beta1.AnnotationMigrationNodeClass:  targetNodeClass.Name,
+				v1beta1.AnnotationMigrationPercentage: "30",
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, targetNodeClass)
+		})
+		It("should record the EC2NodeClass that NodeClaims are launched from without a migration", func() {
+			delete(nodePool.Annotations, v1beta1.AnnotationMigrationNodeClass)
+			ExpectApplied(ctx, env.Client, nodePool)
+			Expect(launch(10)).To(HaveEach(nodeClass.Name))
+		})
+		It("should split launches between the EC2NodeClasses by percentage", func() {
+			names := launch(100)
+			targetCount := lo.Count(names, targetNodeClass.Name)
+			Expect(targetCount + lo.Count(names, nodeClass.Name)).To(Equal(100))
+			Expect(targetCount).To(BeNumerically("~", 30, 15))
+
+			// The split is deterministic, so the same NodeClaims launch from the same EC2NodeClasses
+			Expect(launch(100)).To(Equal(names))
+		})
+		It("should only move launches to the target when the percentage is raised", func() {
+			before := launch(100)
+			nodePool.Annotations[v1beta1.AnnotationMigrationPercentage] = "60"
+			ExpectApplied(ctx, env.Client, nodePool)
+			after := launch(100)
+			Expect(lo.Count(after, targetNodeClass.Name)).To(BeNumerically(">", lo.Count(before, targetNodeClass.Name)))
+			for i := range before {
+				if before[i] == targetNodeClass.Name {
+					Expect(after[i]).To(Equal(targetNodeClass.Name))
+				}
+			}
+		})
+		It("should launch every NodeClaim from the target once the percentage reaches 100", func() {
+			nodePool.Annotations[v1beta1.AnnotationMigrationPercentage] = "100"
+			ExpectApplied(ctx, env.Client, nodePool)
+			Expect(launch(20)).To(HaveEach(targetNodeClass.Name))
+			awsEnv.EC2API.Instances.Range(func(_, v any) bool {
+				Expect(v.(*ec2.Instance).Tags).To(ContainElement(&ec2.Tag{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String(targetNodeClass.Name)}))
+				return true
+			})
+		})
+		It("should fail to launch when the percentage is invalid", func() {
+			nodePool.Annotations[v1beta1.AnnotationMigrationPercentage] = "150"
+			ExpectApplied(ctx, env.Client, nodePool)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+		})
+	})
+	Context("Node Naming", func() {
+		It("should get instances without a private DNS name when nodes are named from a template", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeNameConvention: lo.ToPtr("template")}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+
+			id, err := utils.ParseInstanceID(cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			raw, ok := awsEnv.EC2API.Instances.Load(id)
+			Expect(ok).To(BeTrue())
+			raw.(*ec2.Instance).PrivateDnsName = nil
+
+			nc, err := cloudProvider.Get(ctx, cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nc.Status.ProviderID).To(Equal(cloudProviderNodeClaim.Status.ProviderID))
+			Expect(nc.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, cloudProviderNodeClaim.Labels[v1.LabelInstanceTypeStable]))
+		})
+	})
+	Context("Extra Node Labels", func() {
+		It("should render extra node labels from the launched instance", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ExtraNodeLabels: map[string]string{
+				"myorg.io/asset-id":      "{{ .Region }}.{{ .InstanceID }}",
+				"myorg.io/instance-type": "{{ .InstanceType }}",
+			}}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			id, err := utils.ParseInstanceID(cloudProviderNodeClaim.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue("myorg.io/asset-id", fmt.Sprintf("%s.%s", fake.DefaultRegion, id)))
+			Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue("myorg.io/instance-type", cloudProviderNodeClaim.Labels[v1.LabelInstanceTypeStable]))
+		})
+		It("should leave out extra node labels that don't render a valid label value", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ExtraNodeLabels: map[string]string{
+				"myorg.io/asset-id": "{{ .Region }}.{{ .InstanceID }}",
+				// Five instance IDs are longer than the 63 characters that a label value can have
+				"myorg.io/too-long": "{{ .InstanceID }}{{ .InstanceID }}{{ .InstanceID }}{{ .InstanceID }}{{ .InstanceID }}",
+			}}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Labels).To(HaveKey("myorg.io/asset-id"))
+			Expect(cloudProviderNodeClaim.Labels).ToNot(HaveKey("myorg.io/too-long"))
+		})
+	})
+	Context("Provider ID", func() {
+		It("should match the golden provider ID format", func() {
+			awsEnv.EC2API.Instances.Store("i-0123456789abcdef0", &ec2.Instance{
+				State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				PrivateDnsName: aws.String(fake.PrivateDNSName()),
+				Placement:      &ec2.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+				InstanceId:     aws.String("i-0123456789abcdef0"),
+				InstanceType:   aws.String("m5.large"),
+			})
+			golden, err := os.ReadFile("testdata/provider_id.golden")
+			Expect(err).ToNot(HaveOccurred())
+			providerID := strings.TrimSpace(string(golden))
+
+			nc, err := cloudProvider.Get(ctx, providerID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nc.Status.ProviderID).To(Equal(providerID))
+			id, err := utils.ParseInstanceID(nc.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("i-0123456789abcdef0"))
+		})
+	})
 	Context("EFA", func() {
 		It("should include vpc.amazonaws.com/efa on a nodeclaim if it requests it", func() {
 			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
@@ -1098,3 +1636,18 @@ var _ = Describe("CloudProvider", func() {
 		})
 	})
 })
+
+// deletingLaunchTemplateProvider deletes the NodeClaim once launch templates have been ensured, and waits for the launch
+// to be canceled
+type deletingLaunchTemplateProvider struct {
+	launchtemplate.Provider
+	nodeClaim *corev1beta1.NodeClaim
+}
+
+func (d *deletingLaunchTemplateProvider) EnsureAll(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim,
+	instanceTypes []*corecloudproivder.InstanceType, capacityType string, tags map[string]string) ([]*launchtemplate.LaunchTemplate, error) {
+	launchTemplates, err := d.Provider.EnsureAll(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
+	ExpectDeleted(ctx, env.Client, d.nodeClaim)
+	Eventually(ctx.Done()).WithTimeout(5 * time.Second).Should(BeClosed())
+	return launchTemplates, err
+}
@@ -23,11 +23,13 @@ import (
 	nodeclassstatus "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
 	nodeclasstermination "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/termination"
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/pricing"
+	controllersspotadvisor "github.com/aws/karpenter-provider-aws/pkg/controllers/spotadvisor"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	servicesqs "github.com/aws/aws-sdk-go/service/sqs"
-	"github.com/samber/lo"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,36 +37,75 @@ import (
 	"sigs.k8s.io/karpenter/pkg/operator/controller"
 
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	controllersdependencies "github.com/aws/karpenter-provider-aws/pkg/controllers/dependencies"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
+	launchtemplategarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/launchtemplate/garbagecollection"
+	nodeclusterautoscaler "github.com/aws/karpenter-provider-aws/pkg/controllers/node/clusterautoscaler"
 	nodeclaimgarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/garbagecollection"
 	nodeclaimtagging "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/tagging"
+	nodepoolcostlimit "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/costlimit"
+	snapshotgarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/snapshot/garbagecollection"
+	"github.com/aws/karpenter-provider-aws/pkg/metricsexporter"
+	"github.com/aws/karpenter-provider-aws/pkg/operator"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 )
 
-func NewControllers(ctx context.Context, sess *session.Session, clk clock.Clock, kubeClient client.Client, recorder events.Recorder,
+func NewControllers(ctx context.Context, sess *session.Session, ec2api ec2iface.EC2API, clk clock.Clock, kubeClient client.Client, recorder events.Recorder,
 	unavailableOfferings *cache.UnavailableOfferings, cloudProvider cloudprovider.CloudProvider, subnetProvider subnet.Provider,
 	securityGroupProvider securitygroup.Provider, instanceProfileProvider instanceprofile.Provider, instanceProvider instance.Provider,
-	pricingProvider pricing.Provider, amiProvider amifamily.Provider, launchTemplateProvider launchtemplate.Provider) []controller.Controller {
+	pricingProvider pricing.Provider, spotAdvisorProvider spotadvisor.Provider, costLimitProvider costlimit.Provider, amiProvider amifamily.Provider, launchTemplateProvider launchtemplate.Provider,
+	instanceTypeProvider instancetype.Provider, capacityReservationProvider capacityreservation.Provider, metricsExporter *metricsexporter.Exporter,
+	dependencies *operator.Dependencies) []controller.Controller {
 
+	// The queue URL is resolved by the provider so that an unreachable queue degrades interruption handling rather than
+	// failing startup
+	var sqsProvider sqs.Provider
+	if options.FromContext(ctx).InterruptionQueue != "" {
+		sqsProvider = sqs.NewDefaultProviderForQueue(servicesqs.New(sess), options.FromContext(ctx).InterruptionQueue)
+	}
 	controllers := []controller.Controller{
 		nodeclasshash.NewController(kubeClient),
-		nodeclassstatus.NewController(kubeClient, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider),
+		nodeclassstatus.NewController(kubeClient, clk, recorder, ec2api, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider,
+			instanceTypeProvider, pricingProvider, capacityReservationProvider, sqsProvider),
 		nodeclasstermination.NewController(kubeClient, recorder, instanceProfileProvider, launchTemplateProvider),
-		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider),
+		nodeclaimgarbagecollection.NewController(kubeClient, clk, cloudProvider, instanceProvider),
 		nodeclaimtagging.NewController(kubeClient, instanceProvider),
-		controllerspricing.NewController(pricingProvider),
+		nodeclaimtagging.NewRepairController(kubeClient, ec2api),
+		controllerspricing.NewController(clk, pricingProvider),
+		nodepoolcostlimit.NewController(kubeClient, instanceProvider, costLimitProvider),
+		controllersdependencies.NewController(dependencies),
 	}
-	if options.FromContext(ctx).InterruptionQueue != "" {
-		sqsapi := servicesqs.New(sess)
-		out := lo.Must(sqsapi.GetQueueUrlWithContext(ctx, &servicesqs.GetQueueUrlInput{QueueName: lo.ToPtr(options.FromContext(ctx).InterruptionQueue)}))
-		controllers = append(controllers, interruption.NewController(kubeClient, clk, recorder, lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl))), unavailableOfferings))
+	if options.FromContext(ctx).PricingOverrideFile != "" {
+		controllers = append(controllers, controllerspricing.NewOverridesController(pricingProvider))
+	}
+	if options.FromContext(ctx).ClusterAutoscalerCompatibility {
+		controllers = append(controllers, nodeclusterautoscaler.NewController(kubeClient, recorder))
+	}
+	if options.FromContext(ctx).SnapshotGC {
+		controllers = append(controllers, snapshotgarbagecollection.NewController(clk, kubeClient, ec2api))
+	}
+	if options.FromContext(ctx).LaunchTemplateGCGracePeriod > 0 {
+		controllers = append(controllers, launchtemplategarbagecollection.NewController(clk, kubeClient, ec2api, launchTemplateProvider))
+	}
+	if options.FromContext(ctx).CloudWatchMetricsNamespace != "" {
+		controllers = append(controllers, metricsexporter.NewController(metricsExporter))
+	}
+	// The spot advisor data is a public feed rather than an AWS API with a VPC endpoint, so isolated VPCs can't fetch it
+	if options.FromContext(ctx).SpotInterruptionPenalty > 0 && !options.FromContext(ctx).IsolatedVPC {
+		controllers = append(controllers, controllersspotadvisor.NewController(spotAdvisorProvider))
+	}
+	if sqsProvider != nil {
+		controllers = append(controllers, interruption.NewController(kubeClient, clk, recorder, sqsProvider, unavailableOfferings))
 	}
 	return controllers
 }
@@ -17,6 +17,8 @@ package interruption
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	sqsapi "github.com/aws/aws-sdk-go/service/sqs"
@@ -39,6 +41,7 @@ import (
 	interruptionevents "github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/events"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/statechange"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
@@ -49,6 +52,7 @@ import (
 type Action string
 
 const (
+	Cordon         Action = "Cordon"
 	CordonAndDrain Action = "CordonAndDrain"
 	NoAction       Action = "NoAction"
 )
@@ -89,6 +93,7 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("getting messages from queue, %w", err)
 	}
+	c.updateQueueDepth(ctx)
 	if len(sqsMessages) == 0 {
 		return reconcile.Result{}, nil
 	}
@@ -101,20 +106,27 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 		return reconcile.Result{}, fmt.Errorf("making node instance id map, %w", err)
 	}
 	errs := make([]error, len(sqsMessages))
-	workqueue.ParallelizeUntil(ctx, 10, len(sqsMessages), func(i int) {
+	// Only the messages that are done with are deleted. The rest are left on the queue and received again once their
+	// visibility timeout expires.
+	done := make([]bool, len(sqsMessages))
+	// A rebalance recommendation and a spot interruption warning for the same instance can arrive in the same batch,
+	// so the NodeClaims that have been acted on are tracked to only delete each of them once
+	actioned := &sync.Map{}
+	workqueue.ParallelizeUntil(ctx, options.FromContext(ctx).InterruptionQueueParallelism, len(sqsMessages), func(i int) {
 		msg, e := c.parseMessage(sqsMessages[i])
 		if e != nil {
-			// If we fail to parse, then we should delete the message but still log the error
-			logging.FromContext(ctx).Errorf("parsing message, %v", e)
-			errs[i] = c.deleteMessage(ctx, sqsMessages[i])
+			done[i] = c.handleUnparseableMessage(ctx, sqsMessages[i], e)
 			return
 		}
-		if e = c.handleMessage(ctx, nodeClaimInstanceIDMap, nodeInstanceIDMap, msg); e != nil {
+		if e = c.handleMessage(ctx, nodeClaimInstanceIDMap, nodeInstanceIDMap, actioned, msg); e != nil {
 			errs[i] = fmt.Errorf("handling message, %w", e)
 			return
 		}
-		errs[i] = c.deleteMessage(ctx, sqsMessages[i])
+		done[i] = true
 	})
+	if err = c.deleteMessages(ctx, lo.Filter(sqsMessages, func(_ *sqsapi.Message, i int) bool { return done[i] })); err != nil {
+		errs = append(errs, err)
+	}
 	if err = multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -144,7 +156,7 @@ func (c *Controller) parseMessage(raw *sqsapi.Message) (messages.Message, error)
 
 // handleMessage takes an action against every node involved in the message that is owned by a NodePool
 func (c *Controller) handleMessage(ctx context.Context, nodeClaimInstanceIDMap map[string]*v1beta1.NodeClaim,
-	nodeInstanceIDMap map[string]*v1.Node, msg messages.Message) (err error) {
+	nodeInstanceIDMap map[string]*v1.Node, actioned *sync.Map, msg messages.Message) (err error) {
 
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("messageKind", msg.Kind()))
 	receivedMessages.WithLabelValues(string(msg.Kind())).Inc()
@@ -158,7 +170,7 @@ func (c *Controller) handleMessage(ctx context.Context, nodeClaimInstanceIDMap m
 			continue
 		}
 		node := nodeInstanceIDMap[instanceID]
-		if e := c.handleNodeClaim(ctx, msg, nodeClaim, node); e != nil {
+		if e := c.handleNodeClaim(ctx, msg, nodeClaim, node, actioned); e != nil {
 			err = multierr.Append(err, e)
 		}
 	}
@@ -169,18 +181,57 @@ func (c *Controller) handleMessage(ctx context.Context, nodeClaimInstanceIDMap m
 	return nil
 }
 
-// deleteMessage removes the passed SQS message from the queue and fires a metric for the deletion
-func (c *Controller) deleteMessage(ctx context.Context, msg *sqsapi.Message) error {
-	if err := c.sqsProvider.DeleteSQSMessage(ctx, msg); err != nil {
-		return fmt.Errorf("deleting sqs message, %w", err)
+// handleUnparseableMessage logs the parsing error and returns whether the message should be deleted. Messages that
+// can't be parsed are left on the queue in case the failure is transient, until they have been received
+// interruption-queue-max-parse-attempts times.
+func (c *Controller) handleUnparseableMessage(ctx context.Context, raw *sqsapi.Message, err error) bool {
+	logging.FromContext(ctx).Errorf("parsing message, %v", err)
+	if raw == nil {
+		return false
+	}
+	receiveCount, _ := strconv.Atoi(lo.FromPtr(raw.Attributes[sqsapi.MessageSystemAttributeNameApproximateReceiveCount]))
+	if receiveCount < options.FromContext(ctx).InterruptionQueueMaxParseAttempts {
+		return false
+	}
+	logging.FromContext(ctx).With("messageID", lo.FromPtr(raw.MessageId)).Errorf("deleting message after failing to parse it %d times", receiveCount)
+	poisonMessages.Inc()
+	return true
+}
+
+// deleteMessages removes the passed SQS messages from the queue and fires a metric for the deletions
+func (c *Controller) deleteMessages(ctx context.Context, msgs []*sqsapi.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	deleted, err := c.sqsProvider.DeleteSQSMessages(ctx, msgs)
+	deletedMessages.Add(float64(deleted))
+	if err != nil {
+		return fmt.Errorf("deleting sqs messages, %w", err)
 	}
-	deletedMessages.Inc()
 	return nil
 }
 
+// updateQueueDepth records the approximate number of messages waiting on the queue. Failing to get it doesn't stop
+// messages from being handled, so the error is only logged.
+func (c *Controller) updateQueueDepth(ctx context.Context) {
+	depth, err := c.sqsProvider.GetSQSQueueDepth(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("getting queue depth, %v", err)
+		return
+	}
+	queueDepth.Set(float64(depth))
+}
+
 // handleNodeClaim retrieves the action for the message and then performs the appropriate action against the node
-func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message, nodeClaim *v1beta1.NodeClaim, node *v1.Node) error {
-	action := actionForMessage(msg)
+func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message, nodeClaim *v1beta1.NodeClaim, node *v1.Node, actioned *sync.Map) error {
+	action := actionForMessage(ctx, msg)
+	// Only the first message to act on a NodeClaim deletes it. Later messages, like the spot interruption warning that
+	// follows a rebalance recommendation, are still recorded.
+	if action == CordonAndDrain {
+		if _, loaded := actioned.LoadOrStore(nodeClaim.Name, struct{}{}); loaded {
+			action = NoAction
+		}
+	}
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("nodeclaim", nodeClaim.Name, "action", string(action)))
 	if node != nil {
 		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("node", node.Name))
@@ -198,9 +249,42 @@ func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message,
 			c.unavailableOfferingsCache.MarkUnavailable(ctx, string(msg.Kind()), instanceType, zone, v1beta1.CapacityTypeSpot)
 		}
 	}
-	if action != NoAction {
+	// Keep immediate re-launches out of the spot pool that EC2 recommended rebalancing away from
+	if msg.Kind() == messages.RebalanceRecommendationKind && action != NoAction {
+		zone := nodeClaim.Labels[v1.LabelTopologyZone]
+		instanceType := nodeClaim.Labels[v1.LabelInstanceTypeStable]
+		if zone != "" && instanceType != "" {
+			c.unavailableOfferingsCache.MarkRisky(ctx, string(msg.Kind()), instanceType, zone, v1beta1.CapacityTypeSpot)
+		}
+	}
+	switch action {
+	case Cordon:
+		return c.cordonNode(ctx, msg, nodeClaim, node)
+	case CordonAndDrain:
 		return c.deleteNodeClaim(ctx, nodeClaim, node)
+	default:
+		return nil
+	}
+}
+
+// cordonNode marks the node as unschedulable without draining it. A NodeClaim that hasn't registered a node yet is
+// left alone.
+func (c *Controller) cordonNode(ctx context.Context, msg messages.Message, nodeClaim *v1beta1.NodeClaim, node *v1.Node) error {
+	if node == nil || node.Spec.Unschedulable || !node.DeletionTimestamp.IsZero() {
+		return nil
 	}
+	stored := node.DeepCopy()
+	node = node.DeepCopy()
+	node.Spec.Unschedulable = true
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("cordoning the node on interruption message, %w", err))
+	}
+	logging.FromContext(ctx).Infof("cordoned node from interruption message")
+	c.recorder.Publish(interruptionevents.CordonedOnRebalanceRecommendation(node, nodeClaim)...)
+	nodesCordoned.With(prometheus.Labels{
+		actionTypeLabel:  string(Cordon),
+		messageTypeLabel: string(msg.Kind()),
+	}).Inc()
 	return nil
 }
 
@@ -288,11 +372,26 @@ func (c *Controller) makeNodeInstanceIDMap(ctx context.Context) (map[string]*v1.
 	return m, nil
 }
 
-func actionForMessage(msg messages.Message) Action {
+func actionForMessage(ctx context.Context, msg messages.Message) Action {
 	switch msg.Kind() {
 	case messages.ScheduledChangeKind, messages.SpotInterruptionKind, messages.StateChangeKind:
 		return CordonAndDrain
+	case messages.RebalanceRecommendationKind:
+		return rebalanceAction(ctx)
 	default:
 		return NoAction
 	}
 }
+
+// rebalanceAction returns the action for a rebalance recommendation. The deprecated rebalance-recommendations option
+// still cordons and drains when interruption-rebalance-action is left as Ignore.
+func rebalanceAction(ctx context.Context) Action {
+	switch options.FromContext(ctx).InterruptionRebalanceAction {
+	case options.InterruptionRebalanceActionCordon:
+		return Cordon
+	case options.InterruptionRebalanceActionCordonAndDrain:
+		return CordonAndDrain
+	default:
+		return lo.Ternary(options.FromContext(ctx).RebalanceRecommendations, CordonAndDrain, NoAction)
+	}
+}
@@ -61,6 +61,24 @@ func RebalanceRecommendation(node *v1.Node, nodeClaim *v1beta1.NodeClaim) (evts
 	return evts
 }
 
+func CordonedOnRebalanceRecommendation(node *v1.Node, nodeClaim *v1beta1.NodeClaim) (evts []events.Event) {
+	evts = append(evts, events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "CordonedOnRebalanceRecommendation",
+		Message:        "Spot rebalance recommendation cordoned the Node",
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	})
+	evts = append(evts, events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeNormal,
+		Reason:         "CordonedOnRebalanceRecommendation",
+		Message:        "Spot rebalance recommendation cordoned the Node",
+		DedupeValues:   []string{string(node.UID)},
+	})
+	return evts
+}
+
 func Stopping(node *v1.Node, nodeClaim *v1beta1.NodeClaim) (evts []events.Event) {
 	evts = append(evts, events.Event{
 		InvolvedObject: nodeClaim,
@@ -15,6 +15,10 @@ limitations under the License.
 package scheduledchange
 
 import (
+	"strings"
+
+	"github.com/samber/lo"
+
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 )
 
@@ -26,12 +30,12 @@ type Message struct {
 	Detail Detail `json:"detail"`
 }
 
+// EC2InstanceIDs returns the instances affected by the event. Health events can also list entities
+// that aren't instances, like dedicated hosts or volumes, which are skipped.
 func (m Message) EC2InstanceIDs() []string {
-	ids := make([]string, len(m.Detail.AffectedEntities))
-	for i, entity := range m.Detail.AffectedEntities {
-		ids[i] = entity.EntityValue
-	}
-	return ids
+	return lo.FilterMap(m.Detail.AffectedEntities, func(entity AffectedEntity, _ int) (string, bool) {
+		return entity.EntityValue, strings.HasPrefix(entity.EntityValue, "i-")
+	})
 }
 
 func (Message) Kind() messages.Kind {
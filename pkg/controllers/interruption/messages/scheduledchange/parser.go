@@ -18,12 +18,23 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 )
 
 const (
-	acceptedService           = "EC2"
-	acceptedEventTypeCategory = "scheduledChange"
+	acceptedService                  = "EC2"
+	scheduledChangeEventTypeCategory = "scheduledChange"
+	issueEventTypeCategory           = "issue"
+)
+
+// acceptedIssueEventTypeCodes are the AWS Health issues that mean an instance is running on degraded hardware
+// or is going to be retired. Other issues, like service-wide degradations, don't call for replacing instances.
+var acceptedIssueEventTypeCodes = sets.New(
+	"AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED",
+	"AWS_EC2_PERSISTENT_INSTANCE_RETIREMENT_SCHEDULED",
+	"AWS_EC2_INSTANCE_STORE_DRIVE_PERFORMANCE_DEGRADED",
 )
 
 type Parser struct{}
@@ -35,11 +46,18 @@ func (p Parser) Parse(raw string) (messages.Message, error) {
 	}
 
 	// We ignore services and event categories that we don't watch
-	if msg.Detail.Service != acceptedService ||
-		msg.Detail.EventTypeCategory != acceptedEventTypeCategory {
+	if msg.Detail.Service != acceptedService {
 		return nil, nil
 	}
-	return msg, nil
+	switch msg.Detail.EventTypeCategory {
+	case scheduledChangeEventTypeCategory:
+		return msg, nil
+	case issueEventTypeCategory:
+		if acceptedIssueEventTypeCodes.Has(msg.Detail.EventTypeCode) {
+			return msg, nil
+		}
+	}
+	return nil, nil
 }
 
 func (p Parser) Version() string {
@@ -46,6 +46,22 @@ var (
 			Help:      "Count of messages deleted from the SQS queue.",
 		},
 	)
+	poisonMessages = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "poison_messages",
+			Help:      "Count of messages deleted from the SQS queue without being handled because they repeatedly failed to parse.",
+		},
+	)
+	queueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "queue_depth",
+			Help:      "Approximate number of messages on the SQS queue that are waiting to be received.",
+		},
+	)
 	messageLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: metrics.Namespace,
@@ -64,8 +80,17 @@ var (
 		},
 		[]string{actionTypeLabel},
 	)
+	nodesCordoned = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "nodes_cordoned",
+			Help:      "Number of nodes cordoned in response to interruption messages. Labeled by action and message type.",
+		},
+		[]string{actionTypeLabel, messageTypeLabel},
+	)
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(receivedMessages, deletedMessages, messageLatency, actionsPerformed)
+	crmetrics.Registry.MustRegister(receivedMessages, deletedMessages, poisonMessages, queueDepth, messageLatency, actionsPerformed, nodesCordoned)
 }
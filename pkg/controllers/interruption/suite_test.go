@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -44,11 +45,14 @@ import (
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/rebalancerecommendation"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/scheduledchange"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/spotinterruption"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/statechange"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -92,6 +96,7 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
 	unavailableOfferingsCache.Flush()
 	sqsapi.Reset()
 })
@@ -123,7 +128,7 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
 			ExpectNotFound(ctx, env.Client, nodeClaim)
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(deletedMessageCount()).To(Equal(1))
 		})
 		It("should delete the NodeClaim when receiving a scheduled change message", func() {
 			ExpectMessagesCreated(scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
@@ -132,7 +137,79 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
 			ExpectNotFound(ctx, env.Client, nodeClaim)
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should delete the NodeClaim when receiving an AWS Health instance retirement event", func() {
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			// Sample payload of an EC2 instance retirement event delivered by EventBridge
+			ExpectMessagesCreated(json.RawMessage(fmt.Sprintf(`{
+	"version": "0",
+	"id": "7bf73129-1428-4cd3-a780-95db273d1602",
+	"detail-type": "AWS Health Event",
+	"source": "aws.health",
+	"account": "%s",
+	"time": "2023-01-27T01:00:00Z",
+	"region": "%s",
+	"resources": ["%s"],
+	"detail": {
+		"eventArn": "arn:aws:health:%s::event/EC2/AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED/AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED_ABC123",
+		"service": "EC2",
+		"eventTypeCode": "AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED",
+		"eventTypeCategory": "scheduledChange",
+		"startTime": "Sat, 11 Feb 2023 01:00:00 GMT",
+		"endTime": "Sat, 11 Feb 2023 02:00:00 GMT",
+		"eventDescription": [{
+			"language": "en_US",
+			"latestDescription": "EC2 has detected degradation of the underlying hardware hosting your Amazon EC2 instance."
+		}],
+		"affectedEntities": [{"entityValue": "%s"}]
+	}
+}`, defaultAccountID, fake.DefaultRegion, instanceID, fake.DefaultRegion, instanceID)))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should delete the NodeClaim when receiving an AWS Health issue for degraded instance hardware", func() {
+			msg := scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			msg.Detail.EventTypeCategory = "issue"
+			msg.Detail.EventTypeCode = "AWS_EC2_INSTANCE_STORE_DRIVE_PERFORMANCE_DEGRADED"
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should not delete the NodeClaim when receiving an AWS Health issue that isn't about instance hardware", func() {
+			msg := scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			msg.Detail.EventTypeCategory = "issue"
+			msg.Detail.EventTypeCode = "AWS_EC2_OPERATIONAL_ISSUE"
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should ignore unknown event types while handling the rest of the batch", func() {
+			unknownHealthEvent := scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			unknownHealthEvent.Detail.EventTypeCategory = "accountNotification"
+			unknownEvent := scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			unknownEvent.DetailType = "Some Unknown Event"
+			ExpectMessagesCreated(unknownHealthEvent, unknownEvent, scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(3))
+		})
+		It("should only return instances from the entities affected by an AWS Health event", func() {
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			msg := scheduledChangeMessage(instanceID)
+			msg.Detail.AffectedEntities = append(msg.Detail.AffectedEntities, scheduledchange.AffectedEntity{EntityValue: "h-0123456789abcdef0"})
+			Expect(msg.EC2InstanceIDs()).To(ConsistOf(instanceID))
 		})
 		It("should delete the NodeClaim when receiving a state change message", func() {
 			var nodeClaims []*corev1beta1.NodeClaim
@@ -157,7 +234,7 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
 			ExpectNotFound(ctx, env.Client, lo.Map(nodeClaims, func(nc *corev1beta1.NodeClaim, _ int) client.Object { return nc })...)
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(4))
+			Expect(deletedMessageCount()).To(Equal(4))
 		})
 		It("should handle multiple messages that cause nodeClaim deletion", func() {
 			var nodeClaims []*corev1beta1.NodeClaim
@@ -187,22 +264,80 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
 			ExpectNotFound(ctx, env.Client, lo.Map(nodeClaims, func(nc *corev1beta1.NodeClaim, _ int) client.Object { return nc })...)
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(100))
+			Expect(deletedMessageCount()).To(Equal(100))
 		})
-		It("should delete a message when the message can't be parsed", func() {
-			badMessage := &servicesqs.Message{
-				Body: aws.String(string(lo.Must(json.Marshal(map[string]string{
-					"field1": "value1",
-					"field2": "value2",
-				})))),
-				MessageId: aws.String(string(uuid.NewUUID())),
-			}
-
-			ExpectMessagesCreated(badMessage)
+		It("should leave a message on the queue when the message can't be parsed", func() {
+			ExpectRawMessagesCreated(unparseableMessage(1))
 
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(sqsapi.DeleteMessageBatchBehavior.Calls()).To(Equal(0))
+		})
+		It("should delete a message that has failed to parse the maximum number of times", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueueMaxParseAttempts: lo.ToPtr(2)}))
+			poisoned := poisonMessageCount()
+			ExpectRawMessagesCreated(unparseableMessage(2))
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(deletedMessageCount()).To(Equal(1))
+			Expect(poisonMessageCount()).To(Equal(poisoned + 1))
+		})
+		It("should only delete the handled messages when another message in the batch can't be parsed", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			handled := rawMessage(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectRawMessagesCreated(unparseableMessage(1), handled)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.DeleteMessageBatchBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := sqsapi.DeleteMessageBatchBehavior.CalledWithInput.Pop()
+			Expect(input.Entries).To(HaveLen(1))
+			Expect(input.Entries[0].ReceiptHandle).To(Equal(handled.ReceiptHandle))
+		})
+		It("should delete every handled message in batches of 10", func() {
+			var messages []interface{}
+			for i := 0; i < 25; i++ {
+				messages = append(messages, spotInterruptionMessage(fake.InstanceID()))
+			}
+			ExpectMessagesCreated(messages...)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(sqsapi.DeleteMessageBatchBehavior.Calls()).To(Equal(3))
+			Expect(deletedMessageCount()).To(Equal(25))
+		})
+		It("should return an error when some messages in the batch fail to delete", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))), spotInterruptionMessage(fake.InstanceID()))
+			sqsapi.DeleteMessageBatchBehavior.Output.Set(&servicesqs.DeleteMessageBatchOutput{
+				Successful: []*servicesqs.DeleteMessageBatchResultEntry{{Id: aws.String("0")}},
+				Failed:     []*servicesqs.BatchResultErrorEntry{{Id: aws.String("1"), Code: aws.String("ReceiptHandleIsInvalid"), SenderFault: aws.Bool(true)}},
+			})
+
+			ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should handle no more messages at once than the configured parallelism", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueueParallelism: lo.ToPtr(2)}))
+			trackingClient := &concurrencyTrackingClient{Client: env.Client}
+			trackingController := interruption.NewController(trackingClient, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, unavailableOfferingsCache)
+			var nodeClaims []*corev1beta1.NodeClaim
+			var messages []interface{}
+			for i := 0; i < 10; i++ {
+				instanceID := fake.InstanceID()
+				nc, n := coretest.NodeClaimAndNode(corev1beta1.NodeClaim{
+					Status: corev1beta1.NodeClaimStatus{
+						ProviderID: fake.ProviderID(instanceID),
+					},
+				})
+				ExpectApplied(ctx, env.Client, nc, n)
+				nodeClaims = append(nodeClaims, nc)
+				messages = append(messages, spotInterruptionMessage(instanceID))
+			}
+			ExpectMessagesCreated(messages...)
+
+			ExpectReconcileSucceeded(ctx, trackingController, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, lo.Map(nodeClaims, func(nc *corev1beta1.NodeClaim, _ int) client.Object { return nc })...)
+			Expect(trackingClient.maxInFlight.Load()).To(BeEquivalentTo(2))
 		})
 		It("should delete a state change message when the state isn't in accepted states", func() {
 			ExpectMessagesCreated(stateChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)), "creating"))
@@ -211,7 +346,113 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
 			ExpectExists(ctx, env.Client, nodeClaim)
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should not delete the NodeClaim when receiving a rebalance recommendation by default", func() {
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should not cordon the node or mark the offering when receiving a rebalance recommendation by default", func() {
+			nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{
+				v1.LabelTopologyZone:       "coretest-zone-1a",
+				v1.LabelInstanceTypeStable: "t3.large",
+			})
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			cordoned := cordonedNodeCount()
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(ExpectExists(ctx, env.Client, node).Spec.Unschedulable).To(BeFalse())
+			Expect(cordonedNodeCount()).To(Equal(cordoned))
+			Expect(unavailableOfferingsCache.IsUnavailable("t3.large", "coretest-zone-1a", corev1beta1.CapacityTypeSpot)).To(BeFalse())
+		})
+		It("should cordon the node and mark the offering as risky when receiving a rebalance recommendation with the Cordon action", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionRebalanceAction: lo.ToPtr("Cordon")}))
+			nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{
+				v1.LabelTopologyZone:       "coretest-zone-1a",
+				v1.LabelInstanceTypeStable: "t3.large",
+			})
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			cordoned := cordonedNodeCount()
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(deletedMessageCount()).To(Equal(1))
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).DeletionTimestamp.IsZero()).To(BeTrue())
+			Expect(ExpectExists(ctx, env.Client, node).Spec.Unschedulable).To(BeTrue())
+			Expect(cordonedNodeCount()).To(Equal(cordoned + 1))
+			until, ok := unavailableOfferingsCache.UnavailableUntil("t3.large", "coretest-zone-1a", corev1beta1.CapacityTypeSpot)
+			Expect(ok).To(BeTrue())
+			Expect(until).To(BeTemporally("~", time.Now().Add(awscache.RiskyOfferingTTL), 10*time.Second))
+		})
+		It("should still delete a NodeClaim cordoned by a rebalance recommendation on a spot interruption warning", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionRebalanceAction: lo.ToPtr("Cordon")}))
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			ExpectMessagesCreated(rebalanceRecommendationMessage(instanceID), spotInterruptionMessage(instanceID))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(deletedMessageCount()).To(Equal(2))
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should delete the NodeClaim when receiving a rebalance recommendation with the CordonAndDrain action", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionRebalanceAction: lo.ToPtr("CordonAndDrain")}))
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should delete the NodeClaim when receiving a rebalance recommendation with rebalance recommendations enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RebalanceRecommendations: lo.ToPtr(true)}))
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(deletedMessageCount()).To(Equal(1))
+		})
+		It("should only act once when a rebalance recommendation and a spot interruption warning arrive together", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RebalanceRecommendations: lo.ToPtr(true)}))
+			// The finalizer keeps the NodeClaim around after the first deletion, as it would be while draining
+			nodeClaim.Finalizers = []string{corev1beta1.TerminationFinalizer}
+			nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{
+				v1.LabelTopologyZone:             "coretest-zone-1a",
+				v1.LabelInstanceTypeStable:       "t3.large",
+				corev1beta1.CapacityTypeLabelKey: corev1beta1.CapacityTypeSpot,
+			})
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			ExpectMessagesCreated(rebalanceRecommendationMessage(instanceID), spotInterruptionMessage(instanceID))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			terminated := terminatedNodeClaims(nodeClaim)
+
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(deletedMessageCount()).To(Equal(2))
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).DeletionTimestamp.IsZero()).To(BeFalse())
+			Expect(terminatedNodeClaims(nodeClaim) - terminated).To(BeNumerically("==", 1))
+			// The spot interruption warning still marks the offering as unavailable
+			Expect(unavailableOfferingsCache.IsUnavailable("t3.large", "coretest-zone-1a", corev1beta1.CapacityTypeSpot)).To(BeTrue())
+		})
+		It("should not act again on a spot interruption warning for a NodeClaim deleted by a rebalance recommendation", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RebalanceRecommendations: lo.ToPtr(true)}))
+			nodeClaim.Finalizers = []string{corev1beta1.TerminationFinalizer}
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			ExpectMessagesCreated(rebalanceRecommendationMessage(instanceID))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			terminated := terminatedNodeClaims(nodeClaim)
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+
+			ExpectMessagesCreated(spotInterruptionMessage(instanceID))
+			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+			Expect(deletedMessageCount()).To(Equal(2))
+			Expect(terminatedNodeClaims(nodeClaim) - terminated).To(BeNumerically("==", 1))
 		})
 		It("should mark the ICE cache for the offering when getting a spot interruption warning", func() {
 			nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{
@@ -225,7 +466,7 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 			Expect(sqsapi.ReceiveMessageBehavior.SuccessfulCalls()).To(Equal(1))
 			ExpectNotFound(ctx, env.Client, nodeClaim)
-			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(deletedMessageCount()).To(Equal(1))
 
 			// Expect a t3.large in coretest-zone-1a to be added to the ICE cache
 			Expect(unavailableOfferingsCache.IsUnavailable("t3.large", "coretest-zone-1a", corev1beta1.CapacityTypeSpot)).To(BeTrue())
@@ -242,19 +483,48 @@ var _ = Describe("Error Handling", func() {
 		sqsapi.ReceiveMessageBehavior.Error.Set(awsErrWithCode("AccessDenied"), fake.MaxCalls(0))
 		ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
 	})
+	It("should still handle messages when the queue depth can't be retrieved", func() {
+		sqsapi.GetQueueAttributesBehavior.Error.Set(awsErrWithCode("AccessDenied"), fake.MaxCalls(0))
+		ExpectMessagesCreated(spotInterruptionMessage(fake.InstanceID()))
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+		Expect(deletedMessageCount()).To(Equal(1))
+	})
+	It("should report the approximate number of messages on the queue", func() {
+		sqsapi.GetQueueAttributesBehavior.Output.Set(&servicesqs.GetQueueAttributesOutput{
+			Attributes: map[string]*string{servicesqs.QueueAttributeNameApproximateNumberOfMessages: aws.String("42")},
+		})
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+		metric, ok := FindMetricWithLabelValues("karpenter_interruption_queue_depth", map[string]string{})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 42))
+	})
 	It("should not return an error when deleting a nodeClaim that is already deleted", func() {
 		ExpectMessagesCreated(spotInterruptionMessage(fake.InstanceID()))
 		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
 	})
+	It("should resolve the queue url once the queue can be reached", func() {
+		controller := interruption.NewController(env.Client, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqs.NewDefaultProviderForQueue(sqsapi, "test-cluster"), unavailableOfferingsCache)
+		sqsapi.GetQueueURLBehavior.Error.Set(awsErrWithCode(servicesqs.ErrCodeQueueDoesNotExist))
+		ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+		Expect(sqsapi.ReceiveMessageBehavior.Calls()).To(BeZero())
+
+		ExpectMessagesCreated(spotInterruptionMessage(fake.InstanceID()))
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+		Expect(sqsapi.GetQueueURLBehavior.SuccessfulCalls()).To(Equal(1))
+		Expect(aws.StringValue(sqsapi.ReceiveMessageBehavior.CalledWithInput.Pop().QueueUrl)).To(HaveSuffix("/Karpenter-cluster-Queue"))
+		Expect(deletedMessageCount()).To(Equal(1))
+
+		// The resolved url is reused
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+		Expect(sqsapi.GetQueueURLBehavior.SuccessfulCalls()).To(Equal(1))
+	})
 })
 
 func ExpectMessagesCreated(messages ...interface{}) {
-	raw := lo.Map(messages, func(m interface{}, _ int) *servicesqs.Message {
-		return &servicesqs.Message{
-			Body:      aws.String(string(lo.Must(json.Marshal(m)))),
-			MessageId: aws.String(string(uuid.NewUUID())),
-		}
-	})
+	ExpectRawMessagesCreated(lo.Map(messages, func(m interface{}, _ int) *servicesqs.Message { return rawMessage(m) })...)
+}
+
+func ExpectRawMessagesCreated(raw ...*servicesqs.Message) {
 	sqsapi.ReceiveMessageBehavior.Output.Set(
 		&servicesqs.ReceiveMessageOutput{
 			Messages: raw,
@@ -262,6 +532,73 @@ func ExpectMessagesCreated(messages ...interface{}) {
 	)
 }
 
+func rawMessage(m interface{}) *servicesqs.Message {
+	return &servicesqs.Message{
+		Body:          aws.String(string(lo.Must(json.Marshal(m)))),
+		MessageId:     aws.String(string(uuid.NewUUID())),
+		ReceiptHandle: aws.String(string(uuid.NewUUID())),
+	}
+}
+
+// unparseableMessage returns a message with a body that isn't valid JSON that has been received receiveCount times
+func unparseableMessage(receiveCount int) *servicesqs.Message {
+	return &servicesqs.Message{
+		Body:          aws.String(`{"field1": "value1", "field2":`),
+		MessageId:     aws.String(string(uuid.NewUUID())),
+		ReceiptHandle: aws.String(string(uuid.NewUUID())),
+		Attributes: map[string]*string{
+			servicesqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(fmt.Sprint(receiveCount)),
+		},
+	}
+}
+
+// deletedMessageCount returns the number of messages that have been deleted from the queue across every batch
+func deletedMessageCount() int {
+	count := 0
+	sqsapi.DeleteMessageBatchBehavior.CalledWithInput.ForEach(func(input *servicesqs.DeleteMessageBatchInput) {
+		count += len(input.Entries)
+	})
+	return count
+}
+
+func cordonedNodeCount() int {
+	GinkgoHelper()
+	metric, ok := FindMetricWithLabelValues("karpenter_interruption_nodes_cordoned", map[string]string{
+		"action_type":  "Cordon",
+		"message_type": string(messages.RebalanceRecommendationKind),
+	})
+	if !ok {
+		return 0
+	}
+	return int(metric.GetCounter().GetValue())
+}
+
+func poisonMessageCount() int {
+	GinkgoHelper()
+	metric, ok := FindMetricWithLabelValues("karpenter_interruption_poison_messages", map[string]string{})
+	if !ok {
+		return 0
+	}
+	return int(metric.GetCounter().GetValue())
+}
+
+// concurrencyTrackingClient records the most NodeClaim deletes that were in flight at once
+type concurrencyTrackingClient struct {
+	client.Client
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *concurrencyTrackingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for m := c.maxInFlight.Load(); n > m && !c.maxInFlight.CompareAndSwap(m, n); m = c.maxInFlight.Load() {
+	}
+	// Hold the delete open so that messages handled in parallel overlap
+	time.Sleep(50 * time.Millisecond)
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
 func awsErrWithCode(code string) awserr.Error {
 	return awserr.New(code, "", fmt.Errorf(""))
 }
@@ -287,6 +624,40 @@ func spotInterruptionMessage(involvedInstanceID string) spotinterruption.Message
 	}
 }
 
+func rebalanceRecommendationMessage(involvedInstanceID string) rebalancerecommendation.Message {
+	return rebalancerecommendation.Message{
+		Metadata: messages.Metadata{
+			Version:    "0",
+			Account:    defaultAccountID,
+			DetailType: "EC2 Instance Rebalance Recommendation",
+			ID:         string(uuid.NewUUID()),
+			Region:     fake.DefaultRegion,
+			Resources: []string{
+				fmt.Sprintf("arn:aws:ec2:%s:instance/%s", fake.DefaultRegion, involvedInstanceID),
+			},
+			Source: ec2Source,
+			Time:   time.Now(),
+		},
+		Detail: rebalancerecommendation.Detail{
+			InstanceID: involvedInstanceID,
+		},
+	}
+}
+
+// terminatedNodeClaims returns the number of NodeClaims like the passed one that have been deleted because of interruption messages
+func terminatedNodeClaims(nodeClaim *corev1beta1.NodeClaim) float64 {
+	GinkgoHelper()
+	metric, ok := FindMetricWithLabelValues("karpenter_nodeclaims_terminated", map[string]string{
+		"reason":        "interruption",
+		"nodepool":      nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+		"capacity_type": nodeClaim.Labels[corev1beta1.CapacityTypeLabelKey],
+	})
+	if !ok {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
+
 func stateChangeMessage(involvedInstanceID, state string) statechange.Message {
 	return statechange.Message{
 		Metadata: messages.Metadata{
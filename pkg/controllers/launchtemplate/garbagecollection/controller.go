@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+)
+
+const (
+	// batchSize is the maximum number of launch templates that are deleted in a single reconcile. Any remaining
+	// candidates are picked up by the next reconcile.
+	batchSize = 50
+	// deletesPerSecond and deleteBurst limit how quickly launch templates are deleted, so that clearing out a large
+	// backlog doesn't starve launches of EC2 API capacity
+	deletesPerSecond = 2
+	deleteBurst      = 5
+)
+
+// Controller deletes the launch templates that Karpenter created for this cluster once they haven't matched a live
+// EC2NodeClass for the configured grace period. Launch templates are matched to their EC2NodeClass through the
+// nodeclass tag, so launch templates without it are never matched. The time a launch template was first seen
+// unreferenced is only kept in memory, so the grace period starts over when the controller restarts.
+type Controller struct {
+	clk                    clock.Clock
+	kubeClient             client.Client
+	ec2api                 ec2iface.EC2API
+	launchTemplateProvider launchtemplate.Provider
+	limiter                *rate.Limiter
+
+	unreferencedSince map[string]time.Time
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, ec2api ec2iface.EC2API, launchTemplateProvider launchtemplate.Provider) *Controller {
+	return &Controller{
+		clk:                    clk,
+		kubeClient:             kubeClient,
+		ec2api:                 ec2api,
+		launchTemplateProvider: launchTemplateProvider,
+		limiter:                rate.NewLimiter(rate.Limit(deletesPerSecond), deleteBurst),
+		unreferencedSince:      map[string]time.Time{},
+	}
+}
+
+func (c *Controller) Name() string {
+	return "launchtemplate.garbagecollection"
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	launchTemplates, err := c.listLaunchTemplates(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing launch templates, %w", err)
+	}
+	nodeClassList := &v1beta1.EC2NodeClassList{}
+	if err = c.kubeClient.List(ctx, nodeClassList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclasses, %w", err)
+	}
+	nodeClasses := sets.New(lo.Map(nodeClassList.Items, func(nc v1beta1.EC2NodeClass, _ int) string { return nc.Name })...)
+
+	unreferenced := lo.Filter(launchTemplates, func(lt *ec2.LaunchTemplate, _ int) bool {
+		tags := tagMap(lt)
+		nodeClass, ok := tags[v1beta1.LabelNodeClass]
+		return tags[launchtemplate.KarpenterManagedTagKey] == options.FromContext(ctx).ClusterName && (!ok || !nodeClasses.Has(nodeClass))
+	})
+	c.trackUnreferenced(unreferenced)
+
+	gracePeriod := options.FromContext(ctx).LaunchTemplateGCGracePeriod
+	candidates := lo.Filter(unreferenced, func(lt *ec2.LaunchTemplate, _ int) bool {
+		name := aws.StringValue(lt.LaunchTemplateName)
+		if c.clk.Since(c.unreferencedSince[name]) < gracePeriod {
+			return false
+		}
+		// The launch template may still be referenced by a CreateFleet request that is in flight
+		lastUsed, ok := c.launchTemplateProvider.LastUsed(name)
+		return !ok || c.clk.Since(lastUsed) >= gracePeriod
+	})
+	// Delete the launch templates that have been unreferenced the longest first so that batching makes consistent progress
+	sort.Slice(candidates, func(i, j int) bool {
+		return c.unreferencedSince[aws.StringValue(candidates[i].LaunchTemplateName)].Before(c.unreferencedSince[aws.StringValue(candidates[j].LaunchTemplateName)])
+	})
+	garbageCollectionCandidates.Set(float64(len(candidates)))
+
+	var errs []error
+	for _, lt := range lo.Slice(candidates, 0, batchSize) {
+		if err = c.limiter.Wait(ctx); err != nil {
+			return reconcile.Result{}, err
+		}
+		errs = append(errs, c.garbageCollect(ctx, lt))
+	}
+	if err = multierr.Combine(errs...); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: lo.Ternary(len(candidates) > batchSize, time.Minute, 10*time.Minute)}, nil
+}
+
+// trackUnreferenced records when each launch template was first seen unreferenced, forgetting those that are
+// referenced again or no longer exist
+func (c *Controller) trackUnreferenced(unreferenced []*ec2.LaunchTemplate) {
+	names := sets.New(lo.Map(unreferenced, func(lt *ec2.LaunchTemplate, _ int) string { return aws.StringValue(lt.LaunchTemplateName) })...)
+	for name := range c.unreferencedSince {
+		if !names.Has(name) {
+			delete(c.unreferencedSince, name)
+		}
+	}
+	for name := range names {
+		if _, ok := c.unreferencedSince[name]; !ok {
+			c.unreferencedSince[name] = c.clk.Now()
+		}
+	}
+}
+
+func (c *Controller) garbageCollect(ctx context.Context, lt *ec2.LaunchTemplate) error {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("launch-template-name", aws.StringValue(lt.LaunchTemplateName), "launch-template-id", aws.StringValue(lt.LaunchTemplateId)))
+	if _, err := c.ec2api.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateName: lt.LaunchTemplateName}); awserrors.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting launch template, %w", err)
+	}
+	c.launchTemplateProvider.InvalidateCache(ctx, aws.StringValue(lt.LaunchTemplateName), aws.StringValue(lt.LaunchTemplateId))
+	delete(c.unreferencedSince, aws.StringValue(lt.LaunchTemplateName))
+	deletedLaunchTemplates.Inc()
+	logging.FromContext(ctx).Debugf("garbage collected launch template")
+	return nil
+}
+
+func (c *Controller) listLaunchTemplates(ctx context.Context) ([]*ec2.LaunchTemplate, error) {
+	var launchTemplates []*ec2.LaunchTemplate
+	if err := c.ec2api.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", launchtemplate.KarpenterManagedTagKey)),
+				Values: aws.StringSlice([]string{options.FromContext(ctx).ClusterName}),
+			},
+		},
+	}, func(page *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
+		launchTemplates = append(launchTemplates, page.LaunchTemplates...)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return launchTemplates, nil
+}
+
+// tagMap re-reads the tags on the launch template rather than trusting the DescribeLaunchTemplates filters so that a
+// launch template owned by another cluster is never deleted
+func tagMap(lt *ec2.LaunchTemplate) map[string]string {
+	return lo.SliceToMap(lt.Tags, func(t *ec2.Tag) (string, string) {
+		return aws.StringValue(t.Key), aws.StringValue(t.Value)
+	})
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Builder {
+	return controller.NewSingletonManagedBy(m)
+}
@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	launchTemplateSubsystem = "launch_templates"
+)
+
+var (
+	deletedLaunchTemplates = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: launchTemplateSubsystem,
+			Name:      "deleted",
+			Help:      "Count of launch templates deleted by launch template garbage collection.",
+		},
+	)
+	garbageCollectionCandidates = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: launchTemplateSubsystem,
+			Name:      "garbage_collection_candidates",
+			Help:      "Number of launch templates eligible for garbage collection during the last sweep.",
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(deletedLaunchTemplates, garbageCollectionCandidates)
+}
@@ -0,0 +1,190 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/karpenter/pkg/operator/scheme"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/launchtemplate/garbagecollection"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var launchTemplateProvider *usedLaunchTemplateProvider
+var garbageCollectionController *garbagecollection.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchTemplateGarbageCollection")
+}
+
+var _ = BeforeSuite(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	env = coretest.NewEnvironment(scheme.Scheme, coretest.WithCRDs(apis.CRDs...))
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		LaunchTemplateGCGracePeriod: lo.ToPtr(time.Hour),
+	}))
+	awsEnv.Reset()
+	launchTemplateProvider = &usedLaunchTemplateProvider{Provider: awsEnv.LaunchTemplateProvider, lastUsed: map[string]time.Time{}}
+	garbageCollectionController = garbagecollection.NewController(fakeClock, env.Client, awsEnv.EC2API, launchTemplateProvider)
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("LaunchTemplateGarbageCollection", func() {
+	It("should delete launch templates of deleted nodeclasses once the grace period has passed", func() {
+		lt := makeLaunchTemplate("deleted-nodeclass")
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateExists(lt)
+
+		fakeClock.Step(time.Hour)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateDeleted(lt)
+	})
+	It("should not delete launch templates before the grace period has passed", func() {
+		lt := makeLaunchTemplate("deleted-nodeclass")
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(30 * time.Minute)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateExists(lt)
+	})
+	It("should delete launch templates without a nodeclass tag", func() {
+		lt := makeLaunchTemplate("")
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(time.Hour)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateDeleted(lt)
+	})
+	It("should not delete launch templates of live nodeclasses", func() {
+		nodeClass := test.EC2NodeClass()
+		ExpectApplied(ctx, env.Client, nodeClass)
+		lt := makeLaunchTemplate(nodeClass.Name)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(time.Hour)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateExists(lt)
+	})
+	It("should not delete launch templates that were recently used for a launch", func() {
+		lt := makeLaunchTemplate("deleted-nodeclass")
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(time.Hour)
+		launchTemplateProvider.lastUsed[aws.StringValue(lt.LaunchTemplateName)] = fakeClock.Now().Add(-time.Minute)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateExists(lt)
+
+		fakeClock.Step(time.Hour)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateDeleted(lt)
+	})
+	It("should restart the grace period once a launch template is referenced again", func() {
+		nodeClass := test.EC2NodeClass()
+		lt := makeLaunchTemplate(nodeClass.Name)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(45 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+		ExpectDeleted(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(45 * time.Minute)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateExists(lt)
+	})
+	It("should not delete launch templates owned by another cluster", func() {
+		lt := makeLaunchTemplate("deleted-nodeclass")
+		lt.Tags = []*ec2.Tag{
+			{Key: aws.String(launchtemplate.KarpenterManagedTagKey), Value: aws.String("other-cluster")},
+		}
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		fakeClock.Step(time.Hour)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectLaunchTemplateExists(lt)
+	})
+})
+
+// usedLaunchTemplateProvider reports the launch templates in lastUsed as recently used for a launch
+type usedLaunchTemplateProvider struct {
+	launchtemplate.Provider
+	lastUsed map[string]time.Time
+}
+
+func (u *usedLaunchTemplateProvider) LastUsed(name string) (time.Time, bool) {
+	lastUsed, ok := u.lastUsed[name]
+	return lastUsed, ok
+}
+
+func makeLaunchTemplate(nodeClassName string) *ec2.LaunchTemplate {
+	tags := map[string]string{launchtemplate.KarpenterManagedTagKey: options.FromContext(ctx).ClusterName}
+	if nodeClassName != "" {
+		tags[v1beta1.LabelNodeClass] = nodeClassName
+	}
+	lt := &ec2.LaunchTemplate{
+		LaunchTemplateName: aws.String(fake.LaunchTemplateName()),
+		LaunchTemplateId:   aws.String(fake.LaunchTemplateID()),
+		Tags: lo.MapToSlice(tags, func(k, v string) *ec2.Tag {
+			return &ec2.Tag{Key: aws.String(k), Value: aws.String(v)}
+		}),
+	}
+	awsEnv.EC2API.LaunchTemplates.Store(lt.LaunchTemplateName, lt)
+	return lt
+}
+
+func expectLaunchTemplateExists(lt *ec2.LaunchTemplate) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.LaunchTemplates.Load(lt.LaunchTemplateName)
+	Expect(ok).To(BeTrue())
+}
+
+func expectLaunchTemplateDeleted(lt *ec2.LaunchTemplate) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.LaunchTemplates.Load(lt.LaunchTemplateName)
+	Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,160 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterautoscaler
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/logging"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+const ScaleDownDisabledAnnotationKey = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+
+// mapping is an annotation of Karpenter's and the cluster-autoscaler annotation with the same meaning. Both are
+// honored when they're set to "true".
+type mapping struct {
+	karpenter         string
+	clusterAutoscaler string
+	// Bidirectional mappings are also mirrored from the cluster-autoscaler annotation to Karpenter's. Mappings where
+	// Karpenter's annotation would do more than cluster-autoscaler's are only mirrored from Karpenter's, and a warning
+	// is published when only the cluster-autoscaler annotation is set.
+	bidirectional bool
+}
+
+// mappings are the annotations that are mirrored. Cluster-autoscaler's other node markers aren't mirrored:
+//   - The ToBeDeletedByClusterAutoscaler and DeletionCandidateOfClusterAutoscaler taints are removed from every node by
+//     cluster-autoscaler when it starts, so mirroring Karpenter's disruption taint into them would fight a
+//     cluster-autoscaler that's still running during the migration.
+//   - cluster-autoscaler.kubernetes.io/safe-to-evict is a pod annotation, and only the annotations of Nodes are mirrored.
+var mappings = []mapping{
+	{karpenter: corev1beta1.DoNotDisruptAnnotationKey, clusterAutoscaler: ScaleDownDisabledAnnotationKey, bidirectional: true},
+}
+
+// Controller mirrors annotations of the Nodes that Karpenter launched to and from their cluster-autoscaler equivalents,
+// for tooling that only understands cluster-autoscaler's annotations. It's a temporary compatibility layer for the
+// window of a migration from cluster-autoscaler, enabled with cluster-autoscaler-compatibility, and is expected to be
+// removed once that tooling understands Karpenter's annotations.
+//
+// An annotation is only written when it doesn't mean the same as the annotation it's mirrored from, so that the
+// controller doesn't fight other writers over equivalent values. When a user sets both annotations, neither is mirrored.
+type Controller struct {
+	kubeClient client.Client
+	recorder   events.Recorder
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder) corecontroller.Controller {
+	return corecontroller.Typed[*v1.Node](kubeClient, &Controller{
+		kubeClient: kubeClient,
+		recorder:   recorder,
+	})
+}
+
+func (c *Controller) Reconcile(ctx context.Context, node *v1.Node) (reconcile.Result, error) {
+	stored := node.DeepCopy()
+	mirrored := sets.New(lo.Filter(strings.Split(node.Annotations[v1beta1.AnnotationClusterAutoscalerMirrored], ","), func(k string, _ int) bool { return k != "" })...)
+	for _, m := range mappings {
+		c.mirror(ctx, node, m, mirrored)
+	}
+	if mirrored.Len() == 0 {
+		delete(node.Annotations, v1beta1.AnnotationClusterAutoscalerMirrored)
+	} else {
+		keys := mirrored.UnsortedList()
+		sort.Strings(keys)
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1beta1.AnnotationClusterAutoscalerMirrored: strings.Join(keys, ",")})
+	}
+	if equality.Semantic.DeepEqual(stored, node) {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{}, client.IgnoreNotFound(c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)))
+}
+
+// mirror syncs the annotations of a mapping. An annotation that was mirrored keeps following the one it was mirrored
+// from, and is removed when that one is.
+func (c *Controller) mirror(ctx context.Context, node *v1.Node, m mapping, mirrored sets.Set[string]) {
+	karpenter, hasKarpenter := node.Annotations[m.karpenter]
+	clusterAutoscaler, hasClusterAutoscaler := node.Annotations[m.clusterAutoscaler]
+	switch {
+	case mirrored.Has(m.clusterAutoscaler):
+		c.follow(node, m.karpenter, m.clusterAutoscaler, mirrored)
+	case mirrored.Has(m.karpenter):
+		c.follow(node, m.clusterAutoscaler, m.karpenter, mirrored)
+	case hasKarpenter && hasClusterAutoscaler:
+		if isTrue(karpenter) != isTrue(clusterAutoscaler) {
+			logging.FromContext(ctx).With("node", node.Name).Warnf("not mirroring %s=%q and %s=%q, which disagree", m.karpenter, karpenter, m.clusterAutoscaler, clusterAutoscaler)
+			c.recorder.Publish(ConflictingAnnotationsEvent(node, m.karpenter, m.clusterAutoscaler))
+		}
+	case hasKarpenter && isTrue(karpenter):
+		node.Annotations[m.clusterAutoscaler] = karpenter
+		mirrored.Insert(m.clusterAutoscaler)
+	case hasClusterAutoscaler && isTrue(clusterAutoscaler):
+		if !m.bidirectional {
+			logging.FromContext(ctx).With("node", node.Name).Warnf("not mirroring %s to %s, which Karpenter only mirrors the other way", m.clusterAutoscaler, m.karpenter)
+			c.recorder.Publish(OneWayAnnotationEvent(node, m.karpenter, m.clusterAutoscaler))
+			return
+		}
+		node.Annotations[m.karpenter] = clusterAutoscaler
+		mirrored.Insert(m.karpenter)
+	}
+}
+
+// follow updates an annotation that was mirrored to mean the same as the annotation that it was mirrored from
+func (c *Controller) follow(node *v1.Node, from, to string, mirrored sets.Set[string]) {
+	value, ok := node.Annotations[from]
+	if !ok {
+		delete(node.Annotations, to)
+		mirrored.Delete(to)
+		return
+	}
+	if isTrue(value) != isTrue(node.Annotations[to]) {
+		node.Annotations[to] = value
+	}
+}
+
+func isTrue(value string) bool {
+	return value == "true"
+}
+
+func (c *Controller) Name() string {
+	return "node.clusterautoscaler"
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.Adapt(controllerruntime.
+		NewControllerManagedBy(m).
+		For(&v1.Node{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			_, ok := o.GetLabels()[corev1beta1.NodePoolLabelKey]
+			return ok
+		}))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 10}))
+}
@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterautoscaler
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func ConflictingAnnotationsEvent(node *v1.Node, karpenter, clusterAutoscaler string) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ConflictingClusterAutoscalerAnnotations",
+		Message:        fmt.Sprintf("Not mirroring annotations %s and %s, which disagree", karpenter, clusterAutoscaler),
+		DedupeValues:   []string{string(node.UID), karpenter},
+	}
+}
+
+func OneWayAnnotationEvent(node *v1.Node, karpenter, clusterAutoscaler string) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "UnmirroredClusterAutoscalerAnnotation",
+		Message:        fmt.Sprintf("Not mirroring annotation %s to %s, set %s instead", clusterAutoscaler, karpenter, karpenter),
+		DedupeValues:   []string{string(node.UID), karpenter},
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterautoscaler_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	"sigs.k8s.io/karpenter/pkg/operator/scheme"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/node/clusterautoscaler"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller corecontroller.Controller
+
+func TestClusterAutoscaler(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ClusterAutoscaler")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(scheme.Scheme, coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+
+	controller = clusterautoscaler.NewController(env.Client, awsEnv.EventRecorder)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("ClusterAutoscaler", func() {
+	var node *v1.Node
+	BeforeEach(func() {
+		node = coretest.Node(coretest.NodeOptions{ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{corev1beta1.NodePoolLabelKey: "default"},
+		}})
+	})
+
+	It("should mirror do-not-disrupt to scale-down-disabled", func() {
+		node.Annotations = map[string]string{corev1beta1.DoNotDisruptAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(clusterautoscaler.ScaleDownDisabledAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationClusterAutoscalerMirrored, clusterautoscaler.ScaleDownDisabledAnnotationKey))
+	})
+	It("should mirror scale-down-disabled to do-not-disrupt", func() {
+		node.Annotations = map[string]string{clusterautoscaler.ScaleDownDisabledAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationClusterAutoscalerMirrored, corev1beta1.DoNotDisruptAnnotationKey))
+	})
+	It("should follow changes to the annotation that was mirrored", func() {
+		node.Annotations = map[string]string{corev1beta1.DoNotDisruptAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+
+		node.Annotations[corev1beta1.DoNotDisruptAnnotationKey] = "false"
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(clusterautoscaler.ScaleDownDisabledAnnotationKey, "false"))
+	})
+	It("should remove the mirrored annotation when the annotation it was mirrored from is removed", func() {
+		node.Annotations = map[string]string{clusterautoscaler.ScaleDownDisabledAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+
+		stored := node.DeepCopy()
+		delete(node.Annotations, clusterautoscaler.ScaleDownDisabledAnnotationKey)
+		Expect(env.Client.Patch(ctx, node, client.MergeFrom(stored))).To(Succeed())
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationClusterAutoscalerMirrored))
+	})
+	It("should not update the node when the annotations already agree", func() {
+		node.Annotations = map[string]string{
+			corev1beta1.DoNotDisruptAnnotationKey:            "true",
+			clusterautoscaler.ScaleDownDisabledAnnotationKey: "true",
+		}
+		ExpectApplied(ctx, env.Client, node)
+		node = ExpectExists(ctx, env.Client, node)
+		resourceVersion := node.ResourceVersion
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.ResourceVersion).To(Equal(resourceVersion))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationClusterAutoscalerMirrored))
+	})
+	It("should not mirror annotations that aren't honored", func() {
+		node.Annotations = map[string]string{corev1beta1.DoNotDisruptAnnotationKey: "false"}
+		ExpectApplied(ctx, env.Client, node)
+		node = ExpectExists(ctx, env.Client, node)
+		resourceVersion := node.ResourceVersion
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.ResourceVersion).To(Equal(resourceVersion))
+		Expect(node.Annotations).ToNot(HaveKey(clusterautoscaler.ScaleDownDisabledAnnotationKey))
+	})
+	It("should not mirror annotations that were both set and disagree", func() {
+		node.Annotations = map[string]string{
+			corev1beta1.DoNotDisruptAnnotationKey:            "false",
+			clusterautoscaler.ScaleDownDisabledAnnotationKey: "true",
+		}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(node))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "false"))
+		Expect(node.Annotations).To(HaveKeyWithValue(clusterautoscaler.ScaleDownDisabledAnnotationKey, "true"))
+		Expect(awsEnv.EventRecorder.Calls("ConflictingClusterAutoscalerAnnotations")).To(Equal(1))
+	})
+})
@@ -24,6 +24,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -32,19 +33,34 @@ import (
 
 	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	awsv1beta1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
+// orphanConfirmationDelay is how long an instance has to look orphaned before it's described again by ID and, if it
+// still has no NodeClaim, garbage collected. A LIST that's paginated while instances churn can miss the page that
+// carries an instance's latest tags, so a single LIST isn't enough to terminate on.
+const orphanConfirmationDelay = time.Second * 10
+
 type Controller struct {
-	kubeClient      client.Client
-	cloudProvider   cloudprovider.CloudProvider
-	successfulCount uint64 // keeps track of successful reconciles for more aggressive requeueing near the start of the controller
+	kubeClient       client.Client
+	clk              clock.Clock
+	cloudProvider    cloudprovider.CloudProvider
+	instanceProvider instance.Provider
+	successfulCount  uint64               // keeps track of successful reconciles for more aggressive requeueing near the start of the controller
+	candidates       map[string]time.Time // provider ids of instances that looked orphaned, and when they were first seen that way
 }
 
-func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+func NewController(kubeClient client.Client, clk clock.Clock, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider) *Controller {
 	return &Controller{
-		kubeClient:      kubeClient,
-		cloudProvider:   cloudProvider,
-		successfulCount: 0,
+		kubeClient:       kubeClient,
+		clk:              clk,
+		cloudProvider:    cloudProvider,
+		instanceProvider: instanceProvider,
+		successfulCount:  0,
+		candidates:       map[string]time.Time{},
 	}
 }
 
@@ -74,18 +90,85 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 	resolvedProviderIDs := sets.New[string](lo.FilterMap(nodeClaimList.Items, func(n v1beta1.NodeClaim, _ int) (string, bool) {
 		return n.Status.ProviderID, n.Status.ProviderID != ""
 	})...)
-	errs := make([]error, len(retrieved))
-	workqueue.ParallelizeUntil(ctx, 100, len(managedRetrieved), func(i int) {
-		if !resolvedProviderIDs.Has(managedRetrieved[i].Status.ProviderID) &&
-			time.Since(managedRetrieved[i].CreationTimestamp.Time) > time.Second*30 {
-			errs[i] = c.garbageCollect(ctx, managedRetrieved[i], nodeList)
+	candidates := map[string]time.Time{}
+	var confirmable []*v1beta1.NodeClaim
+	for _, nodeClaim := range managedRetrieved {
+		if resolvedProviderIDs.Has(nodeClaim.Status.ProviderID) || c.clk.Since(nodeClaim.CreationTimestamp.Time) <= time.Second*30 {
+			continue
+		}
+		firstSeen, ok := c.candidates[nodeClaim.Status.ProviderID]
+		if !ok {
+			firstSeen = c.clk.Now()
+			orphanCandidates.Inc()
 		}
+		candidates[nodeClaim.Status.ProviderID] = firstSeen
+		if c.clk.Since(firstSeen) >= orphanConfirmationDelay {
+			confirmable = append(confirmable, nodeClaim)
+		}
+	}
+	c.candidates = candidates
+	orphans, err := c.confirmOrphans(ctx, confirmable)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	errs := make([]error, len(orphans))
+	workqueue.ParallelizeUntil(ctx, 100, len(orphans), func(i int) {
+		errs[i] = c.garbageCollect(ctx, orphans[i], nodeList)
 	})
 	if err = multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, err
 	}
 	c.successfulCount++
-	return reconcile.Result{RequeueAfter: lo.Ternary(c.successfulCount <= 20, time.Second*10, time.Minute*2)}, nil
+	requeueAfter := lo.Ternary(c.successfulCount <= 20, time.Second*10, time.Minute*2)
+	if len(c.candidates) > 0 {
+		requeueAfter = lo.Min([]time.Duration{requeueAfter, orphanConfirmationDelay})
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// confirmOrphans describes the candidates again by instance ID and returns those that still have no NodeClaim. The
+// NodeClaims are listed after the describe so that any NodeClaim that resolved in the meantime is seen.
+func (c *Controller) confirmOrphans(ctx context.Context, candidates []*v1beta1.NodeClaim) ([]*v1beta1.NodeClaim, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	instances := make([]*instance.Instance, len(candidates))
+	errs := make([]error, len(candidates))
+	workqueue.ParallelizeUntil(ctx, 100, len(candidates), func(i int) {
+		id, err := utils.ParseInstanceID(candidates[i].Status.ProviderID)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		instances[i], errs[i] = c.instanceProvider.Get(ctx, id)
+		errs[i] = cloudprovider.IgnoreNodeClaimNotFoundError(errs[i])
+	})
+	if err := multierr.Combine(errs...); err != nil {
+		return nil, fmt.Errorf("confirming orphaned instances, %w", err)
+	}
+	nodeClaimList := &v1beta1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return nil, err
+	}
+	names := sets.New[string]()
+	resolvedProviderIDs := sets.New[string]()
+	for _, nodeClaim := range nodeClaimList.Items {
+		names.Insert(nodeClaim.Name)
+		if nodeClaim.Status.ProviderID != "" {
+			resolvedProviderIDs.Insert(nodeClaim.Status.ProviderID)
+		}
+	}
+	var orphans []*v1beta1.NodeClaim
+	for i, candidate := range candidates {
+		if instances[i] == nil || instances[i].Tags[v1beta1.ManagedByAnnotationKey] == "" ||
+			resolvedProviderIDs.Has(candidate.Status.ProviderID) || names.Has(instances[i].Tags[awsv1beta1.TagNodeClaim]) {
+			delete(c.candidates, candidate.Status.ProviderID)
+			continue
+		}
+		orphans = append(orphans, candidate)
+	}
+	confirmedOrphans.Add(float64(len(orphans)))
+	return orphans, nil
 }
 
 func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *v1beta1.NodeClaim, nodeList *v1.NodeList) error {
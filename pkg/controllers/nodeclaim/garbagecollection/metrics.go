@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	instanceSubsystem = "instances"
+)
+
+var (
+	orphanCandidates = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: instanceSubsystem,
+			Name:      "orphan_candidates",
+			Help:      "Count of instances that were listed without a NodeClaim and held for confirmation before garbage collection.",
+		},
+	)
+	confirmedOrphans = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: instanceSubsystem,
+			Name:      "orphans_confirmed",
+			Help:      "Count of orphan candidates that still had no NodeClaim when described again by ID, and were garbage collected.",
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(orphanCandidates, confirmedOrphans)
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -52,6 +53,7 @@ import (
 var ctx context.Context
 var awsEnv *test.Environment
 var env *coretest.Environment
+var fakeClock *clock.FakeClock
 var garbageCollectionController controller.Controller
 var cloudProvider *cloudprovider.CloudProvider
 
@@ -66,8 +68,7 @@ var _ = BeforeSuite(func() {
 	env = coretest.NewEnvironment(scheme.Scheme, coretest.WithCRDs(apis.CRDs...))
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider)
-	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider, awsEnv.CostLimitProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -76,6 +77,8 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	awsEnv.Reset()
+	fakeClock = clock.NewFakeClock(time.Now())
+	garbageCollectionController = garbagecollection.NewController(env.Client, fakeClock, cloudProvider, awsEnv.InstanceProvider)
 })
 
 var _ = Describe("GarbageCollection", func() {
@@ -137,11 +140,142 @@ var _ = Describe("GarbageCollection", func() {
 		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
 		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
 
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 		_, err := cloudProvider.Get(ctx, providerID)
 		Expect(err).To(HaveOccurred())
 		Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
 	})
+	It("should delete instances without a NodeClaim owner from every page of DescribeInstances", func() {
+		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
+		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+		otherID := fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(otherID, &ec2.Instance{
+			State:          instance.State,
+			Tags:           instance.Tags,
+			PrivateDnsName: aws.String(fake.PrivateDNSName()),
+			Placement:      instance.Placement,
+			LaunchTime:     instance.LaunchTime,
+			InstanceId:     aws.String(otherID),
+			InstanceType:   instance.InstanceType,
+		})
+		awsEnv.EC2API.DescribeInstancesPageSize.Set(lo.ToPtr(1))
+
+		ExpectGarbageCollectionConfirmed()
+		Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(BeNumerically(">=", 2))
+		for _, id := range []string{aws.StringValue(instance.InstanceId), otherID} {
+			_, err := cloudProvider.Get(ctx, fake.ProviderID(id))
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+		}
+	})
+	It("should not delete an instance until it's confirmed as orphaned", func() {
+		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
+		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+		candidates, confirmed := counterValue("karpenter_instances_orphan_candidates"), counterValue("karpenter_instances_orphans_confirmed")
+
+		result := ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		Expect(result.RequeueAfter).To(Equal(time.Second * 10))
+		_, err := cloudProvider.Get(ctx, providerID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(counterValue("karpenter_instances_orphan_candidates")).To(Equal(candidates + 1))
+		Expect(counterValue("karpenter_instances_orphans_confirmed")).To(Equal(confirmed))
+
+		fakeClock.Step(time.Second * 10)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		_, err = cloudProvider.Get(ctx, providerID)
+		Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+		Expect(counterValue("karpenter_instances_orphan_candidates")).To(Equal(candidates + 1))
+		Expect(counterValue("karpenter_instances_orphans_confirmed")).To(Equal(confirmed + 1))
+	})
+	It("should not delete an instance whose NodeClaim tag only appears when it's described again", func() {
+		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
+		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+		otherID := fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(otherID, &ec2.Instance{
+			State:          instance.State,
+			PrivateDnsName: aws.String(fake.PrivateDNSName()),
+			Placement:      instance.Placement,
+			LaunchTime:     instance.LaunchTime,
+			InstanceId:     aws.String(otherID),
+			InstanceType:   instance.InstanceType,
+		})
+		awsEnv.EC2API.DescribeInstancesPageSize.Set(lo.ToPtr(1))
+		// The NodeClaim has launched the instance but hasn't resolved its provider id yet
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Spec: corev1beta1.NodeClaimSpec{
+				NodeClassRef: &corev1beta1.NodeClassReference{
+					Name: nodeClass.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), &ec2.Instance{
+			State:          instance.State,
+			Tags:           append(instance.Tags, &ec2.Tag{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String(nodeClaim.Name)}),
+			PrivateDnsName: instance.PrivateDnsName,
+			Placement:      instance.Placement,
+			LaunchTime:     instance.LaunchTime,
+			InstanceId:     instance.InstanceId,
+			InstanceType:   instance.InstanceType,
+		})
+		fakeClock.Step(time.Second * 10)
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+		_, err := cloudProvider.Get(ctx, providerID)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectExists(ctx, env.Client, nodeClaim)
+	})
+	It("should make the same decisions when sweeping instance status as when describing every instance", func() {
+		withID := func(id string, modify func(*ec2.Instance)) *ec2.Instance {
+			i := &ec2.Instance{
+				State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				Tags:           instance.Tags,
+				PrivateDnsName: aws.String(fake.PrivateDNSName()),
+				Placement:      instance.Placement,
+				LaunchTime:     aws.Time(time.Now().Add(-time.Minute)),
+				InstanceId:     aws.String(id),
+				InstanceType:   instance.InstanceType,
+			}
+			modify(i)
+			awsEnv.EC2API.Instances.Store(id, i)
+			return i
+		}
+		orphaned := withID(fake.InstanceID(), func(*ec2.Instance) {})
+		owned := withID(fake.InstanceID(), func(*ec2.Instance) {})
+		recent := withID(fake.InstanceID(), func(i *ec2.Instance) { i.LaunchTime = aws.Time(time.Now()) })
+		unmanaged := withID(fake.InstanceID(), func(i *ec2.Instance) {
+			i.Tags = lo.Reject(i.Tags, func(t *ec2.Tag, _ int) bool { return aws.StringValue(t.Key) == corev1beta1.ManagedByAnnotationKey })
+		})
+		foreign := withID(fake.InstanceID(), func(i *ec2.Instance) { i.Tags = nil })
+		stopped := withID(fake.InstanceID(), func(i *ec2.Instance) {
+			i.Tags = append(i.Tags, &ec2.Tag{Key: aws.String(v1beta1.TagTerminationBehavior), Value: aws.String(string(v1beta1.TerminationBehaviorStop))})
+		})
+		terminating := withID(fake.InstanceID(), func(i *ec2.Instance) { i.State.Name = aws.String(ec2.InstanceStateNameShuttingDown) })
+		ExpectApplied(ctx, env.Client, coretest.NodeClaim(corev1beta1.NodeClaim{
+			Spec: corev1beta1.NodeClaimSpec{
+				NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+			},
+			Status: corev1beta1.NodeClaimStatus{ProviderID: fake.ProviderID(aws.StringValue(owned.InstanceId))},
+		}))
+
+		listed, err := awsEnv.InstanceProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		swept, err := awsEnv.InstanceProvider.Sweep(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(swept).To(Equal(listed))
+
+		ExpectGarbageCollectionConfirmed()
+		var terminated []string
+		awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.ForEach(func(input *ec2.TerminateInstancesInput) {
+			terminated = append(terminated, aws.StringValueSlice(input.InstanceIds)...)
+		})
+		Expect(terminated).To(ConsistOf(aws.StringValue(orphaned.InstanceId)))
+		for _, i := range []*ec2.Instance{owned, recent, unmanaged, foreign, stopped, terminating} {
+			Expect(terminated).ToNot(ContainElement(aws.StringValue(i.InstanceId)))
+		}
+	})
 	It("should delete an instance along with the node if there is no NodeClaim owner (to quicken scheduling)", func() {
 		// Launch time was 1m ago
 		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
@@ -152,7 +286,7 @@ var _ = Describe("GarbageCollection", func() {
 		})
 		ExpectApplied(ctx, env.Client, node)
 
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 		_, err := cloudProvider.Get(ctx, providerID)
 		Expect(err).To(HaveOccurred())
 		Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
@@ -200,7 +334,7 @@ var _ = Describe("GarbageCollection", func() {
 			)
 			ids = append(ids, instanceID)
 		}
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 
 		wg := sync.WaitGroup{}
 		for _, id := range ids {
@@ -258,7 +392,7 @@ var _ = Describe("GarbageCollection", func() {
 			nodeClaims = append(nodeClaims, nodeClaim)
 			ids = append(ids, instanceID)
 		}
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 
 		wg := sync.WaitGroup{}
 		for _, id := range ids {
@@ -282,7 +416,7 @@ var _ = Describe("GarbageCollection", func() {
 		instance.LaunchTime = aws.Time(time.Now())
 		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
 
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 		_, err := cloudProvider.Get(ctx, providerID)
 		Expect(err).NotTo(HaveOccurred())
 	})
@@ -296,7 +430,7 @@ var _ = Describe("GarbageCollection", func() {
 		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
 		awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
 
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 		_, err := cloudProvider.Get(ctx, providerID)
 		Expect(err).NotTo(HaveOccurred())
 	})
@@ -320,7 +454,7 @@ var _ = Describe("GarbageCollection", func() {
 		})
 		ExpectApplied(ctx, env.Client, nodeClaim, node)
 
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 		_, err := cloudProvider.Get(ctx, providerID)
 		Expect(err).ToNot(HaveOccurred())
 		ExpectExists(ctx, env.Client, node)
@@ -378,7 +512,7 @@ var _ = Describe("GarbageCollection", func() {
 			ids = append(ids, instanceID)
 			nodes = append(nodes, node)
 		}
-		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectGarbageCollectionConfirmed()
 
 		wg := sync.WaitGroup{}
 		for i := range ids {
@@ -395,3 +529,21 @@ var _ = Describe("GarbageCollection", func() {
 		wg.Wait()
 	})
 })
+
+// ExpectGarbageCollectionConfirmed reconciles once to find orphan candidates and again, once the confirmation delay
+// has passed, to garbage collect those that are confirmed
+func ExpectGarbageCollectionConfirmed() {
+	GinkgoHelper()
+	ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+	fakeClock.Step(time.Second * 10)
+	ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+}
+
+func counterValue(name string) float64 {
+	GinkgoHelper()
+	metric, ok := FindMetricWithLabelValues(name, nil)
+	if !ok {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
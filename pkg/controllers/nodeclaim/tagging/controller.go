@@ -16,20 +16,22 @@ package tagging
 
 import (
 	"context"
-	"fmt"
+	"sync"
 	"time"
 
+	"go.uber.org/multierr"
+	"golang.org/x/time/rate"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/workqueue"
 	"knative.dev/pkg/logging"
-	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/samber/lo"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
@@ -38,80 +40,163 @@ import (
 	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
 )
 
+// Controller tags the instances of registered NodeClaims with their node and NodeClaim names and, when taint tags are
+// enabled, syncs the taint tags of instances whose nodes haven't registered yet. Instances that need an identical set
+// of tags are tagged together, so tagging many instances only takes a few CreateTags calls.
 type Controller struct {
 	kubeClient       client.Client
 	instanceProvider instance.Provider
+	limiter          *rate.Limiter
 }
 
-func NewController(kubeClient client.Client, instanceProvider instance.Provider) corecontroller.Controller {
-	return corecontroller.Typed[*corev1beta1.NodeClaim](kubeClient, &Controller{
+func NewController(kubeClient client.Client, instanceProvider instance.Provider) *Controller {
+	return &Controller{
 		kubeClient:       kubeClient,
 		instanceProvider: instanceProvider,
-	})
+		// Ensures that no more than 1 CreateTags call is made per second. Rate limiting is required since CreateTags
+		// shares a pool with other mutating calls (e.g. CreateFleet).
+		limiter: rate.NewLimiter(rate.Every(time.Second), 1),
+	}
 }
 
 func (c *Controller) Name() string {
 	return "nodeclaim.tagging"
 }
 
-func (c *Controller) Reconcile(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (reconcile.Result, error) {
-	stored := nodeClaim.DeepCopy()
-	if !isTaggable(nodeClaim) {
-		return reconcile.Result{}, nil
-	}
-	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("provider-id", nodeClaim.Status.ProviderID))
-	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+// tagGroup is a set of tags and the instances, and their NodeClaims, that need them
+type tagGroup struct {
+	tags       map[string]string
+	nodeClaims map[string]*corev1beta1.NodeClaim
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	nodeClaims, err := c.nodeClaimsByInstanceID(ctx)
 	if err != nil {
-		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
-		logging.FromContext(ctx).Errorf("failed to parse instance ID, %w", err)
-		return reconcile.Result{}, nil
+		return reconcile.Result{}, err
 	}
-	if err = c.tagInstance(ctx, nodeClaim, id); err != nil {
-		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	instances := c.getInstances(ctx, lo.Keys(nodeClaims))
+	groups := map[string]*tagGroup{}
+	var errs error
+	for id, nodeClaim := range nodeClaims {
+		inst, ok := instances[id]
+		if !ok {
+			continue
+		}
+		tags := c.missingTags(ctx, nodeClaim, inst)
+		if len(tags) == 0 {
+			errs = multierr.Append(errs, c.markTagged(ctx, nodeClaim, inst))
+			continue
+		}
+		key := tagSetKey(tags)
+		if _, ok := groups[key]; !ok {
+			groups[key] = &tagGroup{tags: tags, nodeClaims: map[string]*corev1beta1.NodeClaim{}}
+		}
+		groups[key].nodeClaims[id] = nodeClaim
 	}
-	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationInstanceTagged: "true"})
-	if !equality.Semantic.DeepEqual(nodeClaim, stored) {
-		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
-			return reconcile.Result{}, client.IgnoreNotFound(err)
+	for _, group := range groups {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return reconcile.Result{}, err
+		}
+		failed := c.instanceProvider.BatchCreateTags(ctx, lo.Keys(group.nodeClaims), group.tags)
+		for id, nodeClaim := range group.nodeClaims {
+			if err, ok := failed[id]; ok {
+				if !cloudprovider.IsNodeClaimNotFoundError(err) {
+					logging.FromContext(ctx).With("provider-id", nodeClaim.Status.ProviderID).Errorf("tagging instance, %s", err)
+				}
+				continue
+			}
+			errs = multierr.Append(errs, c.markTagged(ctx, nodeClaim, instances[id]))
 		}
 	}
-	return reconcile.Result{}, nil
+	if errs != nil {
+		return reconcile.Result{}, errs
+	}
+	return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 }
 
 func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
-	return corecontroller.Adapt(
-		controllerruntime.
-			NewControllerManagedBy(m).
-			For(&corev1beta1.NodeClaim{}).
-			WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
-				return isTaggable(o.(*corev1beta1.NodeClaim))
-			})),
-	)
+	return corecontroller.NewSingletonManagedBy(m)
+}
+
+// nodeClaimsByInstanceID indexes the NodeClaims whose instances need tags by their instance ID
+func (c *Controller) nodeClaimsByInstanceID(ctx context.Context) (map[string]*corev1beta1.NodeClaim, error) {
+	nodeClaimList := &corev1beta1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return nil, err
+	}
+	nodeClaims := map[string]*corev1beta1.NodeClaim{}
+	for i := range nodeClaimList.Items {
+		nodeClaim := &nodeClaimList.Items[i]
+		if !isTaggable(nodeClaim) && !(options.FromContext(ctx).TaintTags && isPendingRegistration(nodeClaim)) {
+			continue
+		}
+		id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+		if err != nil {
+			// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+			logging.FromContext(ctx).With("provider-id", nodeClaim.Status.ProviderID).Errorf("failed to parse instance ID, %s", err)
+			continue
+		}
+		nodeClaims[id] = nodeClaim
+	}
+	return nodeClaims, nil
+}
+
+// getInstances gets the instances concurrently, so that the instance provider batches the calls to describe them.
+// Instances that can't be described are left out.
+func (c *Controller) getInstances(ctx context.Context, ids []string) map[string]*instance.Instance {
+	var mu sync.Mutex
+	instances := map[string]*instance.Instance{}
+	workqueue.ParallelizeUntil(ctx, 50, len(ids), func(i int) {
+		inst, err := c.instanceProvider.Get(ctx, ids[i])
+		if err != nil {
+			if !cloudprovider.IsNodeClaimNotFoundError(err) {
+				logging.FromContext(ctx).With("id", ids[i]).Errorf("getting instance, %s", err)
+			}
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		instances[ids[i]] = inst
+	})
+	return instances
 }
 
-func (c *Controller) tagInstance(ctx context.Context, nc *corev1beta1.NodeClaim, id string) error {
-	tags := map[string]string{
-		v1beta1.TagName:      nc.Status.NodeName,
-		v1beta1.TagNodeClaim: nc.Name,
+// missingTags returns the tags that the NodeClaim's instance needs, but doesn't have yet
+func (c *Controller) missingTags(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, inst *instance.Instance) map[string]string {
+	// Taint tags are updated to reflect any changes to the NodeClaim's taints since launch
+	if isPendingRegistration(nodeClaim) {
+		return lo.OmitBy(instance.TaintTags(nodeClaim), func(k, v string) bool {
+			current, ok := inst.Tags[k]
+			return ok && current == v
+		})
 	}
+	// Tags which have already been populated aren't overwritten
+	return lo.OmitByKeys(map[string]string{
+		v1beta1.TagName:      nodeClaim.Status.NodeName,
+		v1beta1.TagNodeClaim: nodeClaim.Name,
+	}, lo.Keys(inst.Tags))
+}
 
-	// Remove tags which have been already populated
-	instance, err := c.instanceProvider.Get(ctx, id)
-	if err != nil {
-		return fmt.Errorf("tagging nodeclaim, %w", err)
+// markTagged annotates a registered NodeClaim once its instance has been tagged
+func (c *Controller) markTagged(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, inst *instance.Instance) error {
+	if !isTaggable(nodeClaim) {
+		return nil
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationInstanceTagged: "true"})
+	// The spot instance request isn't in the CreateFleet response, so it's recorded from the instance described here
+	if inst.SpotInstanceRequestID != "" {
+		nodeClaim.Annotations[v1beta1.AnnotationSpotInstanceRequestID] = inst.SpotInstanceRequestID
 	}
-	tags = lo.OmitByKeys(tags, lo.Keys(instance.Tags))
-	if len(tags) == 0 {
+	if equality.Semantic.DeepEqual(nodeClaim, stored) {
 		return nil
 	}
+	return client.IgnoreNotFound(c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)))
+}
 
-	// Ensures that no more than 1 CreateTags call is made per second. Rate limiting is required since CreateTags
-	// shares a pool with other mutating calls (e.g. CreateFleet).
-	defer time.Sleep(time.Second)
-	if err := c.instanceProvider.CreateTags(ctx, id, tags); err != nil {
-		return fmt.Errorf("tagging nodeclaim, %w", err)
-	}
-	return nil
+// isPendingRegistration returns true if the NodeClaim has launched but its node hasn't registered yet
+func isPendingRegistration(nc *corev1beta1.NodeClaim) bool {
+	return nc.Status.ProviderID != "" && nc.Status.NodeName == "" && nc.DeletionTimestamp.IsZero()
 }
 
 func isTaggable(nc *corev1beta1.NodeClaim) bool {
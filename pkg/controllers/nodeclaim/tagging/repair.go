@@ -0,0 +1,202 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tagging
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	// repairBatchSize is the maximum number of instances described or tagged by a single EC2 call
+	repairBatchSize = 200
+	// repairAPICallsPerMinute bounds the DescribeInstances and CreateTags calls made while repairing tags. CreateTags
+	// shares a rate limit pool with other mutating calls (e.g. CreateFleet), so repairs shouldn't compete with launches.
+	repairAPICallsPerMinute = 30
+)
+
+// RepairController re-applies the ownership tags that are missing from the instances of registered NodeClaims. An
+// instance without these tags isn't discovered when listing instances, which leads garbage collection to consider
+// its NodeClaim orphaned.
+type RepairController struct {
+	kubeClient client.Client
+	ec2api     ec2iface.EC2API
+	limiter    *rate.Limiter
+}
+
+func NewRepairController(kubeClient client.Client, ec2api ec2iface.EC2API) *RepairController {
+	return &RepairController{
+		kubeClient: kubeClient,
+		ec2api:     ec2api,
+		limiter:    rate.NewLimiter(rate.Every(time.Minute/repairAPICallsPerMinute), repairAPICallsPerMinute),
+	}
+}
+
+func (c *RepairController) Name() string {
+	return "nodeclaim.tagging.repair"
+}
+
+func (c *RepairController) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	expected, err := c.expectedTags(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("resolving expected instance tags, %w", err)
+	}
+	instances, err := c.describeInstances(ctx, lo.Keys(expected))
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("describing instances, %w", err)
+	}
+	// Group the instances by the tags they're missing so that each distinct set of tags is applied with as few calls as possible
+	missing := map[string]map[string]string{}
+	ids := map[string][]string{}
+	for _, inst := range instances {
+		id := aws.StringValue(inst.InstanceId)
+		tags := lo.OmitByKeys(expected[id], lo.Map(inst.Tags, func(t *ec2.Tag, _ int) string { return aws.StringValue(t.Key) }))
+		if len(tags) == 0 {
+			continue
+		}
+		key := tagSetKey(tags)
+		missing[key] = tags
+		ids[key] = append(ids[key], id)
+	}
+	var errs error
+	for _, key := range lo.Keys(missing) {
+		for _, batch := range lo.Chunk(ids[key], repairBatchSize) {
+			if err := c.createTags(ctx, batch, missing[key]); err != nil {
+				errs = multierr.Append(errs, err)
+				continue
+			}
+			repairedInstances.Add(float64(len(batch)))
+			logging.FromContext(ctx).With("ids", batch, "tags", lo.Keys(missing[key])).Infof("repaired missing instance tags")
+		}
+	}
+	if errs != nil {
+		return reconcile.Result{}, errs
+	}
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// expectedTags returns the ownership tags expected on the instance of each registered NodeClaim, keyed by instance ID
+func (c *RepairController) expectedTags(ctx context.Context) (map[string]map[string]string, error) {
+	nodeClaimList := &corev1beta1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return nil, err
+	}
+	nodeClasses := map[string]*v1beta1.EC2NodeClass{}
+	expected := map[string]map[string]string{}
+	for i := range nodeClaimList.Items {
+		nodeClaim := &nodeClaimList.Items[i]
+		nodeClassName := utils.NodeClassName(nodeClaim)
+		if !nodeClaim.StatusConditions().GetCondition(corev1beta1.Registered).IsTrue() || !nodeClaim.DeletionTimestamp.IsZero() ||
+			nodeClassName == "" {
+			continue
+		}
+		id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+		if err != nil {
+			continue
+		}
+		nodeClass, ok := nodeClasses[nodeClassName]
+		if !ok {
+			nodeClass = &v1beta1.EC2NodeClass{}
+			if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: nodeClassName}, nodeClass); err != nil {
+				if !errors.IsNotFound(err) {
+					return nil, err
+				}
+				nodeClass = nil
+			}
+			nodeClasses[nodeClassName] = nodeClass
+		}
+		if nodeClass == nil {
+			continue
+		}
+		expected[id] = instance.OwnershipTags(ctx, nodeClass, nodeClaim)
+	}
+	return expected, nil
+}
+
+func (c *RepairController) describeInstances(ctx context.Context, ids []string) ([]*ec2.Instance, error) {
+	sort.Strings(ids)
+	var instances []*ec2.Instance
+	for _, batch := range lo.Chunk(ids, repairBatchSize) {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		// Filtering on the instance ID, rather than passing InstanceIds, keeps a single terminated instance from failing the call
+		if err := c.ec2api.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("instance-id"), Values: aws.StringSlice(batch)},
+				{
+					Name:   aws.String("instance-state-name"),
+					Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped}),
+				},
+			},
+		}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+			for _, reservation := range page.Reservations {
+				instances = append(instances, reservation.Instances...)
+			}
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return instances, nil
+}
+
+func (c *RepairController) createTags(ctx context.Context, ids []string, tags map[string]string) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if _, err := c.ec2api.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+		Resources: aws.StringSlice(ids),
+		Tags: lo.MapToSlice(tags, func(k, v string) *ec2.Tag {
+			return &ec2.Tag{Key: aws.String(k), Value: aws.String(v)}
+		}),
+	}); err != nil {
+		return fmt.Errorf("repairing instance tags, %w", err)
+	}
+	return nil
+}
+
+// tagSetKey returns a key that is identical for identical sets of tags
+func tagSetKey(tags map[string]string) string {
+	entries := lo.MapToSlice(tags, func(k, v string) string { return k + "=" + v })
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (c *RepairController) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.NewSingletonManagedBy(m)
+}
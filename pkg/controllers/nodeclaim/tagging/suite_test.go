@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -69,6 +70,7 @@ var _ = AfterSuite(func() {
 })
 
 var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
 	awsEnv.Reset()
 })
 
@@ -187,6 +189,112 @@ var _ = Describe("TaggingController", func() {
 		})).To(BeFalse())
 	})
 
+	It("should sync taint tags with the NodeClaim's taints before the node registers", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TaintTags: lo.ToPtr(true)}))
+		ec2Instance.Tags = append(ec2Instance.Tags,
+			&ec2.Tag{Key: aws.String(v1beta1.TagTaints), Value: aws.String("example.com/not-ready:NoSchedule")},
+			&ec2.Tag{Key: aws.String(v1beta1.TagTaintsTruncated), Value: aws.String("false")},
+		)
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Spec: corev1beta1.NodeClaimSpec{
+				StartupTaints: []corev1.Taint{
+					{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule},
+					{Key: "example.com/configuring", Value: "true", Effect: corev1.TaintEffectNoExecute},
+				},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKeyFromObject(nodeClaim))
+		instanceTags := instance.NewInstance(ec2Instance).Tags
+		Expect(instanceTags).To(HaveKeyWithValue(v1beta1.TagTaints, "example.com/not-ready:NoSchedule,example.com/configuring=true:NoExecute"))
+		Expect(instanceTags).To(HaveKeyWithValue(v1beta1.TagTaintsTruncated, "false"))
+		Expect(instanceTags).ToNot(HaveKey(v1beta1.TagName))
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(1))
+
+		// Nothing changed, so there is nothing to sync
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKeyFromObject(nodeClaim))
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(1))
+	})
+	It("should sync identical taint tags of several instances with a single call", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TaintTags: lo.ToPtr(true)}))
+		other := &ec2.Instance{
+			State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			Tags:           ec2Instance.Tags,
+			PrivateDnsName: aws.String(fake.PrivateDNSName()),
+			Placement:      &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			InstanceId:     aws.String(fake.InstanceID()),
+			InstanceType:   aws.String("m5.large"),
+		}
+		awsEnv.EC2API.Instances.Store(*other.InstanceId, other)
+		nodeClaims := lo.Map([]*ec2.Instance{ec2Instance, other}, func(i *ec2.Instance, _ int) *corev1beta1.NodeClaim {
+			return coretest.NodeClaim(corev1beta1.NodeClaim{
+				Spec: corev1beta1.NodeClaimSpec{
+					StartupTaints: []corev1.Taint{{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule}},
+				},
+				Status: corev1beta1.NodeClaimStatus{
+					ProviderID: fake.ProviderID(*i.InstanceId),
+				},
+			})
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClaims[0], nodeClaims[1])
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKey{})
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(1))
+		input := awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValueSlice(input.Resources)).To(ConsistOf(*ec2Instance.InstanceId, *other.InstanceId))
+		Expect(instance.NewInstance(ec2Instance).Tags).To(HaveKeyWithValue(v1beta1.TagTaints, "example.com/not-ready:NoSchedule"))
+		Expect(instance.NewInstance(other).Tags).To(HaveKeyWithValue(v1beta1.TagTaints, "example.com/not-ready:NoSchedule"))
+	})
+	It("should tag the other instances when one instance can't be tagged", func() {
+		other := &ec2.Instance{
+			State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			Tags:           ec2Instance.Tags,
+			PrivateDnsName: aws.String(fake.PrivateDNSName()),
+			Placement:      &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			InstanceId:     aws.String(fake.InstanceID()),
+			InstanceType:   aws.String("m5.large"),
+		}
+		awsEnv.EC2API.Instances.Store(*other.InstanceId, other)
+		tagged := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Status: corev1beta1.NodeClaimStatus{ProviderID: fake.ProviderID(*ec2Instance.InstanceId), NodeName: "tagged"},
+		})
+		failed := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Status: corev1beta1.NodeClaimStatus{ProviderID: fake.ProviderID(*other.InstanceId), NodeName: "failed"},
+		})
+		// Each instance needs a different Name tag, so they're tagged by separate calls and only the first call fails
+		awsEnv.EC2API.CreateTagsBehavior.Error.Set(fmt.Errorf("tagging failed"))
+
+		ExpectApplied(ctx, env.Client, tagged, failed)
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKey{})
+		annotated := lo.Filter([]*corev1beta1.NodeClaim{ExpectExists(ctx, env.Client, tagged), ExpectExists(ctx, env.Client, failed)}, func(nc *corev1beta1.NodeClaim, _ int) bool {
+			return nc.Annotations[v1beta1.AnnotationInstanceTagged] == "true"
+		})
+		Expect(annotated).To(HaveLen(1))
+
+		// The instance that couldn't be tagged is retried
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKey{})
+		Expect(ExpectExists(ctx, env.Client, tagged).Annotations).To(HaveKeyWithValue(v1beta1.AnnotationInstanceTagged, "true"))
+		Expect(ExpectExists(ctx, env.Client, failed).Annotations).To(HaveKeyWithValue(v1beta1.AnnotationInstanceTagged, "true"))
+	})
+	It("shouldn't sync taint tags when disabled", func() {
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Spec: corev1beta1.NodeClaimSpec{
+				StartupTaints: []corev1.Taint{{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKeyFromObject(nodeClaim))
+		Expect(instance.NewInstance(ec2Instance).Tags).ToNot(HaveKey(v1beta1.TagTaints))
+	})
+
 	DescribeTable(
 		"should tag taggable instances",
 		func(customTags ...string) {
@@ -227,4 +335,155 @@ var _ = Describe("TaggingController", func() {
 		Entry("with both Name and karpenter.k8s.aws/nodeclaim tags"),
 		Entry("with nothing to tag", v1beta1.TagName, v1beta1.TagNodeClaim),
 	)
+	It("should annotate the spot instance request of a spot instance", func() {
+		ec2Instance.SpotInstanceRequestId = aws.String("sir-12345678")
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+				NodeName:   "default",
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKeyFromObject(nodeClaim))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSpotInstanceRequestID, "sir-12345678"))
+	})
+	It("should not annotate a spot instance request for an on-demand instance", func() {
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+				NodeName:   "default",
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectReconcileSucceeded(ctx, taggingController, client.ObjectKeyFromObject(nodeClaim))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKey(v1beta1.AnnotationInstanceTagged))
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationSpotInstanceRequestID))
+	})
+})
+
+var _ = Describe("TagRepairController", func() {
+	var repairController controller.Controller
+	var nodeClass *v1beta1.EC2NodeClass
+
+	BeforeEach(func() {
+		repairController = tagging.NewRepairController(env.Client, awsEnv.EC2API)
+		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			Spec: v1beta1.EC2NodeClassSpec{
+				Tags: map[string]string{"team": "platform"},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass)
+	})
+
+	// registeredNodeClaim creates a registered NodeClaim along with an instance that only carries the passed tags
+	registeredNodeClaim := func(tags map[string]string) (*corev1beta1.NodeClaim, *ec2.Instance) {
+		ec2Instance := &ec2.Instance{
+			State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			Placement:    &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			InstanceId:   aws.String(fake.InstanceID()),
+			InstanceType: aws.String("m5.large"),
+			Tags: lo.MapToSlice(tags, func(k, v string) *ec2.Tag {
+				return &ec2.Tag{Key: aws.String(k), Value: aws.String(v)}
+			}),
+		}
+		awsEnv.EC2API.Instances.Store(*ec2Instance.InstanceId, ec2Instance)
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Labels: map[string]string{corev1beta1.NodePoolLabelKey: "default"},
+			},
+			Spec: corev1beta1.NodeClaimSpec{
+				NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+			},
+		})
+		nodeClaim.StatusConditions().MarkTrue(corev1beta1.Registered)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		return nodeClaim, ec2Instance
+	}
+	repairedInstances := func() float64 {
+		m, ok := FindMetricWithLabelValues("karpenter_nodeclaims_instance_tags_repaired", map[string]string{})
+		if !ok {
+			return 0
+		}
+		return m.GetCounter().GetValue()
+	}
+
+	It("should re-apply missing ownership tags", func() {
+		nodeClaim, ec2Instance := registeredNodeClaim(map[string]string{"team": "platform"})
+		before := repairedInstances()
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+
+		Expect(instance.NewInstance(ec2Instance).Tags).To(Equal(map[string]string{
+			fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName): "owned",
+			corev1beta1.NodePoolLabelKey:       "default",
+			corev1beta1.ManagedByAnnotationKey: options.FromContext(ctx).ClusterName,
+			v1beta1.LabelNodeClass:             nodeClass.Name,
+			v1beta1.TagNodeClaim:               nodeClaim.Name,
+			"team":                             "platform",
+		}))
+		Expect(repairedInstances() - before).To(BeNumerically("==", 1))
+	})
+	It("should repair instances that are missing different sets of tags", func() {
+		_, first := registeredNodeClaim(nil)
+		_, second := registeredNodeClaim(nil)
+		before := repairedInstances()
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+
+		// The NodeClaim tag differs per instance, so only the tags shared by both instances can be batched
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(2))
+		Expect(instance.NewInstance(first).Tags).To(HaveKeyWithValue("team", "platform"))
+		Expect(instance.NewInstance(second).Tags).To(HaveKeyWithValue("team", "platform"))
+		Expect(repairedInstances() - before).To(BeNumerically("==", 2))
+	})
+	It("should batch instances that are missing an identical set of tags", func() {
+		var instances []*ec2.Instance
+		for i := 0; i < 3; i++ {
+			nodeClaim, ec2Instance := registeredNodeClaim(nil)
+			ec2Instance.Tags = []*ec2.Tag{{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String(nodeClaim.Name)}}
+			instances = append(instances, ec2Instance)
+		}
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(1))
+		input := awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Pop()
+		Expect(input.Resources).To(HaveLen(3))
+		for _, ec2Instance := range instances {
+			Expect(instance.NewInstance(ec2Instance).Tags).To(HaveKeyWithValue(v1beta1.LabelNodeClass, nodeClass.Name))
+		}
+	})
+	It("shouldn't call CreateTags when no tags are missing", func() {
+		nodeClaim, ec2Instance := registeredNodeClaim(nil)
+		ec2Instance.Tags = lo.MapToSlice(instance.OwnershipTags(ctx, nodeClass, nodeClaim), func(k, v string) *ec2.Tag {
+			return &ec2.Tag{Key: aws.String(k), Value: aws.String(v)}
+		})
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(0))
+	})
+	It("shouldn't repair instances of NodeClaims that haven't registered", func() {
+		nodeClaim, ec2Instance := registeredNodeClaim(nil)
+		nodeClaim.StatusConditions().MarkFalse(corev1beta1.Registered, "", "")
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(0))
+		Expect(ec2Instance.Tags).To(BeEmpty())
+	})
+	It("shouldn't repair instances of NodeClaims whose EC2NodeClass doesn't exist", func() {
+		_, ec2Instance := registeredNodeClaim(nil)
+		ExpectDeleted(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(0))
+		Expect(ec2Instance.Tags).To(BeEmpty())
+	})
+	It("should ignore NodeClaims whose instance no longer exists", func() {
+		_, ec2Instance := registeredNodeClaim(nil)
+		awsEnv.EC2API.Instances.Delete(*ec2Instance.InstanceId)
+		ExpectReconcileSucceeded(ctx, repairController, client.ObjectKey{})
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(0))
+	})
 })
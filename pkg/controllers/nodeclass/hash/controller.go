@@ -45,6 +45,12 @@ func NewController(kubeClient client.Client) corecontroller.Controller {
 }
 
 func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	// The drift hash folds in the resolved subnets and security groups, so it isn't recorded until the status controller
+	// has resolved them. Otherwise NodeClaims would be drifted as soon as they're resolved. Recording them updates the
+	// EC2NodeClass, which triggers another reconcile.
+	if !nodeClass.HasResolvedHashes() {
+		return reconcile.Result{}, nil
+	}
 	stored := nodeClass.DeepCopy()
 
 	if nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHashVersion] != v1beta1.EC2NodeClassHashVersion {
@@ -53,7 +59,7 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 		}
 	}
 	nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
-		v1beta1.AnnotationEC2NodeClassHash:        nodeClass.Hash(),
+		v1beta1.AnnotationEC2NodeClassHash:        nodeClass.DriftHash(),
 		v1beta1.AnnotationEC2NodeClassHashVersion: v1beta1.EC2NodeClassHashVersion,
 	})
 
@@ -101,7 +107,7 @@ func (c *Controller) updateNodeClaimHash(ctx context.Context, nodeClass *v1beta1
 			// Since the hashing mechanism has changed we will not be able to determine if the drifted status of the NodeClaim has changed
 			if nc.StatusConditions().GetCondition(corev1beta1.Drifted) == nil {
 				nc.Annotations = lo.Assign(nc.Annotations, map[string]string{
-					v1beta1.AnnotationEC2NodeClassHash: nodeClass.Hash(),
+					v1beta1.AnnotationEC2NodeClassHash: nodeClass.DriftHash(),
 				})
 			}
 
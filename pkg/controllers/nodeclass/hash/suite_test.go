@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/imdario/mergo"
+	"github.com/samber/lo"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_ "knative.dev/pkg/system/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -79,6 +80,12 @@ var _ = Describe("NodeClass Hash Controller", func() {
 	var nodeClass *v1beta1.EC2NodeClass
 	BeforeEach(func() {
 		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1beta1.AnnotationEC2NodeClassSubnetsHash:        v1beta1.ResolvedIDsHash([]string{"subnet-test1"}),
+					v1beta1.AnnotationEC2NodeClassSecurityGroupsHash: v1beta1.ResolvedIDsHash([]string{"sg-test1"}),
+				},
+			},
 			Spec: v1beta1.EC2NodeClassSpec{
 				SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{
 					{
@@ -103,7 +110,7 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 
-		expectedHash := nodeClass.Hash()
+		expectedHash := nodeClass.DriftHash()
 		Expect(nodeClass.ObjectMeta.Annotations[v1beta1.AnnotationEC2NodeClassHash]).To(Equal(expectedHash))
 
 		Expect(mergo.Merge(nodeClass, changes, mergo.WithOverride)).To(Succeed())
@@ -112,7 +119,7 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 
-		expectedHashTwo := nodeClass.Hash()
+		expectedHashTwo := nodeClass.DriftHash()
 		Expect(nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash]).To(Equal(expectedHashTwo))
 		Expect(expectedHash).ToNot(Equal(expectedHashTwo))
 
@@ -125,12 +132,58 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		Entry("MetadataOptions Drift", &v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPEndpoint: aws.String("disabled")}}}),
 		Entry("Context Drift", &v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{Context: aws.String("context-2")}}),
 	)
+	It("should update the drift hash when the resolved subnets or security groups change", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		expectedHash := nodeClass.DriftHash()
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, expectedHash))
+
+		nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassSecurityGroupsHash] = v1beta1.ResolvedIDsHash([]string{"sg-test2"})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, nodeClass.DriftHash()))
+		Expect(nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash]).ToNot(Equal(expectedHash))
+	})
+	It("should not record the drift hash until the resolved subnets and security groups are recorded", func() {
+		delete(nodeClass.Annotations, v1beta1.AnnotationEC2NodeClassSecurityGroupsHash)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Annotations).ToNot(HaveKey(v1beta1.AnnotationEC2NodeClassHash))
+		Expect(nodeClass.Annotations).ToNot(HaveKey(v1beta1.AnnotationEC2NodeClassHashVersion))
+	})
+	It("should not update the hashes of NodeClaims until the resolved subnets and security groups are recorded", func() {
+		nodeClass.Annotations = map[string]string{
+			v1beta1.AnnotationEC2NodeClassHash:        "abceduefed",
+			v1beta1.AnnotationEC2NodeClassHashVersion: "test",
+		}
+		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1beta1.AnnotationEC2NodeClassHash:        "123456",
+					v1beta1.AnnotationEC2NodeClassHashVersion: "test",
+				},
+			},
+			Spec: corev1beta1.NodeClaimSpec{
+				NodeClassRef: &corev1beta1.NodeClassReference{
+					Name: nodeClass.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, "123456"))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHashVersion, "test"))
+	})
 	It("should not update the drift hash when dynamic field is updated", func() {
 		ExpectApplied(ctx, env.Client, nodeClass)
 		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 
-		expectedHash := nodeClass.Hash()
+		expectedHash := nodeClass.DriftHash()
 		Expect(nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash]).To(Equal(expectedHash))
 
 		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{
@@ -155,25 +208,25 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		Expect(nodeClass.Annotations[v1beta1.AnnotationEC2NodeClassHash]).To(Equal(expectedHash))
 	})
 	It("should update ec2nodeclass-hash-version annotation when the ec2nodeclass-hash-version on the NodeClass does not match with the controller hash version", func() {
-		nodeClass.Annotations = map[string]string{
+		nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 			v1beta1.AnnotationEC2NodeClassHash:        "abceduefed",
 			v1beta1.AnnotationEC2NodeClassHashVersion: "test",
-		}
+		})
 		ExpectApplied(ctx, env.Client, nodeClass)
 
 		ExpectReconcileSucceeded(ctx, hashController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 
-		expectedHash := nodeClass.Hash()
+		expectedHash := nodeClass.DriftHash()
 		// Expect ec2nodeclass-hash on the NodeClass to be updated
 		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, expectedHash))
 		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHashVersion, v1beta1.EC2NodeClassHashVersion))
 	})
 	It("should update ec2nodeclass-hash-versions on all NodeClaims when the ec2nodeclass-hash-version does not match with the controller hash version", func() {
-		nodeClass.Annotations = map[string]string{
+		nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 			v1beta1.AnnotationEC2NodeClassHash:        "abceduefed",
 			v1beta1.AnnotationEC2NodeClassHashVersion: "test",
-		}
+		})
 		nodeClaimOne := coretest.NodeClaim(corev1beta1.NodeClaim{
 			ObjectMeta: metav1.ObjectMeta{
 				Annotations: map[string]string{
@@ -208,7 +261,7 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		nodeClaimOne = ExpectExists(ctx, env.Client, nodeClaimOne)
 		nodeClaimTwo = ExpectExists(ctx, env.Client, nodeClaimTwo)
 
-		expectedHash := nodeClass.Hash()
+		expectedHash := nodeClass.DriftHash()
 		// Expect ec2nodeclass-hash on the NodeClaims to be updated
 		Expect(nodeClaimOne.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, expectedHash))
 		Expect(nodeClaimOne.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHashVersion, v1beta1.EC2NodeClassHashVersion))
@@ -216,10 +269,10 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		Expect(nodeClaimTwo.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHashVersion, v1beta1.EC2NodeClassHashVersion))
 	})
 	It("should not update ec2nodeclass-hash on all NodeClaims when the ec2nodeclass-hash-version matches the controller hash version", func() {
-		nodeClass.Annotations = map[string]string{
+		nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 			v1beta1.AnnotationEC2NodeClassHash:        "abceduefed",
 			v1beta1.AnnotationEC2NodeClassHashVersion: "test-version",
-		}
+		})
 		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
 			ObjectMeta: metav1.ObjectMeta{
 				Annotations: map[string]string{
@@ -239,7 +292,7 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
 
-		expectedHash := nodeClass.Hash()
+		expectedHash := nodeClass.DriftHash()
 
 		// Expect ec2nodeclass-hash on the NodeClass to be updated
 		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, expectedHash))
@@ -249,10 +302,10 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHashVersion, v1beta1.EC2NodeClassHashVersion))
 	})
 	It("should not update ec2nodeclass-hash on the NodeClaim if it's drifted and the ec2nodeclass-hash-version does not match the controller hash version", func() {
-		nodeClass.Annotations = map[string]string{
+		nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 			v1beta1.AnnotationEC2NodeClassHash:        "abceduefed",
 			v1beta1.AnnotationEC2NodeClassHashVersion: "test",
-		}
+		})
 		nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
 			ObjectMeta: metav1.ObjectMeta{
 				Annotations: map[string]string{
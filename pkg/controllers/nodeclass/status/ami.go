@@ -16,31 +16,56 @@ package status
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"sigs.k8s.io/karpenter/pkg/events"
+
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 )
 
 type AMI struct {
 	amiProvider amifamily.Provider
+	clock       clock.Clock
+	recorder    events.Recorder
 }
 
 func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
-	amis, err := a.amiProvider.Get(ctx, nodeClass, &amifamily.Options{})
+	if err := a.validateOwners(nodeClass); err != nil {
+		return reconcile.Result{}, err
+	}
+	amis, err := a.amiProvider.List(ctx, nodeClass, &amifamily.Options{})
 	if err != nil {
+		// The AMIs resolved from the last good value of the parameter are kept, so that a parameter that's deleted or
+		// promoted to a bad value doesn't roll nodes
+		ssmErr := &amifamily.SSMParameterError{}
+		if errors.As(err, &ssmErr) {
+			nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeAMIsReady, ssmErr.Reason, "%s", ssmErr.Error())
+		}
 		return reconcile.Result{}, err
 	}
 	if len(amis) == 0 {
 		nodeClass.Status.AMIs = nil
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeAMIsReady, "AMIsNotFound", "no amis exist given constraints")
 		return reconcile.Result{}, fmt.Errorf("no amis exist given constraints")
 	}
-	nodeClass.Status.AMIs = lo.Map(amis, func(ami amifamily.AMI, _ int) v1beta1.AMI {
+	amis, err = a.filterOwners(ctx, nodeClass, amis)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	resolved := lo.Map(amis, func(ami amifamily.AMI, _ int) v1beta1.AMI {
 		reqs := ami.Requirements.NodeSelectorRequirements()
 		sort.Slice(reqs, func(i, j int) bool {
 			if len(reqs[i].Key) != len(reqs[j].Key) {
@@ -49,10 +74,151 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (r
 			return reqs[i].Key < reqs[j].Key
 		})
 		return v1beta1.AMI{
-			Name:         ami.Name,
-			ID:           ami.AmiID,
-			Requirements: reqs,
+			Name:             ami.Name,
+			ID:               ami.AmiID,
+			Owner:            ami.OwnerID,
+			Requirements:     reqs,
+			RootDeviceName:   ami.RootDeviceName,
+			RootSnapshotSize: ami.RootSnapshotSize,
+			DeprecationTime:  deprecationTime(ami),
 		}
 	})
-	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+	result := reconcile.Result{RequeueAfter: 5 * time.Minute}
+	if requeueAfter, held := a.hold(ctx, nodeClass, resolved); held {
+		result = reconcile.Result{RequeueAfter: lo.Min([]time.Duration{requeueAfter, 5 * time.Minute})}
+	} else {
+		nodeClass.Status.AMIs = resolved
+		nodeClass.Status.PendingAMIs = nil
+		nodeClass.Status.PendingAMIsDetectionTime = nil
+	}
+	nodeClass.StatusConditions().MarkTrue(v1beta1.ConditionTypeAMIsReady)
+	if err := a.validateRootVolumes(ctx, nodeClass); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := a.validateDeprecation(ctx, nodeClass); err != nil {
+		return reconcile.Result{}, err
+	}
+	return result, nil
+}
+
+// deprecationTime parses the deprecation time DescribeImages reported for the AMI, if any
+func deprecationTime(ami amifamily.AMI) *metav1.Time {
+	t, err := time.Parse(time.RFC3339, ami.DeprecationTime)
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
+
+// validateOwners warns through the status when an AMI selector term searches the images of every owner, since a name
+// or tags that are mistyped or reused can then match a look-alike public AMI published by another account.
+func (a *AMI) validateOwners(nodeClass *v1beta1.EC2NodeClass) error {
+	if !amifamily.UsesWildcardOwner(nodeClass.Spec.AMISelectorTerms) {
+		return nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypePublicAMISearch)
+	}
+	nodeClass.StatusConditions().MarkTrueWithReason(v1beta1.ConditionTypePublicAMISearch, "WildcardOwner",
+		"an amiSelectorTerm sets the owner to %q, which searches public AMIs from every account", amifamily.WildcardOwner)
+	return nil
+}
+
+// filterOwners drops the AMIs that aren't owned by an account in the allowed-ami-owners, noting them in the status. If
+// every AMI is dropped, the EC2NodeClass isn't ready, since launches would fail on the same check.
+func (a *AMI) filterOwners(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, amis amifamily.AMIs) (amifamily.AMIs, error) {
+	allowed, disallowed := amifamily.FilterAllowedOwners(ctx, amis)
+	if len(disallowed) == 0 {
+		return allowed, nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeDisallowedAMIs)
+	}
+	message := fmt.Sprintf("dropped amis whose owner isn't in allowed-ami-owners, %s", strings.Join(lo.Map(disallowed, func(ami amifamily.AMI, _ int) string {
+		return fmt.Sprintf("%s (owner %q)", ami.AmiID, ami.OwnerID)
+	}), ", "))
+	nodeClass.StatusConditions().MarkTrueWithReason(v1beta1.ConditionTypeDisallowedAMIs, "OwnerNotAllowed", "%s", message)
+	if len(allowed) == 0 {
+		nodeClass.Status.AMIs = nil
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeAMIsReady, "AMIOwnersNotAllowed", "%s", message)
+		return nil, fmt.Errorf("no amis are owned by an account in allowed-ami-owners")
+	}
+	return allowed, nil
+}
+
+// validateRootVolumes surfaces the AMIs in the status whose root snapshot is larger than the EC2NodeClass's root volume,
+// which EC2 would otherwise only report when rejecting a launch. If undersized root volumes are raised, the raise is
+// reported through an event instead.
+func (a *AMI) validateRootVolumes(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) error {
+	amiFamily := amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{})
+	undersized := amifamily.UndersizedRootVolumes(
+		lo.Ternary(len(nodeClass.Spec.BlockDeviceMappings) > 0, nodeClass.Spec.BlockDeviceMappings, amiFamily.DefaultBlockDeviceMappings()),
+		amifamily.StatusAMIs(nodeClass),
+	)
+	if len(undersized) == 0 || options.FromContext(ctx).RaiseUndersizedRootVolumes {
+		for _, u := range undersized {
+			a.recorder.Publish(RootVolumeRaisedEvent(nodeClass, u))
+		}
+		return nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeBlockDeviceTooSmall)
+	}
+	nodeClass.StatusConditions().MarkTrueWithReason(v1beta1.ConditionTypeBlockDeviceTooSmall, "RootSnapshotLargerThanVolume", "%s",
+		strings.Join(lo.Map(undersized, func(u amifamily.UndersizedRootVolume, _ int) string { return u.String() }), "; "))
+	return nil
+}
+
+// validateDeprecation surfaces the AMIs in the status that are deprecated, or will be within the ami-deprecation-window,
+// so that they can be replaced before selectors stop resolving them and launches start failing.
+func (a *AMI) validateDeprecation(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) error {
+	now := a.clock.Now()
+	deprecating := lo.Filter(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) bool {
+		return ami.DeprecationTime != nil && ami.DeprecationTime.Time.Before(now.Add(options.FromContext(ctx).AMIDeprecationWindow))
+	})
+	if len(deprecating) == 0 {
+		return nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeAMIsDeprecating)
+	}
+	sort.Slice(deprecating, func(i, j int) bool {
+		return deprecating[i].DeprecationTime.Before(deprecating[j].DeprecationTime)
+	})
+	soonest := deprecating[0]
+	reason, tense := "AMIDeprecated", "was"
+	if soonest.DeprecationTime.After(now) {
+		reason, tense = "AMIDeprecating", "will be"
+	}
+	nodeClass.StatusConditions().MarkTrueWithReason(v1beta1.ConditionTypeAMIsDeprecating, reason, "ami %q %s deprecated at %s, deprecating amis: %s",
+		soonest.ID, tense, soonest.DeprecationTime.UTC().Format(time.RFC3339), strings.Join(lo.Map(deprecating, func(ami v1beta1.AMI, _ int) string {
+			return fmt.Sprintf("%s (%s)", ami.ID, ami.DeprecationTime.UTC().Format(time.RFC3339))
+		}), ", "))
+	return nil
+}
+
+// hold determines whether adopting the resolved AMIs should be deferred by the EC2NodeClass's AMI stabilization. Held
+// AMIs are recorded as pending in the status along with the time they were first resolved, and the returned duration is
+// when they should next be considered for adoption.
+func (a *AMI) hold(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, resolved []v1beta1.AMI) (time.Duration, bool) {
+	stabilization := nodeClass.Spec.AMIStabilization
+	// There is nothing to stabilize against until AMIs have been adopted for the first time
+	if stabilization == nil || len(nodeClass.Status.AMIs) == 0 || sameAMIs(nodeClass.Status.AMIs, resolved) {
+		return 0, false
+	}
+	if nodeClass.Annotations[v1beta1.AnnotationForceAMIAdoption] == "true" {
+		logging.FromContext(ctx).With("ids", amiIDs(resolved)).Infof("forcing adoption of amis")
+		return 0, false
+	}
+	now := a.clock.Now()
+	// A different set of AMIs appearing while others are held restarts the stabilization window
+	if nodeClass.Status.PendingAMIsDetectionTime == nil || !sameAMIs(nodeClass.Status.PendingAMIs, resolved) {
+		logging.FromContext(ctx).With("ids", amiIDs(resolved), "window", stabilization.Window.Duration).Infof("holding amis for stabilization")
+		nodeClass.Status.PendingAMIs = resolved
+		nodeClass.Status.PendingAMIsDetectionTime = &metav1.Time{Time: now}
+	}
+	if remaining := nodeClass.Status.PendingAMIsDetectionTime.Add(stabilization.Window.Duration).Sub(now); remaining > 0 {
+		return remaining, true
+	}
+	if len(stabilization.AllowedHoursUTC) > 0 && !lo.Contains(stabilization.AllowedHoursUTC, int32(now.UTC().Hour())) {
+		return now.UTC().Truncate(time.Hour).Add(time.Hour).Sub(now), true
+	}
+	logging.FromContext(ctx).With("ids", amiIDs(resolved)).Infof("adopting stabilized amis")
+	return 0, false
+}
+
+func sameAMIs(a, b []v1beta1.AMI) bool {
+	return sets.New(amiIDs(a)...).Equal(sets.New(amiIDs(b)...))
+}
+
+func amiIDs(amis []v1beta1.AMI) []string {
+	return lo.Map(amis, func(ami v1beta1.AMI, _ int) string { return ami.ID })
 }
@@ -20,6 +20,8 @@ import (
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_ "knative.dev/pkg/system/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -28,6 +30,7 @@ import (
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -343,4 +346,481 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			},
 		))
 	})
+	Context("SSM Parameter", func() {
+		statusAMIs := func() []string {
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			return lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })
+		}
+		BeforeEach(func() {
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{SSMParameter: "/test/ami-id"}}
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/ami-id": "ami-test2"}
+		})
+		It("should resolve the AMI in the SSM parameter into status", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-test2"))
+			Expect(nodeClass.Status.AMIs[0].Requirements).To(ConsistOf(corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}},
+			}))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady).IsTrue()).To(BeTrue())
+		})
+		It("should cache SSM parameter lookups", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.SSMAPI.CalledWithGetParameterInput.Len()).To(Equal(1))
+		})
+		It("should set AMIsReady to false and keep the resolved AMIs when the parameter is deleted", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-test2"))
+
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/other": "ami-test3"}
+			awsEnv.EC2Cache.Flush()
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-test2"))
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("SSMParameterNotFound"))
+		})
+		It("should set AMIsReady to false when the parameter doesn't contain an AMI ID", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/ami-id": "not-an-ami"}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(BeEmpty())
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("SSMParameterInvalid"))
+		})
+		It("should recover once the parameter is fixed", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/ami-id": "not-an-ami"}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/ami-id": "ami-test3"}
+			fakeClock.Step(time.Second)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-test3"))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady).IsTrue()).To(BeTrue())
+		})
+	})
+	Context("AMI Owners", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-1"),
+						ImageId:      aws.String("ami-test1"),
+						OwnerId:      aws.String("123456789012"),
+						CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+						Tags:         []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-ami-1")}},
+					},
+				},
+			})
+		})
+		It("should record the owner of each AMI in status", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Name: "test-ami-1"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].Owner).To(Equal("123456789012"))
+		})
+		It("should restrict terms without an owner to the default owners", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AMIDefaultOwners: []string{"self", "123456789012"}}))
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"Name": "test-ami-1"}}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			input := awsEnv.EC2API.CalledWithDescribeImagesInput.Pop()
+			Expect(aws.StringValueSlice(input.Owners)).To(Equal([]string{"self", "123456789012"}))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypePublicAMISearch)).To(BeNil())
+		})
+		It("should not apply the default owners to terms that set an owner", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Name: "test-ami-1", Owner: "123456789012"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			input := awsEnv.EC2API.CalledWithDescribeImagesInput.Pop()
+			Expect(aws.StringValueSlice(input.Owners)).To(Equal([]string{"123456789012"}))
+		})
+		It("should warn when a term searches the AMIs of every owner", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Name: "test-ami-1", Owner: "*"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			input := awsEnv.EC2API.CalledWithDescribeImagesInput.Pop()
+			Expect(input.Owners).To(BeNil())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypePublicAMISearch)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("WildcardOwner"))
+			// The warning doesn't affect the readiness of the EC2NodeClass
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady).IsTrue()).To(BeTrue())
+		})
+		It("should clear the warning once no term searches the AMIs of every owner", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Name: "test-ami-1", Owner: "*"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Name: "test-ami-1"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypePublicAMISearch)).To(BeNil())
+		})
+	})
+	Context("Allowed AMI Owners", func() {
+		image := func(id, owner, arch string) *ec2.Image {
+			return &ec2.Image{
+				Name:         aws.String(id),
+				ImageId:      aws.String(id),
+				OwnerId:      aws.String(owner),
+				CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+				Architecture: aws.String(arch),
+			}
+		}
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AllowedAMIOwners: []string{"123456789012", "602401143452"}}))
+		})
+		It("should drop an AMI selected by id whose owner isn't allowed", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+				image("ami-allowed", "123456789012", "x86_64"),
+				image("ami-disallowed", "111122223333", "arm64"),
+			}})
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{ID: "ami-allowed"}, {ID: "ami-disallowed"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-allowed"))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady).IsTrue()).To(BeTrue())
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeDisallowedAMIs)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("OwnerNotAllowed"))
+			Expect(condition.Message).To(ContainSubstring(`ami-disallowed (owner "111122223333")`))
+			Expect(condition.Message).ToNot(ContainSubstring("ami-allowed"))
+		})
+		It("should allow the default AMIs of an AMI family that are owned by an allowed account", func() {
+			version := lo.Must(awsEnv.VersionProvider.Get(ctx))
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/latest/image_id", version): "ami-id-123",
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/arm64/latest/image_id", version):  "ami-id-456",
+			}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+				image("ami-id-123", "602401143452", "x86_64"),
+				image("ami-id-456", "602401143452", "arm64"),
+			}})
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+			nodeClass.Spec.AMISelectorTerms = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-id-123", "ami-id-456"))
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.Owner })).To(HaveEach("602401143452"))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady).IsTrue()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeDisallowedAMIs)).To(BeNil())
+		})
+		It("should set AMIsReady to false when no AMI has an allowed owner", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+				image("ami-disallowed", "111122223333", "x86_64"),
+			}})
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{ID: "ami-disallowed"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(BeEmpty())
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("AMIOwnersNotAllowed"))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeDisallowedAMIs).IsTrue()).To(BeTrue())
+		})
+	})
+	Context("AMI Stabilization", func() {
+		setImage := func(id string) {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String(id),
+						ImageId:      aws.String(id),
+						CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			awsEnv.EC2Cache.Flush()
+		}
+		statusAMIs := func() []string {
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			return lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })
+		}
+		pendingAMIs := func() []string {
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			return lo.Map(nodeClass.Status.PendingAMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })
+		}
+		BeforeEach(func() {
+			// 10:00 UTC
+			fakeClock.SetTime(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+			nodeClass.Spec.AMIStabilization = &v1beta1.AMIStabilization{Window: metav1.Duration{Duration: time.Hour}}
+			setImage("ami-current")
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+		})
+		It("should adopt new AMIs immediately without AMI stabilization", func() {
+			nodeClass.Spec.AMIStabilization = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			setImage("ami-new")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-new"))
+			Expect(nodeClass.Status.PendingAMIs).To(BeEmpty())
+			Expect(nodeClass.Status.PendingAMIsDetectionTime).To(BeNil())
+		})
+		It("should hold new AMIs until the stabilization window elapses", func() {
+			setImage("ami-new")
+			result := ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+			Expect(pendingAMIs()).To(ConsistOf("ami-new"))
+			Expect(nodeClass.Status.PendingAMIsDetectionTime.Time).To(BeTemporally("==", fakeClock.Now()))
+
+			fakeClock.Step(59 * time.Minute)
+			result = ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+
+			fakeClock.Step(time.Minute)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-new"))
+			Expect(nodeClass.Status.PendingAMIs).To(BeEmpty())
+			Expect(nodeClass.Status.PendingAMIsDetectionTime).To(BeNil())
+		})
+		It("should hold stabilized AMIs until the current hour is allowed", func() {
+			nodeClass.Spec.AMIStabilization.AllowedHoursUTC = []int32{2, 3}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			setImage("ami-new")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			// 12:30 UTC, the window has elapsed but the hour isn't allowed
+			fakeClock.Step(150 * time.Minute)
+			result := ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+			Expect(pendingAMIs()).To(ConsistOf("ami-new"))
+
+			// 01:58 UTC, the next reconcile should be at the top of the hour
+			fakeClock.SetTime(time.Date(2024, 1, 2, 1, 58, 0, 0, time.UTC))
+			result = ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(2 * time.Minute))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+
+			// 02:00 UTC
+			fakeClock.Step(2 * time.Minute)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-new"))
+			Expect(nodeClass.Status.PendingAMIs).To(BeEmpty())
+		})
+		It("should adopt new AMIs immediately when adoption is forced", func() {
+			setImage("ami-new")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(pendingAMIs()).To(ConsistOf("ami-new"))
+
+			nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{v1beta1.AnnotationForceAMIAdoption: "true"})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-new"))
+			Expect(nodeClass.Status.PendingAMIs).To(BeEmpty())
+			Expect(nodeClass.Status.PendingAMIsDetectionTime).To(BeNil())
+		})
+		It("should restart the stabilization window when another AMI appears during the hold", func() {
+			setImage("ami-new")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(pendingAMIs()).To(ConsistOf("ami-new"))
+
+			fakeClock.Step(30 * time.Minute)
+			setImage("ami-newer")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+			Expect(pendingAMIs()).To(ConsistOf("ami-newer"))
+			Expect(nodeClass.Status.PendingAMIsDetectionTime.Time).To(BeTemporally("==", fakeClock.Now()))
+
+			// The original window would have elapsed, but the newer AMI has only been held for 30 minutes
+			fakeClock.Step(30 * time.Minute)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+
+			fakeClock.Step(30 * time.Minute)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-newer"))
+		})
+		It("should clear pending AMIs when the resolved AMIs revert during the hold", func() {
+			setImage("ami-new")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(pendingAMIs()).To(ConsistOf("ami-new"))
+
+			setImage("ami-current")
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(statusAMIs()).To(ConsistOf("ami-current"))
+			Expect(nodeClass.Status.PendingAMIs).To(BeEmpty())
+			Expect(nodeClass.Status.PendingAMIsDetectionTime).To(BeNil())
+		})
+	})
+	Context("Root Volume Size", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:           aws.String("test-ami-1"),
+						ImageId:        aws.String("ami-test1"),
+						CreationDate:   aws.String(time.Now().Format(time.RFC3339)),
+						Architecture:   aws.String("x86_64"),
+						RootDeviceName: aws.String("/dev/xvda"),
+						BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+							{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsBlockDevice{VolumeSize: aws.Int64(100), SnapshotId: aws.String("snap-test1")}},
+							{DeviceName: aws.String("/dev/xvdb"), Ebs: &ec2.EbsBlockDevice{VolumeSize: aws.Int64(500), SnapshotId: aws.String("snap-test2")}},
+						},
+					},
+				},
+			})
+			nodeClass.Spec.BlockDeviceMappings = []*v1beta1.BlockDeviceMapping{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					EBS:        &v1beta1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("50Gi")), VolumeType: aws.String(ec2.VolumeTypeGp3)},
+				},
+			}
+		})
+		It("should record the root snapshot size of resolved AMIs", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].RootDeviceName).To(Equal("/dev/xvda"))
+			Expect(nodeClass.Status.AMIs[0].RootSnapshotSize.String()).To(Equal("100Gi"))
+		})
+		It("should set BlockDeviceTooSmall when the root volume is smaller than the root snapshot", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeBlockDeviceTooSmall)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Message).To(Equal(`ami "ami-test1" requires a root volume of at least 100Gi, but 50Gi is configured`))
+		})
+		It("should use the volume marked as the root volume", func() {
+			nodeClass.Spec.BlockDeviceMappings[0].DeviceName = aws.String("/dev/sda1")
+			nodeClass.Spec.BlockDeviceMappings[0].RootVolume = true
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeBlockDeviceTooSmall).IsTrue()).To(BeTrue())
+		})
+		It("should compare against the AMI family's default root volume", func() {
+			nodeClass.Spec.BlockDeviceMappings = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeBlockDeviceTooSmall)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Message).To(ContainSubstring("but 20Gi is configured"))
+		})
+		It("should clear BlockDeviceTooSmall once the root volume fits the root snapshot", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeBlockDeviceTooSmall).IsTrue()).To(BeTrue())
+
+			nodeClass.Spec.BlockDeviceMappings[0].EBS.VolumeSize = lo.ToPtr(resource.MustParse("100Gi"))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeBlockDeviceTooSmall)).To(BeNil())
+		})
+		It("should publish an event instead of setting BlockDeviceTooSmall when undersized root volumes are raised", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RaiseUndersizedRootVolumes: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeBlockDeviceTooSmall)).To(BeNil())
+			Expect(awsEnv.EventRecorder.Calls("RootVolumeRaised")).To(Equal(1))
+			Expect(awsEnv.EventRecorder.DetectedEvent(`Raising the root volume from 50Gi to 100Gi to fit the root snapshot of ami "ami-test1"`)).To(BeTrue())
+		})
+	})
+	Context("AMI Deprecation", func() {
+		image := func(id, arch, deprecationTime string) *ec2.Image {
+			return &ec2.Image{
+				Name:            aws.String(id),
+				ImageId:         aws.String(id),
+				CreationDate:    aws.String(time.Now().Format(time.RFC3339)),
+				DeprecationTime: lo.Ternary(deprecationTime != "", aws.String(deprecationTime), nil),
+				Architecture:    aws.String(arch),
+			}
+		}
+		setImages := func(images ...*ec2.Image) {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: images})
+			awsEnv.EC2Cache.Flush()
+		}
+		BeforeEach(func() {
+			fakeClock.SetTime(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+		})
+		It("should not set a deprecation time or AMIsDeprecating for AMIs without a deprecation time", func() {
+			setImages(image("ami-test1", "x86_64", ""))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].DeprecationTime).To(BeNil())
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating)).To(BeNil())
+		})
+		It("should record the deprecation time without AMIsDeprecating when it's outside of the window", func() {
+			setImages(image("ami-test1", "x86_64", "2024-02-01T00:00:00.000Z"))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].DeprecationTime.Time).To(BeTemporally("==", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating)).To(BeNil())
+		})
+		It("should set AMIsDeprecating with the soonest deprecation time when AMIs are deprecating within the window", func() {
+			setImages(
+				image("ami-test1", "x86_64", "2024-01-10T00:00:00.000Z"),
+				image("ami-test2", "arm64", "2024-01-05T00:00:00.000Z"),
+			)
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("AMIDeprecating"))
+			Expect(condition.Message).To(HavePrefix(`ami "ami-test2" will be deprecated at 2024-01-05T00:00:00Z`))
+			Expect(condition.Message).To(ContainSubstring("ami-test1 (2024-01-10T00:00:00Z)"))
+		})
+		It("should only report AMIs that are already deprecated when the window is 0", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AMIDeprecationWindow: lo.ToPtr(time.Duration(0))}))
+			setImages(image("ami-test1", "x86_64", "2024-01-05T00:00:00.000Z"))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating)).To(BeNil())
+
+			fakeClock.SetTime(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC))
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("AMIDeprecated"))
+			Expect(condition.Message).To(HavePrefix(`ami "ami-test1" was deprecated at 2024-01-05T00:00:00Z`))
+		})
+		It("should clear AMIsDeprecating once the deprecating AMIs are replaced", func() {
+			setImages(image("ami-test1", "x86_64", "2024-01-05T00:00:00.000Z"))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating).IsTrue()).To(BeTrue())
+
+			setImages(image("ami-test2", "x86_64", "2025-01-01T00:00:00.000Z"))
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsDeprecating)).To(BeNil())
+		})
+	})
 })
@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+const (
+	areaBackoffBase = time.Second
+	areaBackoffMax  = 5 * time.Minute
+)
+
+// areaBackoff counts the consecutive failures of each status reconciler, by EC2NodeClass, so that a reconciler that
+// keeps failing is retried with exponential backoff without delaying the reconcilers that succeed. The counts are only
+// kept in memory, and start over when the controller restarts.
+type areaBackoff struct {
+	clock    clock.Clock
+	mu       sync.Mutex
+	failures map[types.UID]map[string]areaFailure
+}
+
+// areaFailure is the number of consecutive failures of an area, the generation of the EC2NodeClass that it last failed
+// for, and when it may next be retried
+type areaFailure struct {
+	attempts   int
+	generation int64
+	retryAt    time.Time
+}
+
+func newAreaBackoff(clk clock.Clock) *areaBackoff {
+	return &areaBackoff{clock: clk, failures: map[types.UID]map[string]areaFailure{}}
+}
+
+// waiting returns how long is left until the area may be retried. Areas aren't held back once the spec of the
+// EC2NodeClass changes, since the change may fix what they failed on.
+func (b *areaBackoff) waiting(uid types.UID, generation int64, area string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failure, ok := b.failures[uid][area]
+	if !ok || failure.generation != generation {
+		return 0
+	}
+	return max(failure.retryAt.Sub(b.clock.Now()), 0)
+}
+
+// failed records a failure of the area, returning the number of consecutive attempts that have failed and how long to
+// wait before the next one
+func (b *areaBackoff) failed(uid types.UID, generation int64, area string) (int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.failures[uid]; !ok {
+		b.failures[uid] = map[string]areaFailure{}
+	}
+	attempts := b.failures[uid][area].attempts + 1
+	delay := areaBackoffMax
+	if attempts <= 20 {
+		delay = min(areaBackoffBase<<(attempts-1), areaBackoffMax)
+	}
+	b.failures[uid][area] = areaFailure{attempts: attempts, generation: generation, retryAt: b.clock.Now().Add(delay)}
+	return attempts, delay
+}
+
+// succeeded resets the failures of the area
+func (b *areaBackoff) succeeded(uid types.UID, area string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures[uid], area)
+	if len(b.failures[uid]) == 0 {
+		delete(b.failures, uid)
+	}
+}
+
+// forget drops the failures of every area of the EC2NodeClass
+func (b *areaBackoff) forget(uid types.UID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, uid)
+}
@@ -0,0 +1,129 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Status Controller Backoff", func() {
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			Spec: v1beta1.EC2NodeClassSpec{
+				SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{
+					{
+						Tags: map[string]string{"foo": "invalid"},
+					},
+				},
+				SecurityGroupSelectorTerms: []v1beta1.SecurityGroupSelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				AMISelectorTerms: []v1beta1.AMISelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+			},
+		})
+	})
+	It("should back off only the failing reconciler and keep the status of the others fresh", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		for i, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			securityGroupID := []string{"sg-test1", "sg-test2", "sg-test3"}[i]
+			awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{
+				{GroupId: aws.String(securityGroupID), GroupName: aws.String(securityGroupID)},
+			}})
+			awsEnv.SecurityGroupCache.Flush()
+
+			result := ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(delay))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.SecurityGroups).To(Equal([]v1beta1.SecurityGroup{{ID: securityGroupID, Name: securityGroupID}}))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady).IsTrue()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady).IsTrue()).To(BeTrue())
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("SubnetsNotFound"))
+			Expect(condition.Message).ToNot(ContainSubstring("attempt"))
+			fakeClock.Step(delay)
+		}
+	})
+	It("should report the attempt count of the failing reconciler until it succeeds", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		for i, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			ExpectMetricGaugeValue("karpenter_nodeclasses_status_failed_attempts", float64(i+1), map[string]string{"nodeclass": nodeClass.Name, "reconciler": "subnet"})
+			fakeClock.Step(delay)
+		}
+		_, found := FindMetricWithLabelValues("karpenter_nodeclasses_status_failed_attempts", map[string]string{"nodeclass": nodeClass.Name, "reconciler": "securitygroup"})
+		Expect(found).To(BeFalse())
+
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"*": "*"}}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		_, found = FindMetricWithLabelValues("karpenter_nodeclasses_status_failed_attempts", map[string]string{"nodeclass": nodeClass.Name, "reconciler": "subnet"})
+		Expect(found).To(BeFalse())
+	})
+	It("should not retry the failing reconciler before its backoff expires", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		Expect(ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass)).RequeueAfter).To(Equal(time.Second))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		stored := nodeClass.DeepCopy()
+
+		fakeClock.Step(500 * time.Millisecond)
+		Expect(ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass)).RequeueAfter).To(Equal(500 * time.Millisecond))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.ResourceVersion).To(Equal(stored.ResourceVersion))
+
+		fakeClock.Step(500 * time.Millisecond)
+		Expect(ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass)).RequeueAfter).To(Equal(2 * time.Second))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.ResourceVersion).To(Equal(stored.ResourceVersion))
+	})
+	It("should reset the backoff once the failing reconciler succeeds", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		fakeClock.Step(time.Second)
+		Expect(ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass)).RequeueAfter).To(Equal(2 * time.Second))
+
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"*": "*"}}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		Expect(ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass)).RequeueAfter).To(Equal(5 * time.Minute))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady).IsTrue()).To(BeTrue())
+
+		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"foo": "invalid"}}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		Expect(ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass)).RequeueAfter).To(Equal(time.Second))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady).IsFalse()).To(BeTrue())
+	})
+})
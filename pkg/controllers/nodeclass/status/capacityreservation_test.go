@@ -0,0 +1,114 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Capacity Reservation Status Controller", func() {
+	BeforeEach(func() {
+		awsEnv.EC2API.DescribeCapacityReservationsOutput.Set(&ec2.DescribeCapacityReservationsOutput{
+			CapacityReservations: []*ec2.CapacityReservation{
+				{
+					CapacityReservationId: aws.String("cr-test2"),
+					InstanceType:          aws.String("p5.48xlarge"),
+					AvailabilityZone:      aws.String("test-zone-1b"),
+					ReservationType:       aws.String(ec2.CapacityReservationTypeCapacityBlock),
+					State:                 aws.String(ec2.CapacityReservationStateActive),
+					Tags:                  []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-capacity-block")}},
+				},
+				{
+					CapacityReservationId: aws.String("cr-test1"),
+					InstanceType:          aws.String("m5.large"),
+					AvailabilityZone:      aws.String("test-zone-1a"),
+					ReservationType:       aws.String(ec2.CapacityReservationTypeDefault),
+					State:                 aws.String(ec2.CapacityReservationStateActive),
+					Tags:                  []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-capacity-reservation")}},
+				},
+				{
+					CapacityReservationId: aws.String("cr-test3"),
+					InstanceType:          aws.String("p5.48xlarge"),
+					AvailabilityZone:      aws.String("test-zone-1a"),
+					ReservationType:       aws.String(ec2.CapacityReservationTypeCapacityBlock),
+					State:                 aws.String(ec2.CapacityReservationStateScheduled),
+					Tags:                  []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-capacity-block")}},
+				},
+			},
+		})
+	})
+	It("should not resolve capacity reservations when there are no capacity reservation selector terms", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(BeNil())
+	})
+	It("should resolve the active capacity reservations selected by tags", func() {
+		nodeClass.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+			{Tags: map[string]string{"Name": "*"}},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(Equal([]v1beta1.CapacityReservation{
+			{ID: "cr-test1", InstanceType: "m5.large", AvailabilityZone: "test-zone-1a", ReservationType: ec2.CapacityReservationTypeDefault},
+			{ID: "cr-test2", InstanceType: "p5.48xlarge", AvailabilityZone: "test-zone-1b", ReservationType: ec2.CapacityReservationTypeCapacityBlock},
+		}))
+	})
+	It("should resolve capacity reservations selected by id", func() {
+		nodeClass.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+			{ID: "cr-test2"},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(Equal([]v1beta1.CapacityReservation{
+			{ID: "cr-test2", InstanceType: "p5.48xlarge", AvailabilityZone: "test-zone-1b", ReservationType: ec2.CapacityReservationTypeCapacityBlock},
+		}))
+	})
+	It("should not resolve capacity blocks that haven't started", func() {
+		nodeClass.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+			{ID: "cr-test3"},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(BeEmpty())
+	})
+	It("should clear the capacity reservations when the selector terms are removed", func() {
+		nodeClass.Spec.CapacityReservationSelectorTerms = []v1beta1.CapacityReservationSelectorTerm{
+			{ID: "cr-test2"},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(HaveLen(1))
+
+		nodeClass.Spec.CapacityReservationSelectorTerms = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(BeNil())
+	})
+})
@@ -17,8 +17,13 @@ package status
 import (
 	"context"
 
-	"go.uber.org/multierr"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -26,14 +31,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"sigs.k8s.io/karpenter/pkg/events"
 	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
 	"sigs.k8s.io/karpenter/pkg/utils/result"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 )
 
@@ -43,29 +53,57 @@ type nodeClassStatusReconciler interface {
 	Reconcile(context.Context, *v1beta1.EC2NodeClass) (reconcile.Result, error)
 }
 
+// area is a status reconciler along with the condition that it sets, if any. Each area is retried with its own
+// backoff when it fails.
+type area struct {
+	name       string
+	condition  apis.ConditionType
+	reconciler nodeClassStatusReconciler
+}
+
 type Controller struct {
 	kubeClient client.Client
 
-	ami             *AMI
-	instanceprofile *InstanceProfile
-	subnet          *Subnet
-	securitygroup   *SecurityGroup
-	launchtemplate  *LaunchTemplate
+	refresh             *Refresh
+	ami                 *AMI
+	instanceprofile     *InstanceProfile
+	subnet              *Subnet
+	subnetclustertag    *SubnetClusterTag
+	securitygroup       *SecurityGroup
+	capacityreservation *CapacityReservation
+	launchtemplate      *LaunchTemplate
+	interruptionqueue   *InterruptionQueue
+
+	rateLimiter *awsRateLimiter
+	backoff     *areaBackoff
 }
 
-func NewController(kubeClient client.Client, subnetProvider subnet.Provider, securityGroupProvider securitygroup.Provider,
-	amiProvider amifamily.Provider, instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider) corecontroller.Controller {
+func NewController(kubeClient client.Client, clk clock.Clock, recorder events.Recorder, ec2api ec2iface.EC2API, subnetProvider subnet.Provider, securityGroupProvider securitygroup.Provider,
+	amiProvider amifamily.Provider, instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider, instanceTypeProvider instancetype.Provider,
+	pricingProvider pricing.Provider, capacityReservationProvider capacityreservation.Provider, sqsProvider sqs.Provider) corecontroller.Controller {
 	return corecontroller.Typed[*v1beta1.EC2NodeClass](kubeClient, &Controller{
 		kubeClient: kubeClient,
 
-		ami:             &AMI{amiProvider: amiProvider},
-		subnet:          &Subnet{subnetProvider: subnetProvider},
-		securitygroup:   &SecurityGroup{securityGroupProvider: securityGroupProvider},
-		instanceprofile: &InstanceProfile{instanceProfileProvider: instanceProfileProvider},
-		launchtemplate:  &LaunchTemplate{launchTemplateProvider: launchTemplateProvider},
+		refresh: &Refresh{instanceTypeProvider: instanceTypeProvider, pricingProvider: pricingProvider, subnetProvider: subnetProvider,
+			securityGroupProvider: securityGroupProvider},
+		ami:                 &AMI{amiProvider: amiProvider, clock: clk, recorder: recorder},
+		subnet:              &Subnet{kubeClient: kubeClient, subnetProvider: subnetProvider, recorder: recorder},
+		subnetclustertag:    &SubnetClusterTag{ec2api: ec2api, subnetProvider: subnetProvider},
+		securitygroup:       &SecurityGroup{securityGroupProvider: securityGroupProvider},
+		capacityreservation: &CapacityReservation{capacityReservationProvider: capacityReservationProvider},
+		instanceprofile:     &InstanceProfile{instanceProfileProvider: instanceProfileProvider},
+		launchtemplate:      &LaunchTemplate{launchTemplateProvider: launchTemplateProvider},
+		interruptionqueue:   &InterruptionQueue{kubeClient: kubeClient, clock: clk, recorder: recorder, sqsProvider: sqsProvider},
+
+		rateLimiter: newAWSRateLimiter("ami", "subnet", "securitygroup", "instanceprofile", "launchtemplate"),
+		backoff:     newAreaBackoff(clk),
 	})
 }
 
+// Reconcile runs every area, even when an earlier one fails, and persists what the areas resolved in a single status
+// patch. Failures aren't returned to the workqueue, whose backoff would apply to every area. Instead, the EC2NodeClass
+// is requeued at the soonest of the intervals of the areas that succeeded and the backoffs of the areas that failed.
+// Areas that are still backing off are skipped, so that reconciles triggered by the other areas don't retry them early.
 func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
 	if !controllerutil.ContainsFinalizer(nodeClass, v1beta1.TerminationFinalizer) {
 		stored := nodeClass.DeepCopy()
@@ -77,30 +115,83 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 	stored := nodeClass.DeepCopy()
 
 	var results []reconcile.Result
-	var errs error
-	for _, reconciler := range []nodeClassStatusReconciler{
-		c.ami,
-		c.subnet,
-		c.securitygroup,
-		c.instanceprofile,
-		c.launchtemplate,
+	for _, a := range []area{
+		// Refreshing runs first, and outside of the rate limit, so that the reconcilers after it resolve from fresh data
+		{name: "refresh", reconciler: c.refresh},
+		{name: "ami", condition: v1beta1.ConditionTypeAMIsReady, reconciler: c.rateLimiter.limit("ami", c.ami)},
+		{name: "subnet", condition: v1beta1.ConditionTypeSubnetsReady, reconciler: c.rateLimiter.limit("subnet", c.subnet)},
+		// Subnet cluster tagging lists subnets from the cache and only calls AWS for subnets missing the tag, so it
+		// doesn't take a share of the rate limit
+		{name: "subnetclustertag", reconciler: c.subnetclustertag},
+		{name: "securitygroup", condition: v1beta1.ConditionTypeSecurityGroupsReady, reconciler: c.rateLimiter.limit("securitygroup", c.securitygroup)},
+		// Capacity reservations are only described for the EC2NodeClasses that select them, so resolving them doesn't
+		// take a share of the rate limit
+		{name: "capacityreservation", reconciler: c.capacityreservation},
+		{name: "instanceprofile", reconciler: c.rateLimiter.limit("instanceprofile", c.instanceprofile)},
+		{name: "launchtemplate", reconciler: c.rateLimiter.limit("launchtemplate", c.launchtemplate)},
+		// Probing the interruption queue doesn't take a share of the rate limit since its result is shared by every
+		// EC2NodeClass
+		{name: "interruptionqueue", condition: v1beta1.ConditionTypeInterruptionQueueReady, reconciler: c.interruptionqueue},
 	} {
-		res, err := reconciler.Reconcile(ctx, nodeClass)
-		errs = multierr.Append(errs, err)
-		results = append(results, res)
+		if wait := c.backoff.waiting(nodeClass.UID, nodeClass.Generation, a.name); wait > 0 {
+			results = append(results, reconcile.Result{RequeueAfter: wait})
+			continue
+		}
+		res, err := a.reconciler.Reconcile(ctx, nodeClass)
+		if err == nil {
+			c.backoff.succeeded(nodeClass.UID, a.name)
+			failedAttempts.DeleteLabelValues(nodeClass.Name, a.name)
+			results = append(results, res)
+			continue
+		}
+		attempts, delay := c.backoff.failed(nodeClass.UID, nodeClass.Generation, a.name)
+		logging.FromContext(ctx).With("reconciler", a.name, "attempt", attempts).Errorf("reconciling status, %s", err)
+		failedAttempts.WithLabelValues(nodeClass.Name, a.name).Set(float64(attempts))
+		c.markFailed(nodeClass, a.condition, err)
+		results = append(results, reconcile.Result{RequeueAfter: delay})
+	}
+	if !nodeClass.DeletionTimestamp.IsZero() {
+		c.backoff.forget(nodeClass.UID)
+		failedAttempts.DeletePartialMatch(prometheus.Labels{nodeClassLabel: nodeClass.Name})
 	}
 
+	// The status subresource ignores metadata, so the resolved hashes are read before the status patch overwrites them
+	resolvedHashes := lo.PickByKeys(nodeClass.Annotations, []string{v1beta1.AnnotationEC2NodeClassSubnetsHash, v1beta1.AnnotationEC2NodeClassSecurityGroupsHash})
 	if !equality.Semantic.DeepEqual(stored, nodeClass) {
 		if err := c.kubeClient.Status().Patch(ctx, nodeClass, client.MergeFrom(stored)); err != nil {
-			errs = multierr.Append(errs, client.IgnoreNotFound(err))
+			return reconcile.Result{}, client.IgnoreNotFound(err)
 		}
 	}
-	if errs != nil {
-		return reconcile.Result{}, errs
+	if err := c.patchResolvedHashes(ctx, nodeClass, resolvedHashes); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
 	return result.Min(results...), nil
 }
 
+// patchResolvedHashes records the hashes of the resolved subnet and security group IDs in annotations, which the hash
+// controller folds into the drift hash of the EC2NodeClass
+func (c *Controller) patchResolvedHashes(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, resolvedHashes map[string]string) error {
+	stored := nodeClass.DeepCopy()
+	nodeClass.Annotations = lo.Assign(nodeClass.Annotations, resolvedHashes)
+	if equality.Semantic.DeepEqual(stored, nodeClass) {
+		return nil
+	}
+	return c.kubeClient.Patch(ctx, nodeClass, client.MergeFrom(stored))
+}
+
+// markFailed sets the condition of an area that failed without setting it to false, e.g. because the AWS call failed,
+// to false with the error. The attempt count is left out of the condition, since changing the condition on every attempt
+// would update the EC2NodeClass and trigger another reconcile, and is reported by the status_failed_attempts metric.
+func (c *Controller) markFailed(nodeClass *v1beta1.EC2NodeClass, condition apis.ConditionType, err error) {
+	if condition == "" {
+		return
+	}
+	if cond := nodeClass.StatusConditions().GetCondition(condition); cond != nil && cond.IsFalse() {
+		return
+	}
+	nodeClass.StatusConditions().MarkFalse(condition, "ReconcileFailed", "%s", err.Error())
+}
+
 func (c *Controller) Name() string {
 	return "nodeclass.status"
 }
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+)
+
+func RootVolumeRaisedEvent(nodeClass *v1beta1.EC2NodeClass, undersized amifamily.UndersizedRootVolume) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeNormal,
+		Reason:         "RootVolumeRaised",
+		Message:        fmt.Sprintf("Raising the root volume from %s to %s to fit the root snapshot of ami %q", undersized.VolumeSize.String(), undersized.MinimumSize.String(), undersized.AMIID),
+		DedupeValues:   []string{string(nodeClass.UID), undersized.AMIID},
+	}
+}
+
+func MixedZoneTypesEvent(nodeClass *v1beta1.EC2NodeClass, nodePool string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeWarning,
+		Reason:         "MixedZoneTypes",
+		Message:        fmt.Sprintf("NodePool %q can launch into both Wavelength Zone and other subnets, add a %s requirement to separate them", nodePool, v1.LabelTopologyZone),
+		DedupeValues:   []string{string(nodeClass.UID), nodePool},
+	}
+}
+
+func InterruptionQueueNotConfiguredEvent(nodeClass *v1beta1.EC2NodeClass) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeWarning,
+		Reason:         "InterruptionQueueNotConfigured",
+		Message:        "A NodePool that allows spot uses the EC2NodeClass, but interruption-queue isn't set, so spot interruptions won't be handled before instances are reclaimed",
+		DedupeValues:   []string{string(nodeClass.UID)},
+	}
+}
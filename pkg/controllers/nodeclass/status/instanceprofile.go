@@ -16,7 +16,9 @@ package status
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -32,6 +34,13 @@ type InstanceProfile struct {
 func (ip *InstanceProfile) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
 	if nodeClass.Spec.Role != "" {
 		name, err := ip.instanceProfileProvider.Create(ctx, nodeClass)
+		// A mismatch can't be corrected by retrying, so the instance profile is still used and the mismatch is reported
+		mismatch := &instanceprofile.MismatchError{}
+		if errors.As(err, &mismatch) {
+			nodeClass.Status.InstanceProfile = name
+			nodeClass.StatusConditions().MarkTrueWithReason(v1beta1.ConditionTypeInstanceProfileMismatch, mismatch.Reason, "%s", mismatch.Error())
+			return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+		}
 		if err != nil {
 			return reconcile.Result{}, fmt.Errorf("creating instance profile, %w", err)
 		}
@@ -39,6 +48,5 @@ func (ip *InstanceProfile) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2
 	} else {
 		nodeClass.Status.InstanceProfile = lo.FromPtr(nodeClass.Spec.InstanceProfile)
 	}
-
-	return reconcile.Result{}, nil
+	return reconcile.Result{}, nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeInstanceProfileMismatch)
 }
@@ -15,15 +15,21 @@ limitations under the License.
 package status_test
 
 import (
+	"time"
+
 	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
 	_ "knative.dev/pkg/system/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -132,4 +138,189 @@ var _ = Describe("NodeClass InstanceProfile Status Controller", func() {
 		Expect(awsEnv.IAMAPI.CreateInstanceProfileBehavior.Calls()).To(BeZero())
 		Expect(awsEnv.IAMAPI.AddRoleToInstanceProfileBehavior.Calls()).To(BeZero())
 	})
+	Context("Path and Permissions Boundary", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.Role = "test-role"
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				InstanceProfilePath:                lo.ToPtr("/karpenter/"),
+				InstanceProfilePermissionsBoundary: lo.ToPtr("arn:aws:iam::123456789012:policy/boundary"),
+			}))
+			awsEnv.IAMAPI.Roles = map[string]*iam.Role{
+				"test-role": {
+					RoleName:            aws.String("test-role"),
+					PermissionsBoundary: &iam.AttachedPermissionsBoundary{PermissionsBoundaryArn: aws.String("arn:aws:iam::123456789012:policy/boundary")},
+				},
+			}
+		})
+		It("should create the instance profile with the configured path", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			Expect(aws.StringValue(awsEnv.IAMAPI.InstanceProfiles[profileName].Path)).To(Equal("/karpenter/"))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.InstanceProfile).To(Equal(profileName))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInstanceProfileMismatch)).To(BeNil())
+		})
+		It("should report a mismatch when the existing instance profile has a different path", func() {
+			awsEnv.IAMAPI.InstanceProfiles = map[string]*iam.InstanceProfile{
+				profileName: {
+					InstanceProfileId:   aws.String(fake.InstanceProfileID()),
+					InstanceProfileName: aws.String(profileName),
+					Path:                aws.String("/"),
+					Roles:               []*iam.Role{{RoleName: aws.String("test-role")}},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			result := ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+
+			Expect(awsEnv.IAMAPI.DeleteInstanceProfileBehavior.Calls()).To(BeZero())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.InstanceProfile).To(Equal(profileName))
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInstanceProfileMismatch)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("PathMismatch"))
+		})
+		It("should report a mismatch when the role doesn't have the permissions boundary", func() {
+			awsEnv.IAMAPI.Roles["test-role"].PermissionsBoundary = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.InstanceProfile).To(Equal(profileName))
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInstanceProfileMismatch)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("PermissionsBoundaryMismatch"))
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeTrue())
+		})
+		It("should clear the mismatch once the role has the permissions boundary", func() {
+			awsEnv.IAMAPI.Roles["test-role"].PermissionsBoundary = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			awsEnv.IAMAPI.Roles["test-role"].PermissionsBoundary = &iam.AttachedPermissionsBoundary{PermissionsBoundaryArn: aws.String("arn:aws:iam::123456789012:policy/boundary")}
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInstanceProfileMismatch)).To(BeNil())
+		})
+		It("should not read the role when no permissions boundary is configured", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceProfilePath: lo.ToPtr("/karpenter/")}))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			Expect(awsEnv.IAMAPI.GetRoleBehavior.Calls()).To(BeZero())
+		})
+	})
+	Context("Drift", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.Role = "test-role"
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			awsEnv.IAMAPI.Reset()
+			awsEnv.IAMAPI.InstanceProfiles = map[string]*iam.InstanceProfile{
+				profileName: {
+					InstanceProfileId:   aws.String(fake.InstanceProfileID()),
+					InstanceProfileName: aws.String(profileName),
+					Roles:               []*iam.Role{{RoleName: aws.String("other-role")}},
+					Tags:                []*iam.Tag{{Key: aws.String(v1.LabelTopologyRegion), Value: aws.String("other-region")}, {Key: aws.String("custom-tag"), Value: aws.String("custom-value")}},
+				},
+			}
+		})
+		It("should not read the instance profile from IAM when it's cached with the same role and tags", func() {
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.IAMAPI.GetInstanceProfileBehavior.Calls()).To(BeZero())
+			Expect(*awsEnv.IAMAPI.InstanceProfiles[profileName].Roles[0].RoleName).To(Equal("other-role"))
+		})
+		It("should correct the role and tags when revalidation is forced", func() {
+			roleCorrections, tagCorrections := driftCorrections("role"), driftCorrections("tags")
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ForceInstanceProfileRevalidation: lo.ToPtr(true)}))
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			Expect(awsEnv.IAMAPI.RemoveRoleFromInstanceProfileBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.IAMAPI.AddRoleToInstanceProfileBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.IAMAPI.InstanceProfiles[profileName].Roles).To(HaveLen(1))
+			Expect(*awsEnv.IAMAPI.InstanceProfiles[profileName].Roles[0].RoleName).To(Equal("test-role"))
+			tags := lo.SliceToMap(awsEnv.IAMAPI.InstanceProfiles[profileName].Tags, func(t *iam.Tag) (string, string) { return *t.Key, *t.Value })
+			Expect(tags).To(HaveKeyWithValue(v1.LabelTopologyRegion, fake.DefaultRegion))
+			Expect(tags).To(HaveKeyWithValue(v1beta1.LabelNodeClass, nodeClass.Name))
+			Expect(tags).To(HaveKeyWithValue("custom-tag", "custom-value"))
+			Expect(driftCorrections("role")).To(Equal(roleCorrections + 1))
+			Expect(driftCorrections("tags")).To(Equal(tagCorrections + 1))
+
+			// Once corrected, forced revalidation reads the instance profile without changing it
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.IAMAPI.GetInstanceProfileBehavior.Calls()).To(Equal(2))
+			Expect(awsEnv.IAMAPI.AddRoleToInstanceProfileBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.IAMAPI.TagInstanceProfileBehavior.Calls()).To(Equal(1))
+		})
+		It("should correct the role once the cached instance profile expires", func() {
+			awsEnv.InstanceProfileCache.Flush()
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			Expect(awsEnv.IAMAPI.GetInstanceProfileBehavior.Calls()).To(Equal(1))
+			Expect(*awsEnv.IAMAPI.InstanceProfiles[profileName].Roles[0].RoleName).To(Equal("test-role"))
+		})
+	})
+	Context("Shared", func() {
+		var sharedProfileName string
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SharedInstanceProfiles: lo.ToPtr(true)}))
+			nodeClass.Spec.Role = "test-role"
+			sharedProfileName = nodeClass.SharedInstanceProfileName(options.FromContext(ctx).ClusterName, fake.DefaultRegion)
+		})
+		It("should share a single instance profile between EC2NodeClasses with the same role", func() {
+			other := test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: *nodeClass.Spec.DeepCopy()})
+			other.Spec.Tags = map[string]string{"team": "other"}
+			ExpectApplied(ctx, env.Client, nodeClass, other)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(other))
+
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(1))
+			Expect(*awsEnv.IAMAPI.InstanceProfiles[sharedProfileName].Roles[0].RoleName).To(Equal("test-role"))
+			Expect(awsEnv.IAMAPI.CreateInstanceProfileBehavior.Calls()).To(Equal(1))
+			tags := lo.SliceToMap(awsEnv.IAMAPI.InstanceProfiles[sharedProfileName].Tags, func(t *iam.Tag) (string, string) { return *t.Key, *t.Value })
+			Expect(tags).To(HaveKeyWithValue(v1beta1.LabelNodeClass, "role/test-role"))
+			Expect(tags).ToNot(HaveKey("team"))
+			Expect(ExpectExists(ctx, env.Client, nodeClass).Status.InstanceProfile).To(Equal(sharedProfileName))
+			Expect(ExpectExists(ctx, env.Client, other).Status.InstanceProfile).To(Equal(sharedProfileName))
+		})
+		It("should use separate instance profiles for EC2NodeClasses with different roles", func() {
+			other := test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: *nodeClass.Spec.DeepCopy()})
+			other.Spec.Role = "other-role"
+			ExpectApplied(ctx, env.Client, nodeClass, other)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(other))
+
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(2))
+			Expect(ExpectExists(ctx, env.Client, other).Status.InstanceProfile).ToNot(Equal(sharedProfileName))
+		})
+		It("should use the shared instance profile when another controller creates it concurrently", func() {
+			roleCorrections := driftCorrections("role")
+			awsEnv.IAMAPI.InstanceProfiles = map[string]*iam.InstanceProfile{
+				sharedProfileName: {
+					InstanceProfileId:   aws.String(fake.InstanceProfileID()),
+					InstanceProfileName: aws.String(sharedProfileName),
+					Roles:               []*iam.Role{{RoleName: aws.String("test-role")}},
+				},
+			}
+			// The instance profile is created after it's first read
+			awsEnv.IAMAPI.GetInstanceProfileBehavior.Error.Set(awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil), fake.MaxCalls(1))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(1))
+			Expect(awsEnv.IAMAPI.AddRoleToInstanceProfileBehavior.Calls()).To(BeZero())
+			Expect(driftCorrections("role")).To(Equal(roleCorrections))
+			Expect(ExpectExists(ctx, env.Client, nodeClass).Status.InstanceProfile).To(Equal(sharedProfileName))
+		})
+	})
 })
+
+func driftCorrections(drift string) float64 {
+	GinkgoHelper()
+	metric, ok := FindMetricWithLabelValues("karpenter_instance_profiles_drift_corrections_total", map[string]string{"drift": drift})
+	if !ok {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+)
+
+const (
+	// InterruptionQueueNotConfiguredReason is the reason that the InterruptionQueueReady condition is false when a
+	// NodePool allows spot but the interruption-queue isn't set
+	InterruptionQueueNotConfiguredReason = "InterruptionQueueNotConfigured"
+	// InterruptionQueueUnreachableReason is the reason of the InterruptionQueueUnhealthy condition, and of the
+	// InterruptionQueueReady condition when it's false, when the interruption-queue can't be reached
+	InterruptionQueueUnreachableReason = "InterruptionQueueUnreachable"
+
+	// interruptionQueueProbeInterval is how long the result of probing the interruption queue is shared by every
+	// EC2NodeClass, and how often it's checked again
+	interruptionQueueProbeInterval = 5 * time.Minute
+)
+
+// InterruptionQueue checks that spot interruptions can be handled for EC2NodeClasses used by a NodePool that allows
+// spot. Clusters with a misconfigured interruption queue otherwise run without interruption handling until it's
+// noticed during a spot reclaim.
+type InterruptionQueue struct {
+	kubeClient client.Client
+	clock      clock.Clock
+	recorder   events.Recorder
+	// sqsProvider is nil when the interruption-queue isn't set
+	sqsProvider sqs.Provider
+
+	mu       sync.Mutex
+	probedAt time.Time
+	probeErr error
+	// warned holds the EC2NodeClasses that have been warned about the interruption-queue not being set
+	warned sets.Set[types.UID]
+}
+
+func (q *InterruptionQueue) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	spotAllowed, err := q.spotAllowed(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !spotAllowed {
+		nodeClass.StatusConditions().MarkTrue(v1beta1.ConditionTypeInterruptionQueueReady)
+		return reconcile.Result{RequeueAfter: interruptionQueueProbeInterval}, nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)
+	}
+	if q.sqsProvider == nil {
+		q.warnNotConfigured(nodeClass)
+		markInterruptionQueueReady(ctx, nodeClass, InterruptionQueueNotConfiguredReason, "a NodePool that allows spot uses the EC2NodeClass, but interruption-queue isn't set")
+		return reconcile.Result{RequeueAfter: interruptionQueueProbeInterval}, nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)
+	}
+	if err := q.probe(ctx); err != nil {
+		message := fmt.Sprintf("interruption queue %q can't be reached, spot interruptions won't be handled, %s", q.sqsProvider.Name(), err)
+		nodeClass.StatusConditions().MarkTrueWithReason(v1beta1.ConditionTypeInterruptionQueueUnhealthy, InterruptionQueueUnreachableReason, "%s", message)
+		markInterruptionQueueReady(ctx, nodeClass, InterruptionQueueUnreachableReason, message)
+		return reconcile.Result{RequeueAfter: interruptionQueueProbeInterval}, nil
+	}
+	nodeClass.StatusConditions().MarkTrue(v1beta1.ConditionTypeInterruptionQueueReady)
+	return reconcile.Result{RequeueAfter: interruptionQueueProbeInterval}, nodeClass.StatusConditions().ClearCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)
+}
+
+// spotAllowed returns whether a NodePool that uses the EC2NodeClass allows spot
+func (q *InterruptionQueue) spotAllowed(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (bool, error) {
+	nodePoolList := &corev1beta1.NodePoolList{}
+	if err := q.kubeClient.List(ctx, nodePoolList); err != nil {
+		return false, fmt.Errorf("listing nodepools, %w", err)
+	}
+	for i := range nodePoolList.Items {
+		nodePool := &nodePoolList.Items[i]
+		if nodePool.Spec.Template.Spec.NodeClassRef == nil || nodePool.Spec.Template.Spec.NodeClassRef.Name != nodeClass.Name {
+			continue
+		}
+		if scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...).Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// probe resolves the queue URL and reads the queue's attributes, which fails when the queue doesn't exist or the
+// controller isn't permitted to use it. The result is shared by every EC2NodeClass until the probe interval elapses.
+func (q *InterruptionQueue) probe(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.probedAt.IsZero() && q.clock.Since(q.probedAt) < interruptionQueueProbeInterval {
+		return q.probeErr
+	}
+	_, q.probeErr = q.sqsProvider.GetSQSQueueDepth(ctx)
+	q.probedAt = q.clock.Now()
+	return q.probeErr
+}
+
+// warnNotConfigured publishes a warning event the first time an EC2NodeClass is found to be used by a NodePool that
+// allows spot without an interruption-queue
+func (q *InterruptionQueue) warnNotConfigured(nodeClass *v1beta1.EC2NodeClass) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.warned == nil {
+		q.warned = sets.New[types.UID]()
+	}
+	if q.warned.Has(nodeClass.UID) {
+		return
+	}
+	q.warned.Insert(nodeClass.UID)
+	q.recorder.Publish(InterruptionQueueNotConfiguredEvent(nodeClass))
+}
+
+// markInterruptionQueueReady only sets the InterruptionQueueReady condition to false, which keeps the EC2NodeClass from
+// being ready, when interruption-queue-required is enabled
+func markInterruptionQueueReady(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, reason, message string) {
+	if !options.FromContext(ctx).InterruptionQueueRequired {
+		nodeClass.StatusConditions().MarkTrue(v1beta1.ConditionTypeInterruptionQueueReady)
+		return
+	}
+	nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeInterruptionQueueReady, reason, "%s", message)
+}
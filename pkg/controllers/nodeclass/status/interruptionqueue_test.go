@@ -0,0 +1,165 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Interruption Queue Status Controller", func() {
+	var nodePool *corev1beta1.NodePool
+	var sqsapi *fake.SQSAPI
+	var queueStatusController corecontroller.Controller
+	BeforeEach(func() {
+		nodePool = coretest.NodePool(corev1beta1.NodePool{Spec: corev1beta1.NodePoolSpec{Template: corev1beta1.NodeClaimTemplate{
+			Spec: corev1beta1.NodeClaimSpec{
+				NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+				Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{{
+					NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}},
+				}},
+			},
+		}}})
+		sqsapi = &fake.SQSAPI{}
+		queueStatusController = status.NewController(env.Client, fakeClock, awsEnv.EventRecorder, awsEnv.EC2API, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider,
+			awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.InstanceTypesProvider, awsEnv.PricingProvider,
+			awsEnv.CapacityReservationProvider, sqs.NewDefaultProviderForQueue(sqsapi, "test-queue"))
+	})
+	It("should be healthy when the interruption queue can be reached", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)).To(BeNil())
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueReady).IsTrue()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().IsHappy()).To(BeTrue())
+		Expect(sqsapi.GetQueueURLBehavior.Calls()).To(Equal(1))
+		Expect(sqsapi.GetQueueAttributesBehavior.Calls()).To(Equal(1))
+	})
+	It("should be unhealthy, but ready, when the interruption queue can't be reached", func() {
+		sqsapi.GetQueueURLBehavior.Error.Set(awserr.New("AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist", nil))
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Reason).To(Equal(status.InterruptionQueueUnreachableReason))
+		Expect(condition.Message).To(ContainSubstring(`interruption queue "test-queue" can't be reached`))
+		Expect(nodeClass.StatusConditions().IsHappy()).To(BeTrue())
+	})
+	It("should be unhealthy when the controller isn't permitted to read the interruption queue", func() {
+		sqsapi.GetQueueAttributesBehavior.Error.Set(awserr.New("AccessDenied", "not authorized to perform sqs:GetQueueAttributes", nil))
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy).IsTrue()).To(BeTrue())
+	})
+	It("should clear the unhealthy condition once the interruption queue can be reached", func() {
+		sqsapi.GetQueueURLBehavior.Error.Set(fmt.Errorf("unreachable"), fake.MaxCalls(1))
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy).IsTrue()).To(BeTrue())
+
+		// The result of the probe is reused until it's due to be checked again
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(sqsapi.GetQueueURLBehavior.Calls()).To(Equal(1))
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)).To(BeNil())
+	})
+	It("should not probe the interruption queue when no NodePool of the EC2NodeClass allows spot", func() {
+		nodePool.Spec.Template.Spec.Requirements[0].Values = []string{corev1beta1.CapacityTypeOnDemand}
+		sqsapi.GetQueueURLBehavior.Error.Set(fmt.Errorf("unreachable"))
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueUnhealthy)).To(BeNil())
+		Expect(sqsapi.GetQueueURLBehavior.Calls()).To(Equal(0))
+	})
+	It("should warn once, without blocking readiness, when a NodePool allows spot and the interruption queue isn't set", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.EventRecorder.Calls("InterruptionQueueNotConfigured")).To(Equal(1))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueReady).IsTrue()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().IsHappy()).To(BeTrue())
+	})
+	It("should not warn when the NodePools that allow spot use other EC2NodeClasses", func() {
+		nodePool.Spec.Template.Spec.NodeClassRef.Name = "other"
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.EventRecorder.Calls("InterruptionQueueNotConfigured")).To(Equal(0))
+	})
+	Context("Required", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueueRequired: lo.ToPtr(true)}))
+		})
+		It("should not be ready when the interruption queue can't be reached", func() {
+			sqsapi.GetQueueURLBehavior.Error.Set(fmt.Errorf("unreachable"))
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal(status.InterruptionQueueUnreachableReason))
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeFalse())
+		})
+		It("should not be ready when a NodePool allows spot and the interruption queue isn't set", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeInterruptionQueueReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal(status.InterruptionQueueNotConfiguredReason))
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeFalse())
+		})
+		It("should be ready when the interruption queue can be reached", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectReconcileSucceeded(ctx, queueStatusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeTrue())
+		})
+		It("should be ready when no NodePool of the EC2NodeClass allows spot", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeTrue())
+		})
+	})
+})
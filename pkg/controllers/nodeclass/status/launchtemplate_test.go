@@ -60,6 +60,7 @@ var _ = Describe("NodeClass Launch Template CIDR Resolution Controller", func()
 			v1beta1.AMIFamilyUbuntu,
 			v1beta1.AMIFamilyWindows2019,
 			v1beta1.AMIFamilyWindows2022,
+			v1beta1.AMIFamilyWindows2025,
 			v1beta1.AMIFamilyCustom,
 		} {
 			nodeClass.Spec.AMIFamily = lo.ToPtr(family)
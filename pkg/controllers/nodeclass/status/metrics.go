@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	nodeClassSubsystem = "nodeclasses"
+	nodeClassLabel     = "nodeclass"
+	reconcilerLabel    = "reconciler"
+)
+
+var (
+	rateLimiterWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: nodeClassSubsystem,
+			Name:      "status_rate_limiter_wait_duration_seconds",
+			Help:      "Duration that EC2NodeClass status reconcilers waited on the AWS rate limiter, by reconciler.",
+			Buckets:   metrics.DurationBuckets(),
+		},
+		[]string{reconcilerLabel},
+	)
+	failedAttempts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: nodeClassSubsystem,
+			Name:      "status_failed_attempts",
+			Help:      "Number of consecutive attempts of an EC2NodeClass status reconciler that have failed, by EC2NodeClass and reconciler. Reconcilers that last succeeded aren't reported.",
+		},
+		[]string{nodeClassLabel, reconcilerLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(rateLimiterWaitDuration, failedAttempts)
+}
@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// awsRateLimiter bounds the rate at which the status reconcilers resolve resources from AWS, so that invalidating the
+// provider caches doesn't result in every EC2NodeClass calling AWS at once. This is separate from the controller's
+// workqueue rate limiter, which only governs requeues. Each reconciler is given an even share of the configured rate
+// and burst in its own token bucket so that a storm of AMI resolutions can't starve the subnet checks.
+type awsRateLimiter struct {
+	reconcilers []string
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newAWSRateLimiter(reconcilers ...string) *awsRateLimiter {
+	return &awsRateLimiter{
+		reconcilers: reconcilers,
+		limiters:    map[string]*rate.Limiter{},
+	}
+}
+
+// Wait blocks until the named reconciler is allowed to reconcile, or the context is done
+func (l *awsRateLimiter) Wait(ctx context.Context, reconciler string) error {
+	opts := options.FromContext(ctx)
+	if opts.NodeClassStatusAWSQPS <= 0 {
+		return nil
+	}
+	start := time.Now()
+	defer func() {
+		rateLimiterWaitDuration.With(prometheus.Labels{reconcilerLabel: reconciler}).Observe(time.Since(start).Seconds())
+	}()
+	return l.limiter(reconciler, opts.NodeClassStatusAWSQPS, opts.NodeClassStatusAWSBurst).Wait(ctx)
+}
+
+// limiter returns the reconciler's token bucket, updating its share of the rate and burst if the options have changed
+func (l *awsRateLimiter) limiter(reconciler string, qps float64, burst int) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := rate.Limit(qps / float64(len(l.reconcilers)))
+	burst = lo.Max([]int{burst / len(l.reconcilers), 1})
+	limiter, ok := l.limiters[reconciler]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		l.limiters[reconciler] = limiter
+	}
+	if limiter.Limit() != limit {
+		limiter.SetLimit(limit)
+	}
+	if limiter.Burst() != burst {
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
+
+// limit returns a reconciler that waits on the rate limiter before reconciling
+func (l *awsRateLimiter) limit(name string, reconciler nodeClassStatusReconciler) nodeClassStatusReconciler {
+	return rateLimitedReconciler{name: name, limiter: l, reconciler: reconciler}
+}
+
+type rateLimitedReconciler struct {
+	name       string
+	limiter    *awsRateLimiter
+	reconciler nodeClassStatusReconciler
+}
+
+func (r rateLimitedReconciler) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	if err := r.limiter.Wait(ctx, r.name); err != nil {
+		return reconcile.Result{}, fmt.Errorf("waiting on %s rate limiter, %w", r.name, err)
+	}
+	return r.reconciler.Reconcile(ctx, nodeClass)
+}
@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Status Controller AWS Rate Limiting", func() {
+	var nodeClasses []*v1beta1.EC2NodeClass
+
+	BeforeEach(func() {
+		nodeClasses = nil
+		var subnets []*ec2.Subnet
+		for i := 0; i < 50; i++ {
+			// Each EC2NodeClass selects its own subnet so that none of them are served from the subnet cache
+			subnets = append(subnets, &ec2.Subnet{
+				SubnetId:                aws.String(fmt.Sprintf("subnet-test%d", i)),
+				AvailabilityZone:        aws.String("test-zone-1a"),
+				AvailableIpAddressCount: aws.Int64(100),
+				Tags:                    []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(fmt.Sprintf("test-subnet-%d", i))}},
+			})
+			nodeClasses = append(nodeClasses, test.EC2NodeClass(v1beta1.EC2NodeClass{
+				Spec: v1beta1.EC2NodeClassSpec{
+					SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"Name": fmt.Sprintf("test-subnet-%d", i)}}},
+				},
+			}))
+		}
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: subnets})
+		for _, nc := range nodeClasses {
+			ExpectApplied(ctx, env.Client, nc)
+		}
+	})
+	reconcileAll := func() time.Duration {
+		GinkgoHelper()
+		start := time.Now()
+		workqueue := make(chan *v1beta1.EC2NodeClass, len(nodeClasses))
+		for _, nc := range nodeClasses {
+			workqueue <- nc
+		}
+		close(workqueue)
+		// Reconcile with the same concurrency as the controller
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for nc := range workqueue {
+					ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nc))
+				}
+			}()
+		}
+		wg.Wait()
+		return time.Since(start)
+	}
+	It("should keep the rate of AWS calls under the configured ceiling when many EC2NodeClasses reconcile at once", func() {
+		// 250 reconciles per second shared between 5 reconcilers allows 50 subnet reconciles per second, with a burst of 1
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			NodeClassStatusAWSQPS:   lo.ToPtr[float64](250),
+			NodeClassStatusAWSBurst: lo.ToPtr(5),
+		}))
+		elapsed := reconcileAll()
+
+		Expect(awsEnv.EC2API.CalledWithDescribeSubnetsInput.Len()).To(Equal(50))
+		Expect(elapsed).To(BeNumerically(">=", 49*time.Second/50))
+		for _, nc := range nodeClasses {
+			nc = ExpectExists(ctx, env.Client, nc)
+			Expect(nc.Status.Subnets).To(HaveLen(1))
+		}
+	})
+	It("should record the time each reconciler waited on the rate limiter", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			NodeClassStatusAWSQPS:   lo.ToPtr[float64](250),
+			NodeClassStatusAWSBurst: lo.ToPtr(5),
+		}))
+		reconcileAll()
+
+		for _, reconciler := range []string{"ami", "subnet", "securitygroup", "instanceprofile", "launchtemplate"} {
+			metric, ok := FindMetricWithLabelValues("karpenter_nodeclasses_status_rate_limiter_wait_duration_seconds", map[string]string{"reconciler": reconciler})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetHistogram().GetSampleCount()).To(BeNumerically(">=", 50))
+		}
+	})
+})
@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/multierr"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+)
+
+// Refresh drops the cached AWS data that launches are resolved from when the EC2NodeClass's refresh annotation changes,
+// for when IAM permissions or service quotas have changed and waiting for the caches to expire isn't acceptable. The
+// handled value is recorded in the status so that each value only triggers one refresh, even when pricing can't be
+// refetched. The known prices are kept in that case, until the pricing controller next updates them.
+type Refresh struct {
+	instanceTypeProvider  instancetype.Provider
+	pricingProvider       pricing.Provider
+	subnetProvider        subnet.Provider
+	securityGroupProvider securitygroup.Provider
+}
+
+func (r *Refresh) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	value, ok := nodeClass.Annotations[v1beta1.AnnotationRefresh]
+	if !ok || value == nodeClass.Status.LastRefresh {
+		return reconcile.Result{}, nil
+	}
+	r.subnetProvider.Invalidate()
+	r.securityGroupProvider.Invalidate()
+	// Pricing is refetched before the instance types are invalidated so that the instance types resolved afterwards
+	// carry the new prices
+	r.pricingProvider.Invalidate()
+	errs := multierr.Combine(r.pricingProvider.UpdateOnDemandPricing(ctx), r.pricingProvider.UpdateSpotPricing(ctx))
+	r.instanceTypeProvider.Invalidate()
+	nodeClass.Status.LastRefresh = value
+	if errs != nil {
+		return reconcile.Result{}, fmt.Errorf("refreshing pricing, %w", errs)
+	}
+	logging.FromContext(ctx).With("refresh", value).Infof("refreshed instance types, offerings, pricing, subnets and security groups")
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Refresh Status Controller", func() {
+	BeforeEach(func() {
+		awsEnv.PricingAPI.GetProductsOutput.Set(&pricing.GetProductsOutput{
+			PriceList: []aws.JSONValue{fake.NewOnDemandPrice("c5.large", 1.23)},
+		})
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{{
+				AvailabilityZone: aws.String("test-zone-1a"),
+				InstanceType:     aws.String("c5.large"),
+				SpotPrice:        aws.String("0.45"),
+				Timestamp:        aws.Time(time.Now().Add(-time.Hour)),
+			}},
+		})
+		// Populate the instance type cache, which the status controller doesn't read from
+		_, err := awsEnv.InstanceTypesProvider.List(ctx, nil, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.InstanceTypeCache.ItemCount()).ToNot(BeZero())
+	})
+	It("should refresh the cached data when the refresh annotation is set", func() {
+		staticPrice, ok := awsEnv.PricingProvider.OnDemandPrice("c5.large")
+		Expect(ok).To(BeTrue())
+		nodeClass.Annotations = map[string]string{v1beta1.AnnotationRefresh: "2024-04-16T00:00:00Z"}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		Expect(nodeClass.Status.LastRefresh).To(Equal("2024-04-16T00:00:00Z"))
+		Expect(awsEnv.InstanceTypeCache.ItemCount()).To(BeZero())
+		Expect(awsEnv.PricingAPI.GetProductsInput.Len()).ToNot(BeZero())
+		price, ok := awsEnv.PricingProvider.OnDemandPrice("c5.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).ToNot(Equal(staticPrice))
+		price, ok = awsEnv.PricingProvider.SpotPrice("c5.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.45))
+	})
+	It("should only refresh once for each value of the refresh annotation", func() {
+		nodeClass.Annotations = map[string]string{v1beta1.AnnotationRefresh: "1"}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		calls := awsEnv.PricingAPI.GetProductsInput.Len()
+
+		_, err := awsEnv.InstanceTypesProvider.List(ctx, nil, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.InstanceTypeCache.ItemCount()).ToNot(BeZero())
+		Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(calls))
+
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		nodeClass.Annotations[v1beta1.AnnotationRefresh] = "2"
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.InstanceTypeCache.ItemCount()).To(BeZero())
+		Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(BeNumerically(">", calls))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.LastRefresh).To(Equal("2"))
+	})
+	It("should not refresh without the refresh annotation", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.InstanceTypeCache.ItemCount()).ToNot(BeZero())
+		Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(BeZero())
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.LastRefresh).To(BeEmpty())
+	})
+	It("should keep the known prices and not refresh again when pricing can't be refetched", func() {
+		Expect(awsEnv.PricingProvider.UpdateSpotPricing(ctx)).To(Succeed())
+		awsEnv.PricingAPI.NextError.Set(fmt.Errorf("failed"))
+		awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+		nodeClass.Annotations = map[string]string{v1beta1.AnnotationRefresh: "1"}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		result := ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(result.RequeueAfter).To(Equal(time.Second))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.LastRefresh).To(Equal("1"))
+		price, ok := awsEnv.PricingProvider.SpotPrice("c5.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.45))
+
+		_, err := awsEnv.InstanceTypesProvider.List(ctx, nil, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		fakeClock.Step(time.Second)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.InstanceTypeCache.ItemCount()).ToNot(BeZero())
+	})
+})
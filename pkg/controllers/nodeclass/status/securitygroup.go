@@ -18,8 +18,10 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -37,8 +39,11 @@ func (sg *SecurityGroup) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2No
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	// A security group matched by more than one selector term is only attached once
+	securityGroups = lo.UniqBy(securityGroups, func(s *ec2.SecurityGroup) string { return aws.StringValue(s.GroupId) })
 	if len(securityGroups) == 0 && len(nodeClass.Spec.SecurityGroupSelectorTerms) > 0 {
 		nodeClass.Status.SecurityGroups = nil
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeSecurityGroupsReady, "SecurityGroupsNotFound", "no security groups exist given constraints")
 		return reconcile.Result{}, fmt.Errorf("no security groups exist given constraints")
 	}
 	sort.Slice(securityGroups, func(i, j int) bool {
@@ -46,9 +51,39 @@ func (sg *SecurityGroup) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2No
 	})
 	nodeClass.Status.SecurityGroups = lo.Map(securityGroups, func(securityGroup *ec2.SecurityGroup, _ int) v1beta1.SecurityGroup {
 		return v1beta1.SecurityGroup{
-			ID:   *securityGroup.GroupId,
-			Name: *securityGroup.GroupName,
+			ID:    *securityGroup.GroupId,
+			Name:  *securityGroup.GroupName,
+			VPCID: aws.StringValue(securityGroup.VpcId),
 		}
 	})
+	nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
+		v1beta1.AnnotationEC2NodeClassSecurityGroupsHash: v1beta1.ResolvedIDsHash(lo.Map(nodeClass.Status.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) string { return s.ID })),
+	})
+	// Wildcard names can match more security groups than EC2 allows on an instance, which is surfaced here rather
+	// than as a failed launch
+	if len(securityGroups) > securitygroup.MaxSecurityGroups {
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeSecurityGroupsReady, "TooManySecurityGroups",
+			"%d security groups exist given constraints, more than the %d that can be attached to an instance", len(securityGroups), securitygroup.MaxSecurityGroups)
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+	}
+	// EC2 rejects launches with security groups from a different VPC than the subnet, which is surfaced here rather
+	// than as a failed launch
+	if ids, vpcs := securityGroupsOutsideSubnetVPCs(nodeClass); len(ids) > 0 {
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeSecurityGroupsReady, securitygroup.NotInSubnetVPCReason,
+			"SecurityGroups [%s] not in VPC of selected subnets %s", strings.Join(ids, ", "), strings.Join(vpcs, ", "))
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+	}
+	nodeClass.StatusConditions().MarkTrue(v1beta1.ConditionTypeSecurityGroupsReady)
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
+
+// securityGroupsOutsideSubnetVPCs returns the resolved security groups that aren't in the VPC of every resolved subnet,
+// along with the VPCs of the subnets. Security groups and subnets whose VPC isn't known aren't compared.
+func securityGroupsOutsideSubnetVPCs(nodeClass *v1beta1.EC2NodeClass) ([]string, []string) {
+	vpcs := lo.Uniq(lo.Compact(lo.Map(nodeClass.Status.Subnets, func(s v1beta1.Subnet, _ int) string { return s.VPCID })))
+	sort.Strings(vpcs)
+	ids := lo.FilterMap(nodeClass.Status.SecurityGroups, func(sg v1beta1.SecurityGroup, _ int) (string, bool) {
+		return sg.ID, sg.VPCID != "" && lo.ContainsBy(vpcs, func(vpc string) bool { return vpc != sg.VPCID })
+	})
+	return ids, vpcs
+}
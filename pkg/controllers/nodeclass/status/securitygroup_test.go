@@ -15,10 +15,15 @@ limitations under the License.
 package status_test
 
 import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	_ "knative.dev/pkg/system/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -147,6 +152,22 @@ var _ = Describe("NodeClass Security Group Status Controller", func() {
 			},
 		}))
 	})
+	It("Should record the hash of the resolved Security Group IDs", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassSecurityGroupsHash, v1beta1.ResolvedIDsHash([]string{"sg-test1", "sg-test2", "sg-test3"})))
+
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+			{
+				ID: "sg-test1",
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassSecurityGroupsHash, v1beta1.ResolvedIDsHash([]string{"sg-test1"})))
+	})
 	It("Should update Security Groups status when the Security Groups selector gets updated by ids", func() {
 		ExpectApplied(ctx, env.Client, nodeClass)
 		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
@@ -188,7 +209,7 @@ var _ = Describe("NodeClass Security Group Status Controller", func() {
 			},
 		}
 		ExpectApplied(ctx, env.Client, nodeClass)
-		ExpectReconcileFailed(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.SecurityGroups).To(BeNil())
 	})
@@ -217,8 +238,115 @@ var _ = Describe("NodeClass Security Group Status Controller", func() {
 			},
 		}
 		ExpectApplied(ctx, env.Client, nodeClass)
-		ExpectReconcileFailed(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.SecurityGroups).To(BeNil())
 	})
+	It("Should only list a security group once when it's matched by multiple selector terms", func() {
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+			{
+				Name: "securityGroup-test1",
+			},
+			{
+				Name: "securityGroup-*",
+			},
+			{
+				Tags: map[string]string{"Name": "test-security-group-1"},
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.SecurityGroups).To(Equal([]v1beta1.SecurityGroup{
+			{
+				ID:   "sg-test1",
+				Name: "securityGroup-test1",
+			},
+			{
+				ID:   "sg-test2",
+				Name: "securityGroup-test2",
+			},
+			{
+				ID:   "sg-test3",
+				Name: "securityGroup-test3",
+			},
+		}))
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady).IsTrue()).To(BeTrue())
+	})
+	It("Should not mark SecurityGroupsReady when no security groups are resolved", func() {
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+			{
+				Name: "does-not-exist-*",
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("SecurityGroupsNotFound"))
+	})
+	It("Should not mark SecurityGroupsReady when a wildcard name matches more security groups than can be attached to an instance", func() {
+		var securityGroups []*ec2.SecurityGroup
+		for i := 0; i < 6; i++ {
+			securityGroups = append(securityGroups, &ec2.SecurityGroup{
+				GroupId:   aws.String(fmt.Sprintf("sg-test%d", i)),
+				GroupName: aws.String(fmt.Sprintf("eksctl-test-cluster-nodegroup-ng%d-SG", i)),
+			})
+		}
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: securityGroups})
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+			{
+				Name: "eksctl-test-cluster-nodegroup-*-SG",
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.SecurityGroups).To(HaveLen(6))
+		condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("TooManySecurityGroups"))
+		Expect(nodeClass.StatusConditions().IsHappy()).To(BeFalse())
+	})
+	Context("VPC", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100), VpcId: aws.String("vpc-test1")},
+			}})
+		})
+		It("Should record the VPC of the Security Groups and mark SecurityGroupsReady when they're in the VPC of the subnets", func() {
+			awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{
+				{GroupId: aws.String("sg-test1"), GroupName: aws.String("securityGroup-test1"), VpcId: aws.String("vpc-test1")},
+			}})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.SecurityGroups).To(Equal([]v1beta1.SecurityGroup{
+				{
+					ID:    "sg-test1",
+					Name:  "securityGroup-test1",
+					VPCID: "vpc-test1",
+				},
+			}))
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady).IsTrue()).To(BeTrue())
+		})
+		It("Should not mark SecurityGroupsReady when a Security Group isn't in the VPC of the subnets", func() {
+			awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{
+				{GroupId: aws.String("sg-test1"), GroupName: aws.String("securityGroup-test1"), VpcId: aws.String("vpc-test1")},
+				{GroupId: aws.String("sg-test2"), GroupName: aws.String("securityGroup-test2"), VpcId: aws.String("vpc-test2")},
+			}})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.SecurityGroups).To(HaveLen(2))
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSecurityGroupsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal(securitygroup.NotInSubnetVPCReason))
+			Expect(condition.Message).To(ContainSubstring("sg-test2"))
+			Expect(condition.Message).ToNot(ContainSubstring("sg-test1"))
+			Expect(condition.Message).To(ContainSubstring("vpc-test1"))
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeFalse())
+		})
+	})
 })
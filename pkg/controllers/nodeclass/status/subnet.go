@@ -20,16 +20,26 @@ import (
 	"sort"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 )
 
 type Subnet struct {
+	kubeClient     client.Client
 	subnetProvider subnet.Provider
+	recorder       events.Recorder
 }
 
 func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
@@ -39,6 +49,7 @@ func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass)
 	}
 	if len(subnets) == 0 {
 		nodeClass.Status.Subnets = nil
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeSubnetsReady, "SubnetsNotFound", "no subnets exist given constraints")
 		return reconcile.Result{}, fmt.Errorf("no subnets exist given constraints %v", nodeClass.Spec.SubnetSelectorTerms)
 	}
 	sort.Slice(subnets, func(i, j int) bool {
@@ -47,12 +58,66 @@ func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass)
 		}
 		return *subnets[i].SubnetId < *subnets[j].SubnetId
 	})
+	zones, err := s.subnetProvider.AvailabilityZones(ctx, subnets)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
 	nodeClass.Status.Subnets = lo.Map(subnets, func(ec2subnet *ec2.Subnet, _ int) v1beta1.Subnet {
+		zone := lo.FromPtr(zones[*ec2subnet.AvailabilityZone])
 		return v1beta1.Subnet{
-			ID:   *ec2subnet.SubnetId,
-			Zone: *ec2subnet.AvailabilityZone,
+			ID:                      *ec2subnet.SubnetId,
+			Zone:                    *ec2subnet.AvailabilityZone,
+			ZoneID:                  aws.StringValue(ec2subnet.AvailabilityZoneId),
+			VPCID:                   aws.StringValue(ec2subnet.VpcId),
+			ZoneType:                aws.StringValue(zone.ZoneType),
+			NetworkBorderGroup:      aws.StringValue(zone.NetworkBorderGroup),
+			AvailableIPAddressCount: aws.Int64Value(ec2subnet.AvailableIpAddressCount),
 		}
 	})
-
+	// Subnets are sorted by free IP addresses, which change between reconciles, so the hash is over the sorted IDs
+	nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
+		v1beta1.AnnotationEC2NodeClassSubnetsHash: v1beta1.ResolvedIDsHash(lo.Map(nodeClass.Status.Subnets, func(s v1beta1.Subnet, _ int) string { return s.ID })),
+	})
+	if err = s.warnMixedZoneTypes(ctx, nodeClass); err != nil {
+		return reconcile.Result{}, err
+	}
+	// Subnets are sorted by free IP addresses, so the first subnet has the most
+	if threshold := options.FromContext(ctx).SubnetFreeIPThreshold; threshold > 0 && nodeClass.Status.Subnets[0].AvailableIPAddressCount < int64(threshold) {
+		nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeSubnetsReady, "InsufficientFreeAddresses",
+			"all subnets have fewer than %d free IP addresses", threshold)
+		// Recheck sooner so that the condition clears shortly after addresses are released
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	nodeClass.StatusConditions().MarkTrue(v1beta1.ConditionTypeSubnetsReady)
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
+
+// warnMixedZoneTypes warns about each NodePool of the EC2NodeClass whose zone requirements allow both Wavelength Zone
+// subnets and other subnets. A NodeClaim of such a NodePool can be launched into either, but Wavelength Zones only
+// offer on-demand capacity of a few instance types and assign carrier IPs rather than public IPs.
+func (s *Subnet) warnMixedZoneTypes(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) error {
+	wavelength, other := lo.Filter(nodeClass.Status.Subnets, isWavelength), lo.Reject(nodeClass.Status.Subnets, isWavelength)
+	if len(wavelength) == 0 || len(other) == 0 {
+		return nil
+	}
+	nodePoolList := &corev1beta1.NodePoolList{}
+	if err := s.kubeClient.List(ctx, nodePoolList); err != nil {
+		return fmt.Errorf("listing nodepools, %w", err)
+	}
+	for i := range nodePoolList.Items {
+		nodePool := &nodePoolList.Items[i]
+		if nodePool.Spec.Template.Spec.NodeClassRef == nil || nodePool.Spec.Template.Spec.NodeClassRef.Name != nodeClass.Name {
+			continue
+		}
+		zones := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...).Get(v1.LabelTopologyZone)
+		allowed := func(s v1beta1.Subnet) bool { return zones.Has(s.Zone) }
+		if lo.SomeBy(wavelength, allowed) && lo.SomeBy(other, allowed) {
+			s.recorder.Publish(MixedZoneTypesEvent(nodeClass, nodePool.Name))
+		}
+	}
+	return nil
+}
+
+func isWavelength(s v1beta1.Subnet, _ int) bool {
+	return s.ZoneType == subnet.ZoneTypeWavelengthZone
+}
@@ -15,13 +15,20 @@ limitations under the License.
 package status_test
 
 import (
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
 	_ "knative.dev/pkg/system/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -57,23 +64,69 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test3",
-				Zone: "test-zone-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test4",
-				Zone: "test-zone-1a-local",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneType:                "local-zone",
+				NetworkBorderGroup:      "test-zone-1-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 	})
+	It("Should record the hash of the resolved Subnet IDs regardless of their free IP addresses", func() {
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(20)},
+			{SubnetId: aws.String("subnet-test2"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(100)},
+		}})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		hash := v1beta1.ResolvedIDsHash([]string{"subnet-test1", "subnet-test2"})
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassSubnetsHash, hash))
+
+		// Reordering the subnets by free IP addresses doesn't change the hash
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(100)},
+			{SubnetId: aws.String("subnet-test2"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(20)},
+		}})
+		awsEnv.SubnetCache.Flush()
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.Subnets[0].ID).To(Equal("subnet-test1"))
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassSubnetsHash, hash))
+
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(100)},
+		}})
+		awsEnv.SubnetCache.Flush()
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassSubnetsHash, v1beta1.ResolvedIDsHash([]string{"subnet-test1"})))
+	})
 	It("Should have the correct ordering for the Subnets", func() {
 		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
 			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(20)},
@@ -85,16 +138,25 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test3",
-				Zone: "test-zone-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				AvailableIPAddressCount: 50,
 			},
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				AvailableIPAddressCount: 20,
 			},
 		}))
 	})
@@ -112,12 +174,20 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 	})
@@ -132,8 +202,12 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 	})
@@ -143,20 +217,36 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test3",
-				Zone: "test-zone-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test4",
-				Zone: "test-zone-1a-local",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneType:                "local-zone",
+				NetworkBorderGroup:      "test-zone-1-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 
@@ -177,12 +267,20 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 	})
@@ -192,20 +290,36 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test3",
-				Zone: "test-zone-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test4",
-				Zone: "test-zone-1a-local",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneType:                "local-zone",
+				NetworkBorderGroup:      "test-zone-1-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 
@@ -219,8 +333,12 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 	})
@@ -231,7 +349,7 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 			},
 		}
 		ExpectApplied(ctx, env.Client, nodeClass)
-		ExpectReconcileFailed(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(BeNil())
 	})
@@ -241,20 +359,36 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:   "subnet-test1",
-				Zone: "test-zone-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test2",
-				Zone: "test-zone-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test3",
-				Zone: "test-zone-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:   "subnet-test4",
-				Zone: "test-zone-1a-local",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneType:                "local-zone",
+				NetworkBorderGroup:      "test-zone-1-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 
@@ -264,8 +398,137 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 			},
 		}
 		ExpectApplied(ctx, env.Client, nodeClass)
-		ExpectReconcileFailed(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(BeNil())
 	})
+	It("Should mark SubnetsReady when subnets are resolved", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady).IsTrue()).To(BeTrue())
+	})
+	It("Should record the VPC of the Subnets", func() {
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100), VpcId: aws.String("vpc-test1")},
+		}})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
+			{
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneType:                "availability-zone",
+				NetworkBorderGroup:      "test-zone-1",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
+				VPCID:                   "vpc-test1",
+			},
+		}))
+	})
+	It("Should not mark SubnetsReady when no subnets are resolved", func() {
+		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{
+			{
+				Tags: map[string]string{`foo`: `invalid`},
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("SubnetsNotFound"))
+	})
+	Context("Free IP Addresses", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SubnetFreeIPThreshold: lo.ToPtr(30)}))
+		})
+		It("Should not mark SubnetsReady and requeue sooner when every subnet is below the free IP threshold", func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(20)},
+				{SubnetId: aws.String("subnet-test2"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(10)},
+			}})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			result := ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("InsufficientFreeAddresses"))
+			Expect(nodeClass.StatusConditions().IsHappy()).To(BeFalse())
+		})
+		It("Should mark SubnetsReady when any subnet is at or above the free IP threshold", func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(30)},
+				{SubnetId: aws.String("subnet-test2"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(10)},
+			}})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady).IsTrue()).To(BeTrue())
+		})
+		It("Should mark SubnetsReady once free IP addresses are released", func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(20)},
+			}})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady).IsFalse()).To(BeTrue())
+
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(200)},
+			}})
+			awsEnv.SubnetCache.Flush()
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeSubnetsReady).IsTrue()).To(BeTrue())
+			Expect(nodeClass.Status.Subnets[0].AvailableIPAddressCount).To(BeNumerically("==", 200))
+		})
+	})
+	Context("Wavelength Zones", func() {
+		var nodePool *corev1beta1.NodePool
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-wavelength"), AvailabilityZone: aws.String("test-zone-1a-wl1"), AvailableIpAddressCount: aws.Int64(100)},
+				{SubnetId: aws.String("subnet-region"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(50)},
+			}})
+			awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
+				{ZoneName: aws.String("test-zone-1a-wl1"), ZoneType: aws.String("wavelength-zone"), NetworkBorderGroup: aws.String("test-zone-1-wl1")},
+				{ZoneName: aws.String("test-zone-1b"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+			}})
+			nodePool = coretest.NodePool(corev1beta1.NodePool{Spec: corev1beta1.NodePoolSpec{Template: corev1beta1.NodeClaimTemplate{
+				Spec: corev1beta1.NodeClaimSpec{NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name}},
+			}}})
+		})
+		It("Should record the zone type and network border group of the Subnets", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
+				{ID: "subnet-wavelength", Zone: "test-zone-1a-wl1", ZoneType: "wavelength-zone", NetworkBorderGroup: "test-zone-1-wl1", AvailableIPAddressCount: 100},
+				{ID: "subnet-region", Zone: "test-zone-1b", ZoneType: "availability-zone", NetworkBorderGroup: "test-zone-1", AvailableIPAddressCount: 50},
+			}))
+		})
+		It("Should warn when a NodePool can launch into both Wavelength Zone and other Subnets", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.EventRecorder.Calls("MixedZoneTypes")).To(Equal(1))
+		})
+		It("Should not warn when the zone requirements of the NodePool separate the Subnets", func() {
+			nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1a-wl1"}},
+			}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.EventRecorder.Calls("MixedZoneTypes")).To(Equal(0))
+		})
+		It("Should not warn about NodePools of other EC2NodeClasses", func() {
+			nodePool.Spec.Template.Spec.NodeClassRef.Name = "other"
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.EventRecorder.Calls("MixedZoneTypes")).To(Equal(0))
+		})
+	})
 })
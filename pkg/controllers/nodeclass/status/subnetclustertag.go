@@ -0,0 +1,85 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+)
+
+// SubnetClusterTag ensures that the subnets selected by an EC2NodeClass carry the kubernetes.io/cluster/<cluster-name>
+// tag. Subnets may be shared with other clusters, so the tag is only ever added with the value "shared" and tags
+// belonging to other clusters are left untouched.
+type SubnetClusterTag struct {
+	ec2api         ec2iface.EC2API
+	subnetProvider subnet.Provider
+}
+
+func (s *SubnetClusterTag) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	if !options.FromContext(ctx).SubnetClusterTagging {
+		return reconcile.Result{}, nil
+	}
+	// The subnets are served from the cache populated by the subnet reconciler
+	subnets, err := s.subnetProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	subnetTags := lo.SliceToMap(subnets, func(s *ec2.Subnet) (string, map[string]string) {
+		return aws.StringValue(s.SubnetId), lo.SliceToMap(s.Tags, func(t *ec2.Tag) (string, string) { return aws.StringValue(t.Key), aws.StringValue(t.Value) })
+	})
+	key := fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)
+	var errs error
+	for i := range nodeClass.Status.Subnets {
+		id := nodeClass.Status.Subnets[i].ID
+		if _, ok := subnetTags[id][key]; ok {
+			nodeClass.Status.Subnets[i].ClusterTag = v1beta1.SubnetClusterTagPresent
+			continue
+		}
+		if options.FromContext(ctx).SubnetClusterTaggingDryRun {
+			nodeClass.Status.Subnets[i].ClusterTag = v1beta1.SubnetClusterTagWouldTag
+			continue
+		}
+		// Tag each subnet separately so that a subnet we can't tag, such as one shared from another account, doesn't
+		// prevent the others from being tagged
+		if _, err := s.ec2api.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+			Resources: aws.StringSlice([]string{id}),
+			Tags:      []*ec2.Tag{{Key: aws.String(key), Value: aws.String("shared")}},
+		}); err != nil {
+			if awserrors.IsUnauthorized(err) {
+				logging.FromContext(ctx).With("subnet", id).Errorf("tagging subnet with %s, %s", key, err)
+				nodeClass.Status.Subnets[i].ClusterTag = v1beta1.SubnetClusterTagFailed
+				continue
+			}
+			errs = multierr.Append(errs, fmt.Errorf("tagging subnet %s, %w", id, err))
+			continue
+		}
+		logging.FromContext(ctx).With("subnet", id).Infof("tagged subnet with %s", key)
+		nodeClass.Status.Subnets[i].ClusterTag = v1beta1.SubnetClusterTagTagged
+	}
+	return reconcile.Result{}, errs
+}
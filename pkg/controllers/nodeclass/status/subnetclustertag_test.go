@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	_ "knative.dev/pkg/system/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Subnet Cluster Tag Status Controller", func() {
+	BeforeEach(func() {
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{
+				SubnetId:                aws.String("subnet-untagged"),
+				AvailabilityZone:        aws.String("test-zone-1a"),
+				AvailableIpAddressCount: aws.Int64(300),
+			},
+			{
+				SubnetId:                aws.String("subnet-owned"),
+				AvailabilityZone:        aws.String("test-zone-1b"),
+				AvailableIpAddressCount: aws.Int64(200),
+				Tags:                    []*ec2.Tag{{Key: aws.String("kubernetes.io/cluster/test-cluster"), Value: aws.String("owned")}},
+			},
+			{
+				SubnetId:                aws.String("subnet-other-cluster"),
+				AvailabilityZone:        aws.String("test-zone-1c"),
+				AvailableIpAddressCount: aws.Int64(100),
+				Tags:                    []*ec2.Tag{{Key: aws.String("kubernetes.io/cluster/other-cluster"), Value: aws.String("owned")}},
+			},
+		}})
+		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			Spec: v1beta1.EC2NodeClassSpec{
+				SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"*": "*"}}},
+			},
+		})
+	})
+	It("should not tag subnets by default", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(0))
+		for _, subnet := range nodeClass.Status.Subnets {
+			Expect(subnet.ClusterTag).To(BeEmpty())
+		}
+	})
+	It("should tag subnets that are missing the cluster tag", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SubnetClusterTagging: lo.ToPtr(true)}))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		Expect(awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Len()).To(Equal(2))
+		var tagged []string
+		awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.ForEach(func(input *ec2.CreateTagsInput) {
+			Expect(input.Tags).To(Equal([]*ec2.Tag{{Key: aws.String("kubernetes.io/cluster/test-cluster"), Value: aws.String("shared")}}))
+			tagged = append(tagged, aws.StringValueSlice(input.Resources)...)
+		})
+		Expect(tagged).To(ConsistOf("subnet-untagged", "subnet-other-cluster"))
+		Expect(lo.SliceToMap(nodeClass.Status.Subnets, func(s v1beta1.Subnet) (string, v1beta1.SubnetClusterTag) { return s.ID, s.ClusterTag })).To(Equal(map[string]v1beta1.SubnetClusterTag{
+			"subnet-untagged":      v1beta1.SubnetClusterTagTagged,
+			"subnet-owned":         v1beta1.SubnetClusterTagPresent,
+			"subnet-other-cluster": v1beta1.SubnetClusterTagTagged,
+		}))
+	})
+	It("should not change existing cluster tags", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SubnetClusterTagging: lo.ToPtr(true)}))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+
+		Expect(awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+		awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.ForEach(func(input *ec2.CreateTagsInput) {
+			// The subnet already owned by this cluster keeps its "owned" value
+			Expect(aws.StringValueSlice(input.Resources)).ToNot(ContainElement("subnet-owned"))
+			// Tags belonging to other clusters are never written
+			for _, tag := range input.Tags {
+				Expect(aws.StringValue(tag.Key)).ToNot(Equal("kubernetes.io/cluster/other-cluster"))
+			}
+		})
+	})
+	It("should report the subnets that it wasn't permitted to tag", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SubnetClusterTagging: lo.ToPtr(true)}))
+		// Subnets are tagged in order of free IP addresses, so only subnet-untagged is denied
+		awsEnv.EC2API.CreateTagsBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), fake.MaxCalls(1))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		Expect(lo.SliceToMap(nodeClass.Status.Subnets, func(s v1beta1.Subnet) (string, v1beta1.SubnetClusterTag) { return s.ID, s.ClusterTag })).To(Equal(map[string]v1beta1.SubnetClusterTag{
+			"subnet-untagged":      v1beta1.SubnetClusterTagFailed,
+			"subnet-owned":         v1beta1.SubnetClusterTagPresent,
+			"subnet-other-cluster": v1beta1.SubnetClusterTagTagged,
+		}))
+	})
+	It("should only report the subnets that it would tag in dry-run mode", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			SubnetClusterTagging:       lo.ToPtr(true),
+			SubnetClusterTaggingDryRun: lo.ToPtr(true),
+		}))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, statusController, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(0))
+		Expect(lo.SliceToMap(nodeClass.Status.Subnets, func(s v1beta1.Subnet) (string, v1beta1.SubnetClusterTag) { return s.ID, s.ClusterTag })).To(Equal(map[string]v1beta1.SubnetClusterTag{
+			"subnet-untagged":      v1beta1.SubnetClusterTagWouldTag,
+			"subnet-owned":         v1beta1.SubnetClusterTagPresent,
+			"subnet-other-cluster": v1beta1.SubnetClusterTagWouldTag,
+		}))
+	})
+})
@@ -17,7 +17,9 @@ package status_test
 import (
 	"context"
 	"testing"
+	"time"
 
+	clock "k8s.io/utils/clock/testing"
 	_ "knative.dev/pkg/system/testing"
 
 	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
@@ -41,6 +43,7 @@ var ctx context.Context
 var env *coretest.Environment
 var awsEnv *test.Environment
 var nodeClass *v1beta1.EC2NodeClass
+var fakeClock *clock.FakeClock
 var statusController corecontroller.Controller
 
 func TestAPIs(t *testing.T) {
@@ -54,14 +57,22 @@ var _ = BeforeSuite(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
 
 	statusController = status.NewController(
 		env.Client,
+		fakeClock,
+		awsEnv.EventRecorder,
+		awsEnv.EC2API,
 		awsEnv.SubnetProvider,
 		awsEnv.SecurityGroupProvider,
 		awsEnv.AMIProvider,
 		awsEnv.InstanceProfileProvider,
 		awsEnv.LaunchTemplateProvider,
+		awsEnv.InstanceTypesProvider,
+		awsEnv.PricingProvider,
+		awsEnv.CapacityReservationProvider,
+		nil,
 	)
 })
 
@@ -71,6 +82,7 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
 	nodeClass = test.EC2NodeClass()
 	awsEnv.Reset()
 })
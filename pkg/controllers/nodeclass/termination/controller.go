@@ -44,7 +44,9 @@ import (
 	corecontroller "sigs.k8s.io/karpenter/pkg/operator/controller"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 var _ corecontroller.FinalizingTypedController[*v1beta1.EC2NodeClass] = (*Controller)(nil)
@@ -67,7 +69,29 @@ func NewController(kubeClient client.Client, recorder events.Recorder,
 	})
 }
 
+// Reconcile deletes the shared instance profile that the EC2NodeClass used before its role changed, once no other
+// EC2NodeClass or NodeClaim uses it. The shared instance profile that the EC2NodeClass uses is recorded in an
+// annotation, since its status only holds the current one. Deletion is retried when NodeClaims are deleted.
 func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	if !options.FromContext(ctx).SharedInstanceProfiles || nodeClass.Spec.Role == "" || nodeClass.Status.InstanceProfile == "" {
+		return reconcile.Result{}, nil
+	}
+	if nodeClass.Annotations[v1beta1.AnnotationSharedInstanceProfile] == nodeClass.Status.InstanceProfile {
+		return reconcile.Result{}, nil
+	}
+	nodeClaimList := &corev1beta1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	released, err := c.releasePreviousInstanceProfile(ctx, nodeClass, nodeClaimList.Items)
+	if err != nil || !released {
+		return reconcile.Result{}, err
+	}
+	stored := nodeClass.DeepCopy()
+	nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{v1beta1.AnnotationSharedInstanceProfile: nodeClass.Status.InstanceProfile})
+	if err := c.kubeClient.Patch(ctx, nodeClass, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("recording shared instance profile, %w", err))
+	}
 	return reconcile.Result{}, nil
 }
 
@@ -77,17 +101,35 @@ func (c *Controller) Finalize(ctx context.Context, nodeClass *v1beta1.EC2NodeCla
 		return reconcile.Result{}, nil
 	}
 	nodeClaimList := &corev1beta1.NodeClaimList{}
-	if err := c.kubeClient.List(ctx, nodeClaimList, client.MatchingFields{"spec.nodeClassRef.name": nodeClass.Name}); err != nil {
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
 		return reconcile.Result{}, fmt.Errorf("listing nodeclaims that are using nodeclass, %w", err)
 	}
-	if len(nodeClaimList.Items) > 0 {
-		c.recorder.Publish(WaitingOnNodeClaimTerminationEvent(nodeClass, lo.Map(nodeClaimList.Items, func(nc corev1beta1.NodeClaim, _ int) string { return nc.Name })))
+	// NodeClaims use both the EC2NodeClass that they reference and the one that they were launched from
+	nodeClaims := lo.Filter(nodeClaimList.Items, func(nc corev1beta1.NodeClaim, _ int) bool {
+		return (nc.Spec.NodeClassRef != nil && nc.Spec.NodeClassRef.Name == nodeClass.Name) || utils.NodeClassName(&nc) == nodeClass.Name
+	})
+	if len(nodeClaims) > 0 {
+		c.recorder.Publish(WaitingOnNodeClaimTerminationEvent(nodeClass, lo.Map(nodeClaims, func(nc corev1beta1.NodeClaim, _ int) string { return nc.Name })))
 		return reconcile.Result{RequeueAfter: time.Minute * 10}, nil // periodically fire the event
 	}
 	if nodeClass.Spec.Role != "" {
 		if err := c.instanceProfileProvider.Delete(ctx, nodeClass); err != nil {
 			return reconcile.Result{}, fmt.Errorf("deleting instance profile, %w", err)
 		}
+		if options.FromContext(ctx).SharedInstanceProfiles {
+			if _, err := c.releasePreviousInstanceProfile(ctx, nodeClass, nodeClaimList.Items); err != nil {
+				return reconcile.Result{}, err
+			}
+			shared, err := c.sharesInstanceProfile(ctx, nodeClass, nodeClass.Status.InstanceProfile, nodeClass.Spec.Role, nodeClaimList.Items)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if !shared {
+				if err := c.instanceProfileProvider.DeleteShared(ctx, nodeClass); err != nil {
+					return reconcile.Result{}, fmt.Errorf("deleting shared instance profile, %w", err)
+				}
+			}
+		}
 	}
 	if err := c.launchTemplateProvider.DeleteAll(ctx, nodeClass); err != nil {
 		return reconcile.Result{}, fmt.Errorf("deleting launch templates, %w", err)
@@ -107,6 +149,53 @@ func (c *Controller) Finalize(ctx context.Context, nodeClass *v1beta1.EC2NodeCla
 	return reconcile.Result{}, nil
 }
 
+// releasePreviousInstanceProfile deletes the shared instance profile that's recorded on the EC2NodeClass if it isn't
+// the current one and nothing else uses it, returning true if there's no previous instance profile left to delete. The
+// role of the previous instance profile isn't known, so other EC2NodeClasses are only matched by their status.
+func (c *Controller) releasePreviousInstanceProfile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaims []corev1beta1.NodeClaim) (bool, error) {
+	previous := nodeClass.Annotations[v1beta1.AnnotationSharedInstanceProfile]
+	if previous == "" || previous == nodeClass.Status.InstanceProfile {
+		return true, nil
+	}
+	shared, err := c.sharesInstanceProfile(ctx, nodeClass, previous, "", nodeClaims)
+	if err != nil || shared {
+		return false, err
+	}
+	if err := c.instanceProfileProvider.DeleteSharedByName(ctx, previous); err != nil {
+		return false, fmt.Errorf("deleting shared instance profile of previous role, %w", err)
+	}
+	return true, nil
+}
+
+// sharesInstanceProfile returns true if another EC2NodeClass or a NodeClaim still uses the shared instance profile,
+// which other EC2NodeClasses use if it's in their status or if they have its role. An EC2NodeClass that's being deleted
+// only uses it while NodeClaims still use the EC2NodeClass, so that EC2NodeClasses of the same role that are deleted
+// together don't each leave the instance profile for the other to delete. NodeClaims are matched by the instance
+// profile that they were launched with, since the role of the EC2NodeClass that they were launched from may have
+// changed since.
+func (c *Controller) sharesInstanceProfile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, profileName, role string, nodeClaims []corev1beta1.NodeClaim) (bool, error) {
+	if profileName != "" && lo.ContainsBy(nodeClaims, func(nodeClaim corev1beta1.NodeClaim) bool {
+		return nodeClaim.Annotations[v1beta1.AnnotationInstanceProfile] == profileName
+	}) {
+		return true, nil
+	}
+	nodeClassList := &v1beta1.EC2NodeClassList{}
+	if err := c.kubeClient.List(ctx, nodeClassList); err != nil {
+		return false, fmt.Errorf("listing nodeclasses sharing the instance profile, %w", err)
+	}
+	return lo.ContainsBy(nodeClassList.Items, func(nc v1beta1.EC2NodeClass) bool {
+		if nc.Name == nodeClass.Name {
+			return false
+		}
+		if (role == "" || nc.Spec.Role != role) && (profileName == "" || nc.Status.InstanceProfile != profileName) {
+			return false
+		}
+		return nc.DeletionTimestamp.IsZero() || lo.ContainsBy(nodeClaims, func(nodeClaim corev1beta1.NodeClaim) bool {
+			return (nodeClaim.Spec.NodeClassRef != nil && nodeClaim.Spec.NodeClassRef.Name == nc.Name) || utils.NodeClassName(&nodeClaim) == nc.Name
+		})
+	}), nil
+}
+
 func (c *Controller) Name() string {
 	return "nodeclass.termination"
 }
@@ -122,7 +211,9 @@ func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontrolle
 				if nc.Spec.NodeClassRef == nil {
 					return nil
 				}
-				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: nc.Spec.NodeClassRef.Name}}}
+				return lo.Map(lo.Uniq([]string{nc.Spec.NodeClassRef.Name, utils.NodeClassName(nc)}), func(name string, _ int) reconcile.Request {
+					return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+				})
 			}),
 			// Watch for NodeClaim deletion events
 			builder.WithPredicates(predicate.Funcs{
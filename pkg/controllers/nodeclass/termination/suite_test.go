@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	_ "knative.dev/pkg/system/testing"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -256,6 +257,205 @@ var _ = Describe("NodeClass Termination", func() {
 		Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(0))
 		ExpectNotFound(ctx, env.Client, nodeClass)
 	})
+	It("should not delete the EC2NodeClass until the NodeClaims launched from it are terminated", func() {
+		nc := coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1beta1.AnnotationLaunchedNodeClass: nodeClass.Name},
+			},
+			Spec: corev1beta1.NodeClaimSpec{
+				NodeClassRef: &corev1beta1.NodeClassReference{
+					Name: "migrating-nodeclass",
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nc)
+		controllerutil.AddFinalizer(nodeClass, v1beta1.TerminationFinalizer)
+		ExpectApplied(ctx, env.Client, nodeClass)
+
+		Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+		res := ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+		Expect(res.RequeueAfter).To(Equal(time.Minute * 10))
+		ExpectExists(ctx, env.Client, nodeClass)
+
+		ExpectDeleted(ctx, env.Client, nc)
+		ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+		ExpectNotFound(ctx, env.Client, nodeClass)
+	})
+	Context("Shared Instance Profiles", func() {
+		var sharedProfileName string
+		var other *v1beta1.EC2NodeClass
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SharedInstanceProfiles: lo.ToPtr(true)}))
+			sharedProfileName = nodeClass.SharedInstanceProfileName(options.FromContext(ctx).ClusterName, fake.DefaultRegion)
+			awsEnv.IAMAPI.InstanceProfiles = map[string]*iam.InstanceProfile{
+				sharedProfileName: {
+					InstanceProfileName: aws.String(sharedProfileName),
+					Roles:               []*iam.Role{{RoleName: aws.String(nodeClass.Spec.Role)}},
+				},
+			}
+			other = test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: *nodeClass.Spec.DeepCopy()})
+			controllerutil.AddFinalizer(nodeClass, v1beta1.TerminationFinalizer)
+			controllerutil.AddFinalizer(other, v1beta1.TerminationFinalizer)
+		})
+		AfterEach(func() {
+			ctx = options.ToContext(ctx, test.Options())
+		})
+		It("should not delete the shared instance profile while another EC2NodeClass references its role", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, other)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+			ExpectNotFound(ctx, env.Client, nodeClass)
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(sharedProfileName))
+
+			Expect(env.Client.Delete(ctx, other)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(other))
+			ExpectNotFound(ctx, env.Client, other)
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(BeEmpty())
+		})
+		It("should delete the shared instance profile when the other EC2NodeClass of its role is being deleted without NodeClaims", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, other)
+			Expect(env.Client.Delete(ctx, other)).To(Succeed())
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+			ExpectNotFound(ctx, env.Client, nodeClass)
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(BeEmpty())
+
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(other))
+			ExpectNotFound(ctx, env.Client, other)
+		})
+		It("should not delete the shared instance profile while the other EC2NodeClass of its role has NodeClaims", func() {
+			nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+				Spec: corev1beta1.NodeClaimSpec{
+					NodeClassRef: &corev1beta1.NodeClassReference{Name: other.Name},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodeClass, other, nodeClaim)
+			Expect(env.Client.Delete(ctx, other)).To(Succeed())
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+			ExpectNotFound(ctx, env.Client, nodeClass)
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(sharedProfileName))
+
+			ExpectDeleted(ctx, env.Client, nodeClaim)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(other))
+			ExpectNotFound(ctx, env.Client, other)
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(BeEmpty())
+		})
+		It("should not delete the shared instance profile while NodeClaims launched with it remain", func() {
+			nodeClass.Status.InstanceProfile = sharedProfileName
+			// The role of the EC2NodeClass that the NodeClaim was launched from has changed since
+			other.Spec.Role = "other-role"
+			nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{v1beta1.AnnotationInstanceProfile: sharedProfileName},
+				},
+				Spec: corev1beta1.NodeClaimSpec{
+					NodeClassRef: &corev1beta1.NodeClassReference{Name: other.Name},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodeClass, other, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+			ExpectNotFound(ctx, env.Client, nodeClass)
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(sharedProfileName))
+		})
+		It("should delete the instance profile the EC2NodeClass had before instance profiles were shared", func() {
+			awsEnv.IAMAPI.InstanceProfiles[profileName] = &iam.InstanceProfile{InstanceProfileName: aws.String(profileName)}
+			ExpectApplied(ctx, env.Client, nodeClass, other)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(1))
+			Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(sharedProfileName))
+		})
+		Context("Role Changes", func() {
+			var otherProfileName string
+			BeforeEach(func() {
+				awsEnv.IAMAPI.InstanceProfiles[sharedProfileName].Tags = []*iam.Tag{{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String(fmt.Sprintf("role/%s", nodeClass.Spec.Role))}}
+				other.Spec.Role = "other-role"
+				otherProfileName = other.SharedInstanceProfileName(options.FromContext(ctx).ClusterName, fake.DefaultRegion)
+				awsEnv.IAMAPI.InstanceProfiles[otherProfileName] = &iam.InstanceProfile{
+					InstanceProfileName: aws.String(otherProfileName),
+					Roles:               []*iam.Role{{RoleName: aws.String("other-role")}},
+					Tags:                []*iam.Tag{{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String("role/other-role")}},
+				}
+				nodeClass.Status.InstanceProfile = sharedProfileName
+				ExpectApplied(ctx, env.Client, nodeClass)
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				Expect(nodeClass.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSharedInstanceProfile, sharedProfileName))
+			})
+			changeRole := func() {
+				GinkgoHelper()
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				nodeClass.Spec.Role = "other-role"
+				nodeClass.Status.InstanceProfile = otherProfileName
+				ExpectApplied(ctx, env.Client, nodeClass)
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+			}
+			It("should delete the shared instance profile of the previous role when the role changes", func() {
+				changeRole()
+				Expect(awsEnv.IAMAPI.InstanceProfiles).ToNot(HaveKey(sharedProfileName))
+				Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(otherProfileName))
+				Expect(ExpectExists(ctx, env.Client, nodeClass).Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSharedInstanceProfile, otherProfileName))
+			})
+			It("should not delete the shared instance profile of the previous role while another EC2NodeClass uses it", func() {
+				other.Spec.Role = nodeClass.Spec.Role
+				other.Status.InstanceProfile = sharedProfileName
+				ExpectApplied(ctx, env.Client, other)
+				changeRole()
+				Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(sharedProfileName))
+				Expect(ExpectExists(ctx, env.Client, nodeClass).Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSharedInstanceProfile, sharedProfileName))
+			})
+			It("should delete the shared instance profile of the previous role once the NodeClaims launched with it are deleted", func() {
+				nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{v1beta1.AnnotationInstanceProfile: sharedProfileName},
+					},
+					Spec: corev1beta1.NodeClaimSpec{
+						NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					},
+				})
+				ExpectApplied(ctx, env.Client, nodeClaim)
+				changeRole()
+				Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(sharedProfileName))
+
+				ExpectDeleted(ctx, env.Client, nodeClaim)
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+				Expect(awsEnv.IAMAPI.InstanceProfiles).ToNot(HaveKey(sharedProfileName))
+				Expect(ExpectExists(ctx, env.Client, nodeClass).Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSharedInstanceProfile, otherProfileName))
+			})
+			It("should delete the shared instance profile of the previous role when the EC2NodeClass is deleted", func() {
+				nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{v1beta1.AnnotationInstanceProfile: sharedProfileName},
+					},
+					Spec: corev1beta1.NodeClaimSpec{
+						NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					},
+				})
+				ExpectApplied(ctx, env.Client, nodeClaim)
+				changeRole()
+				ExpectDeleted(ctx, env.Client, nodeClaim)
+
+				Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+				ExpectNotFound(ctx, env.Client, nodeClass)
+				Expect(awsEnv.IAMAPI.InstanceProfiles).To(BeEmpty())
+			})
+			It("should not delete an instance profile that the EC2NodeClass used before instance profiles were shared", func() {
+				awsEnv.IAMAPI.InstanceProfiles[profileName] = &iam.InstanceProfile{
+					InstanceProfileName: aws.String(profileName),
+					Tags:                []*iam.Tag{{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String(nodeClass.Name)}},
+				}
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{v1beta1.AnnotationSharedInstanceProfile: profileName})
+				ExpectApplied(ctx, env.Client, nodeClass)
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(nodeClass))
+				Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey(profileName))
+				Expect(ExpectExists(ctx, env.Client, nodeClass).Annotations).To(HaveKeyWithValue(v1beta1.AnnotationSharedInstanceProfile, sharedProfileName))
+			})
+		})
+	})
 	It("should not call the IAM API when deleting a NodeClass with an instanceProfile specified", func() {
 		awsEnv.IAMAPI.InstanceProfiles = map[string]*iam.InstanceProfile{
 			profileName: {
@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costlimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+)
+
+// Controller computes the hourly cost of the running instances of NodePools with the
+// karpenter.k8s.aws/limit-hourly-cost annotation, which launches for these NodePools are checked against
+type Controller struct {
+	kubeClient        client.Client
+	instanceProvider  instance.Provider
+	costLimitProvider costlimit.Provider
+}
+
+func NewController(kubeClient client.Client, instanceProvider instance.Provider, costLimitProvider costlimit.Provider) *Controller {
+	return &Controller{
+		kubeClient:        kubeClient,
+		instanceProvider:  instanceProvider,
+		costLimitProvider: costLimitProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	nodePoolList := &corev1beta1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodepools, %w", err)
+	}
+	var instances []*instance.Instance
+	// Instances are only listed when a NodePool has a limit, so clusters that don't use limits don't pay for the calls
+	if lo.ContainsBy(nodePoolList.Items, func(np corev1beta1.NodePool) bool {
+		_, ok, _ := costlimit.NodePoolLimit(&np)
+		return ok
+	}) {
+		var err error
+		if instances, err = c.instanceProvider.Sweep(ctx); err != nil {
+			return reconcile.Result{}, fmt.Errorf("listing instances, %w", err)
+		}
+	}
+	c.costLimitProvider.Update(ctx, nodePoolList.Items, lo.Map(instances, func(i *instance.Instance, _ int) costlimit.Instance {
+		return costlimit.Instance{
+			ID:           i.ID,
+			NodePool:     i.Tags[corev1beta1.NodePoolLabelKey],
+			Type:         i.Type,
+			Zone:         i.Zone,
+			CapacityType: i.CapacityType,
+		}
+	}))
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) Name() string {
+	return "nodepool.costlimit"
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Builder {
+	return controller.NewSingletonManagedBy(m)
+}
@@ -19,22 +19,31 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/samber/lo"
 	lop "github.com/samber/lo/parallel"
 	"go.uber.org/multierr"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"sigs.k8s.io/karpenter/pkg/operator/controller"
 
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 )
 
+// Controller updates spot prices every spot-price-refresh-interval and on-demand prices every 12 hours
 type Controller struct {
+	clk             clock.Clock
 	pricingProvider pricing.Provider
+
+	// onDemandUpdatedAt is when on-demand prices were last updated successfully
+	onDemandUpdatedAt time.Time
 }
 
-func NewController(pricingProvider pricing.Provider) *Controller {
+func NewController(clk clock.Clock, pricingProvider pricing.Provider) *Controller {
 	return &Controller{
+		clk:             clk,
 		pricingProvider: pricingProvider,
 	}
 }
@@ -42,7 +51,11 @@ func NewController(pricingProvider pricing.Provider) *Controller {
 func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
 	work := []func(ctx context.Context) error{
 		c.pricingProvider.UpdateSpotPricing,
-		c.pricingProvider.UpdateOnDemandPricing,
+	}
+	// On-demand prices rarely change and are expensive to fetch, so they aren't updated with every spot price update
+	onDemandDue := c.onDemandUpdatedAt.IsZero() || c.clk.Since(c.onDemandUpdatedAt) >= pricing.OnDemandRefreshInterval
+	if onDemandDue {
+		work = append(work, c.pricingProvider.UpdateOnDemandPricing)
 	}
 	errs := make([]error, len(work))
 	lop.ForEach(work, func(f func(ctx context.Context) error, i int) {
@@ -50,10 +63,16 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 			errs[i] = err
 		}
 	})
+	if onDemandDue && errs[len(work)-1] == nil {
+		c.onDemandUpdatedAt = c.clk.Now()
+	}
 	if err := multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, fmt.Errorf("updating pricing, %w", err)
 	}
-	return reconcile.Result{RequeueAfter: 12 * time.Hour}, nil
+	return reconcile.Result{RequeueAfter: lo.Min([]time.Duration{
+		options.FromContext(ctx).SpotPriceRefreshInterval,
+		pricing.OnDemandRefreshInterval - c.clk.Since(c.onDemandUpdatedAt),
+	})}, nil
 }
 
 func (c *Controller) Name() string {
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+// OverridesController re-reads the pricing override file every minute, so that edits to a mounted ConfigMap take effect
+// without waiting for the next pricing update. It's the only reader of the file, so an invalid edit leaves the previous
+// overrides in place without holding up price updates.
+type OverridesController struct {
+	pricingProvider pricing.Provider
+}
+
+func NewOverridesController(pricingProvider pricing.Provider) *OverridesController {
+	return &OverridesController{
+		pricingProvider: pricingProvider,
+	}
+}
+
+func (c *OverridesController) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	if err := c.pricingProvider.UpdateOverrides(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("updating pricing overrides, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *OverridesController) Name() string {
+	return "pricing.overrides"
+}
+
+func (c *OverridesController) Builder(_ context.Context, m manager.Manager) controller.Builder {
+	return controller.NewSingletonManagedBy(m)
+}
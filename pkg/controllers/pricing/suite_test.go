@@ -17,6 +17,8 @@ package pricing_test
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -24,7 +26,11 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awspricing "github.com/aws/aws-sdk-go/service/pricing"
 	"github.com/samber/lo"
+	"go.uber.org/goleak"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/operator/scheme"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
@@ -60,7 +66,6 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	ctx, stop = context.WithCancel(ctx)
 	awsEnv = test.NewEnvironment(ctx, env)
-	controller = controllerspricing.NewController(awsEnv.PricingProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -73,6 +78,7 @@ var _ = BeforeEach(func() {
 	ctx = options.ToContext(ctx, test.Options())
 
 	awsEnv.Reset()
+	controller = controllerspricing.NewController(clock.RealClock{}, awsEnv.PricingProvider)
 })
 
 var _ = AfterEach(func() {
@@ -84,7 +90,7 @@ var _ = Describe("Pricing", func() {
 		"should return correct static data for all partitions",
 		func(staticPricing map[string]map[string]float64) {
 			for region, prices := range staticPricing {
-				provider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, region)
+				provider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, region, clock.RealClock{})
 				for instance, price := range prices {
 					val, ok := provider.OnDemandPrice(instance)
 					Expect(ok).To(BeTrue())
@@ -297,9 +303,24 @@ var _ = Describe("Pricing", func() {
 		Expect(ok).To(BeTrue())
 		Expect(price).To(BeNumerically("==", 1.10))
 	})
+	DescribeTable("should keep static on-demand prices current when they aren't updated from the pricing API",
+		func(region string, isolatedVPC bool) {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IsolatedVPC: lo.ToPtr(isolatedVPC)}))
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, region, fakeClock)
+			tmpController := controllerspricing.NewController(fakeClock, tmpPricingProvider)
+
+			fakeClock.Step(2 * pricing.OnDemandRefreshInterval)
+			ExpectReconcileSucceeded(ctx, tmpController, types.NamespacedName{})
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(0))
+			Expect(tmpPricingProvider.UpdatedAt(corev1beta1.CapacityTypeOnDemand)).To(Equal(fakeClock.Now()))
+		},
+		Entry("isolated VPC", fake.DefaultRegion, true),
+		Entry("partition without the pricing API", "us-gov-west-1", false),
+	)
 	It("should update on-demand pricing with response from the pricing API when in the CN partition", func() {
-		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "cn-anywhere-1")
-		tmpController := controllerspricing.NewController(tmpPricingProvider)
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "cn-anywhere-1", clock.RealClock{})
+		tmpController := controllerspricing.NewController(clock.RealClock{}, tmpPricingProvider)
 
 		now := time.Now()
 		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
@@ -328,4 +349,409 @@ var _ = Describe("Pricing", func() {
 		Expect(ok).To(BeTrue())
 		Expect(price).To(BeNumerically("==", 1.23))
 	})
+	It("should return static on-demand data and update spot pricing when in the GovCloud partition", func() {
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "us-gov-west-1", clock.RealClock{})
+		tmpController := controllerspricing.NewController(clock.RealClock{}, tmpPricingProvider)
+
+		now := time.Now()
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{
+					AvailabilityZone: aws.String("us-gov-west-1a"),
+					InstanceType:     aws.String("c5.large"),
+					SpotPrice:        aws.String("0.05"),
+					Timestamp:        &now,
+				},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []aws.JSONValue{
+				fake.NewOnDemandPrice("c5.large", 1.20),
+			},
+		})
+		ExpectReconcileSucceeded(ctx, tmpController, types.NamespacedName{})
+		Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(0))
+
+		price, ok := tmpPricingProvider.OnDemandPrice("c5.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("~", pricing.InitialOnDemandPricesUSGov["us-gov-west-1"]["c5.large"]*0.72))
+
+		price, ok = tmpPricingProvider.SpotPrice("c5.large", "us-gov-west-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.05))
+	})
+	DescribeTable(
+		"should price regions with the pricing API endpoint in their partition",
+		func(region, apiRegion string) {
+			Expect(pricing.APIRegion(region)).To(Equal(apiRegion))
+		},
+		Entry("aws", "us-west-2", "us-east-1"),
+		Entry("aws in Asia Pacific", "ap-northeast-1", "ap-south-1"),
+		Entry("aws in Europe", "eu-west-1", "eu-central-1"),
+		Entry("aws-cn", "cn-north-1", "cn-northwest-1"),
+		Entry("aws-iso", "us-iso-west-1", "us-iso-east-1"),
+	)
+	It("should serve the static prices of another region in the partition for regions without static prices", func() {
+		Expect(pricing.InitialOnDemandPricesCN).ToNot(HaveKey("cn-northwest-1"))
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "cn-northwest-1", clock.RealClock{})
+		for instanceType, staticPrice := range pricing.InitialOnDemandPricesCN["cn-north-1"] {
+			price, ok := tmpPricingProvider.OnDemandPrice(instanceType)
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", staticPrice*0.72))
+		}
+	})
+	It("should pass the liveness probe in partitions without the pricing API", func() {
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "us-gov-west-1", clock.RealClock{})
+		ExpectReconcileSucceeded(ctx, controllerspricing.NewController(clock.RealClock{}, tmpPricingProvider), types.NamespacedName{})
+		Expect(tmpPricingProvider.LivenessProbe(nil)).To(Succeed())
+	})
+	Context("Spot Price Updates", func() {
+		var fakeClock *clocktesting.FakeClock
+		var provider *pricing.DefaultProvider
+		var providerController *controllerspricing.Controller
+		var start time.Time
+		spotPrice := func(instanceType, zone, price string, timestamp time.Time) *ec2.SpotPrice {
+			return &ec2.SpotPrice{
+				AvailabilityZone: aws.String(zone),
+				InstanceType:     aws.String(instanceType),
+				SpotPrice:        aws.String(price),
+				Timestamp:        aws.Time(timestamp),
+			}
+		}
+		BeforeEach(func() {
+			start = time.Now().Add(-time.Hour)
+			fakeClock = clocktesting.NewFakeClock(start)
+			provider = pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, fake.DefaultRegion, fakeClock)
+			providerController = controllerspricing.NewController(fakeClock, provider)
+			awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+				PriceList: []aws.JSONValue{fake.NewOnDemandPrice("c98.large", 1.20)},
+			})
+			awsEnv.EC2API.DescribeSpotPriceHistoryPageSize.Set(lo.ToPtr(1))
+		})
+		It("should request prices from the newest known spot price after the first update", func() {
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{
+					spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-2*time.Hour)),
+					spotPrice("c98.large", "test-zone-1b", "2.00", start.Add(-time.Hour)),
+				},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(aws.TimeValue(awsEnv.EC2API.DescribeSpotPriceHistoryInput.Clone().StartTime)).To(BeTemporally("==", start))
+
+			fakeClock.Step(12 * time.Hour)
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(aws.TimeValue(awsEnv.EC2API.DescribeSpotPriceHistoryInput.Clone().StartTime)).To(BeTemporally("==", start.Add(-time.Hour)))
+		})
+		It("should merge the newest price of each offering across pages", func() {
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{
+					spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-2*time.Hour)),
+					spotPrice("c98.large", "test-zone-1b", "2.00", start.Add(-time.Hour)),
+				},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+
+			// prices that took effect since the last update are returned after an older price of the same offering
+			fakeClock.Step(12 * time.Hour)
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{
+					spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-2*time.Hour)),
+					spotPrice("c98.large", "test-zone-1b", "2.00", start.Add(-time.Hour)),
+					spotPrice("c98.large", "test-zone-1a", "1.50", start.Add(time.Hour)),
+					spotPrice("c98.large", "test-zone-1a", "1.25", start.Add(2*time.Hour)),
+					spotPrice("c99.large", "test-zone-1a", "3.00", start.Add(time.Hour)),
+				},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+
+			price, ok := provider.SpotPrice("c98.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.25))
+			price, ok = provider.SpotPrice("c98.large", "test-zone-1b")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 2.00))
+			price, ok = provider.SpotPrice("c99.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 3.00))
+		})
+		It("should succeed when no spot prices changed since the last update", func() {
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour))},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+
+			fakeClock.Step(12 * time.Hour)
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			price, ok := provider.SpotPrice("c98.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.00))
+		})
+		It("should evict prices that aren't reported within the spot price TTL", func() {
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{
+					spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour)),
+					spotPrice("c98.large", "test-zone-1b", "2.00", start.Add(-time.Hour)),
+				},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+
+			// test-zone-1b stops being reported, while test-zone-1a keeps being reported as the price in effect
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour))},
+			})
+			fakeClock.Step(12 * time.Hour)
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			_, ok := provider.SpotPrice("c98.large", "test-zone-1b")
+			Expect(ok).To(BeTrue())
+
+			fakeClock.Step(12*time.Hour + time.Second)
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			_, ok = provider.SpotPrice("c98.large", "test-zone-1b")
+			Expect(ok).To(BeFalse())
+			price, ok := provider.SpotPrice("c98.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.00))
+		})
+		It("should not evict prices when the spot price TTL is disabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotPriceTTL: lo.ToPtr(time.Duration(0))}))
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1b", "2.00", start.Add(-time.Hour))},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(time.Hour))},
+			})
+			fakeClock.Step(7 * 24 * time.Hour)
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			price, ok := provider.SpotPrice("c98.large", "test-zone-1b")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 2.00))
+		})
+		It("should refresh spot prices every spot-price-refresh-interval and on-demand prices every 12 hours", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotPriceRefreshInterval: lo.ToPtr(5 * time.Minute)}))
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour))},
+			})
+			result := ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(2))
+
+			awsEnv.PricingAPI.GetProductsInput.Reset()
+			awsEnv.EC2API.DescribeSpotPriceHistoryInput.Reset()
+			fakeClock.Step(5 * time.Minute)
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "2.00", start.Add(time.Minute))},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(awsEnv.EC2API.DescribeSpotPriceHistoryInput.IsNil()).To(BeFalse())
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(0))
+			price, ok := provider.SpotPrice("c98.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 2.00))
+
+			fakeClock.Step(12 * time.Hour)
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(2))
+		})
+		It("should requeue for the next on-demand price update when it's due before the next spot price update", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotPriceRefreshInterval: lo.ToPtr(24 * time.Hour)}))
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour))},
+			})
+			result := ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(result.RequeueAfter).To(Equal(12 * time.Hour))
+		})
+		It("should fail the liveness probe once spot prices are older than spot-price-max-staleness", func() {
+			provider = pricing.NewDefaultProvider(options.ToContext(ctx, test.Options(test.OptionsFields{SpotPriceMaxStaleness: lo.ToPtr(time.Hour)})),
+				awsEnv.PricingAPI, awsEnv.EC2API, fake.DefaultRegion, fakeClock)
+			providerController = controllerspricing.NewController(fakeClock, provider)
+			Expect(provider.LivenessProbe(nil)).To(Succeed())
+
+			fakeClock.Step(time.Hour + time.Second)
+			Expect(provider.LivenessProbe(nil)).ToNot(Succeed())
+
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour))},
+			})
+			ExpectReconcileSucceeded(ctx, providerController, types.NamespacedName{})
+			Expect(provider.LivenessProbe(nil)).To(Succeed())
+		})
+		It("should report the staleness of spot and on-demand prices", func() {
+			awsEnv.PricingAPI.GetProductsOutput.Reset()
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{spotPrice("c98.large", "test-zone-1a", "1.00", start.Add(-time.Hour))},
+			})
+			fakeClock.Step(time.Minute)
+			ExpectReconcileFailed(ctx, providerController, types.NamespacedName{})
+			ExpectMetricGaugeValue("karpenter_pricing_staleness_seconds", 0, map[string]string{"capacity_type": corev1beta1.CapacityTypeSpot})
+			// the on-demand prices are still the static prices the provider started with
+			ExpectMetricGaugeValue("karpenter_pricing_staleness_seconds", 60, map[string]string{"capacity_type": corev1beta1.CapacityTypeOnDemand})
+
+			fakeClock.Step(time.Hour)
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			ExpectReconcileFailed(ctx, providerController, types.NamespacedName{})
+			ExpectMetricGaugeValue("karpenter_pricing_staleness_seconds", 3600, map[string]string{"capacity_type": corev1beta1.CapacityTypeSpot})
+		})
+	})
+	Context("Pricing Override File", func() {
+		var path string
+		var overridesController *controllerspricing.OverridesController
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "prices.json")
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				PricingOverrideFile: lo.ToPtr(path),
+			}))
+			overridesController = controllerspricing.NewOverridesController(awsEnv.PricingProvider)
+			awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+				PriceList: []aws.JSONValue{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+					fake.NewOnDemandPrice("c99.large", 1.23),
+				},
+			})
+		})
+		It("should use the prices in the override file as-is in place of the pricing API", func() {
+			Expect(os.WriteFile(path, []byte(`{"c98.large": 0.5, "c97.large": 0.25}`), 0600)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, overridesController, types.NamespacedName{})
+			ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.5))
+
+			price, ok = awsEnv.PricingProvider.OnDemandPrice("c97.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.25))
+			Expect(awsEnv.PricingProvider.InstanceTypes()).To(ContainElement("c97.large"))
+
+			price, ok = awsEnv.PricingProvider.OnDemandPrice("c99.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", 1.23*0.72))
+		})
+		It("should use the prices in the override file when in isolated-vpc", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				PricingOverrideFile: lo.ToPtr(path),
+				IsolatedVPC:         lo.ToPtr(true),
+			}))
+			Expect(os.WriteFile(path, []byte(`{"c5.large": 0.5}`), 0600)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, overridesController, types.NamespacedName{})
+			ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(0))
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c5.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.5))
+		})
+		It("should keep the previous overrides and still update on-demand prices when the override file is invalid", func() {
+			Expect(os.WriteFile(path, []byte(`{"c98.large": 0.5}`), 0600)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, overridesController, types.NamespacedName{})
+			ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(2))
+
+			awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+				PriceList: []aws.JSONValue{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+					fake.NewOnDemandPrice("c99.large", 2.46),
+				},
+			})
+			Expect(os.WriteFile(path, []byte(`{"c98.large": `), 0600)).To(Succeed())
+			ExpectReconcileFailed(ctx, overridesController, types.NamespacedName{})
+			Expect(awsEnv.PricingProvider.UpdateOnDemandPricing(ctx)).To(Succeed())
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(4))
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.5))
+			price, ok = awsEnv.PricingProvider.OnDemandPrice("c99.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", 2.46*0.72))
+		})
+		It("should use the on-demand and spot prices by zone in a structured override file", func() {
+			Expect(os.WriteFile(path, []byte(`{"onDemand": {"c98.large": 0.5}, "spot": {"c98.large": {"test-zone-1a": 0.125}}}`), 0600)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, overridesController, types.NamespacedName{})
+			ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.5))
+
+			price, ok = awsEnv.PricingProvider.SpotPrice("c98.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.125))
+			// zones without an override keep their usual spot price
+			_, ok = awsEnv.PricingProvider.SpotPrice("c98.large", "test-zone-1b")
+			Expect(ok).To(BeFalse())
+		})
+		It("should reject unknown fields in a structured override file", func() {
+			Expect(os.WriteFile(path, []byte(`{"onDemand": {"c98.large": 0.5}, "spots": {}}`), 0600)).To(Succeed())
+			Expect(awsEnv.PricingProvider.UpdateOverrides(ctx)).ToNot(Succeed())
+		})
+		It("should not read the override file when updating prices", func() {
+			Expect(os.WriteFile(path, []byte(`{"c98.large": 0.5}`), 0600)).To(Succeed())
+			ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", 1.20*0.72))
+		})
+		It("should pick up changes to the override file without updating prices", func() {
+			Expect(os.WriteFile(path, []byte(`{"spot": {"c5.large": {"test-zone-1a": 0.25}}}`), 0600)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, overridesController, types.NamespacedName{})
+			price, ok := awsEnv.PricingProvider.SpotPrice("c5.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.25))
+
+			Expect(os.WriteFile(path, []byte(`{"spot": {"c5.large": {"test-zone-1a": 0.125}}}`), 0600)).To(Succeed())
+			ExpectReconcileSucceeded(ctx, overridesController, types.NamespacedName{})
+			price, ok = awsEnv.PricingProvider.SpotPrice("c5.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.125))
+			Expect(awsEnv.PricingAPI.GetProductsInput.Len()).To(Equal(0))
+			Expect(awsEnv.EC2API.DescribeSpotPriceHistoryInput.IsNil()).To(BeTrue())
+		})
+	})
+	Context("Lifecycle", func() {
+		var provider *pricing.DefaultProvider
+		var providerController *controllerspricing.Controller
+		BeforeEach(func() {
+			provider = pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, fake.DefaultRegion, clock.RealClock{})
+			providerController = controllerspricing.NewController(clock.RealClock{}, provider)
+		})
+		AfterEach(func() {
+			provider.Stop()
+		})
+		It("should not leak goroutines when pricing updates repeatedly fail", func() {
+			ignored := goleak.IgnoreCurrent()
+			awsEnv.PricingAPI.NextError.Set(fmt.Errorf("failed"), fake.MaxCalls(20))
+			for i := 0; i < 10; i++ {
+				ExpectReconcileFailed(ctx, providerController, types.NamespacedName{})
+			}
+			provider.Stop()
+			Expect(goleak.Find(ignored)).To(Succeed())
+		})
+		It("should not have any active workers after pricing updates fail", func() {
+			awsEnv.PricingAPI.NextError.Set(fmt.Errorf("failed"), fake.MaxCalls(2))
+			ExpectReconcileFailed(ctx, providerController, types.NamespacedName{})
+			for _, worker := range []string{"on-demand", "on-demand-metal", "spot"} {
+				metric, ok := FindMetricWithLabelValues("karpenter_pricing_active_workers", map[string]string{"worker": worker})
+				Expect(ok).To(BeTrue())
+				Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 0))
+			}
+		})
+		It("should fail pricing updates and retain the last known prices once stopped", func() {
+			awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+				PriceList: []aws.JSONValue{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+				},
+			})
+			provider.Stop()
+			ExpectReconcileFailed(ctx, providerController, types.NamespacedName{})
+			Expect(awsEnv.EC2API.DescribeSpotPriceHistoryInput.IsNil()).To(BeTrue())
+			_, ok := provider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeFalse())
+			price, ok := provider.OnDemandPrice("c5.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically(">", 0))
+		})
+	})
 })
@@ -0,0 +1,198 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	// batchSize is the maximum number of snapshots that are deleted in a single reconcile. Any remaining
+	// candidates are picked up by the next reconcile.
+	batchSize = 100
+	// snapshotInUseCode is returned by EC2 when a snapshot still backs a registered AMI
+	snapshotInUseCode = "InvalidSnapshot.InUse"
+)
+
+// Controller deletes EBS snapshots that were created for this cluster once they are older than the configured
+// retention period. A snapshot is only considered when it carries both the cluster ownership tag and the
+// created-by marker tag, and is never deleted while it backs an AMI resolved by an EC2NodeClass.
+type Controller struct {
+	clk        clock.Clock
+	kubeClient client.Client
+	ec2api     ec2iface.EC2API
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, ec2api ec2iface.EC2API) *Controller {
+	return &Controller{
+		clk:        clk,
+		kubeClient: kubeClient,
+		ec2api:     ec2api,
+	}
+}
+
+func (c *Controller) Name() string {
+	return "snapshot.garbagecollection"
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	snapshots, err := c.listSnapshots(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing snapshots, %w", err)
+	}
+	inUse, err := c.referencedSnapshotIDs(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("resolving snapshots referenced by nodeclasses, %w", err)
+	}
+	retention := options.FromContext(ctx).SnapshotGCRetention
+	candidates := lo.Filter(snapshots, func(s *ec2.Snapshot, _ int) bool {
+		return isOwned(ctx, s) && !inUse.Has(aws.StringValue(s.SnapshotId)) &&
+			c.clk.Since(aws.TimeValue(s.StartTime)) > retention
+	})
+	// Delete the oldest snapshots first so that batching makes consistent progress
+	sort.Slice(candidates, func(i, j int) bool {
+		return aws.TimeValue(candidates[i].StartTime).Before(aws.TimeValue(candidates[j].StartTime))
+	})
+	garbageCollectionCandidates.Set(float64(len(candidates)))
+	if options.FromContext(ctx).SnapshotGCDryRun {
+		for _, s := range candidates {
+			logging.FromContext(ctx).With("snapshot-id", aws.StringValue(s.SnapshotId), "start-time", aws.TimeValue(s.StartTime)).Infof("dry-run, would have garbage collected snapshot")
+		}
+		return reconcile.Result{RequeueAfter: time.Hour}, nil
+	}
+	batch := lo.Slice(candidates, 0, batchSize)
+	errs := make([]error, len(batch))
+	workqueue.ParallelizeUntil(ctx, 10, len(batch), func(i int) {
+		errs[i] = c.garbageCollect(ctx, batch[i])
+	})
+	if err = multierr.Combine(errs...); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: lo.Ternary(len(candidates) > batchSize, time.Minute, time.Hour)}, nil
+}
+
+func (c *Controller) garbageCollect(ctx context.Context, snapshot *ec2.Snapshot) error {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("snapshot-id", aws.StringValue(snapshot.SnapshotId)))
+	if _, err := c.ec2api.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{SnapshotId: snapshot.SnapshotId}); err != nil {
+		// The snapshot backs a registered AMI that isn't referenced by any nodeclass; leave it alone
+		if e, ok := err.(awserr.Error); ok && e.Code() == snapshotInUseCode {
+			logging.FromContext(ctx).Debugf("skipping snapshot garbage collection, snapshot is in use")
+			return nil
+		}
+		return awserrors.IgnoreNotFound(err)
+	}
+	deletedSnapshots.Inc()
+	logging.FromContext(ctx).Debugf("garbage collected snapshot")
+	return nil
+}
+
+func (c *Controller) listSnapshots(ctx context.Context) ([]*ec2.Snapshot, error) {
+	var snapshots []*ec2.Snapshot
+	if err := c.ec2api.DescribeSnapshotsPagesWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		OwnerIds: aws.StringSlice([]string{"self"}),
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: aws.StringSlice([]string{clusterTagKey(ctx)}),
+			},
+			{
+				Name:   aws.String("tag-key"),
+				Values: aws.StringSlice([]string{v1beta1.TagCreatedBy}),
+			},
+		},
+	}, func(page *ec2.DescribeSnapshotsOutput, _ bool) bool {
+		snapshots = append(snapshots, page.Snapshots...)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// referencedSnapshotIDs returns the snapshots that back the AMIs currently resolved or pending in any EC2NodeClass status
+func (c *Controller) referencedSnapshotIDs(ctx context.Context) (sets.Set[string], error) {
+	nodeClassList := &v1beta1.EC2NodeClassList{}
+	if err := c.kubeClient.List(ctx, nodeClassList); err != nil {
+		return nil, err
+	}
+	imageIDs := sets.New[string]()
+	for _, nodeClass := range nodeClassList.Items {
+		// Pending AMIs will be launched once they're adopted, so their snapshots are kept as well
+		for _, ami := range append(nodeClass.Status.AMIs, nodeClass.Status.PendingAMIs...) {
+			imageIDs.Insert(ami.ID)
+		}
+	}
+	snapshotIDs := sets.New[string]()
+	if imageIDs.Len() == 0 {
+		return snapshotIDs, nil
+	}
+	if err := c.ec2api.DescribeImagesPagesWithContext(ctx, &ec2.DescribeImagesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("image-id"), Values: aws.StringSlice(sets.List(imageIDs))}},
+	}, func(page *ec2.DescribeImagesOutput, _ bool) bool {
+		for _, image := range page.Images {
+			for _, bdm := range image.BlockDeviceMappings {
+				if bdm.Ebs != nil && bdm.Ebs.SnapshotId != nil {
+					snapshotIDs.Insert(aws.StringValue(bdm.Ebs.SnapshotId))
+				}
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return snapshotIDs, nil
+}
+
+// isOwned re-checks the tags on the snapshot rather than trusting the DescribeSnapshots filters so that a snapshot
+// without the created-by marker is never deleted
+func isOwned(ctx context.Context, snapshot *ec2.Snapshot) bool {
+	tags := lo.SliceToMap(snapshot.Tags, func(t *ec2.Tag) (string, string) {
+		return aws.StringValue(t.Key), aws.StringValue(t.Value)
+	})
+	_, hasClusterTag := tags[clusterTagKey(ctx)]
+	_, hasMarkerTag := tags[v1beta1.TagCreatedBy]
+	return hasClusterTag && hasMarkerTag
+}
+
+func clusterTagKey(ctx context.Context) string {
+	return fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Builder {
+	return controller.NewSingletonManagedBy(m)
+}
@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	snapshotSubsystem = "snapshots"
+)
+
+var (
+	deletedSnapshots = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: snapshotSubsystem,
+			Name:      "deleted",
+			Help:      "Count of EBS snapshots deleted by snapshot garbage collection.",
+		},
+	)
+	garbageCollectionCandidates = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: snapshotSubsystem,
+			Name:      "garbage_collection_candidates",
+			Help:      "Number of EBS snapshots eligible for garbage collection during the last sweep, including those only reported in dry-run mode.",
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(deletedSnapshots, garbageCollectionCandidates)
+}
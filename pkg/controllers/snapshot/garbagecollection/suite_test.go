@@ -0,0 +1,204 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/scheme"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/snapshot/garbagecollection"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var garbageCollectionController *garbagecollection.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SnapshotGarbageCollection")
+}
+
+var _ = BeforeSuite(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	env = coretest.NewEnvironment(scheme.Scheme, coretest.WithCRDs(apis.CRDs...))
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	garbageCollectionController = garbagecollection.NewController(fakeClock, env.Client, awsEnv.EC2API)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		SnapshotGC:          lo.ToPtr(true),
+		SnapshotGCRetention: lo.ToPtr(24 * time.Hour),
+	}))
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("SnapshotGarbageCollection", func() {
+	It("should delete snapshots that are older than the retention period", func() {
+		old := makeSnapshot(fakeClock.Now().Add(-48*time.Hour), ownedTags()...)
+		recent := makeSnapshot(fakeClock.Now().Add(-time.Hour), ownedTags()...)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectSnapshotDeleted(old)
+		expectSnapshotExists(recent)
+	})
+	It("should delete snapshots in batches, oldest first", func() {
+		var snapshots []*ec2.Snapshot
+		for i := 0; i < 150; i++ {
+			snapshots = append(snapshots, makeSnapshot(fakeClock.Now().Add(-48*time.Hour-time.Duration(i)*time.Minute), ownedTags()...))
+		}
+		result := ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		for _, s := range snapshots[:50] {
+			expectSnapshotExists(s)
+		}
+		for _, s := range snapshots[50:] {
+			expectSnapshotDeleted(s)
+		}
+
+		result = ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+		for _, s := range snapshots {
+			expectSnapshotDeleted(s)
+		}
+	})
+	It("should not delete snapshots that back an AMI referenced in a nodeclass status", func() {
+		referenced := makeSnapshot(fakeClock.Now().Add(-48*time.Hour), ownedTags()...)
+		unreferenced := makeSnapshot(fakeClock.Now().Add(-48*time.Hour), ownedTags()...)
+		amiID := fake.ImageID()
+		awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+			{
+				Name:    aws.String("test-ami"),
+				ImageId: aws.String(amiID),
+				BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+					{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsBlockDevice{SnapshotId: referenced.SnapshotId}},
+				},
+			},
+		}})
+		nodeClass := test.EC2NodeClass()
+		nodeClass.Status.AMIs = []v1beta1.AMI{{ID: amiID, Name: "test-ami", Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{}}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectSnapshotExists(referenced)
+		expectSnapshotDeleted(unreferenced)
+	})
+	It("should only report candidates when dry-run is enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			SnapshotGC:          lo.ToPtr(true),
+			SnapshotGCRetention: lo.ToPtr(24 * time.Hour),
+			SnapshotGCDryRun:    lo.ToPtr(true),
+		}))
+		snapshots := []*ec2.Snapshot{
+			makeSnapshot(fakeClock.Now().Add(-48*time.Hour), ownedTags()...),
+			makeSnapshot(fakeClock.Now().Add(-72*time.Hour), ownedTags()...),
+		}
+		makeSnapshot(fakeClock.Now().Add(-time.Hour), ownedTags()...)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		for _, s := range snapshots {
+			expectSnapshotExists(s)
+		}
+		ExpectMetricGaugeValue("karpenter_snapshots_garbage_collection_candidates", 2, nil)
+	})
+	It("should never delete snapshots without the created-by marker tag", func() {
+		clusterOnly := makeSnapshot(fakeClock.Now().Add(-48*time.Hour), &ec2.Tag{
+			Key:   aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)),
+			Value: aws.String("owned"),
+		})
+		markerOnly := makeSnapshot(fakeClock.Now().Add(-48*time.Hour), &ec2.Tag{
+			Key:   aws.String(v1beta1.TagCreatedBy),
+			Value: aws.String("karpenter"),
+		})
+		otherCluster := makeSnapshot(fakeClock.Now().Add(-48*time.Hour),
+			&ec2.Tag{Key: aws.String("kubernetes.io/cluster/other-cluster"), Value: aws.String("owned")},
+			&ec2.Tag{Key: aws.String(v1beta1.TagCreatedBy), Value: aws.String("karpenter")},
+		)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		expectSnapshotExists(clusterOnly)
+		expectSnapshotExists(markerOnly)
+		expectSnapshotExists(otherCluster)
+	})
+})
+
+func ownedTags() []*ec2.Tag {
+	return []*ec2.Tag{
+		{
+			Key:   aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)),
+			Value: aws.String("owned"),
+		},
+		{
+			Key:   aws.String(v1beta1.TagCreatedBy),
+			Value: aws.String("karpenter"),
+		},
+	}
+}
+
+func makeSnapshot(startTime time.Time, tags ...*ec2.Tag) *ec2.Snapshot {
+	snapshot := &ec2.Snapshot{
+		SnapshotId: aws.String(fake.SnapshotID()),
+		StartTime:  aws.Time(startTime),
+		Tags:       tags,
+	}
+	awsEnv.EC2API.Snapshots.Store(aws.StringValue(snapshot.SnapshotId), snapshot)
+	return snapshot
+}
+
+func expectSnapshotExists(snapshot *ec2.Snapshot) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.Snapshots.Load(aws.StringValue(snapshot.SnapshotId))
+	Expect(ok).To(BeTrue(), "expected snapshot %s to exist", aws.StringValue(snapshot.SnapshotId))
+}
+
+func expectSnapshotDeleted(snapshot *ec2.Snapshot) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.Snapshots.Load(aws.StringValue(snapshot.SnapshotId))
+	Expect(ok).To(BeFalse(), "expected snapshot %s to be deleted", aws.StringValue(snapshot.SnapshotId))
+}
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotadvisor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/operator/controller"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
+)
+
+// Controller refreshes the spot advisor interruption frequencies that spot instance types are ordered by when
+// spot-interruption-penalty is set
+type Controller struct {
+	spotAdvisorProvider spotadvisor.Provider
+}
+
+func NewController(spotAdvisorProvider spotadvisor.Provider) *Controller {
+	return &Controller{
+		spotAdvisorProvider: spotAdvisorProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	if err := c.spotAdvisorProvider.UpdateSpotAdvisorData(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("updating spot advisor data, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: time.Hour}, nil
+}
+
+func (c *Controller) Name() string {
+	return "spotadvisor"
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Builder {
+	return controller.NewSingletonManagedBy(m)
+}
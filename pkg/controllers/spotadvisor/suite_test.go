@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotadvisor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	"sigs.k8s.io/karpenter/pkg/operator/scheme"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	controllersspotadvisor "github.com/aws/karpenter-provider-aws/pkg/controllers/spotadvisor"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllersspotadvisor.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SpotAdvisor")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(scheme.Scheme, coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = controllersspotadvisor.NewController(awsEnv.SpotAdvisorProvider)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+// httpClient serves a fixed response in place of the spot advisor feed
+type httpClient struct {
+	statusCode int
+	body       string
+}
+
+func (c httpClient) Do(_ *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:     http.StatusText(c.statusCode),
+		StatusCode: c.statusCode,
+		Body:       io.NopCloser(strings.NewReader(c.body)),
+	}, nil
+}
+
+var _ = Describe("SpotAdvisor", func() {
+	It("should update interruption frequencies with the spot advisor data", func() {
+		awsEnv.SpotAdvisorAPI.Output.Set(fake.NewSpotAdvisorData(fake.DefaultRegion, map[string]int{"m5.large": 0, "c5.large": 2, "t3.large": 4}))
+		result := ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+		for instanceType, expected := range map[string]float64{"m5.large": 0.05, "c5.large": 0.16, "t3.large": 1} {
+			rate, ok := awsEnv.SpotAdvisorProvider.InterruptionRate(instanceType, fake.DefaultRegion)
+			Expect(ok).To(BeTrue())
+			Expect(rate).To(BeNumerically("~", expected))
+		}
+	})
+	It("should respond with false for instance types and regions that the spot advisor doesn't list", func() {
+		awsEnv.SpotAdvisorAPI.Output.Set(fake.NewSpotAdvisorData(fake.DefaultRegion, map[string]int{"m5.large": 0}))
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+
+		_, ok := awsEnv.SpotAdvisorProvider.InterruptionRate("c5.large", fake.DefaultRegion)
+		Expect(ok).To(BeFalse())
+		_, ok = awsEnv.SpotAdvisorProvider.InterruptionRate("m5.large", "eu-west-1")
+		Expect(ok).To(BeFalse())
+	})
+	It("should respond with false before the spot advisor data has been fetched", func() {
+		ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+		_, ok := awsEnv.SpotAdvisorProvider.InterruptionRate("m5.large", fake.DefaultRegion)
+		Expect(ok).To(BeFalse())
+	})
+	It("should retain the last interruption frequencies when an update fails", func() {
+		awsEnv.SpotAdvisorAPI.Output.Set(fake.NewSpotAdvisorData(fake.DefaultRegion, map[string]int{"m5.large": 1}))
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+
+		awsEnv.SpotAdvisorAPI.NextError.Set(fmt.Errorf("failed"))
+		ExpectReconcileFailed(ctx, controller, types.NamespacedName{})
+		rate, ok := awsEnv.SpotAdvisorProvider.InterruptionRate("m5.large", fake.DefaultRegion)
+		Expect(ok).To(BeTrue())
+		Expect(rate).To(BeNumerically("~", 0.11))
+	})
+	It("should only use the interruption frequencies for Linux", func() {
+		data := fake.NewSpotAdvisorData(fake.DefaultRegion, map[string]int{"m5.large": 0})
+		data.SpotAdvisor[fake.DefaultRegion]["Windows"] = map[string]spotadvisor.Advice{"m5.large": {RangeIndex: 4}, "c5.large": {RangeIndex: 4}}
+		awsEnv.SpotAdvisorAPI.Output.Set(data)
+		ExpectReconcileSucceeded(ctx, controller, types.NamespacedName{})
+
+		rate, ok := awsEnv.SpotAdvisorProvider.InterruptionRate("m5.large", fake.DefaultRegion)
+		Expect(ok).To(BeTrue())
+		Expect(rate).To(BeNumerically("~", 0.05))
+		_, ok = awsEnv.SpotAdvisorProvider.InterruptionRate("c5.large", fake.DefaultRegion)
+		Expect(ok).To(BeFalse())
+	})
+	It("should fail to update when the feed responds with an error status", func() {
+		provider := spotadvisor.NewDefaultProvider(httpClient{statusCode: http.StatusForbidden}, spotadvisor.DataURL)
+		Expect(provider.UpdateSpotAdvisorData(ctx)).To(MatchError(ContainSubstring("unexpected status")))
+	})
+	It("should fail to update when the feed can't be decoded", func() {
+		provider := spotadvisor.NewDefaultProvider(httpClient{statusCode: http.StatusOK, body: "<html>"}, spotadvisor.DataURL)
+		Expect(provider.UpdateSpotAdvisorData(ctx)).To(MatchError(ContainSubstring("decoding spot advisor data")))
+	})
+	It("should fail to update when the feed doesn't list any interruption frequencies", func() {
+		provider := spotadvisor.NewDefaultProvider(httpClient{statusCode: http.StatusOK, body: `{"ranges":[],"spot_advisor":{}}`}, spotadvisor.DataURL)
+		Expect(provider.UpdateSpotAdvisorData(ctx)).To(HaveOccurred())
+	})
+	It("should pass the liveness probe", func() {
+		Expect(awsEnv.SpotAdvisorProvider.LivenessProbe(nil)).To(Succeed())
+	})
+})
@@ -15,9 +15,12 @@ limitations under the License.
 package errors
 
 import (
+	"context"
 	"errors"
+	"net"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -34,12 +37,17 @@ var (
 		"InvalidInstanceID.NotFound",
 		launchTemplateNameNotFoundCode,
 		"InvalidLaunchTemplateId.NotFound",
+		"InvalidSnapshot.NotFound",
 		sqs.ErrCodeQueueDoesNotExist,
 		iam.ErrCodeNoSuchEntityException,
 	)
 	alreadyExistsErrorCodes = sets.New[string](
 		iam.ErrCodeEntityAlreadyExistsException,
 	)
+	unauthorizedErrorCodes = sets.New[string](
+		"UnauthorizedOperation",
+		"AccessDenied",
+	)
 	// unfulfillableCapacityErrorCodes signify that capacity is temporarily unable to be launched
 	unfulfillableCapacityErrorCodes = sets.New[string](
 		"InsufficientInstanceCapacity",
@@ -48,6 +56,7 @@ var (
 		"UnfulfillableCapacity",
 		"Unsupported",
 		"InsufficientFreeAddressesInSubnet",
+		"ReservationCapacityExceeded",
 	)
 )
 
@@ -90,6 +99,19 @@ func IgnoreAlreadyExists(err error) error {
 	return err
 }
 
+// IsUnauthorized returns true if the err is an AWS error (even if it's
+// wrapped) and means that the caller isn't permitted to perform the operation
+func IsUnauthorized(err error) bool {
+	if err == nil {
+		return false
+	}
+	var awsError awserr.Error
+	if errors.As(err, &awsError) {
+		return unauthorizedErrorCodes.Has(awsError.Code())
+	}
+	return false
+}
+
 // IsUnfulfillableCapacity returns true if the Fleet err means
 // capacity is temporarily unavailable for launching.
 // This could be due to account limits, insufficient ec2 capacity, etc.
@@ -107,3 +129,23 @@ func IsLaunchTemplateNotFound(err error) bool {
 	}
 	return false
 }
+
+// IsRequestTimeout returns true if the err signals that the request timed out or was canceled before a response was
+// received. In this case the request may or may not have been applied by the AWS API.
+func IsRequestTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var awsError awserr.Error
+	if errors.As(err, &awsError) {
+		if awsError.Code() == request.CanceledErrorCode {
+			return true
+		}
+		return IsRequestTimeout(awsError.OrigErr())
+	}
+	var netError net.Error
+	return errors.As(err, &netError) && netError.Timeout()
+}
@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+// CloudWatchAPIBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type CloudWatchAPIBehavior struct {
+	PutMetricDataBehavior MockedFunction[cloudwatch.PutMetricDataInput, cloudwatch.PutMetricDataOutput]
+}
+
+type CloudWatchAPI struct {
+	cloudwatchiface.CloudWatchAPI
+	CloudWatchAPIBehavior
+}
+
+func NewCloudWatchAPI() *CloudWatchAPI {
+	return &CloudWatchAPI{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (c *CloudWatchAPI) Reset() {
+	c.PutMetricDataBehavior.Reset()
+}
+
+func (c *CloudWatchAPI) PutMetricDataWithContext(_ context.Context, input *cloudwatch.PutMetricDataInput, _ ...request.Option) (*cloudwatch.PutMetricDataOutput, error) {
+	return c.PutMetricDataBehavior.Invoke(input, func(*cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+		return &cloudwatch.PutMetricDataOutput{}, nil
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -55,16 +57,33 @@ type EC2Behavior struct {
 	DescribeAvailabilityZonesOutput     AtomicPtr[ec2.DescribeAvailabilityZonesOutput]
 	DescribeSpotPriceHistoryInput       AtomicPtr[ec2.DescribeSpotPriceHistoryInput]
 	DescribeSpotPriceHistoryOutput      AtomicPtr[ec2.DescribeSpotPriceHistoryOutput]
-	CreateFleetBehavior                 MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
-	TerminateInstancesBehavior          MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
-	DescribeInstancesBehavior           MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
+	// DescribeSpotPriceHistoryPageSize is the maximum number of prices returned by each DescribeSpotPriceHistory call.
+	// All the prices are returned in a single page if it isn't set.
+	DescribeSpotPriceHistoryPageSize AtomicPtr[int]
+	DescribePlacementGroupsOutput    AtomicPtr[ec2.DescribePlacementGroupsOutput]
+	// DescribeCapacityReservationsOutput is filtered by the request's filters. No capacity reservations exist if it isn't set.
+	DescribeCapacityReservationsOutput AtomicPtr[ec2.DescribeCapacityReservationsOutput]
+	CreateFleetBehavior                MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
+	TerminateInstancesBehavior         MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
+	StopInstancesBehavior              MockedFunction[ec2.StopInstancesInput, ec2.StopInstancesOutput]
+	DescribeInstancesBehavior          MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
+	DescribeInstanceStatusBehavior     MockedFunction[ec2.DescribeInstanceStatusInput, ec2.DescribeInstanceStatusOutput]
+	// DescribeInstancesPageSize is the maximum number of instances returned by each DescribeInstances and
+	// DescribeInstanceStatus call. All the instances are returned in a single page if it isn't set.
+	DescribeInstancesPageSize           AtomicPtr[int]
 	CreateTagsBehavior                  MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
 	CalledWithCreateLaunchTemplateInput AtomicPtrSlice[ec2.CreateLaunchTemplateInput]
 	CalledWithDescribeImagesInput       AtomicPtrSlice[ec2.DescribeImagesInput]
+	CalledWithDescribeSubnetsInput      AtomicPtrSlice[ec2.DescribeSubnetsInput]
 	Instances                           sync.Map
 	LaunchTemplates                     sync.Map
+	Snapshots                           sync.Map
 	InsufficientCapacityPools           atomic.Slice[CapacityPool]
 	NextError                           AtomicError
+	// CreateFleetResponseError is returned by CreateFleet after the instances have been launched, simulating a
+	// response that is lost to the client even though EC2 fulfilled the request
+	CreateFleetResponseError AtomicError
+	fleetsByClientToken      sync.Map
 }
 
 type EC2API struct {
@@ -90,12 +109,20 @@ func (e *EC2API) Reset() {
 	e.DescribeInstanceTypeOfferingsOutput.Reset()
 	e.DescribeAvailabilityZonesOutput.Reset()
 	e.CreateFleetBehavior.Reset()
+	e.CreateTagsBehavior.Reset()
 	e.TerminateInstancesBehavior.Reset()
+	e.StopInstancesBehavior.Reset()
 	e.DescribeInstancesBehavior.Reset()
+	e.DescribeInstanceStatusBehavior.Reset()
+	e.DescribeInstancesPageSize.Reset()
 	e.CalledWithCreateLaunchTemplateInput.Reset()
 	e.CalledWithDescribeImagesInput.Reset()
+	e.CalledWithDescribeSubnetsInput.Reset()
 	e.DescribeSpotPriceHistoryInput.Reset()
 	e.DescribeSpotPriceHistoryOutput.Reset()
+	e.DescribeSpotPriceHistoryPageSize.Reset()
+	e.DescribePlacementGroupsOutput.Reset()
+	e.DescribeCapacityReservationsOutput.Reset()
 	e.Instances.Range(func(k, v any) bool {
 		e.Instances.Delete(k)
 		return true
@@ -104,6 +131,15 @@ func (e *EC2API) Reset() {
 		e.LaunchTemplates.Delete(k)
 		return true
 	})
+	e.Snapshots.Range(func(k, v any) bool {
+		e.Snapshots.Delete(k)
+		return true
+	})
+	e.fleetsByClientToken.Range(func(k, v any) bool {
+		e.fleetsByClientToken.Delete(k)
+		return true
+	})
+	e.CreateFleetResponseError.Reset()
 	e.InsufficientCapacityPools.Reset()
 	e.NextError.Reset()
 }
@@ -114,13 +150,26 @@ func (e *EC2API) CreateFleetWithContext(_ context.Context, input *ec2.CreateFlee
 		if input.LaunchTemplateConfigs[0].LaunchTemplateSpecification.LaunchTemplateName == nil {
 			return nil, fmt.Errorf("missing launch template name")
 		}
+		// Requests with a previously seen client token return the original result rather than launching again
+		if output, ok := e.fleetsByClientToken.Load(aws.StringValue(input.ClientToken)); ok {
+			return output.(*ec2.CreateFleetOutput), nil
+		}
+		var instanceTags []*ec2.Tag
+		for _, spec := range input.TagSpecifications {
+			if aws.StringValue(spec.ResourceType) == ec2.ResourceTypeInstance {
+				instanceTags = spec.Tags
+			}
+		}
 		var instanceIds []*string
 		var skippedPools []CapacityPool
-		var spotInstanceRequestID *string
+		var spotInstanceRequestID, instanceLifecycle *string
 
 		if aws.StringValue(input.TargetCapacitySpecification.DefaultTargetCapacityType) == corev1beta1.CapacityTypeSpot {
 			spotInstanceRequestID = aws.String(test.RandomName())
 		}
+		if aws.StringValue(input.TargetCapacitySpecification.DefaultTargetCapacityType) == ec2.InstanceLifecycleTypeCapacityBlock {
+			instanceLifecycle = aws.String(ec2.InstanceLifecycleTypeCapacityBlock)
+		}
 
 		fulfilled := 0
 		for _, ltc := range input.LaunchTemplateConfigs {
@@ -154,9 +203,11 @@ func (e *EC2API) CreateFleetWithContext(_ context.Context, input *ec2.CreateFlee
 						PrivateDnsName:        aws.String(randomdata.IpV4Address()),
 						InstanceType:          input.LaunchTemplateConfigs[0].Overrides[0].InstanceType,
 						SpotInstanceRequestId: spotInstanceRequestID,
+						InstanceLifecycle:     instanceLifecycle,
 						State: &ec2.InstanceState{
 							Name: &instanceState,
 						},
+						Tags: instanceTags,
 					}
 					e.Instances.Store(*instance.InstanceId, instance)
 					instanceIds = append(instanceIds, instance.InstanceId)
@@ -166,12 +217,16 @@ func (e *EC2API) CreateFleetWithContext(_ context.Context, input *ec2.CreateFlee
 				break
 			}
 		}
-		result := &ec2.CreateFleetOutput{Instances: []*ec2.CreateFleetInstance{
+		result := &ec2.CreateFleetOutput{FleetId: aws.String(fmt.Sprintf("fleet-%s", test.RandomName())), Instances: []*ec2.CreateFleetInstance{
 			{
 				InstanceIds:  instanceIds,
 				InstanceType: input.LaunchTemplateConfigs[0].Overrides[0].InstanceType,
 				Lifecycle:    input.TargetCapacitySpecification.DefaultTargetCapacityType,
 				LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecification{
+						LaunchTemplateName: input.LaunchTemplateConfigs[0].LaunchTemplateSpecification.LaunchTemplateName,
+						Version:            input.LaunchTemplateConfigs[0].LaunchTemplateSpecification.Version,
+					},
 					Overrides: &ec2.FleetLaunchTemplateOverrides{
 						SubnetId:         input.LaunchTemplateConfigs[0].Overrides[0].SubnetId,
 						ImageId:          input.LaunchTemplateConfigs[0].Overrides[0].ImageId,
@@ -192,6 +247,12 @@ func (e *EC2API) CreateFleetWithContext(_ context.Context, input *ec2.CreateFlee
 				},
 			})
 		}
+		if input.ClientToken != nil {
+			e.fleetsByClientToken.Store(aws.StringValue(input.ClientToken), result)
+		}
+		if err := e.CreateFleetResponseError.Get(); err != nil {
+			return nil, err
+		}
 		return result, nil
 	})
 }
@@ -213,6 +274,26 @@ func (e *EC2API) TerminateInstancesWithContext(_ context.Context, input *ec2.Ter
 	})
 }
 
+func (e *EC2API) StopInstancesWithContext(_ context.Context, input *ec2.StopInstancesInput, _ ...request.Option) (*ec2.StopInstancesOutput, error) {
+	return e.StopInstancesBehavior.Invoke(input, func(input *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+		var instanceStateChanges []*ec2.InstanceStateChange
+		for _, id := range input.InstanceIds {
+			raw, ok := e.Instances.Load(aws.StringValue(id))
+			if !ok {
+				return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", aws.StringValue(id)), nil)
+			}
+			instance := raw.(*ec2.Instance)
+			instanceStateChanges = append(instanceStateChanges, &ec2.InstanceStateChange{
+				PreviousState: instance.State,
+				CurrentState:  &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopping), Code: aws.Int64(64)},
+				InstanceId:    id,
+			})
+			instance.State = &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopping), Code: aws.Int64(64)}
+		}
+		return &ec2.StopInstancesOutput{StoppingInstances: instanceStateChanges}, nil
+	})
+}
+
 func (e *EC2API) CreateLaunchTemplateWithContext(_ context.Context, input *ec2.CreateLaunchTemplateInput, _ ...request.Option) (*ec2.CreateLaunchTemplateOutput, error) {
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
@@ -228,6 +309,10 @@ func (e *EC2API) CreateTagsWithContext(_ context.Context, input *ec2.CreateTagsI
 	return e.CreateTagsBehavior.Invoke(input, func(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
 		// Update passed in instances with the passed tags
 		for _, id := range input.Resources {
+			// Subnets aren't tracked by the fake, so tagging them always succeeds
+			if strings.HasPrefix(aws.StringValue(id), "subnet-") {
+				continue
+			}
 			raw, ok := e.Instances.Load(aws.StringValue(id))
 			if !ok {
 				return nil, fmt.Errorf("instance with id '%s' does not exist", aws.StringValue(id))
@@ -265,19 +350,103 @@ func (e *EC2API) DescribeInstancesWithContext(_ context.Context, input *ec2.Desc
 			}
 			instances = append(instances, instance.(*ec2.Instance))
 		}
-		return &ec2.DescribeInstancesOutput{
-			Reservations: []*ec2.Reservation{{Instances: filterInstances(instances, input.Filters)}},
-		}, nil
+		instances = filterInstances(instances, input.Filters)
+		// Describes by instance ID aren't paginated
+		if e.DescribeInstancesPageSize.IsNil() || len(input.InstanceIds) > 0 {
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []*ec2.Reservation{{Instances: instances}},
+			}, nil
+		}
+		// Pages are cut from the instances ordered by ID so that they're stable between calls
+		sort.Slice(instances, func(i, j int) bool {
+			return aws.StringValue(instances[i].InstanceId) < aws.StringValue(instances[j].InstanceId)
+		})
+		start, err := strconv.Atoi(lo.Ternary(input.NextToken == nil, "0", aws.StringValue(input.NextToken)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid next token %q", aws.StringValue(input.NextToken))
+		}
+		end := lo.Min([]int{start + *e.DescribeInstancesPageSize.Clone(), len(instances)})
+		output := &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{Instances: instances[start:end]}},
+		}
+		if end < len(instances) {
+			output.NextToken = aws.String(strconv.Itoa(end))
+		}
+		return output, nil
 	})
 }
 
 func (e *EC2API) DescribeInstancesPagesWithContext(ctx context.Context, input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, opts ...request.Option) error {
-	output, err := e.DescribeInstancesWithContext(ctx, input, opts...)
-	if err != nil {
-		return err
+	for {
+		output, err := e.DescribeInstancesWithContext(ctx, input, opts...)
+		if err != nil {
+			return err
+		}
+		lastPage := output.NextToken == nil
+		if !fn(output, lastPage) || lastPage {
+			return nil
+		}
+		next := *input
+		next.NextToken = output.NextToken
+		input = &next
+	}
+}
+
+func (e *EC2API) DescribeInstanceStatusWithContext(_ context.Context, input *ec2.DescribeInstanceStatusInput, _ ...request.Option) (*ec2.DescribeInstanceStatusOutput, error) {
+	return e.DescribeInstanceStatusBehavior.Invoke(input, func(input *ec2.DescribeInstanceStatusInput) (*ec2.DescribeInstanceStatusOutput, error) {
+		var instances []*ec2.Instance
+		e.Instances.Range(func(k interface{}, v interface{}) bool {
+			instances = append(instances, v.(*ec2.Instance))
+			return true
+		})
+		instances = filterInstances(instances, input.Filters)
+		// Only running instances are described unless all instances are asked for
+		if !aws.BoolValue(input.IncludeAllInstances) {
+			instances = lo.Filter(instances, func(i *ec2.Instance, _ int) bool {
+				return aws.StringValue(i.State.Name) == ec2.InstanceStateNameRunning
+			})
+		}
+		sort.Slice(instances, func(i, j int) bool {
+			return aws.StringValue(instances[i].InstanceId) < aws.StringValue(instances[j].InstanceId)
+		})
+		start, err := strconv.Atoi(lo.Ternary(input.NextToken == nil, "0", aws.StringValue(input.NextToken)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid next token %q", aws.StringValue(input.NextToken))
+		}
+		end := len(instances)
+		if !e.DescribeInstancesPageSize.IsNil() {
+			end = lo.Min([]int{start + *e.DescribeInstancesPageSize.Clone(), len(instances)})
+		}
+		output := &ec2.DescribeInstanceStatusOutput{
+			InstanceStatuses: lo.Map(instances[start:end], func(i *ec2.Instance, _ int) *ec2.InstanceStatus {
+				return &ec2.InstanceStatus{
+					InstanceId:       i.InstanceId,
+					InstanceState:    i.State,
+					AvailabilityZone: lo.Ternary(i.Placement != nil, i.Placement, &ec2.Placement{}).AvailabilityZone,
+				}
+			}),
+		}
+		if end < len(instances) {
+			output.NextToken = aws.String(strconv.Itoa(end))
+		}
+		return output, nil
+	})
+}
+
+func (e *EC2API) DescribeInstanceStatusPagesWithContext(ctx context.Context, input *ec2.DescribeInstanceStatusInput, fn func(*ec2.DescribeInstanceStatusOutput, bool) bool, opts ...request.Option) error {
+	for {
+		output, err := e.DescribeInstanceStatusWithContext(ctx, input, opts...)
+		if err != nil {
+			return err
+		}
+		lastPage := output.NextToken == nil
+		if !fn(output, lastPage) || lastPage {
+			return nil
+		}
+		next := *input
+		next.NextToken = output.NextToken
+		input = &next
 	}
-	fn(output, false)
-	return nil
 }
 
 //nolint:gocyclo
@@ -293,6 +462,11 @@ func filterInstances(instances []*ec2.Instance, filters []*ec2.Filter) []*ec2.In
 					passesFilter = false
 					break OUTER
 				}
+			case aws.StringValue(filter.Name) == "instance-id":
+				if !sets.New(aws.StringValueSlice(filter.Values)...).Has(aws.StringValue(instance.InstanceId)) {
+					passesFilter = false
+					break OUTER
+				}
 			case aws.StringValue(filter.Name) == "tag-key":
 				values := sets.New(aws.StringValueSlice(filter.Values)...)
 				if _, ok := lo.Find(instance.Tags, func(t *ec2.Tag) bool {
@@ -404,7 +578,62 @@ func (e *EC2API) DeleteLaunchTemplateWithContext(_ context.Context, input *ec2.D
 	return nil, nil
 }
 
+func (e *EC2API) DescribePlacementGroupsWithContext(_ context.Context, input *ec2.DescribePlacementGroupsInput, _ ...request.Option) (*ec2.DescribePlacementGroupsOutput, error) {
+	if !e.NextError.IsNil() {
+		defer e.NextError.Reset()
+		return nil, e.NextError.Get()
+	}
+	if !e.DescribePlacementGroupsOutput.IsNil() {
+		output := e.DescribePlacementGroupsOutput.Clone()
+		output.PlacementGroups = lo.Filter(output.PlacementGroups, func(pg *ec2.PlacementGroup, _ int) bool {
+			return len(input.GroupNames) == 0 || lo.Contains(aws.StringValueSlice(input.GroupNames), aws.StringValue(pg.GroupName))
+		})
+		return output, nil
+	}
+	return nil, awserr.New("InvalidPlacementGroup.Unknown", "The specified placement group does not exist.", nil)
+}
+
+func (e *EC2API) DescribeSnapshotsWithContext(_ context.Context, input *ec2.DescribeSnapshotsInput, _ ...request.Option) (*ec2.DescribeSnapshotsOutput, error) {
+	if !e.NextError.IsNil() {
+		defer e.NextError.Reset()
+		return nil, e.NextError.Get()
+	}
+	output := &ec2.DescribeSnapshotsOutput{}
+	e.Snapshots.Range(func(_, value interface{}) bool {
+		snapshot := value.(*ec2.Snapshot)
+		if len(input.SnapshotIds) != 0 && !lo.Contains(aws.StringValueSlice(input.SnapshotIds), aws.StringValue(snapshot.SnapshotId)) {
+			return true
+		}
+		if Filter(input.Filters, aws.StringValue(snapshot.SnapshotId), "", snapshot.Tags) {
+			output.Snapshots = append(output.Snapshots, snapshot)
+		}
+		return true
+	})
+	return output, nil
+}
+
+func (e *EC2API) DescribeSnapshotsPagesWithContext(ctx context.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, _ ...request.Option) error {
+	out, err := e.DescribeSnapshotsWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	fn(out, false)
+	return nil
+}
+
+func (e *EC2API) DeleteSnapshotWithContext(_ context.Context, input *ec2.DeleteSnapshotInput, _ ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+	if !e.NextError.IsNil() {
+		defer e.NextError.Reset()
+		return nil, e.NextError.Get()
+	}
+	if _, ok := e.Snapshots.LoadAndDelete(aws.StringValue(input.SnapshotId)); !ok {
+		return nil, awserr.New("InvalidSnapshot.NotFound", fmt.Sprintf("The snapshot '%s' does not exist.", aws.StringValue(input.SnapshotId)), nil)
+	}
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
 func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	e.CalledWithDescribeSubnetsInput.Add(input)
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
 		return nil, e.NextError.Get()
@@ -418,6 +647,7 @@ func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.Descri
 		{
 			SubnetId:                aws.String("subnet-test1"),
 			AvailabilityZone:        aws.String("test-zone-1a"),
+			AvailabilityZoneId:      aws.String("tstz1-1a"),
 			AvailableIpAddressCount: aws.Int64(100),
 			MapPublicIpOnLaunch:     aws.Bool(false),
 			Tags: []*ec2.Tag{
@@ -428,6 +658,7 @@ func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.Descri
 		{
 			SubnetId:                aws.String("subnet-test2"),
 			AvailabilityZone:        aws.String("test-zone-1b"),
+			AvailabilityZoneId:      aws.String("tstz1-1b"),
 			AvailableIpAddressCount: aws.Int64(100),
 			MapPublicIpOnLaunch:     aws.Bool(true),
 			Tags: []*ec2.Tag{
@@ -438,6 +669,7 @@ func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.Descri
 		{
 			SubnetId:                aws.String("subnet-test3"),
 			AvailabilityZone:        aws.String("test-zone-1c"),
+			AvailabilityZoneId:      aws.String("tstz1-1c"),
 			AvailableIpAddressCount: aws.Int64(100),
 			Tags: []*ec2.Tag{
 				{Key: aws.String("Name"), Value: aws.String("test-subnet-3")},
@@ -448,6 +680,7 @@ func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.Descri
 		{
 			SubnetId:                aws.String("subnet-test4"),
 			AvailabilityZone:        aws.String("test-zone-1a-local"),
+			AvailabilityZoneId:      aws.String("tstz1-1alocal"),
 			AvailableIpAddressCount: aws.Int64(100),
 			MapPublicIpOnLaunch:     aws.Bool(true),
 			Tags: []*ec2.Tag{
@@ -469,7 +702,7 @@ func (e *EC2API) DescribeSecurityGroupsWithContext(_ context.Context, input *ec2
 	if !e.DescribeSecurityGroupsOutput.IsNil() {
 		describeSecurityGroupsOutput := e.DescribeSecurityGroupsOutput.Clone()
 		describeSecurityGroupsOutput.SecurityGroups = FilterDescribeSecurtyGroups(describeSecurityGroupsOutput.SecurityGroups, input.Filters)
-		return e.DescribeSecurityGroupsOutput.Clone(), nil
+		return describeSecurityGroupsOutput, nil
 	}
 	sgs := []*ec2.SecurityGroup{
 		{
@@ -504,6 +737,19 @@ func (e *EC2API) DescribeSecurityGroupsWithContext(_ context.Context, input *ec2
 	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: FilterDescribeSecurtyGroups(sgs, input.Filters)}, nil
 }
 
+func (e *EC2API) DescribeCapacityReservationsWithContext(_ context.Context, input *ec2.DescribeCapacityReservationsInput, _ ...request.Option) (*ec2.DescribeCapacityReservationsOutput, error) {
+	if !e.NextError.IsNil() {
+		defer e.NextError.Reset()
+		return nil, e.NextError.Get()
+	}
+	if e.DescribeCapacityReservationsOutput.IsNil() {
+		return &ec2.DescribeCapacityReservationsOutput{}, nil
+	}
+	describeCapacityReservationsOutput := e.DescribeCapacityReservationsOutput.Clone()
+	describeCapacityReservationsOutput.CapacityReservations = FilterDescribeCapacityReservations(describeCapacityReservationsOutput.CapacityReservations, input.Filters)
+	return describeCapacityReservationsOutput, nil
+}
+
 func (e *EC2API) DescribeAvailabilityZonesWithContext(context.Context, *ec2.DescribeAvailabilityZonesInput, ...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error) {
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
@@ -513,10 +759,10 @@ func (e *EC2API) DescribeAvailabilityZonesWithContext(context.Context, *ec2.Desc
 		return e.DescribeAvailabilityZonesOutput.Clone(), nil
 	}
 	return &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
-		{ZoneName: aws.String("test-zone-1a"), ZoneId: aws.String("testzone1a"), ZoneType: aws.String("availability-zone")},
-		{ZoneName: aws.String("test-zone-1b"), ZoneId: aws.String("testzone1b"), ZoneType: aws.String("availability-zone")},
-		{ZoneName: aws.String("test-zone-1c"), ZoneId: aws.String("testzone1c"), ZoneType: aws.String("availability-zone")},
-		{ZoneName: aws.String("test-zone-1a-local"), ZoneId: aws.String("testzone1alocal"), ZoneType: aws.String("local-zone")},
+		{ZoneName: aws.String("test-zone-1a"), ZoneId: aws.String("testzone1a"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+		{ZoneName: aws.String("test-zone-1b"), ZoneId: aws.String("testzone1b"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+		{ZoneName: aws.String("test-zone-1c"), ZoneId: aws.String("testzone1c"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+		{ZoneName: aws.String("test-zone-1a-local"), ZoneId: aws.String("testzone1alocal"), ZoneType: aws.String("local-zone"), NetworkBorderGroup: aws.String("test-zone-1-local")},
 	}}, nil
 }
 
@@ -677,18 +923,60 @@ func (e *EC2API) DescribeSpotPriceHistoryWithContext(_ aws.Context, input *ec2.D
 		defer e.NextError.Reset()
 		return nil, e.NextError.Get()
 	}
-	if !e.DescribeSpotPriceHistoryOutput.IsNil() {
-		return e.DescribeSpotPriceHistoryOutput.Clone(), nil
+	if e.DescribeSpotPriceHistoryOutput.IsNil() {
+		// fail if the test doesn't provide specific data which causes our pricing provider to use its static price list
+		return nil, errors.New("no pricing data provided")
 	}
-	// fail if the test doesn't provide specific data which causes our pricing provider to use its static price list
-	return nil, errors.New("no pricing data provided")
+	prices := spotPriceHistory(e.DescribeSpotPriceHistoryOutput.Clone().SpotPriceHistory, input.StartTime)
+	if e.DescribeSpotPriceHistoryPageSize.IsNil() {
+		return &ec2.DescribeSpotPriceHistoryOutput{SpotPriceHistory: prices}, nil
+	}
+	start, err := strconv.Atoi(lo.Ternary(input.NextToken == nil, "0", aws.StringValue(input.NextToken)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid next token %q", aws.StringValue(input.NextToken))
+	}
+	end := lo.Min([]int{start + *e.DescribeSpotPriceHistoryPageSize.Clone(), len(prices)})
+	output := &ec2.DescribeSpotPriceHistoryOutput{SpotPriceHistory: prices[start:end]}
+	if end < len(prices) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
 }
 
 func (e *EC2API) DescribeSpotPriceHistoryPagesWithContext(ctx aws.Context, input *ec2.DescribeSpotPriceHistoryInput, fn func(*ec2.DescribeSpotPriceHistoryOutput, bool) bool, _ ...request.Option) error {
-	out, err := e.DescribeSpotPriceHistoryWithContext(ctx, input)
-	if err != nil {
-		return err
+	for {
+		out, err := e.DescribeSpotPriceHistoryWithContext(ctx, input)
+		if err != nil {
+			return err
+		}
+		if !fn(out, out.NextToken == nil) || out.NextToken == nil {
+			return nil
+		}
+		next := *input
+		next.NextToken = out.NextToken
+		input = &next
 	}
-	fn(out, false)
-	return nil
+}
+
+// spotPriceHistory returns the prices that DescribeSpotPriceHistory reports from a start time, which are the price of
+// each offering in effect at the start time along with every price that took effect since
+func spotPriceHistory(prices []*ec2.SpotPrice, startTime *time.Time) []*ec2.SpotPrice {
+	if startTime == nil {
+		return prices
+	}
+	key := func(p *ec2.SpotPrice) string {
+		return fmt.Sprintf("%s/%s/%s", aws.StringValue(p.InstanceType), aws.StringValue(p.AvailabilityZone), aws.StringValue(p.ProductDescription))
+	}
+	inEffect := map[string]*ec2.SpotPrice{}
+	for _, p := range prices {
+		if p.Timestamp == nil || p.Timestamp.After(*startTime) {
+			continue
+		}
+		if current, ok := inEffect[key(p)]; !ok || p.Timestamp.After(*current.Timestamp) {
+			inEffect[key(p)] = p
+		}
+	}
+	return lo.Filter(prices, func(p *ec2.SpotPrice, _ int) bool {
+		return p.Timestamp == nil || p.Timestamp.After(*startTime) || inEffect[key(p)] == p
+	})
 }
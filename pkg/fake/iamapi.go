@@ -38,6 +38,8 @@ type IAMAPIBehavior struct {
 	DeleteInstanceProfileBehavior         MockedFunction[iam.DeleteInstanceProfileInput, iam.DeleteInstanceProfileOutput]
 	AddRoleToInstanceProfileBehavior      MockedFunction[iam.AddRoleToInstanceProfileInput, iam.AddRoleToInstanceProfileOutput]
 	RemoveRoleFromInstanceProfileBehavior MockedFunction[iam.RemoveRoleFromInstanceProfileInput, iam.RemoveRoleFromInstanceProfileOutput]
+	TagInstanceProfileBehavior            MockedFunction[iam.TagInstanceProfileInput, iam.TagInstanceProfileOutput]
+	GetRoleBehavior                       MockedFunction[iam.GetRoleInput, iam.GetRoleOutput]
 }
 
 type IAMAPI struct {
@@ -47,10 +49,11 @@ type IAMAPI struct {
 	IAMAPIBehavior
 
 	InstanceProfiles map[string]*iam.InstanceProfile
+	Roles            map[string]*iam.Role
 }
 
 func NewIAMAPI() *IAMAPI {
-	return &IAMAPI{InstanceProfiles: map[string]*iam.InstanceProfile{}}
+	return &IAMAPI{InstanceProfiles: map[string]*iam.InstanceProfile{}, Roles: map[string]*iam.Role{}}
 }
 
 // Reset must be called between tests otherwise tests will pollute
@@ -61,7 +64,10 @@ func (s *IAMAPI) Reset() {
 	s.DeleteInstanceProfileBehavior.Reset()
 	s.AddRoleToInstanceProfileBehavior.Reset()
 	s.RemoveRoleFromInstanceProfileBehavior.Reset()
+	s.TagInstanceProfileBehavior.Reset()
+	s.GetRoleBehavior.Reset()
 	s.InstanceProfiles = map[string]*iam.InstanceProfile{}
+	s.Roles = map[string]*iam.Role{}
 }
 
 func (s *IAMAPI) GetInstanceProfileWithContext(_ context.Context, input *iam.GetInstanceProfileInput, _ ...request.Option) (*iam.GetInstanceProfileOutput, error) {
@@ -146,3 +152,31 @@ func (s *IAMAPI) RemoveRoleFromInstanceProfileWithContext(_ context.Context, inp
 		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, fmt.Sprintf("Instance Profile %s cannot be found", aws.StringValue(input.InstanceProfileName)), nil)
 	})
 }
+
+func (s *IAMAPI) TagInstanceProfileWithContext(_ context.Context, input *iam.TagInstanceProfileInput, _ ...request.Option) (*iam.TagInstanceProfileOutput, error) {
+	return s.TagInstanceProfileBehavior.Invoke(input, func(output *iam.TagInstanceProfileInput) (*iam.TagInstanceProfileOutput, error) {
+		s.Lock()
+		defer s.Unlock()
+
+		if i, ok := s.InstanceProfiles[aws.StringValue(input.InstanceProfileName)]; ok {
+			tags := lo.Reject(i.Tags, func(t *iam.Tag, _ int) bool {
+				return lo.ContainsBy(input.Tags, func(n *iam.Tag) bool { return aws.StringValue(n.Key) == aws.StringValue(t.Key) })
+			})
+			i.Tags = append(tags, input.Tags...)
+			return nil, nil
+		}
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, fmt.Sprintf("Instance Profile %s cannot be found", aws.StringValue(input.InstanceProfileName)), nil)
+	})
+}
+
+func (s *IAMAPI) GetRoleWithContext(_ context.Context, input *iam.GetRoleInput, _ ...request.Option) (*iam.GetRoleOutput, error) {
+	return s.GetRoleBehavior.Invoke(input, func(*iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+		s.Lock()
+		defer s.Unlock()
+
+		if r, ok := s.Roles[aws.StringValue(input.RoleName)]; ok {
+			return &iam.GetRoleOutput{Role: r}, nil
+		}
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, fmt.Sprintf("The role with name %s cannot be found", aws.StringValue(input.RoleName)), nil)
+	})
+}
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/outposts"
+	"github.com/aws/aws-sdk-go/service/outposts/outpostsiface"
+)
+
+// OutpostsAPIBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type OutpostsAPIBehavior struct {
+	GetOutpostInstanceTypesBehavior MockedFunction[outposts.GetOutpostInstanceTypesInput, outposts.GetOutpostInstanceTypesOutput]
+}
+
+type OutpostsAPI struct {
+	outpostsiface.OutpostsAPI
+	OutpostsAPIBehavior
+}
+
+func NewOutpostsAPI() *OutpostsAPI {
+	return &OutpostsAPI{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (s *OutpostsAPI) Reset() {
+	s.GetOutpostInstanceTypesBehavior.Reset()
+}
+
+func (s *OutpostsAPI) GetOutpostInstanceTypesPagesWithContext(_ context.Context, input *outposts.GetOutpostInstanceTypesInput, fn func(*outposts.GetOutpostInstanceTypesOutput, bool) bool, _ ...request.Option) error {
+	output, err := s.GetOutpostInstanceTypesBehavior.Invoke(input, func(*outposts.GetOutpostInstanceTypesInput) (*outposts.GetOutpostInstanceTypesOutput, error) {
+		return &outposts.GetOutpostInstanceTypesOutput{OutpostId: input.OutpostId}, nil
+	})
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
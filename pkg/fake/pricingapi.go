@@ -31,14 +31,17 @@ type PricingAPI struct {
 type PricingBehavior struct {
 	NextError         AtomicError
 	GetProductsOutput AtomicPtr[pricing.GetProductsOutput]
+	GetProductsInput  AtomicPtrSlice[pricing.GetProductsInput]
 }
 
 func (p *PricingAPI) Reset() {
 	p.NextError.Reset()
 	p.GetProductsOutput.Reset()
+	p.GetProductsInput.Reset()
 }
 
-func (p *PricingAPI) GetProductsPagesWithContext(_ aws.Context, _ *pricing.GetProductsInput, fn func(*pricing.GetProductsOutput, bool) bool, _ ...request.Option) error {
+func (p *PricingAPI) GetProductsPagesWithContext(_ aws.Context, input *pricing.GetProductsInput, fn func(*pricing.GetProductsOutput, bool) bool, _ ...request.Option) error {
+	p.GetProductsInput.Add(input)
 	if !p.NextError.IsNil() {
 		return p.NextError.Get()
 	}
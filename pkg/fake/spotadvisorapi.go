@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
+)
+
+// SpotAdvisorAPI serves the spot advisor data in place of the public feed
+type SpotAdvisorAPI struct {
+	SpotAdvisorBehavior
+}
+type SpotAdvisorBehavior struct {
+	NextError AtomicError
+	Output    AtomicPtr[spotadvisor.Data]
+}
+
+func (s *SpotAdvisorAPI) Reset() {
+	s.NextError.Reset()
+	s.Output.Reset()
+}
+
+func (s *SpotAdvisorAPI) Do(_ *http.Request) (*http.Response, error) {
+	if !s.NextError.IsNil() {
+		return nil, s.NextError.Get()
+	}
+	// fail if the test doesn't provide specific data which causes instance types to be ordered by price alone
+	if s.Output.IsNil() {
+		return nil, errors.New("no spot advisor data provided")
+	}
+	body, err := json.Marshal(s.Output.Clone())
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// NewSpotAdvisorData returns spot advisor data that lists the interruption frequency range of each instance type in the
+// region. The ranges match the ones published in the public feed.
+func NewSpotAdvisorData(region string, rangeIndices map[string]int) *spotadvisor.Data {
+	advice := map[string]spotadvisor.Advice{}
+	for instanceType, index := range rangeIndices {
+		advice[instanceType] = spotadvisor.Advice{RangeIndex: index}
+	}
+	return &spotadvisor.Data{
+		Ranges: []spotadvisor.Range{
+			{Index: 0, Label: "<5%", Max: 5},
+			{Index: 1, Label: "5-10%", Max: 11},
+			{Index: 2, Label: "10-15%", Max: 16},
+			{Index: 3, Label: "15-20%", Max: 22},
+			{Index: 4, Label: ">20%", Max: 100},
+		},
+		SpotAdvisor: map[string]map[string]map[string]spotadvisor.Advice{region: {"Linux": advice}},
+	}
+}
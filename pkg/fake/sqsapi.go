@@ -21,6 +21,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/samber/lo"
 )
 
 const (
@@ -30,9 +31,11 @@ const (
 // SQSBehavior must be reset between tests otherwise tests will
 // pollute each other.
 type SQSBehavior struct {
-	GetQueueURLBehavior    MockedFunction[sqs.GetQueueUrlInput, sqs.GetQueueUrlOutput]
-	ReceiveMessageBehavior MockedFunction[sqs.ReceiveMessageInput, sqs.ReceiveMessageOutput]
-	DeleteMessageBehavior  MockedFunction[sqs.DeleteMessageInput, sqs.DeleteMessageOutput]
+	GetQueueURLBehavior        MockedFunction[sqs.GetQueueUrlInput, sqs.GetQueueUrlOutput]
+	ReceiveMessageBehavior     MockedFunction[sqs.ReceiveMessageInput, sqs.ReceiveMessageOutput]
+	DeleteMessageBehavior      MockedFunction[sqs.DeleteMessageInput, sqs.DeleteMessageOutput]
+	DeleteMessageBatchBehavior MockedFunction[sqs.DeleteMessageBatchInput, sqs.DeleteMessageBatchOutput]
+	GetQueueAttributesBehavior MockedFunction[sqs.GetQueueAttributesInput, sqs.GetQueueAttributesOutput]
 }
 
 type SQSAPI struct {
@@ -46,6 +49,8 @@ func (s *SQSAPI) Reset() {
 	s.GetQueueURLBehavior.Reset()
 	s.ReceiveMessageBehavior.Reset()
 	s.DeleteMessageBehavior.Reset()
+	s.DeleteMessageBatchBehavior.Reset()
+	s.GetQueueAttributesBehavior.Reset()
 }
 
 //nolint:revive,stylecheck
@@ -59,7 +64,7 @@ func (s *SQSAPI) GetQueueUrlWithContext(_ context.Context, input *sqs.GetQueueUr
 
 func (s *SQSAPI) ReceiveMessageWithContext(_ context.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
 	return s.ReceiveMessageBehavior.Invoke(input, func(_ *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
-		return nil, nil
+		return &sqs.ReceiveMessageOutput{}, nil
 	})
 }
 
@@ -68,3 +73,21 @@ func (s *SQSAPI) DeleteMessageWithContext(_ context.Context, input *sqs.DeleteMe
 		return nil, nil
 	})
 }
+
+func (s *SQSAPI) DeleteMessageBatchWithContext(_ context.Context, input *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	return s.DeleteMessageBatchBehavior.Invoke(input, func(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+		return &sqs.DeleteMessageBatchOutput{
+			Successful: lo.Map(input.Entries, func(e *sqs.DeleteMessageBatchRequestEntry, _ int) *sqs.DeleteMessageBatchResultEntry {
+				return &sqs.DeleteMessageBatchResultEntry{Id: e.Id}
+			}),
+		}, nil
+	})
+}
+
+func (s *SQSAPI) GetQueueAttributesWithContext(_ context.Context, input *sqs.GetQueueAttributesInput, _ ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	return s.GetQueueAttributesBehavior.Invoke(input, func(_ *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+		return &sqs.GetQueueAttributesOutput{
+			Attributes: map[string]*string{sqs.QueueAttributeNameApproximateNumberOfMessages: aws.String("0")},
+		}, nil
+	})
+}
@@ -32,13 +32,16 @@ type SSMAPI struct {
 	Parameters         map[string]string
 	GetParameterOutput *ssm.GetParameterOutput
 	WantErr            error
+
+	CalledWithGetParameterInput AtomicPtrSlice[ssm.GetParameterInput]
 }
 
 func NewSSMAPI() *SSMAPI {
 	return &SSMAPI{}
 }
 
-func (a SSMAPI) GetParameterWithContext(_ context.Context, input *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
+func (a *SSMAPI) GetParameterWithContext(_ context.Context, input *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
+	a.CalledWithGetParameterInput.Add(input)
 	if a.WantErr != nil {
 		return nil, a.WantErr
 	}
@@ -64,4 +67,5 @@ func (a *SSMAPI) Reset() {
 	a.GetParameterOutput = nil
 	a.Parameters = nil
 	a.WantErr = nil
+	a.CalledWithGetParameterInput.Reset()
 }
@@ -17,6 +17,7 @@ package fake
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/Pallinder/go-randomdata"
@@ -24,6 +25,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
@@ -52,6 +54,10 @@ func SubnetID() string {
 	return fmt.Sprintf("subnet-%s", randomdata.Alphanumeric(17))
 }
 
+func SnapshotID() string {
+	return fmt.Sprintf("snap-%s", strings.ToLower(randomdata.Alphanumeric(17)))
+}
+
 func InstanceProfileID() string {
 	return fmt.Sprintf("instanceprofile-%s", randomdata.Alphanumeric(17))
 }
@@ -101,6 +107,18 @@ func FilterDescribeSubnets(subnets []*ec2.Subnet, filters []*ec2.Filter) []*ec2.
 	})
 }
 
+// FilterDescribeCapacityReservations filters the passed in capacity reservations based on the filters passed in.
+// Filters are chained with a logical "AND"
+func FilterDescribeCapacityReservations(capacityReservations []*ec2.CapacityReservation, filters []*ec2.Filter) []*ec2.CapacityReservation {
+	isState := func(filter *ec2.Filter, _ int) bool { return aws.StringValue(filter.Name) == "state" }
+	stateFilters, otherFilters := lo.Filter(filters, isState), lo.Reject(filters, isState)
+	return lo.Filter(capacityReservations, func(capacityReservation *ec2.CapacityReservation, _ int) bool {
+		return lo.EveryBy(stateFilters, func(filter *ec2.Filter) bool {
+			return lo.Contains(aws.StringValueSlice(filter.Values), aws.StringValue(capacityReservation.State))
+		}) && Filter(otherFilters, *capacityReservation.CapacityReservationId, "", capacityReservation.Tags)
+	})
+}
+
 func FilterDescribeImages(images []*ec2.Image, filters []*ec2.Filter) []*ec2.Image {
 	return lo.Filter(images, func(image *ec2.Image, _ int) bool {
 		return Filter(filters, *image.ImageId, *image.Name, image.Tags)
@@ -111,7 +129,7 @@ func FilterDescribeImages(images []*ec2.Image, filters []*ec2.Filter) []*ec2.Ima
 func Filter(filters []*ec2.Filter, id, name string, tags []*ec2.Tag) bool {
 	return lo.EveryBy(filters, func(filter *ec2.Filter) bool {
 		switch filterName := aws.StringValue(filter.Name); {
-		case filterName == "subnet-id" || filterName == "group-id" || filterName == "image-id":
+		case filterName == "subnet-id" || filterName == "group-id" || filterName == "image-id" || filterName == "capacity-reservation-id":
 			for _, val := range filter.Values {
 				if id == aws.StringValue(val) {
 					return true
@@ -119,7 +137,7 @@ func Filter(filters []*ec2.Filter, id, name string, tags []*ec2.Tag) bool {
 			}
 		case filterName == "group-name" || filterName == "name":
 			for _, val := range filter.Values {
-				if name == aws.StringValue(val) {
+				if matchWildcard(aws.StringValue(val), name) {
 					return true
 				}
 			}
@@ -134,6 +152,12 @@ func Filter(filters []*ec2.Filter, id, name string, tags []*ec2.Tag) bool {
 	})
 }
 
+// matchWildcard matches a value against a filter value that may contain the EC2 filter wildcards '*' and '?'
+func matchWildcard(pattern, value string) bool {
+	expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern))
+	return regexp.MustCompile("^" + expr + "$").MatchString(value)
+}
+
 // matchTags is a predicate that matches a slice of tags with a tag:<key> or tag-keys filter
 // nolint: gocyclo
 func matchTags(tags []*ec2.Tag, filter *ec2.Filter) bool {
@@ -166,7 +190,7 @@ func MakeInstances() []*ec2.InstanceTypeInfo {
 	ctx := options.ToContext(context.Background(), &options.Options{IsolatedVPC: true})
 	// Use keys from the static pricing data so that we guarantee pricing for the data
 	// Create uniform instance data so all of them schedule for a given pod
-	for _, it := range pricing.NewDefaultProvider(ctx, nil, nil, "us-east-1").InstanceTypes() {
+	for _, it := range pricing.NewDefaultProvider(ctx, nil, nil, "us-east-1", clock.RealClock{}).InstanceTypes() {
 		instanceTypes = append(instanceTypes, &ec2.InstanceTypeInfo{
 			InstanceType: aws.String(it),
 			ProcessorInfo: &ec2.ProcessorInfo{
@@ -34,13 +34,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("AWS"),
-				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
+				Manufacturer:             aws.String("AWS"),
+				SupportedArchitectures:   aws.StringSlice([]string{"arm64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(2),
-				DefaultVCpus: aws.Int64(2),
+				DefaultCores:        aws.Int64(2),
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(4096),
@@ -54,6 +57,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(3),
+						BaselineBandwidthInGbps:  aws.Float64(0.75),
+						PeakBandwidthInGbps:      aws.Float64(10),
 					},
 				},
 			},
@@ -65,13 +70,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(false),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(3),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(48),
-				DefaultVCpus: aws.Int64(96),
+				DefaultCores:        aws.Int64(48),
+				DefaultVCpus:        aws.Int64(96),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(786432),
@@ -103,18 +111,26 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(15),
+						BaselineBandwidthInGbps:  aws.Float64(100),
+						PeakBandwidthInGbps:      aws.Float64(100),
 					},
 					{
 						NetworkCardIndex:         aws.Int64(1),
 						MaximumNetworkInterfaces: aws.Int64(15),
+						BaselineBandwidthInGbps:  aws.Float64(100),
+						PeakBandwidthInGbps:      aws.Float64(100),
 					},
 					{
 						NetworkCardIndex:         aws.Int64(2),
 						MaximumNetworkInterfaces: aws.Int64(15),
+						BaselineBandwidthInGbps:  aws.Float64(100),
+						PeakBandwidthInGbps:      aws.Float64(100),
 					},
 					{
 						NetworkCardIndex:         aws.Int64(3),
 						MaximumNetworkInterfaces: aws.Int64(15),
+						BaselineBandwidthInGbps:  aws.Float64(100),
+						PeakBandwidthInGbps:      aws.Float64(100),
 					},
 				},
 			},
@@ -126,13 +142,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(16),
-				DefaultVCpus: aws.Int64(32),
+				DefaultCores:        aws.Int64(16),
+				DefaultVCpus:        aws.Int64(32),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(131072),
@@ -164,6 +183,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(4),
+						BaselineBandwidthInGbps:  aws.Float64(50),
+						PeakBandwidthInGbps:      aws.Float64(50),
 					},
 				},
 			},
@@ -175,13 +196,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(4),
-				DefaultVCpus: aws.Int64(8),
+				DefaultCores:        aws.Int64(4),
+				DefaultVCpus:        aws.Int64(8),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(16384),
@@ -204,6 +228,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(4),
+						BaselineBandwidthInGbps:  aws.Float64(5),
+						PeakBandwidthInGbps:      aws.Float64(25),
 					},
 				},
 			},
@@ -215,13 +241,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(12),
-				DefaultVCpus: aws.Int64(24),
+				DefaultCores:        aws.Int64(12),
+				DefaultVCpus:        aws.Int64(24),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(49152),
@@ -244,6 +273,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(8),
+						BaselineBandwidthInGbps:  aws.Float64(25),
+						PeakBandwidthInGbps:      aws.Float64(25),
 					},
 				},
 			},
@@ -255,13 +286,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(3.1),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(1),
-				DefaultVCpus: aws.Int64(2),
+				DefaultCores:        aws.Int64(1),
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(8192),
@@ -275,6 +309,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(3),
+						BaselineBandwidthInGbps:  aws.Float64(0.75),
+						PeakBandwidthInGbps:      aws.Float64(10),
 					},
 				},
 			},
@@ -286,9 +322,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(true),
 			Hypervisor:                    aws.String(""),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(3.1),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
 				DefaultCores: aws.Int64(48),
@@ -306,6 +344,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(15),
+						BaselineBandwidthInGbps:  aws.Float64(25),
+						PeakBandwidthInGbps:      aws.Float64(25),
 					},
 				},
 			},
@@ -317,13 +357,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(3.1),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(2),
-				DefaultVCpus: aws.Int64(4),
+				DefaultCores:        aws.Int64(2),
+				DefaultVCpus:        aws.Int64(4),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(16384),
@@ -337,6 +380,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(4),
+						BaselineBandwidthInGbps:  aws.Float64(1.25),
+						PeakBandwidthInGbps:      aws.Float64(10),
 					},
 				},
 			},
@@ -348,13 +393,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(3.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(64),
-				DefaultVCpus: aws.Int64(128),
+				DefaultCores:        aws.Int64(64),
+				DefaultVCpus:        aws.Int64(128),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(524288),
@@ -374,10 +422,14 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(7),
+						BaselineBandwidthInGbps:  aws.Float64(100),
+						PeakBandwidthInGbps:      aws.Float64(100),
 					},
 					{
 						NetworkCardIndex:         aws.Int64(1),
 						MaximumNetworkInterfaces: aws.Int64(7),
+						BaselineBandwidthInGbps:  aws.Float64(100),
+						PeakBandwidthInGbps:      aws.Float64(100),
 					},
 				},
 			},
@@ -389,13 +441,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("xen"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.3),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(16),
-				DefaultVCpus: aws.Int64(32),
+				DefaultCores:        aws.Int64(16),
+				DefaultVCpus:        aws.Int64(32),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(249856),
@@ -421,6 +476,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(8),
+						BaselineBandwidthInGbps:  aws.Float64(10),
+						PeakBandwidthInGbps:      aws.Float64(10),
 					},
 				},
 			},
@@ -432,13 +489,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(1),
-				DefaultVCpus: aws.Int64(2),
+				DefaultCores:        aws.Int64(1),
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(8192),
@@ -452,6 +512,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(3),
+						BaselineBandwidthInGbps:  aws.Float64(0.512),
+						PeakBandwidthInGbps:      aws.Float64(5),
 					},
 				},
 			},
@@ -463,13 +525,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(false),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("AWS"),
-				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
+				Manufacturer:             aws.String("AWS"),
+				SupportedArchitectures:   aws.StringSlice([]string{"arm64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(2),
-				DefaultVCpus: aws.Int64(2),
+				DefaultCores:        aws.Int64(2),
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(4096),
@@ -483,6 +548,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(3),
+						BaselineBandwidthInGbps:  aws.Float64(0.256),
+						PeakBandwidthInGbps:      aws.Float64(5),
 					},
 				},
 			},
@@ -494,13 +561,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(false),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("AWS"),
-				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
+				Manufacturer:             aws.String("AWS"),
+				SupportedArchitectures:   aws.StringSlice([]string{"arm64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(2),
-				DefaultVCpus: aws.Int64(2),
+				DefaultCores:        aws.Int64(2),
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(2048),
@@ -514,6 +584,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(3),
+						BaselineBandwidthInGbps:  aws.Float64(0.128),
+						PeakBandwidthInGbps:      aws.Float64(5),
 					},
 				},
 			},
@@ -525,13 +597,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(false),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("AWS"),
-				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
+				Manufacturer:             aws.String("AWS"),
+				SupportedArchitectures:   aws.StringSlice([]string{"arm64"}),
+				SustainedClockSpeedInGhz: aws.Float64(2.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(4),
-				DefaultVCpus: aws.Int64(4),
+				DefaultCores:        aws.Int64(4),
+				DefaultVCpus:        aws.Int64(4),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(16384),
@@ -545,6 +620,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(4),
+						BaselineBandwidthInGbps:  aws.Float64(0.512),
+						PeakBandwidthInGbps:      aws.Float64(5),
 					},
 				},
 			},
@@ -556,13 +633,16 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			DedicatedHostsSupported:       aws.Bool(true),
 			ProcessorInfo: &ec2.ProcessorInfo{
-				Manufacturer:           aws.String("Intel"),
-				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+				Manufacturer:             aws.String("Intel"),
+				SupportedArchitectures:   aws.StringSlice([]string{"x86_64"}),
+				SustainedClockSpeedInGhz: aws.Float64(3.5),
 			},
 			VCpuInfo: &ec2.VCpuInfo{
-				DefaultCores: aws.Int64(4),
-				DefaultVCpus: aws.Int64(8),
+				DefaultCores:        aws.Int64(4),
+				DefaultVCpus:        aws.Int64(8),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
 			},
 			MemoryInfo: &ec2.MemoryInfo{
 				SizeInMiB: aws.Int64(32768),
@@ -579,6 +659,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 					{
 						NetworkCardIndex:         aws.Int64(0),
 						MaximumNetworkInterfaces: aws.Int64(4),
+						BaselineBandwidthInGbps:  aws.Float64(3.125),
+						PeakBandwidthInGbps:      aws.Float64(12.5),
 					},
 				},
 			},
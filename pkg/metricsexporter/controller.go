@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricsexporter
+
+import (
+	"context"
+	"time"
+
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/operator/controller"
+)
+
+// flushInterval is how often metrics are published, which matches the standard resolution of CloudWatch metrics
+const flushInterval = time.Minute
+
+// Controller publishes the metrics recorded by the Exporter to CloudWatch every minute
+type Controller struct {
+	exporter *Exporter
+}
+
+func NewController(exporter *Exporter) *Controller {
+	return &Controller{
+		exporter: exporter,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	// Publishing is best effort, failures are logged rather than retried with backoff so that metrics keep being
+	// published every interval once CloudWatch can be reached again
+	if err := c.exporter.Flush(ctx); err != nil {
+		logging.FromContext(ctx).Errorf("publishing metrics to cloudwatch, %s", err)
+	}
+	return reconcile.Result{RequeueAfter: flushInterval}, nil
+}
+
+func (c *Controller) Name() string {
+	return "metricsexporter"
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Builder {
+	return controller.NewSingletonManagedBy(m)
+}
@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricsexporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/utils/clock"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	MetricInstanceLaunchLatency = "InstanceLaunchLatency"
+	MetricCreateFleetErrors     = "CreateFleetErrors"
+	MetricUnavailableOfferings  = "UnavailableOfferings"
+
+	DimensionClusterName = "ClusterName"
+	DimensionErrorCode   = "ErrorCode"
+
+	// maxDatumsPerRequest is the most metric datums that CloudWatch accepts in a single PutMetricData request
+	maxDatumsPerRequest = 1000
+	// pendingLaunchTTL bounds how long a launch is tracked while waiting for its instance to be found
+	pendingLaunchTTL = 15 * time.Minute
+)
+
+// Exporter publishes metrics to CloudWatch for clusters whose dashboards and alarms live there rather than in
+// Prometheus. Metrics are aggregated in memory as they're recorded and published in batches by the Controller, so
+// recording a metric never waits on CloudWatch. Every method is a no-op on a nil Exporter, which is what's used when
+// publishing to CloudWatch is disabled.
+type Exporter struct {
+	cloudwatchAPI        cloudwatchiface.CloudWatchAPI
+	clk                  clock.Clock
+	unavailableOfferings *awscache.UnavailableOfferings
+
+	mu            sync.Mutex
+	launchLatency *cloudwatch.StatisticSet
+	fleetErrors   map[string]float64
+	// pendingLaunches holds when the launch of each instance started until the instance is first found, keyed by
+	// instance ID
+	pendingLaunches *cache.Cache
+}
+
+func NewExporter(cloudwatchAPI cloudwatchiface.CloudWatchAPI, clk clock.Clock, unavailableOfferings *awscache.UnavailableOfferings) *Exporter {
+	return &Exporter{
+		cloudwatchAPI:        cloudwatchAPI,
+		clk:                  clk,
+		unavailableOfferings: unavailableOfferings,
+		fleetErrors:          map[string]float64{},
+		pendingLaunches:      cache.New(pendingLaunchTTL, awscache.DefaultCleanupInterval),
+	}
+}
+
+// ObserveLaunch records that an instance was launched by a request that started at start. Its launch latency is
+// recorded once the instance is found.
+func (e *Exporter) ObserveLaunch(instanceID string, start time.Time) {
+	if e == nil {
+		return
+	}
+	e.pendingLaunches.SetDefault(instanceID, start)
+}
+
+// ObserveInstanceFound records the launch latency of an instance the first time that it's found after its launch
+func (e *Exporter) ObserveInstanceFound(instanceID string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	start, ok := e.pendingLaunches.Get(instanceID)
+	if !ok {
+		return
+	}
+	e.pendingLaunches.Delete(instanceID)
+	latency := e.clk.Since(start.(time.Time)).Seconds()
+	if e.launchLatency == nil {
+		e.launchLatency = &cloudwatch.StatisticSet{SampleCount: aws.Float64(0), Sum: aws.Float64(0), Minimum: aws.Float64(latency), Maximum: aws.Float64(latency)}
+	}
+	e.launchLatency.SampleCount = aws.Float64(aws.Float64Value(e.launchLatency.SampleCount) + 1)
+	e.launchLatency.Sum = aws.Float64(aws.Float64Value(e.launchLatency.Sum) + latency)
+	e.launchLatency.Minimum = aws.Float64(lo.Min([]float64{aws.Float64Value(e.launchLatency.Minimum), latency}))
+	e.launchLatency.Maximum = aws.Float64(lo.Max([]float64{aws.Float64Value(e.launchLatency.Maximum), latency}))
+}
+
+// ObserveCreateFleetError records a CreateFleet error, either of the request or of a launch template override
+func (e *Exporter) ObserveCreateFleetError(code string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fleetErrors[code]++
+}
+
+// Flush publishes the metrics recorded since the last flush. Metrics that fail to publish are dropped rather than kept
+// for the next flush, so that an unreachable CloudWatch can't grow them without bound.
+func (e *Exporter) Flush(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	datums := e.datums(ctx)
+	var errs error
+	for _, batch := range lo.Chunk(datums, maxDatumsPerRequest) {
+		if _, err := e.cloudwatchAPI.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(options.FromContext(ctx).CloudWatchMetricsNamespace),
+			MetricData: batch,
+		}); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("putting %d metric datums, %w", len(batch), err))
+		}
+	}
+	return errs
+}
+
+// datums takes the metrics recorded since the last flush, along with the current number of unavailable offerings
+func (e *Exporter) datums(ctx context.Context) []*cloudwatch.MetricDatum {
+	e.mu.Lock()
+	launchLatency, fleetErrors := e.launchLatency, e.fleetErrors
+	e.launchLatency, e.fleetErrors = nil, map[string]float64{}
+	e.mu.Unlock()
+
+	now := e.clk.Now()
+	cluster := &cloudwatch.Dimension{Name: aws.String(DimensionClusterName), Value: aws.String(options.FromContext(ctx).ClusterName)}
+	datums := []*cloudwatch.MetricDatum{{
+		MetricName: aws.String(MetricUnavailableOfferings),
+		Dimensions: []*cloudwatch.Dimension{cluster},
+		Timestamp:  aws.Time(now),
+		Unit:       aws.String(cloudwatch.StandardUnitCount),
+		Value:      aws.Float64(float64(e.unavailableOfferings.Len())),
+	}}
+	if launchLatency != nil {
+		datums = append(datums, &cloudwatch.MetricDatum{
+			MetricName:      aws.String(MetricInstanceLaunchLatency),
+			Dimensions:      []*cloudwatch.Dimension{cluster},
+			Timestamp:       aws.Time(now),
+			Unit:            aws.String(cloudwatch.StandardUnitSeconds),
+			StatisticValues: launchLatency,
+		})
+	}
+	for _, code := range lo.Keys(fleetErrors) {
+		datums = append(datums, &cloudwatch.MetricDatum{
+			MetricName: aws.String(MetricCreateFleetErrors),
+			Dimensions: []*cloudwatch.Dimension{cluster, {Name: aws.String(DimensionErrorCode), Value: aws.String(code)}},
+			Timestamp:  aws.Time(now),
+			Unit:       aws.String(cloudwatch.StandardUnitCount),
+			Value:      aws.Float64(fleetErrors[code]),
+		})
+	}
+	return datums
+}
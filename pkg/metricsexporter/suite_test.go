@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricsexporter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/samber/lo"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/metricsexporter"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+)
+
+var ctx context.Context
+var fakeClock *clocktesting.FakeClock
+var cloudwatchAPI *fake.CloudWatchAPI
+var unavailableOfferings *awscache.UnavailableOfferings
+var exporter *metricsexporter.Exporter
+
+func TestMetricsExporter(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MetricsExporter")
+}
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{CloudWatchMetricsNamespace: lo.ToPtr("Karpenter")}))
+	fakeClock = clocktesting.NewFakeClock(time.Now())
+	cloudwatchAPI = fake.NewCloudWatchAPI()
+	unavailableOfferings = awscache.NewUnavailableOfferings()
+	exporter = metricsexporter.NewExporter(cloudwatchAPI, fakeClock, unavailableOfferings)
+})
+
+var _ = Describe("Exporter", func() {
+	It("should publish to the configured namespace with the cluster name dimension", func() {
+		Expect(exporter.Flush(ctx)).To(Succeed())
+		input := cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValue(input.Namespace)).To(Equal("Karpenter"))
+		for _, datum := range input.MetricData {
+			Expect(dimension(datum, metricsexporter.DimensionClusterName)).To(Equal("test-cluster"))
+		}
+	})
+	It("should publish the number of unavailable offerings", func() {
+		unavailableOfferings.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		unavailableOfferings.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1b", corev1beta1.CapacityTypeSpot)
+		Expect(exporter.Flush(ctx)).To(Succeed())
+		datum := datumNamed(cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop(), metricsexporter.MetricUnavailableOfferings)
+		Expect(datum).ToNot(BeNil())
+		Expect(aws.Float64Value(datum.Value)).To(BeNumerically("==", 2))
+	})
+	It("should publish launch latency statistics for instances that were found", func() {
+		start := fakeClock.Now()
+		exporter.ObserveLaunch("i-1", start)
+		exporter.ObserveLaunch("i-2", start)
+		exporter.ObserveLaunch("i-3", start)
+		fakeClock.Step(10 * time.Second)
+		exporter.ObserveInstanceFound("i-1")
+		fakeClock.Step(20 * time.Second)
+		exporter.ObserveInstanceFound("i-2")
+		// later lookups of an instance don't record its latency again
+		exporter.ObserveInstanceFound("i-1")
+		Expect(exporter.Flush(ctx)).To(Succeed())
+
+		datum := datumNamed(cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop(), metricsexporter.MetricInstanceLaunchLatency)
+		Expect(datum).ToNot(BeNil())
+		Expect(aws.StringValue(datum.Unit)).To(Equal(cloudwatch.StandardUnitSeconds))
+		Expect(aws.Float64Value(datum.StatisticValues.SampleCount)).To(BeNumerically("==", 2))
+		Expect(aws.Float64Value(datum.StatisticValues.Sum)).To(BeNumerically("==", 40))
+		Expect(aws.Float64Value(datum.StatisticValues.Minimum)).To(BeNumerically("==", 10))
+		Expect(aws.Float64Value(datum.StatisticValues.Maximum)).To(BeNumerically("==", 30))
+	})
+	It("should not publish launch latency for instances that weren't launched by this process", func() {
+		exporter.ObserveInstanceFound("i-1")
+		Expect(exporter.Flush(ctx)).To(Succeed())
+		Expect(datumNamed(cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop(), metricsexporter.MetricInstanceLaunchLatency)).To(BeNil())
+	})
+	It("should publish CreateFleet errors by error code", func() {
+		exporter.ObserveCreateFleetError("InsufficientInstanceCapacity")
+		exporter.ObserveCreateFleetError("InsufficientInstanceCapacity")
+		exporter.ObserveCreateFleetError("UnfulfillableCapacity")
+		Expect(exporter.Flush(ctx)).To(Succeed())
+
+		input := cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop()
+		errorCounts := map[string]float64{}
+		for _, datum := range input.MetricData {
+			if aws.StringValue(datum.MetricName) == metricsexporter.MetricCreateFleetErrors {
+				errorCounts[dimension(datum, metricsexporter.DimensionErrorCode)] = aws.Float64Value(datum.Value)
+			}
+		}
+		Expect(errorCounts).To(Equal(map[string]float64{"InsufficientInstanceCapacity": 2, "UnfulfillableCapacity": 1}))
+	})
+	It("should only publish the metrics recorded since the last flush", func() {
+		exporter.ObserveLaunch("i-1", fakeClock.Now())
+		exporter.ObserveInstanceFound("i-1")
+		exporter.ObserveCreateFleetError("InsufficientInstanceCapacity")
+		Expect(exporter.Flush(ctx)).To(Succeed())
+		Expect(exporter.Flush(ctx)).To(Succeed())
+
+		Expect(cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Len()).To(Equal(2))
+		input := cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop()
+		Expect(input.MetricData).To(HaveLen(1))
+		Expect(aws.StringValue(input.MetricData[0].MetricName)).To(Equal(metricsexporter.MetricUnavailableOfferings))
+	})
+	It("should split metrics across requests when there are more than CloudWatch accepts in one", func() {
+		for i := 0; i < 1000; i++ {
+			exporter.ObserveCreateFleetError(fmt.Sprintf("Error%d", i))
+		}
+		Expect(exporter.Flush(ctx)).To(Succeed())
+		Expect(cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Len()).To(Equal(2))
+		sizes := []int{}
+		cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.ForEach(func(input *cloudwatch.PutMetricDataInput) {
+			sizes = append(sizes, len(input.MetricData))
+		})
+		Expect(sizes).To(ConsistOf(1000, 1))
+	})
+	It("should return an error and drop the metrics when publishing fails", func() {
+		cloudwatchAPI.PutMetricDataBehavior.Error.Set(errors.New("throttled"), fake.MaxCalls(1))
+		exporter.ObserveCreateFleetError("InsufficientInstanceCapacity")
+		Expect(exporter.Flush(ctx)).ToNot(Succeed())
+
+		Expect(exporter.Flush(ctx)).To(Succeed())
+		input := cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop()
+		Expect(datumNamed(input, metricsexporter.MetricCreateFleetErrors)).To(BeNil())
+	})
+	It("should do nothing when nil", func() {
+		var nilExporter *metricsexporter.Exporter
+		nilExporter.ObserveLaunch("i-1", fakeClock.Now())
+		nilExporter.ObserveInstanceFound("i-1")
+		nilExporter.ObserveCreateFleetError("InsufficientInstanceCapacity")
+		Expect(nilExporter.Flush(ctx)).To(Succeed())
+	})
+})
+
+var _ = Describe("Controller", func() {
+	It("should publish metrics and requeue after a minute", func() {
+		result, err := metricsexporter.NewController(exporter).Reconcile(ctx, reconcile.Request{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		Expect(cloudwatchAPI.PutMetricDataBehavior.Calls()).To(Equal(1))
+	})
+	It("should requeue after a minute when publishing fails", func() {
+		cloudwatchAPI.PutMetricDataBehavior.Error.Set(errors.New("throttled"), fake.MaxCalls(1))
+		result, err := metricsexporter.NewController(exporter).Reconcile(ctx, reconcile.Request{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+	})
+})
+
+func datumNamed(input *cloudwatch.PutMetricDataInput, name string) *cloudwatch.MetricDatum {
+	datum, _ := lo.Find(input.MetricData, func(d *cloudwatch.MetricDatum) bool { return aws.StringValue(d.MetricName) == name })
+	return datum
+}
+
+func dimension(datum *cloudwatch.MetricDatum, name string) string {
+	d, _ := lo.Find(datum.Dimensions, func(d *cloudwatch.Dimension) bool { return aws.StringValue(d.Name) == name })
+	return aws.StringValue(d.Value)
+}
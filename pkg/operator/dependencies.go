@@ -0,0 +1,183 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"knative.dev/pkg/logging"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// The AWS APIs that the operator depends on are checked once on startup:
+//
+//	dependency | required | when it's checked                        | while it can't be reached
+//	-----------+----------+------------------------------------------+------------------------------------------------
+//	ec2        | yes      | always, with STS when assuming a role    | the operator fails to start
+//	pricing    | no       | unless in an isolated VPC or a partition | static prices are served
+//	           |          | without the pricing API                  |
+//	iam        | no       | always                                   | instance profiles can't be managed for
+//	           |          |                                          | EC2NodeClasses that set spec.role
+//	sqs        | no       | when an interruption queue is configured | interruption messages aren't received
+//
+// The region, cluster endpoint and cluster CA bundle are also required, and are resolved before the dependencies are
+// checked. Dependencies that can't be reached are marked degraded and rechecked until they recover.
+const (
+	DependencyEC2     = "ec2"
+	DependencyPricing = "pricing"
+	DependencyIAM     = "iam"
+	DependencySQS     = "sqs"
+)
+
+// Dependency is an AWS API that the operator depends on
+type Dependency struct {
+	Name string
+	// Required dependencies fail startup when they can't be reached
+	Required bool
+	Check    func(context.Context) error
+}
+
+// Dependencies tracks which of the operator's optional dependencies are degraded
+type Dependencies struct {
+	dependencies []Dependency
+
+	mu       sync.RWMutex
+	degraded map[string]error
+}
+
+func NewDependencies(dependencies ...Dependency) *Dependencies {
+	return &Dependencies{
+		dependencies: dependencies,
+		degraded:     map[string]error{},
+	}
+}
+
+// StartupDependencies returns the dependencies of an operator that is configured from the context
+func StartupDependencies(ctx context.Context, ec2api ec2iface.EC2API, pricingAPI pricingiface.PricingAPI, pricingAPIAvailable bool,
+	iamapi iamiface.IAMAPI, sqsapi sqsiface.SQSAPI) []Dependency {
+	dependencies := []Dependency{
+		{Name: DependencyEC2, Required: true, Check: func(ctx context.Context) error { return CheckEC2Connectivity(ctx, ec2api) }},
+	}
+	if pricingAPIAvailable && !options.FromContext(ctx).IsolatedVPC {
+		dependencies = append(dependencies, Dependency{Name: DependencyPricing, Check: func(ctx context.Context) error { return CheckPricingConnectivity(ctx, pricingAPI) }})
+	}
+	dependencies = append(dependencies, Dependency{Name: DependencyIAM, Check: func(ctx context.Context) error { return CheckIAMConnectivity(ctx, iamapi) }})
+	if queue := options.FromContext(ctx).InterruptionQueue; queue != "" {
+		dependencies = append(dependencies, Dependency{Name: DependencySQS, Check: func(ctx context.Context) error { return CheckSQSConnectivity(ctx, sqsapi, queue) }})
+	}
+	return dependencies
+}
+
+// Check checks every dependency, returning an error if a required dependency can't be reached. Optional dependencies
+// that can't be reached are marked degraded.
+func (d *Dependencies) Check(ctx context.Context) error {
+	var errs []error
+	for _, dependency := range d.dependencies {
+		err := dependency.Check(ctx)
+		if err != nil && dependency.Required {
+			errs = append(errs, fmt.Errorf("checking required dependency %s, %w", dependency.Name, err))
+			continue
+		}
+		d.mark(ctx, dependency.Name, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Recheck checks the degraded dependencies, marking those that can be reached again as recovered
+func (d *Dependencies) Recheck(ctx context.Context) {
+	for _, dependency := range d.dependencies {
+		if d.IsDegraded(dependency.Name) {
+			d.mark(ctx, dependency.Name, dependency.Check(ctx))
+		}
+	}
+}
+
+// IsDegraded returns whether the named dependency couldn't be reached when it was last checked
+func (d *Dependencies) IsDegraded(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.degraded[name]
+	return ok
+}
+
+// Degraded returns the names of the degraded dependencies
+func (d *Dependencies) Degraded() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	names := lo.Keys(d.degraded)
+	sort.Strings(names)
+	return names
+}
+
+func (d *Dependencies) mark(ctx context.Context, name string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, degraded := d.degraded[name]
+	switch {
+	case err != nil:
+		if !degraded {
+			logging.FromContext(ctx).With("dependency", name).Warnf("running degraded, dependency can't be reached, %s", err)
+		}
+		d.degraded[name] = err
+		dependencyDegraded.With(prometheus.Labels{dependencyLabel: name}).Set(1)
+	case degraded:
+		logging.FromContext(ctx).With("dependency", name).Infof("dependency recovered")
+		delete(d.degraded, name)
+		dependencyDegraded.With(prometheus.Labels{dependencyLabel: name}).Set(0)
+	default:
+		dependencyDegraded.With(prometheus.Labels{dependencyLabel: name}).Set(0)
+	}
+}
+
+// CheckPricingConnectivity requests a single page of EC2 prices from the pricing API
+func CheckPricingConnectivity(ctx context.Context, api pricingiface.PricingAPI) error {
+	return api.GetProductsPagesWithContext(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		MaxResults:  aws.Int64(1),
+	}, func(*pricing.GetProductsOutput, bool) bool { return false })
+}
+
+// CheckIAMConnectivity requests an instance profile that doesn't exist from IAM. Being told that it doesn't exist
+// shows that IAM can be reached.
+func CheckIAMConnectivity(ctx context.Context, api iamiface.IAMAPI) error {
+	_, err := api.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String("karpenter-connectivity-check")})
+	var aerr awserr.Error
+	if err == nil || (errors.As(err, &aerr) && aerr.Code() == iam.ErrCodeNoSuchEntityException) {
+		return nil
+	}
+	return err
+}
+
+// CheckSQSConnectivity resolves the URL of the interruption queue
+func CheckSQSConnectivity(ctx context.Context, api sqsiface.SQSAPI, queue string) error {
+	_, err := api.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(queue)})
+	return err
+}
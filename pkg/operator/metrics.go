@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	awsSubsystem    = "aws"
+	dependencyLabel = "dependency"
+	serviceLabel    = "service"
+	operationLabel  = "operation"
+)
+
+var (
+	dependencyDegraded = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: awsSubsystem,
+			Name:      "dependency_degraded",
+			Help:      "Whether an optional AWS API that Karpenter depends on couldn't be reached when it was last checked. Labeled by the dependency.",
+		},
+		[]string{dependencyLabel},
+	)
+	operationTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: awsSubsystem,
+			Name:      "operation_timeouts_total",
+			Help:      "Number of AWS API calls that didn't complete within the timeout of their operation. Labeled by the service and operation.",
+		},
+		[]string{serviceLabel, operationLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(dependencyDegraded, operationTimeouts)
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -30,13 +31,17 @@ import (
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/outposts"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -52,14 +57,19 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/metricsexporter"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/placementgroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 )
@@ -73,19 +83,25 @@ func init() {
 type Operator struct {
 	*operator.Operator
 
-	Session                   *session.Session
-	UnavailableOfferingsCache *awscache.UnavailableOfferings
-	EC2API                    ec2iface.EC2API
-	SubnetProvider            subnet.Provider
-	SecurityGroupProvider     securitygroup.Provider
-	InstanceProfileProvider   instanceprofile.Provider
-	AMIProvider               amifamily.Provider
-	AMIResolver               *amifamily.Resolver
-	LaunchTemplateProvider    launchtemplate.Provider
-	PricingProvider           pricing.Provider
-	VersionProvider           version.Provider
-	InstanceTypesProvider     instancetype.Provider
-	InstanceProvider          instance.Provider
+	Session                     *session.Session
+	UnavailableOfferingsCache   *awscache.UnavailableOfferings
+	EC2API                      ec2iface.EC2API
+	SubnetProvider              subnet.Provider
+	SecurityGroupProvider       securitygroup.Provider
+	InstanceProfileProvider     instanceprofile.Provider
+	AMIProvider                 amifamily.Provider
+	AMIResolver                 *amifamily.Resolver
+	LaunchTemplateProvider      launchtemplate.Provider
+	PlacementGroupProvider      placementgroup.Provider
+	CapacityReservationProvider capacityreservation.Provider
+	PricingProvider             pricing.Provider
+	SpotAdvisorProvider         spotadvisor.Provider
+	CostLimitProvider           costlimit.Provider
+	VersionProvider             version.Provider
+	InstanceTypesProvider       instancetype.Provider
+	InstanceProvider            instance.Provider
+	MetricsExporter             *metricsexporter.Exporter
+	Dependencies                *Dependencies
 }
 
 func NewOperator(ctx context.Context, operator *operator.Operator) (context.Context, *Operator) {
@@ -98,21 +114,27 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 			func(provider *stscreds.AssumeRoleProvider) { SetDurationAndExpiry(ctx, provider) })
 	}
 
-	sess := WithUserAgent(session.Must(session.NewSession(
+	sess := WithOperationTimeouts(ctx, WithUserAgent(session.Must(session.NewSession(
 		request.WithRetryer(
 			config,
 			awsclient.DefaultRetryer{NumMaxRetries: awsclient.DefaultRetryerMaxNumRetries},
 		),
-	)))
+	))))
 
 	if *sess.Config.Region == "" {
 		logging.FromContext(ctx).Debug("retrieving region from IMDS")
 		region, err := ec2metadata.New(sess).Region()
-		*sess.Config.Region = lo.Must(region, err, "failed to get region from metadata server")
+		if err != nil {
+			logging.FromContext(ctx).Fatalf("unable to retrieve the region from IMDS, set AWS_REGION if IMDS isn't reachable, %s", err)
+		}
+		*sess.Config.Region = region
 	}
 	ec2api := ec2.New(sess)
-	if err := CheckEC2Connectivity(ctx, ec2api); err != nil {
-		logging.FromContext(ctx).Fatalf("Checking EC2 API connectivity, %s", err)
+	iamapi := iam.New(sess)
+	pricingAPI := pricing.NewAPI(sess, *sess.Config.Region)
+	dependencies := NewDependencies(StartupDependencies(ctx, ec2api, pricingAPI, pricing.APIAvailable(*sess.Config.Region), iamapi, sqs.New(sess))...)
+	if err := dependencies.Check(ctx); err != nil {
+		logging.FromContext(ctx).Fatalf("%s", err)
 	}
 	logging.FromContext(ctx).With("region", *sess.Config.Region).Debugf("discovered region")
 	clusterEndpoint, err := ResolveClusterEndpoint(ctx, eks.New(sess))
@@ -131,19 +153,39 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		logging.FromContext(ctx).With("kube-dns-ip", kubeDNSIP).Debugf("discovered kube dns")
 	}
 
+	logging.FromContext(ctx).With("labels", instancetype.EmittedLabels(ctx)).Infof("labeling instance types")
+	// Templated node names don't match the system:node:{{EC2PrivateDNSName}} username that EKS maps the node role to,
+	// so the NodeRestriction admission plugin rejects the kubelet's node unless the mapping is changed
+	if opts := options.FromContext(ctx); opts.NodeNameConvention == options.NodeNameConventionTemplate {
+		if opts.NodeNamedByInstanceID() {
+			logging.FromContext(ctx).Warnf("nodes are named after their instance ID, nodes can't register unless the node role is mapped to the system:node:{{SessionName}} username in the aws-auth ConfigMap")
+		} else {
+			logging.FromContext(ctx).With("node-name-template", opts.NodeNameTemplate).Warnf("nodes are named from a template that doesn't render the instance ID alone, nodes can't register while the NodeRestriction admission plugin is enabled")
+		}
+	}
+
 	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
 	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
-	instanceProfileProvider := instanceprofile.NewDefaultProvider(*sess.Config.Region, iam.New(sess), cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
+	placementGroupProvider := placementgroup.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	capacityReservationProvider := capacityreservation.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	instanceProfileProvider := instanceprofile.NewDefaultProvider(*sess.Config.Region, iamapi, cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
 	pricingProvider := pricing.NewDefaultProvider(
 		ctx,
-		pricing.NewAPI(sess, *sess.Config.Region),
+		pricingAPI,
 		ec2api,
 		*sess.Config.Region,
+		operator.Clock,
 	)
+	spotAdvisorProvider := spotadvisor.NewDefaultProvider(&http.Client{Timeout: time.Minute}, spotadvisor.DataURL)
+	costLimitProvider := costlimit.NewDefaultProvider(pricingProvider, operator.Clock)
 	versionProvider := version.NewDefaultProvider(operator.KubernetesInterface, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	amiProvider := amifamily.NewDefaultProvider(versionProvider, ssm.New(sess), ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	amiResolver := amifamily.NewResolver(amiProvider)
+	caBundle, err := GetCABundle(ctx, operator.GetConfig())
+	if err != nil {
+		logging.FromContext(ctx).Fatalf("unable to discover the cluster CA bundle, %s", err)
+	}
 	launchTemplateProvider := launchtemplate.NewDefaultProvider(
 		ctx,
 		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
@@ -152,7 +194,7 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		amiResolver,
 		securityGroupProvider,
 		subnetProvider,
-		lo.Must(GetCABundle(ctx, operator.GetConfig())),
+		caBundle,
 		operator.Elected(),
 		kubeDNSIP,
 		clusterEndpoint,
@@ -161,10 +203,16 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		*sess.Config.Region,
 		cache.New(awscache.InstanceTypesAndZonesTTL, awscache.DefaultCleanupInterval),
 		ec2api,
+		outposts.New(sess),
 		subnetProvider,
 		unavailableOfferingsCache,
 		pricingProvider,
 	)
+	// The exporter is left nil when publishing to CloudWatch is disabled, which makes recording metrics to it a no-op
+	var metricsExporter *metricsexporter.Exporter
+	if options.FromContext(ctx).CloudWatchMetricsNamespace != "" {
+		metricsExporter = metricsexporter.NewExporter(cloudwatch.New(sess), operator.Clock, unavailableOfferingsCache)
+	}
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
 		aws.StringValue(sess.Config.Region),
@@ -173,23 +221,39 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		instanceTypeProvider,
 		subnetProvider,
 		launchTemplateProvider,
+		placementGroupProvider,
+		spotAdvisorProvider,
+		costLimitProvider,
+		cache.New(awscache.InflightLaunchTTL, awscache.DefaultCleanupInterval),
+		cache.New(awscache.LaunchAttemptTTL, awscache.DefaultCleanupInterval),
+		cache.New(awscache.SpotFallbackZoneTTL, awscache.DefaultCleanupInterval),
+		cache.New(awscache.InstanceDescriptionTTL, awscache.DefaultCleanupInterval),
+		operator.Clock,
+		operator.EventRecorder,
+		metricsExporter,
 	)
 
 	return ctx, &Operator{
-		Operator:                  operator,
-		Session:                   sess,
-		UnavailableOfferingsCache: unavailableOfferingsCache,
-		EC2API:                    ec2api,
-		SubnetProvider:            subnetProvider,
-		SecurityGroupProvider:     securityGroupProvider,
-		InstanceProfileProvider:   instanceProfileProvider,
-		AMIProvider:               amiProvider,
-		AMIResolver:               amiResolver,
-		VersionProvider:           versionProvider,
-		LaunchTemplateProvider:    launchTemplateProvider,
-		PricingProvider:           pricingProvider,
-		InstanceTypesProvider:     instanceTypeProvider,
-		InstanceProvider:          instanceProvider,
+		Operator:                    operator,
+		Session:                     sess,
+		UnavailableOfferingsCache:   unavailableOfferingsCache,
+		EC2API:                      ec2api,
+		SubnetProvider:              subnetProvider,
+		SecurityGroupProvider:       securityGroupProvider,
+		InstanceProfileProvider:     instanceProfileProvider,
+		AMIProvider:                 amiProvider,
+		AMIResolver:                 amiResolver,
+		VersionProvider:             versionProvider,
+		LaunchTemplateProvider:      launchTemplateProvider,
+		PlacementGroupProvider:      placementGroupProvider,
+		CapacityReservationProvider: capacityReservationProvider,
+		PricingProvider:             pricingProvider,
+		SpotAdvisorProvider:         spotAdvisorProvider,
+		CostLimitProvider:           costLimitProvider,
+		InstanceTypesProvider:       instanceTypeProvider,
+		InstanceProvider:            instanceProvider,
+		MetricsExporter:             metricsExporter,
+		Dependencies:                dependencies,
 	}
 }
 
@@ -200,6 +264,29 @@ func WithUserAgent(sess *session.Session) *session.Session {
 	return sess
 }
 
+// WithOperationTimeouts bounds every AWS API call made through the session, including its retries, by the timeout of
+// its operation so that a degraded API fails the call instead of blocking the controller making it. Calls that time out
+// fail with a RequestCanceled error, which callers already treat as retryable.
+func WithOperationTimeouts(ctx context.Context, sess *session.Session) *session.Session {
+	sess.Handlers.Validate.PushFront(func(r *request.Request) {
+		timeout := options.FromContext(ctx).AWSOperationTimeout(r.Operation.Name)
+		if timeout <= 0 {
+			return
+		}
+		parent := r.Context()
+		timeoutCtx, cancel := context.WithTimeout(parent, timeout)
+		r.SetContext(timeoutCtx)
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			// Only count calls cancelled by their own deadline rather than by the caller
+			if r.Error != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) && parent.Err() == nil {
+				operationTimeouts.With(prometheus.Labels{serviceLabel: r.ClientInfo.ServiceName, operationLabel: r.Operation.Name}).Inc()
+			}
+			cancel()
+		})
+	})
+	return sess
+}
+
 // CheckEC2Connectivity makes a dry-run call to DescribeInstanceTypes.  If it fails, we provide an early indicator that we
 // are having issues connecting to the EC2 API.
 func CheckEC2Connectivity(ctx context.Context, api ec2iface.EC2API) error {
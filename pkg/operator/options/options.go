@@ -20,8 +20,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/utils/env"
 )
@@ -32,16 +37,134 @@ func init() {
 
 type optionsKey struct{}
 
+const (
+	NodeNameConventionPrivateDNS   = "private-dns"
+	NodeNameConventionResourceName = "resource-name"
+	NodeNameConventionTemplate     = "template"
+)
+
+const (
+	InterruptionRebalanceActionIgnore         = "Ignore"
+	InterruptionRebalanceActionCordon         = "Cordon"
+	InterruptionRebalanceActionCordonAndDrain = "CordonAndDrain"
+)
+
+// NodeNameTemplateData is the data that node-name-template is rendered with
+type NodeNameTemplateData struct {
+	ClusterName string
+	NodePool    string
+	InstanceID  string
+}
+
+// NodeLabelTemplateData is the data that the values of extra-node-labels are rendered with
+type NodeLabelTemplateData struct {
+	InstanceID   string
+	Region       string
+	ZoneID       string
+	InstanceType string
+}
+
+// RenderNodeLabel renders an extra-node-labels value for an instance
+func RenderNodeLabel(value string, data NodeLabelTemplateData) (string, error) {
+	tmpl, err := template.New("node-label").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// NodeNamedByInstanceID returns whether node-name-template names nodes after their instance ID alone. The kubelet
+// authenticates with a role session named after the instance ID, so these are the only templated names that the node
+// role can be mapped to with system:node:{{SessionName}}. No mapping can authorize any other templated name.
+func (o Options) NodeNamedByInstanceID() bool {
+	tmpl, err := template.New("node-name").Parse(o.NodeNameTemplate)
+	if err != nil {
+		return false
+	}
+	data := NodeNameTemplateData{ClusterName: o.ClusterName, NodePool: "nodepool", InstanceID: "i-0123456789abcdef0"}
+	var name strings.Builder
+	if err := tmpl.Execute(&name, data); err != nil {
+		return false
+	}
+	return name.String() == data.InstanceID
+}
+
 type Options struct {
-	AssumeRoleARN           string
-	AssumeRoleDuration      time.Duration
-	ClusterCABundle         string
-	ClusterName             string
-	ClusterEndpoint         string
-	IsolatedVPC             bool
-	VMMemoryOverheadPercent float64
-	InterruptionQueue       string
-	ReservedENIs            int
+	AssumeRoleARN                      string
+	AssumeRoleDuration                 time.Duration
+	ClusterCABundle                    string
+	ClusterName                        string
+	ClusterEndpoint                    string
+	ClusterAutoscalerCompatibility     bool
+	IsolatedVPC                        bool
+	PricingOverrideFile                string
+	SpotPriceTTL                       time.Duration
+	SpotPriceRefreshInterval           time.Duration
+	SpotPriceMaxStaleness              time.Duration
+	VMMemoryOverheadPercent            float64
+	GravitonCMAReservedMemoryMiB       int
+	InstanceStoreKubeReservedStorage   string
+	InstanceStoreEvictionPercent       float64
+	InterruptionQueue                  string
+	ReservedENIs                       int
+	ENIPrefixDelegation                bool
+	SnapshotGC                         bool
+	SnapshotGCRetention                time.Duration
+	SnapshotGCDryRun                   bool
+	OnDemandAllocationStrategy         string
+	TaintTags                          bool
+	InstanceTypeAllowlist              []string
+	InstanceTypeDenylist               []string
+	MinLaunchInstanceTypes             int
+	RaiseUndersizedRootVolumes         bool
+	NetworkBandwidthResource           bool
+	NodeClassStatusAWSQPS              float64
+	NodeClassStatusAWSBurst            int
+	SubnetFreeIPThreshold              int
+	NodeNameConvention                 string
+	NodeNameTemplate                   string
+	SubnetClusterTagging               bool
+	SubnetClusterTaggingDryRun         bool
+	InstanceTypeMaxStaleness           time.Duration
+	InstanceTypeCacheMaxKeys           int
+	AMIDefaultOwners                   []string
+	AllowedAMIOwners                   []string
+	AMIDeprecationWindow               time.Duration
+	RebalanceRecommendations           bool
+	InterruptionRebalanceAction        string
+	InterruptionQueueWaitTime          time.Duration
+	InterruptionQueueParallelism       int
+	InterruptionQueueMaxParseAttempts  int
+	InterruptionQueueRequired          bool
+	SpotInterruptionPenalty            float64
+	SpotUnfulfillableCapacityTTL       time.Duration
+	OnDemandInsufficientCapacityTTL    time.Duration
+	ReservationCapacityExceededTTL     time.Duration
+	RequirePrivateDNSName              bool
+	ExtraNodeLabels                    map[string]string
+	EnabledLabels                      []string
+	DisabledLabels                     []string
+	ForceInstanceProfileRevalidation   bool
+	InstanceProfilePath                string
+	InstanceProfilePermissionsBoundary string
+	SharedInstanceProfiles             bool
+	LaunchTemplateGCGracePeriod        time.Duration
+	HourlyCostLimitFailOpen            bool
+	AWSOperationTimeouts               map[string]time.Duration
+	CloudWatchMetricsNamespace         string
+
+	instanceTypeAllowlistRaw string
+	instanceTypeDenylistRaw  string
+	amiDefaultOwnersRaw      string
+	allowedAMIOwnersRaw      string
+	extraNodeLabelsRaw       string
+	enabledLabelsRaw         string
+	disabledLabelsRaw        string
+	awsOperationTimeoutsRaw  string
 }
 
 func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
@@ -50,10 +173,63 @@ func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
 	fs.StringVar(&o.ClusterCABundle, "cluster-ca-bundle", env.WithDefaultString("CLUSTER_CA_BUNDLE", ""), "Cluster CA bundle for nodes to use for TLS connections with the API server. If not set, this is taken from the controller's TLS configuration.")
 	fs.StringVar(&o.ClusterName, "cluster-name", env.WithDefaultString("CLUSTER_NAME", ""), "[REQUIRED] The kubernetes cluster name for resource discovery.")
 	fs.StringVar(&o.ClusterEndpoint, "cluster-endpoint", env.WithDefaultString("CLUSTER_ENDPOINT", ""), "The external kubernetes cluster endpoint for new nodes to connect with. If not specified, will discover the cluster endpoint using DescribeCluster API.")
+	fs.BoolVarWithEnv(&o.ClusterAutoscalerCompatibility, "cluster-autoscaler-compatibility", "CLUSTER_AUTOSCALER_COMPATIBILITY", false, "If true, mirror the karpenter.sh/do-not-disrupt annotation of nodes launched by Karpenter to and from cluster-autoscaler's cluster-autoscaler.kubernetes.io/scale-down-disabled annotation, for tooling that only understands cluster-autoscaler's annotations. Intended for the window of a migration from cluster-autoscaler.")
 	fs.BoolVarWithEnv(&o.IsolatedVPC, "isolated-vpc", "ISOLATED_VPC", false, "If true, then assume we can't reach AWS services which don't have a VPC endpoint. This also has the effect of disabling look-ups to the AWS on-demand pricing endpoint.")
+	fs.StringVar(&o.PricingOverrideFile, "pricing-override-file", env.WithDefaultString("PRICING_OVERRIDE_FILE", ""), "Path to a JSON file of hourly prices, such as a mounted ConfigMap. The file maps instance types to their on-demand price, or has an onDemand map of instance types to their price and a spot map of instance types to their price by zone. Prices in the file are used as-is in place of the prices from the AWS pricing API or the static price list, and the file is re-read every minute, so an edit to a mounted ConfigMap takes effect within the kubelet's ConfigMap sync period plus a minute.")
+	fs.DurationVar(&o.SpotPriceTTL, "spot-price-ttl", env.WithDefaultDuration("SPOT_PRICE_TTL", 24*time.Hour), "How long the spot price of an instance type in a zone is kept after EC2 last reported it. Spot prices are updated every spot-price-refresh-interval, and EC2 reports the price of every offering that is still available on each update. Disabled if set to 0.")
+	fs.DurationVar(&o.SpotPriceRefreshInterval, "spot-price-refresh-interval", env.WithDefaultDuration("SPOT_PRICE_REFRESH_INTERVAL", 12*time.Hour), "How often spot prices are updated from EC2. Each update only requests the prices that changed since the previous one. Must be at least 1m.")
+	fs.DurationVar(&o.SpotPriceMaxStaleness, "spot-price-max-staleness", env.WithDefaultDuration("SPOT_PRICE_MAX_STALENESS", 0), "How long after the last successful spot price update the liveness probe fails, restarting the controller. Disabled if set to 0.")
 	fs.Float64Var(&o.VMMemoryOverheadPercent, "vm-memory-overhead-percent", env.WithDefaultFloat64("VM_MEMORY_OVERHEAD_PERCENT", 0.075), "The VM memory overhead as a percent that will be subtracted from the total memory for all instance types.")
+	fs.IntVar(&o.GravitonCMAReservedMemoryMiB, "graviton-cma-reserved-memory-mib", env.WithDefaultInt("GRAVITON_CMA_RESERVED_MEMORY_MIB", 64), "The memory in MiB that Graviton instance types reserve for the contiguous memory allocator, which is subtracted from their total memory. Only applies to arm64 instance types with an AWS designed processor.")
+	fs.StringVar(&o.InstanceStoreKubeReservedStorage, "instance-store-kube-reserved-ephemeral-storage", env.WithDefaultString("INSTANCE_STORE_KUBE_RESERVED_EPHEMERAL_STORAGE", "5Gi"), "The default kube-reserved ephemeral-storage of instance types whose ephemeral storage is on instance store, which is the case when an EC2NodeClass sets an instanceStorePolicy. Instance types with ephemeral storage on EBS reserve 1Gi. An ephemeral-storage kube-reserved in the kubelet configuration takes precedence.")
+	fs.Float64Var(&o.InstanceStoreEvictionPercent, "instance-store-eviction-threshold-percent", env.WithDefaultFloat64("INSTANCE_STORE_EVICTION_THRESHOLD_PERCENT", 5), "The default nodefs.available eviction threshold, as a percent of the instance store size, of instance types whose ephemeral storage is on instance store, which is the case when an EC2NodeClass sets an instanceStorePolicy. Instance types with ephemeral storage on EBS use 10% of the volume size. A nodefs.available eviction threshold in the kubelet configuration takes precedence.")
 	fs.StringVar(&o.InterruptionQueue, "interruption-queue", env.WithDefaultString("INTERRUPTION_QUEUE", ""), "Interruption queue is disabled if not specified. Enabling interruption handling may require additional permissions on the controller service account. Additional permissions are outlined in the docs.")
 	fs.IntVar(&o.ReservedENIs, "reserved-enis", env.WithDefaultInt("RESERVED_ENIS", 0), "Reserved ENIs are not included in the calculations for max-pods or kube-reserved. This is most often used in the VPC CNI custom networking setup https://docs.aws.amazon.com/eks/latest/userguide/cni-custom-network.html.")
+	fs.BoolVarWithEnv(&o.ENIPrefixDelegation, "eni-prefix-delegation", "ENI_PREFIX_DELEGATION", false, "If true, assume the VPC CNI assigns /28 IPv4 prefixes to ENIs rather than individual secondary IPs when calculating max-pods and kube-reserved. Enable this when ENABLE_PREFIX_DELEGATION is set on the VPC CNI https://docs.aws.amazon.com/eks/latest/userguide/cni-increase-ip-addresses.html.")
+	fs.BoolVarWithEnv(&o.SnapshotGC, "snapshot-gc", "SNAPSHOT_GC", false, "If true, garbage collect EBS snapshots tagged with the cluster and the karpenter.k8s.aws/created-by marker once they are older than the snapshot-gc-retention period. Karpenter doesn't create snapshots itself, so tooling that creates snapshots for the cluster, such as diagnostic captures or AMI builds, must apply the marker for them to be collected. Snapshots backing an AMI referenced by an EC2NodeClass are never deleted.")
+	fs.DurationVar(&o.SnapshotGCRetention, "snapshot-gc-retention", env.WithDefaultDuration("SNAPSHOT_GC_RETENTION", 7*24*time.Hour), "The minimum age of a Karpenter-created EBS snapshot before it is eligible for garbage collection. Not used unless snapshot-gc is set.")
+	fs.BoolVarWithEnv(&o.SnapshotGCDryRun, "snapshot-gc-dry-run", "SNAPSHOT_GC_DRY_RUN", false, "If true, snapshot garbage collection only reports the snapshots it would delete without deleting them.")
+	fs.StringVar(&o.OnDemandAllocationStrategy, "on-demand-allocation-strategy", env.WithDefaultString("ON_DEMAND_ALLOCATION_STRATEGY", ec2.FleetOnDemandAllocationStrategyLowestPrice), "The allocation strategy used for on-demand fleet requests. Can be one of 'lowest-price' or 'prioritized'. When set to 'prioritized', instance types are prioritized from cheapest to most expensive.")
+	fs.BoolVarWithEnv(&o.TaintTags, "taint-tags", "TAINT_TAGS", false, "If true, serialize the NodeClaim's taints and startup taints into the karpenter.sh/taints instance tag at launch so that they can be read from the host before the node registers.")
+	fs.StringVar(&o.instanceTypeAllowlistRaw, "instance-type-allowlist", env.WithDefaultString("INSTANCE_TYPE_ALLOWLIST", ""), "Comma separated list of instance type globs (e.g. 'm5.*,c5.large') that Karpenter is allowed to launch. If not set, all instance types are allowed.")
+	fs.StringVar(&o.instanceTypeDenylistRaw, "instance-type-denylist", env.WithDefaultString("INSTANCE_TYPE_DENYLIST", ""), "Comma separated list of instance type globs (e.g. 'p5.*,*.metal') that Karpenter will never launch, regardless of NodePool requirements. Takes precedence over instance-type-allowlist.")
+	fs.IntVar(&o.MinLaunchInstanceTypes, "min-launch-instance-types", env.WithDefaultInt("MIN_LAUNCH_INSTANCE_TYPES", 0), "If greater than zero, a warning event is published on the NodeClaim when fewer instance types than this remain launchable after filtering. Disabled if set to 0.")
+	fs.BoolVarWithEnv(&o.RaiseUndersizedRootVolumes, "raise-undersized-root-volumes", "RAISE_UNDERSIZED_ROOT_VOLUMES", false, "If true, root volumes in an EC2NodeClass's block device mappings that are smaller than the root snapshot of a resolved AMI are raised to the snapshot size at launch instead of failing the launch.")
+	fs.BoolVarWithEnv(&o.NetworkBandwidthResource, "network-bandwidth-resource", "NETWORK_BANDWIDTH_RESOURCE", false, "If true, instance types report their network bandwidth in megabits per second as the karpenter.k8s.aws/network-bandwidth capacity resource so that pods can request it. The kubelet doesn't advertise this resource, so nodes only initialize with pods requesting it once it is added to the node's capacity.")
+	fs.Float64Var(&o.NodeClassStatusAWSQPS, "nodeclass-status-aws-qps", env.WithDefaultFloat64("NODECLASS_STATUS_AWS_QPS", 20), "The maximum rate, in reconciles per second, at which the EC2NodeClass status controller resolves AMIs, subnets, security groups, instance profiles and the cluster CIDR from AWS. The rate is shared evenly between them. Disabled if set to 0.")
+	fs.IntVar(&o.NodeClassStatusAWSBurst, "nodeclass-status-aws-burst", env.WithDefaultInt("NODECLASS_STATUS_AWS_BURST", 100), "The maximum burst of EC2NodeClass status reconciles that resolve resources from AWS. The burst is shared evenly in the same way as nodeclass-status-aws-qps.")
+	fs.IntVar(&o.SubnetFreeIPThreshold, "subnet-free-ip-threshold", env.WithDefaultInt("SUBNET_FREE_IP_THRESHOLD", 0), "If greater than zero, an EC2NodeClass's SubnetsReady status condition is set to false when every subnet it selects has fewer free IP addresses than this. Disabled if set to 0.")
+	fs.StringVar(&o.NodeNameConvention, "node-name-convention", env.WithDefaultString("NODE_NAME_CONVENTION", NodeNameConventionPrivateDNS), "How nodes are named when they register. Can be one of 'private-dns', 'resource-name' or 'template'. 'private-dns' uses the instance's private DNS name, 'resource-name' launches instances with EC2 resource-based hostnames, and 'template' renders node-name-template on the instance at boot.")
+	fs.StringVar(&o.NodeNameTemplate, "node-name-template", env.WithDefaultString("NODE_NAME_TEMPLATE", "{{ .ClusterName }}-{{ .NodePool }}-{{ .InstanceID }}"), "The Go template that node names are rendered from when node-name-convention is 'template'. The template can reference .ClusterName, .NodePool and .InstanceID, and must reference .InstanceID so that node names can't collide. Nodes can only register under the NodeRestriction admission plugin when the template renders .InstanceID alone and the node role is mapped to the system:node:{{SessionName}} username in the aws-auth ConfigMap.")
+	fs.BoolVarWithEnv(&o.SubnetClusterTagging, "subnet-cluster-tagging", "SUBNET_CLUSTER_TAGGING", false, "If true, subnets selected by an EC2NodeClass that lack the kubernetes.io/cluster/<cluster-name> tag are tagged with the value 'shared'. Existing cluster tags are never changed or removed. Requires ec2:CreateTags on the selected subnets.")
+	fs.BoolVarWithEnv(&o.SubnetClusterTaggingDryRun, "subnet-cluster-tagging-dry-run", "SUBNET_CLUSTER_TAGGING_DRY_RUN", false, "If true, subnet cluster tagging only reports the subnets it would tag in the EC2NodeClass status without tagging them.")
+	fs.DurationVar(&o.InstanceTypeMaxStaleness, "instance-type-max-staleness", env.WithDefaultDuration("INSTANCE_TYPE_MAX_STALENESS", 6*time.Hour), "How long instance types and offerings from the last successful EC2 refresh are served when refreshing them fails. Once exceeded, the refresh errors are returned. Disabled if set to 0.")
+	fs.IntVar(&o.InstanceTypeCacheMaxKeys, "instance-type-cache-max-keys", env.WithDefaultInt("INSTANCE_TYPE_CACHE_MAX_KEYS", 20), "The maximum number of fully initialized instance type sets that are cached. Each distinct kubelet configuration and EC2NodeClass needs its own set, and the least recently used are recomputed on their next use once this is exceeded. A warning is logged once more than half of it is in use.")
+	fs.StringVar(&o.amiDefaultOwnersRaw, "ami-default-owners", env.WithDefaultString("AMI_DEFAULT_OWNERS", "self,amazon"), "Comma separated list of AMI owners (account IDs, 'self', 'amazon' or 'aws-marketplace') that AMI selector terms with a name or tags but no owner are restricted to. Terms that set an owner aren't affected.")
+	fs.StringVar(&o.allowedAMIOwnersRaw, "allowed-ami-owners", env.WithDefaultString("ALLOWED_AMI_OWNERS", ""), "Comma separated list of account IDs that resolved AMIs must be owned by. AMIs owned by any other account are dropped, however they were selected, including the default AMIs of an AMI family. If not set, AMIs of any owner are allowed.")
+	fs.DurationVar(&o.AMIDeprecationWindow, "ami-deprecation-window", env.WithDefaultDuration("AMI_DEPRECATION_WINDOW", 14*24*time.Hour), "How long before the deprecation time of an AMI in an EC2NodeClass's status that the EC2NodeClass reports it through the AMIsDeprecating condition. AMIs that are already deprecated are always reported. If set to 0, only AMIs that are already deprecated are reported.")
+	fs.BoolVarWithEnv(&o.RebalanceRecommendations, "rebalance-recommendations", "REBALANCE_RECOMMENDATIONS", false, "Deprecated, use interruption-rebalance-action=CordonAndDrain instead. If true, NodeClaims are cordoned, drained and deleted when their spot instance receives an EC2 rebalance recommendation, ahead of the spot interruption notice. Not used unless interruption-queue is set.")
+	fs.StringVar(&o.InterruptionRebalanceAction, "interruption-rebalance-action", env.WithDefaultString("INTERRUPTION_REBALANCE_ACTION", InterruptionRebalanceActionIgnore), "How NodeClaims are handled when their spot instance receives an EC2 rebalance recommendation. Can be one of 'Ignore', 'Cordon' or 'CordonAndDrain'. 'Cordon' cordons the node, and 'CordonAndDrain' cordons, drains and deletes the NodeClaim ahead of the spot interruption notice. Both keep the instance's offering from being launched for a minute. Not used unless interruption-queue is set.")
+	fs.BoolVarWithEnv(&o.InterruptionQueueRequired, "interruption-queue-required", "INTERRUPTION_QUEUE_REQUIRED", false, "If true, EC2NodeClasses used by a NodePool that allows spot aren't ready while the interruption queue isn't set or can't be reached. Otherwise, this is only reported by the InterruptionQueueUnhealthy condition of the EC2NodeClass and a warning event.")
+	fs.DurationVar(&o.InterruptionQueueWaitTime, "interruption-queue-wait-time", env.WithDefaultDuration("INTERRUPTION_QUEUE_WAIT_TIME", 20*time.Second), "How long each receive from the interruption queue long polls for messages before returning empty. Must be between 0 and 20 seconds, rounded down to a whole second. Not used unless interruption-queue is set.")
+	fs.IntVar(&o.InterruptionQueueParallelism, "interruption-queue-parallelism", env.WithDefaultInt("INTERRUPTION_QUEUE_PARALLELISM", 10), "The maximum number of messages from a single receive that are handled concurrently. Not used unless interruption-queue is set.")
+	fs.IntVar(&o.InterruptionQueueMaxParseAttempts, "interruption-queue-max-parse-attempts", env.WithDefaultInt("INTERRUPTION_QUEUE_MAX_PARSE_ATTEMPTS", 3), "The number of times a message from the interruption queue that can't be parsed is received before it is logged and deleted. Until then, the message is left on the queue to be received again after its visibility timeout. Not used unless interruption-queue is set.")
+	fs.Float64Var(&o.SpotInterruptionPenalty, "spot-interruption-penalty", env.WithDefaultFloat64("SPOT_INTERRUPTION_PENALTY", 0), "If greater than zero, spot instance types are ordered by their price scaled up by this multiple of their interruption frequency from the EC2 Spot Instance Advisor, and spot instances are launched with the 'capacity-optimized-prioritized' allocation strategy in that order. For example, 1 doubles the price of an instance type interrupted 100% of the time. Disabled if set to 0.")
+	fs.DurationVar(&o.SpotUnfulfillableCapacityTTL, "spot-unfulfillable-capacity-ttl", env.WithDefaultDuration("SPOT_UNFULFILLABLE_CAPACITY_TTL", 3*time.Minute), "How long a spot offering is not launched after EC2 reports it has insufficient capacity. The time doubles, up to an hour, each time the offering fails again within 10 minutes of becoming available.")
+	fs.DurationVar(&o.OnDemandInsufficientCapacityTTL, "on-demand-insufficient-capacity-ttl", env.WithDefaultDuration("ON_DEMAND_INSUFFICIENT_CAPACITY_TTL", 15*time.Minute), "How long an on-demand offering is not launched after EC2 reports it has insufficient capacity. The time doubles, up to an hour, each time the offering fails again within 10 minutes of becoming available.")
+	fs.DurationVar(&o.ReservationCapacityExceededTTL, "reservation-capacity-exceeded-ttl", env.WithDefaultDuration("RESERVATION_CAPACITY_EXCEEDED_TTL", time.Minute), "How long an offering is not launched after EC2 reports that its capacity reservation is exhausted. The time doubles, up to an hour, each time the offering fails again within 10 minutes of becoming available.")
+	fs.BoolVarWithEnv(&o.RequirePrivateDNSName, "require-private-dns-name", "REQUIRE_PRIVATE_DNS_NAME", true, "If true, a warning is logged when Karpenter gets an instance that has no private DNS name. The warning names the enableDnsHostnames attribute of the instance's VPC. Instances without a private DNS name are used either way. Set to false for clusters whose node names don't depend on the private DNS name. Can only be false when node-name-convention is 'resource-name' or 'template'.")
+	fs.StringVar(&o.extraNodeLabelsRaw, "extra-node-labels", env.WithDefaultString("EXTRA_NODE_LABELS", ""), "Comma separated list of labels (e.g. 'myorg.io/asset-id={{ .Region }}.{{ .InstanceID }}') added to every NodeClaim when its instance is launched, so that they propagate to the node. Values are Go templates that can reference .InstanceID, .Region, .ZoneID and .InstanceType, and labels whose rendered value isn't a valid label value are left out.")
+	fs.StringVar(&o.enabledLabelsRaw, "enabled-labels", env.WithDefaultString("ENABLED_LABELS", ""), "Comma separated list of gated karpenter.k8s.aws labels that nodes are labeled with. New labels are gated until they've been released for two minor versions, since strict admission policies and older core versions can reject NodeClaims with requirement keys they don't recognize.")
+	fs.StringVar(&o.disabledLabelsRaw, "disabled-labels", env.WithDefaultString("DISABLED_LABELS", ""), "Comma separated list of karpenter.k8s.aws labels that nodes are not labeled with, even if they're emitted by default. NodePools can still select instance types by a disabled label.")
+	fs.StringVar(&o.InstanceProfilePath, "instance-profile-path", env.WithDefaultString("INSTANCE_PROFILE_PATH", "/"), "The IAM path that instance profiles for EC2NodeClasses with spec.role are created with. The path of an instance profile can't be changed after it's created, so instance profiles with a different path are reported on their EC2NodeClass's InstanceProfileMismatch condition rather than being changed.")
+	fs.StringVar(&o.InstanceProfilePermissionsBoundary, "instance-profile-permissions-boundary", env.WithDefaultString("INSTANCE_PROFILE_PERMISSIONS_BOUNDARY", ""), "The ARN of the IAM policy that the roles of EC2NodeClasses with spec.role must have as their permissions boundary. IAM only attaches permissions boundaries to roles, which Karpenter doesn't manage, so roles without it are reported on their EC2NodeClass's InstanceProfileMismatch condition. Requires iam:GetRole. Disabled if not set.")
+	fs.BoolVarWithEnv(&o.SharedInstanceProfiles, "shared-instance-profiles", "SHARED_INSTANCE_PROFILES", false, "If true, EC2NodeClasses with spec.role share a single instance profile per role rather than each having their own. A shared instance profile is deleted once no EC2NodeClass references its role and no NodeClaim was launched with it, including when an EC2NodeClass changes from its role to another, and isn't tagged with the tags of any EC2NodeClass. Its karpenter.k8s.aws/ec2nodeclass tag is valued role/<role>. Instance profiles created for EC2NodeClasses before this is enabled are deleted with their EC2NodeClass.")
+	fs.DurationVar(&o.LaunchTemplateGCGracePeriod, "launch-template-gc-grace-period", env.WithDefaultDuration("LAUNCH_TEMPLATE_GC_GRACE_PERIOD", time.Hour), "How long a launch template tagged with the cluster must go without a matching EC2NodeClass before it's garbage collected. Launch templates that were used to launch instances within this period are kept. Garbage collection is disabled if set to 0.")
+	fs.BoolVarWithEnv(&o.HourlyCostLimitFailOpen, "hourly-cost-limit-fail-open", "HOURLY_COST_LIMIT_FAIL_OPEN", true, "If true, launches for NodePools with the karpenter.k8s.aws/limit-hourly-cost annotation are allowed, with a warning, when their hourly cost can't be trusted because it hasn't been computed in the last 5 minutes or prices have missed an update, which is twice spot-price-refresh-interval for spot prices and 24 hours for on-demand prices. Otherwise, these launches are rejected. Static on-demand prices, in isolated VPCs and partitions without the pricing API, are always trusted.")
+	fs.StringVar(&o.awsOperationTimeoutsRaw, "aws-operation-timeouts", env.WithDefaultString("AWS_OPERATION_TIMEOUTS", ""), "Comma separated list of AWS API operations and how long calls to them may take, including retries (e.g. 'DescribeImages=10s,CreateFleet=3m'), overriding the defaults of 30s for Describe, Get and List operations, 2m for CreateFleet and 1m for every other operation. A timeout of 0 disables the deadline of the operation.")
+	fs.StringVar(&o.CloudWatchMetricsNamespace, "cloudwatch-metrics-namespace", env.WithDefaultString("CLOUDWATCH_METRICS_NAMESPACE", ""), "The CloudWatch namespace that instance launch latency, CreateFleet errors by error code and the number of unavailable offerings are published to every minute, in addition to the Prometheus metrics. Requires cloudwatch:PutMetricData. Disabled if not set.")
+	fs.BoolVarWithEnv(&o.ForceInstanceProfileRevalidation, "force-instance-profile-revalidation", "FORCE_INSTANCE_PROFILE_REVALIDATION", false, "If true, the instance profiles that Karpenter manages for EC2NodeClasses with spec.role are read from IAM on every EC2NodeClass status reconcile, and roles or tags changed outside of Karpenter are corrected. Otherwise, they're only read again once their cached state expires after 15 minutes.")
 }
 
 func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
@@ -63,6 +239,22 @@ func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
 		}
 		return fmt.Errorf("parsing flags, %w", err)
 	}
+	o.InstanceTypeAllowlist = splitList(o.instanceTypeAllowlistRaw)
+	o.InstanceTypeDenylist = splitList(o.instanceTypeDenylistRaw)
+	o.AMIDefaultOwners = splitList(o.amiDefaultOwnersRaw)
+	o.AllowedAMIOwners = splitList(o.allowedAMIOwnersRaw)
+	o.EnabledLabels = splitList(o.enabledLabelsRaw)
+	o.DisabledLabels = splitList(o.disabledLabelsRaw)
+	extraNodeLabels, err := splitLabels(o.extraNodeLabelsRaw)
+	if err != nil {
+		return fmt.Errorf("parsing extra-node-labels, %w", err)
+	}
+	o.ExtraNodeLabels = extraNodeLabels
+	awsOperationTimeouts, err := splitDurations(o.awsOperationTimeoutsRaw)
+	if err != nil {
+		return fmt.Errorf("parsing aws-operation-timeouts, %w", err)
+	}
+	o.AWSOperationTimeouts = awsOperationTimeouts
 	if err := o.Validate(); err != nil {
 		return fmt.Errorf("validating options, %w", err)
 	}
@@ -84,3 +276,65 @@ func FromContext(ctx context.Context) *Options {
 	}
 	return retval.(*Options)
 }
+
+// AWSOperationTimeout returns how long calls to an AWS API operation may take, including retries. Describe, Get and List
+// operations return quickly unless AWS is degraded, while CreateFleet searches the requested capacity pools, so its
+// calls are given longer. No deadline is set when the timeout is 0.
+func (o Options) AWSOperationTimeout(operation string) time.Duration {
+	if timeout, ok := o.AWSOperationTimeouts[operation]; ok {
+		return timeout
+	}
+	if operation == "CreateFleet" {
+		return 2 * time.Minute
+	}
+	if lo.SomeBy([]string{"Describe", "Get", "List"}, func(prefix string) bool { return strings.HasPrefix(operation, prefix) }) {
+		return 30 * time.Second
+	}
+	return time.Minute
+}
+
+// splitList parses a comma separated flag value, dropping surrounding whitespace and empty entries
+func splitList(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	return lo.Compact(lo.Map(strings.Split(raw, ","), func(s string, _ int) string { return strings.TrimSpace(s) }))
+}
+
+// splitLabels parses a comma separated list of key=value labels
+func splitLabels(raw string) (map[string]string, error) {
+	entries := splitList(raw)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	labels := map[string]string{}
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a key=value label", entry)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// splitDurations parses a comma separated list of key=duration pairs
+func splitDurations(raw string) (map[string]time.Duration, error) {
+	entries := splitList(raw)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	durations := map[string]time.Duration{}
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a key=duration pair", entry)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q, %w", entry, err)
+		}
+		durations[strings.TrimSpace(key)] = duration
+	}
+	return durations, nil
+}
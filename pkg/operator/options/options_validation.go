@@ -17,17 +17,64 @@ package options
 import (
 	"fmt"
 	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+var (
+	accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+	policyARNPattern = regexp.MustCompile(`^arn:[^:]+:iam::([0-9]{12}|aws):policy/.+$`)
 )
 
 func (o Options) Validate() error {
 	return multierr.Combine(
 		o.validateEndpoint(),
 		o.validateVMMemoryOverheadPercent(),
+		o.validateGravitonCMAReservedMemory(),
+		o.validateInstanceStoreKubeReservedStorage(),
+		o.validateInstanceStoreEvictionPercent(),
 		o.validateAssumeRoleDuration(),
 		o.validateReservedENIs(),
+		o.validateSnapshotGCRetention(),
+		o.validateLaunchTemplateGCGracePeriod(),
+		o.validateOnDemandAllocationStrategy(),
+		o.validateInstanceTypeGlobs(),
+		o.validateMinLaunchInstanceTypes(),
+		o.validateNodeClassStatusAWSRateLimit(),
+		o.validateSubnetFreeIPThreshold(),
+		o.validateNodeNameConvention(),
+		o.validateRequirePrivateDNSName(),
+		o.validateInstanceTypeMaxStaleness(),
+		o.validateInstanceTypeCacheMaxKeys(),
+		o.validateSpotPriceTTL(),
+		o.validateSpotPriceRefreshInterval(),
+		o.validateSpotPriceMaxStaleness(),
+		o.validateAMIDeprecationWindow(),
+		o.validateAMIDefaultOwners(),
+		o.validateAllowedAMIOwners(),
+		o.validateInterruptionQueueConsumption(),
+		o.validateInterruptionRebalanceAction(),
+		o.validateSpotInterruptionPenalty(),
+		o.validateUnavailableOfferingTTLs(),
+		o.validateExtraNodeLabels(),
+		o.validateInstanceTypeLabels(),
+		o.validateAWSOperationTimeouts(),
+		o.validateInstanceProfilePath(),
+		o.validateInstanceProfilePermissionsBoundary(),
+		o.validateCloudWatchMetricsNamespace(),
 		o.validateRequiredFields(),
 	)
 }
@@ -59,6 +106,31 @@ func (o Options) validateVMMemoryOverheadPercent() error {
 	return nil
 }
 
+func (o Options) validateGravitonCMAReservedMemory() error {
+	if o.GravitonCMAReservedMemoryMiB < 0 {
+		return fmt.Errorf("graviton-cma-reserved-memory-mib cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateInstanceStoreKubeReservedStorage() error {
+	quantity, err := resource.ParseQuantity(o.InstanceStoreKubeReservedStorage)
+	if err != nil {
+		return fmt.Errorf("instance-store-kube-reserved-ephemeral-storage %q is not a valid quantity, %w", o.InstanceStoreKubeReservedStorage, err)
+	}
+	if quantity.Sign() < 0 {
+		return fmt.Errorf("instance-store-kube-reserved-ephemeral-storage cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateInstanceStoreEvictionPercent() error {
+	if o.InstanceStoreEvictionPercent < 0 || o.InstanceStoreEvictionPercent > 100 {
+		return fmt.Errorf("instance-store-eviction-threshold-percent must be between 0 and 100")
+	}
+	return nil
+}
+
 func (o Options) validateReservedENIs() error {
 	if o.ReservedENIs < 0 {
 		return fmt.Errorf("reserved-enis cannot be negative")
@@ -66,6 +138,281 @@ func (o Options) validateReservedENIs() error {
 	return nil
 }
 
+func (o Options) validateMinLaunchInstanceTypes() error {
+	if o.MinLaunchInstanceTypes < 0 {
+		return fmt.Errorf("min-launch-instance-types cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateNodeClassStatusAWSRateLimit() error {
+	if o.NodeClassStatusAWSQPS < 0 {
+		return fmt.Errorf("nodeclass-status-aws-qps cannot be negative")
+	}
+	if o.NodeClassStatusAWSBurst < 1 {
+		return fmt.Errorf("nodeclass-status-aws-burst must be at least 1")
+	}
+	return nil
+}
+
+func (o Options) validateSubnetFreeIPThreshold() error {
+	if o.SubnetFreeIPThreshold < 0 {
+		return fmt.Errorf("subnet-free-ip-threshold cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateNodeNameConvention() error {
+	if !lo.Contains([]string{NodeNameConventionPrivateDNS, NodeNameConventionResourceName, NodeNameConventionTemplate}, o.NodeNameConvention) {
+		return fmt.Errorf("%q is not a valid node-name-convention, must be one of 'private-dns', 'resource-name' or 'template'", o.NodeNameConvention)
+	}
+	if o.NodeNameConvention != NodeNameConventionTemplate {
+		return nil
+	}
+	tmpl, err := template.New("node-name").Parse(o.NodeNameTemplate)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid node-name-template, %w", o.NodeNameTemplate, err)
+	}
+	// Render the template with sample values to catch references to unknown fields and names that the kubelet can't register with
+	data := NodeNameTemplateData{ClusterName: "cluster", NodePool: "nodepool", InstanceID: "i-0123456789abcdef0"}
+	var name strings.Builder
+	if err := tmpl.Execute(&name, data); err != nil {
+		return fmt.Errorf("%q is not a valid node-name-template, %w", o.NodeNameTemplate, err)
+	}
+	if !strings.Contains(name.String(), data.InstanceID) {
+		return fmt.Errorf("node-name-template must reference .InstanceID so that node names are unique")
+	}
+	if errs := validation.IsDNS1123Subdomain(name.String()); len(errs) > 0 {
+		return fmt.Errorf("node-name-template must render a valid node name, %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (o Options) validateInstanceTypeLabels() error {
+	registered := lo.SliceToMap(v1beta1.InstanceTypeLabels, func(l v1beta1.InstanceTypeLabel) (string, v1beta1.InstanceTypeLabel) { return l.Key, l })
+	for _, key := range lo.Flatten([][]string{o.EnabledLabels, o.DisabledLabels}) {
+		if _, ok := registered[key]; !ok {
+			return fmt.Errorf("%q is not an instance type label that can be enabled or disabled", key)
+		}
+	}
+	for _, key := range o.DisabledLabels {
+		if registered[key].Required {
+			return fmt.Errorf("%q is required when launching instances and can't be disabled", key)
+		}
+	}
+	if both := lo.Intersect(o.EnabledLabels, o.DisabledLabels); len(both) > 0 {
+		return fmt.Errorf("labels can't be both enabled and disabled, got %s", strings.Join(both, ", "))
+	}
+	return nil
+}
+
+func (o Options) validateExtraNodeLabels() error {
+	// Render the templates with sample values to catch references to unknown fields and values that aren't valid label values
+	data := NodeLabelTemplateData{InstanceID: "i-0123456789abcdef0", Region: "us-west-2", ZoneID: "usw2-az1", InstanceType: "m5.large"}
+	var errs error
+	for key, value := range o.ExtraNodeLabels {
+		if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+			errs = multierr.Append(errs, fmt.Errorf("%q is not a valid extra-node-labels key, %s", key, strings.Join(msgs, ", ")))
+			continue
+		}
+		if corev1beta1.IsRestrictedNodeLabel(key) {
+			errs = multierr.Append(errs, fmt.Errorf("%q is a restricted label and can't be an extra-node-labels key", key))
+			continue
+		}
+		rendered, err := RenderNodeLabel(value, data)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%q is not a valid extra-node-labels value for %s, %w", value, key, err))
+			continue
+		}
+		if msgs := validation.IsValidLabelValue(rendered); len(msgs) > 0 {
+			errs = multierr.Append(errs, fmt.Errorf("extra-node-labels value for %s must render a valid label value, %s", key, strings.Join(msgs, ", ")))
+		}
+	}
+	return errs
+}
+
+func (o Options) validateInstanceProfilePath() error {
+	if len(o.InstanceProfilePath) > 512 || !strings.HasPrefix(o.InstanceProfilePath, "/") || !strings.HasSuffix(o.InstanceProfilePath, "/") {
+		return fmt.Errorf("%q is not a valid instance-profile-path, must begin and end with a slash and be at most 512 characters", o.InstanceProfilePath)
+	}
+	return nil
+}
+
+func (o Options) validateInstanceProfilePermissionsBoundary() error {
+	if o.InstanceProfilePermissionsBoundary != "" && !policyARNPattern.MatchString(o.InstanceProfilePermissionsBoundary) {
+		return fmt.Errorf("%q is not a valid instance-profile-permissions-boundary, must be the ARN of an IAM policy", o.InstanceProfilePermissionsBoundary)
+	}
+	return nil
+}
+
+// validateCloudWatchMetricsNamespace rejects namespaces that CloudWatch would reject on every publish. Namespaces
+// beginning with AWS/ are reserved for AWS services.
+func (o Options) validateCloudWatchMetricsNamespace() error {
+	if len(o.CloudWatchMetricsNamespace) > 255 || strings.HasPrefix(o.CloudWatchMetricsNamespace, "AWS/") {
+		return fmt.Errorf("%q is not a valid cloudwatch-metrics-namespace, must be at most 255 characters and can't begin with AWS/", o.CloudWatchMetricsNamespace)
+	}
+	return nil
+}
+
+func (o Options) validateRequirePrivateDNSName() error {
+	if !o.RequirePrivateDNSName && o.NodeNameConvention == NodeNameConventionPrivateDNS {
+		return fmt.Errorf("require-private-dns-name can't be false when node-name-convention is 'private-dns', since nodes are named after the private DNS name")
+	}
+	return nil
+}
+
+func (o Options) validateInstanceTypeMaxStaleness() error {
+	if o.InstanceTypeMaxStaleness < 0 {
+		return fmt.Errorf("instance-type-max-staleness cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateInstanceTypeCacheMaxKeys() error {
+	if o.InstanceTypeCacheMaxKeys < 1 {
+		return fmt.Errorf("instance-type-cache-max-keys must be at least 1")
+	}
+	return nil
+}
+
+func (o Options) validateSpotPriceTTL() error {
+	if o.SpotPriceTTL < 0 {
+		return fmt.Errorf("spot-price-ttl cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateSpotPriceRefreshInterval() error {
+	if o.SpotPriceRefreshInterval < time.Minute {
+		return fmt.Errorf("spot-price-refresh-interval must be at least 1m")
+	}
+	return nil
+}
+
+func (o Options) validateSpotPriceMaxStaleness() error {
+	if o.SpotPriceMaxStaleness < 0 {
+		return fmt.Errorf("spot-price-max-staleness cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateAMIDeprecationWindow() error {
+	if o.AMIDeprecationWindow < 0 {
+		return fmt.Errorf("ami-deprecation-window cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateAMIDefaultOwners() error {
+	if len(o.AMIDefaultOwners) == 0 {
+		return fmt.Errorf("ami-default-owners cannot be empty")
+	}
+	if lo.Contains(o.AMIDefaultOwners, "*") {
+		return fmt.Errorf("ami-default-owners cannot contain a wildcard, set the owner on the AMI selector terms that should search all public images")
+	}
+	return nil
+}
+
+// validateAllowedAMIOwners requires account IDs, since they're compared against the owner that DescribeImages reports,
+// which is never an alias like 'self' or 'amazon'
+func (o Options) validateAllowedAMIOwners() error {
+	for _, owner := range o.AllowedAMIOwners {
+		if !accountIDPattern.MatchString(owner) {
+			return fmt.Errorf("allowed-ami-owners must only contain 12 digit account IDs, got %q", owner)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateInterruptionQueueConsumption() error {
+	if o.InterruptionQueueWaitTime < 0 || o.InterruptionQueueWaitTime > 20*time.Second {
+		return fmt.Errorf("interruption-queue-wait-time must be between 0 and 20 seconds")
+	}
+	if o.InterruptionQueueParallelism < 1 {
+		return fmt.Errorf("interruption-queue-parallelism must be at least 1")
+	}
+	if o.InterruptionQueueMaxParseAttempts < 1 {
+		return fmt.Errorf("interruption-queue-max-parse-attempts must be at least 1")
+	}
+	return nil
+}
+
+func (o Options) validateAWSOperationTimeouts() error {
+	for operation, timeout := range o.AWSOperationTimeouts {
+		if timeout < 0 {
+			return fmt.Errorf("aws-operation-timeouts for %s cannot be negative", operation)
+		}
+	}
+	// Receives from the interruption queue long poll for interruption-queue-wait-time, so they'd always time out
+	if timeout := o.AWSOperationTimeout("ReceiveMessage"); timeout > 0 && timeout <= o.InterruptionQueueWaitTime {
+		return fmt.Errorf("aws-operation-timeouts for ReceiveMessage must be longer than interruption-queue-wait-time")
+	}
+	return nil
+}
+
+func (o Options) validateSpotInterruptionPenalty() error {
+	if o.SpotInterruptionPenalty < 0 {
+		return fmt.Errorf("spot-interruption-penalty cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateUnavailableOfferingTTLs() error {
+	if o.SpotUnfulfillableCapacityTTL <= 0 {
+		return fmt.Errorf("spot-unfulfillable-capacity-ttl must be positive")
+	}
+	if o.OnDemandInsufficientCapacityTTL <= 0 {
+		return fmt.Errorf("on-demand-insufficient-capacity-ttl must be positive")
+	}
+	if o.ReservationCapacityExceededTTL <= 0 {
+		return fmt.Errorf("reservation-capacity-exceeded-ttl must be positive")
+	}
+	return nil
+}
+
+func (o Options) validateSnapshotGCRetention() error {
+	if o.SnapshotGCRetention < 0 {
+		return fmt.Errorf("snapshot-gc-retention cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateLaunchTemplateGCGracePeriod() error {
+	if o.LaunchTemplateGCGracePeriod < 0 {
+		return fmt.Errorf("launch-template-gc-grace-period cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateInterruptionRebalanceAction() error {
+	if !lo.Contains([]string{InterruptionRebalanceActionIgnore, InterruptionRebalanceActionCordon, InterruptionRebalanceActionCordonAndDrain}, o.InterruptionRebalanceAction) {
+		return fmt.Errorf("%q is not a valid interruption-rebalance-action, must be one of 'Ignore', 'Cordon' or 'CordonAndDrain'", o.InterruptionRebalanceAction)
+	}
+	if o.RebalanceRecommendations && o.InterruptionRebalanceAction == InterruptionRebalanceActionCordon {
+		return fmt.Errorf("rebalance-recommendations can't be set when interruption-rebalance-action is 'Cordon'")
+	}
+	return nil
+}
+
+func (o Options) validateOnDemandAllocationStrategy() error {
+	if !lo.Contains([]string{ec2.FleetOnDemandAllocationStrategyLowestPrice, ec2.FleetOnDemandAllocationStrategyPrioritized}, o.OnDemandAllocationStrategy) {
+		return fmt.Errorf("%q is not a valid on-demand-allocation-strategy, must be one of 'lowest-price' or 'prioritized'", o.OnDemandAllocationStrategy)
+	}
+	return nil
+}
+
+func (o Options) validateInstanceTypeGlobs() error {
+	var errs error
+	for flag, patterns := range map[string][]string{"instance-type-allowlist": o.InstanceTypeAllowlist, "instance-type-denylist": o.InstanceTypeDenylist} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("%q is not a valid %s glob, %w", pattern, flag, err))
+			}
+		}
+	}
+	return errs
+}
+
 func (o Options) validateRequiredFields() error {
 	if o.ClusterName == "" {
 		return fmt.Errorf("missing field, cluster-name")
@@ -62,21 +62,123 @@ var _ = Describe("Options", func() {
 			"--cluster-ca-bundle", "env-bundle",
 			"--cluster-name", "env-cluster",
 			"--cluster-endpoint", "https://env-cluster",
+			"--cluster-autoscaler-compatibility",
 			"--isolated-vpc",
+			"--pricing-override-file", "/etc/karpenter/prices.json",
+			"--spot-price-ttl", "48h",
+			"--spot-price-refresh-interval", "5m",
+			"--spot-price-max-staleness", "1h",
 			"--vm-memory-overhead-percent", "0.1",
+			"--graviton-cma-reserved-memory-mib", "128",
+			"--instance-store-kube-reserved-ephemeral-storage", "10Gi",
+			"--instance-store-eviction-threshold-percent", "2.5",
 			"--interruption-queue", "env-cluster",
-			"--reserved-enis", "10")
+			"--reserved-enis", "10",
+			"--eni-prefix-delegation",
+			"--snapshot-gc",
+			"--snapshot-gc-retention", "48h",
+			"--snapshot-gc-dry-run",
+			"--on-demand-allocation-strategy", "prioritized",
+			"--taint-tags",
+			"--instance-type-allowlist", "m5.*, c5.large",
+			"--instance-type-denylist", "*.metal,p5.*",
+			"--min-launch-instance-types", "5",
+			"--raise-undersized-root-volumes",
+			"--network-bandwidth-resource",
+			"--nodeclass-status-aws-qps", "5",
+			"--nodeclass-status-aws-burst", "10",
+			"--subnet-free-ip-threshold", "16",
+			"--node-name-convention", "template",
+			"--node-name-template", "{{ .NodePool }}-{{ .InstanceID }}",
+			"--subnet-cluster-tagging",
+			"--subnet-cluster-tagging-dry-run",
+			"--instance-type-max-staleness", "1h",
+			"--instance-type-cache-max-keys", "50",
+			"--ami-default-owners", "self,123456789012",
+			"--allowed-ami-owners", "123456789012, 602401143452",
+			"--enabled-labels", "karpenter.k8s.aws/instance-local-nvme-count",
+			"--disabled-labels", "karpenter.k8s.aws/instance-hypervisor,karpenter.k8s.aws/instance-smt-supported",
+			"--ami-deprecation-window", "72h",
+			"--rebalance-recommendations",
+			"--interruption-rebalance-action", "CordonAndDrain",
+			"--interruption-queue-wait-time", "10s",
+			"--interruption-queue-parallelism", "5",
+			"--interruption-queue-max-parse-attempts", "2",
+			"--interruption-queue-required",
+			"--spot-interruption-penalty", "1.5",
+			"--spot-unfulfillable-capacity-ttl", "5m",
+			"--on-demand-insufficient-capacity-ttl", "30m",
+			"--reservation-capacity-exceeded-ttl", "2m",
+			"--require-private-dns-name=false",
+			"--extra-node-labels", "myorg.io/asset-id={{ .Region }}.{{ .InstanceID }}, myorg.io/zone-id={{ .ZoneID }}",
+			"--force-instance-profile-revalidation",
+			"--instance-profile-path", "/karpenter/",
+			"--instance-profile-permissions-boundary", "arn:aws:iam::123456789012:policy/boundary",
+			"--launch-template-gc-grace-period", "2h",
+			"--aws-operation-timeouts", "DescribeImages=10s, CreateFleet=3m",
+			"--cloudwatch-metrics-namespace", "Karpenter/env-cluster")
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
-			AssumeRoleARN:           lo.ToPtr("env-role"),
-			AssumeRoleDuration:      lo.ToPtr(20 * time.Minute),
-			ClusterCABundle:         lo.ToPtr("env-bundle"),
-			ClusterName:             lo.ToPtr("env-cluster"),
-			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
-			IsolatedVPC:             lo.ToPtr(true),
-			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
-			InterruptionQueue:       lo.ToPtr("env-cluster"),
-			ReservedENIs:            lo.ToPtr(10),
+			AssumeRoleARN:                      lo.ToPtr("env-role"),
+			AssumeRoleDuration:                 lo.ToPtr(20 * time.Minute),
+			ClusterCABundle:                    lo.ToPtr("env-bundle"),
+			ClusterName:                        lo.ToPtr("env-cluster"),
+			ClusterEndpoint:                    lo.ToPtr("https://env-cluster"),
+			ClusterAutoscalerCompatibility:     lo.ToPtr(true),
+			IsolatedVPC:                        lo.ToPtr(true),
+			PricingOverrideFile:                lo.ToPtr("/etc/karpenter/prices.json"),
+			SpotPriceTTL:                       lo.ToPtr(48 * time.Hour),
+			SpotPriceRefreshInterval:           lo.ToPtr(5 * time.Minute),
+			SpotPriceMaxStaleness:              lo.ToPtr(time.Hour),
+			VMMemoryOverheadPercent:            lo.ToPtr[float64](0.1),
+			GravitonCMAReservedMemoryMiB:       lo.ToPtr(128),
+			InstanceStoreKubeReservedStorage:   lo.ToPtr("10Gi"),
+			InstanceStoreEvictionPercent:       lo.ToPtr(2.5),
+			InterruptionQueue:                  lo.ToPtr("env-cluster"),
+			ReservedENIs:                       lo.ToPtr(10),
+			ENIPrefixDelegation:                lo.ToPtr(true),
+			SnapshotGC:                         lo.ToPtr(true),
+			SnapshotGCRetention:                lo.ToPtr(48 * time.Hour),
+			SnapshotGCDryRun:                   lo.ToPtr(true),
+			OnDemandAllocationStrategy:         lo.ToPtr("prioritized"),
+			TaintTags:                          lo.ToPtr(true),
+			InstanceTypeAllowlist:              []string{"m5.*", "c5.large"},
+			InstanceTypeDenylist:               []string{"*.metal", "p5.*"},
+			MinLaunchInstanceTypes:             lo.ToPtr(5),
+			RaiseUndersizedRootVolumes:         lo.ToPtr(true),
+			NetworkBandwidthResource:           lo.ToPtr(true),
+			NodeClassStatusAWSQPS:              lo.ToPtr[float64](5),
+			NodeClassStatusAWSBurst:            lo.ToPtr(10),
+			SubnetFreeIPThreshold:              lo.ToPtr(16),
+			NodeNameConvention:                 lo.ToPtr("template"),
+			NodeNameTemplate:                   lo.ToPtr("{{ .NodePool }}-{{ .InstanceID }}"),
+			SubnetClusterTagging:               lo.ToPtr(true),
+			SubnetClusterTaggingDryRun:         lo.ToPtr(true),
+			InstanceTypeMaxStaleness:           lo.ToPtr(time.Hour),
+			InstanceTypeCacheMaxKeys:           lo.ToPtr(50),
+			AMIDefaultOwners:                   []string{"self", "123456789012"},
+			AllowedAMIOwners:                   []string{"123456789012", "602401143452"},
+			EnabledLabels:                      []string{"karpenter.k8s.aws/instance-local-nvme-count"},
+			DisabledLabels:                     []string{"karpenter.k8s.aws/instance-hypervisor", "karpenter.k8s.aws/instance-smt-supported"},
+			AMIDeprecationWindow:               lo.ToPtr(72 * time.Hour),
+			RebalanceRecommendations:           lo.ToPtr(true),
+			InterruptionRebalanceAction:        lo.ToPtr("CordonAndDrain"),
+			InterruptionQueueWaitTime:          lo.ToPtr(10 * time.Second),
+			InterruptionQueueParallelism:       lo.ToPtr(5),
+			InterruptionQueueMaxParseAttempts:  lo.ToPtr(2),
+			InterruptionQueueRequired:          lo.ToPtr(true),
+			SpotInterruptionPenalty:            lo.ToPtr[float64](1.5),
+			SpotUnfulfillableCapacityTTL:       lo.ToPtr(5 * time.Minute),
+			OnDemandInsufficientCapacityTTL:    lo.ToPtr(30 * time.Minute),
+			ReservationCapacityExceededTTL:     lo.ToPtr(2 * time.Minute),
+			RequirePrivateDNSName:              lo.ToPtr(false),
+			ExtraNodeLabels:                    map[string]string{"myorg.io/asset-id": "{{ .Region }}.{{ .InstanceID }}", "myorg.io/zone-id": "{{ .ZoneID }}"},
+			AWSOperationTimeouts:               map[string]time.Duration{"DescribeImages": 10 * time.Second, "CreateFleet": 3 * time.Minute},
+			CloudWatchMetricsNamespace:         lo.ToPtr("Karpenter/env-cluster"),
+			ForceInstanceProfileRevalidation:   lo.ToPtr(true),
+			InstanceProfilePath:                lo.ToPtr("/karpenter/"),
+			InstanceProfilePermissionsBoundary: lo.ToPtr("arn:aws:iam::123456789012:policy/boundary"),
+			LaunchTemplateGCGracePeriod:        lo.ToPtr(2 * time.Hour),
 		}))
 	})
 	It("should correctly fallback to env vars when CLI flags aren't set", func() {
@@ -85,10 +187,61 @@ var _ = Describe("Options", func() {
 		os.Setenv("CLUSTER_CA_BUNDLE", "env-bundle")
 		os.Setenv("CLUSTER_NAME", "env-cluster")
 		os.Setenv("CLUSTER_ENDPOINT", "https://env-cluster")
+		os.Setenv("CLUSTER_AUTOSCALER_COMPATIBILITY", "true")
 		os.Setenv("ISOLATED_VPC", "true")
+		os.Setenv("PRICING_OVERRIDE_FILE", "/etc/karpenter/prices.json")
+		os.Setenv("SPOT_PRICE_TTL", "48h")
+		os.Setenv("SPOT_PRICE_REFRESH_INTERVAL", "5m")
+		os.Setenv("SPOT_PRICE_MAX_STALENESS", "1h")
 		os.Setenv("VM_MEMORY_OVERHEAD_PERCENT", "0.1")
+		os.Setenv("GRAVITON_CMA_RESERVED_MEMORY_MIB", "128")
+		os.Setenv("INSTANCE_STORE_KUBE_RESERVED_EPHEMERAL_STORAGE", "10Gi")
+		os.Setenv("INSTANCE_STORE_EVICTION_THRESHOLD_PERCENT", "2.5")
 		os.Setenv("INTERRUPTION_QUEUE", "env-cluster")
 		os.Setenv("RESERVED_ENIS", "10")
+		os.Setenv("ENI_PREFIX_DELEGATION", "true")
+		os.Setenv("SNAPSHOT_GC", "true")
+		os.Setenv("SNAPSHOT_GC_RETENTION", "48h")
+		os.Setenv("SNAPSHOT_GC_DRY_RUN", "true")
+		os.Setenv("ON_DEMAND_ALLOCATION_STRATEGY", "prioritized")
+		os.Setenv("TAINT_TAGS", "true")
+		os.Setenv("INSTANCE_TYPE_ALLOWLIST", "m5.*, c5.large")
+		os.Setenv("INSTANCE_TYPE_DENYLIST", "*.metal,p5.*")
+		os.Setenv("MIN_LAUNCH_INSTANCE_TYPES", "5")
+		os.Setenv("RAISE_UNDERSIZED_ROOT_VOLUMES", "true")
+		os.Setenv("NETWORK_BANDWIDTH_RESOURCE", "true")
+		os.Setenv("NODECLASS_STATUS_AWS_QPS", "5")
+		os.Setenv("NODECLASS_STATUS_AWS_BURST", "10")
+		os.Setenv("SUBNET_FREE_IP_THRESHOLD", "16")
+		os.Setenv("NODE_NAME_CONVENTION", "template")
+		os.Setenv("NODE_NAME_TEMPLATE", "{{ .NodePool }}-{{ .InstanceID }}")
+		os.Setenv("SUBNET_CLUSTER_TAGGING", "true")
+		os.Setenv("SUBNET_CLUSTER_TAGGING_DRY_RUN", "true")
+		os.Setenv("INSTANCE_TYPE_MAX_STALENESS", "1h")
+		os.Setenv("INSTANCE_TYPE_CACHE_MAX_KEYS", "50")
+		os.Setenv("AMI_DEFAULT_OWNERS", "self,123456789012")
+		os.Setenv("ALLOWED_AMI_OWNERS", "123456789012, 602401143452")
+		os.Setenv("ENABLED_LABELS", "karpenter.k8s.aws/instance-local-nvme-count")
+		os.Setenv("DISABLED_LABELS", "karpenter.k8s.aws/instance-hypervisor,karpenter.k8s.aws/instance-smt-supported")
+		os.Setenv("AMI_DEPRECATION_WINDOW", "72h")
+		os.Setenv("REBALANCE_RECOMMENDATIONS", "true")
+		os.Setenv("INTERRUPTION_REBALANCE_ACTION", "CordonAndDrain")
+		os.Setenv("INTERRUPTION_QUEUE_WAIT_TIME", "10s")
+		os.Setenv("INTERRUPTION_QUEUE_PARALLELISM", "5")
+		os.Setenv("INTERRUPTION_QUEUE_MAX_PARSE_ATTEMPTS", "2")
+		os.Setenv("INTERRUPTION_QUEUE_REQUIRED", "true")
+		os.Setenv("SPOT_INTERRUPTION_PENALTY", "1.5")
+		os.Setenv("SPOT_UNFULFILLABLE_CAPACITY_TTL", "5m")
+		os.Setenv("ON_DEMAND_INSUFFICIENT_CAPACITY_TTL", "30m")
+		os.Setenv("RESERVATION_CAPACITY_EXCEEDED_TTL", "2m")
+		os.Setenv("REQUIRE_PRIVATE_DNS_NAME", "false")
+		os.Setenv("EXTRA_NODE_LABELS", "myorg.io/asset-id={{ .Region }}.{{ .InstanceID }}, myorg.io/zone-id={{ .ZoneID }}")
+		os.Setenv("FORCE_INSTANCE_PROFILE_REVALIDATION", "true")
+		os.Setenv("INSTANCE_PROFILE_PATH", "/karpenter/")
+		os.Setenv("INSTANCE_PROFILE_PERMISSIONS_BOUNDARY", "arn:aws:iam::123456789012:policy/boundary")
+		os.Setenv("LAUNCH_TEMPLATE_GC_GRACE_PERIOD", "2h")
+		os.Setenv("AWS_OPERATION_TIMEOUTS", "DescribeImages=10s, CreateFleet=3m")
+		os.Setenv("CLOUDWATCH_METRICS_NAMESPACE", "Karpenter/env-cluster")
 
 		// Add flags after we set the environment variables so that the parsing logic correctly refers
 		// to the new environment variable values
@@ -96,15 +249,66 @@ var _ = Describe("Options", func() {
 		err := opts.Parse(fs)
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
-			AssumeRoleARN:           lo.ToPtr("env-role"),
-			AssumeRoleDuration:      lo.ToPtr(20 * time.Minute),
-			ClusterCABundle:         lo.ToPtr("env-bundle"),
-			ClusterName:             lo.ToPtr("env-cluster"),
-			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
-			IsolatedVPC:             lo.ToPtr(true),
-			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
-			InterruptionQueue:       lo.ToPtr("env-cluster"),
-			ReservedENIs:            lo.ToPtr(10),
+			AssumeRoleARN:                      lo.ToPtr("env-role"),
+			AssumeRoleDuration:                 lo.ToPtr(20 * time.Minute),
+			ClusterCABundle:                    lo.ToPtr("env-bundle"),
+			ClusterName:                        lo.ToPtr("env-cluster"),
+			ClusterEndpoint:                    lo.ToPtr("https://env-cluster"),
+			ClusterAutoscalerCompatibility:     lo.ToPtr(true),
+			IsolatedVPC:                        lo.ToPtr(true),
+			PricingOverrideFile:                lo.ToPtr("/etc/karpenter/prices.json"),
+			SpotPriceTTL:                       lo.ToPtr(48 * time.Hour),
+			SpotPriceRefreshInterval:           lo.ToPtr(5 * time.Minute),
+			SpotPriceMaxStaleness:              lo.ToPtr(time.Hour),
+			VMMemoryOverheadPercent:            lo.ToPtr[float64](0.1),
+			GravitonCMAReservedMemoryMiB:       lo.ToPtr(128),
+			InstanceStoreKubeReservedStorage:   lo.ToPtr("10Gi"),
+			InstanceStoreEvictionPercent:       lo.ToPtr(2.5),
+			InterruptionQueue:                  lo.ToPtr("env-cluster"),
+			ReservedENIs:                       lo.ToPtr(10),
+			ENIPrefixDelegation:                lo.ToPtr(true),
+			SnapshotGC:                         lo.ToPtr(true),
+			SnapshotGCRetention:                lo.ToPtr(48 * time.Hour),
+			SnapshotGCDryRun:                   lo.ToPtr(true),
+			OnDemandAllocationStrategy:         lo.ToPtr("prioritized"),
+			TaintTags:                          lo.ToPtr(true),
+			InstanceTypeAllowlist:              []string{"m5.*", "c5.large"},
+			InstanceTypeDenylist:               []string{"*.metal", "p5.*"},
+			MinLaunchInstanceTypes:             lo.ToPtr(5),
+			RaiseUndersizedRootVolumes:         lo.ToPtr(true),
+			NetworkBandwidthResource:           lo.ToPtr(true),
+			NodeClassStatusAWSQPS:              lo.ToPtr[float64](5),
+			NodeClassStatusAWSBurst:            lo.ToPtr(10),
+			SubnetFreeIPThreshold:              lo.ToPtr(16),
+			NodeNameConvention:                 lo.ToPtr("template"),
+			NodeNameTemplate:                   lo.ToPtr("{{ .NodePool }}-{{ .InstanceID }}"),
+			SubnetClusterTagging:               lo.ToPtr(true),
+			SubnetClusterTaggingDryRun:         lo.ToPtr(true),
+			InstanceTypeMaxStaleness:           lo.ToPtr(time.Hour),
+			InstanceTypeCacheMaxKeys:           lo.ToPtr(50),
+			AMIDefaultOwners:                   []string{"self", "123456789012"},
+			AllowedAMIOwners:                   []string{"123456789012", "602401143452"},
+			EnabledLabels:                      []string{"karpenter.k8s.aws/instance-local-nvme-count"},
+			DisabledLabels:                     []string{"karpenter.k8s.aws/instance-hypervisor", "karpenter.k8s.aws/instance-smt-supported"},
+			AMIDeprecationWindow:               lo.ToPtr(72 * time.Hour),
+			RebalanceRecommendations:           lo.ToPtr(true),
+			InterruptionRebalanceAction:        lo.ToPtr("CordonAndDrain"),
+			InterruptionQueueWaitTime:          lo.ToPtr(10 * time.Second),
+			InterruptionQueueParallelism:       lo.ToPtr(5),
+			InterruptionQueueMaxParseAttempts:  lo.ToPtr(2),
+			InterruptionQueueRequired:          lo.ToPtr(true),
+			SpotInterruptionPenalty:            lo.ToPtr[float64](1.5),
+			SpotUnfulfillableCapacityTTL:       lo.ToPtr(5 * time.Minute),
+			OnDemandInsufficientCapacityTTL:    lo.ToPtr(30 * time.Minute),
+			ReservationCapacityExceededTTL:     lo.ToPtr(2 * time.Minute),
+			RequirePrivateDNSName:              lo.ToPtr(false),
+			ExtraNodeLabels:                    map[string]string{"myorg.io/asset-id": "{{ .Region }}.{{ .InstanceID }}", "myorg.io/zone-id": "{{ .ZoneID }}"},
+			AWSOperationTimeouts:               map[string]time.Duration{"DescribeImages": 10 * time.Second, "CreateFleet": 3 * time.Minute},
+			CloudWatchMetricsNamespace:         lo.ToPtr("Karpenter/env-cluster"),
+			ForceInstanceProfileRevalidation:   lo.ToPtr(true),
+			InstanceProfilePath:                lo.ToPtr("/karpenter/"),
+			InstanceProfilePermissionsBoundary: lo.ToPtr("arn:aws:iam::123456789012:policy/boundary"),
+			LaunchTemplateGCGracePeriod:        lo.ToPtr(2 * time.Hour),
 		}))
 	})
 
@@ -128,10 +332,254 @@ var _ = Describe("Options", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overhead-percent", "-0.01")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when gravitonCMAReservedMemoryMiB is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--graviton-cma-reserved-memory-mib", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceStoreKubeReservedStorage is not a quantity", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-store-kube-reserved-ephemeral-storage", "5 gigs")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceStoreKubeReservedStorage is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-store-kube-reserved-ephemeral-storage", "-1Gi")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceStoreEvictionPercent is above 100", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-store-eviction-threshold-percent", "101")
+			Expect(err).To(HaveOccurred())
+		})
 		It("should fail when reservedENIs is negative", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--reserved-enis", "-1")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when minLaunchInstanceTypes is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--min-launch-instance-types", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeClassStatusAWSQPS is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--nodeclass-status-aws-qps", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeClassStatusAWSBurst is less than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--nodeclass-status-aws-burst", "0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when subnetFreeIPThreshold is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--subnet-free-ip-threshold", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeNameConvention is not a supported convention", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-convention", "ip-name")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionRebalanceAction is not a supported action", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-rebalance-action", "Drain")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when rebalanceRecommendations is set with the Cordon interruptionRebalanceAction", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--rebalance-recommendations", "--interruption-rebalance-action", "Cordon")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeNameTemplate doesn't reference the instance ID", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-convention", "template", "--node-name-template", "{{ .ClusterName }}-{{ .NodePool }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeNameTemplate references an unknown field", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-convention", "template", "--node-name-template", "{{ .Zone }}-{{ .InstanceID }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeNameTemplate doesn't render a valid node name", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-convention", "template", "--node-name-template", "{{ .NodePool }}_{{ .InstanceID }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should not validate nodeNameTemplate unless the template convention is used", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-template", "{{ .NodePool }}")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should fail when an extraNodeLabels entry isn't a key=value label", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--extra-node-labels", "myorg.io/asset-id")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an extraNodeLabels key isn't a valid label key", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--extra-node-labels", "myorg.io/asset id={{ .InstanceID }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an extraNodeLabels key is a restricted label", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--extra-node-labels", "karpenter.sh/asset-id={{ .InstanceID }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an extraNodeLabels value references an unknown field", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--extra-node-labels", "myorg.io/asset-id={{ .AccountID }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an extraNodeLabels value doesn't render a valid label value", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--extra-node-labels", "myorg.io/asset-id={{ .Region }}/{{ .InstanceID }}")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when requirePrivateDNSName is false with the private-dns node name convention", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--require-private-dns-name=false")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should succeed when requirePrivateDNSName is false with the resource-name node name convention", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--require-private-dns-name=false", "--node-name-convention", "resource-name")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should fail when instanceTypeMaxStaleness is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-type-max-staleness", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotPriceTTL is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-price-ttl", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotPriceRefreshInterval is less than a minute", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-price-refresh-interval", "30s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotPriceMaxStaleness is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-price-max-staleness", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when amiDefaultOwners is empty", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--ami-default-owners", "")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when amiDefaultOwners contains a wildcard", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--ami-default-owners", "self,*")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when allowedAMIOwners contains an owner alias", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--allowed-ami-owners", "123456789012,amazon")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when enabledLabels contains a label that isn't registered", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--enabled-labels", "karpenter.k8s.aws/instance-flex")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when disabledLabels contains a label that isn't registered", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--disabled-labels", "topology.kubernetes.io/zone")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when a label is both enabled and disabled", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--enabled-labels", "karpenter.k8s.aws/instance-smt-supported", "--disabled-labels", "karpenter.k8s.aws/instance-smt-supported")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when disabledLabels contains a required label", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--disabled-labels", "karpenter.k8s.aws/instance-gpu-count")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when amiDeprecationWindow is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--ami-deprecation-window", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionQueueWaitTime is longer than 20 seconds", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue-wait-time", "21s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionQueueWaitTime is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue-wait-time", "-1s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an awsOperationTimeouts entry isn't an operation=duration pair", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-operation-timeouts", "DescribeImages")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an awsOperationTimeouts entry is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-operation-timeouts", "DescribeImages=-1s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when the ReceiveMessage timeout doesn't outlast interruptionQueueWaitTime", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue-wait-time", "20s", "--aws-operation-timeouts", "ReceiveMessage=20s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionQueueParallelism is less than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue-parallelism", "0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionQueueMaxParseAttempts is less than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue-max-parse-attempts", "0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotInterruptionPenalty is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-interruption-penalty", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotUnfulfillableCapacityTTL is zero", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-unfulfillable-capacity-ttl", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when onDemandInsufficientCapacityTTL is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--on-demand-insufficient-capacity-ttl", "-1m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when reservationCapacityExceededTTL is zero", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--reservation-capacity-exceeded-ttl", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when snapshotGCRetention is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--snapshot-gc-retention", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when launchTemplateGCGracePeriod is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--launch-template-gc-grace-period", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceTypeCacheMaxKeys is less than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-type-cache-max-keys", "0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when onDemandAllocationStrategy is not a supported strategy", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--on-demand-allocation-strategy", "capacity-optimized")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an instance type glob is malformed", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-type-denylist", "p5.[")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceProfilePath doesn't begin and end with a slash", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-profile-path", "karpenter")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when cloudWatchMetricsNamespace is reserved for AWS services", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--cloudwatch-metrics-namespace", "AWS/EC2")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceProfilePermissionsBoundary isn't the ARN of an IAM policy", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-profile-permissions-boundary", "arn:aws:iam::123456789012:role/boundary")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("AWSOperationTimeout", func() {
+		BeforeEach(func() {
+			opts.AddFlags(fs)
+		})
+		It("should default timeouts by operation", func() {
+			Expect(opts.Parse(fs, "--cluster-name", "test-cluster")).To(Succeed())
+			Expect(opts.AWSOperationTimeout("DescribeImages")).To(Equal(30 * time.Second))
+			Expect(opts.AWSOperationTimeout("GetProducts")).To(Equal(30 * time.Second))
+			Expect(opts.AWSOperationTimeout("ListInstanceProfilesForRole")).To(Equal(30 * time.Second))
+			Expect(opts.AWSOperationTimeout("CreateFleet")).To(Equal(2 * time.Minute))
+			Expect(opts.AWSOperationTimeout("TerminateInstances")).To(Equal(time.Minute))
+		})
+		It("should prefer configured timeouts over the defaults", func() {
+			Expect(opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-operation-timeouts", "DescribeImages=10s,CreateFleet=0s")).To(Succeed())
+			Expect(opts.AWSOperationTimeout("DescribeImages")).To(Equal(10 * time.Second))
+			Expect(opts.AWSOperationTimeout("CreateFleet")).To(BeZero())
+			Expect(opts.AWSOperationTimeout("DescribeSubnets")).To(Equal(30 * time.Second))
+		})
+	})
+	Context("NodeNamedByInstanceID", func() {
+		BeforeEach(func() {
+			opts.AddFlags(fs)
+		})
+		It("should be true when the template renders the instance ID alone", func() {
+			Expect(opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-convention", "template", "--node-name-template", "{{ .InstanceID }}")).To(Succeed())
+			Expect(opts.NodeNamedByInstanceID()).To(BeTrue())
+		})
+		It("should be false when the template renders more than the instance ID", func() {
+			Expect(opts.Parse(fs, "--cluster-name", "test-cluster", "--node-name-convention", "template")).To(Succeed())
+			Expect(opts.NodeNamedByInstanceID()).To(BeFalse())
+		})
 	})
 })
 
@@ -142,8 +590,59 @@ func expectOptionsEqual(optsA *options.Options, optsB *options.Options) {
 	Expect(optsA.ClusterCABundle).To(Equal(optsB.ClusterCABundle))
 	Expect(optsA.ClusterName).To(Equal(optsB.ClusterName))
 	Expect(optsA.ClusterEndpoint).To(Equal(optsB.ClusterEndpoint))
+	Expect(optsA.ClusterAutoscalerCompatibility).To(Equal(optsB.ClusterAutoscalerCompatibility))
 	Expect(optsA.IsolatedVPC).To(Equal(optsB.IsolatedVPC))
+	Expect(optsA.PricingOverrideFile).To(Equal(optsB.PricingOverrideFile))
+	Expect(optsA.SpotPriceTTL).To(Equal(optsB.SpotPriceTTL))
+	Expect(optsA.SpotPriceRefreshInterval).To(Equal(optsB.SpotPriceRefreshInterval))
+	Expect(optsA.SpotPriceMaxStaleness).To(Equal(optsB.SpotPriceMaxStaleness))
 	Expect(optsA.VMMemoryOverheadPercent).To(Equal(optsB.VMMemoryOverheadPercent))
+	Expect(optsA.GravitonCMAReservedMemoryMiB).To(Equal(optsB.GravitonCMAReservedMemoryMiB))
+	Expect(optsA.InstanceStoreKubeReservedStorage).To(Equal(optsB.InstanceStoreKubeReservedStorage))
+	Expect(optsA.InstanceStoreEvictionPercent).To(Equal(optsB.InstanceStoreEvictionPercent))
 	Expect(optsA.InterruptionQueue).To(Equal(optsB.InterruptionQueue))
 	Expect(optsA.ReservedENIs).To(Equal(optsB.ReservedENIs))
+	Expect(optsA.ENIPrefixDelegation).To(Equal(optsB.ENIPrefixDelegation))
+	Expect(optsA.SnapshotGC).To(Equal(optsB.SnapshotGC))
+	Expect(optsA.SnapshotGCRetention).To(Equal(optsB.SnapshotGCRetention))
+	Expect(optsA.SnapshotGCDryRun).To(Equal(optsB.SnapshotGCDryRun))
+	Expect(optsA.OnDemandAllocationStrategy).To(Equal(optsB.OnDemandAllocationStrategy))
+	Expect(optsA.TaintTags).To(Equal(optsB.TaintTags))
+	Expect(optsA.InstanceTypeAllowlist).To(Equal(optsB.InstanceTypeAllowlist))
+	Expect(optsA.InstanceTypeDenylist).To(Equal(optsB.InstanceTypeDenylist))
+	Expect(optsA.MinLaunchInstanceTypes).To(Equal(optsB.MinLaunchInstanceTypes))
+	Expect(optsA.RaiseUndersizedRootVolumes).To(Equal(optsB.RaiseUndersizedRootVolumes))
+	Expect(optsA.NetworkBandwidthResource).To(Equal(optsB.NetworkBandwidthResource))
+	Expect(optsA.NodeClassStatusAWSQPS).To(Equal(optsB.NodeClassStatusAWSQPS))
+	Expect(optsA.NodeClassStatusAWSBurst).To(Equal(optsB.NodeClassStatusAWSBurst))
+	Expect(optsA.SubnetFreeIPThreshold).To(Equal(optsB.SubnetFreeIPThreshold))
+	Expect(optsA.NodeNameConvention).To(Equal(optsB.NodeNameConvention))
+	Expect(optsA.NodeNameTemplate).To(Equal(optsB.NodeNameTemplate))
+	Expect(optsA.SubnetClusterTagging).To(Equal(optsB.SubnetClusterTagging))
+	Expect(optsA.SubnetClusterTaggingDryRun).To(Equal(optsB.SubnetClusterTaggingDryRun))
+	Expect(optsA.InstanceTypeMaxStaleness).To(Equal(optsB.InstanceTypeMaxStaleness))
+	Expect(optsA.InstanceTypeCacheMaxKeys).To(Equal(optsB.InstanceTypeCacheMaxKeys))
+	Expect(optsA.AMIDefaultOwners).To(Equal(optsB.AMIDefaultOwners))
+	Expect(optsA.AllowedAMIOwners).To(Equal(optsB.AllowedAMIOwners))
+	Expect(optsA.EnabledLabels).To(Equal(optsB.EnabledLabels))
+	Expect(optsA.DisabledLabels).To(Equal(optsB.DisabledLabels))
+	Expect(optsA.AMIDeprecationWindow).To(Equal(optsB.AMIDeprecationWindow))
+	Expect(optsA.RebalanceRecommendations).To(Equal(optsB.RebalanceRecommendations))
+	Expect(optsA.InterruptionRebalanceAction).To(Equal(optsB.InterruptionRebalanceAction))
+	Expect(optsA.InterruptionQueueWaitTime).To(Equal(optsB.InterruptionQueueWaitTime))
+	Expect(optsA.InterruptionQueueParallelism).To(Equal(optsB.InterruptionQueueParallelism))
+	Expect(optsA.InterruptionQueueMaxParseAttempts).To(Equal(optsB.InterruptionQueueMaxParseAttempts))
+	Expect(optsA.InterruptionQueueRequired).To(Equal(optsB.InterruptionQueueRequired))
+	Expect(optsA.SpotInterruptionPenalty).To(Equal(optsB.SpotInterruptionPenalty))
+	Expect(optsA.SpotUnfulfillableCapacityTTL).To(Equal(optsB.SpotUnfulfillableCapacityTTL))
+	Expect(optsA.OnDemandInsufficientCapacityTTL).To(Equal(optsB.OnDemandInsufficientCapacityTTL))
+	Expect(optsA.ReservationCapacityExceededTTL).To(Equal(optsB.ReservationCapacityExceededTTL))
+	Expect(optsA.RequirePrivateDNSName).To(Equal(optsB.RequirePrivateDNSName))
+	Expect(optsA.ExtraNodeLabels).To(Equal(optsB.ExtraNodeLabels))
+	Expect(optsA.ForceInstanceProfileRevalidation).To(Equal(optsB.ForceInstanceProfileRevalidation))
+	Expect(optsA.InstanceProfilePath).To(Equal(optsB.InstanceProfilePath))
+	Expect(optsA.InstanceProfilePermissionsBoundary).To(Equal(optsB.InstanceProfilePermissionsBoundary))
+	Expect(optsA.LaunchTemplateGCGracePeriod).To(Equal(optsB.LaunchTemplateGCGracePeriod))
+	Expect(optsA.AWSOperationTimeouts).To(Equal(optsB.AWSOperationTimeouts))
+	Expect(optsA.CloudWatchMetricsNamespace).To(Equal(optsB.CloudWatchMetricsNamespace))
 }
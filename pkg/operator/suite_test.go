@@ -17,15 +17,25 @@ package operator_test
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/pricing"
 	"github.com/samber/lo"
 
 	"sigs.k8s.io/karpenter/pkg/operator/scheme"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	awscontext "github.com/aws/karpenter-provider-aws/pkg/operator"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
@@ -103,3 +113,149 @@ var _ = Describe("Operator", func() {
 		Expect(err).To(HaveOccurred())
 	})
 })
+
+var _ = Describe("Dependencies", func() {
+	var ec2api *fake.EC2API
+	var pricingAPI *fake.PricingAPI
+	var iamapi *fake.IAMAPI
+	var sqsapi *fake.SQSAPI
+	var dependencies *awscontext.Dependencies
+
+	BeforeEach(func() {
+		ec2api = fake.NewEC2API()
+		pricingAPI = &fake.PricingAPI{}
+		pricingAPI.GetProductsOutput.Set(&pricing.GetProductsOutput{PriceList: []aws.JSONValue{fake.NewOnDemandPrice("c5.large", 1.23)}})
+		iamapi = fake.NewIAMAPI()
+		sqsapi = &fake.SQSAPI{}
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr("test-cluster")}))
+		dependencies = awscontext.NewDependencies(awscontext.StartupDependencies(ctx, ec2api, pricingAPI, true, iamapi, sqsapi)...)
+	})
+	It("should start without degraded dependencies when every dependency can be reached", func() {
+		Expect(dependencies.Check(ctx)).To(Succeed())
+		Expect(dependencies.Degraded()).To(BeEmpty())
+	})
+	It("should fail to start when EC2 can't be reached", func() {
+		ec2api.NextError.Set(errors.New("test error"))
+		Expect(dependencies.Check(ctx)).To(MatchError(ContainSubstring("checking required dependency ec2")))
+	})
+	DescribeTable("should start degraded when an optional dependency can't be reached",
+		func(dependency string, fail func(error)) {
+			// The dependency can't be reached on startup or when it's first rechecked
+			fail(errors.New("test error"))
+			Expect(dependencies.Check(ctx)).To(Succeed())
+			Expect(dependencies.Degraded()).To(ConsistOf(dependency))
+			Expect(dependencies.IsDegraded(dependency)).To(BeTrue())
+
+			dependencies.Recheck(ctx)
+			Expect(dependencies.Degraded()).To(ConsistOf(dependency))
+
+			dependencies.Recheck(ctx)
+			Expect(dependencies.Degraded()).To(BeEmpty())
+		},
+		Entry("pricing", awscontext.DependencyPricing, func(err error) { pricingAPI.NextError.Set(err, fake.MaxCalls(2)) }),
+		Entry("iam", awscontext.DependencyIAM, func(err error) { iamapi.GetInstanceProfileBehavior.Error.Set(err, fake.MaxCalls(2)) }),
+		Entry("sqs", awscontext.DependencySQS, func(err error) { sqsapi.GetQueueURLBehavior.Error.Set(err, fake.MaxCalls(2)) }),
+	)
+	It("should treat a missing instance profile as IAM being reachable", func() {
+		Expect(awscontext.CheckIAMConnectivity(ctx, iamapi)).To(Succeed())
+		iamapi.GetInstanceProfileBehavior.Error.Set(awserr.New("AccessDenied", "test error", nil))
+		Expect(awscontext.CheckIAMConnectivity(ctx, iamapi)).ToNot(Succeed())
+	})
+	It("should only depend on the pricing API where it's available", func() {
+		names := lo.Map(awscontext.StartupDependencies(ctx, ec2api, pricingAPI, false, iamapi, sqsapi), func(d awscontext.Dependency, _ int) string { return d.Name })
+		Expect(names).ToNot(ContainElement(awscontext.DependencyPricing))
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IsolatedVPC: lo.ToPtr(true)}))
+		names = lo.Map(awscontext.StartupDependencies(ctx, ec2api, pricingAPI, true, iamapi, sqsapi), func(d awscontext.Dependency, _ int) string { return d.Name })
+		Expect(names).ToNot(ContainElement(awscontext.DependencyPricing))
+	})
+	It("should only depend on SQS when an interruption queue is configured", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr("")}))
+		names := lo.Map(awscontext.StartupDependencies(ctx, ec2api, pricingAPI, true, iamapi, sqsapi), func(d awscontext.Dependency, _ int) string { return d.Name })
+		Expect(names).To(ConsistOf(awscontext.DependencyEC2, awscontext.DependencyPricing, awscontext.DependencyIAM))
+	})
+})
+
+var _ = Describe("OperationTimeouts", func() {
+	var server *httptest.Server
+	var unblock chan struct{}
+	var ec2api *ec2.EC2
+
+	BeforeEach(func() {
+		// DescribeImages hangs until the test ends while every other operation responds immediately
+		unblock = make(chan struct{})
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ParseForm()).To(Succeed())
+			if r.Form.Get("Action") == "DescribeImages" {
+				select {
+				case <-unblock:
+				case <-r.Context().Done():
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write([]byte(`<DescribeSubnetsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><subnetSet/></DescribeSubnetsResponse>`))
+		}))
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			AWSOperationTimeouts: map[string]time.Duration{"DescribeImages": 200 * time.Millisecond},
+		}))
+		ec2api = ec2.New(awscontext.WithOperationTimeouts(ctx, session.Must(session.NewSession(&aws.Config{
+			Region:      lo.ToPtr("us-west-2"),
+			Endpoint:    lo.ToPtr(server.URL),
+			Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		}))))
+	})
+	AfterEach(func() {
+		close(unblock)
+		server.Close()
+	})
+
+	It("should fail calls that exceed the timeout of their operation with a retryable error", func() {
+		start := time.Now()
+		_, err := ec2api.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{})
+		Expect(err).To(HaveOccurred())
+		Expect(awserrors.IsRequestTimeout(err)).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("~", 200*time.Millisecond, 150*time.Millisecond))
+
+		metric, ok := FindMetricWithLabelValues("karpenter_aws_operation_timeouts_total", map[string]string{"service": "ec2", "operation": "DescribeImages"})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetCounter().GetValue()).To(BeNumerically(">=", 1))
+	})
+	It("should not block calls to other operations while a call is hanging", func() {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := ec2api.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{})
+			errs <- err
+		}()
+		start := time.Now()
+		_, err := ec2api.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 200*time.Millisecond))
+		Eventually(errs).Should(Receive(HaveOccurred()))
+	})
+	It("should not count calls cancelled by the caller as timeouts", func() {
+		metric, _ := FindMetricWithLabelValues("karpenter_aws_operation_timeouts_total", map[string]string{"service": "ec2", "operation": "DescribeImages"})
+		before := metric.GetCounter().GetValue()
+		cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := ec2api.DescribeImagesWithContext(cancelCtx, &ec2.DescribeImagesInput{})
+		Expect(awserrors.IsRequestTimeout(err)).To(BeTrue())
+		metric, _ = FindMetricWithLabelValues("karpenter_aws_operation_timeouts_total", map[string]string{"service": "ec2", "operation": "DescribeImages"})
+		Expect(metric.GetCounter().GetValue()).To(Equal(before))
+	})
+	It("should not set a deadline when the timeout of the operation is 0", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			AWSOperationTimeouts: map[string]time.Duration{"DescribeImages": 0},
+		}))
+		ec2api = ec2.New(awscontext.WithOperationTimeouts(ctx, session.Must(session.NewSession(&aws.Config{
+			Region:      lo.ToPtr("us-west-2"),
+			Endpoint:    lo.ToPtr(server.URL),
+			Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		}))))
+		errs := make(chan error, 1)
+		go func() {
+			_, err := ec2api.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{})
+			errs <- err
+		}()
+		Consistently(errs, 500*time.Millisecond).ShouldNot(Receive())
+	})
+})
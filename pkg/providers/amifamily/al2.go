@@ -86,6 +86,8 @@ func (a AL2) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints []
 			CABundle:            caBundle,
 			CustomUserData:      customUserData,
 			InstanceStorePolicy: instanceStorePolicy,
+			InstanceStore:       a.Options.InstanceStore,
+			NodeName:            a.Options.NodeName,
 		},
 	}
 }
@@ -62,6 +62,8 @@ func (a AL2023) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints
 			AWSENILimitedPodDensity: false,
 			CustomUserData:          customUserData,
 			InstanceStorePolicy:     instanceStorePolicy,
+			InstanceStore:           a.Options.InstanceStore,
+			NodeName:                a.Options.NodeName,
 		},
 	}
 }
@@ -16,12 +16,16 @@ package amifamily
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -30,21 +34,29 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"knative.dev/pkg/logging"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
 )
 
+// amiIDPattern matches the values accepted for an AMI selector term's id
+var amiIDPattern = regexp.MustCompile(`^ami-[0-9a-z]+$`)
+
 type Provider interface {
 	Get(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, options *Options) (AMIs, error)
+	List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, options *Options) (AMIs, error)
 }
 
 type DefaultProvider struct {
+	sync.Mutex
 	cache           *cache.Cache
 	ssm             ssmiface.SSMAPI
 	ec2api          ec2iface.EC2API
@@ -53,10 +65,14 @@ type DefaultProvider struct {
 }
 
 type AMI struct {
-	Name         string
-	AmiID        string
-	CreationDate string
-	Requirements scheduling.Requirements
+	Name             string
+	AmiID            string
+	OwnerID          string
+	CreationDate     string
+	DeprecationTime  string
+	Requirements     scheduling.Requirements
+	RootDeviceName   string
+	RootSnapshotSize *resource.Quantity
 }
 
 type AMIs []AMI
@@ -105,6 +121,37 @@ func (a AMIs) MapToInstanceTypes(instanceTypes []*cloudprovider.InstanceType) ma
 	return amiIDs
 }
 
+// PinnedAMIs returns the AMIs in the EC2NodeClass status while newly resolved AMIs are held by its AMI stabilization,
+// or while an SSM parameter in its AMI selector terms can't be resolved. Launches and drift use the pinned AMIs rather
+// than resolving AMIs until the pending AMIs are adopted or the parameter is fixed.
+func PinnedAMIs(nodeClass *v1beta1.EC2NodeClass) (AMIs, bool) {
+	if len(nodeClass.Status.AMIs) == 0 {
+		return nil, false
+	}
+	if nodeClass.Spec.AMIStabilization != nil && len(nodeClass.Status.PendingAMIs) > 0 {
+		return StatusAMIs(nodeClass), true
+	}
+	if cond := nodeClass.StatusConditions().GetCondition(v1beta1.ConditionTypeAMIsReady); cond != nil && cond.IsFalse() &&
+		lo.Contains([]string{SSMParameterNotFoundReason, SSMParameterInvalidReason}, cond.Reason) {
+		return StatusAMIs(nodeClass), true
+	}
+	return nil, false
+}
+
+// StatusAMIs returns the AMIs in the EC2NodeClass status
+func StatusAMIs(nodeClass *v1beta1.EC2NodeClass) AMIs {
+	return lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) AMI {
+		return AMI{
+			Name:             ami.Name,
+			AmiID:            ami.ID,
+			OwnerID:          ami.Owner,
+			Requirements:     scheduling.NewNodeSelectorRequirementsWithMinValues(ami.Requirements...),
+			RootDeviceName:   ami.RootDeviceName,
+			RootSnapshotSize: ami.RootSnapshotSize,
+		}
+	})
+}
+
 func NewDefaultProvider(versionProvider version.Provider, ssm ssmiface.SSMAPI, ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		cache:           cache,
@@ -115,8 +162,18 @@ func NewDefaultProvider(versionProvider version.Provider, ssm ssmiface.SSMAPI, e
 	}
 }
 
-// Get Returning a list of AMIs with its associated requirements
+// Get Returning a list of AMIs with its associated requirements, excluding the AMIs whose owner isn't allowed
 func (p *DefaultProvider) Get(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, options *Options) (AMIs, error) {
+	amis, err := p.List(ctx, nodeClass, options)
+	if err != nil {
+		return nil, err
+	}
+	allowed, _ := FilterAllowedOwners(ctx, amis)
+	return allowed, nil
+}
+
+// List returns every AMI that the EC2NodeClass resolves to, including the AMIs whose owner isn't allowed
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, options *Options) (AMIs, error) {
 	var err error
 	var amis AMIs
 	if len(nodeClass.Spec.AMISelectorTerms) == 0 {
@@ -125,7 +182,11 @@ func (p *DefaultProvider) Get(ctx context.Context, nodeClass *v1beta1.EC2NodeCla
 			return nil, err
 		}
 	} else {
-		amis, err = p.getAMIs(ctx, nodeClass.Spec.AMISelectorTerms)
+		terms, err := p.resolveSSMParameterTerms(ctx, nodeClass.Spec.AMISelectorTerms)
+		if err != nil {
+			return nil, err
+		}
+		amis, err = p.getAMIs(ctx, terms)
 		if err != nil {
 			return nil, err
 		}
@@ -154,16 +215,21 @@ func (p *DefaultProvider) getDefaultAMIs(ctx context.Context, nodeClass *v1beta1
 			res = append(res, AMI{AmiID: id, Requirements: ami.Requirements})
 		}
 	}
-	// Resolve Name and CreationDate information into the DefaultAMIs
+	// Resolve Name, CreationDate and DeprecationTime information into the DefaultAMIs
 	if err = p.ec2api.DescribeImagesPagesWithContext(ctx, &ec2.DescribeImagesInput{
-		Filters:    []*ec2.Filter{{Name: aws.String("image-id"), Values: aws.StringSlice(lo.Map(res, func(a AMI, _ int) string { return a.AmiID }))}},
-		MaxResults: aws.Int64(500),
+		Filters: []*ec2.Filter{{Name: aws.String("image-id"), Values: aws.StringSlice(lo.Map(res, func(a AMI, _ int) string { return a.AmiID }))}},
+		// The SSM parameters can still point to an AMI once it's deprecated
+		IncludeDeprecated: aws.Bool(true),
+		MaxResults:        aws.Int64(500),
 	}, func(page *ec2.DescribeImagesOutput, _ bool) bool {
 		for i := range page.Images {
 			for j := range res {
 				if res[j].AmiID == aws.StringValue(page.Images[i].ImageId) {
 					res[j].Name = aws.StringValue(page.Images[i].Name)
+					res[j].OwnerID = aws.StringValue(page.Images[i].OwnerId)
 					res[j].CreationDate = aws.StringValue(page.Images[i].CreationDate)
+					res[j].DeprecationTime = aws.StringValue(page.Images[i].DeprecationTime)
+					res[j].RootDeviceName, res[j].RootSnapshotSize = rootVolume(page.Images[i])
 				}
 			}
 		}
@@ -184,8 +250,67 @@ func (p *DefaultProvider) resolveSSMParameter(ctx context.Context, ssmQuery stri
 	return ami, nil
 }
 
+const (
+	SSMParameterNotFoundReason = "SSMParameterNotFound"
+	SSMParameterInvalidReason  = "SSMParameterInvalid"
+)
+
+// SSMParameterError is returned when an SSM parameter in the AMI selector terms is missing or doesn't contain an AMI ID
+type SSMParameterError struct {
+	Parameter string
+	// Reason is a CamelCase description of the failure, suitable for a status condition
+	Reason string
+	err    error
+}
+
+func (e *SSMParameterError) Error() string {
+	return fmt.Sprintf("resolving ssm parameter %q, %s", e.Parameter, e.err)
+}
+
+func (e *SSMParameterError) Unwrap() error {
+	return e.err
+}
+
+// resolveSSMParameterTerms replaces the SSM parameter terms with ID terms for the AMIs that the parameters contain, so
+// that they're resolved through DescribeImages like any other term
+func (p *DefaultProvider) resolveSSMParameterTerms(ctx context.Context, terms []v1beta1.AMISelectorTerm) ([]v1beta1.AMISelectorTerm, error) {
+	if !lo.ContainsBy(terms, func(t v1beta1.AMISelectorTerm) bool { return t.SSMParameter != "" }) {
+		return terms, nil
+	}
+	// Concurrent reconciles of EC2NodeClasses referencing the same parameter wait on the first lookup rather than
+	// all calling SSM
+	p.Lock()
+	defer p.Unlock()
+	resolved := make([]v1beta1.AMISelectorTerm, 0, len(terms))
+	for _, term := range terms {
+		if term.SSMParameter == "" {
+			resolved = append(resolved, term)
+			continue
+		}
+		key := fmt.Sprintf("ssm/%s", term.SSMParameter)
+		if id, ok := p.cache.Get(key); ok {
+			resolved = append(resolved, v1beta1.AMISelectorTerm{ID: id.(string)})
+			continue
+		}
+		id, err := p.resolveSSMParameter(ctx, term.SSMParameter)
+		if err != nil {
+			var aerr awserr.Error
+			if errors.As(err, &aerr) && aerr.Code() == ssm.ErrCodeParameterNotFound {
+				return nil, &SSMParameterError{Parameter: term.SSMParameter, Reason: SSMParameterNotFoundReason, err: err}
+			}
+			return nil, err
+		}
+		if !amiIDPattern.MatchString(id) {
+			return nil, &SSMParameterError{Parameter: term.SSMParameter, Reason: SSMParameterInvalidReason, err: fmt.Errorf("value %q is not an ami id", id)}
+		}
+		p.cache.SetDefault(key, id)
+		resolved = append(resolved, v1beta1.AMISelectorTerm{ID: id})
+	}
+	return resolved, nil
+}
+
 func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelectorTerm) (AMIs, error) {
-	filterAndOwnerSets := GetFilterAndOwnerSets(terms)
+	filterAndOwnerSets := GetFilterAndOwnerSets(terms, options.FromContext(ctx).AMIDefaultOwners)
 	hash, err := hashstructure.Hash(filterAndOwnerSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
 		return nil, err
@@ -218,11 +343,16 @@ func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelect
 						continue
 					}
 				}
+				rootDeviceName, rootSnapshotSize := rootVolume(page.Images[i])
 				images[reqsHash] = AMI{
-					Name:         lo.FromPtr(page.Images[i].Name),
-					AmiID:        lo.FromPtr(page.Images[i].ImageId),
-					CreationDate: lo.FromPtr(page.Images[i].CreationDate),
-					Requirements: reqs,
+					Name:             lo.FromPtr(page.Images[i].Name),
+					AmiID:            lo.FromPtr(page.Images[i].ImageId),
+					OwnerID:          lo.FromPtr(page.Images[i].OwnerId),
+					CreationDate:     lo.FromPtr(page.Images[i].CreationDate),
+					DeprecationTime:  lo.FromPtr(page.Images[i].DeprecationTime),
+					Requirements:     reqs,
+					RootDeviceName:   rootDeviceName,
+					RootSnapshotSize: rootSnapshotSize,
 				}
 			}
 			return true
@@ -234,32 +364,65 @@ func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelect
 	return lo.Values(images), nil
 }
 
+// FilterAllowedOwners splits the AMIs into those owned by an account in the allowed-ami-owners and those that aren't.
+// Every AMI is allowed if allowed-ami-owners isn't set. The owner is the one DescribeImages reported, so an AMI whose
+// owner wasn't reported is never allowed.
+func FilterAllowedOwners(ctx context.Context, amis AMIs) (allowed AMIs, disallowed AMIs) {
+	owners := options.FromContext(ctx).AllowedAMIOwners
+	if len(owners) == 0 {
+		return amis, nil
+	}
+	for _, ami := range amis {
+		if lo.Contains(owners, ami.OwnerID) {
+			allowed = append(allowed, ami)
+		} else {
+			disallowed = append(disallowed, ami)
+		}
+	}
+	return allowed, disallowed
+}
+
+// WildcardOwner is the AMI selector term owner that searches the images of every owner, including public images
+const WildcardOwner = "*"
+
+// UsesWildcardOwner returns whether any of the AMI selector terms search the images of every owner
+func UsesWildcardOwner(terms []v1beta1.AMISelectorTerm) bool {
+	return lo.ContainsBy(terms, func(term v1beta1.AMISelectorTerm) bool { return term.Owner == WildcardOwner })
+}
+
 type FiltersAndOwners struct {
 	Filters []*ec2.Filter
 	Owners  []string
 }
 
-func GetFilterAndOwnerSets(terms []v1beta1.AMISelectorTerm) (res []FiltersAndOwners) {
+// GetFilterAndOwnerSets converts the AMI selector terms into DescribeImages filters and owners. Terms that select by
+// name or tags without an owner are restricted to the default owners, and terms with the wildcard owner search every
+// image the account can launch, including public images.
+func GetFilterAndOwnerSets(terms []v1beta1.AMISelectorTerm, defaultOwners []string) (res []FiltersAndOwners) {
 	idFilter := &ec2.Filter{Name: aws.String("image-id")}
 	for _, term := range terms {
 		switch {
 		case term.ID != "":
 			idFilter.Values = append(idFilter.Values, aws.String(term.ID))
 		default:
-			elem := FiltersAndOwners{
-				Owners: lo.Ternary(term.Owner != "", []string{term.Owner}, []string{}),
+			elem := FiltersAndOwners{}
+			switch {
+			case term.Owner == WildcardOwner:
+				elem.Owners = []string{}
+			case term.Owner != "":
+				elem.Owners = []string{term.Owner}
+			// Default owners to ensure Karpenter only discovers cross-account AMIs if the user specifically allows it.
+			// Removing this default would cause Karpenter to discover publicly shared AMIs passing the name or tag filters.
+			case term.Name != "" || len(term.Tags) > 0:
+				elem.Owners = append([]string{}, defaultOwners...)
+			default:
+				elem.Owners = []string{}
 			}
 			if term.Name != "" {
-				// Default owners to self,amazon to ensure Karpenter only discovers cross-account AMIs if the user specifically allows it.
-				// Removing this default would cause Karpenter to discover publicly shared AMIs passing the name filter.
-				elem = FiltersAndOwners{
-					Owners: lo.Ternary(term.Owner != "", []string{term.Owner}, []string{"self", "amazon"}),
-				}
 				elem.Filters = append(elem.Filters, &ec2.Filter{
 					Name:   aws.String("name"),
 					Values: aws.StringSlice([]string{term.Name}),
 				})
-
 			}
 			for k, v := range term.Tags {
 				if v == "*" {
@@ -293,3 +456,15 @@ func (p *DefaultProvider) getRequirementsFromImage(ec2Image *ec2.Image) scheduli
 	requirements.Add(scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, architecture))
 	return requirements
 }
+
+// rootVolume returns the device name of the image's root volume along with the size of the snapshot backing it, if any
+func rootVolume(ec2Image *ec2.Image) (string, *resource.Quantity) {
+	rootDeviceName := aws.StringValue(ec2Image.RootDeviceName)
+	blockDeviceMapping, ok := lo.Find(ec2Image.BlockDeviceMappings, func(bdm *ec2.BlockDeviceMapping) bool {
+		return aws.StringValue(bdm.DeviceName) == rootDeviceName
+	})
+	if !ok || blockDeviceMapping.Ebs == nil || blockDeviceMapping.Ebs.VolumeSize == nil {
+		return rootDeviceName, nil
+	}
+	return rootDeviceName, resources.Quantity(fmt.Sprintf("%dGi", aws.Int64Value(blockDeviceMapping.Ebs.VolumeSize)))
+}
@@ -0,0 +1,127 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// UndersizedRootVolume is an AMI whose root snapshot is larger than the root volume it would be launched with
+type UndersizedRootVolume struct {
+	AMIID       string
+	VolumeSize  resource.Quantity
+	MinimumSize resource.Quantity
+}
+
+func (u UndersizedRootVolume) String() string {
+	return fmt.Sprintf("ami %q requires a root volume of at least %s, but %s is configured", u.AMIID, u.MinimumSize.String(), u.VolumeSize.String())
+}
+
+// UndersizedRootVolumes returns the AMIs whose root snapshot is larger than their root volume in the block device
+// mappings. Instances can't be launched from these AMIs until the root volume is at least as large as the snapshot.
+func UndersizedRootVolumes(blockDeviceMappings []*v1beta1.BlockDeviceMapping, amis AMIs) []UndersizedRootVolume {
+	var undersized []UndersizedRootVolume
+	for _, ami := range amis {
+		blockDeviceMapping, ok := rootBlockDeviceMapping(blockDeviceMappings, ami)
+		if !ok || ami.RootSnapshotSize == nil || blockDeviceMapping.EBS == nil || blockDeviceMapping.EBS.VolumeSize == nil {
+			continue
+		}
+		if blockDeviceMapping.EBS.VolumeSize.Cmp(*ami.RootSnapshotSize) < 0 {
+			undersized = append(undersized, UndersizedRootVolume{
+				AMIID:       ami.AmiID,
+				VolumeSize:  *blockDeviceMapping.EBS.VolumeSize,
+				MinimumSize: *ami.RootSnapshotSize,
+			})
+		}
+	}
+	return undersized
+}
+
+// BlockDeviceMappings returns the block device mappings that the EC2NodeClass's instances are launched with from the
+// passed AMIs. If undersized root volumes are raised, a root volume smaller than the root snapshot of any of the AMIs is
+// raised to the largest of those snapshots so that every AMI launches with, and reports capacity for, the same size.
+func BlockDeviceMappings(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, amiFamily AMIFamily, amis AMIs) []*v1beta1.BlockDeviceMapping {
+	if !options.FromContext(ctx).RaiseUndersizedRootVolumes {
+		return nodeClass.Spec.BlockDeviceMappings
+	}
+	blockDeviceMappings := lo.Ternary(len(nodeClass.Spec.BlockDeviceMappings) > 0, nodeClass.Spec.BlockDeviceMappings, amiFamily.DefaultBlockDeviceMappings())
+	undersized := UndersizedRootVolumes(blockDeviceMappings, amis)
+	if len(undersized) == 0 {
+		return nodeClass.Spec.BlockDeviceMappings
+	}
+	raised := lo.Map(blockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping, _ int) *v1beta1.BlockDeviceMapping { return bdm.DeepCopy() })
+	for _, ami := range amis {
+		if !lo.ContainsBy(undersized, func(u UndersizedRootVolume) bool { return u.AMIID == ami.AmiID }) {
+			continue
+		}
+		// The AMI is only undersized if its root block device mapping exists and has a volume size
+		blockDeviceMapping, _ := rootBlockDeviceMapping(raised, ami)
+		if blockDeviceMapping.EBS.VolumeSize.Cmp(*ami.RootSnapshotSize) < 0 {
+			blockDeviceMapping.EBS.VolumeSize = lo.ToPtr(ami.RootSnapshotSize.DeepCopy())
+		}
+	}
+	return raised
+}
+
+// WithBlockDeviceDefaults returns the block device mappings with the block device defaults applied to the EBS volumes
+// that don't set them. IOPS and throughput are only applied to volume types that support them, and the KMS key is only
+// applied to encrypted volumes, so that the defaults never make a block device mapping invalid.
+func WithBlockDeviceDefaults(blockDeviceMappings []*v1beta1.BlockDeviceMapping, defaults *v1beta1.BlockDeviceDefaults) []*v1beta1.BlockDeviceMapping {
+	if defaults == nil {
+		return blockDeviceMappings
+	}
+	return lo.Map(blockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping, _ int) *v1beta1.BlockDeviceMapping {
+		bdm = bdm.DeepCopy()
+		if bdm.EBS == nil {
+			return bdm
+		}
+		if bdm.EBS.VolumeType == nil && defaults.VolumeType != nil {
+			bdm.EBS.VolumeType = lo.ToPtr(*defaults.VolumeType)
+		}
+		volumeType := lo.FromPtr(bdm.EBS.VolumeType)
+		if bdm.EBS.IOPS == nil && defaults.IOPS != nil && lo.Contains([]string{ec2.VolumeTypeGp3, ec2.VolumeTypeIo1, ec2.VolumeTypeIo2}, volumeType) {
+			bdm.EBS.IOPS = lo.ToPtr(*defaults.IOPS)
+		}
+		if bdm.EBS.Throughput == nil && defaults.Throughput != nil && volumeType == ec2.VolumeTypeGp3 {
+			bdm.EBS.Throughput = lo.ToPtr(*defaults.Throughput)
+		}
+		if bdm.EBS.Encrypted == nil && defaults.Encrypted != nil {
+			bdm.EBS.Encrypted = lo.ToPtr(*defaults.Encrypted)
+		}
+		if bdm.EBS.KMSKeyID == nil && defaults.KMSKeyID != nil && lo.FromPtr(bdm.EBS.Encrypted) {
+			bdm.EBS.KMSKeyID = lo.ToPtr(*defaults.KMSKeyID)
+		}
+		return bdm
+	})
+}
+
+// rootBlockDeviceMapping returns the block device mapping for the AMI's root volume, which is either the mapping
+// explicitly marked as the root volume or the mapping for the AMI's root device
+func rootBlockDeviceMapping(blockDeviceMappings []*v1beta1.BlockDeviceMapping, ami AMI) (*v1beta1.BlockDeviceMapping, bool) {
+	if blockDeviceMapping, ok := lo.Find(blockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping) bool { return bdm.RootVolume }); ok {
+		return blockDeviceMapping, true
+	}
+	return lo.Find(blockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping) bool {
+		return ami.RootDeviceName != "" && lo.FromPtr(bdm.DeviceName) == ami.RootDeviceName
+	})
+}
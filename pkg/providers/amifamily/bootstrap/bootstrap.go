@@ -42,6 +42,64 @@ type Options struct {
 	ContainerRuntime        *string
 	CustomUserData          *string
 	InstanceStorePolicy     *v1beta1.InstanceStorePolicy
+	InstanceStore           *v1beta1.InstanceStore
+	// NodeName overrides the name the kubelet registers the node with. It may reference InstanceIDVariable. EKS maps the
+	// node role to system:node:{{EC2PrivateDNSName}} by default, so the NodeRestriction admission plugin rejects the node
+	// unless the role is mapped to system:node:{{SessionName}} in aws-auth and NodeName renders the instance ID alone.
+	NodeName string
+}
+
+// InstanceIDVariable is the shell variable that bootstrap scripts set to the instance ID before the kubelet starts.
+// Node names are rendered before the instance exists, so they reference the variable in place of the ID.
+const InstanceIDVariable = "${INSTANCE_ID}"
+
+// instanceIDScript sets InstanceIDVariable from IMDSv2
+const instanceIDScript = `TOKEN=$(curl -s -X PUT "http://169.254.169.254/latest/api/token" -H "X-aws-ec2-metadata-token-ttl-seconds: 60")
+INSTANCE_ID=$(curl -s -H "X-aws-ec2-metadata-token: ${TOKEN}" "http://169.254.169.254/latest/meta-data/instance-id")
+`
+
+// instanceStoreScript assembles the instance store disks into an array, formats it, and mounts it at the paths that
+// the kubelet and containerd store their data in. It's only used when the InstanceStore asks for something other
+// than the striped xfs array that the AMIs set up on their own.
+const instanceStoreScript = `DISKS=($(find -L /dev/disk/by-id/ -xtype l -name '*NVMe_Instance_Storage_*' | xargs -r readlink -f | sort -u))
+if [ ${#DISKS[@]} -gt 0 ]; then
+  mdadm --create --force --verbose /dev/md/kubernetes --level=%d --name=kubernetes --raid-devices=${#DISKS[@]} "${DISKS[@]}"
+  %s /dev/md/kubernetes
+  mkdir -p /mnt/k8s-disks
+  mount -o defaults,noatime /dev/md/kubernetes /mnt/k8s-disks
+  for DIR in /var/lib/kubelet /var/lib/containerd /var/log/pods; do
+    mkdir -p /mnt/k8s-disks${DIR} ${DIR}
+    mount --bind /mnt/k8s-disks${DIR} ${DIR}
+  done
+fi
+`
+
+// customInstanceStore returns true if the instance store disks need to be set up by instanceStoreScript
+func (o Options) customInstanceStore() bool {
+	// The AMIs only know how to stripe the disks
+	if lo.FromPtr(o.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID10 {
+		return true
+	}
+	if lo.FromPtr(o.InstanceStorePolicy) != v1beta1.InstanceStorePolicyRAID0 || o.InstanceStore == nil {
+		return false
+	}
+	return lo.FromPtr(o.InstanceStore.RAIDLevel) == v1beta1.RAIDLevelRAID1 ||
+		lo.FromPtr(o.InstanceStore.Filesystem) == v1beta1.InstanceStoreFilesystemExt4
+}
+
+func (o Options) instanceStoreScript() string {
+	if lo.FromPtr(o.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID10 {
+		return fmt.Sprintf(instanceStoreScript, 10, "mkfs.xfs -f")
+	}
+	level := 0
+	if lo.FromPtr(o.InstanceStore.RAIDLevel) == v1beta1.RAIDLevelRAID1 {
+		level = 1
+	}
+	mkfs := "mkfs.xfs -f"
+	if lo.FromPtr(o.InstanceStore.Filesystem) == v1beta1.InstanceStoreFilesystemExt4 {
+		mkfs = "mkfs.ext4 -F -m 0"
+	}
+	return fmt.Sprintf(instanceStoreScript, level, mkfs)
 }
 
 func (o Options) kubeletExtraArgs() (args []string) {
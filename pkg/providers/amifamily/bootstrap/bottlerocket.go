@@ -33,6 +33,10 @@ type Bottlerocket struct {
 
 // nolint:gocyclo
 func (b Bottlerocket) Script() (string, error) {
+	// Bottlerocket settings are static, so they can't name the node after an instance ID that isn't known until launch
+	if b.NodeName != "" {
+		return "", fmt.Errorf("node name templates are not supported by the Bottlerocket AMI family")
+	}
 	s, err := NewBottlerocketConfig(b.CustomUserData)
 	if err != nil {
 		return "", fmt.Errorf("invalid UserData %w", err)
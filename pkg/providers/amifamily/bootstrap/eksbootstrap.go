@@ -62,6 +62,12 @@ func (e EKS) eksBootstrapScript() string {
 	var userData bytes.Buffer
 	userData.WriteString("#!/bin/bash -xe\n")
 	userData.WriteString("exec > >(tee /var/log/user-data.log|logger -t user-data -s 2>/dev/console) 2>&1\n")
+	if e.NodeName != "" {
+		userData.WriteString(instanceIDScript)
+	}
+	if e.customInstanceStore() {
+		userData.WriteString(e.instanceStoreScript())
+	}
 	// Due to the way bootstrap.sh is written, parameters should not be passed to it with an equal sign
 	userData.WriteString(fmt.Sprintf("/etc/eks/bootstrap.sh '%s' --apiserver-endpoint '%s' %s", e.ClusterName, e.ClusterEndpoint, caBundleArg))
 
@@ -74,10 +80,15 @@ func (e EKS) eksBootstrapScript() string {
 	if (e.KubeletConfig != nil && e.KubeletConfig.MaxPods != nil) || !e.AWSENILimitedPodDensity {
 		userData.WriteString(" \\\n--use-max-pods false")
 	}
-	if args := e.kubeletExtraArgs(); len(args) > 0 {
+	if args := e.kubeletExtraArgs(); len(args) > 0 && e.NodeName != "" {
+		// The node name is double quoted so that the instance ID is expanded when the script runs
+		userData.WriteString(fmt.Sprintf(" \\\n--kubelet-extra-args '%s '\"--hostname-override=%s\"", strings.Join(args, " "), e.NodeName))
+	} else if len(args) > 0 {
 		userData.WriteString(fmt.Sprintf(" \\\n--kubelet-extra-args '%s'", strings.Join(args, " ")))
+	} else if e.NodeName != "" {
+		userData.WriteString(fmt.Sprintf(" \\\n--kubelet-extra-args \"--hostname-override=%s\"", e.NodeName))
 	}
-	if lo.FromPtr(e.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 {
+	if lo.FromPtr(e.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 && !e.customInstanceStore() {
 		userData.WriteString(" \\\n--local-disks raid0")
 	}
 	return userData.String()
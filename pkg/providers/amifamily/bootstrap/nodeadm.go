@@ -45,10 +45,23 @@ func (n Nodeadm) Script() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("parsing custom UserData, %w", err)
 	}
-	mimeArchive := mime.Archive(append([]mime.Entry{{
+	entries := []mime.Entry{{
 		ContentType: mime.ContentTypeNodeConfig,
 		Content:     nodeConfigYAML,
-	}}, customEntries...))
+	}}
+	if n.customInstanceStore() {
+		entries = append(entries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash\n" + n.instanceStoreScript(),
+		})
+	}
+	if n.NodeName != "" {
+		entries = append(entries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     n.nodeNameScript(),
+		})
+	}
+	mimeArchive := mime.Archive(append(entries, customEntries...))
 	userData, err := mimeArchive.Serialize()
 	if err != nil {
 		return "", err
@@ -83,7 +96,7 @@ func (n Nodeadm) getNodeConfigYAML() (string, error) {
 	} else {
 		return "", cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("resolving cluster CIDR"))
 	}
-	if lo.FromPtr(n.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 {
+	if lo.FromPtr(n.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 && !n.customInstanceStore() {
 		config.Spec.Instance.LocalStorage.Strategy = admv1alpha1.LocalStorageRAID0
 	}
 	inlineConfig, err := n.generateInlineKubeletConfiguration()
@@ -103,6 +116,22 @@ func (n Nodeadm) getNodeConfigYAML() (string, error) {
 	return fmt.Sprintf("# Karpenter Generated NodeConfig\n%s", string(configYAML)), nil
 }
 
+// nodeNameScript returns a shell script that overrides the name the kubelet registers the node with. The NodeConfig
+// can't reference the instance ID, so the script resolves it and adds the flag to the kubelet unit. nodeadm doesn't
+// start the kubelet until cloud-init has run the script.
+func (n Nodeadm) nodeNameScript() string {
+	var script strings.Builder
+	script.WriteString("#!/bin/bash\n")
+	script.WriteString(instanceIDScript)
+	script.WriteString("mkdir -p /etc/systemd/system/kubelet.service.d\n")
+	script.WriteString("cat <<EOF > /etc/systemd/system/kubelet.service.d/90-karpenter-node-name.conf\n")
+	script.WriteString("[Service]\nExecStart=\n")
+	script.WriteString(fmt.Sprintf("ExecStart=/usr/bin/kubelet \\$NODEADM_KUBELET_ARGS --hostname-override=%s\n", n.NodeName))
+	script.WriteString("EOF\n")
+	script.WriteString("systemctl daemon-reload\n")
+	return script.String()
+}
+
 // generateInlineKubeletConfiguration returns a serialized form of the KubeletConfiguration specified by the Nodeadm
 // options, for use with nodeadm's NodeConfig struct.
 func (n Nodeadm) generateInlineKubeletConfiguration() (map[string]runtime.RawExtension, error) {
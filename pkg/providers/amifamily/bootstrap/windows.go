@@ -29,6 +29,9 @@ type Windows struct {
 
 // nolint:gocyclo
 func (w Windows) Script() (string, error) {
+	if w.NodeName != "" {
+		return "", fmt.Errorf("node name templates are not supported by the Windows AMI families")
+	}
 	var userData bytes.Buffer
 	userData.WriteString("<powershell>\n")
 
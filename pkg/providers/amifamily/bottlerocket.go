@@ -98,6 +98,7 @@ func (b Bottlerocket) UserData(kubeletConfig *corev1beta1.KubeletConfiguration,
 			Labels:          labels,
 			CABundle:        caBundle,
 			CustomUserData:  customUserData,
+			NodeName:        b.Options.NodeName,
 		},
 	}
 }
@@ -54,13 +54,20 @@ type Options struct {
 	InstanceProfile     string
 	CABundle            *string `hash:"ignore"`
 	InstanceStorePolicy *v1beta1.InstanceStorePolicy
+	InstanceStore       *v1beta1.InstanceStore
 	// Level-triggered fields that may change out of sync.
 	SecurityGroups           []v1beta1.SecurityGroup
 	Tags                     map[string]string
 	Labels                   map[string]string `hash:"ignore"`
 	KubeDNSIP                net.IP
 	AssociatePublicIPAddress *bool
-	NodeClassName            string
+	// AssociateCarrierIPAddress is set in place of AssociatePublicIPAddress when launching into a Wavelength Zone
+	AssociateCarrierIPAddress *bool
+	IPv6                      *v1beta1.IPv6
+	NodeClassName             string
+	NodeNameConvention        string
+	// NodeName is the rendered node-name-template, which references the instance ID through bootstrap.InstanceIDVariable
+	NodeName string
 }
 
 // LaunchTemplate holds the dynamically generated launch template parameters
@@ -74,6 +81,11 @@ type LaunchTemplate struct {
 	DetailedMonitoring  bool
 	EFACount            int
 	CapacityType        string
+	Placement           *v1beta1.Placement
+	Tenancy             *string
+	HostPlacement       *v1beta1.HostPlacement
+	// CapacityReservationID is the capacity block that capacity-block launch templates target
+	CapacityReservationID string
 }
 
 // AMIFamily can be implemented to override the default logic for generating dynamic launch template parameters
@@ -122,9 +134,15 @@ func NewResolver(amiProvider Provider) *Resolver {
 // Multiple ResolvedTemplates are returned based on the instanceTypes passed in to support special AMIs for certain instance types like GPUs.
 func (r Resolver) Resolve(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, options *Options) ([]*LaunchTemplate, error) {
 	amiFamily := GetAMIFamily(nodeClass.Spec.AMIFamily, options)
-	amis, err := r.amiProvider.Get(ctx, nodeClass, options)
-	if err != nil {
-		return nil, err
+	amis, pinned := PinnedAMIs(nodeClass)
+	if pinned {
+		// The pinned AMIs may have been adopted before their owner was disallowed
+		amis, _ = FilterAllowedOwners(ctx, amis)
+	} else {
+		var err error
+		if amis, err = r.amiProvider.Get(ctx, nodeClass, options); err != nil {
+			return nil, err
+		}
 	}
 	if len(amis) == 0 {
 		return nil, fmt.Errorf("no amis exist given constraints")
@@ -133,6 +151,14 @@ func (r Resolver) Resolve(ctx context.Context, nodeClass *v1beta1.EC2NodeClass,
 	if len(mappedAMIs) == 0 {
 		return nil, fmt.Errorf("no instance types satisfy requirements of amis %v", amis)
 	}
+	blockDeviceMappings := BlockDeviceMappings(ctx, nodeClass, amiFamily, amis)
+	// EC2 rejects launches with a root volume smaller than the AMI's root snapshot, so fail before launching
+	if undersized := UndersizedRootVolumes(
+		lo.Ternary(len(blockDeviceMappings) > 0, blockDeviceMappings, amiFamily.DefaultBlockDeviceMappings()),
+		lo.Filter(amis, func(ami AMI, _ int) bool { _, ok := mappedAMIs[ami.AmiID]; return ok }),
+	); len(undersized) > 0 {
+		return nil, fmt.Errorf("root volume is too small, %s", undersized[0])
+	}
 	var resolvedTemplates []*LaunchTemplate
 	for amiID, instanceTypes := range mappedAMIs {
 		// In order to support reserved ENIs for CNI custom networking setups,
@@ -155,7 +181,7 @@ func (r Resolver) Resolve(ctx context.Context, nodeClass *v1beta1.EC2NodeClass,
 			}
 		})
 		for params, instanceTypes := range paramsToInstanceTypes {
-			resolved, err := r.resolveLaunchTemplate(nodeClass, nodeClaim, instanceTypes, capacityType, amiFamily, amiID, params.maxPods, params.efaCount, options)
+			resolved, err := r.resolveLaunchTemplate(nodeClass, nodeClaim, instanceTypes, capacityType, amiFamily, amiID, blockDeviceMappings, params.maxPods, params.efaCount, options)
 			if err != nil {
 				return nil, err
 			}
@@ -175,6 +201,8 @@ func GetAMIFamily(amiFamily *string, options *Options) AMIFamily {
 		return &Windows{Options: options, Version: v1beta1.Windows2019, Build: v1beta1.Windows2019Build}
 	case v1beta1.AMIFamilyWindows2022:
 		return &Windows{Options: options, Version: v1beta1.Windows2022, Build: v1beta1.Windows2022Build}
+	case v1beta1.AMIFamilyWindows2025:
+		return &Windows{Options: options, Version: v1beta1.Windows2025, Build: v1beta1.Windows2025Build}
 	case v1beta1.AMIFamilyCustom:
 		return &Custom{Options: options}
 	case v1beta1.AMIFamilyAL2023:
@@ -211,14 +239,18 @@ func (r Resolver) defaultClusterDNS(opts *Options, kubeletConfig *corev1beta1.Ku
 }
 
 func (r Resolver) resolveLaunchTemplate(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string,
-	amiFamily AMIFamily, amiID string, maxPods int, efaCount int, options *Options) (*LaunchTemplate, error) {
+	amiFamily AMIFamily, amiID string, blockDeviceMappings []*v1beta1.BlockDeviceMapping, maxPods int, efaCount int, options *Options) (*LaunchTemplate, error) {
 	kubeletConfig := &corev1beta1.KubeletConfiguration{}
 	if nodeClaim.Spec.Kubelet != nil {
 		if err := mergo.Merge(kubeletConfig, nodeClaim.Spec.Kubelet); err != nil {
 			return nil, err
 		}
 	}
-	if kubeletConfig.MaxPods == nil {
+	// Max-pods overrides on the EC2NodeClass take precedence over the kubelet's max-pods
+	if kubeletConfig.MaxPods == nil || lo.ContainsBy(instanceTypes, func(it *cloudprovider.InstanceType) bool {
+		_, ok := nodeClass.MaxPodsOverride(it.Name)
+		return ok
+	}) {
 		kubeletConfig.MaxPods = lo.ToPtr(int32(maxPods))
 	}
 	resolved := &LaunchTemplate{
@@ -232,19 +264,43 @@ func (r Resolver) resolveLaunchTemplate(nodeClass *v1beta1.EC2NodeClass, nodeCla
 			nodeClass.Spec.UserData,
 			options.InstanceStorePolicy,
 		),
-		BlockDeviceMappings: nodeClass.Spec.BlockDeviceMappings,
+		BlockDeviceMappings: blockDeviceMappings,
 		MetadataOptions:     nodeClass.Spec.MetadataOptions,
 		DetailedMonitoring:  aws.BoolValue(nodeClass.Spec.DetailedMonitoring),
 		AMIID:               amiID,
 		InstanceTypes:       instanceTypes,
 		EFACount:            efaCount,
 		CapacityType:        capacityType,
+		Placement:           nodeClass.Spec.Placement,
+		Tenancy:             nodeClass.Spec.Tenancy,
+		HostPlacement:       nodeClass.Spec.HostPlacement,
+	}
+	if capacityType == v1beta1.CapacityTypeCapacityBlock {
+		resolved.CapacityReservationID = capacityBlockReservationID(nodeClass, instanceTypes)
 	}
 	if len(resolved.BlockDeviceMappings) == 0 {
 		resolved.BlockDeviceMappings = amiFamily.DefaultBlockDeviceMappings()
 	}
+	resolved.BlockDeviceMappings = WithBlockDeviceDefaults(resolved.BlockDeviceMappings, nodeClass.Spec.BlockDeviceDefaults)
 	if resolved.MetadataOptions == nil {
 		resolved.MetadataOptions = amiFamily.DefaultMetadataOptions()
 	}
 	return resolved, nil
 }
+
+// capacityBlockReservationID returns the capacity block of the first available capacity-block offering of the instance
+// types. Capacity block launches are narrowed to the instance type and zone of a single capacity block before their
+// launch templates are resolved.
+func capacityBlockReservationID(nodeClass *v1beta1.EC2NodeClass, instanceTypes []*cloudprovider.InstanceType) string {
+	for _, instanceType := range instanceTypes {
+		for _, offering := range instanceType.Offerings.Available() {
+			if offering.CapacityType != v1beta1.CapacityTypeCapacityBlock {
+				continue
+			}
+			if capacityReservation, ok := nodeClass.CapacityBlockReservation(instanceType.Name, offering.Zone); ok {
+				return capacityReservation.ID
+			}
+		}
+	}
+	return ""
+}
@@ -16,6 +16,7 @@ package amifamily_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"testing"
@@ -190,6 +191,15 @@ var _ = Describe("AMIProvider", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(amis).To(HaveLen(1))
 	})
+	It("should succeed to resolve AMIs (Windows2025)", func() {
+		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyWindows2025
+		awsEnv.SSMAPI.Parameters = map[string]string{
+			fmt.Sprintf("/aws/service/ami-windows-latest/Windows_Server-2025-English-Core-EKS_Optimized-%s/image_id", version): amd64AMI,
+		}
+		amis, err := awsEnv.AMIProvider.Get(ctx, nodeClass, &amifamily.Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(1))
+	})
 	It("should succeed to resolve AMIs (Custom)", func() {
 		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyCustom
 		amis, err := awsEnv.AMIProvider.Get(ctx, nodeClass, &amifamily.Options{})
@@ -286,7 +296,7 @@ var _ = Describe("AMIProvider", func() {
 	Context("AMI Selectors", func() {
 		// When you tag public or shared resources, the tags you assign are available only to your AWS account; no other AWS account will have access to those tags
 		// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/Using_Tags.html#tag-restrictions
-		It("should have empty owners and use tags when prefixes aren't set", func() {
+		It("should have default owners and use tags when prefixes aren't set", func() {
 			amiSelectorTerms := []v1beta1.AMISelectorTerm{
 				{
 					Tags: map[string]string{
@@ -294,7 +304,7 @@ var _ = Describe("AMIProvider", func() {
 					},
 				},
 			}
-			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms)
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"self", "amazon"})
 			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
 				{
 					Filters: []*ec2.Filter{
@@ -303,6 +313,48 @@ var _ = Describe("AMIProvider", func() {
 							Values: aws.StringSlice([]string{"my-ami"}),
 						},
 					},
+					Owners: []string{
+						"amazon",
+						"self",
+					},
+				},
+			}, filterAndOwnersSets)
+		})
+		It("should use the configured default owners", func() {
+			amiSelectorTerms := []v1beta1.AMISelectorTerm{
+				{
+					Name: "my-ami",
+				},
+			}
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"123456789012"})
+			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
+				{
+					Filters: []*ec2.Filter{
+						{
+							Name:   aws.String("name"),
+							Values: aws.StringSlice([]string{"my-ami"}),
+						},
+					},
+					Owners: []string{"123456789012"},
+				},
+			}, filterAndOwnersSets)
+		})
+		It("should have empty owners when the owner is a wildcard", func() {
+			amiSelectorTerms := []v1beta1.AMISelectorTerm{
+				{
+					Name:  "my-ami",
+					Owner: "*",
+				},
+			}
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"self", "amazon"})
+			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
+				{
+					Filters: []*ec2.Filter{
+						{
+							Name:   aws.String("name"),
+							Values: aws.StringSlice([]string{"my-ami"}),
+						},
+					},
 					Owners: []string{},
 				},
 			}, filterAndOwnersSets)
@@ -313,7 +365,7 @@ var _ = Describe("AMIProvider", func() {
 					Name: "my-ami",
 				},
 			}
-			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms)
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"self", "amazon"})
 			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
 				{
 					Filters: []*ec2.Filter{
@@ -338,7 +390,7 @@ var _ = Describe("AMIProvider", func() {
 					ID: "ami-cafeaced",
 				},
 			}
-			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms)
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"self", "amazon"})
 			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
 				{
 					Filters: []*ec2.Filter{
@@ -359,7 +411,7 @@ var _ = Describe("AMIProvider", func() {
 					Owner: "123456789012",
 				},
 			}
-			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms)
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"self", "amazon"})
 			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
 				{
 					Owners: []string{"abcdef"},
@@ -380,7 +432,7 @@ var _ = Describe("AMIProvider", func() {
 					Owner: "self",
 				},
 			}
-			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms)
+			filterAndOwnersSets := amifamily.GetFilterAndOwnerSets(amiSelectorTerms, []string{"self", "amazon"})
 			ExpectConsistsOfFiltersAndOwners([]amifamily.FiltersAndOwners{
 				{
 					Owners: []string{"0123456789"},
@@ -460,6 +512,54 @@ var _ = Describe("AMIProvider", func() {
 			))
 		})
 	})
+	Context("SSM Parameter Selectors", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+				{
+					Name:         aws.String(amd64AMI),
+					ImageId:      aws.String("ami-0123456789"),
+					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					Architecture: aws.String("x86_64"),
+				},
+			}})
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{SSMParameter: "/test/ami-id"}}
+		})
+		It("should resolve the AMI ID in the SSM parameter through DescribeImages", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/ami-id": "ami-0123456789"}
+			amis, err := awsEnv.AMIProvider.Get(ctx, nodeClass, &amifamily.Options{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].AmiID).To(Equal("ami-0123456789"))
+			Expect(amis[0].Name).To(Equal(amd64AMI))
+			input := awsEnv.EC2API.CalledWithDescribeImagesInput.Pop()
+			Expect(input.Filters).To(ConsistOf(&ec2.Filter{Name: aws.String("image-id"), Values: aws.StringSlice([]string{"ami-0123456789"})}))
+		})
+		It("should return an SSMParameterError when the parameter doesn't exist", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/other": "ami-0123456789"}
+			_, err := awsEnv.AMIProvider.Get(ctx, nodeClass, &amifamily.Options{})
+			ssmErr := &amifamily.SSMParameterError{}
+			Expect(errors.As(err, &ssmErr)).To(BeTrue())
+			Expect(ssmErr.Reason).To(Equal(amifamily.SSMParameterNotFoundReason))
+		})
+		It("should return an SSMParameterError when the parameter isn't an AMI ID", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{"/test/ami-id": "latest"}
+			_, err := awsEnv.AMIProvider.Get(ctx, nodeClass, &amifamily.Options{})
+			ssmErr := &amifamily.SSMParameterError{}
+			Expect(errors.As(err, &ssmErr)).To(BeTrue())
+			Expect(ssmErr.Reason).To(Equal(amifamily.SSMParameterInvalidReason))
+		})
+		It("should pin the status AMIs while the SSM parameter can't be resolved", func() {
+			nodeClass.Status.AMIs = []v1beta1.AMI{{ID: "ami-current"}}
+			_, pinned := amifamily.PinnedAMIs(nodeClass)
+			Expect(pinned).To(BeFalse())
+
+			nodeClass.StatusConditions().MarkFalse(v1beta1.ConditionTypeAMIsReady, amifamily.SSMParameterNotFoundReason, "not found")
+			amis, pinned := amifamily.PinnedAMIs(nodeClass)
+			Expect(pinned).To(BeTrue())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].AmiID).To(Equal("ami-current"))
+		})
+	})
 })
 
 func ExpectConsistsOfFiltersAndOwners(expected, actual []amifamily.FiltersAndOwners) {
@@ -63,6 +63,7 @@ func (u Ubuntu) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints
 			Labels:          labels,
 			CABundle:        caBundle,
 			CustomUserData:  customUserData,
+			NodeName:        u.Options.NodeName,
 		},
 	}
 }
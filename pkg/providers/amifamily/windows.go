@@ -65,6 +65,7 @@ func (w Windows) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taint
 			Labels:          labels,
 			CABundle:        caBundle,
 			CustomUserData:  customUserData,
+			NodeName:        w.Options.NodeName,
 		},
 	}
 }
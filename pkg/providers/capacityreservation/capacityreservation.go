@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"knative.dev/pkg/logging"
+
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+type Provider interface {
+	List(context.Context, *v1beta1.EC2NodeClass) ([]*ec2.CapacityReservation, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	ec2api ec2iface.EC2API
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+}
+
+func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+		cm:     pretty.NewChangeMonitor(),
+		cache:  cache,
+	}
+}
+
+// List returns the active capacity reservations selected by the capacityReservationSelectorTerms of the EC2NodeClass.
+// Reservations that haven't started or have ended can't be launched into, so they aren't returned.
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) ([]*ec2.CapacityReservation, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	filterSets := getFilterSets(nodeClass.Spec.CapacityReservationSelectorTerms)
+	if len(filterSets) == 0 {
+		return nil, nil
+	}
+	hash, err := hashstructure.Hash(filterSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+	if capacityReservations, ok := p.cache.Get(fmt.Sprint(hash)); ok {
+		return capacityReservations.([]*ec2.CapacityReservation), nil
+	}
+	capacityReservations := map[string]*ec2.CapacityReservation{}
+	for _, filters := range filterSets {
+		output, err := p.ec2api.DescribeCapacityReservationsWithContext(ctx, &ec2.DescribeCapacityReservationsInput{Filters: filters})
+		if err != nil {
+			return nil, fmt.Errorf("describing capacity reservations %+v, %w", filterSets, err)
+		}
+		for i := range output.CapacityReservations {
+			capacityReservations[lo.FromPtr(output.CapacityReservations[i].CapacityReservationId)] = output.CapacityReservations[i]
+		}
+	}
+	p.cache.SetDefault(fmt.Sprint(hash), lo.Values(capacityReservations))
+	if p.cm.HasChanged(fmt.Sprintf("capacity-reservations/%s", nodeClass.Name), lo.Keys(capacityReservations)) {
+		logging.FromContext(ctx).
+			With("capacity-reservations", lo.Keys(capacityReservations)).
+			Debugf("discovered capacity reservations")
+	}
+	return lo.Values(capacityReservations), nil
+}
+
+func getFilterSets(terms []v1beta1.CapacityReservationSelectorTerm) (res [][]*ec2.Filter) {
+	stateFilter := &ec2.Filter{Name: aws.String("state"), Values: []*string{aws.String(ec2.CapacityReservationStateActive)}}
+	idFilter := &ec2.Filter{Name: aws.String("capacity-reservation-id")}
+	for _, term := range terms {
+		switch {
+		case term.ID != "":
+			idFilter.Values = append(idFilter.Values, aws.String(term.ID))
+		default:
+			filters := []*ec2.Filter{stateFilter}
+			for k, v := range term.Tags {
+				if v == "*" {
+					filters = append(filters, &ec2.Filter{
+						Name:   aws.String("tag-key"),
+						Values: []*string{aws.String(k)},
+					})
+				} else {
+					filters = append(filters, &ec2.Filter{
+						Name:   aws.String(fmt.Sprintf("tag:%s", k)),
+						Values: []*string{aws.String(v)},
+					})
+				}
+			}
+			res = append(res, filters)
+		}
+	}
+	if len(idFilter.Values) > 0 {
+		res = append(res, []*ec2.Filter{stateFilter, idFilter})
+	}
+	return res
+}
@@ -0,0 +1,400 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costlimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+const (
+	// spotPriceSmoothing is the weight that a newly observed spot price is given in the smoothed spot price, so that
+	// short-lived spot price changes don't flap enforcement
+	spotPriceSmoothing = 0.2
+	// RateStaleAfter is how long the spend rates of NodePools are trusted after they were last computed
+	RateStaleAfter = 5 * time.Minute
+)
+
+// PricingStaleAfter is how long the prices of a capacity type are trusted after they were last updated. Prices are stale
+// once an update of the pricing controller has been missed, which is twice the interval that they're refreshed at.
+func PricingStaleAfter(ctx context.Context, capacityType string) time.Duration {
+	if capacityType == corev1beta1.CapacityTypeSpot {
+		return 2 * options.FromContext(ctx).SpotPriceRefreshInterval
+	}
+	return 2 * pricing.OnDemandRefreshInterval
+}
+
+// Instance is a running instance that counts towards the spend rate of its NodePool
+type Instance struct {
+	ID           string
+	NodePool     string
+	Type         string
+	Zone         string
+	CapacityType string
+}
+
+// Provider enforces the karpenter.k8s.aws/limit-hourly-cost annotation of NodePools. The spend rate of a NodePool is
+// the sum of the hourly prices of its running instances, where spot instances are priced at their smoothed spot price,
+// and of the prices reserved by launches that are in flight.
+type Provider interface {
+	Update(context.Context, []corev1beta1.NodePool, []Instance)
+	MarkUnavailable(context.Context, string, []*cloudprovider.InstanceType) []*cloudprovider.InstanceType
+	Filter(context.Context, *corev1beta1.NodeClaim, []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error)
+	Record(nodeClaim *corev1beta1.NodeClaim, instanceID, instanceType, zone, capacityType string)
+	Release(*corev1beta1.NodeClaim)
+}
+
+// reservation is the price that an in-flight launch holds against the hourly cost limit of its NodePool
+type reservation struct {
+	nodePool string
+	price    float64
+}
+
+// launch is the price of an instance that was launched for a NodeClaim, which counts towards the spend rate of its
+// NodePool until the instance is listed by an update
+type launch struct {
+	nodePool   string
+	instanceID string
+	price      float64
+	launchedAt time.Time
+}
+
+type DefaultProvider struct {
+	pricingProvider pricing.Provider
+	clk             clock.Clock
+
+	mu     sync.RWMutex
+	limits map[string]float64
+	rates  map[string]float64
+	// updatedAt is when the spend rates were last computed from the running instances
+	updatedAt time.Time
+	// spotPrices are the smoothed spot prices, keyed by instance type and zone
+	spotPrices map[string]float64
+	// reservations are the prices held by in-flight launches, keyed by NodeClaim UID
+	reservations map[types.UID]reservation
+	// launches are the prices of recorded launches whose instances haven't been listed yet, keyed by NodeClaim UID
+	launches map[types.UID]launch
+}
+
+func NewDefaultProvider(pricingProvider pricing.Provider, clk clock.Clock) *DefaultProvider {
+	return &DefaultProvider{
+		pricingProvider: pricingProvider,
+		clk:             clk,
+		limits:          map[string]float64{},
+		rates:           map[string]float64{},
+		spotPrices:      map[string]float64{},
+		reservations:    map[types.UID]reservation{},
+		launches:        map[types.UID]launch{},
+	}
+}
+
+// HourlyCostLimitExceededError is returned when none of the offerings that a NodeClaim can launch fit within the
+// hourly cost limit of its NodePool
+type HourlyCostLimitExceededError struct {
+	NodePool string
+	Rate     float64
+	Limit    float64
+}
+
+func (e HourlyCostLimitExceededError) Error() string {
+	return fmt.Sprintf("launching would exceed the hourly cost limit of nodepool %s, spending $%.4f/hour of $%.4f/hour", e.NodePool, e.Rate, e.Limit)
+}
+
+func IsHourlyCostLimitExceeded(err error) bool {
+	return errors.As(err, &HourlyCostLimitExceededError{})
+}
+
+// StalePricingError is returned, unless hourly-cost-limit-fail-open is set, when the spend rate of a NodePool with an
+// hourly cost limit can't be trusted
+type StalePricingError struct {
+	NodePool string
+	Reason   string
+}
+
+func (e StalePricingError) Error() string {
+	return fmt.Sprintf("enforcing the hourly cost limit of nodepool %s, %s", e.NodePool, e.Reason)
+}
+
+// NodePoolLimit returns the hourly cost limit of the NodePool, if it has one
+func NodePoolLimit(nodePool *corev1beta1.NodePool) (float64, bool, error) {
+	value, ok := nodePool.Annotations[v1beta1.AnnotationLimitHourlyCost]
+	if !ok {
+		return 0, false, nil
+	}
+	limit, err := strconv.ParseFloat(value, 64)
+	if err != nil || limit < 0 {
+		return 0, false, fmt.Errorf("%s must be a non-negative number of dollars, got %q", v1beta1.AnnotationLimitHourlyCost, value)
+	}
+	return limit, true, nil
+}
+
+// Update recomputes the spend rates of the NodePools with an hourly cost limit from their running instances. Listing
+// instances is eventually consistent, so recorded launches whose instances aren't listed yet keep counting towards the
+// spend rate until they are, or until they were recorded longer than RateStaleAfter ago.
+func (p *DefaultProvider) Update(ctx context.Context, nodePools []corev1beta1.NodePool, instances []Instance) {
+	limits := map[string]float64{}
+	for i := range nodePools {
+		limit, ok, err := NodePoolLimit(&nodePools[i])
+		if err != nil {
+			logging.FromContext(ctx).With("nodepool", nodePools[i].Name).Errorf("ignoring hourly cost limit, %s", err)
+			continue
+		}
+		if ok {
+			limits[nodePools[i].Name] = limit
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.smoothSpotPrices(instances)
+	rates := map[string]float64{}
+	for _, instance := range instances {
+		if _, ok := limits[instance.NodePool]; !ok {
+			continue
+		}
+		price, ok := p.price(instance.Type, instance.Zone, instance.CapacityType)
+		if !ok {
+			logging.FromContext(ctx).With("nodepool", instance.NodePool, "instance-type", instance.Type, "zone", instance.Zone, "capacity-type", instance.CapacityType).
+				Debugf("no price for instance, not counting it towards the hourly cost of its nodepool")
+			continue
+		}
+		rates[instance.NodePool] += price
+	}
+	listed := lo.SliceToMap(instances, func(instance Instance) (string, struct{}) { return instance.ID, struct{}{} })
+	for uid, l := range p.launches {
+		if _, ok := listed[l.instanceID]; ok || p.clk.Since(l.launchedAt) > RateStaleAfter {
+			delete(p.launches, uid)
+			continue
+		}
+		if _, ok := limits[l.nodePool]; ok {
+			rates[l.nodePool] += l.price
+		}
+	}
+	for name := range p.limits {
+		if _, ok := limits[name]; !ok {
+			hourlyCost.DeletePartialMatch(prometheus.Labels{nodePoolLabel: name})
+			hourlyCostLimit.DeletePartialMatch(prometheus.Labels{nodePoolLabel: name})
+		}
+	}
+	for name, limit := range limits {
+		hourlyCost.With(prometheus.Labels{nodePoolLabel: name}).Set(rates[name])
+		hourlyCostLimit.With(prometheus.Labels{nodePoolLabel: name}).Set(limit)
+	}
+	p.limits = limits
+	p.rates = rates
+	p.updatedAt = p.clk.Now()
+}
+
+// smoothSpotPrices folds the current spot prices of the running instances into the smoothed spot prices
+func (p *DefaultProvider) smoothSpotPrices(instances []Instance) {
+	spotPrices := map[string]float64{}
+	for _, instance := range instances {
+		if instance.CapacityType != corev1beta1.CapacityTypeSpot {
+			continue
+		}
+		key := spotPriceKey(instance.Type, instance.Zone)
+		if _, ok := spotPrices[key]; ok {
+			continue
+		}
+		price, ok := p.pricingProvider.SpotPrice(instance.Type, instance.Zone)
+		if !ok {
+			continue
+		}
+		if smoothed, ok := p.spotPrices[key]; ok {
+			price = smoothed + spotPriceSmoothing*(price-smoothed)
+		}
+		spotPrices[key] = price
+	}
+	// Spot prices of instance types that are no longer running are dropped, so launches are priced at the current
+	// spot price until the instance is seen running
+	p.spotPrices = spotPrices
+}
+
+// price returns the hourly price of an instance, using the smoothed spot price for spot instances when it's known.
+// Capacity blocks are paid for when they're reserved, so instances launched into them don't add to the spend rate.
+func (p *DefaultProvider) price(instanceType, zone, capacityType string) (float64, bool) {
+	if capacityType == v1beta1.CapacityTypeCapacityBlock {
+		return 0, true
+	}
+	if capacityType == corev1beta1.CapacityTypeSpot {
+		if price, ok := p.spotPrices[spotPriceKey(instanceType, zone)]; ok {
+			return price, true
+		}
+		return p.pricingProvider.SpotPrice(instanceType, zone)
+	}
+	return p.pricingProvider.OnDemandPrice(instanceType)
+}
+
+// MarkUnavailable returns copies of the instance types of the NodePool, with the offerings that would push its spend
+// rate over its hourly cost limit marked unavailable, so that the scheduler doesn't create NodeClaims for them. When the
+// spend rate can't be trusted, every offering is marked unavailable unless hourly-cost-limit-fail-open is set.
+func (p *DefaultProvider) MarkUnavailable(ctx context.Context, nodePool string, instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	limit, ok := p.limits[nodePool]
+	if !ok {
+		return instanceTypes
+	}
+	_, stale := p.stale(ctx)
+	if stale && options.FromContext(ctx).HourlyCostLimitFailOpen {
+		return instanceTypes
+	}
+	rate := p.rates[nodePool] + p.reserved(nodePool, "")
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			price, ok := p.price(it.Name, o.Zone, o.CapacityType)
+			o.Available = o.Available && !stale && ok && rate+price <= limit
+			return o
+		}))
+	})
+}
+
+// Filter drops the offerings that would push the spend rate of the NodeClaim's NodePool over its hourly cost limit, and
+// reserves the price of the most expensive offering that's left against the limit until the launch is recorded or
+// released. When the spend rate can't be trusted, the instance types are returned as they are if
+// hourly-cost-limit-fail-open is set, and a StalePricingError is returned otherwise.
+func (p *DefaultProvider) Filter(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error) {
+	nodePool, ok := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	if !ok {
+		return instanceTypes, nil
+	}
+	// The check and the reservation are made under the same lock, so concurrent launches for the NodePool can't all
+	// fit within the limit that only one of them fits in
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.reservations, nodeClaim.UID)
+	limit, ok := p.limits[nodePool]
+	if !ok {
+		return instanceTypes, nil
+	}
+	if reason, stale := p.stale(ctx); stale {
+		if options.FromContext(ctx).HourlyCostLimitFailOpen {
+			logging.FromContext(ctx).With("nodepool", nodePool).Warnf("not enforcing hourly cost limit, %s", reason)
+			return instanceTypes, nil
+		}
+		return nil, StalePricingError{NodePool: nodePool, Reason: reason}
+	}
+	rate := p.rates[nodePool] + p.reserved(nodePool, nodeClaim.UID)
+	var maxPrice float64
+	filtered := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (*cloudprovider.InstanceType, bool) {
+		offerings := cloudprovider.Offerings(lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool {
+			price, ok := p.price(it.Name, o.Zone, o.CapacityType)
+			if ok && o.Available && rate+price <= limit {
+				maxPrice = lo.Max([]float64{maxPrice, price})
+			}
+			return ok && rate+price <= limit
+		}))
+		if len(offerings.Available()) == 0 {
+			return nil, false
+		}
+		return withOfferings(it, offerings), true
+	})
+	if len(filtered) == 0 {
+		return nil, HourlyCostLimitExceededError{NodePool: nodePool, Rate: rate, Limit: limit}
+	}
+	p.reservations[nodeClaim.UID] = reservation{nodePool: nodePool, price: maxPrice}
+	return filtered, nil
+}
+
+// reserved returns the prices reserved against the hourly cost limit of the NodePool by launches other than the
+// NodeClaim's
+func (p *DefaultProvider) reserved(nodePool string, uid types.UID) float64 {
+	var total float64
+	for id, r := range p.reservations {
+		if r.nodePool == nodePool && id != uid {
+			total += r.price
+		}
+	}
+	return total
+}
+
+func withOfferings(it *cloudprovider.InstanceType, offerings cloudprovider.Offerings) *cloudprovider.InstanceType {
+	return &cloudprovider.InstanceType{
+		Name:         it.Name,
+		Requirements: it.Requirements,
+		Offerings:    offerings,
+		Capacity:     it.Capacity,
+		Overhead:     it.Overhead,
+	}
+}
+
+// stale returns why the spend rates can't be trusted, if they can't
+func (p *DefaultProvider) stale(ctx context.Context) (string, bool) {
+	if age := p.clk.Since(p.updatedAt); age > RateStaleAfter {
+		return fmt.Sprintf("spend rate was last computed %s ago", age.Round(time.Second)), true
+	}
+	for _, capacityType := range []string{corev1beta1.CapacityTypeOnDemand, corev1beta1.CapacityTypeSpot} {
+		if age := p.clk.Since(p.pricingProvider.UpdatedAt(capacityType)); age > PricingStaleAfter(ctx, capacityType) {
+			return fmt.Sprintf("%s prices were last updated %s ago", capacityType, age.Round(time.Second)), true
+		}
+	}
+	return "", false
+}
+
+// Record replaces the reservation of a NodeClaim's launch with the price of the instance it launched, which counts
+// towards the spend rate of its NodePool until the instance is listed
+func (p *DefaultProvider) Record(nodeClaim *corev1beta1.NodeClaim, instanceID, instanceType, zone, capacityType string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.reservations, nodeClaim.UID)
+	nodePool := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	if _, ok := p.limits[nodePool]; !ok {
+		return
+	}
+	if price, ok := p.price(instanceType, zone, capacityType); ok {
+		p.launches[nodeClaim.UID] = launch{nodePool: nodePool, instanceID: instanceID, price: price, launchedAt: p.clk.Now()}
+		p.rates[nodePool] += price
+		hourlyCost.With(prometheus.Labels{nodePoolLabel: nodePool}).Set(p.rates[nodePool])
+	}
+}
+
+// Release drops the reservation of a NodeClaim's launch that didn't launch an instance
+func (p *DefaultProvider) Release(nodeClaim *corev1beta1.NodeClaim) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.reservations, nodeClaim.UID)
+}
+
+func spotPriceKey(instanceType, zone string) string {
+	return instanceType + "/" + zone
+}
+
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limits = map[string]float64{}
+	p.rates = map[string]float64{}
+	p.updatedAt = time.Time{}
+	p.spotPrices = map[string]float64{}
+	p.reservations = map[types.UID]reservation{}
+	p.launches = map[types.UID]launch{}
+}
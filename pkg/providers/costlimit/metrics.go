@@ -0,0 +1,52 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costlimit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	nodePoolSubsystem = "nodepool"
+	nodePoolLabel     = "nodepool"
+)
+
+var (
+	hourlyCost = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: nodePoolSubsystem,
+			Name:      "hourly_cost",
+			Help:      "The hourly cost in dollars of the running instances of a NodePool with the karpenter.k8s.aws/limit-hourly-cost annotation. Labeled by NodePool.",
+		},
+		[]string{nodePoolLabel},
+	)
+	hourlyCostLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: nodePoolSubsystem,
+			Name:      "hourly_cost_limit",
+			Help:      "The hourly cost limit in dollars set by the karpenter.k8s.aws/limit-hourly-cost annotation of a NodePool. Labeled by NodePool.",
+		},
+		[]string{nodePoolLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(hourlyCost, hourlyCostLimit)
+}
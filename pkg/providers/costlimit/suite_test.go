@@ -0,0 +1,281 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costlimit_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+)
+
+var ctx context.Context
+var fakeClock *clocktesting.FakeClock
+var pricingProvider *fakePricingProvider
+var costLimitProvider *costlimit.DefaultProvider
+
+func TestCostLimit(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CostLimit")
+}
+
+// fakePricingProvider serves fixed prices whose capacity types were last updated at updatedAt
+type fakePricingProvider struct {
+	onDemandPrices map[string]float64
+	spotPrices     map[string]float64
+	updatedAt      map[string]time.Time
+}
+
+func (f *fakePricingProvider) LivenessProbe(*http.Request) error           { return nil }
+func (f *fakePricingProvider) InstanceTypes() []string                     { return lo.Keys(f.onDemandPrices) }
+func (f *fakePricingProvider) UpdateOnDemandPricing(context.Context) error { return nil }
+func (f *fakePricingProvider) UpdateSpotPricing(context.Context) error     { return nil }
+func (f *fakePricingProvider) UpdateOverrides(context.Context) error       { return nil }
+func (f *fakePricingProvider) UpdatedAt(ct string) time.Time               { return f.updatedAt[ct] }
+func (f *fakePricingProvider) Invalidate()                                 {}
+
+func (f *fakePricingProvider) OnDemandPrice(instanceType string) (float64, bool) {
+	price, ok := f.onDemandPrices[instanceType]
+	return price, ok
+}
+
+func (f *fakePricingProvider) SpotPrice(instanceType, zone string) (float64, bool) {
+	price, ok := f.spotPrices[instanceType+"/"+zone]
+	return price, ok
+}
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	fakeClock = clocktesting.NewFakeClock(time.Now())
+	// prices are powers of two so that sums of them are exact
+	pricingProvider = &fakePricingProvider{
+		onDemandPrices: map[string]float64{"m5.large": 0.5, "m5.xlarge": 1},
+		spotPrices:     map[string]float64{"m5.large/test-zone-1a": 0.25, "m5.xlarge/test-zone-1a": 0.5},
+		updatedAt: map[string]time.Time{
+			corev1beta1.CapacityTypeOnDemand: fakeClock.Now(),
+			corev1beta1.CapacityTypeSpot:     fakeClock.Now(),
+		},
+	}
+	costLimitProvider = costlimit.NewDefaultProvider(pricingProvider, fakeClock)
+})
+
+var _ = Describe("CostLimit", func() {
+	nodePool := func(name string, limit string) corev1beta1.NodePool {
+		np := corev1beta1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if limit != "" {
+			np.Annotations = map[string]string{v1beta1.AnnotationLimitHourlyCost: limit}
+		}
+		return np
+	}
+	nodeClaim := func(nodePool string) *corev1beta1.NodeClaim {
+		return &corev1beta1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1beta1.NodePoolLabelKey: nodePool}}}
+	}
+	instanceType := func(name string, capacityTypes ...string) *cloudprovider.InstanceType {
+		return &cloudprovider.InstanceType{
+			Name: name,
+			Offerings: lo.Map(capacityTypes, func(ct string, _ int) cloudprovider.Offering {
+				return cloudprovider.Offering{CapacityType: ct, Zone: "test-zone-1a", Available: true}
+			}),
+		}
+	}
+	offerings := func(instanceTypes []*cloudprovider.InstanceType) []string {
+		return lo.FlatMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) []string {
+			return lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) string { return it.Name + "/" + o.CapacityType })
+		})
+	}
+	instanceTypes := func() []*cloudprovider.InstanceType {
+		return []*cloudprovider.InstanceType{
+			instanceType("m5.large", corev1beta1.CapacityTypeOnDemand, corev1beta1.CapacityTypeSpot),
+			instanceType("m5.xlarge", corev1beta1.CapacityTypeOnDemand, corev1beta1.CapacityTypeSpot),
+		}
+	}
+	// a fleet of two on-demand m5.large and a spot m5.large costs $1.25/hour
+	fleet := []costlimit.Instance{
+		{NodePool: "default", Type: "m5.large", Zone: "test-zone-1a", CapacityType: corev1beta1.CapacityTypeOnDemand},
+		{NodePool: "default", Type: "m5.large", Zone: "test-zone-1a", CapacityType: corev1beta1.CapacityTypeOnDemand},
+		{NodePool: "default", Type: "m5.large", Zone: "test-zone-1a", CapacityType: corev1beta1.CapacityTypeSpot},
+		{NodePool: "other", Type: "m5.xlarge", Zone: "test-zone-1a", CapacityType: corev1beta1.CapacityTypeOnDemand},
+	}
+
+	It("should not filter launches for NodePools without a limit", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(HaveLen(2))
+	})
+	It("should ignore limits that aren't a non-negative number", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "-1"), nodePool("other", "ten")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(HaveLen(2))
+		filtered, err = costLimitProvider.Filter(ctx, nodeClaim("other"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filtered).To(HaveLen(2))
+	})
+	It("should count spot and on-demand instances of the NodePool towards its hourly cost", func() {
+		// $1.25/hour is spent, so only offerings of up to $0.50/hour fit within the limit
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+	})
+	It("should allow a launch that reaches the limit exactly and reject one that exceeds it", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.5")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/spot"))
+
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.4")}, fleet)
+		_, err = costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).To(HaveOccurred())
+		Expect(costlimit.IsHourlyCostLimitExceeded(err)).To(BeTrue())
+	})
+	It("should count launches towards the hourly cost before their instances are listed", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		costLimitProvider.Record(nodeClaim("default"), "i-launched", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/spot"))
+	})
+	It("should keep counting a launch whose instance isn't listed yet when the hourly cost is recomputed", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		costLimitProvider.Record(nodeClaim("default"), "i-launched", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		fakeClock.Step(time.Minute)
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/spot"))
+	})
+	It("should count a launch once when its instance is listed", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "2")}, fleet)
+		costLimitProvider.Record(nodeClaim("default"), "i-launched", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		// the launched instance is listed with its smoothed spot price, so $1.50/hour is spent
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "2")}, append(append([]costlimit.Instance{}, fleet...),
+			costlimit.Instance{ID: "i-launched", NodePool: "default", Type: "m5.large", Zone: "test-zone-1a", CapacityType: corev1beta1.CapacityTypeSpot}))
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+	})
+	It("should stop counting a launch whose instance isn't listed once the launch is stale", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		costLimitProvider.Record(nodeClaim("default"), "i-launched", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		fakeClock.Step(costlimit.RateStaleAfter + time.Second)
+		pricingProvider.updatedAt[corev1beta1.CapacityTypeOnDemand] = fakeClock.Now()
+		pricingProvider.updatedAt[corev1beta1.CapacityTypeSpot] = fakeClock.Now()
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+	})
+	It("should reserve the most expensive offering that fits for a launch until it's recorded or released", func() {
+		// $1.25/hour is spent, so a launch of up to $0.50/hour fits and the next one only has $0.25/hour left
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "2.00")}, fleet)
+		first := nodeClaim("default")
+		first.UID = "first"
+		second := nodeClaim("default")
+		second.UID = "second"
+		filtered, err := costLimitProvider.Filter(ctx, first, instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+		filtered, err = costLimitProvider.Filter(ctx, second, instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/spot"))
+
+		// filtering again for the same launch replaces its reservation instead of adding to it
+		_, err = costLimitProvider.Filter(ctx, first, instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		filtered, err = costLimitProvider.Filter(ctx, second, instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/spot"))
+
+		costLimitProvider.Release(second)
+		costLimitProvider.Record(first, "i-first", "m5.large", "test-zone-1a", corev1beta1.CapacityTypeSpot)
+		filtered, err = costLimitProvider.Filter(ctx, second, instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+	})
+	It("should mark offerings that exceed the limit unavailable when listing instance types", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		marked := costLimitProvider.MarkUnavailable(ctx, "default", instanceTypes())
+		Expect(offerings(lo.Map(marked, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+			return &cloudprovider.InstanceType{Name: it.Name, Offerings: it.Offerings.Available()}
+		}))).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+		Expect(costLimitProvider.MarkUnavailable(ctx, "other", instanceTypes())).To(HaveLen(2))
+	})
+	It("should price running spot instances at their smoothed spot price", func() {
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.75")}, fleet)
+		// the spot price of m5.large rising to $1.25/hour only moves its smoothed price to $0.45/hour
+		pricingProvider.spotPrices["m5.large/test-zone-1a"] = 1.25
+		costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.95")}, fleet)
+		filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offerings(filtered)).To(ConsistOf("m5.large/on-demand", "m5.large/spot", "m5.xlarge/spot"))
+	})
+	Context("Stale Pricing", func() {
+		BeforeEach(func() {
+			costLimitProvider.Update(ctx, []corev1beta1.NodePool{nodePool("default", "1.4")}, fleet)
+		})
+		It("should not enforce the limit when the hourly cost hasn't been computed recently", func() {
+			fakeClock.Step(costlimit.RateStaleAfter + time.Second)
+			filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filtered).To(HaveLen(2))
+		})
+		It("should not enforce the limit when prices haven't been updated recently", func() {
+			pricingProvider.updatedAt[corev1beta1.CapacityTypeOnDemand] = fakeClock.Now().Add(-costlimit.PricingStaleAfter(ctx, corev1beta1.CapacityTypeOnDemand) - time.Second)
+			filtered, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filtered).To(HaveLen(2))
+		})
+		It("should consider spot prices stale once an update at the spot price refresh interval has been missed", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				SpotPriceRefreshInterval: lo.ToPtr(time.Hour),
+				HourlyCostLimitFailOpen:  lo.ToPtr(false),
+			}))
+			Expect(costlimit.PricingStaleAfter(ctx, corev1beta1.CapacityTypeSpot)).To(Equal(2 * time.Hour))
+			pricingProvider.updatedAt[corev1beta1.CapacityTypeSpot] = fakeClock.Now().Add(-time.Hour)
+			_, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+			Expect(costlimit.IsHourlyCostLimitExceeded(err)).To(BeTrue())
+
+			pricingProvider.updatedAt[corev1beta1.CapacityTypeSpot] = fakeClock.Now().Add(-2*time.Hour - time.Second)
+			_, err = costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+			Expect(err).To(BeAssignableToTypeOf(costlimit.StalePricingError{}))
+		})
+		It("should reject launches when prices haven't been updated recently and hourly-cost-limit-fail-open is false", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{HourlyCostLimitFailOpen: lo.ToPtr(false)}))
+			pricingProvider.updatedAt[corev1beta1.CapacityTypeOnDemand] = fakeClock.Now().Add(-costlimit.PricingStaleAfter(ctx, corev1beta1.CapacityTypeOnDemand) - time.Second)
+			_, err := costLimitProvider.Filter(ctx, nodeClaim("default"), instanceTypes())
+			Expect(err).To(BeAssignableToTypeOf(costlimit.StalePricingError{}))
+			Expect(costlimit.IsHourlyCostLimitExceeded(err)).To(BeFalse())
+		})
+	})
+})
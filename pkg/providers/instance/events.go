@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func LaunchableInstanceTypesBelowMinimumEvent(nodeClaim *corev1beta1.NodeClaim, count, minimum int) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "LaunchableInstanceTypesBelowMinimum",
+		Message:        fmt.Sprintf("Only %d instance type(s) remained launchable after filtering, fewer than the configured minimum of %d", count, minimum),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func HourlyCostLimitExceededEvent(nodeClaim *corev1beta1.NodeClaim, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "HourlyCostLimitExceeded",
+		Message:        fmt.Sprintf("Not launching, %s", err),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func InsufficientPodENIsEvent(nodeClaim *corev1beta1.NodeClaim, requested int64) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "InsufficientPodENIs",
+		Message:        fmt.Sprintf("Not launching, no allowed instance type has enough branch interfaces for the %d pod ENIs requested by the pods", requested),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
@@ -19,29 +19,39 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"path"
 	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/batcher"
-	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/metricsexporter"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/placementgroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
@@ -49,6 +59,19 @@ import (
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 )
 
+const (
+	// maxTagValueLength is the maximum number of characters in an EC2 tag value
+	maxTagValueLength = 255
+	// maxInstanceTypes is the maximum number of instance types sent in a fleet request. This mirrors the limit that the
+	// scheduler applies when it creates NodeClaims.
+	maxInstanceTypes = 60
+	// maxCreateTagsResources is the maximum number of instances tagged by a single CreateTags call. EC2 accepts up to
+	// 1000 resources, but recommends smaller batches.
+	maxCreateTagsResources = 200
+	// maxDescribeFilterValues is the maximum number of instance IDs passed in a single instance-id filter
+	maxDescribeFilterValues = 200
+)
+
 var (
 	instanceTypeFlexibilityThreshold = 5 // falling back to on-demand without flexibility risks insufficient capacity errors
 
@@ -62,22 +85,52 @@ type Provider interface {
 	Create(context.Context, *v1beta1.EC2NodeClass, *corev1beta1.NodeClaim, []*cloudprovider.InstanceType) (*Instance, error)
 	Get(context.Context, string) (*Instance, error)
 	List(context.Context) ([]*Instance, error)
+	Sweep(context.Context) ([]*Instance, error)
 	Delete(context.Context, string) error
+	Stop(context.Context, string) error
 	CreateTags(context.Context, string, map[string]string) error
+	BatchCreateTags(context.Context, []string, map[string]string) map[string]error
 }
 
 type DefaultProvider struct {
 	region                 string
 	ec2api                 ec2iface.EC2API
-	unavailableOfferings   *cache.UnavailableOfferings
+	unavailableOfferings   *awscache.UnavailableOfferings
 	instanceTypeProvider   instancetype.Provider
 	subnetProvider         subnet.Provider
 	launchTemplateProvider launchtemplate.Provider
+	placementGroupProvider placementgroup.Provider
+	spotAdvisorProvider    spotadvisor.Provider
+	costLimitProvider      costlimit.Provider
 	ec2Batcher             *batcher.EC2API
+	recorder               events.Recorder
+	// inflightLaunches tracks the NodeClaims whose CreateFleet request timed out. The instance may have been launched
+	// regardless, so we look for it before launching again.
+	inflightLaunches *cache.Cache
+	// launchAttempts counts the CreateFleet requests that EC2 answered for each NodeClaim. The count keys the client
+	// token, so a request retried after a timeout reuses its token while any other retry gets a new one.
+	launchAttempts *cache.Cache
+	// spotFallbackZones tracks the zone where each NodePool's last spot launch failed with insufficient capacity, so
+	// that the on-demand launch that falls back from it prefers the zone the spot launch targeted.
+	spotFallbackZones *cache.Cache
+	// instanceDescriptions holds the full description of each instance that Sweep has seen, or nil for the instances
+	// that aren't the cluster's, along with the state it was described in
+	instanceDescriptions *cache.Cache
+	clk                  clock.Clock
+	metricsExporter      *metricsexporter.Exporter
 }
 
-func NewDefaultProvider(ctx context.Context, region string, ec2api ec2iface.EC2API, unavailableOfferings *cache.UnavailableOfferings,
-	instanceTypeProvider instancetype.Provider, subnetProvider subnet.Provider, launchTemplateProvider launchtemplate.Provider) *DefaultProvider {
+// instanceDescription is the result of describing an instance in full. The instance is nil when it isn't one of the
+// cluster's instances.
+type instanceDescription struct {
+	instance *Instance
+	state    string
+}
+
+func NewDefaultProvider(ctx context.Context, region string, ec2api ec2iface.EC2API, unavailableOfferings *awscache.UnavailableOfferings,
+	instanceTypeProvider instancetype.Provider, subnetProvider subnet.Provider, launchTemplateProvider launchtemplate.Provider,
+	placementGroupProvider placementgroup.Provider, spotAdvisorProvider spotadvisor.Provider, costLimitProvider costlimit.Provider,
+	inflightLaunches *cache.Cache, launchAttempts *cache.Cache, spotFallbackZones *cache.Cache, instanceDescriptions *cache.Cache, clk clock.Clock, recorder events.Recorder, metricsExporter *metricsexporter.Exporter) *DefaultProvider {
 	return &DefaultProvider{
 		region:                 region,
 		ec2api:                 ec2api,
@@ -85,28 +138,138 @@ func NewDefaultProvider(ctx context.Context, region string, ec2api ec2iface.EC2A
 		instanceTypeProvider:   instanceTypeProvider,
 		subnetProvider:         subnetProvider,
 		launchTemplateProvider: launchTemplateProvider,
+		placementGroupProvider: placementGroupProvider,
+		spotAdvisorProvider:    spotAdvisorProvider,
+		costLimitProvider:      costLimitProvider,
 		ec2Batcher:             batcher.EC2(ctx, ec2api),
+		recorder:               recorder,
+		inflightLaunches:       inflightLaunches,
+		launchAttempts:         launchAttempts,
+		spotFallbackZones:      spotFallbackZones,
+		instanceDescriptions:   instanceDescriptions,
+		clk:                    clk,
+		metricsExporter:        metricsExporter,
 	}
 }
 
 func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) (*Instance, error) {
+	instanceTypeFunnel.With(prometheus.Labels{stageLabel: funnelStageRequirements}).Observe(float64(len(instanceTypes)))
+	instanceTypes, err := p.costLimitProvider.Filter(ctx, nodeClaim, instanceTypes)
+	if err != nil {
+		if costlimit.IsHourlyCostLimitExceeded(err) {
+			p.recorder.Publish(HourlyCostLimitExceededEvent(nodeClaim, err))
+		}
+		return nil, cloudprovider.NewInsufficientCapacityError(err)
+	}
+	// The price that Filter reserved against the NodePool's hourly cost limit is released when nothing is launched,
+	// and replaced by the price of the instance otherwise
+	instance, err := p.create(ctx, nodeClass, nodeClaim, instanceTypes)
+	if err != nil {
+		p.costLimitProvider.Release(nodeClaim)
+		return nil, err
+	}
+	p.costLimitProvider.Record(nodeClaim, instance.ID, instance.Type, instance.Zone, instance.CapacityType)
+	return instance, nil
+}
+
+func (p *DefaultProvider) create(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) (*Instance, error) {
+	start := p.clk.Now()
 	schedulingRequirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	// Only filter the instances if there are no minValues in the requirement.
+	var err error
+	if instanceTypes, err = p.filterInsufficientPodENIs(nodeClaim, instanceTypes); err != nil {
+		return nil, err
+	}
+	// Only filter the instances if there are no minValues in the requirement. Otherwise, the instance types are only
+	// truncated, keeping enough of them to satisfy the minValues.
 	if !schedulingRequirements.HasMinValues() {
-		instanceTypes = p.filterInstanceTypes(nodeClaim, instanceTypes)
+		instanceTypes = p.filterInstanceTypes(ctx, nodeClass, nodeClaim, instanceTypes)
+	} else {
+		if instanceTypes, err = p.truncateInstanceTypes(ctx, nodeClass, instanceTypes, schedulingRequirements, maxInstanceTypes); err != nil {
+			return nil, err
+		}
+		instanceTypeFunnel.With(prometheus.Labels{stageLabel: funnelStageMaxInstanceTypes}).Observe(float64(len(instanceTypes)))
 	}
 	tags := getTags(ctx, nodeClass, nodeClaim)
-	fleetInstance, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+	if _, ok := p.inflightLaunches.Get(string(nodeClaim.UID)); ok {
+		instance, err := p.getLaunchedInstance(ctx, nodeClaim)
+		if err != nil {
+			return nil, fmt.Errorf("checking for instance launched by timed out request, %w", err)
+		}
+		if instance != nil {
+			p.inflightLaunches.Delete(string(nodeClaim.UID))
+			if err := ctx.Err(); err != nil {
+				return nil, p.abandonLaunch(ctx, instance.ID, err)
+			}
+			logging.FromContext(ctx).With("id", instance.ID).Infof("found instance launched by timed out request")
+			return instance, nil
+		}
+	}
+	fleetInstance, fleetID, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
 	if awserrors.IsLaunchTemplateNotFound(err) {
 		// retry once if launch template is not found. This allows karpenter to generate a new LT if the
 		// cache was out-of-sync on the first try
-		fleetInstance, err = p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+		fleetInstance, fleetID, err = p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+	}
+	if awserrors.IsRequestTimeout(err) {
+		p.inflightLaunches.SetDefault(string(nodeClaim.UID), struct{}{})
 	}
 	if err != nil {
+		// The fleet request may have been cut short after EC2 accepted it, so look for an instance that was launched
+		// on our behalf before giving up on the launch
+		if ctxErr := ctx.Err(); ctxErr != nil && awserrors.IsRequestTimeout(err) {
+			if instance, getErr := p.getLaunchedInstance(context.WithoutCancel(ctx), nodeClaim); getErr == nil && instance != nil {
+				p.inflightLaunches.Delete(string(nodeClaim.UID))
+				return nil, p.abandonLaunch(ctx, instance.ID, ctxErr)
+			}
+		}
 		return nil, err
 	}
+	p.inflightLaunches.Delete(string(nodeClaim.UID))
+	if err := ctx.Err(); err != nil {
+		return nil, p.abandonLaunch(ctx, aws.StringValue(fleetInstance.InstanceIds[0]), err)
+	}
 	efaEnabled := lo.Contains(lo.Keys(nodeClaim.Spec.Resources.Requests), v1beta1.ResourceEFA)
-	return NewInstanceFromFleet(fleetInstance, tags, efaEnabled), nil
+	instance := NewInstanceFromFleet(fleetInstance, fleetID, lo.Assign(tags, getInstanceTags(ctx, nodeClaim)), efaEnabled)
+	p.metricsExporter.ObserveLaunch(instance.ID, start)
+	return instance, nil
+}
+
+// abandonLaunch terminates an instance that was launched for a request whose context has since been canceled, so that
+// a NodeClaim deleted mid-provisioning doesn't leave behind an instance that nothing tracks
+func (p *DefaultProvider) abandonLaunch(ctx context.Context, id string, cause error) error {
+	logging.FromContext(ctx).With("id", id).Infof("terminating instance launched for canceled request")
+	if err := p.Delete(context.WithoutCancel(ctx), id); err != nil && !cloudprovider.IsNodeClaimNotFoundError(err) {
+		return fmt.Errorf("launching instance, %w", multierr.Append(cause, fmt.Errorf("terminating instance %s, %w", id, err)))
+	}
+	return fmt.Errorf("launching instance, %w", cause)
+}
+
+// getLaunchedInstance returns the instance tagged for the NodeClaim, or nil if EC2 doesn't know of one
+func (p *DefaultProvider) getLaunchedInstance(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (*Instance, error) {
+	out, err := p.ec2api.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", v1beta1.TagNodeClaim)),
+				Values: aws.StringSlice([]string{nodeClaim.Name}),
+			},
+			{
+				Name:   aws.String("tag-key"),
+				Values: aws.StringSlice([]string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)}),
+			},
+			instanceStateFilter,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing ec2 instances, %w", err)
+	}
+	instances, err := instancesFromOutput(out)
+	if cloudprovider.IsNodeClaimNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return instances[0], nil
 }
 
 func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error) {
@@ -127,38 +290,133 @@ func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error)
 	if len(instances) != 1 {
 		return nil, fmt.Errorf("expected a single instance, %w", err)
 	}
+	p.metricsExporter.ObserveInstanceFound(instances[0].ID)
 	return instances[0], nil
 }
 
 func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 	var out = &ec2.DescribeInstancesOutput{}
+	var bytes int64
 	err := p.ec2api.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("tag-key"),
-				Values: aws.StringSlice([]string{corev1beta1.NodePoolLabelKey}),
-			},
-			{
-				Name:   aws.String("tag-key"),
-				Values: aws.StringSlice([]string{v1beta1.LabelNodeClass}),
-			},
-			{
-				Name:   aws.String("tag-key"),
-				Values: aws.StringSlice([]string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)}),
-			},
-			instanceStateFilter,
-		},
+		Filters: append(listFilters(ctx), instanceStateFilter),
 	}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
 		out.Reservations = append(out.Reservations, page.Reservations...)
 		return true
-	})
+	}, responseBytes(&bytes))
 	if err != nil {
 		return nil, fmt.Errorf("describing ec2 instances, %w", err)
 	}
 	instances, err := instancesFromOutput(out)
+	observeSweep(describeInstancesAPI, len(instances), bytes)
 	return instances, cloudprovider.IgnoreNodeClaimNotFoundError(err)
 }
 
+// Sweep returns the same instances as List, but only describes the instances in full that it hasn't described within
+// the InstanceDescriptionTTL or whose state has changed since. The state of every instance is read with
+// DescribeInstanceStatus, whose responses are a fraction of the size of DescribeInstances' since they don't carry the
+// tags and network interfaces, so that sweeps of large clusters don't read and unmarshal every instance in full.
+// DescribeInstanceStatus can't filter by tag, so it lists every instance in the region, but instances that aren't the
+// cluster's are only described once per InstanceDescriptionTTL, and only to find that they aren't. Sweep falls back to
+// List when the controller isn't permitted to call DescribeInstanceStatus.
+func (p *DefaultProvider) Sweep(ctx context.Context) ([]*Instance, error) {
+	states := map[string]string{}
+	var statusBytes int64
+	if err := p.ec2api.DescribeInstanceStatusPagesWithContext(ctx, &ec2.DescribeInstanceStatusInput{
+		IncludeAllInstances: aws.Bool(true),
+		Filters:             []*ec2.Filter{instanceStateFilter},
+	}, func(page *ec2.DescribeInstanceStatusOutput, _ bool) bool {
+		for _, status := range page.InstanceStatuses {
+			states[aws.StringValue(status.InstanceId)] = aws.StringValue(status.InstanceState.Name)
+		}
+		return true
+	}, responseBytes(&statusBytes)); err != nil {
+		if awserrors.IsUnauthorized(err) {
+			logging.FromContext(ctx).Debugf("falling back to describing instances in full, %s", err)
+			return p.List(ctx)
+		}
+		return nil, fmt.Errorf("describing ec2 instance status, %w", err)
+	}
+	observeSweep(describeInstanceStatusAPI, len(states), statusBytes)
+
+	stale := lo.Filter(lo.Keys(states), func(id string, _ int) bool {
+		description, ok := p.instanceDescriptions.Get(id)
+		return !ok || description.(instanceDescription).state != states[id]
+	})
+	described, bytes, err := p.describeByID(ctx, stale)
+	if err != nil {
+		return nil, err
+	}
+	observeSweep(describeInstancesAPI, len(described), bytes)
+	for _, id := range stale {
+		p.instanceDescriptions.SetDefault(id, instanceDescription{instance: described[id], state: states[id]})
+	}
+
+	var instances []*Instance
+	for id, state := range states {
+		description, ok := p.instanceDescriptions.Get(id)
+		if !ok || description.(instanceDescription).instance == nil {
+			continue
+		}
+		instance := *description.(instanceDescription).instance
+		instance.State = state
+		instances = append(instances, &instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+// describeByID describes the instances in full, returning those that are the cluster's by ID along with the number of
+// response bytes read. Instances are matched with an instance-id filter, rather than by ID, so that instances that were
+// terminated since they were listed are left out instead of failing the request.
+func (p *DefaultProvider) describeByID(ctx context.Context, ids []string) (map[string]*Instance, int64, error) {
+	var out = &ec2.DescribeInstancesOutput{}
+	var bytes int64
+	for _, chunk := range lo.Chunk(ids, maxDescribeFilterValues) {
+		if err := p.ec2api.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+			Filters: append(listFilters(ctx), &ec2.Filter{Name: aws.String("instance-id"), Values: aws.StringSlice(chunk)}),
+		}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+			out.Reservations = append(out.Reservations, page.Reservations...)
+			return true
+		}, responseBytes(&bytes)); err != nil {
+			return nil, 0, fmt.Errorf("describing ec2 instances, %w", err)
+		}
+	}
+	instances, err := instancesFromOutput(out)
+	if err = cloudprovider.IgnoreNodeClaimNotFoundError(err); err != nil {
+		return nil, 0, err
+	}
+	return lo.SliceToMap(instances, func(i *Instance) (string, *Instance) { return i.ID, i }), bytes, nil
+}
+
+// listFilters match the instances that are the cluster's and were launched for a NodePool
+func listFilters(ctx context.Context) []*ec2.Filter {
+	return []*ec2.Filter{
+		{
+			Name:   aws.String("tag-key"),
+			Values: aws.StringSlice([]string{corev1beta1.NodePoolLabelKey}),
+		},
+		{
+			Name:   aws.String("tag-key"),
+			Values: aws.StringSlice([]string{v1beta1.LabelNodeClass}),
+		},
+		{
+			Name:   aws.String("tag-key"),
+			Values: aws.StringSlice([]string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)}),
+		},
+	}
+}
+
+// responseBytes adds the size of each response to the total, for the responses whose size EC2 reports
+func responseBytes(total *int64) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			if r.HTTPResponse != nil && r.HTTPResponse.ContentLength > 0 {
+				*total += r.HTTPResponse.ContentLength
+			}
+		})
+	}
+}
+
 func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
 	if _, err := p.ec2Batcher.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
 		InstanceIds: []*string{aws.String(id)},
@@ -177,13 +435,30 @@ func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Stop stops the instance and then tags it with TagTerminationBehavior, which hides it from Get and List. The instance
+// is only tagged once it's stopping, so that an instance that can't be stopped is still managed by Karpenter. Stopping
+// an instance that is already stopping succeeds, so a failed tag is retried by stopping the instance again.
+func (p *DefaultProvider) Stop(ctx context.Context, id string) error {
+	if _, err := p.ec2api.StopInstancesWithContext(ctx, &ec2.StopInstancesInput{
+		InstanceIds: aws.StringSlice([]string{id}),
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance already terminated"))
+		}
+		return fmt.Errorf("stopping instance, %w", err)
+	}
+	if err := p.CreateTags(ctx, id, map[string]string{v1beta1.TagTerminationBehavior: string(v1beta1.TerminationBehaviorStop)}); err != nil {
+		return fmt.Errorf("tagging stopped instance, %w", err)
+	}
+	return nil
+}
+
 func (p *DefaultProvider) CreateTags(ctx context.Context, id string, tags map[string]string) error {
-	ec2Tags := lo.MapToSlice(tags, func(key, value string) *ec2.Tag {
-		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
-	})
+	// The instance's next sweep describes it again to pick up the tags
+	defer p.instanceDescriptions.Delete(id)
 	if _, err := p.ec2api.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
 		Resources: aws.StringSlice([]string{id}),
-		Tags:      ec2Tags,
+		Tags:      ec2Tags(tags),
 	}); err != nil {
 		if awserrors.IsNotFound(err) {
 			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("tagging instance, %w", err))
@@ -193,21 +468,69 @@ func (p *DefaultProvider) CreateTags(ctx context.Context, id string, tags map[st
 	return nil
 }
 
-func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, tags map[string]string) (*ec2.CreateFleetInstance, error) {
-	capacityType := p.getCapacityType(nodeClaim, instanceTypes)
-	zonalSubnets, err := p.subnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, capacityType)
+// BatchCreateTags applies the same tags to each of the instances, tagging up to maxCreateTagsResources instances with
+// a single CreateTags call. It returns the error of each instance that couldn't be tagged, keyed by instance ID.
+func (p *DefaultProvider) BatchCreateTags(ctx context.Context, ids []string, tags map[string]string) map[string]error {
+	errs := map[string]error{}
+	for _, batch := range lo.Chunk(ids, maxCreateTagsResources) {
+		if len(batch) > 1 {
+			if _, err := p.ec2api.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+				Resources: aws.StringSlice(batch),
+				Tags:      ec2Tags(tags),
+			}); err == nil {
+				for _, id := range batch {
+					p.instanceDescriptions.Delete(id)
+				}
+				continue
+			}
+		}
+		// A single instance that can't be tagged (e.g. because it was terminated) fails the whole call, so the batch
+		// is retried one instance at a time to attribute the failure to the instances that caused it
+		for _, id := range batch {
+			if err := p.CreateTags(ctx, id, tags); err != nil {
+				errs[id] = err
+			}
+		}
+	}
+	return errs
+}
+
+func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, tags map[string]string) (*ec2.CreateFleetInstance, string, error) {
+	capacityType := p.getCapacityType(nodeClass, nodeClaim, instanceTypes)
+	// A launch template targets a single capacity block, so capacity block launches are narrowed to the instance type
+	// and zone of one
+	if capacityType == v1beta1.CapacityTypeCapacityBlock {
+		instanceTypes = capacityBlockInstanceTypes(nodeClass, nodeClaim, instanceTypes)
+	}
+	zonalSubnets, err := p.subnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, zonalInstanceTypes(instanceTypes, scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone)), capacityType)
 	if err != nil {
-		return nil, fmt.Errorf("getting subnets, %w", err)
+		return nil, "", fmt.Errorf("getting subnets, %w", err)
+	}
+	zonalSubnets = zoneIDSubnets(zonalSubnets, scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1beta1.LabelTopologyZoneID))
+	placementGroup, err := p.placementGroupProvider.Get(ctx, nodeClass)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting placement group, %w", err)
+	}
+	// Cluster placement groups are confined to a single availability zone, so we can't let fleet choose across zones
+	if placementGroup != nil && aws.StringValue(placementGroup.Strategy) == ec2.PlacementStrategyCluster {
+		zonalSubnets = singleZoneSubnets(zonalSubnets, instanceTypes, scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone), capacityType)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("getting launch template configs, %w", err)
 	}
 
 	// Get Launch Template Configs, which may differ due to GPU or Architecture requirements
-	launchTemplateConfigs, err := p.getLaunchTemplateConfigs(ctx, nodeClass, nodeClaim, instanceTypes, zonalSubnets, capacityType, tags)
+	fallbackZone := p.getSpotFallbackZone(nodeClaim, capacityType, zonalSubnets)
+	launchTemplateConfigs, err := p.getLaunchTemplateConfigs(ctx, nodeClass, nodeClaim, instanceTypes, zonalSubnets, capacityType, fallbackZone, tags)
 	if err != nil {
-		return nil, fmt.Errorf("getting launch template configs, %w", err)
+		return nil, "", fmt.Errorf("getting launch template configs, %w", err)
 	}
-	if err := p.checkODFallback(nodeClaim, instanceTypes, launchTemplateConfigs); err != nil {
+	if err := p.checkODFallback(nodeClass, nodeClaim, instanceTypes, launchTemplateConfigs); err != nil {
 		logging.FromContext(ctx).Warn(err.Error())
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("creating fleet, %w", err)
+	}
 	// Create fleet
 	createFleetInput := &ec2.CreateFleetInput{
 		Type:                  aws.String(ec2.FleetTypeInstant),
@@ -218,37 +541,62 @@ func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1beta1
 			TotalTargetCapacity:       aws.Int64(1),
 		},
 		TagSpecifications: []*ec2.TagSpecification{
-			{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: utils.MergeTags(tags)},
+			// NodeClaim specific tags are only applied to the instance so that launch templates remain shareable across NodeClaims
+			{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: utils.MergeTags(tags, getInstanceTags(ctx, nodeClaim))},
 			{ResourceType: aws.String(ec2.ResourceTypeVolume), Tags: utils.MergeTags(tags)},
 			{ResourceType: aws.String(ec2.ResourceTypeFleet), Tags: utils.MergeTags(tags)},
 		},
 	}
 	if capacityType == corev1beta1.CapacityTypeSpot {
-		createFleetInput.SpotOptions = &ec2.SpotOptionsRequest{AllocationStrategy: aws.String(ec2.SpotAllocationStrategyPriceCapacityOptimized)}
-	} else {
-		createFleetInput.OnDemandOptions = &ec2.OnDemandOptionsRequest{AllocationStrategy: aws.String(ec2.FleetOnDemandAllocationStrategyLowestPrice)}
+		createFleetInput.SpotOptions = &ec2.SpotOptionsRequest{AllocationStrategy: aws.String(spotAllocationStrategy(ctx))}
+	} else if capacityType == corev1beta1.CapacityTypeOnDemand {
+		createFleetInput.OnDemandOptions = &ec2.OnDemandOptionsRequest{AllocationStrategy: aws.String(lo.Ternary(fallbackZone != "",
+			ec2.FleetOnDemandAllocationStrategyPrioritized, onDemandAllocationStrategy(ctx, nodeClass)))}
+	}
+	attempt := 0
+	if count, ok := p.launchAttempts.Get(string(nodeClaim.UID)); ok {
+		attempt = count.(int)
 	}
+	createFleetInput.ClientToken = aws.String(clientToken(nodeClaim, attempt))
 
 	createFleetOutput, err := p.ec2Batcher.CreateFleet(ctx, createFleetInput)
+	if !awserrors.IsRequestTimeout(err) {
+		p.launchAttempts.SetDefault(string(nodeClaim.UID), attempt+1)
+	}
 	p.subnetProvider.UpdateInflightIPs(createFleetInput, createFleetOutput, instanceTypes, lo.Values(zonalSubnets), capacityType)
 	if err != nil {
+		p.metricsExporter.ObserveCreateFleetError(createFleetErrorCode(err))
 		if awserrors.IsLaunchTemplateNotFound(err) {
 			for _, lt := range launchTemplateConfigs {
 				p.launchTemplateProvider.InvalidateCache(ctx, aws.StringValue(lt.LaunchTemplateSpecification.LaunchTemplateName), aws.StringValue(lt.LaunchTemplateSpecification.LaunchTemplateId))
 			}
-			return nil, fmt.Errorf("creating fleet %w", err)
+			return nil, "", fmt.Errorf("creating fleet %w", err)
 		}
 		var reqFailure awserr.RequestFailure
 		if errors.As(err, &reqFailure) {
-			return nil, fmt.Errorf("creating fleet %w (%s)", err, reqFailure.RequestID())
+			return nil, "", fmt.Errorf("creating fleet %w (%s)", err, reqFailure.RequestID())
 		}
-		return nil, fmt.Errorf("creating fleet %w", err)
+		return nil, "", fmt.Errorf("creating fleet %w", err)
+	}
+	for _, fleetErr := range createFleetOutput.Errors {
+		p.metricsExporter.ObserveCreateFleetError(aws.StringValue(fleetErr.ErrorCode))
 	}
-	p.updateUnavailableOfferingsCache(ctx, createFleetOutput.Errors, capacityType)
+	p.updateUnavailableOfferingsCache(ctx, nodeClass, createFleetOutput.Errors, capacityType)
 	if len(createFleetOutput.Instances) == 0 || len(createFleetOutput.Instances[0].InstanceIds) == 0 {
-		return nil, combineFleetErrors(createFleetOutput.Errors)
+		if capacityType == corev1beta1.CapacityTypeSpot {
+			p.recordSpotFallbackZone(nodeClaim, createFleetOutput.Errors)
+		}
+		return nil, "", combineFleetErrors(createFleetOutput.Errors)
 	}
-	return createFleetOutput.Instances[0], nil
+	return createFleetOutput.Instances[0], aws.StringValue(createFleetOutput.FleetId), nil
+}
+
+// clientToken derives the CreateFleet idempotency token from the NodeClaim UID and its launch attempt so that a request
+// retried after a timeout returns the original instance instead of launching another one. The attempt moves on once EC2
+// answers a request, including when it rejects a retry whose offerings or subnets changed, since reusing the token after
+// that would only return the original result.
+func clientToken(nodeClaim *corev1beta1.NodeClaim, attempt int) string {
+	return fmt.Sprintf("%s-%d", nodeClaim.UID, attempt)
 }
 
 func getTags(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim) map[string]string {
@@ -261,9 +609,50 @@ func getTags(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *co
 	return lo.Assign(nodeClass.Spec.Tags, staticTags)
 }
 
-func (p *DefaultProvider) checkODFallback(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, launchTemplateConfigs []*ec2.FleetLaunchTemplateConfigRequest) error {
-	// only evaluate for on-demand fallback if the capacity type for the request is OD and both OD and spot are allowed in requirements
-	if p.getCapacityType(nodeClaim, instanceTypes) != corev1beta1.CapacityTypeOnDemand || !scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+// OwnershipTags returns the tags applied to an instance at launch that associate it with its cluster, NodePool,
+// EC2NodeClass and NodeClaim, along with the EC2NodeClass's tags. Instances missing any of the ownership tags are not
+// discovered when listing instances.
+func OwnershipTags(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim) map[string]string {
+	return lo.Assign(getTags(ctx, nodeClass, nodeClaim), map[string]string{v1beta1.TagNodeClaim: nodeClaim.Name})
+}
+
+// getInstanceTags returns the tags that are specific to the NodeClaim and only applied to the instance
+func getInstanceTags(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) map[string]string {
+	tags := map[string]string{v1beta1.TagNodeClaim: nodeClaim.Name}
+	if options.FromContext(ctx).TaintTags {
+		tags = lo.Assign(tags, TaintTags(nodeClaim))
+	}
+	return tags
+}
+
+// TaintTags serializes the NodeClaim's taints, followed by its startup taints, into the TagTaints tag. Taints that don't
+// fit within the tag value length limit are dropped from the end of the list and TagTaintsTruncated is set to "true".
+func TaintTags(nodeClaim *corev1beta1.NodeClaim) map[string]string {
+	var serialized []string
+	truncated := false
+	length := 0
+	for _, taint := range lo.Flatten([][]v1.Taint{nodeClaim.Spec.Taints, nodeClaim.Spec.StartupTaints}) {
+		entry := taint.ToString()
+		if len(serialized) > 0 {
+			entry = "," + entry
+		}
+		if length+len(entry) > maxTagValueLength {
+			truncated = true
+			break
+		}
+		serialized = append(serialized, entry)
+		length += len(entry)
+	}
+	return map[string]string{
+		v1beta1.TagTaints:          strings.Join(serialized, ""),
+		v1beta1.TagTaintsTruncated: lo.Ternary(truncated, "true", "false"),
+	}
+}
+
+func (p *DefaultProvider) checkODFallback(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, launchTemplateConfigs []*ec2.FleetLaunchTemplateConfigRequest) error {
+	// only evaluate for on-demand fallback if the capacity type for the request is OD and both OD and spot are allowed in requirements.
+	// Launches onto an Outpost are always on-demand, so they never fall back.
+	if nodeClass.Spec.OutpostARN != nil || p.getCapacityType(nodeClass, nodeClaim, instanceTypes) != corev1beta1.CapacityTypeOnDemand || !scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
 		return nil
 	}
 
@@ -285,15 +674,37 @@ func (p *DefaultProvider) checkODFallback(nodeClaim *corev1beta1.NodeClaim, inst
 }
 
 func (p *DefaultProvider) getLaunchTemplateConfigs(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim,
-	instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*ec2.Subnet, capacityType string, tags map[string]string) ([]*ec2.FleetLaunchTemplateConfigRequest, error) {
+	instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*ec2.Subnet, capacityType string, fallbackZone string, tags map[string]string) ([]*ec2.FleetLaunchTemplateConfigRequest, error) {
 	var launchTemplateConfigs []*ec2.FleetLaunchTemplateConfigRequest
 	launchTemplates, err := p.launchTemplateProvider.EnsureAll(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
 	if err != nil {
 		return nil, fmt.Errorf("getting launch templates, %w", err)
 	}
+	var priorities map[string]float64
+	if capacityType == corev1beta1.CapacityTypeOnDemand && onDemandAllocationStrategy(ctx, nodeClass) == ec2.FleetOnDemandAllocationStrategyPrioritized {
+		var patterns []string
+		if nodeClass.Spec.OnDemandOptions != nil {
+			patterns = nodeClass.Spec.OnDemandOptions.InstanceTypePriorities
+		}
+		priorities = instanceTypePriorities(nodeClaim, instanceTypes, capacityType, patterns)
+	} else if fallbackZone != "" {
+		priorities = instanceTypePriorities(nodeClaim, instanceTypes, capacityType, nil)
+	}
+	if capacityType == corev1beta1.CapacityTypeSpot && spotAllocationStrategy(ctx) == ec2.SpotAllocationStrategyCapacityOptimizedPrioritized {
+		priorities = p.spotPriorities(ctx, nodeClaim, instanceTypes)
+	}
+	amiArchitectures := amiArchitectures(nodeClass)
+	incompatible := sets.New[string]()
 	for _, launchTemplate := range launchTemplates {
+		compatible := lo.Filter(launchTemplate.InstanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+			if matchesArchitecture(it, amiArchitectures) {
+				return true
+			}
+			incompatible.Insert(it.Name)
+			return false
+		})
 		launchTemplateConfig := &ec2.FleetLaunchTemplateConfigRequest{
-			Overrides: p.getOverrides(launchTemplate.InstanceTypes, zonalSubnets, scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone), capacityType, launchTemplate.ImageID),
+			Overrides: p.getOverrides(compatible, zonalSubnets, scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone), capacityType, launchTemplate.ImageID, priorities, fallbackZone),
 			LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
 				LaunchTemplateName: aws.String(launchTemplate.Name),
 				Version:            aws.String("$Latest"),
@@ -303,15 +714,55 @@ func (p *DefaultProvider) getLaunchTemplateConfigs(ctx context.Context, nodeClas
 			launchTemplateConfigs = append(launchTemplateConfigs, launchTemplateConfig)
 		}
 	}
+	if incompatible.Len() > 0 {
+		logging.FromContext(ctx).With("instance-types", sets.List(incompatible)).Debugf("dropping instance types whose architecture doesn't match any resolved AMI")
+	}
+	p.recordLaunchableInstanceTypes(ctx, nodeClaim, launchTemplateConfigs)
 	if len(launchTemplateConfigs) == 0 {
 		return nil, fmt.Errorf("no capacity offerings are currently available given the constraints")
 	}
 	return launchTemplateConfigs, nil
 }
 
+// amiArchitectures returns the architecture requirement of each AMI that the EC2NodeClass resolved
+func amiArchitectures(nodeClass *v1beta1.EC2NodeClass) []*scheduling.Requirement {
+	return lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) *scheduling.Requirement {
+		return scheduling.NewNodeSelectorRequirementsWithMinValues(ami.Requirements...).Get(v1.LabelArchStable)
+	})
+}
+
+// matchesArchitecture returns whether the instance type's architecture is allowed by any of the AMIs' architectures.
+// CreateFleet fails overrides that launch an AMI on an instance type of another architecture, so those are dropped
+// before the fleet is submitted. Every instance type matches when no AMIs have been resolved.
+func matchesArchitecture(instanceType *cloudprovider.InstanceType, amiArchitectures []*scheduling.Requirement) bool {
+	if len(amiArchitectures) == 0 {
+		return true
+	}
+	return lo.ContainsBy(amiArchitectures, func(architecture *scheduling.Requirement) bool {
+		return architecture.Intersection(instanceType.Requirements.Get(v1.LabelArchStable)).Len() > 0
+	})
+}
+
+// recordLaunchableInstanceTypes observes the number of distinct instance types that made it into the fleet request and
+// warns on the NodeClaim when that number falls below the configured minimum
+func (p *DefaultProvider) recordLaunchableInstanceTypes(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, launchTemplateConfigs []*ec2.FleetLaunchTemplateConfigRequest) {
+	launchable := sets.New[string]()
+	for _, launchTemplateConfig := range launchTemplateConfigs {
+		for _, override := range launchTemplateConfig.Overrides {
+			launchable.Insert(aws.StringValue(override.InstanceType))
+		}
+	}
+	instanceTypeFunnel.With(prometheus.Labels{stageLabel: funnelStageOfferings}).Observe(float64(launchable.Len()))
+	if minimum := options.FromContext(ctx).MinLaunchInstanceTypes; launchable.Len() < minimum {
+		p.recorder.Publish(LaunchableInstanceTypesBelowMinimumEvent(nodeClaim, launchable.Len(), minimum))
+	}
+}
+
 // getOverrides creates and returns launch template overrides for the cross product of InstanceTypes and subnets (with subnets being constrained by
-// zones and the offerings in InstanceTypes)
-func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*ec2.Subnet, zones *scheduling.Requirement, capacityType string, image string) []*ec2.FleetLaunchTemplateOverridesRequest {
+// zones and the offerings in InstanceTypes). If priorities are passed, each override is assigned the priority of its instance type.
+// If a fallback zone is passed, the overrides outside of it are ranked after every override in it.
+func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*ec2.Subnet, zones *scheduling.Requirement, capacityType string, image string,
+	priorities map[string]float64, fallbackZone string) []*ec2.FleetLaunchTemplateOverridesRequest {
 	// Unwrap all the offerings to a flat slice that includes a pointer
 	// to the parent instance type name
 	type offeringWithParentName struct {
@@ -341,37 +792,260 @@ func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceTy
 		if !ok {
 			continue
 		}
-		overrides = append(overrides, &ec2.FleetLaunchTemplateOverridesRequest{
+		override := &ec2.FleetLaunchTemplateOverridesRequest{
 			InstanceType: aws.String(offering.parentInstanceTypeName),
 			SubnetId:     subnet.SubnetId,
 			ImageId:      aws.String(image),
 			// This is technically redundant, but is useful if we have to parse insufficient capacity errors from
 			// CreateFleet so that we can figure out the zone rather than additional API calls to look up the subnet
 			AvailabilityZone: subnet.AvailabilityZone,
-		})
+		}
+		if priority, ok := priorities[offering.parentInstanceTypeName]; ok {
+			if fallbackZone != "" && offering.Zone != fallbackZone {
+				priority += float64(len(priorities))
+			}
+			override.Priority = aws.Float64(priority)
+		}
+		overrides = append(overrides, override)
 	}
 	return overrides
 }
 
-func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, errors []*ec2.CreateFleetError, capacityType string) {
+// onDemandAllocationStrategy returns the on-demand allocation strategy of the EC2NodeClass, falling back to the strategy
+// configured on the operator
+func onDemandAllocationStrategy(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) string {
+	if nodeClass.Spec.OnDemandOptions != nil && nodeClass.Spec.OnDemandOptions.AllocationStrategy != nil {
+		return aws.StringValue(nodeClass.Spec.OnDemandOptions.AllocationStrategy)
+	}
+	return options.FromContext(ctx).OnDemandAllocationStrategy
+}
+
+// spotAllocationStrategy returns the allocation strategy for spot fleet requests. Instance types are prioritized by the
+// order that accounts for their interruption frequency when a spot interruption penalty is configured.
+func spotAllocationStrategy(ctx context.Context) string {
+	if options.FromContext(ctx).SpotInterruptionPenalty > 0 {
+		return ec2.SpotAllocationStrategyCapacityOptimizedPrioritized
+	}
+	return ec2.SpotAllocationStrategyPriceCapacityOptimized
+}
+
+// spotPriorities assigns each instance type its position in the spot order from orderInstanceTypes. Fleet favors the
+// override with the lowest priority value on a best-effort basis, after optimizing for capacity.
+func (p *DefaultProvider) spotPriorities(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) map[string]float64 {
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	requirements.Add(scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeSpot))
+	ordered := p.orderInstanceTypes(ctx, instanceTypes, requirements)
+	return lo.SliceToMap(lo.Range(len(ordered)), func(i int) (string, float64) {
+		return ordered[i].Name, float64(i)
+	})
+}
+
+// orderInstanceTypes orders the instance types from cheapest to most expensive, breaking ties by name. When a spot
+// interruption penalty is configured and the requirements allow spot, the price of each instance type with a compatible
+// spot offering is scaled up by the penalty multiplied by the instance type's interruption frequency from the spot
+// advisor. Instance types that the spot advisor doesn't list, which is all of them until its data has been fetched, are
+// ordered by price alone.
+func (p *DefaultProvider) orderInstanceTypes(ctx context.Context, instanceTypes []*cloudprovider.InstanceType, requirements scheduling.Requirements) []*cloudprovider.InstanceType {
+	// OrderByPrice sorts in place, so we copy the slice to avoid reordering the caller's instance types
+	ordered := append(cloudprovider.InstanceTypes{}, instanceTypes...).OrderByPrice(requirements)
+	penalty := options.FromContext(ctx).SpotInterruptionPenalty
+	if penalty == 0 || !requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+		return ordered
+	}
+	scores := lo.SliceToMap(ordered, func(it *cloudprovider.InstanceType) (string, float64) {
+		offerings := it.Offerings.Available().Compatible(requirements)
+		if len(offerings) == 0 {
+			return it.Name, math.MaxFloat64
+		}
+		price := offerings.Cheapest().Price
+		if !lo.ContainsBy(offerings, func(o cloudprovider.Offering) bool { return o.CapacityType == corev1beta1.CapacityTypeSpot }) {
+			return it.Name, price
+		}
+		rate, ok := p.spotAdvisorProvider.InterruptionRate(it.Name, p.region)
+		if !ok {
+			return it.Name, price
+		}
+		return it.Name, price * (1 + penalty*rate)
+	})
+	// The sort is stable so that equal scores keep the price and name order
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scores[ordered[i].Name] < scores[ordered[j].Name]
+	})
+	return ordered
+}
+
+// truncationOrder orders the instance types with orderInstanceTypes for truncation to maxInstanceTypes. When on-demand
+// launches use the prioritized allocation strategy and the requirements allow on-demand, the instance types are then
+// ordered by the first of the EC2NodeClass's instance type priorities they match, so that the prioritized instance types
+// aren't truncated away in favor of cheaper ones.
+func (p *DefaultProvider) truncationOrder(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, instanceTypes []*cloudprovider.InstanceType, requirements scheduling.Requirements) []*cloudprovider.InstanceType {
+	ordered := p.orderInstanceTypes(ctx, instanceTypes, requirements)
+	if onDemandAllocationStrategy(ctx, nodeClass) != ec2.FleetOnDemandAllocationStrategyPrioritized || nodeClass.Spec.OnDemandOptions == nil ||
+		!requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeOnDemand) {
+		return ordered
+	}
+	patterns := nodeClass.Spec.OnDemandOptions.InstanceTypePriorities
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return patternRank(patterns, ordered[i]) < patternRank(patterns, ordered[j])
+	})
+	return ordered
+}
+
+// patternRank returns the index of the first pattern that the instance type matches, or the number of patterns if it
+// doesn't match any.
+func patternRank(patterns []string, it *cloudprovider.InstanceType) int {
+	_, i, ok := lo.FindIndexOf(patterns, func(pattern string) bool {
+		matched, err := path.Match(pattern, it.Name)
+		return err == nil && matched
+	})
+	return lo.Ternary(ok, i, len(patterns))
+}
+
+// instanceTypePriorities assigns each instance type its position when ordered by the first of the patterns it matches and
+// then by price, from cheapest to most expensive. Instance types that don't match any pattern are ordered last. Fleet
+// launches the override with the lowest priority value first when using the prioritized allocation strategy.
+func instanceTypePriorities(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, patterns []string) map[string]float64 {
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	requirements.Add(scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, capacityType))
+	// OrderByPrice sorts in place, so we copy the slice to avoid reordering the caller's instance types
+	ordered := append(cloudprovider.InstanceTypes{}, instanceTypes...).OrderByPrice(requirements)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return patternRank(patterns, ordered[i]) < patternRank(patterns, ordered[j])
+	})
+	return lo.SliceToMap(lo.Range(len(ordered)), func(i int) (string, float64) {
+		return ordered[i].Name, float64(i)
+	})
+}
+
+// zonalInstanceTypes copies the instance types with only the offerings in the required zones, so that subnets in zones
+// the NodeClaim can't launch into aren't chosen over exhausted subnets in the zones it can.
+func zonalInstanceTypes(instanceTypes []*cloudprovider.InstanceType, zones *scheduling.Requirement) []*cloudprovider.InstanceType {
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		return &cloudprovider.InstanceType{
+			Name:         it.Name,
+			Requirements: it.Requirements,
+			Offerings:    lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool { return zones.Has(o.Zone) }),
+			Capacity:     it.Capacity,
+			Overhead:     it.Overhead,
+		}
+	})
+}
+
+// zoneIDSubnets drops the zonal subnets whose zone ID doesn't satisfy the zone ID requirement, so that launches
+// restricted by zone ID only use subnets in those zones. Offerings are keyed by zone name, so this is what restricts the
+// overrides to the required zone IDs.
+func zoneIDSubnets(zonalSubnets map[string]*ec2.Subnet, zoneIDs *scheduling.Requirement) map[string]*ec2.Subnet {
+	if zoneIDs.Operator() == v1.NodeSelectorOpExists {
+		return zonalSubnets
+	}
+	return lo.PickBy(zonalSubnets, func(_ string, subnet *ec2.Subnet) bool {
+		return subnet.AvailabilityZoneId != nil && zoneIDs.Has(aws.StringValue(subnet.AvailabilityZoneId))
+	})
+}
+
+// singleZoneSubnets narrows the zonal subnets down to the zone with the most available offerings so that launches into a
+// cluster placement group retain as much instance type flexibility as possible
+func singleZoneSubnets(zonalSubnets map[string]*ec2.Subnet, instanceTypes []*cloudprovider.InstanceType, zones *scheduling.Requirement, capacityType string) map[string]*ec2.Subnet {
+	offeringsPerZone := map[string]int{}
+	for _, it := range instanceTypes {
+		for _, offering := range it.Offerings.Available() {
+			if _, ok := zonalSubnets[offering.Zone]; ok && offering.CapacityType == capacityType && zones.Has(offering.Zone) {
+				offeringsPerZone[offering.Zone]++
+			}
+		}
+	}
+	if len(offeringsPerZone) == 0 {
+		return zonalSubnets
+	}
+	zone := lo.MaxBy(lo.Keys(offeringsPerZone), func(a, b string) bool {
+		if offeringsPerZone[a] == offeringsPerZone[b] {
+			return a < b
+		}
+		return offeringsPerZone[a] > offeringsPerZone[b]
+	})
+	return map[string]*ec2.Subnet{zone: zonalSubnets[zone]}
+}
+
+// updateUnavailableOfferingsCache marks the offerings that the fleet request couldn't launch as unavailable. Launches
+// into a capacity block only mark the offering unavailable in the scope of the reservation.
+func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, errors []*ec2.CreateFleetError, capacityType string) {
 	for _, err := range errors {
-		if awserrors.IsUnfulfillableCapacity(err) {
-			p.unavailableOfferings.MarkUnavailableForFleetErr(ctx, err, capacityType)
+		if !awserrors.IsUnfulfillableCapacity(err) {
+			continue
+		}
+		scope := awscache.UnavailableOfferingsScopeDefault
+		if capacityType == v1beta1.CapacityTypeCapacityBlock {
+			if capacityReservation, ok := nodeClass.CapacityBlockReservation(aws.StringValue(err.LaunchTemplateAndOverrides.Overrides.InstanceType),
+				aws.StringValue(err.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)); ok {
+				scope = awscache.ReservationScope(capacityReservation.ID)
+			}
+		}
+		p.unavailableOfferings.MarkUnavailableForFleetErr(ctx, err, capacityType, scope)
+	}
+}
+
+// recordSpotFallbackZone remembers the zone where the NodePool's spot launch failed with insufficient capacity. When the
+// failed overrides span several zones, the zone with the most of them is the one the launch was targeting, with ties
+// broken by name.
+func (p *DefaultProvider) recordSpotFallbackZone(nodeClaim *corev1beta1.NodeClaim, errs []*ec2.CreateFleetError) {
+	failuresPerZone := map[string]int{}
+	for _, err := range errs {
+		if awserrors.IsUnfulfillableCapacity(err) && err.LaunchTemplateAndOverrides != nil && err.LaunchTemplateAndOverrides.Overrides != nil {
+			if zone := aws.StringValue(err.LaunchTemplateAndOverrides.Overrides.AvailabilityZone); zone != "" {
+				failuresPerZone[zone]++
+			}
 		}
 	}
+	if len(failuresPerZone) == 0 {
+		return
+	}
+	zone := lo.MaxBy(lo.Keys(failuresPerZone), func(a, b string) bool {
+		if failuresPerZone[a] == failuresPerZone[b] {
+			return a < b
+		}
+		return failuresPerZone[a] > failuresPerZone[b]
+	})
+	p.spotFallbackZones.SetDefault(nodeClaim.Labels[corev1beta1.NodePoolLabelKey], zone)
+}
+
+// getSpotFallbackZone returns the zone that an on-demand launch should prefer because the NodePool's spot launch just
+// failed there. No zone is preferred when the NodeClaim is restricted to a single zone, since there's nothing else for
+// fleet to choose, or when the NodeClaim can't launch into the zone.
+func (p *DefaultProvider) getSpotFallbackZone(nodeClaim *corev1beta1.NodeClaim, capacityType string, zonalSubnets map[string]*ec2.Subnet) string {
+	if capacityType != corev1beta1.CapacityTypeOnDemand {
+		return ""
+	}
+	zone, ok := p.spotFallbackZones.Get(nodeClaim.Labels[corev1beta1.NodePoolLabelKey])
+	if !ok {
+		return ""
+	}
+	zones := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone)
+	if _, ok := zonalSubnets[zone.(string)]; !ok || zones.Len() < 2 || !zones.Has(zone.(string)) {
+		return ""
+	}
+	return zone.(string)
 }
 
-// getCapacityType selects spot if both constraints are flexible and there is an
-// available offering. The AWS Cloud Provider defaults to [ on-demand ], so spot
-// must be explicitly included in capacity type requirements.
-func (p *DefaultProvider) getCapacityType(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) string {
+// getCapacityType selects capacity-block if it's allowed and there is an available
+// offering, since capacity blocks are already paid for. Otherwise, it selects spot
+// if both constraints are flexible and there is an available offering. The AWS
+// Cloud Provider defaults to [ on-demand ], so spot must be explicitly included
+// in capacity type requirements. Outposts don't support spot, so EC2NodeClasses
+// with an Outpost always launch on-demand.
+func (p *DefaultProvider) getCapacityType(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) string {
+	if nodeClass.Spec.OutpostARN != nil {
+		return corev1beta1.CapacityTypeOnDemand
+	}
 	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.
 		Spec.Requirements...)
-	if requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+	for _, capacityType := range []string{v1beta1.CapacityTypeCapacityBlock, corev1beta1.CapacityTypeSpot} {
+		if !requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(capacityType) {
+			continue
+		}
 		for _, instanceType := range instanceTypes {
 			for _, offering := range instanceType.Offerings.Available() {
-				if requirements.Get(v1.LabelTopologyZone).Has(offering.Zone) && offering.CapacityType == corev1beta1.CapacityTypeSpot {
-					return corev1beta1.CapacityTypeSpot
+				if requirements.Get(v1.LabelTopologyZone).Has(offering.Zone) && offering.CapacityType == capacityType {
+					return capacityType
 				}
 			}
 		}
@@ -379,18 +1053,125 @@ func (p *DefaultProvider) getCapacityType(nodeClaim *corev1beta1.NodeClaim, inst
 	return corev1beta1.CapacityTypeOnDemand
 }
 
+// capacityBlockInstanceTypes narrows the instance types down to the first one with an available capacity-block
+// offering in the required zones, with only that offering
+func capacityBlockInstanceTypes(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	zones := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone)
+	for _, it := range instanceTypes {
+		for _, offering := range it.Offerings.Available() {
+			if offering.CapacityType != v1beta1.CapacityTypeCapacityBlock || !zones.Has(offering.Zone) {
+				continue
+			}
+			if _, ok := nodeClass.CapacityBlockReservation(it.Name, offering.Zone); !ok {
+				continue
+			}
+			return []*cloudprovider.InstanceType{{
+				Name:         it.Name,
+				Requirements: it.Requirements,
+				Offerings:    cloudprovider.Offerings{offering},
+				Capacity:     it.Capacity,
+				Overhead:     it.Overhead,
+			}}
+		}
+	}
+	return nil
+}
+
 // filterInstanceTypes is used to provide filtering on the list of potential instance types to further limit it to those
 // that make the most sense given our specific AWS cloudprovider.
-func (p *DefaultProvider) filterInstanceTypes(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+func (p *DefaultProvider) filterInstanceTypes(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
 	instanceTypes = filterExoticInstanceTypes(instanceTypes)
+	instanceTypeFunnel.With(prometheus.Labels{stageLabel: funnelStageExotic}).Observe(float64(len(instanceTypes)))
 	// If we could potentially launch either a spot or on-demand node, we want to filter out the spot instance types that
 	// are more expensive than the cheapest on-demand type.
 	if p.isMixedCapacityLaunch(nodeClaim, instanceTypes) {
 		instanceTypes = filterUnwantedSpot(instanceTypes)
 	}
+	instanceTypeFunnel.With(prometheus.Labels{stageLabel: funnelStageSpotPrice}).Observe(float64(len(instanceTypes)))
+	if len(instanceTypes) > maxInstanceTypes {
+		requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+		instanceTypes = p.truncationOrder(ctx, nodeClass, instanceTypes, requirements)[:maxInstanceTypes]
+	}
+	instanceTypeFunnel.With(prometheus.Labels{stageLabel: funnelStageMaxInstanceTypes}).Observe(float64(len(instanceTypes)))
 	return instanceTypes
 }
 
+// filterInsufficientPodENIs removes the instance types without enough branch interfaces for the pod ENIs requested by
+// the NodeClaim's pods. Pods using security groups for pods each consume a branch interface of the trunk ENI, so these
+// pods would stay pending on a node launched with too few of them. An ICE error is returned if no instance type has enough.
+func (p *DefaultProvider) filterInsufficientPodENIs(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error) {
+	requested, ok := nodeClaim.Spec.Resources.Requests[v1beta1.ResourceAWSPodENI]
+	if !ok || requested.IsZero() {
+		return instanceTypes, nil
+	}
+	remaining := lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+		capacity, ok := it.Capacity[v1beta1.ResourceAWSPodENI]
+		return ok && capacity.Cmp(requested) >= 0
+	})
+	if len(remaining) == 0 && len(instanceTypes) > 0 {
+		p.recorder.Publish(InsufficientPodENIsEvent(nodeClaim, requested.Value()))
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("no instance type supports the %d %s requested by the pods", requested.Value(), v1beta1.ResourceAWSPodENI))
+	}
+	return remaining, nil
+}
+
+// truncateInstanceTypes orders the instance types with truncationOrder and truncates them to at most maxItems, while
+// keeping at least minValues distinct values for every requirement that sets minValues. The first instance types that
+// add a value still needed by a requirement are kept first, and the remaining room is filled with the first of the rest.
+// An error is returned if the minValues can't be satisfied within maxItems instance types.
+func (p *DefaultProvider) truncateInstanceTypes(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, instanceTypes []*cloudprovider.InstanceType, requirements scheduling.Requirements, maxItems int) ([]*cloudprovider.InstanceType, error) {
+	ordered := p.truncationOrder(ctx, nodeClass, instanceTypes, requirements)
+	minValues := lo.PickBy(lo.SliceToMap(requirements.Keys().UnsortedList(), func(key string) (string, int) {
+		return key, lo.FromPtr(requirements.Get(key).MinValues)
+	}), func(_ string, minValues int) bool { return minValues > 0 })
+
+	values := lo.MapValues(minValues, func(_ int, _ string) sets.Set[string] { return sets.New[string]() })
+	satisfied := func() bool {
+		return lo.EveryBy(lo.Keys(minValues), func(key string) bool { return values[key].Len() >= minValues[key] })
+	}
+	kept := sets.New[string]()
+	for _, it := range ordered {
+		if satisfied() {
+			break
+		}
+		addsValue := false
+		for key := range minValues {
+			if values[key].Len() < minValues[key] && !values[key].IsSuperset(sets.New(it.Requirements.Get(key).Values()...)) {
+				addsValue = true
+			}
+		}
+		if !addsValue {
+			continue
+		}
+		kept.Insert(it.Name)
+		for key := range minValues {
+			values[key].Insert(it.Requirements.Get(key).Values()...)
+		}
+	}
+	if !satisfied() {
+		return nil, fmt.Errorf("instance types don't satisfy minValues, %s", minValuesShortfall(minValues, values))
+	}
+	if kept.Len() > maxItems {
+		return nil, fmt.Errorf("satisfying minValues requires %d instance types, more than the maximum of %d in a launch", kept.Len(), maxItems)
+	}
+	for _, it := range ordered {
+		if kept.Len() >= maxItems {
+			break
+		}
+		kept.Insert(it.Name)
+	}
+	return lo.Filter(ordered, func(it *cloudprovider.InstanceType, _ int) bool { return kept.Has(it.Name) }), nil
+}
+
+// minValuesShortfall describes the requirements whose minValues aren't met by the values found
+func minValuesShortfall(minValues map[string]int, values map[string]sets.Set[string]) string {
+	keys := lo.Filter(lo.Keys(minValues), func(key string, _ int) bool { return values[key].Len() < minValues[key] })
+	sort.Strings(keys)
+	return strings.Join(lo.Map(keys, func(key string, _ int) string {
+		return fmt.Sprintf("%s requires %d values but only %d are available", key, minValues[key], values[key].Len())
+	}), ", ")
+}
+
 // isMixedCapacityLaunch returns true if nodepools and available offerings could potentially allow either a spot or
 // and on-demand node to launch
 func (p *DefaultProvider) isMixedCapacityLaunch(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) bool {
@@ -452,7 +1233,7 @@ func filterExoticInstanceTypes(instanceTypes []*cloudprovider.InstanceType) []*c
 	for _, it := range instanceTypes {
 		// deprioritize metal even if our opinionated filter isn't applied due to something like an instance family
 		// requirement
-		if _, ok := lo.Find(it.Requirements.Get(v1beta1.LabelInstanceSize).Values(), func(size string) bool { return strings.Contains(size, "metal") }); ok {
+		if it.Requirements.Get(v1beta1.LabelInstanceBareMetal).Has("true") {
 			continue
 		}
 		if !resources.IsZero(it.Capacity[v1beta1.ResourceAWSNeuron]) ||
@@ -477,6 +1258,14 @@ func instancesFromOutput(out *ec2.DescribeInstancesOutput) ([]*Instance, error)
 	instances := lo.Flatten(lo.Map(out.Reservations, func(r *ec2.Reservation, _ int) []*ec2.Instance {
 		return r.Instances
 	}))
+	// Pages can repeat an instance when its state changes mid-pagination
+	instances = lo.UniqBy(instances, func(i *ec2.Instance) string { return aws.StringValue(i.InstanceId) })
+	// Instances that Karpenter stopped are handed off and are treated as if they no longer exist
+	instances = lo.Reject(instances, func(i *ec2.Instance, _ int) bool {
+		return lo.ContainsBy(i.Tags, func(t *ec2.Tag) bool {
+			return aws.StringValue(t.Key) == v1beta1.TagTerminationBehavior && aws.StringValue(t.Value) == string(v1beta1.TerminationBehaviorStop)
+		})
+	})
 	if len(instances) == 0 {
 		return nil, cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance not found"))
 	}
@@ -487,7 +1276,38 @@ func instancesFromOutput(out *ec2.DescribeInstancesOutput) ([]*Instance, error)
 	return lo.Map(instances, func(i *ec2.Instance, _ int) *Instance { return NewInstance(i) }), nil
 }
 
-func combineFleetErrors(errors []*ec2.CreateFleetError) (errs error) {
+// fleetError is returned when a fleet request doesn't launch an instance. It keeps the errors EC2 reported for each
+// launch template override, which identify the instance types and zones that were rejected.
+type fleetError struct {
+	error
+	errors []*ec2.CreateFleetError
+}
+
+func (e *fleetError) Unwrap() error {
+	return e.error
+}
+
+// FleetErrors returns the errors EC2 reported for the fleet request that caused err, if any
+func FleetErrors(err error) []*ec2.CreateFleetError {
+	var fleetErr *fleetError
+	if errors.As(err, &fleetErr) {
+		return fleetErr.errors
+	}
+	return nil
+}
+
+// createFleetErrorCode returns the AWS error code of a failed CreateFleet request, or Unknown when the request failed
+// without one
+func createFleetErrorCode(err error) string {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	return "Unknown"
+}
+
+func combineFleetErrors(errors []*ec2.CreateFleetError) error {
+	var errs error
 	unique := sets.NewString()
 	for _, err := range errors {
 		unique.Insert(fmt.Sprintf("%s: %s", aws.StringValue(err.ErrorCode), aws.StringValue(err.ErrorMessage)))
@@ -498,7 +1318,13 @@ func combineFleetErrors(errors []*ec2.CreateFleetError) (errs error) {
 	// If all the Fleet errors are ICE errors then we should wrap the combined error in the generic ICE error
 	iceErrorCount := lo.CountBy(errors, func(err *ec2.CreateFleetError) bool { return awserrors.IsUnfulfillableCapacity(err) })
 	if iceErrorCount == len(errors) {
-		return cloudprovider.NewInsufficientCapacityError(fmt.Errorf("with fleet error(s), %w", errs))
+		return &fleetError{error: cloudprovider.NewInsufficientCapacityError(fmt.Errorf("with fleet error(s), %w", errs)), errors: errors}
 	}
-	return fmt.Errorf("with fleet error(s), %w", errs)
+	return &fleetError{error: fmt.Errorf("with fleet error(s), %w", errs), errors: errors}
+}
+
+func ec2Tags(tags map[string]string) []*ec2.Tag {
+	return lo.MapToSlice(tags, func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	})
 }
@@ -0,0 +1,193 @@
+//go:build test_performance
+
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/patrickmn/go-cache"
+	"k8s.io/utils/clock"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+const sweepBenchmarkInstances = 2000
+
+// BenchmarkList2000 describes every instance in full on each sweep, which is how garbage collection used to sweep
+func BenchmarkList2000(b *testing.B) {
+	benchmarkSweep(b, func(ctx context.Context, p *instance.DefaultProvider) ([]*instance.Instance, error) {
+		return p.List(ctx)
+	}, false)
+}
+
+// BenchmarkSweep2000 sweeps with DescribeInstanceStatus against a warm description cache
+func BenchmarkSweep2000(b *testing.B) {
+	benchmarkSweep(b, func(ctx context.Context, p *instance.DefaultProvider) ([]*instance.Instance, error) {
+		return p.Sweep(ctx)
+	}, false)
+}
+
+// BenchmarkColdSweep2000 sweeps with DescribeInstanceStatus against an empty description cache, which is the worst case
+func BenchmarkColdSweep2000(b *testing.B) {
+	benchmarkSweep(b, func(ctx context.Context, p *instance.DefaultProvider) ([]*instance.Instance, error) {
+		return p.Sweep(ctx)
+	}, true)
+}
+
+func benchmarkSweep(b *testing.B, sweep func(context.Context, *instance.DefaultProvider) ([]*instance.Instance, error), cold bool) {
+	ctx := options.ToContext(context.Background(), test.Options())
+	server := newEC2Server(options.FromContext(ctx).ClusterName, sweepBenchmarkInstances)
+	defer server.Close()
+
+	ec2api := ec2.New(session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+	descriptions := cache.New(awscache.InstanceDescriptionTTL, awscache.DefaultCleanupInterval)
+	provider := instance.NewDefaultProvider(ctx, "us-west-2", ec2api, nil, nil, nil, nil, nil, nil, nil,
+		nil, nil, nil, descriptions, &clock.RealClock{}, nil, nil)
+
+	// Warm the description cache so that steady state sweeps are measured
+	if _, err := sweep(ctx, provider); err != nil {
+		b.Fatalf("sweeping instances, %v", err)
+	}
+	server.bytes.Store(0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if cold {
+			descriptions.Flush()
+		}
+		instances, err := sweep(ctx, provider)
+		if err != nil {
+			b.Fatalf("sweeping instances, %v", err)
+		}
+		if len(instances) != sweepBenchmarkInstances {
+			b.Fatalf("expected %d instances, got %d", sweepBenchmarkInstances, len(instances))
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(server.bytes.Load())/float64(b.N), "response-bytes/op")
+}
+
+type ec2Server struct {
+	*httptest.Server
+	bytes     atomic.Int64
+	ids       []string
+	instances map[string]string
+	statuses  map[string]string
+}
+
+// newEC2Server serves DescribeInstances and DescribeInstanceStatus for count running instances owned by the cluster.
+// Instances carry the tags, network interfaces and security groups that a Karpenter launched instance does, so that
+// response sizes are representative of a real DescribeInstances sweep.
+func newEC2Server(clusterName string, count int) *ec2Server {
+	s := &ec2Server{instances: map[string]string{}, statuses: map[string]string{}}
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("i-%017x", i)
+		zone := fmt.Sprintf("us-west-2%c", 'a'+i%3)
+		s.ids = append(s.ids, id)
+		s.instances[id] = fmt.Sprintf(`<item><reservationId>r-%017x</reservationId><ownerId>123456789012</ownerId><instancesSet><item>`+
+			`<instanceId>%s</instanceId><imageId>ami-0123456789abcdef0</imageId><instanceState><code>16</code><name>running</name></instanceState>`+
+			`<privateDnsName>ip-10-0-%d-%d.us-west-2.compute.internal</privateDnsName><instanceType>m5.large</instanceType>`+
+			`<launchTime>2024-01-01T00:00:00.000Z</launchTime><placement><availabilityZone>%s</availabilityZone><tenancy>default</tenancy></placement>`+
+			`<subnetId>subnet-%d</subnetId><vpcId>vpc-0123456789abcdef0</vpcId><privateIpAddress>10.0.%d.%d</privateIpAddress>`+
+			`<architecture>x86_64</architecture><rootDeviceType>ebs</rootDeviceType><rootDeviceName>/dev/xvda</rootDeviceName>`+
+			`<blockDeviceMapping><item><deviceName>/dev/xvda</deviceName><ebs><volumeId>vol-%017x</volumeId><status>attached</status><deleteOnTermination>true</deleteOnTermination></ebs></item></blockDeviceMapping>`+
+			`<groupSet><item><groupId>sg-0123456789abcdef0</groupId><groupName>node</groupName></item><item><groupId>sg-0fedcba9876543210</groupId><groupName>cluster</groupName></item></groupSet>`+
+			`<networkInterfaceSet><item><networkInterfaceId>eni-%017x</networkInterfaceId><subnetId>subnet-%d</subnetId><vpcId>vpc-0123456789abcdef0</vpcId>`+
+			`<status>in-use</status><privateIpAddress>10.0.%d.%d</privateIpAddress><attachment><deviceIndex>0</deviceIndex><status>attached</status></attachment>`+
+			`<privateIpAddressesSet><item><privateIpAddress>10.0.%d.%d</privateIpAddress><primary>true</primary></item></privateIpAddressesSet></item></networkInterfaceSet>`+
+			`<tagSet><item><key>kubernetes.io/cluster/%s</key><value>owned</value></item><item><key>karpenter.sh/nodepool</key><value>default</value></item>`+
+			`<item><key>karpenter.k8s.aws/ec2nodeclass</key><value>default</value></item><item><key>karpenter.sh/managed-by</key><value>%s</value></item>`+
+			`<item><key>karpenter.sh/nodeclaim</key><value>default-%d</value></item><item><key>Name</key><value>default-%d</value></item></tagSet>`+
+			`</item></instancesSet></item>`,
+			i, id, i/256, i%256, zone, i%3, i/256, i%256, i, i, i%3, i/256, i%256, i/256, i%256, clusterName, clusterName, i, i)
+		s.statuses[id] = fmt.Sprintf(`<item><instanceId>%s</instanceId><availabilityZone>%s</availabilityZone>`+
+			`<instanceState><code>16</code><name>running</name></instanceState>`+
+			`<systemStatus><status>ok</status><details><item><name>reachability</name><status>passed</status></item></details></systemStatus>`+
+			`<instanceStatus><status>ok</status><details><item><name>reachability</name><status>passed</status></item></details></instanceStatus></item>`, id, zone)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *ec2Server) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids := s.ids
+	if filtered := instanceIDFilter(r); filtered != nil {
+		ids = filtered
+	}
+	var body strings.Builder
+	switch r.Form.Get("Action") {
+	case "DescribeInstances":
+		body.WriteString(`<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>request</requestId><reservationSet>`)
+		for _, id := range ids {
+			body.WriteString(s.instances[id])
+		}
+		body.WriteString(`</reservationSet></DescribeInstancesResponse>`)
+	case "DescribeInstanceStatus":
+		body.WriteString(`<DescribeInstanceStatusResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>request</requestId><instanceStatusSet>`)
+		for _, id := range ids {
+			body.WriteString(s.statuses[id])
+		}
+		body.WriteString(`</instanceStatusSet></DescribeInstanceStatusResponse>`)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported action %q", r.Form.Get("Action")), http.StatusBadRequest)
+		return
+	}
+	s.bytes.Add(int64(body.Len()))
+	w.Header().Set("Content-Type", "text/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	_, _ = w.Write([]byte(body.String()))
+}
+
+// instanceIDFilter returns the values of an instance-id filter on the request, if there is one. Tag and state
+// filters are ignored since every instance served is a running instance owned by the cluster.
+func instanceIDFilter(r *http.Request) []string {
+	for i := 1; r.Form.Has(fmt.Sprintf("Filter.%d.Name", i)); i++ {
+		if r.Form.Get(fmt.Sprintf("Filter.%d.Name", i)) != "instance-id" {
+			continue
+		}
+		var ids []string
+		for j := 1; r.Form.Has(fmt.Sprintf("Filter.%d.Value.%d", i, j)); j++ {
+			ids = append(ids, r.Form.Get(fmt.Sprintf("Filter.%d.Value.%d", i, j)))
+		}
+		return ids
+	}
+	return nil
+}
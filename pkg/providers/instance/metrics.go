@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	awsSubsystem = "aws"
+	stageLabel   = "stage"
+	apiLabel     = "api"
+
+	// funnelStageRequirements is the number of instance types passed to the provider after they have been intersected
+	// with the NodeClaim's requirements
+	funnelStageRequirements = "requirements"
+	// funnelStageExotic is the number of instance types remaining after less desirable types (e.g. GPU or metal) are removed
+	funnelStageExotic = "exotic"
+	// funnelStageSpotPrice is the number of instance types remaining after spot types that are more expensive than the
+	// cheapest on-demand type are removed
+	funnelStageSpotPrice = "spot_price"
+	// funnelStageMaxInstanceTypes is the number of instance types remaining after the list is truncated to MaxInstanceTypes
+	funnelStageMaxInstanceTypes = "max_instance_types"
+	// funnelStageOfferings is the number of instance types with at least one available offering in the fleet request
+	funnelStageOfferings = "offerings"
+
+	describeInstancesAPI      = "DescribeInstances"
+	describeInstanceStatusAPI = "DescribeInstanceStatus"
+)
+
+var (
+	instanceTypeFunnel = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: awsSubsystem,
+			Name:      "instance_type_funnel",
+			Help:      "Number of instance types remaining after each filtering stage of a launch attempt. Labeled by the filtering stage.",
+			Buckets:   []float64{0, 1, 2, 5, 10, 20, 40, 60, 100, 200, 400, 800},
+		},
+		[]string{stageLabel},
+	)
+	instanceSweepObjects = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: awsSubsystem,
+			Name:      "instance_sweep_objects",
+			Help:      "Number of instances read by each sweep of the cluster's instances. Labeled by the EC2 API that described them.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		},
+		[]string{apiLabel},
+	)
+	instanceSweepBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: awsSubsystem,
+			Name:      "instance_sweep_bytes",
+			Help:      "Size of the EC2 responses read by each sweep of the cluster's instances, counting the responses whose size EC2 reports. Labeled by the EC2 API.",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 10),
+		},
+		[]string{apiLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(instanceTypeFunnel, instanceSweepObjects, instanceSweepBytes)
+}
+
+func observeSweep(api string, objects int, bytes int64) {
+	instanceSweepObjects.With(prometheus.Labels{apiLabel: api}).Observe(float64(objects))
+	instanceSweepBytes.With(prometheus.Labels{apiLabel: api}).Observe(float64(bytes))
+}
@@ -17,28 +17,41 @@ package instance_test
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/events"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/operator/scheme"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/metricsexporter"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -64,7 +77,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider, awsEnv.CostLimitProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -126,6 +139,886 @@ var _ = Describe("InstanceProvider", func() {
 		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(instance).To(BeNil())
 	})
+	It("should drop overrides whose architecture doesn't match any resolved AMI", func() {
+		nodeClass.Status.AMIs = []v1beta1.AMI{{
+			ID: "ami-arm64",
+			Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureArm64}}},
+			},
+		}}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool {
+			return i.Requirements.Get(corev1.LabelArchStable).Has(corev1beta1.ArchitectureAmd64)
+		})
+
+		// The launch templates resolve x86_64 AMIs, so every amd64 instance type is dropped before CreateFleet
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(MatchError(ContainSubstring("no capacity offerings are currently available given the constraints")))
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+	})
+	It("should keep overrides whose architecture matches a resolved AMI", func() {
+		nodeClass.Status.AMIs = []v1beta1.AMI{{
+			ID: "ami-amd64",
+			Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}}},
+			},
+		}}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		architectures := lo.SliceToMap(instanceTypes, func(i *corecloudprovider.InstanceType) (string, string) {
+			return i.Name, i.Requirements.Get(corev1.LabelArchStable).Any()
+		})
+		for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+			Expect(ltc.Overrides).ToNot(BeEmpty())
+			for _, override := range ltc.Overrides {
+				Expect(architectures[aws.StringValue(override.InstanceType)]).To(Equal(corev1beta1.ArchitectureAmd64))
+			}
+		}
+	})
+	It("should record CreateFleet errors and launch latency for the CloudWatch metrics exporter", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{CloudWatchMetricsNamespace: lo.ToPtr("Karpenter")}))
+		cloudwatchAPI := fake.NewCloudWatchAPI()
+		fakeClock := clocktesting.NewFakeClock(time.Now())
+		exporter := metricsexporter.NewExporter(cloudwatchAPI, fakeClock, awsEnv.UnavailableOfferingsCache)
+		provider := instance.NewDefaultProvider(ctx, "", awsEnv.EC2API, awsEnv.UnavailableOfferingsCache, awsEnv.InstanceTypesProvider,
+			awsEnv.SubnetProvider, awsEnv.LaunchTemplateProvider, awsEnv.PlacementGroupProvider, awsEnv.SpotAdvisorProvider, awsEnv.CostLimitProvider,
+			awsEnv.InflightLaunchCache, awsEnv.LaunchAttemptCache, awsEnv.SpotFallbackZoneCache, awsEnv.InstanceDescriptionCache, fakeClock, awsEnv.EventRecorder, exporter)
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		awsEnv.EC2API.InsufficientCapacityPools.Set(lo.FlatMap([]string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand}, func(capacityType string, _ int) []fake.CapacityPool {
+			return lo.Map([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}, func(zone string, _ int) fake.CapacityPool {
+				return fake.CapacityPool{CapacityType: capacityType, InstanceType: "m5.xlarge", Zone: zone}
+			})
+		}))
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = provider.Create(ctx, nodeClass, nodeClaim, lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" }))
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		launched, err := provider.Create(ctx, nodeClass, nodeClaim, lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.large" }))
+		Expect(err).ToNot(HaveOccurred())
+		fakeClock.Step(30 * time.Second)
+		_, err = provider.Get(ctx, launched.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exporter.Flush(ctx)).To(Succeed())
+
+		metricData := cloudwatchAPI.PutMetricDataBehavior.CalledWithInput.Pop().MetricData
+		metricNames := lo.Map(metricData, func(d *cloudwatch.MetricDatum, _ int) string { return aws.StringValue(d.MetricName) })
+		Expect(metricNames).To(ContainElements(metricsexporter.MetricCreateFleetErrors, metricsexporter.MetricInstanceLaunchLatency))
+		launchLatency, ok := lo.Find(metricData, func(d *cloudwatch.MetricDatum) bool {
+			return aws.StringValue(d.MetricName) == metricsexporter.MetricInstanceLaunchLatency
+		})
+		Expect(ok).To(BeTrue())
+		Expect(aws.Float64Value(launchLatency.StatisticValues.Sum)).To(BeNumerically("==", 30))
+	})
+	It("should constrain the fleet request to a single zone when using a cluster placement group", func() {
+		nodeClass.Spec.Placement = &v1beta1.Placement{GroupName: "test-pg"}
+		awsEnv.EC2API.DescribePlacementGroupsOutput.Set(&ec2.DescribePlacementGroupsOutput{
+			PlacementGroups: []*ec2.PlacementGroup{{GroupName: aws.String("test-pg"), Strategy: aws.String(ec2.PlacementStrategyCluster)}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		zones := sets.New[string]()
+		for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+			for _, override := range ltc.Overrides {
+				zones.Insert(aws.StringValue(override.AvailabilityZone))
+			}
+		}
+		Expect(zones.Len()).To(Equal(1))
+	})
+	It("should not constrain the fleet request to a single zone when using a spread placement group", func() {
+		nodeClass.Spec.Placement = &v1beta1.Placement{GroupName: "test-pg"}
+		// Spot launches drop instance types priced above the cheapest on-demand offering, which leaves a single offering
+		// with the fake pricing, so launch on-demand to keep offerings from every zone
+		nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+		}
+		awsEnv.EC2API.DescribePlacementGroupsOutput.Set(&ec2.DescribePlacementGroupsOutput{
+			PlacementGroups: []*ec2.PlacementGroup{{GroupName: aws.String("test-pg"), Strategy: aws.String(ec2.PlacementStrategySpread)}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		zones := sets.New[string]()
+		for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+			for _, override := range ltc.Overrides {
+				zones.Insert(aws.StringValue(override.AvailabilityZone))
+			}
+		}
+		Expect(zones.Len()).To(BeNumerically(">", 1))
+	})
+	It("should return an error when the placement group doesn't exist", func() {
+		nodeClass.Spec.Placement = &v1beta1.Placement{GroupName: "test-pg"}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+	})
+	It("should set a client token derived from the NodeClaim UID on the fleet request", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValue(createFleetInput.ClientToken)).To(Equal(fmt.Sprintf("%s-0", nodeClaim.UID)))
+		Expect(len(aws.StringValue(createFleetInput.ClientToken))).To(BeNumerically("<=", 64))
+	})
+	It("should set a new client token when retrying after a launch that EC2 answered", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		awsEnv.EC2API.InsufficientCapacityPools.Set(lo.FlatMap(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) []fake.CapacityPool {
+			return lo.Map(it.Offerings, func(o corecloudprovider.Offering, _ int) fake.CapacityPool {
+				return fake.CapacityPool{CapacityType: o.CapacityType, InstanceType: it.Name, Zone: o.Zone}
+			})
+		}))
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+		awsEnv.EC2API.InsufficientCapacityPools.Reset()
+		awsEnv.UnavailableOfferingsCache.Flush()
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(2))
+		Expect(aws.StringValue(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop().ClientToken)).To(Equal(fmt.Sprintf("%s-1", nodeClaim.UID)))
+		Expect(aws.StringValue(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop().ClientToken)).To(Equal(fmt.Sprintf("%s-0", nodeClaim.UID)))
+	})
+	It("should not launch a second instance when retrying after a CreateFleet timeout", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		awsEnv.EC2API.CreateFleetResponseError.Set(awserr.New(request.CanceledErrorCode, "request context canceled", context.DeadlineExceeded), fake.MaxCalls(1))
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		Expect(instancesForNodeClaim(nodeClaim)).To(ConsistOf(instance.ID))
+	})
+	It("should reuse the client token when the timed out launch isn't yet visible", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		awsEnv.EC2API.CreateFleetResponseError.Set(awserr.New(request.CanceledErrorCode, "request context canceled", context.DeadlineExceeded), fake.MaxCalls(1))
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+
+		// DescribeInstances is eventually consistent, so the instance may not be returned yet
+		awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{})
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(Equal(1))
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(2))
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop().ClientToken).To(Equal(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop().ClientToken))
+
+		awsEnv.EC2API.DescribeInstancesBehavior.Output.Reset()
+		Expect(instancesForNodeClaim(nodeClaim)).To(ConsistOf(instance.ID))
+	})
+	It("should not look up existing instances when the launch didn't time out", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		awsEnv.EC2API.CreateFleetBehavior.Error.Set(fmt.Errorf("failed"), fake.MaxCalls(1))
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(Equal(0))
+	})
+	It("should spread launches across same-zone subnets with the Balanced subnet selection policy", func() {
+		nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyBalanced)
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-a"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(1000),
+				Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-a")}}},
+			{SubnetId: aws.String("subnet-b"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(1000),
+				Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-b")}}},
+		}})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 20; i++ {
+			nc := nodeClaim.DeepCopy()
+			nc.Name = fmt.Sprintf("%s-%d", nodeClaim.Name, i)
+			nc.UID = types.UID(fmt.Sprintf("%s-%d", nodeClaim.UID, i))
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nc, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		launchedSubnets := sets.New[string]()
+		awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.ForEach(func(input *ec2.CreateFleetInput) {
+			for _, ltc := range input.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					launchedSubnets.Insert(aws.StringValue(override.SubnetId))
+				}
+			}
+		})
+		Expect(sets.List(launchedSubnets)).To(ConsistOf("subnet-a", "subnet-b"))
+	})
+	It("should launch into an exhausted subnet in the zone the NodeClaim requires when other zones have capacity", func() {
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-exhausted"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(1),
+				Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-exhausted")}}},
+			{SubnetId: aws.String("subnet-other-zone"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(1000),
+				Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-other-zone")}}},
+		}})
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1a"}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) bool { return it.Name == "m5.large" })
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+			for _, override := range ltc.Overrides {
+				Expect(aws.StringValue(override.SubnetId)).To(Equal("subnet-exhausted"))
+			}
+		}
+	})
+	Context("Canceled Launches", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		var launchCtx context.Context
+		var cancel context.CancelFunc
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			launchCtx, cancel = context.WithCancel(ctx)
+		})
+		AfterEach(func() {
+			cancel()
+		})
+		It("should not call CreateFleet when the request is canceled before launch", func() {
+			cancel()
+			_, err := awsEnv.InstanceProvider.Create(launchCtx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+		})
+		It("should not call CreateFleet when the request is canceled after ensuring launch templates", func() {
+			provider := instance.NewDefaultProvider(ctx, "", awsEnv.EC2API, awsEnv.UnavailableOfferingsCache, awsEnv.InstanceTypesProvider,
+				awsEnv.SubnetProvider, &cancelingLaunchTemplateProvider{Provider: awsEnv.LaunchTemplateProvider, cancel: cancel},
+				awsEnv.PlacementGroupProvider, awsEnv.SpotAdvisorProvider, awsEnv.CostLimitProvider, awsEnv.InflightLaunchCache, awsEnv.LaunchAttemptCache, awsEnv.SpotFallbackZoneCache, awsEnv.InstanceDescriptionCache, &clock.RealClock{}, awsEnv.EventRecorder, nil)
+			_, err := provider.Create(launchCtx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+		})
+		It("should terminate the launched instance when the request is canceled after CreateFleet", func() {
+			provider := instance.NewDefaultProvider(ctx, "", &cancelingEC2API{EC2API: awsEnv.EC2API, cancel: cancel}, awsEnv.UnavailableOfferingsCache,
+				awsEnv.InstanceTypesProvider, awsEnv.SubnetProvider, awsEnv.LaunchTemplateProvider, awsEnv.PlacementGroupProvider, awsEnv.SpotAdvisorProvider, awsEnv.CostLimitProvider, awsEnv.InflightLaunchCache, awsEnv.LaunchAttemptCache, awsEnv.SpotFallbackZoneCache, awsEnv.InstanceDescriptionCache, &clock.RealClock{}, awsEnv.EventRecorder, nil)
+			_, err := provider.Create(launchCtx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+			Expect(instancesForNodeClaim(nodeClaim)).To(BeEmpty())
+		})
+		It("should terminate the launched instance when the CreateFleet call is canceled after the launch was applied", func() {
+			awsEnv.EC2API.CreateFleetResponseError.Set(awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled), fake.MaxCalls(1))
+			provider := instance.NewDefaultProvider(ctx, "", &cancelingEC2API{EC2API: awsEnv.EC2API, cancel: cancel}, awsEnv.UnavailableOfferingsCache,
+				awsEnv.InstanceTypesProvider, awsEnv.SubnetProvider, awsEnv.LaunchTemplateProvider, awsEnv.PlacementGroupProvider, awsEnv.SpotAdvisorProvider, awsEnv.CostLimitProvider, awsEnv.InflightLaunchCache, awsEnv.LaunchAttemptCache, awsEnv.SpotFallbackZoneCache, awsEnv.InstanceDescriptionCache, &clock.RealClock{}, awsEnv.EventRecorder, nil)
+			_, err := provider.Create(launchCtx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+			Expect(instancesForNodeClaim(nodeClaim)).To(BeEmpty())
+			_, ok := awsEnv.InflightLaunchCache.Get(string(nodeClaim.UID))
+			Expect(ok).To(BeFalse())
+		})
+	})
+	It("should use the lowest-price on-demand allocation strategy without override priorities by default", func() {
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyLowestPrice))
+		for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+			for _, override := range ltc.Overrides {
+				Expect(override.Priority).To(BeNil())
+			}
+		}
+	})
+	It("should prioritize on-demand overrides by price when using the prioritized allocation strategy", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{OnDemandAllocationStrategy: lo.ToPtr(ec2.FleetOnDemandAllocationStrategyPrioritized)}))
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyPrioritized))
+
+		prices := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, float64) {
+			return it.Name, it.Offerings.Available().Compatible(scheduling.NewRequirements(
+				scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, corev1beta1.CapacityTypeOnDemand),
+			)).Cheapest().Price
+		})
+		overrides := lo.FlatMap(createFleetInput.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []*ec2.FleetLaunchTemplateOverridesRequest {
+			return ltc.Overrides
+		})
+		Expect(overrides).ToNot(BeEmpty())
+		for _, a := range overrides {
+			Expect(a.Priority).ToNot(BeNil())
+			for _, b := range overrides {
+				if aws.Float64Value(a.Priority) < aws.Float64Value(b.Priority) {
+					Expect(prices[aws.StringValue(a.InstanceType)]).To(BeNumerically("<=", prices[aws.StringValue(b.InstanceType)]))
+				}
+			}
+		}
+	})
+	It("should prioritize on-demand overrides by the instance type priorities of the EC2NodeClass", func() {
+		nodeClass.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+			AllocationStrategy:     lo.ToPtr(ec2.FleetOnDemandAllocationStrategyPrioritized),
+			InstanceTypePriorities: []string{"m5.xlarge", "t3.*"},
+		}
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) bool {
+			return lo.Contains([]string{"m5.large", "m5.xlarge", "t3.large"}, it.Name)
+		})
+
+		priorities := func() map[string]float64 {
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyPrioritized))
+			result := map[string]float64{}
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).ToNot(BeNil())
+					result[aws.StringValue(override.InstanceType)] = aws.Float64Value(override.Priority)
+				}
+			}
+			return result
+		}
+		expected := map[string]float64{"m5.xlarge": 0, "t3.large": 1, "m5.large": 2}
+		Expect(priorities()).To(Equal(expected))
+		Expect(priorities()).To(Equal(expected))
+	})
+	It("should keep prioritized instance types when truncating to the maximum number of instance types", func() {
+		nodeClass.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+			AllocationStrategy:     lo.ToPtr(ec2.FleetOnDemandAllocationStrategyPrioritized),
+			InstanceTypePriorities: []string{"pricey.*"},
+		}
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		base, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		// The 70 cheap instance types all cost less than the prioritized one, so truncating by price alone drops it
+		candidates := lo.Times(70, func(i int) *corecloudprovider.InstanceType {
+			return cloneInstanceType(base, fmt.Sprintf("cheap.size%d", i), "cheap", float64(i+1))
+		})
+		candidates = append(candidates, cloneInstanceType(base, "pricey.size0", "pricey", 1000))
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, candidates)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		overrides := lo.FlatMap(createFleetInput.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []*ec2.FleetLaunchTemplateOverridesRequest {
+			return ltc.Overrides
+		})
+		Expect(lo.Uniq(lo.Map(overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest, _ int) string { return aws.StringValue(o.InstanceType) }))).To(HaveLen(60))
+		prioritized, ok := lo.Find(overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest) bool {
+			return aws.StringValue(o.InstanceType) == "pricey.size0"
+		})
+		Expect(ok).To(BeTrue())
+		Expect(aws.Float64Value(prioritized.Priority)).To(BeZero())
+	})
+	Context("Spot Interruption Penalty", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}},
+			})
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) bool {
+				return lo.Contains([]string{"m5.large", "m5.xlarge", "t3.large"}, it.Name)
+			})
+		})
+		priorities := func() map[string]float64 {
+			GinkgoHelper()
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyCapacityOptimizedPrioritized))
+			result := map[string]float64{}
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).ToNot(BeNil())
+					result[aws.StringValue(override.InstanceType)] = aws.Float64Value(override.Priority)
+				}
+			}
+			return result
+		}
+		It("should use the price-capacity-optimized spot allocation strategy without override priorities by default", func() {
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyPriceCapacityOptimized))
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).To(BeNil())
+				}
+			}
+		})
+		It("should prioritize spot overrides by price scaled by their interruption frequency", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotInterruptionPenalty: lo.ToPtr[float64](1)}))
+			// t3.large is the cheapest, but is interrupted often enough that m5.large is preferred over it
+			awsEnv.SpotAdvisorAPI.Output.Set(fake.NewSpotAdvisorData(fake.DefaultRegion, map[string]int{"m5.large": 0, "m5.xlarge": 0, "t3.large": 4}))
+			Expect(awsEnv.SpotAdvisorProvider.UpdateSpotAdvisorData(ctx)).To(Succeed())
+
+			expected := map[string]float64{"m5.large": 0, "t3.large": 1, "m5.xlarge": 2}
+			Expect(priorities()).To(Equal(expected))
+			Expect(priorities()).To(Equal(expected))
+		})
+		It("should prioritize spot overrides by price when the spot advisor data is unavailable", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotInterruptionPenalty: lo.ToPtr[float64](1)}))
+			Expect(awsEnv.SpotAdvisorProvider.UpdateSpotAdvisorData(ctx)).ToNot(Succeed())
+
+			Expect(priorities()).To(Equal(map[string]float64{"t3.large": 0, "m5.large": 1, "m5.xlarge": 2}))
+		})
+		It("should order instance types that the spot advisor doesn't list by price", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotInterruptionPenalty: lo.ToPtr[float64](1)}))
+			awsEnv.SpotAdvisorAPI.Output.Set(fake.NewSpotAdvisorData(fake.DefaultRegion, map[string]int{"t3.large": 4}))
+			Expect(awsEnv.SpotAdvisorProvider.UpdateSpotAdvisorData(ctx)).To(Succeed())
+
+			Expect(priorities()).To(Equal(map[string]float64{"m5.large": 0, "t3.large": 1, "m5.xlarge": 2}))
+		})
+	})
+	It("should prefer the EC2NodeClass on-demand allocation strategy over the operator default", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{OnDemandAllocationStrategy: lo.ToPtr(ec2.FleetOnDemandAllocationStrategyPrioritized)}))
+		nodeClass.Spec.OnDemandOptions = &v1beta1.OnDemandOptions{
+			AllocationStrategy: lo.ToPtr(ec2.FleetOnDemandAllocationStrategyLowestPrice),
+		}
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyLowestPrice))
+		for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+			for _, override := range ltc.Overrides {
+				Expect(override.Priority).To(BeNil())
+			}
+		}
+	})
+	Context("Spot Fallback Zone", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		requirements := func(zones ...string) []corev1beta1.NodeSelectorRequirementWithMinValues {
+			return []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand}}},
+				{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: zones}},
+			}
+		}
+		getInstanceTypes := func() []*corecloudprovider.InstanceType {
+			GinkgoHelper()
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			return lo.Filter(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) bool {
+				return lo.Contains([]string{"m5.large", "m5.xlarge"}, it.Name)
+			})
+		}
+		BeforeEach(func() {
+			// Spot launches into test-zone-1a fail, while test-zone-1b has no spot capacity left to fall back to either
+			awsEnv.EC2API.InsufficientCapacityPools.Set([]fake.CapacityPool{
+				{CapacityType: corev1beta1.CapacityTypeSpot, InstanceType: "m5.large", Zone: "test-zone-1a"},
+				{CapacityType: corev1beta1.CapacityTypeSpot, InstanceType: "m5.xlarge", Zone: "test-zone-1a"},
+			})
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.large", "test-zone-1b", corev1beta1.CapacityTypeSpot)
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.xlarge", "test-zone-1b", corev1beta1.CapacityTypeSpot)
+			nodeClaim.Spec.Requirements = requirements("test-zone-1a")
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes = getInstanceTypes()
+
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(aws.StringValue(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop().TargetCapacitySpecification.DefaultTargetCapacityType)).To(Equal(corev1beta1.CapacityTypeSpot))
+		})
+		It("should prefer the zone where spot failed when falling back to on-demand", func() {
+			fallback := coretest.NodeClaim(corev1beta1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1beta1.NodePoolLabelKey: nodePool.Name}},
+				Spec: corev1beta1.NodeClaimSpec{
+					NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					Requirements: requirements("test-zone-1a", "test-zone-1b"),
+				},
+			})
+			ExpectApplied(ctx, env.Client, fallback)
+
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, fallback, getInstanceTypes())
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.TargetCapacitySpecification.DefaultTargetCapacityType)).To(Equal(corev1beta1.CapacityTypeOnDemand))
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyPrioritized))
+			priorities := map[string]float64{}
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).ToNot(BeNil())
+					priorities[fmt.Sprintf("%s/%s", aws.StringValue(override.InstanceType), aws.StringValue(override.AvailabilityZone))] = aws.Float64Value(override.Priority)
+				}
+			}
+			Expect(priorities).To(Equal(map[string]float64{
+				"m5.large/test-zone-1a":  0,
+				"m5.xlarge/test-zone-1a": 1,
+				"m5.large/test-zone-1b":  2,
+				"m5.xlarge/test-zone-1b": 3,
+			}))
+		})
+		It("should not prefer a zone when the on-demand launch is restricted to a single zone", func() {
+			fallback := coretest.NodeClaim(corev1beta1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1beta1.NodePoolLabelKey: nodePool.Name}},
+				Spec: corev1beta1.NodeClaimSpec{
+					NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					Requirements: requirements("test-zone-1a"),
+				},
+			})
+			ExpectApplied(ctx, env.Client, fallback)
+
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, fallback, getInstanceTypes())
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyLowestPrice))
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).To(BeNil())
+				}
+			}
+		})
+		It("should not prefer a zone for the on-demand launches of other NodePools", func() {
+			other := coretest.NodePool(corev1beta1.NodePool{
+				Spec: corev1beta1.NodePoolSpec{
+					Template: corev1beta1.NodeClaimTemplate{
+						Spec: corev1beta1.NodeClaimSpec{NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name}},
+					},
+				},
+			})
+			fallback := coretest.NodeClaim(corev1beta1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1beta1.NodePoolLabelKey: other.Name}},
+				Spec: corev1beta1.NodeClaimSpec{
+					NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					Requirements: requirements("test-zone-1a", "test-zone-1b"),
+				},
+			})
+			ExpectApplied(ctx, env.Client, other, fallback)
+
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, fallback, getInstanceTypes())
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyLowestPrice))
+		})
+	})
+	Context("Instance Type Funnel", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		var stages = []string{"requirements", "exotic", "spot_price", "max_instance_types", "offerings"}
+		var observed map[string]funnelSample
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1beta1.CapacityTypeLabelKey,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{corev1beta1.CapacityTypeOnDemand},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "t3.large", "test-zone-1a", corev1beta1.CapacityTypeOnDemand)
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "t3.large", "test-zone-1b", corev1beta1.CapacityTypeOnDemand)
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "t3.large", "test-zone-1c", corev1beta1.CapacityTypeOnDemand)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			// Two generic instance types with capacity, one generic instance type without any available offerings and two
+			// exotic instance types
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool {
+				return lo.Contains([]string{"m5.large", "m5.xlarge", "t3.large", "p3.8xlarge", "m5.metal"}, i.Name)
+			})
+			Expect(instanceTypes).To(HaveLen(5))
+
+			// Histograms accumulate across tests, so we compare against the observations made before each launch
+			observed = lo.Associate(stages, func(stage string) (string, funnelSample) { return stage, funnelObservations(stage) })
+		})
+		It("should observe the number of instance types remaining after each filtering stage", func() {
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			expectFunnelObservation(observed, "requirements", 5)
+			expectFunnelObservation(observed, "exotic", 3)
+			expectFunnelObservation(observed, "spot_price", 3)
+			expectFunnelObservation(observed, "max_instance_types", 3)
+			expectFunnelObservation(observed, "offerings", 2)
+		})
+		It("should not observe filtering stages that are skipped for NodeClaims with minValues", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpExists,
+				},
+				MinValues: lo.ToPtr(2),
+			})
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			expectFunnelObservation(observed, "requirements", 5)
+			expectFunnelObservation(observed, "max_instance_types", 5)
+			expectFunnelObservation(observed, "offerings", 4)
+			Expect(funnelObservations("exotic")).To(Equal(observed["exotic"]))
+			Expect(funnelObservations("spot_price")).To(Equal(observed["spot_price"]))
+		})
+		It("should publish a warning event when fewer instance types than the minimum are launchable", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MinLaunchInstanceTypes: lo.ToPtr(3)}))
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EventRecorder.Calls("LaunchableInstanceTypesBelowMinimum")).To(Equal(1))
+			Expect(awsEnv.EventRecorder.DetectedEvent("Only 2 instance type(s) remained launchable after filtering, fewer than the configured minimum of 3")).To(BeTrue())
+		})
+		It("should not publish a warning event when enough instance types are launchable", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MinLaunchInstanceTypes: lo.ToPtr(2)}))
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EventRecorder.Calls("LaunchableInstanceTypesBelowMinimum")).To(Equal(0))
+		})
+		It("should not publish a warning event when no minimum is configured", func() {
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EventRecorder.Calls("LaunchableInstanceTypesBelowMinimum")).To(Equal(0))
+		})
+	})
+	Context("MinValues Truncation", func() {
+		var base *corecloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1beta1.CapacityTypeLabelKey,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{corev1beta1.CapacityTypeSpot},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			base, _ = lo.Find(instanceTypes, func(i *corecloudprovider.InstanceType) bool { return i.Name == "m5.large" })
+			Expect(base).ToNot(BeNil())
+		})
+		// familyInstanceTypes returns count instance types in each of the families, where every instance type of a
+		// family is cheaper than the instance types of the families after it
+		familyInstanceTypes := func(counts map[string]int, families ...string) []*corecloudprovider.InstanceType {
+			var instanceTypes []*corecloudprovider.InstanceType
+			for f, family := range families {
+				for i := 0; i < counts[family]; i++ {
+					instanceTypes = append(instanceTypes, cloneInstanceType(base, fmt.Sprintf("%s.size%d", family, i), family, float64(f*100+i+1)))
+				}
+			}
+			return instanceTypes
+		}
+		fleetRequestInstanceTypes := func() []string {
+			GinkgoHelper()
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			return lo.Uniq(lo.FlatMap(createFleetInput.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []string {
+				return lo.Map(ltc.Overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest, _ int) string { return aws.StringValue(o.InstanceType) })
+			}))
+		}
+		It("should keep enough instance families to satisfy instance-family minValues", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      v1beta1.LabelInstanceFamily,
+					Operator: corev1.NodeSelectorOpExists,
+				},
+				MinValues: lo.ToPtr(10),
+			})
+			// The 60 cheapest instance types all belong to the cheap family, so truncating by price alone keeps a single family
+			counts := map[string]int{"cheap": 60}
+			families := []string{"cheap"}
+			for i := 0; i < 12; i++ {
+				family := fmt.Sprintf("family%d", i)
+				counts[family] = 1
+				families = append(families, family)
+			}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, familyInstanceTypes(counts, families...))
+			Expect(err).ToNot(HaveOccurred())
+
+			launched := fleetRequestInstanceTypes()
+			Expect(launched).To(HaveLen(60))
+			launchedFamilies := lo.Uniq(lo.Map(launched, func(name string, _ int) string { return strings.Split(name, ".")[0] }))
+			Expect(launchedFamilies).To(HaveLen(10))
+			// The cheapest instance types of the additional families are kept
+			Expect(launchedFamilies).To(ContainElements("cheap", "family0", "family8"))
+			Expect(launchedFamilies).ToNot(ContainElements("family9", "family10", "family11"))
+		})
+		It("should keep enough instance types to satisfy instance-type minValues", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpExists,
+				},
+				MinValues: lo.ToPtr(40),
+			})
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, familyInstanceTypes(map[string]int{"cheap": 80}, "cheap"))
+			Expect(err).ToNot(HaveOccurred())
+
+			launched := fleetRequestInstanceTypes()
+			Expect(launched).To(HaveLen(60))
+			Expect(launched).To(ContainElement("cheap.size0"))
+			Expect(launched).ToNot(ContainElement("cheap.size60"))
+		})
+		It("should satisfy minValues on instance-family and instance-type together", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements,
+				corev1beta1.NodeSelectorRequirementWithMinValues{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: v1beta1.LabelInstanceFamily, Operator: corev1.NodeSelectorOpExists},
+					MinValues:               lo.ToPtr(3),
+				},
+				corev1beta1.NodeSelectorRequirementWithMinValues{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelInstanceTypeStable, Operator: corev1.NodeSelectorOpExists},
+					MinValues:               lo.ToPtr(50),
+				},
+			)
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, familyInstanceTypes(map[string]int{"cheap": 70, "mid": 5, "pricey": 5}, "cheap", "mid", "pricey"))
+			Expect(err).ToNot(HaveOccurred())
+
+			launched := fleetRequestInstanceTypes()
+			Expect(launched).To(HaveLen(60))
+			Expect(lo.Uniq(lo.Map(launched, func(name string, _ int) string { return strings.Split(name, ".")[0] }))).To(ConsistOf("cheap", "mid", "pricey"))
+		})
+		It("should return an error when minValues can't be satisfied within the maximum number of instance types", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      v1beta1.LabelInstanceFamily,
+					Operator: corev1.NodeSelectorOpExists,
+				},
+				MinValues: lo.ToPtr(61),
+			})
+			counts := map[string]int{}
+			var families []string
+			for i := 0; i < 70; i++ {
+				family := fmt.Sprintf("family%d", i)
+				counts[family] = 1
+				families = append(families, family)
+			}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, familyInstanceTypes(counts, families...))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("satisfying minValues requires 61 instance types, more than the maximum of 60"))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should return an error when there aren't enough distinct values to satisfy minValues", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      v1beta1.LabelInstanceFamily,
+					Operator: corev1.NodeSelectorOpExists,
+				},
+				MinValues: lo.ToPtr(3),
+			})
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, familyInstanceTypes(map[string]int{"cheap": 70, "mid": 1}, "cheap", "mid"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("karpenter.k8s.aws/instance-family requires 3 values but only 2 are available"))
+		})
+	})
+	Context("Taint Tags", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TaintTags: lo.ToPtr(true)}))
+		})
+		It("should serialize taints and startup taints into the instance tags at launch", func() {
+			nodeClaim.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+			nodeClaim.Spec.StartupTaints = []corev1.Taint{{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoExecute}}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			instanceTags := lo.SliceToMap(createFleetInput.TagSpecifications[0].Tags, func(t *ec2.Tag) (string, string) {
+				return aws.StringValue(t.Key), aws.StringValue(t.Value)
+			})
+			Expect(instanceTags).To(HaveKeyWithValue(v1beta1.TagTaints, "dedicated=gpu:NoSchedule,example.com/not-ready:NoExecute"))
+			Expect(instanceTags).To(HaveKeyWithValue(v1beta1.TagTaintsTruncated, "false"))
+			// Launch templates are shared across NodeClaims, so they shouldn't carry the taint tags
+			for _, spec := range createFleetInput.TagSpecifications[1:] {
+				Expect(lo.ContainsBy(spec.Tags, func(t *ec2.Tag) bool { return aws.StringValue(t.Key) == v1beta1.TagTaints })).To(BeFalse())
+			}
+		})
+		It("should not add taint tags when disabled", func() {
+			ctx = options.ToContext(ctx, test.Options())
+			nodeClaim.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(lo.ContainsBy(createFleetInput.TagSpecifications[0].Tags, func(t *ec2.Tag) bool {
+				return aws.StringValue(t.Key) == v1beta1.TagTaints || aws.StringValue(t.Key) == v1beta1.TagTaintsTruncated
+			})).To(BeFalse())
+		})
+		It("should truncate taints that don't fit in the tag value and set the overflow marker", func() {
+			for i := 0; i < 20; i++ {
+				nodeClaim.Spec.StartupTaints = append(nodeClaim.Spec.StartupTaints, corev1.Taint{
+					Key: fmt.Sprintf("example.com/taint-%02d", i), Value: "value", Effect: corev1.TaintEffectNoSchedule,
+				})
+			}
+			tags := instance.TaintTags(nodeClaim)
+			Expect(len(tags[v1beta1.TagTaints])).To(BeNumerically("<=", 255))
+			Expect(tags[v1beta1.TagTaintsTruncated]).To(Equal("true"))
+			// Truncation drops whole taints from the end of the list
+			entries := strings.Split(tags[v1beta1.TagTaints], ",")
+			Expect(len(entries)).To(BeNumerically("<", 20))
+			for i, entry := range entries {
+				Expect(entry).To(Equal(nodeClaim.Spec.StartupTaints[i].ToString()))
+			}
+			Expect(instance.TaintTags(nodeClaim)).To(Equal(tags))
+		})
+	})
 	It("should return all NodePool-owned instances from List", func() {
 		ids := sets.New[string]()
 		// Provision instances that have the karpenter.sh/nodepool key
@@ -194,4 +1087,370 @@ var _ = Describe("InstanceProvider", func() {
 		retrievedIDs := sets.New[string](lo.Map(instances, func(i *instance.Instance, _ int) string { return i.ID })...)
 		Expect(ids.Equal(retrievedIDs)).To(BeTrue())
 	})
+	It("should return the instances from every page of DescribeInstances from List", func() {
+		ids := sets.New[string]()
+		for i := 0; i < 25; i++ {
+			instanceID := fake.InstanceID()
+			awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+				State: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				Tags: []*ec2.Tag{
+					{Key: aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)), Value: aws.String("owned")},
+					{Key: aws.String(corev1beta1.NodePoolLabelKey), Value: aws.String("default")},
+					{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String("default")},
+				},
+				PrivateDnsName: aws.String(fake.PrivateDNSName()),
+				Placement:      &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+				LaunchTime:     aws.Time(time.Now().Add(-time.Minute)),
+				InstanceId:     aws.String(instanceID),
+				InstanceType:   aws.String("m5.large"),
+			})
+			ids.Insert(instanceID)
+		}
+		awsEnv.EC2API.DescribeInstancesPageSize.Set(lo.ToPtr(10))
+
+		instances, err := awsEnv.InstanceProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(Equal(3))
+		Expect(sets.New(lo.Map(instances, func(i *instance.Instance, _ int) string { return i.ID })...).Equal(ids)).To(BeTrue())
+		Expect(instances).To(HaveLen(25))
+	})
+	It("should return an instance once from List when DescribeInstances repeats it across pages", func() {
+		instanceID := fake.InstanceID()
+		inst := &ec2.Instance{
+			State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			PrivateDnsName: aws.String(fake.PrivateDNSName()),
+			Placement:      &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			LaunchTime:     aws.Time(time.Now().Add(-time.Minute)),
+			InstanceId:     aws.String(instanceID),
+			InstanceType:   aws.String("m5.large"),
+		}
+		awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{inst}}, {Instances: []*ec2.Instance{inst}}},
+		})
+
+		instances, err := awsEnv.InstanceProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instances).To(HaveLen(1))
+		Expect(instances[0].ID).To(Equal(instanceID))
+	})
+	Context("Sweep", func() {
+		var ids []string
+		BeforeEach(func() {
+			ids = nil
+			for i := 0; i < 25; i++ {
+				instanceID := fake.InstanceID()
+				awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+					State: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+					Tags: []*ec2.Tag{
+						{Key: aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)), Value: aws.String("owned")},
+						{Key: aws.String(corev1beta1.NodePoolLabelKey), Value: aws.String("default")},
+						{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String("default")},
+					},
+					PrivateDnsName: aws.String(fake.PrivateDNSName()),
+					Placement:      &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+					LaunchTime:     aws.Time(time.Now().Add(-time.Minute)),
+					InstanceId:     aws.String(instanceID),
+					InstanceType:   aws.String("m5.large"),
+				})
+				ids = append(ids, instanceID)
+			}
+			// Instances that aren't the cluster's are listed by DescribeInstanceStatus, but never returned
+			for i := 0; i < 5; i++ {
+				instanceID := fake.InstanceID()
+				awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+					State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+					Placement:    &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+					InstanceId:   aws.String(instanceID),
+					InstanceType: aws.String("m5.large"),
+				})
+			}
+			awsEnv.EC2API.DescribeInstancesPageSize.Set(lo.ToPtr(10))
+		})
+		sweptIDs := func() []string {
+			GinkgoHelper()
+			instances, err := awsEnv.InstanceProvider.Sweep(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			return lo.Map(instances, func(i *instance.Instance, _ int) string { return i.ID })
+		}
+		It("should return the same instances as List", func() {
+			listed, err := awsEnv.InstanceProvider.List(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			swept, err := awsEnv.InstanceProvider.Sweep(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swept).To(Equal(listed))
+			Expect(sweptIDs()).To(ConsistOf(ids))
+		})
+		It("should only describe instances in full the first time they're swept", func() {
+			Expect(sweptIDs()).To(ConsistOf(ids))
+			Expect(awsEnv.EC2API.DescribeInstanceStatusBehavior.Calls()).To(Equal(3))
+			described := awsEnv.EC2API.DescribeInstancesBehavior.Calls()
+			Expect(described).To(BeNumerically(">", 0))
+
+			Expect(sweptIDs()).To(ConsistOf(ids))
+			Expect(awsEnv.EC2API.DescribeInstanceStatusBehavior.Calls()).To(Equal(6))
+			Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(Equal(described))
+		})
+		It("should describe an instance in full again when its state changes", func() {
+			sweptIDs()
+			raw, _ := awsEnv.EC2API.Instances.Load(ids[0])
+			raw.(*ec2.Instance).State = &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopping)}
+			awsEnv.EC2API.DescribeInstancesBehavior.CalledWithInput.Reset()
+
+			instances, err := awsEnv.InstanceProvider.Sweep(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.DescribeInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := awsEnv.EC2API.DescribeInstancesBehavior.CalledWithInput.Pop()
+			filter, ok := lo.Find(input.Filters, func(f *ec2.Filter) bool { return aws.StringValue(f.Name) == "instance-id" })
+			Expect(ok).To(BeTrue())
+			Expect(aws.StringValueSlice(filter.Values)).To(ConsistOf(ids[0]))
+			stopping, ok := lo.Find(instances, func(i *instance.Instance) bool { return i.ID == ids[0] })
+			Expect(ok).To(BeTrue())
+			Expect(stopping.State).To(Equal(ec2.InstanceStateNameStopping))
+		})
+		It("should describe an instance in full again after it's tagged", func() {
+			sweptIDs()
+			Expect(awsEnv.InstanceProvider.CreateTags(ctx, ids[0], map[string]string{"custom-tag": "custom-value"})).To(Succeed())
+
+			instances, err := awsEnv.InstanceProvider.Sweep(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			tagged, ok := lo.Find(instances, func(i *instance.Instance) bool { return i.ID == ids[0] })
+			Expect(ok).To(BeTrue())
+			Expect(tagged.Tags).To(HaveKeyWithValue("custom-tag", "custom-value"))
+		})
+		It("should fall back to List when it isn't permitted to describe instance status", func() {
+			awsEnv.EC2API.DescribeInstanceStatusBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil))
+			Expect(sweptIDs()).To(ConsistOf(ids))
+			Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(BeNumerically(">", 0))
+		})
+		It("should not return instances that were terminated since they were last swept", func() {
+			sweptIDs()
+			raw, _ := awsEnv.EC2API.Instances.Load(ids[0])
+			raw.(*ec2.Instance).State = &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameTerminated)}
+
+			Expect(sweptIDs()).To(ConsistOf(ids[1:]))
+		})
+	})
+	Context("Private DNS Name", func() {
+		var instanceID string
+		BeforeEach(func() {
+			// Instances in a VPC with DNS hostnames disabled aren't assigned a private DNS name
+			instanceID = fake.InstanceID()
+			awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+				State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				VpcId:        aws.String("vpc-without-dns-hostnames"),
+				Placement:    &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+				LaunchTime:   aws.Time(time.Now().Add(-time.Minute)),
+				InstanceId:   aws.String(instanceID),
+				InstanceType: aws.String("m5.large"),
+			})
+		})
+		It("should get an instance without a private DNS name", func() {
+			inst, err := awsEnv.InstanceProvider.Get(ctx, instanceID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inst.ID).To(Equal(instanceID))
+			Expect(inst.VPCID).To(Equal("vpc-without-dns-hostnames"))
+			Expect(inst.PrivateDNSName).To(BeEmpty())
+		})
+		It("should get an instance without a private DNS name when it isn't required", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeNameConvention: lo.ToPtr("resource-name"), RequirePrivateDNSName: lo.ToPtr(false)}))
+			inst, err := awsEnv.InstanceProvider.Get(ctx, instanceID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inst.ID).To(Equal(instanceID))
+			Expect(inst.PrivateDNSName).To(BeEmpty())
+		})
+	})
+	Context("Hourly Cost Limit", func() {
+		It("should return an ICE error and publish an event when no offering fits within the NodePool's hourly cost limit", func() {
+			nodePool.Annotations = map[string]string{v1beta1.AnnotationLimitHourlyCost: "0"}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			awsEnv.CostLimitProvider.Update(ctx, []corev1beta1.NodePool{*nodePool}, nil)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(costlimit.IsHourlyCostLimitExceeded(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EventRecorder.Calls("HourlyCostLimitExceeded")).To(Equal(1))
+		})
+		It("should list the offerings that don't fit within the NodePool's hourly cost limit as unavailable", func() {
+			nodePool.Annotations = map[string]string{v1beta1.AnnotationLimitHourlyCost: "0"}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			awsEnv.CostLimitProvider.Update(ctx, []corev1beta1.NodePool{*nodePool}, nil)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			for _, it := range instanceTypes {
+				Expect(it.Offerings.Available()).To(BeEmpty())
+			}
+		})
+		It("should launch instance types that fit within the NodePool's hourly cost limit", func() {
+			nodePool.Annotations = map[string]string{v1beta1.AnnotationLimitHourlyCost: "1000"}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			awsEnv.CostLimitProvider.Update(ctx, []corev1beta1.NodePool{*nodePool}, nil)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Pod ENIs", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool {
+				return lo.Contains([]string{"m5.large", "m5.xlarge", "t3.large"}, i.Name)
+			})
+		})
+		It("should only launch instance types with enough branch interfaces for the requested pod ENIs", func() {
+			nodeClaim.Spec.Resources.Requests = corev1.ResourceList{v1beta1.ResourceAWSPodENI: resource.MustParse("10")}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			names := lo.Uniq(lo.FlatMap(call.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []string {
+				return lo.Map(ltc.Overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest, _ int) string { return aws.StringValue(o.InstanceType) })
+			}))
+			Expect(names).To(ConsistOf("m5.xlarge"))
+			Expect(awsEnv.EventRecorder.Calls("InsufficientPodENIs")).To(Equal(0))
+		})
+		It("should not filter instance types when no pod ENIs are requested", func() {
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			names := lo.Uniq(lo.FlatMap(call.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []string {
+				return lo.Map(ltc.Overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest, _ int) string { return aws.StringValue(o.InstanceType) })
+			}))
+			Expect(names).To(ContainElement("t3.large"))
+		})
+		It("should return an ICE error and publish an event when no instance type has enough branch interfaces", func() {
+			nodeClaim.Spec.Resources.Requests = corev1.ResourceList{v1beta1.ResourceAWSPodENI: resource.MustParse("20")}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EventRecorder.Calls("InsufficientPodENIs")).To(Equal(1))
+			Expect(awsEnv.EventRecorder.DetectedEvent("Not launching, no allowed instance type has enough branch interfaces for the 20 pod ENIs requested by the pods")).To(BeTrue())
+		})
+	})
+	Context("Batch Create Tags", func() {
+		var ids []string
+		BeforeEach(func() {
+			ids = lo.Times(3, func(_ int) string {
+				id := fake.InstanceID()
+				awsEnv.EC2API.Instances.Store(id, &ec2.Instance{
+					State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+					Placement:    &ec2.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+					InstanceId:   aws.String(id),
+					InstanceType: aws.String("m5.large"),
+				})
+				return id
+			})
+		})
+		It("should tag every instance with a single call", func() {
+			Expect(awsEnv.InstanceProvider.BatchCreateTags(ctx, ids, map[string]string{"team": "platform"})).To(BeEmpty())
+			Expect(awsEnv.EC2API.CreateTagsBehavior.Calls()).To(Equal(1))
+			input := awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValueSlice(input.Resources)).To(ConsistOf(ids))
+			for _, id := range ids {
+				raw, _ := awsEnv.EC2API.Instances.Load(id)
+				Expect(instance.NewInstance(raw.(*ec2.Instance)).Tags).To(HaveKeyWithValue("team", "platform"))
+			}
+		})
+		It("should only report the instances that couldn't be tagged", func() {
+			missing := fake.InstanceID()
+			errs := awsEnv.InstanceProvider.BatchCreateTags(ctx, append(ids, missing), map[string]string{"team": "platform"})
+			Expect(errs).To(HaveLen(1))
+			Expect(errs).To(HaveKey(missing))
+			for _, id := range ids {
+				raw, _ := awsEnv.EC2API.Instances.Load(id)
+				Expect(instance.NewInstance(raw.(*ec2.Instance)).Tags).To(HaveKeyWithValue("team", "platform"))
+			}
+		})
+	})
 })
+
+type funnelSample struct {
+	count uint64
+	sum   float64
+}
+
+func funnelObservations(stage string) funnelSample {
+	GinkgoHelper()
+	metric, ok := FindMetricWithLabelValues("karpenter_aws_instance_type_funnel", map[string]string{"stage": stage})
+	if !ok {
+		return funnelSample{}
+	}
+	return funnelSample{count: metric.GetHistogram().GetSampleCount(), sum: metric.GetHistogram().GetSampleSum()}
+}
+
+// expectFunnelObservation expects a single observation of count for the stage since the before samples were taken
+func expectFunnelObservation(before map[string]funnelSample, stage string, count int) {
+	GinkgoHelper()
+	after := funnelObservations(stage)
+	Expect(after.count - before[stage].count).To(BeNumerically("==", 1))
+	Expect(after.sum - before[stage].sum).To(BeNumerically("==", count))
+}
+
+// cloneInstanceType copies the instance type under a new name and instance family, with every offering at the price
+func cloneInstanceType(it *corecloudprovider.InstanceType, name, family string, price float64) *corecloudprovider.InstanceType {
+	requirements := scheduling.NewRequirements(lo.Filter(lo.Values(it.Requirements), func(r *scheduling.Requirement, _ int) bool {
+		return r.Key != corev1.LabelInstanceTypeStable && r.Key != v1beta1.LabelInstanceFamily
+	})...)
+	requirements.Add(
+		scheduling.NewRequirement(corev1.LabelInstanceTypeStable, corev1.NodeSelectorOpIn, name),
+		scheduling.NewRequirement(v1beta1.LabelInstanceFamily, corev1.NodeSelectorOpIn, family),
+	)
+	return &corecloudprovider.InstanceType{
+		Name:         name,
+		Requirements: requirements,
+		Offerings: lo.Map(it.Offerings, func(o corecloudprovider.Offering, _ int) corecloudprovider.Offering {
+			o.Price = price
+			return o
+		}),
+		Capacity: it.Capacity,
+		Overhead: it.Overhead,
+	}
+}
+
+func instancesForNodeClaim(nodeClaim *corev1beta1.NodeClaim) []string {
+	GinkgoHelper()
+	out, err := awsEnv.EC2API.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String(fmt.Sprintf("tag:%s", v1beta1.TagNodeClaim)),
+			Values: aws.StringSlice([]string{nodeClaim.Name}),
+		}},
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return lo.FlatMap(out.Reservations, func(r *ec2.Reservation, _ int) []string {
+		return lo.Map(r.Instances, func(i *ec2.Instance, _ int) string { return aws.StringValue(i.InstanceId) })
+	})
+}
+
+// cancelingEC2API cancels the launch request once CreateFleet returns, simulating a NodeClaim that is deleted while its
+// fleet request is in flight
+type cancelingEC2API struct {
+	*fake.EC2API
+	cancel context.CancelFunc
+}
+
+func (c *cancelingEC2API) CreateFleetWithContext(ctx context.Context, input *ec2.CreateFleetInput, opts ...request.Option) (*ec2.CreateFleetOutput, error) {
+	defer c.cancel()
+	return c.EC2API.CreateFleetWithContext(ctx, input, opts...)
+}
+
+// cancelingLaunchTemplateProvider cancels the launch request once launch templates have been ensured
+type cancelingLaunchTemplateProvider struct {
+	launchtemplate.Provider
+	cancel context.CancelFunc
+}
+
+func (c *cancelingLaunchTemplateProvider) EnsureAll(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim,
+	instanceTypes []*corecloudprovider.InstanceType, capacityType string, tags map[string]string) ([]*launchtemplate.LaunchTemplate, error) {
+	defer c.cancel()
+	return c.Provider.EnsureAll(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
+}
@@ -22,6 +22,8 @@ import (
 	"github.com/samber/lo"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 )
 
 // Instance is an internal data representation of either an ec2.Instance or an ec2.FleetInstance
@@ -36,8 +38,16 @@ type Instance struct {
 	CapacityType     string
 	SecurityGroupIDs []string
 	SubnetID         string
+	VPCID            string
+	PrivateDNSName   string
 	Tags             map[string]string
 	EFAEnabled       bool
+	// FleetID and the launch template are only known for instances that were just launched
+	FleetID               string
+	LaunchTemplateName    string
+	LaunchTemplateVersion string
+	// SpotInstanceRequestID is only known for instances that were described
+	SpotInstanceRequestID string
 }
 
 func NewInstance(out *ec2.Instance) *Instance {
@@ -48,20 +58,37 @@ func NewInstance(out *ec2.Instance) *Instance {
 		ImageID:      aws.StringValue(out.ImageId),
 		Type:         aws.StringValue(out.InstanceType),
 		Zone:         aws.StringValue(out.Placement.AvailabilityZone),
-		CapacityType: lo.Ternary(out.SpotInstanceRequestId != nil, corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand),
+		CapacityType: capacityType(out),
 		SecurityGroupIDs: lo.Map(out.SecurityGroups, func(securitygroup *ec2.GroupIdentifier, _ int) string {
 			return aws.StringValue(securitygroup.GroupId)
 		}),
-		SubnetID: aws.StringValue(out.SubnetId),
-		Tags:     lo.SliceToMap(out.Tags, func(t *ec2.Tag) (string, string) { return aws.StringValue(t.Key), aws.StringValue(t.Value) }),
+		SubnetID:       aws.StringValue(out.SubnetId),
+		VPCID:          aws.StringValue(out.VpcId),
+		PrivateDNSName: aws.StringValue(out.PrivateDnsName),
+		Tags:           lo.SliceToMap(out.Tags, func(t *ec2.Tag) (string, string) { return aws.StringValue(t.Key), aws.StringValue(t.Value) }),
 		EFAEnabled: lo.ContainsBy(out.NetworkInterfaces, func(ni *ec2.InstanceNetworkInterface) bool {
 			return ni != nil && lo.FromPtr(ni.InterfaceType) == ec2.NetworkInterfaceTypeEfa
 		}),
+		SpotInstanceRequestID: aws.StringValue(out.SpotInstanceRequestId),
 	}
 
 }
 
-func NewInstanceFromFleet(out *ec2.CreateFleetInstance, tags map[string]string, efaEnabled bool) *Instance {
+// capacityType returns the capacity type of a described instance. Instances in capacity blocks have their own
+// lifecycle, while spot instances are identified by their spot instance request.
+func capacityType(out *ec2.Instance) string {
+	switch {
+	case aws.StringValue(out.InstanceLifecycle) == ec2.InstanceLifecycleTypeCapacityBlock:
+		return v1beta1.CapacityTypeCapacityBlock
+	case out.SpotInstanceRequestId != nil:
+		return corev1beta1.CapacityTypeSpot
+	default:
+		return corev1beta1.CapacityTypeOnDemand
+	}
+}
+
+func NewInstanceFromFleet(out *ec2.CreateFleetInstance, fleetID string, tags map[string]string, efaEnabled bool) *Instance {
+	launchTemplate := lo.FromPtr(out.LaunchTemplateAndOverrides.LaunchTemplateSpecification)
 	return &Instance{
 		LaunchTime:   time.Now(), // estimate the launch time since we just launched
 		State:        ec2.StatePending,
@@ -73,5 +100,9 @@ func NewInstanceFromFleet(out *ec2.CreateFleetInstance, tags map[string]string,
 		SubnetID:     aws.StringValue(out.LaunchTemplateAndOverrides.Overrides.SubnetId),
 		Tags:         tags,
 		EFAEnabled:   efaEnabled,
+
+		FleetID:               fleetID,
+		LaunchTemplateName:    aws.StringValue(launchTemplate.LaunchTemplateName),
+		LaunchTemplateVersion: aws.StringValue(launchTemplate.Version),
 	}
 }
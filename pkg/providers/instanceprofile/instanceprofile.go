@@ -17,15 +17,21 @@ package instanceprofile
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
 
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
@@ -34,19 +40,26 @@ import (
 type ResourceOwner interface {
 	GetUID() types.UID
 	InstanceProfileName(string, string) string
+	SharedInstanceProfileName(string, string) string
 	InstanceProfileRole() string
 	InstanceProfileTags(string) map[string]string
+	SharedInstanceProfileTags(string) map[string]string
 }
 
 type Provider interface {
 	Create(context.Context, ResourceOwner) (string, error)
 	Delete(context.Context, ResourceOwner) error
+	DeleteShared(context.Context, ResourceOwner) error
+	DeleteSharedByName(context.Context, string) error
 }
 
 type DefaultProvider struct {
 	region string
 	iamapi iamiface.IAMAPI
 	cache  *cache.Cache
+	// locks serializes the creation and deletion of each instance profile, since shared instance profiles are
+	// reconciled for every EC2NodeClass that references their role
+	locks sync.Map
 }
 
 func NewDefaultProvider(region string, iamapi iamiface.IAMAPI, cache *cache.Cache) *DefaultProvider {
@@ -57,16 +70,50 @@ func NewDefaultProvider(region string, iamapi iamiface.IAMAPI, cache *cache.Cach
 	}
 }
 
+// cachedInstanceProfile is the role and tags that an instance profile was last validated with, along with the path and
+// permissions boundary that were required of it. An instance profile that's cached with the desired state is assumed
+// not to have drifted, and isn't read from IAM again until the cache entry expires.
+type cachedInstanceProfile struct {
+	role                string
+	tagsHash            uint64
+	path                string
+	permissionsBoundary string
+}
+
+const (
+	PathMismatchReason                = "PathMismatch"
+	PermissionsBoundaryMismatchReason = "PermissionsBoundaryMismatch"
+)
+
+// MismatchError is returned when an instance profile doesn't have the configured path, or its role doesn't have the
+// configured permissions boundary. Neither can be corrected by Karpenter, so the instance profile is left as it is.
+type MismatchError struct {
+	Reason  string
+	message string
+}
+
+func (e *MismatchError) Error() string {
+	return e.message
+}
+
 func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string, error) {
-	profileName := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
-	tags := lo.Assign(m.InstanceProfileTags(options.FromContext(ctx).ClusterName), map[string]string{v1.LabelTopologyRegion: p.region})
+	profileName, tags, cacheKey := p.instanceProfile(ctx, m)
+	desired := cachedInstanceProfile{
+		role:                m.InstanceProfileRole(),
+		tagsHash:            lo.Must(hashstructure.Hash(tags, hashstructure.FormatV2, nil)),
+		path:                options.FromContext(ctx).InstanceProfilePath,
+		permissionsBoundary: options.FromContext(ctx).InstanceProfilePermissionsBoundary,
+	}
 
-	// An instance profile exists for this NodeClass
-	if _, ok := p.cache.Get(string(m.GetUID())); ok {
+	// An instance profile exists for this NodeClass with the desired role and tags
+	if cached, ok := p.cache.Get(cacheKey); ok && cached.(cachedInstanceProfile) == desired && !options.FromContext(ctx).ForceInstanceProfileRevalidation {
 		return profileName, nil
 	}
-	// Validate if the instance profile exists and has the correct role assigned to it
+	unlock := p.lock(profileName)
+	defer unlock()
+	// Validate if the instance profile exists and has the correct role and tags assigned to it
 	var instanceProfile *iam.InstanceProfile
+	created := false
 	out, err := p.iamapi.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String(profileName)})
 	if err != nil {
 		if !awserrors.IsNotFound(err) {
@@ -74,46 +121,190 @@ func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string,
 		}
 		o, err := p.iamapi.CreateInstanceProfileWithContext(ctx, &iam.CreateInstanceProfileInput{
 			InstanceProfileName: aws.String(profileName),
-			Tags:                lo.MapToSlice(tags, func(k, v string) *iam.Tag { return &iam.Tag{Key: aws.String(k), Value: aws.String(v)} }),
+			Path:                aws.String(desired.path),
+			Tags:                iamTags(tags),
 		})
 		if err != nil {
-			return "", fmt.Errorf("creating instance profile %q, %w", profileName, err)
+			if !awserrors.IsAlreadyExists(err) {
+				return "", fmt.Errorf("creating instance profile %q, %w", profileName, err)
+			}
+			// Another controller created the instance profile since it was read, such as a replica that was leader
+			// when it was read
+			if out, err = p.iamapi.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String(profileName)}); err != nil {
+				return "", fmt.Errorf("getting instance profile %q, %w", profileName, err)
+			}
+			instanceProfile = out.InstanceProfile
+		} else {
+			instanceProfile = o.InstanceProfile
 		}
-		instanceProfile = o.InstanceProfile
+		created = true
 	} else {
 		instanceProfile = out.InstanceProfile
 	}
+	if err = p.ensureTags(ctx, instanceProfile, tags); err != nil {
+		return "", err
+	}
+	if err = p.ensureRole(ctx, instanceProfile, m.InstanceProfileRole(), created); err != nil {
+		return "", err
+	}
+	// The instance profile can still be used when it doesn't match, so its name is returned along with the mismatch
+	if err = p.checkImmutable(ctx, instanceProfile, desired); err != nil {
+		return aws.StringValue(instanceProfile.InstanceProfileName), err
+	}
+	p.cache.SetDefault(cacheKey, desired)
+	return aws.StringValue(instanceProfile.InstanceProfileName), nil
+}
+
+// instanceProfile returns the name and tags of the instance profile that the owner uses, along with the key its
+// validated state is cached under. Shared instance profiles are cached by name, so that every EC2NodeClass sharing
+// one is validated against the same state.
+func (p *DefaultProvider) instanceProfile(ctx context.Context, m ResourceOwner) (string, map[string]string, string) {
+	clusterName := options.FromContext(ctx).ClusterName
+	if options.FromContext(ctx).SharedInstanceProfiles {
+		profileName := m.SharedInstanceProfileName(clusterName, p.region)
+		return profileName, lo.Assign(m.SharedInstanceProfileTags(clusterName), map[string]string{v1.LabelTopologyRegion: p.region}), profileName
+	}
+	return m.InstanceProfileName(clusterName, p.region), lo.Assign(m.InstanceProfileTags(clusterName), map[string]string{v1.LabelTopologyRegion: p.region}), string(m.GetUID())
+}
+
+// lock acquires the lock of the instance profile, returning a function that releases it
+func (p *DefaultProvider) lock(profileName string) func() {
+	mu, _ := p.locks.LoadOrStore(profileName, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// checkImmutable returns a MismatchError if the instance profile doesn't have the desired path, or its role doesn't
+// have the desired permissions boundary. The path of an instance profile can't be changed once it's created, and
+// Karpenter doesn't manage roles.
+func (p *DefaultProvider) checkImmutable(ctx context.Context, instanceProfile *iam.InstanceProfile, desired cachedInstanceProfile) error {
+	profileName := aws.StringValue(instanceProfile.InstanceProfileName)
+	// IAM defaults the path to "/" when it isn't set
+	path := aws.StringValue(instanceProfile.Path)
+	if path == "" {
+		path = "/"
+	}
+	if path != desired.path {
+		return &MismatchError{
+			Reason:  PathMismatchReason,
+			message: fmt.Sprintf("instance profile %q has path %q rather than %q, and must be deleted to change it", profileName, path, desired.path),
+		}
+	}
+	if desired.permissionsBoundary == "" {
+		return nil
+	}
+	out, err := p.iamapi.GetRoleWithContext(ctx, &iam.GetRoleInput{RoleName: aws.String(desired.role)})
+	if err != nil {
+		return fmt.Errorf("getting role %q, %w", desired.role, err)
+	}
+	var boundary string
+	if out.Role.PermissionsBoundary != nil {
+		boundary = aws.StringValue(out.Role.PermissionsBoundary.PermissionsBoundaryArn)
+	}
+	if boundary != desired.permissionsBoundary {
+		return &MismatchError{
+			Reason:  PermissionsBoundaryMismatchReason,
+			message: fmt.Sprintf("role %q of instance profile %q doesn't have the permissions boundary %q", desired.role, profileName, desired.permissionsBoundary),
+		}
+	}
+	return nil
+}
+
+// ensureTags adds the tags that are missing from the instance profile, or that have a different value. Other tags are
+// left in place.
+func (p *DefaultProvider) ensureTags(ctx context.Context, instanceProfile *iam.InstanceProfile, tags map[string]string) error {
+	existing := lo.SliceToMap(instanceProfile.Tags, func(t *iam.Tag) (string, string) { return aws.StringValue(t.Key), aws.StringValue(t.Value) })
+	drifted := lo.OmitBy(tags, func(k, v string) bool {
+		value, ok := existing[k]
+		return ok && value == v
+	})
+	if len(drifted) == 0 {
+		return nil
+	}
+	if _, err := p.iamapi.TagInstanceProfileWithContext(ctx, &iam.TagInstanceProfileInput{
+		InstanceProfileName: instanceProfile.InstanceProfileName,
+		Tags:                iamTags(drifted),
+	}); err != nil {
+		return fmt.Errorf("tagging instance profile %q, %w", aws.StringValue(instanceProfile.InstanceProfileName), err)
+	}
+	logging.FromContext(ctx).With("instance-profile", aws.StringValue(instanceProfile.InstanceProfileName), "tags", lo.Keys(drifted)).Infof("corrected drifted instance profile tags")
+	driftCorrectionsCount.With(prometheus.Labels{driftLabel: "tags"}).Inc()
+	return nil
+}
+
+// ensureRole replaces the role of the instance profile if it isn't the desired role
+func (p *DefaultProvider) ensureRole(ctx context.Context, instanceProfile *iam.InstanceProfile, role string, created bool) error {
+	profileName := aws.StringValue(instanceProfile.InstanceProfileName)
 	// Instance profiles can only have a single role assigned to them so this profile either has 1 or 0 roles
 	// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_use_switch-role-ec2_instance-profiles.html
 	if len(instanceProfile.Roles) == 1 {
-		if aws.StringValue(instanceProfile.Roles[0].RoleName) == m.InstanceProfileRole() {
-			return profileName, nil
+		if aws.StringValue(instanceProfile.Roles[0].RoleName) == role {
+			return nil
 		}
-		if _, err = p.iamapi.RemoveRoleFromInstanceProfileWithContext(ctx, &iam.RemoveRoleFromInstanceProfileInput{
+		if _, err := p.iamapi.RemoveRoleFromInstanceProfileWithContext(ctx, &iam.RemoveRoleFromInstanceProfileInput{
 			InstanceProfileName: aws.String(profileName),
 			RoleName:            instanceProfile.Roles[0].RoleName,
 		}); err != nil {
-			return "", fmt.Errorf("removing role %q for instance profile %q, %w", aws.StringValue(instanceProfile.Roles[0].RoleName), profileName, err)
+			return fmt.Errorf("removing role %q for instance profile %q, %w", aws.StringValue(instanceProfile.Roles[0].RoleName), profileName, err)
 		}
 	}
-	if _, err = p.iamapi.AddRoleToInstanceProfileWithContext(ctx, &iam.AddRoleToInstanceProfileInput{
+	if _, err := p.iamapi.AddRoleToInstanceProfileWithContext(ctx, &iam.AddRoleToInstanceProfileInput{
 		InstanceProfileName: aws.String(profileName),
-		RoleName:            aws.String(m.InstanceProfileRole()),
+		RoleName:            aws.String(role),
 	}); err != nil {
-		return "", fmt.Errorf("adding role %q to instance profile %q, %w", m.InstanceProfileRole(), profileName, err)
+		// Another controller may have added the role to an instance profile that was created concurrently
+		if out, getErr := p.iamapi.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String(profileName)}); getErr == nil &&
+			len(out.InstanceProfile.Roles) == 1 && aws.StringValue(out.InstanceProfile.Roles[0].RoleName) == role {
+			return nil
+		}
+		return fmt.Errorf("adding role %q to instance profile %q, %w", role, profileName, err)
 	}
-	p.cache.SetDefault(string(m.GetUID()), nil)
-	return aws.StringValue(instanceProfile.InstanceProfileName), nil
+	// A role is always added to an instance profile that was just created, which isn't a correction
+	if !created {
+		logging.FromContext(ctx).With("instance-profile", profileName, "role", role).Infof("corrected drifted instance profile role")
+		driftCorrectionsCount.With(prometheus.Labels{driftLabel: "role"}).Inc()
+	}
+	return nil
 }
 
+// Delete deletes the instance profile that was created for the owner. Shared instance profiles aren't deleted, since
+// other owners may still use them.
 func (p *DefaultProvider) Delete(ctx context.Context, m ResourceOwner) error {
-	profileName := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
+	return p.delete(ctx, m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region), false)
+}
+
+// DeleteShared deletes the instance profile that's shared by every owner with the same role as this one. The caller
+// must ensure that no other owner still uses it.
+func (p *DefaultProvider) DeleteShared(ctx context.Context, m ResourceOwner) error {
+	profileName := m.SharedInstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
+	// Owners that still share the instance profile validate it again rather than assuming it exists
+	p.cache.Delete(profileName)
+	return p.delete(ctx, profileName, false)
+}
+
+// DeleteSharedByName deletes the named instance profile if it's a shared instance profile, which is recognized by its
+// EC2NodeClass tag being valued with a role. A name that was recorded from an owner's status may be of an instance
+// profile that was created for that owner alone before instance profiles were shared, and that's left for the owner to
+// delete. The caller must ensure that no other owner still uses it.
+func (p *DefaultProvider) DeleteSharedByName(ctx context.Context, profileName string) error {
+	p.cache.Delete(profileName)
+	return p.delete(ctx, profileName, true)
+}
+
+func (p *DefaultProvider) delete(ctx context.Context, profileName string, sharedOnly bool) error {
+	unlock := p.lock(profileName)
+	defer unlock()
 	out, err := p.iamapi.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{
 		InstanceProfileName: aws.String(profileName),
 	})
 	if err != nil {
 		return awserrors.IgnoreNotFound(fmt.Errorf("getting instance profile %q, %w", profileName, err))
 	}
+	if sharedOnly && !lo.ContainsBy(out.InstanceProfile.Tags, func(t *iam.Tag) bool {
+		return aws.StringValue(t.Key) == v1beta1.LabelNodeClass && strings.HasPrefix(aws.StringValue(t.Value), "role/")
+	}) {
+		return nil
+	}
 	// Instance profiles can only have a single role assigned to them so this profile either has 1 or 0 roles
 	// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_use_switch-role-ec2_instance-profiles.html
 	if len(out.InstanceProfile.Roles) == 1 {
@@ -131,3 +322,7 @@ func (p *DefaultProvider) Delete(ctx context.Context, m ResourceOwner) error {
 	}
 	return nil
 }
+
+func iamTags(tags map[string]string) []*iam.Tag {
+	return lo.MapToSlice(tags, func(k, v string) *iam.Tag { return &iam.Tag{Key: aws.String(k), Value: aws.String(v)} })
+}
@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instanceprofile
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	instanceProfileSubsystem = "instance_profiles"
+	driftLabel               = "drift"
+)
+
+var (
+	driftCorrectionsCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: instanceProfileSubsystem,
+			Name:      "drift_corrections_total",
+			Help:      "Number of corrections to instance profiles managed by Karpenter whose role or tags were changed outside of Karpenter, by the kind of drift.",
+		},
+		[]string{driftLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(driftCorrectionsCount)
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"container/list"
+	"sync"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+// kubeletConfigurationKey holds the fields of the kubelet configuration that NewInstanceType reads. Only these are
+// hashed into the instance type cache key, so that NodePools whose kubelet configurations only differ in fields that
+// don't change instance types, such as clusterDNS or the image GC thresholds, share cached instance types.
+type kubeletConfigurationKey struct {
+	MaxPods        *int32
+	PodsPerCore    *int32
+	KubeReserved   map[string]string
+	SystemReserved map[string]string
+	EvictionHard   map[string]string
+	EvictionSoft   map[string]string
+}
+
+func newKubeletConfigurationKey(kc *corev1beta1.KubeletConfiguration) kubeletConfigurationKey {
+	return kubeletConfigurationKey{
+		MaxPods:        kc.MaxPods,
+		PodsPerCore:    kc.PodsPerCore,
+		KubeReserved:   kc.KubeReserved,
+		SystemReserved: kc.SystemReserved,
+		EvictionHard:   kc.EvictionHard,
+		EvictionSoft:   kc.EvictionSoft,
+	}
+}
+
+// cacheKeys orders the keys of the fully initialized instance types in the cache from most to least recently used,
+// so that the least recently used are evicted once there are too many of them
+type cacheKeys struct {
+	mu       sync.Mutex
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newCacheKeys() *cacheKeys {
+	return &cacheKeys{order: list.New(), elements: map[string]*list.Element{}}
+}
+
+// touch marks the key as the most recently used
+func (k *cacheKeys) touch(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if e, ok := k.elements[key]; ok {
+		k.order.MoveToFront(e)
+	}
+}
+
+// add adds the key as the most recently used, dropping the keys that are no longer live and then the least recently
+// used keys beyond max. It returns the keys that were evicted to stay within max and the number of keys that remain.
+func (k *cacheKeys) add(key string, max int, live func(string) bool) ([]string, int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for e := k.order.Front(); e != nil; {
+		next := e.Next()
+		if !live(e.Value.(string)) {
+			k.remove(e)
+		}
+		e = next
+	}
+	if e, ok := k.elements[key]; ok {
+		k.order.MoveToFront(e)
+	} else {
+		k.elements[key] = k.order.PushFront(key)
+	}
+	var evicted []string
+	for k.order.Len() > max {
+		e := k.order.Back()
+		evicted = append(evicted, e.Value.(string))
+		k.remove(e)
+	}
+	return evicted, k.order.Len()
+}
+
+func (k *cacheKeys) remove(e *list.Element) {
+	k.order.Remove(e)
+	delete(k.elements, e.Value.(string))
+}
+
+func (k *cacheKeys) reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.order.Init()
+	k.elements = map[string]*list.Element{}
+}
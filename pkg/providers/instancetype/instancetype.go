@@ -16,10 +16,14 @@ package instancetype
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"path"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
@@ -33,10 +37,13 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/outposts"
+	"github.com/aws/aws-sdk-go/service/outposts/outpostsiface"
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/logging"
 
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 
@@ -49,17 +56,22 @@ import (
 const (
 	InstanceTypesCacheKey         = "types"
 	InstanceTypeOfferingsCacheKey = "offerings"
+	// OutpostInstanceTypesCacheKeyPrefix is followed by the ARN of the Outpost
+	OutpostInstanceTypesCacheKeyPrefix = "outpost-types:"
 )
 
 type Provider interface {
 	LivenessProbe(*http.Request) error
 
 	List(context.Context, *corev1beta1.KubeletConfiguration, *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error)
+	EphemeralStorageModel(context.Context, *v1beta1.EC2NodeClass, string) (string, error)
+	Invalidate()
 }
 
 type DefaultProvider struct {
 	region          string
 	ec2api          ec2iface.EC2API
+	outpostsapi     outpostsiface.OutpostsAPI
 	subnetProvider  subnet.Provider
 	pricingProvider pricing.Provider
 	// Has one cache entry for all the instance types (key: InstanceTypesCacheKey)
@@ -70,6 +82,8 @@ type DefaultProvider struct {
 
 	mu    sync.Mutex
 	cache *cache.Cache
+	// cacheKeys bounds the number of fully initialized instance type sets in the cache, evicting the least recently used
+	cacheKeys *cacheKeys
 
 	unavailableOfferings *awscache.UnavailableOfferings
 	cm                   *pretty.ChangeMonitor
@@ -77,16 +91,39 @@ type DefaultProvider struct {
 	instanceTypesSeqNum uint64
 	// instanceTypeOfferingsSeqNum is a monotonically increasing change counter used to avoid the expensive hashing operation on instance types
 	instanceTypeOfferingsSeqNum uint64
+
+	// The last successful responses from EC2 are kept apart from the TTL cache so that they can be served when EC2
+	// calls fail, for up to the instance-type-max-staleness
+	lastKnownInstanceTypes             []*ec2.InstanceTypeInfo
+	lastKnownInstanceTypesTime         time.Time
+	lastKnownInstanceTypeOfferings     map[string]sets.Set[string]
+	lastKnownInstanceTypeOfferingsTime time.Time
+}
+
+// NoSubnetZonesError is returned when the subnetSelectorTerms of an EC2NodeClass don't select any subnets, which leaves
+// its instance types without a zone to be offered in
+type NoSubnetZonesError struct {
+	NodeClass string
 }
 
-func NewDefaultProvider(region string, cache *cache.Cache, ec2api ec2iface.EC2API, subnetProvider subnet.Provider,
+func (e NoSubnetZonesError) Error() string {
+	return fmt.Sprintf("no subnets matched the subnetSelectorTerms of ec2nodeclass %s, instance types have no zones to launch into", e.NodeClass)
+}
+
+func IsNoSubnetZones(err error) bool {
+	return errors.As(err, &NoSubnetZonesError{})
+}
+
+func NewDefaultProvider(region string, cache *cache.Cache, ec2api ec2iface.EC2API, outpostsapi outpostsiface.OutpostsAPI, subnetProvider subnet.Provider,
 	unavailableOfferingsCache *awscache.UnavailableOfferings, pricingProvider pricing.Provider) *DefaultProvider {
 	return &DefaultProvider{
 		ec2api:               ec2api,
+		outpostsapi:          outpostsapi,
 		region:               region,
 		subnetProvider:       subnetProvider,
 		pricingProvider:      pricingProvider,
 		cache:                cache,
+		cacheKeys:            newCacheKeys(),
 		unavailableOfferings: unavailableOfferingsCache,
 		cm:                   pretty.NewChangeMonitor(),
 		instanceTypesSeqNum:  0,
@@ -108,9 +145,30 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 	if err != nil {
 		return nil, err
 	}
+	var outpostInstanceTypes sets.Set[string]
+	if nodeClass != nil && nodeClass.Spec.OutpostARN != nil {
+		if outpostInstanceTypes, err = p.getOutpostInstanceTypes(ctx, aws.StringValue(nodeClass.Spec.OutpostARN)); err != nil {
+			return nil, err
+		}
+	}
 	subnetZones := sets.New[string](lo.Map(subnets, func(s *ec2.Subnet, _ int) string {
 		return aws.StringValue(s.AvailabilityZone)
 	})...)
+	// An EC2NodeClass without subnetSelectorTerms, which is what's listed when there's no NodePool, is expected to
+	// have no zones. Otherwise every offering would be dropped and scheduling would fail without pointing at the cause.
+	if subnetZones.Len() == 0 && nodeClass != nil && len(nodeClass.Spec.SubnetSelectorTerms) > 0 {
+		return nil, NoSubnetZonesError{NodeClass: nodeClass.Name}
+	}
+	zones, err := p.subnetProvider.AvailabilityZones(ctx, subnets)
+	if err != nil {
+		return nil, err
+	}
+	wavelengthZones := sets.New[string](lo.FilterMap(lo.Values(zones), func(z *ec2.AvailabilityZone, _ int) (string, bool) {
+		return aws.StringValue(z.ZoneName), aws.StringValue(z.ZoneType) == subnet.ZoneTypeWavelengthZone
+	})...)
+	zoneIDs := lo.SliceToMap(lo.Filter(subnets, func(s *ec2.Subnet, _ int) bool { return s.AvailabilityZoneId != nil }), func(s *ec2.Subnet) (string, string) {
+		return aws.StringValue(s.AvailabilityZone), aws.StringValue(s.AvailabilityZoneId)
+	})
 
 	if kc == nil {
 		kc = &corev1beta1.KubeletConfiguration{}
@@ -119,21 +177,53 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 		nodeClass = &v1beta1.EC2NodeClass{}
 	}
 
+	amiFamily := amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{})
+	// Root volumes raised to fit the resolved AMIs' root snapshots are reflected in ephemeral-storage capacity
+	blockDeviceMappings := amifamily.BlockDeviceMappings(ctx, nodeClass, amiFamily, amifamily.StatusAMIs(nodeClass))
+	vmMemoryOverheadPercent := vmMemoryOverheadPercent(ctx, nodeClass)
+	reservedENIs := reservedENIs(ctx, nodeClass)
+
 	// Compute fully initialized instance types hash key
 	subnetZonesHash, _ := hashstructure.Hash(subnetZones, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	kcHash, _ := hashstructure.Hash(kc, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	blockDeviceMappingsHash, _ := hashstructure.Hash(nodeClass.Spec.BlockDeviceMappings, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	key := fmt.Sprintf("%d-%d-%d-%016x-%016x-%016x-%s-%s",
+	kcHash, _ := hashstructure.Hash(newKubeletConfigurationKey(kc), hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	blockDeviceMappingsHash, _ := hashstructure.Hash(blockDeviceMappings, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	instanceTypeListsHash, _ := hashstructure.Hash([][]string{options.FromContext(ctx).InstanceTypeAllowlist, options.FromContext(ctx).InstanceTypeDenylist}, hashstructure.FormatV2, nil)
+	maxPodsOverridesHash, _ := hashstructure.Hash(nodeClass.Spec.MaxPodsOverrides, hashstructure.FormatV2, nil)
+	instanceStoreHash, _ := hashstructure.Hash(nodeClass.Spec.InstanceStore, hashstructure.FormatV2, nil)
+	ipv6Hash, _ := hashstructure.Hash(nodeClass.Spec.IPv6, hashstructure.FormatV2, nil)
+	outpostInstanceTypesHash, _ := hashstructure.Hash(outpostInstanceTypes, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	capacityBlocks := capacityBlocks(nodeClass)
+	capacityBlocksHash, _ := hashstructure.Hash(capacityBlocks, hashstructure.FormatV2, nil)
+	// The resolved AMIs change the root volume and so the ephemeral-storage capacity, so they're keyed on directly
+	// rather than relying on each of their effects being reflected in another part of the key
+	amisHash, _ := hashstructure.Hash(nodeClass.Status.AMIs, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	key := fmt.Sprintf("%d-%d-%d-%016x-%016x-%016x-%016x-%016x-%016x-%016x-%016x-%016x-%016x-%t-%g-%d-%d-%s-%g-%s-%s-%s-%s",
 		p.instanceTypesSeqNum,
 		p.instanceTypeOfferingsSeqNum,
-		p.unavailableOfferings.SeqNum,
+		p.unavailableOfferings.SeqNum(),
 		subnetZonesHash,
 		kcHash,
 		blockDeviceMappingsHash,
+		instanceTypeListsHash,
+		maxPodsOverridesHash,
+		instanceStoreHash,
+		ipv6Hash,
+		outpostInstanceTypesHash,
+		capacityBlocksHash,
+		amisHash,
+		options.FromContext(ctx).NetworkBandwidthResource,
+		vmMemoryOverheadPercent,
+		reservedENIs,
+		options.FromContext(ctx).GravitonCMAReservedMemoryMiB,
+		options.FromContext(ctx).InstanceStoreKubeReservedStorage,
+		options.FromContext(ctx).InstanceStoreEvictionPercent,
 		aws.StringValue((*string)(nodeClass.Spec.InstanceStorePolicy)),
 		aws.StringValue(nodeClass.Spec.AMIFamily),
+		aws.StringValue(nodeClass.Spec.Tenancy),
+		aws.StringValue(nodeClass.Spec.OutpostARN),
 	)
 	if item, ok := p.cache.Get(key); ok {
+		p.cacheKeys.touch(key)
 		return item.([]*cloudprovider.InstanceType), nil
 	}
 
@@ -148,7 +238,33 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 	if p.cm.HasChanged("zones", allZones) {
 		logging.FromContext(ctx).With("zones", allZones.UnsortedList()).Debugf("discovered zones")
 	}
-	amiFamily := amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{})
+	instanceTypes = lo.Filter(instanceTypes, func(i *ec2.InstanceTypeInfo, _ int) bool {
+		return allowedByOperator(ctx, aws.StringValue(i.InstanceType))
+	})
+	tenancy := lo.FromPtrOr(nodeClass.Spec.Tenancy, ec2.TenancyDefault)
+	// DedicatedHostsSupported only describes Dedicated Host support, so instance types aren't filtered for dedicated
+	// tenancy, which EC2 doesn't report support for
+	if tenancy == ec2.TenancyHost {
+		instanceTypes = lo.Filter(instanceTypes, func(i *ec2.InstanceTypeInfo, _ int) bool {
+			return aws.BoolValue(i.DedicatedHostsSupported)
+		})
+	}
+	if nodeClass.Spec.OutpostARN != nil {
+		instanceTypes = lo.Filter(instanceTypes, func(i *ec2.InstanceTypeInfo, _ int) bool {
+			return outpostInstanceTypes.Has(aws.StringValue(i.InstanceType))
+		})
+		if p.cm.HasChanged(fmt.Sprintf("outpost-pricing/%s", nodeClass.Name), nodeClass.Spec.OutpostARN) {
+			logging.FromContext(ctx).With("outpost", aws.StringValue(nodeClass.Spec.OutpostARN)).Infof("pricing outpost instance types with the on-demand prices of the region, which don't reflect the cost of outpost capacity")
+		}
+	}
+	// Mirroring needs at least two disks, so instance types with a single instance store disk can't be used
+	if lo.FromPtr(nodeClass.Spec.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID10 ||
+		(lo.FromPtr(nodeClass.Spec.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 && nodeClass.Spec.InstanceStore != nil &&
+			lo.FromPtr(nodeClass.Spec.InstanceStore.RAIDLevel) == v1beta1.RAIDLevelRAID1) {
+		instanceTypes = lo.Filter(instanceTypes, func(i *ec2.InstanceTypeInfo, _ int) bool {
+			return instanceStoreDiskCount(i) != 1
+		})
+	}
 	result := lo.Map(instanceTypes, func(i *ec2.InstanceTypeInfo, _ int) *cloudprovider.InstanceType {
 		instanceTypeVCPU.With(prometheus.Labels{
 			instanceTypeLabel: *i.InstanceType,
@@ -159,17 +275,77 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 
 		// !!! Important !!!
 		// Any changes to the values passed into the NewInstanceType method will require making updates to the cache key
-		// so that Karpenter is able to cache the set of InstanceTypes based on values that alter the set of instance types
+		// so that Karpenter is able to cache the set of InstanceTypes based on values that alter the set of instance types.
+		// Kubelet configuration fields that are passed in must also be added to kubeletConfigurationKey.
 		// !!! Important !!!
-		return NewInstanceType(ctx, i, p.region,
-			nodeClass.Spec.BlockDeviceMappings, nodeClass.Spec.InstanceStorePolicy,
-			kc.MaxPods, kc.PodsPerCore, kc.KubeReserved, kc.SystemReserved, kc.EvictionHard, kc.EvictionSoft,
-			amiFamily, p.createOfferings(ctx, i, instanceTypeOfferings[aws.StringValue(i.InstanceType)], allZones, subnetZones))
+		maxPods := kc.MaxPods
+		if override, ok := nodeClass.MaxPodsOverride(aws.StringValue(i.InstanceType)); ok {
+			maxPods = lo.ToPtr(override)
+		}
+		// Instance types on an Outpost are available in the zone of its subnets, regardless of the region's offerings
+		instanceTypeZones := instanceTypeOfferings[aws.StringValue(i.InstanceType)]
+		if nodeClass.Spec.OutpostARN != nil {
+			instanceTypeZones = subnetZones
+		}
+		it := NewInstanceType(ctx, i, p.region,
+			blockDeviceMappings, nodeClass.Spec.InstanceStorePolicy, nodeClass.Spec.InstanceStore, nodeClass.Spec.IPv6, reservedENIs, vmMemoryOverheadPercent,
+			maxPods, kc.PodsPerCore, kc.KubeReserved, kc.SystemReserved, kc.EvictionHard, kc.EvictionSoft,
+			amiFamily, p.createOfferings(ctx, i, instanceTypeZones, allZones, subnetZones, wavelengthZones, capacityBlocks[aws.StringValue(i.InstanceType)],
+				tenancy, nodeClass.Spec.OutpostARN != nil))
+		it.Requirements.Add(zoneIDRequirement(it.Offerings, zoneIDs))
+		return it
 	})
 	p.cache.SetDefault(key, result)
+	p.trackCacheKey(ctx, key)
 	return result, nil
 }
 
+// trackCacheKey bounds the number of fully initialized instance type sets in the cache. Each distinct kubelet
+// configuration and EC2NodeClass holds its own copy of every instance type, so configurations that vary per NodePool
+// evict the least recently used sets, which are recomputed when they're next listed, rather than growing the cache
+// without bound.
+func (p *DefaultProvider) trackCacheKey(ctx context.Context, key string) {
+	maxKeys := options.FromContext(ctx).InstanceTypeCacheMaxKeys
+	evicted, count := p.cacheKeys.add(key, maxKeys, func(k string) bool {
+		_, ok := p.cache.Get(k)
+		return ok
+	})
+	for _, k := range evicted {
+		p.cache.Delete(k)
+	}
+	instanceTypeCacheKeys.Set(float64(count))
+	warning := count > maxKeys/2
+	if p.cm.HasChanged("instance-type-cache-keys-warning", warning) && warning {
+		logging.FromContext(ctx).With("count", count, "max", maxKeys).Warnf("caching instance types for many distinct kubelet configurations and nodeclasses, the least recently used are recomputed once the max is exceeded")
+	}
+}
+
+// instanceStoreDiskCount returns the number of instance store disks that the instance type has
+func instanceStoreDiskCount(info *ec2.InstanceTypeInfo) int64 {
+	if info.InstanceStorageInfo == nil {
+		return 0
+	}
+	return lo.SumBy(info.InstanceStorageInfo.Disks, func(d *ec2.DiskInfo) int64 { return aws.Int64Value(d.Count) })
+}
+
+// vmMemoryOverheadPercent returns the EC2NodeClass's VM memory overhead, falling back to the operator's when it isn't set
+func vmMemoryOverheadPercent(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) float64 {
+	if nodeClass.Spec.VMMemoryOverheadPercent != nil {
+		if percent, err := strconv.ParseFloat(aws.StringValue(nodeClass.Spec.VMMemoryOverheadPercent), 64); err == nil {
+			return percent
+		}
+	}
+	return options.FromContext(ctx).VMMemoryOverheadPercent
+}
+
+// reservedENIs returns the EC2NodeClass's reserved ENIs, falling back to the operator's when it isn't set
+func reservedENIs(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) int {
+	if nodeClass.Spec.ReservedENIs != nil {
+		return int(*nodeClass.Spec.ReservedENIs)
+	}
+	return options.FromContext(ctx).ReservedENIs
+}
+
 func (p *DefaultProvider) LivenessProbe(req *http.Request) error {
 	if err := p.subnetProvider.LivenessProbe(req); err != nil {
 		return err
@@ -177,12 +353,40 @@ func (p *DefaultProvider) LivenessProbe(req *http.Request) error {
 	return p.pricingProvider.LivenessProbe(req)
 }
 
-func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2.InstanceTypeInfo, instanceTypeZones, zones, subnetZones sets.Set[string]) []cloudprovider.Offering {
+// capacityBlocks returns the IDs of the EC2NodeClass's capacity block reservations by their zone, by the instance type
+// that they reserve
+func capacityBlocks(nodeClass *v1beta1.EC2NodeClass) map[string]map[string]string {
+	reservations := map[string]map[string]string{}
+	for _, capacityReservation := range nodeClass.Status.CapacityReservations {
+		if capacityReservation.ReservationType != ec2.CapacityReservationTypeCapacityBlock {
+			continue
+		}
+		if _, ok := reservations[capacityReservation.InstanceType]; !ok {
+			reservations[capacityReservation.InstanceType] = map[string]string{}
+		}
+		reservations[capacityReservation.InstanceType][capacityReservation.AvailabilityZone] = capacityReservation.ID
+	}
+	return reservations
+}
+
+func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2.InstanceTypeInfo, instanceTypeZones, zones, subnetZones, wavelengthZones sets.Set[string],
+	capacityBlocks map[string]string, tenancy string, outpost bool) []cloudprovider.Offering {
 	var offerings []cloudprovider.Offering
+	// while usage classes should be a distinct set, there's no guarantee of that
+	capacityTypes := sets.NewString(aws.StringValueSlice(instanceType.SupportedUsageClasses)...)
+	if len(capacityBlocks) > 0 {
+		capacityTypes.Insert(ec2.UsageClassTypeCapacityBlock)
+	}
 	for zone := range zones {
-		// while usage classes should be a distinct set, there's no guarantee of that
-		for capacityType := range sets.NewString(aws.StringValueSlice(instanceType.SupportedUsageClasses)...) {
-			// exclude any offerings that have recently seen an insufficient capacity error from EC2
+		for capacityType := range capacityTypes {
+			// spot instances are only launched with default tenancy, and can't be launched onto Outposts or into
+			// Wavelength Zones, which the default spot price would otherwise make available when spot prices haven't
+			// been fetched
+			if (tenancy != ec2.TenancyDefault || outpost || wavelengthZones.Has(zone)) && capacityType == ec2.UsageClassTypeSpot {
+				continue
+			}
+			// exclude any offerings that have recently seen an insufficient capacity error from EC2. Capacity blocks only
+			// see the errors of launches into their own reservation.
 			isUnavailable := p.unavailableOfferings.IsUnavailable(*instanceType.InstanceType, zone, capacityType)
 			var price float64
 			var ok bool
@@ -191,13 +395,22 @@ func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2
 				price, ok = p.pricingProvider.SpotPrice(*instanceType.InstanceType, zone)
 			case ec2.UsageClassTypeOnDemand:
 				price, ok = p.pricingProvider.OnDemandPrice(*instanceType.InstanceType)
-			case "capacity-block":
-				// ignore since karpenter doesn't support it yet, but do not log an unknown capacity type error
-				continue
+			case ec2.UsageClassTypeCapacityBlock:
+				// Capacity blocks are only offered in the zones that the EC2NodeClass has reserved them in. They're paid
+				// for up front, so launching into one has no marginal cost.
+				id, reserved := capacityBlocks[zone]
+				if !reserved {
+					continue
+				}
+				isUnavailable = p.unavailableOfferings.IsUnavailable(*instanceType.InstanceType, zone, capacityType, awscache.ReservationScope(id))
+				price, ok = 0, true
 			default:
 				logging.FromContext(ctx).Errorf("Received unknown capacity type %s for instance type %s", capacityType, *instanceType.InstanceType)
 				continue
 			}
+			if !ok {
+				instanceTypeOfferingPriceMissingTotal.With(prometheus.Labels{capacityTypeLabel: capacityType}).Inc()
+			}
 			available := !isUnavailable && ok && instanceTypeZones.Has(zone) && subnetZones.Has(zone)
 			offerings = append(offerings, cloudprovider.Offering{
 				Zone:         zone,
@@ -243,8 +456,14 @@ func (p *DefaultProvider) getInstanceTypeOfferings(ctx context.Context) (map[str
 			}
 			return true
 		}); err != nil {
-		return nil, fmt.Errorf("describing instance type zone offerings, %w", err)
+		err = fmt.Errorf("describing instance type zone offerings, %w", err)
+		if p.serveStale(ctx, describeInstanceTypeOfferingsAPI, p.lastKnownInstanceTypeOfferingsTime, err) {
+			return p.lastKnownInstanceTypeOfferings, nil
+		}
+		return nil, err
 	}
+	p.lastKnownInstanceTypeOfferings, p.lastKnownInstanceTypeOfferingsTime = instanceTypeOfferings, time.Now()
+	instanceTypeDataStale.With(prometheus.Labels{apiLabel: describeInstanceTypeOfferingsAPI}).Set(0)
 	if p.cm.HasChanged("instance-type-offering", instanceTypeOfferings) {
 		// Only update instanceTypesSeqNun with the instance type offerings  have been changed
 		// This is to not create new keys with duplicate instance type offerings option
@@ -255,6 +474,30 @@ func (p *DefaultProvider) getInstanceTypeOfferings(ctx context.Context) (map[str
 	return instanceTypeOfferings, nil
 }
 
+// getOutpostInstanceTypes returns the names of the instance types that are available on the Outpost
+func (p *DefaultProvider) getOutpostInstanceTypes(ctx context.Context, outpostARN string) (sets.Set[string], error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, ok := p.cache.Get(OutpostInstanceTypesCacheKeyPrefix + outpostARN); ok {
+		return cached.(sets.Set[string]), nil
+	}
+	instanceTypes := sets.New[string]()
+	if err := p.outpostsapi.GetOutpostInstanceTypesPagesWithContext(ctx, &outposts.GetOutpostInstanceTypesInput{OutpostId: aws.String(outpostARN)},
+		func(output *outposts.GetOutpostInstanceTypesOutput, _ bool) bool {
+			for _, item := range output.InstanceTypes {
+				instanceTypes.Insert(aws.StringValue(item.InstanceType))
+			}
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("getting instance types of outpost %s, %w", outpostARN, err)
+	}
+	if p.cm.HasChanged(OutpostInstanceTypesCacheKeyPrefix+outpostARN, instanceTypes) {
+		logging.FromContext(ctx).With("outpost", outpostARN, "count", instanceTypes.Len()).Debugf("discovered outpost instance types")
+	}
+	p.cache.SetDefault(OutpostInstanceTypesCacheKeyPrefix+outpostARN, instanceTypes)
+	return instanceTypes, nil
+}
+
 // GetInstanceTypes retrieves all instance types from the ec2 DescribeInstanceTypes API using some opinionated filters
 func (p *DefaultProvider) GetInstanceTypes(ctx context.Context) ([]*ec2.InstanceTypeInfo, error) {
 	// DO NOT REMOVE THIS LOCK ----------------------------------------------------------------------------
@@ -284,8 +527,14 @@ func (p *DefaultProvider) GetInstanceTypes(ctx context.Context) ([]*ec2.Instance
 		instanceTypes = append(instanceTypes, page.InstanceTypes...)
 		return true
 	}); err != nil {
-		return nil, fmt.Errorf("fetching instance types using ec2.DescribeInstanceTypes, %w", err)
+		err = fmt.Errorf("fetching instance types using ec2.DescribeInstanceTypes, %w", err)
+		if p.serveStale(ctx, describeInstanceTypesAPI, p.lastKnownInstanceTypesTime, err) {
+			return p.lastKnownInstanceTypes, nil
+		}
+		return nil, err
 	}
+	p.lastKnownInstanceTypes, p.lastKnownInstanceTypesTime = instanceTypes, time.Now()
+	instanceTypeDataStale.With(prometheus.Labels{apiLabel: describeInstanceTypesAPI}).Set(0)
 	if p.cm.HasChanged("instance-types", instanceTypes) {
 		// Only update instanceTypesSeqNun with the instance types have been changed
 		// This is to not create new keys with duplicate instance types option
@@ -296,3 +545,62 @@ func (p *DefaultProvider) GetInstanceTypes(ctx context.Context) ([]*ec2.Instance
 	p.cache.SetDefault(InstanceTypesCacheKey, instanceTypes)
 	return instanceTypes, nil
 }
+
+// EphemeralStorageModel returns where the ephemeral storage of the named instance type is when it's launched from the
+// EC2NodeClass, which is what its ephemeral-storage overhead was computed against
+func (p *DefaultProvider) EphemeralStorageModel(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, name string) (string, error) {
+	instanceTypes, err := p.GetInstanceTypes(ctx)
+	if err != nil {
+		return "", err
+	}
+	info, ok := lo.Find(instanceTypes, func(i *ec2.InstanceTypeInfo) bool { return aws.StringValue(i.InstanceType) == name })
+	if !ok {
+		return "", fmt.Errorf("instance type %q not found", name)
+	}
+	return EphemeralStorageModel(info, nodeClass.Spec.InstanceStorePolicy, amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{})), nil
+}
+
+// serveStale returns whether the last successful response from an EC2 API, received at lastRefresh, should be served
+// in place of a failed refresh. The stale data isn't cached, so every call retries EC2 until it recovers.
+func (p *DefaultProvider) serveStale(ctx context.Context, api string, lastRefresh time.Time, err error) bool {
+	instanceTypeDataStale.With(prometheus.Labels{apiLabel: api}).Set(1)
+	if lastRefresh.IsZero() || time.Since(lastRefresh) > options.FromContext(ctx).InstanceTypeMaxStaleness {
+		return false
+	}
+	logging.FromContext(ctx).With("api", api, "last-refresh", lastRefresh.Format(time.RFC3339)).Warnf("serving instance type data from the last successful refresh, %s", err)
+	instanceTypeStaleServesTotal.With(prometheus.Labels{apiLabel: api}).Inc()
+	return true
+}
+
+// Invalidate drops the cached instance types and offerings so that the next List refetches them from EC2. Bumping the
+// sequence numbers also retires every instance type list that was resolved from them.
+func (p *DefaultProvider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache.Flush()
+	atomic.AddUint64(&p.instanceTypesSeqNum, 1)
+	atomic.AddUint64(&p.instanceTypeOfferingsSeqNum, 1)
+}
+
+// Reset clears the last known instance types and offerings
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastKnownInstanceTypes, p.lastKnownInstanceTypesTime = nil, time.Time{}
+	p.lastKnownInstanceTypeOfferings, p.lastKnownInstanceTypeOfferingsTime = nil, time.Time{}
+	p.cacheKeys.reset()
+}
+
+// allowedByOperator returns whether the instance type passes the operator's instance type allowlist and denylist. An
+// empty allowlist allows every instance type, and a type matching both lists is denied.
+func allowedByOperator(ctx context.Context, instanceType string) bool {
+	matches := func(pattern string) bool {
+		matched, _ := path.Match(pattern, instanceType)
+		return matched
+	}
+	opts := options.FromContext(ctx)
+	if lo.ContainsBy(opts.InstanceTypeDenylist, matches) {
+		return false
+	}
+	return len(opts.InstanceTypeAllowlist) == 0 || lo.ContainsBy(opts.InstanceTypeAllowlist, matches)
+}
@@ -26,6 +26,10 @@ const (
 	instanceTypeLabel      = "instance_type"
 	capacityTypeLabel      = "capacity_type"
 	zoneLabel              = "zone"
+	apiLabel               = "api"
+
+	describeInstanceTypesAPI         = "DescribeInstanceTypes"
+	describeInstanceTypeOfferingsAPI = "DescribeInstanceTypeOfferings"
 )
 
 var (
@@ -76,8 +80,50 @@ var (
 			capacityTypeLabel,
 			zoneLabel,
 		})
+	instanceTypeOfferingPriceMissingTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_type_offering_price_missing_total",
+			Help:      "Number of instance type offerings that were made unavailable because their price wasn't known when the instance types were listed, labeled by capacity type.",
+		},
+		[]string{
+			capacityTypeLabel,
+		},
+	)
+	instanceTypeStaleServesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_type_stale_serves_total",
+			Help:      "Number of times instance type data from the last successful refresh was served because refreshing it from EC2 failed, labeled by EC2 API.",
+		},
+		[]string{
+			apiLabel,
+		},
+	)
+	instanceTypeDataStale = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_type_data_stale",
+			Help:      "Whether the last refresh of instance type data from EC2 failed, labeled by EC2 API. 1 while refreshes are failing and 0 once they succeed.",
+		},
+		[]string{
+			apiLabel,
+		},
+	)
+	instanceTypeCacheKeys = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_type_cache_keys",
+			Help:      "Number of fully initialized instance type sets in the cache. Each distinct kubelet configuration and EC2NodeClass needs its own set.",
+		},
+	)
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(instanceTypeVCPU, instanceTypeMemory, instanceTypeOfferingAvailable, instanceTypeOfferingPriceEstimate)
+	crmetrics.Registry.MustRegister(instanceTypeVCPU, instanceTypeMemory, instanceTypeOfferingAvailable, instanceTypeOfferingPriceEstimate,
+		instanceTypeOfferingPriceMissingTotal, instanceTypeStaleServesTotal, instanceTypeDataStale, instanceTypeCacheKeys)
 }
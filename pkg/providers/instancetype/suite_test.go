@@ -21,18 +21,21 @@ import (
 	"net"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/outposts"
 	"github.com/imdario/mergo"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 	clock "k8s.io/utils/clock/testing"
@@ -53,6 +56,7 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
@@ -82,7 +86,7 @@ var _ = BeforeSuite(func() {
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider, awsEnv.CostLimitProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster)
 })
@@ -158,6 +162,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 	})
 
 	It("should support individual instance type labels", func() {
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
 		ExpectApplied(ctx, env.Client, nodePool, windowsNodePool, nodeClass, windowsNodeClass)
 
 		nodeSelector := map[string]string{
@@ -165,6 +170,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			corev1beta1.NodePoolLabelKey:     nodePool.Name,
 			v1.LabelTopologyRegion:           fake.DefaultRegion,
 			v1.LabelTopologyZone:             "test-zone-1a",
+			v1beta1.LabelTopologyZoneID:      "tstz1-1a",
 			v1.LabelInstanceTypeStable:       "g4dn.8xlarge",
 			v1.LabelOSStable:                 "linux",
 			v1.LabelArchStable:               "amd64",
@@ -178,16 +184,26 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceSize:                         "8xlarge",
 			v1beta1.LabelInstanceCPU:                          "32",
 			v1beta1.LabelInstanceCPUManufacturer:              "intel",
+			v1beta1.LabelInstanceCPUSustainedClockSpeedMhz:    "2500",
+			v1beta1.LabelInstanceSMTSupported:                 "true",
+			v1beta1.LabelInstanceBareMetal:                    "false",
 			v1beta1.LabelInstanceMemory:                       "131072",
 			v1beta1.LabelInstanceNetworkBandwidth:             "50000",
+			v1beta1.LabelInstanceNetworkCards:                 "1",
+			v1beta1.LabelInstanceNetworkCardsBandwidth:        "50000",
+			v1beta1.LabelInstanceEFANetworkCards:              "1",
 			v1beta1.LabelInstanceGPUName:                      "t4",
 			v1beta1.LabelInstanceGPUManufacturer:              "nvidia",
 			v1beta1.LabelInstanceGPUCount:                     "1",
 			v1beta1.LabelInstanceGPUMemory:                    "16384",
 			v1beta1.LabelInstanceLocalNVME:                    "900",
+			v1beta1.LabelInstanceLocalNVMECount:               "1",
+			v1beta1.LabelInstanceLocalNVMEDiskSize:            "900",
+			v1beta1.LabelInstanceStorePolicy:                  "RAID0",
 			v1beta1.LabelInstanceAcceleratorName:              "inferentia",
 			v1beta1.LabelInstanceAcceleratorManufacturer:      "aws",
 			v1beta1.LabelInstanceAcceleratorCount:             "1",
+			v1beta1.LabelInstanceAcceleratorMemory:            "32768",
 			// Deprecated Labels
 			v1.LabelFailureDomainBetaRegion: fake.DefaultRegion,
 			v1.LabelFailureDomainBetaZone:   "test-zone-1a",
@@ -211,6 +227,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 		}
 	})
 	It("should support combined instance type labels", func() {
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 
 		nodeSelector := map[string]string{
@@ -218,6 +235,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			corev1beta1.NodePoolLabelKey:     nodePool.Name,
 			v1.LabelTopologyRegion:           fake.DefaultRegion,
 			v1.LabelTopologyZone:             "test-zone-1a",
+			v1beta1.LabelTopologyZoneID:      "tstz1-1a",
 			v1.LabelInstanceTypeStable:       "g4dn.8xlarge",
 			v1.LabelOSStable:                 "linux",
 			v1.LabelArchStable:               "amd64",
@@ -231,13 +249,22 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceSize:                         "8xlarge",
 			v1beta1.LabelInstanceCPU:                          "32",
 			v1beta1.LabelInstanceCPUManufacturer:              "intel",
+			v1beta1.LabelInstanceCPUSustainedClockSpeedMhz:    "2500",
+			v1beta1.LabelInstanceSMTSupported:                 "true",
+			v1beta1.LabelInstanceBareMetal:                    "false",
 			v1beta1.LabelInstanceMemory:                       "131072",
 			v1beta1.LabelInstanceNetworkBandwidth:             "50000",
+			v1beta1.LabelInstanceNetworkCards:                 "1",
+			v1beta1.LabelInstanceNetworkCardsBandwidth:        "50000",
+			v1beta1.LabelInstanceEFANetworkCards:              "1",
 			v1beta1.LabelInstanceGPUName:                      "t4",
 			v1beta1.LabelInstanceGPUManufacturer:              "nvidia",
 			v1beta1.LabelInstanceGPUCount:                     "1",
 			v1beta1.LabelInstanceGPUMemory:                    "16384",
 			v1beta1.LabelInstanceLocalNVME:                    "900",
+			v1beta1.LabelInstanceLocalNVMECount:               "1",
+			v1beta1.LabelInstanceLocalNVMEDiskSize:            "900",
+			v1beta1.LabelInstanceStorePolicy:                  "RAID0",
 			// Deprecated Labels
 			v1.LabelFailureDomainBetaRegion: fake.DefaultRegion,
 			v1.LabelFailureDomainBetaZone:   "test-zone-1a",
@@ -254,6 +281,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					v1beta1.LabelInstanceAcceleratorCount,
 					v1beta1.LabelInstanceAcceleratorName,
 					v1beta1.LabelInstanceAcceleratorManufacturer,
+					v1beta1.LabelInstanceAcceleratorMemory,
 					v1.LabelWindowsBuild,
 				)).UnsortedList(), lo.Keys(corev1beta1.NormalizedLabels)...)))
 
@@ -269,6 +297,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			corev1beta1.NodePoolLabelKey:     nodePool.Name,
 			v1.LabelTopologyRegion:           fake.DefaultRegion,
 			v1.LabelTopologyZone:             "test-zone-1a",
+			v1beta1.LabelTopologyZoneID:      "tstz1-1a",
 			v1.LabelInstanceTypeStable:       "inf1.2xlarge",
 			v1.LabelOSStable:                 "linux",
 			v1.LabelArchStable:               "amd64",
@@ -282,8 +311,13 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceSize:                         "2xlarge",
 			v1beta1.LabelInstanceCPU:                          "8",
 			v1beta1.LabelInstanceCPUManufacturer:              "intel",
+			v1beta1.LabelInstanceCPUSustainedClockSpeedMhz:    "2500",
+			v1beta1.LabelInstanceSMTSupported:                 "true",
+			v1beta1.LabelInstanceBareMetal:                    "false",
 			v1beta1.LabelInstanceMemory:                       "16384",
 			v1beta1.LabelInstanceNetworkBandwidth:             "5000",
+			v1beta1.LabelInstanceNetworkCards:                 "1",
+			v1beta1.LabelInstanceNetworkCardsBandwidth:        "5000",
 			v1beta1.LabelInstanceAcceleratorName:              "inferentia",
 			v1beta1.LabelInstanceAcceleratorManufacturer:      "aws",
 			v1beta1.LabelInstanceAcceleratorCount:             "1",
@@ -303,6 +337,11 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceGPUManufacturer,
 			v1beta1.LabelInstanceGPUMemory,
 			v1beta1.LabelInstanceLocalNVME,
+			v1beta1.LabelInstanceLocalNVMECount,
+			v1beta1.LabelInstanceLocalNVMEDiskSize,
+			v1beta1.LabelInstanceStorePolicy,
+			v1beta1.LabelInstanceEFANetworkCards,
+			v1beta1.LabelInstanceAcceleratorMemory,
 			v1.LabelWindowsBuild,
 		)).UnsortedList(), lo.Keys(corev1beta1.NormalizedLabels)...)
 		Expect(lo.Keys(nodeSelector)).To(ContainElements(expectedLabels))
@@ -311,6 +350,183 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 		ExpectScheduled(ctx, env.Client, pod)
 	})
+	It("should add the zone IDs of the subnets that offerings are available in as requirements", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		byName := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, *corecloudprovider.InstanceType) { return it.Name, it })
+		Expect(byName["m5.large"].Requirements.Get(v1beta1.LabelTopologyZoneID).Values()).To(ConsistOf("tstz1-1a", "tstz1-1b", "tstz1-1c", "tstz1-1alocal"))
+	})
+	It("should return a NoSubnetZones error when the subnetSelectorTerms don't select any subnets", func() {
+		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "nothing"}}}
+		_, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(instancetype.IsNoSubnetZones(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(nodeClass.Name))
+	})
+	It("should list instance types without offerings for an EC2NodeClass without subnetSelectorTerms", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nil, &v1beta1.EC2NodeClass{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).ToNot(BeEmpty())
+		for _, it := range instanceTypes {
+			Expect(it.Offerings).To(BeEmpty())
+		}
+	})
+	It("should not add zone ID requirements when subnets don't report zone IDs", func() {
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(100)},
+		}})
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		for _, it := range instanceTypes {
+			Expect(it.Requirements.Get(v1beta1.LabelTopologyZoneID).Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+		}
+	})
+	It("should compute network card labels across all network cards", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		byName := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, *corecloudprovider.InstanceType) { return it.Name, it })
+
+		dl1 := byName["dl1.24xlarge"].Requirements
+		Expect(dl1.Get(v1beta1.LabelInstanceNetworkCards).Any()).To(Equal("4"))
+		Expect(dl1.Get(v1beta1.LabelInstanceNetworkCardsBandwidth).Any()).To(Equal("400000"))
+		Expect(dl1.Get(v1beta1.LabelInstanceEFANetworkCards).Any()).To(Equal("4"))
+
+		m6idn := byName["m6idn.32xlarge"].Requirements
+		Expect(m6idn.Get(v1beta1.LabelInstanceNetworkCards).Any()).To(Equal("2"))
+		Expect(m6idn.Get(v1beta1.LabelInstanceNetworkCardsBandwidth).Any()).To(Equal("200000"))
+		Expect(m6idn.Get(v1beta1.LabelInstanceEFANetworkCards).Any()).To(Equal("2"))
+
+		m5 := byName["m5.large"].Requirements
+		Expect(m5.Get(v1beta1.LabelInstanceEFANetworkCards).Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+	})
+	It("should compute CPU clock speed and SMT labels", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		byName := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, *corecloudprovider.InstanceType) { return it.Name, it })
+
+		m5 := byName["m5.large"].Requirements
+		Expect(m5.Get(v1beta1.LabelInstanceCPUSustainedClockSpeedMhz).Any()).To(Equal("3100"))
+		Expect(m5.Get(v1beta1.LabelInstanceSMTSupported).Any()).To(Equal("true"))
+
+		// Threads per core can't be configured on bare metal or Graviton instance types
+		Expect(byName["m5.metal"].Requirements.Get(v1beta1.LabelInstanceSMTSupported).Any()).To(Equal("false"))
+		Expect(byName["c6g.large"].Requirements.Get(v1beta1.LabelInstanceSMTSupported).Any()).To(Equal("false"))
+	})
+	It("should not label nodes with disabled labels", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			DisabledLabels: []string{v1beta1.LabelInstanceHypervisor, v1beta1.LabelInstanceSMTSupported},
+		}))
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		for _, it := range instanceTypes {
+			Expect(it.Requirements).To(HaveKey(v1beta1.LabelInstanceHypervisor))
+			Expect(it.Requirements).To(HaveKey(v1beta1.LabelInstanceSMTSupported))
+		}
+
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).ToNot(HaveKey(v1beta1.LabelInstanceHypervisor))
+		Expect(node.Labels).ToNot(HaveKey(v1beta1.LabelInstanceSMTSupported))
+		Expect(node.Labels).To(HaveKey(v1beta1.LabelInstanceCPU))
+	})
+	It("should only label nodes with the gated labels that are enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			EnabledLabels: []string{v1beta1.LabelInstanceSMTSupported},
+		}))
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		for _, it := range instanceTypes {
+			Expect(it.Requirements).To(HaveKey(v1beta1.LabelInstanceBareMetal))
+			Expect(it.Requirements).To(HaveKey(v1beta1.LabelInstanceStorePolicy))
+		}
+
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKey(v1beta1.LabelInstanceSMTSupported))
+		Expect(node.Labels).ToNot(HaveKey(v1beta1.LabelInstanceBareMetal))
+		Expect(node.Labels).ToNot(HaveKey(v1beta1.LabelTopologyZoneID))
+		Expect(node.Labels).ToNot(HaveKey(v1beta1.LabelInstanceStorePolicy))
+	})
+	It("should filter instance types by a NodePool requirement on a gated label that isn't enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{EnabledLabels: []string{}}))
+		nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: v1.NodeSelectorRequirement{
+				Key:      v1beta1.LabelInstanceBareMetal,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{"true"},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "m5.metal"))
+	})
+	It("should schedule pods that target instance types with a higher sustained clock speed", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []v1.NodeSelectorRequirement{{
+			Key:      v1beta1.LabelInstanceCPUSustainedClockSpeedMhz,
+			Operator: v1.NodeSelectorOpGt,
+			Values:   []string{"3400"},
+		}}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelInstanceCPUSustainedClockSpeedMhz, "3500"))
+	})
+	It("should schedule pods that select instance types by whether they support SMT", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1beta1.LabelInstanceSMTSupported: "false"}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelInstanceSMTSupported, "false"))
+	})
+	It("should compute instance generations that can be compared as integers", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		for _, it := range instanceTypes {
+			generation := it.Requirements.Get(v1beta1.LabelInstanceGeneration)
+			Expect(generation.Len()).To(Equal(1), it.Name)
+			_, err := strconv.Atoi(generation.Any())
+			Expect(err).ToNot(HaveOccurred(), it.Name)
+		}
+	})
+	It("should schedule pods that target newer instance generations", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []v1.NodeSelectorRequirement{{
+			Key:      v1beta1.LabelInstanceGeneration,
+			Operator: v1.NodeSelectorOpGt,
+			Values:   []string{"5"},
+		}}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(strconv.Atoi(node.Labels[v1beta1.LabelInstanceGeneration])).To(BeNumerically(">=", 6))
+	})
+	It("should schedule pods that target older instance generations", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []v1.NodeSelectorRequirement{{
+			Key:      v1beta1.LabelInstanceGeneration,
+			Operator: v1.NodeSelectorOpLt,
+			Values:   []string{"4"},
+		}}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(strconv.Atoi(node.Labels[v1beta1.LabelInstanceGeneration])).To(BeNumerically("<", 4))
+	})
+	It("should schedule pods that target multi-card EFA instance types", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []v1.NodeSelectorRequirement{{
+			Key:      v1beta1.LabelInstanceEFANetworkCards,
+			Operator: v1.NodeSelectorOpGt,
+			Values:   []string{"2"},
+		}}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "dl1.24xlarge"))
+	})
 	It("should not launch AWS Pod ENI on a t3", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 		pod := coretest.UnschedulablePod(coretest.PodOptions{
@@ -532,6 +748,50 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 		ExpectScheduled(ctx, env.Client, pod)
 	})
+	It("should compute the bare metal label", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		byName := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, *corecloudprovider.InstanceType) { return it.Name, it })
+		Expect(byName["m5.metal"].Requirements.Get(v1beta1.LabelInstanceBareMetal).Any()).To(Equal("true"))
+		Expect(byName["m5.large"].Requirements.Get(v1beta1.LabelInstanceBareMetal).Any()).To(Equal("false"))
+	})
+	It("should compute the local NVMe disk count and disk size labels", func() {
+		out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+		Expect(err).To(BeNil())
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: lo.Map(out.InstanceTypes, func(i *ec2.InstanceTypeInfo, _ int) *ec2.InstanceTypeInfo {
+			if aws.StringValue(i.InstanceType) != "m6idn.32xlarge" {
+				return i
+			}
+			copied := *i
+			copied.InstanceStorageInfo = &ec2.InstanceStorageInfo{
+				Disks:         []*ec2.DiskInfo{{Count: aws.Int64(4), SizeInGB: aws.Int64(1900), Type: aws.String(ec2.DiskTypeSsd)}},
+				NvmeSupport:   aws.String(ec2.EphemeralNvmeSupportRequired),
+				TotalSizeInGB: aws.Int64(7600),
+			}
+			return &copied
+		})})
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		byName := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, *corecloudprovider.InstanceType) { return it.Name, it })
+		Expect(byName["m6idn.32xlarge"].Requirements.Get(v1beta1.LabelInstanceLocalNVME).Any()).To(Equal("7600"))
+		Expect(byName["m6idn.32xlarge"].Requirements.Get(v1beta1.LabelInstanceLocalNVMECount).Any()).To(Equal("4"))
+		Expect(byName["m6idn.32xlarge"].Requirements.Get(v1beta1.LabelInstanceLocalNVMEDiskSize).Any()).To(Equal("1900"))
+		Expect(byName["m5.large"].Requirements.Get(v1beta1.LabelInstanceLocalNVMECount).Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+		Expect(byName["m5.large"].Requirements.Get(v1beta1.LabelInstanceLocalNVMEDiskSize).Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+	})
+	It("should launch on metal selected by the bare metal label", func() {
+		nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: v1.NodeSelectorRequirement{
+				Key:      v1.LabelInstanceTypeStable,
+				Operator: v1.NodeSelectorOpExists,
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1beta1.LabelInstanceBareMetal: "true"}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelInstanceBareMetal, "true"))
+	})
 	It("should launch AWS Pod ENI on a compatible instance type", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 		pod := coretest.UnschedulablePod(coretest.PodOptions{
@@ -674,6 +934,71 @@ var _ = Describe("InstanceTypeProvider", func() {
 		}
 		Expect(nodeNames.Len()).To(Equal(1))
 	})
+	Context("Accelerator Overlay", func() {
+		var info *ec2.InstanceTypeInfo
+		newInstanceType := func(info *ec2.InstanceTypeInfo) *corecloudprovider.InstanceType {
+			return instancetype.NewInstanceType(ctx,
+				info,
+				fake.DefaultRegion,
+				nodeClass.Spec.BlockDeviceMappings,
+				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
+				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
+				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
+				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
+				nodePool.Spec.Template.Spec.Kubelet.SystemReserved,
+				nodePool.Spec.Template.Spec.Kubelet.EvictionHard,
+				nodePool.Spec.Template.Spec.Kubelet.EvictionSoft,
+				amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{}),
+				nil,
+			)
+		}
+		BeforeEach(func() {
+			instanceInfo, err := awsEnv.InstanceTypesProvider.GetInstanceTypes(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			info, _ = lo.Find(instanceInfo, func(i *ec2.InstanceTypeInfo) bool { return aws.StringValue(i.InstanceType) == "trn1.2xlarge" })
+			Expect(info).ToNot(BeNil())
+			Expect(info.InferenceAcceleratorInfo).To(BeNil())
+		})
+		It("should fill in accelerator data that DescribeInstanceTypes doesn't report", func() {
+			it := newInstanceType(info)
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorName).Values()).To(ConsistOf("inferentia"))
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Values()).To(ConsistOf("aws"))
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Values()).To(ConsistOf("1"))
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorMemory).Values()).To(ConsistOf("32768"))
+			neurons := it.Capacity[v1beta1.ResourceAWSNeuron]
+			Expect(neurons.Value()).To(BeNumerically("==", 1))
+		})
+		It("should prefer accelerator data that DescribeInstanceTypes reports", func() {
+			info = lo.ToPtr(*info)
+			info.InferenceAcceleratorInfo = &ec2.InferenceAcceleratorInfo{
+				Accelerators: []*ec2.InferenceDeviceInfo{{
+					Name:         aws.String("Trainium"),
+					Manufacturer: aws.String("AWS"),
+					Count:        aws.Int64(2),
+				}},
+			}
+			it := newInstanceType(info)
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorName).Values()).To(ConsistOf("trainium"))
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Values()).To(ConsistOf("2"))
+			neurons := it.Capacity[v1beta1.ResourceAWSNeuron]
+			Expect(neurons.Value()).To(BeNumerically("==", 2))
+			// the overlay still fills in the memory, which the API didn't report
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorMemory).Values()).To(ConsistOf("32768"))
+		})
+		It("should not apply to instance types without an overlay entry", func() {
+			info = lo.ToPtr(*info)
+			info.InstanceType = aws.String("trn9.2xlarge")
+			it := newInstanceType(info)
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorName).Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+			Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorMemory).Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+			neurons := it.Capacity[v1beta1.ResourceAWSNeuron]
+			Expect(neurons.IsZero()).To(BeTrue())
+		})
+	})
 	It("should launch instances for vpc.amazonaws.com/efa resource requests", func() {
 		nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
 			{
@@ -708,6 +1033,188 @@ var _ = Describe("InstanceTypeProvider", func() {
 		}
 		Expect(nodes.Len()).To(Equal(1))
 	})
+	It("should launch instances for karpenter.k8s.aws/network-bandwidth resource requests when enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NetworkBandwidthResource: lo.ToPtr(true)}))
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1beta1.ResourceNetworkBandwidth: resource.MustParse("50000")},
+				Limits:   v1.ResourceList{v1beta1.ResourceNetworkBandwidth: resource.MustParse("50000")},
+			},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		bandwidth := node.Status.Capacity[v1beta1.ResourceNetworkBandwidth]
+		Expect(bandwidth.Value()).To(BeNumerically(">=", 50000))
+		Expect(bandwidth.Value()).To(BeNumerically("==", instancetype.InstanceTypeBandwidthMegabits[node.Labels[v1.LabelInstanceTypeStable]]))
+	})
+	It("should not launch instances for karpenter.k8s.aws/network-bandwidth resource requests by default", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1beta1.ResourceNetworkBandwidth: resource.MustParse("1")},
+				Limits:   v1.ResourceList{v1beta1.ResourceNetworkBandwidth: resource.MustParse("1")},
+			},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectNotScheduled(ctx, env.Client, pod)
+	})
+	It("should report network bandwidth capacity in megabits when enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NetworkBandwidthResource: lo.ToPtr(true)}))
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		Expect(m5Large.Capacity).To(HaveKeyWithValue(v1beta1.ResourceNetworkBandwidth, resource.MustParse("750")))
+
+		ctx = options.ToContext(ctx, test.Options())
+		instanceTypes, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		for _, it := range instanceTypes {
+			Expect(it.Capacity).ToNot(HaveKey(v1beta1.ResourceNetworkBandwidth))
+		}
+	})
+	It("should use the EC2NodeClass's max-pods overrides for matching instance types", func() {
+		nodeClass.Spec.MaxPodsOverrides = map[string]int32{"t3.*": 17, "m5.*": 20, "m5.large": 29}
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		pods := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, int64) {
+			return it.Name, it.Capacity.Pods().Value()
+		})
+		Expect(pods).To(HaveKeyWithValue("t3.large", int64(17)))
+		Expect(pods).To(HaveKeyWithValue("m5.large", int64(29)))
+		Expect(pods).To(HaveKeyWithValue("m5.xlarge", int64(20)))
+	})
+	It("should fall back to the kubelet's maxPods for instance types that don't match a max-pods override", func() {
+		nodeClass.Spec.MaxPodsOverrides = map[string]int32{"t3.*": 17}
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, &corev1beta1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](50)}, nodeClass)
+		Expect(err).To(BeNil())
+		pods := lo.SliceToMap(instanceTypes, func(it *corecloudprovider.InstanceType) (string, int64) {
+			return it.Name, it.Capacity.Pods().Value()
+		})
+		Expect(pods).To(HaveKeyWithValue("t3.large", int64(17)))
+		Expect(pods).To(HaveKeyWithValue("m5.large", int64(50)))
+	})
+	It("should not share cached capacities between EC2NodeClasses with different max-pods overrides", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		t3Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+		Expect(ok).To(BeTrue())
+		Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 35))
+
+		nodeClass.Spec.MaxPodsOverrides = map[string]int32{"t3.*": 17}
+		instanceTypes, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		t3Large, ok = lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+		Expect(ok).To(BeTrue())
+		Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 17))
+	})
+	It("should use the EC2NodeClass's VM memory overhead over the operator's", func() {
+		nodeClass.Spec.VMMemoryOverheadPercent = lo.ToPtr("0.1")
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		Expect(m5Large.Capacity.Memory().String()).To(Equal("7372Mi"))
+	})
+	It("should not share cached capacities between EC2NodeClasses with different VM memory overheads", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		Expect(m5Large.Capacity.Memory().String()).To(Equal("7577Mi"))
+
+		noOverheadNodeClass := test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{VMMemoryOverheadPercent: lo.ToPtr("0")}})
+		noOverheadNodeClass.Status = nodeClass.Status
+		instanceTypes, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, noOverheadNodeClass)
+		Expect(err).To(BeNil())
+		m5Large, ok = lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		Expect(m5Large.Capacity.Memory().String()).To(Equal("8Gi"))
+	})
+	It("should not serve cached capacities after the instance store policy changes", func() {
+		ephemeralStorage := func() string {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m6idn.32xlarge" })
+			Expect(ok).To(BeTrue())
+			return it.Capacity.StorageEphemeral().String()
+		}
+		rootVolume := ephemeralStorage()
+		Expect(rootVolume).ToNot(Equal("7600G"))
+
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+		Expect(ephemeralStorage()).To(Equal("7600G"))
+
+		nodeClass.Spec.InstanceStorePolicy = nil
+		Expect(ephemeralStorage()).To(Equal(rootVolume))
+	})
+	Context("Graviton Reserved Memory", func() {
+		memory := func(name string) string {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == name })
+			Expect(ok).To(BeTrue())
+			return it.Capacity.Memory().String()
+		}
+		BeforeEach(func() {
+			nodeClass.Spec.VMMemoryOverheadPercent = lo.ToPtr("0")
+		})
+		It("should subtract the cma reserved memory from Graviton instance types", func() {
+			Expect(memory("c6g.large")).To(Equal("4032Mi"))
+			Expect(memory("m5.large")).To(Equal("8Gi"))
+		})
+		It("should subtract the configured cma reserved memory from Graviton instance types", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{GravitonCMAReservedMemoryMiB: lo.ToPtr(256)}))
+			Expect(memory("c6g.large")).To(Equal("3840Mi"))
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{GravitonCMAReservedMemoryMiB: lo.ToPtr(0)}))
+			Expect(memory("c6g.large")).To(Equal("4Gi"))
+		})
+		It("should not subtract the cma reserved memory from arm64 instance types that aren't Gravitons", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			infos := lo.Map(out.InstanceTypes, func(i *ec2.InstanceTypeInfo, _ int) *ec2.InstanceTypeInfo {
+				if aws.StringValue(i.InstanceType) != "c6g.large" {
+					return i
+				}
+				copied := *i
+				processorInfo := *i.ProcessorInfo
+				processorInfo.Manufacturer = aws.String("Ampere")
+				copied.ProcessorInfo = &processorInfo
+				return &copied
+			})
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: infos})
+			Expect(memory("c6g.large")).To(Equal("4Gi"))
+		})
+	})
+	It("should use the EC2NodeClass's reserved ENIs over the operator's", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			ReservedENIs: lo.ToPtr(1),
+		}))
+		nodeClass.Spec.ReservedENIs = lo.ToPtr[int32](0)
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		t3Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+		Expect(ok).To(BeTrue())
+		// (3 - 0) * (12 - 1) + 2 = 35
+		Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 35))
+	})
+	It("should not share cached capacities between EC2NodeClasses with different reserved ENIs", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		t3Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+		Expect(ok).To(BeTrue())
+		Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 35))
+
+		podENINodeClass := test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{ReservedENIs: lo.ToPtr[int32](1)}})
+		podENINodeClass.Status = nodeClass.Status
+		instanceTypes, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, podENINodeClass)
+		Expect(err).To(BeNil())
+		t3Large, ok = lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+		Expect(ok).To(BeTrue())
+		// (3 - 1) * (12 - 1) + 2 = 24
+		Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 24))
+	})
 	It("should not launch instances w/ instance storage for ephemeral storage resource requests when exceeding blockDeviceMapping", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 		pod := coretest.UnschedulablePod(coretest.PodOptions{
@@ -731,6 +1238,219 @@ var _ = Describe("InstanceTypeProvider", func() {
 		Expect(node.Labels[v1.LabelInstanceTypeStable]).To(Equal("m6idn.32xlarge"))
 		Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("7600G")))
 	})
+	Context("Instance Store", func() {
+		// withDisks returns a copy of the instance type's info with the instance store disks replaced
+		withDisks := func(name string, disks ...*ec2.DiskInfo) *ec2.InstanceTypeInfo {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			info, ok := lo.Find(out.InstanceTypes, func(i *ec2.InstanceTypeInfo) bool { return aws.StringValue(i.InstanceType) == name })
+			Expect(ok).To(BeTrue())
+			copied := *info
+			copied.InstanceStorageInfo = &ec2.InstanceStorageInfo{
+				Disks:         disks,
+				TotalSizeInGB: aws.Int64(lo.SumBy(disks, func(d *ec2.DiskInfo) int64 { return aws.Int64Value(d.Count) * aws.Int64Value(d.SizeInGB) })),
+			}
+			return &copied
+		}
+		ephemeralStorage := func(info *ec2.InstanceTypeInfo) string {
+			it := instancetype.NewInstanceType(ctx,
+				info,
+				fake.DefaultRegion,
+				nodeClass.Spec.BlockDeviceMappings,
+				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+				amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{}),
+				nil,
+			)
+			return it.Capacity.StorageEphemeral().String()
+		}
+		BeforeEach(func() {
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+		})
+		It("should report the total size of the disks when they're striped", func() {
+			info := withDisks("m6idn.32xlarge", &ec2.DiskInfo{Count: aws.Int64(4), SizeInGB: aws.Int64(1900)})
+			Expect(ephemeralStorage(info)).To(Equal("7600G"))
+			nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID0)}
+			Expect(ephemeralStorage(info)).To(Equal("7600G"))
+		})
+		It("should report half the total size of the disks when they're striped and mirrored", func() {
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID10)
+			info := withDisks("m6idn.32xlarge", &ec2.DiskInfo{Count: aws.Int64(4), SizeInGB: aws.Int64(1900)})
+			Expect(ephemeralStorage(info)).To(Equal("3800G"))
+		})
+		It("should report the size of the smallest disk when they're mirrored", func() {
+			nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}
+			info := withDisks("m6idn.32xlarge",
+				&ec2.DiskInfo{Count: aws.Int64(2), SizeInGB: aws.Int64(1900)},
+				&ec2.DiskInfo{Count: aws.Int64(2), SizeInGB: aws.Int64(900)},
+			)
+			Expect(ephemeralStorage(info)).To(Equal("900G"))
+		})
+		DescribeTable("should subtract the filesystem overhead when reporting usable capacity",
+			func(filesystem string, expected string) {
+				nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{
+					Filesystem:           lo.ToPtr(filesystem),
+					ReportUsableCapacity: lo.ToPtr(true),
+				}
+				Expect(ephemeralStorage(withDisks("m6idn.32xlarge", &ec2.DiskInfo{Count: aws.Int64(4), SizeInGB: aws.Int64(1900)}))).To(Equal(expected))
+			},
+			Entry("xfs", v1beta1.InstanceStoreFilesystemXFS, "7524G"),
+			Entry("ext4", v1beta1.InstanceStoreFilesystemExt4, "7448G"),
+		)
+		Context("Ephemeral Storage Overhead", func() {
+			// i4i.large has a single 468 GB NVMe instance store disk, and is otherwise built from m5.large
+			i4iLarge := func() *ec2.InstanceTypeInfo {
+				info := withDisks("m5.large", &ec2.DiskInfo{Count: aws.Int64(1), SizeInGB: aws.Int64(468), Type: aws.String(ec2.DiskTypeSsd)})
+				info.InstanceType = aws.String("i4i.large")
+				info.MemoryInfo = &ec2.MemoryInfo{SizeInMiB: aws.Int64(16 * 1024)}
+				info.InstanceStorageInfo.NvmeSupport = aws.String(ec2.EphemeralNvmeSupportRequired)
+				return info
+			}
+			newInstanceType := func(info *ec2.InstanceTypeInfo, kubeReserved, evictionHard map[string]string) *corecloudprovider.InstanceType {
+				return instancetype.NewInstanceType(ctx,
+					info,
+					fake.DefaultRegion,
+					nodeClass.Spec.BlockDeviceMappings,
+					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
+					nil,
+					nil,
+					kubeReserved,
+					nil,
+					evictionHard,
+					nil,
+					amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{}),
+					nil,
+				)
+			}
+			ephemeralStorageModel := func(info *ec2.InstanceTypeInfo) string {
+				return instancetype.EphemeralStorageModel(info, nodeClass.Spec.InstanceStorePolicy, amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{}))
+			}
+			// The AL2 root volume is 20Gi, and the instance store array is 468G
+			ebsThreshold := func(percent int64) int64 { return 20 * 1024 * 1024 * 1024 * percent / 100 }
+			instanceStoreThreshold := func(percent int64) int64 { return 468 * 1000 * 1000 * 1000 * percent / 100 }
+
+			It("should compute the overhead of an i4i.large against its EBS volume without an instance store policy", func() {
+				nodeClass.Spec.InstanceStorePolicy = nil
+				it := newInstanceType(i4iLarge(), nil, nil)
+				Expect(ephemeralStorageModel(i4iLarge())).To(Equal(instancetype.EphemeralStorageModelEBS))
+				Expect(it.Capacity.StorageEphemeral().Value()).To(Equal(int64(20 * 1024 * 1024 * 1024)))
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(ebsThreshold(10)))
+			})
+			It("should compute the overhead of an i4i.large against its instance store with the RAID0 instance store policy", func() {
+				it := newInstanceType(i4iLarge(), nil, nil)
+				Expect(ephemeralStorageModel(i4iLarge())).To(Equal(instancetype.EphemeralStorageModelInstanceStore))
+				Expect(it.Capacity.StorageEphemeral().Value()).To(Equal(int64(468 * 1000 * 1000 * 1000)))
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(5 * 1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(instanceStoreThreshold(5)))
+			})
+			It("should compute the overhead against the instance store when the local NVMe labels are disabled", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					DisabledLabels: []string{v1beta1.LabelInstanceLocalNVME, v1beta1.LabelInstanceLocalNVMECount, v1beta1.LabelInstanceLocalNVMEDiskSize},
+				}))
+				it := newInstanceType(i4iLarge(), nil, nil)
+				Expect(ephemeralStorageModel(i4iLarge())).To(Equal(instancetype.EphemeralStorageModelInstanceStore))
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(5 * 1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(instanceStoreThreshold(5)))
+			})
+			It("should use the configured instance store defaults", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					InstanceStoreKubeReservedStorage: lo.ToPtr("20Gi"),
+					InstanceStoreEvictionPercent:     lo.ToPtr(2.0),
+				}))
+				it := newInstanceType(i4iLarge(), nil, nil)
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(20 * 1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(instanceStoreThreshold(2)))
+
+				// The defaults of instance types with ephemeral storage on EBS aren't configurable
+				nodeClass.Spec.InstanceStorePolicy = nil
+				it = newInstanceType(i4iLarge(), nil, nil)
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(ebsThreshold(10)))
+			})
+			It("should prefer the kubelet configuration over the instance store defaults", func() {
+				it := newInstanceType(i4iLarge(), map[string]string{string(v1.ResourceEphemeralStorage): "2Gi"}, map[string]string{instancetype.NodeFSAvailable: "15%"})
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(2 * 1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(instanceStoreThreshold(15)))
+			})
+			It("should compute the overhead against EBS for instance types without instance store", func() {
+				info := i4iLarge()
+				info.InstanceStorageInfo = nil
+				it := newInstanceType(info, nil, nil)
+				Expect(ephemeralStorageModel(info)).To(Equal(instancetype.EphemeralStorageModelEBS))
+				Expect(it.Overhead.KubeReserved.StorageEphemeral().Value()).To(Equal(int64(1024 * 1024 * 1024)))
+				Expect(it.Overhead.EvictionThreshold.StorageEphemeral().Value()).To(Equal(ebsThreshold(10)))
+			})
+		})
+		It("should not return instance types with a single instance store disk when they're mirrored", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			infos := lo.Map(out.InstanceTypes, func(i *ec2.InstanceTypeInfo, _ int) *ec2.InstanceTypeInfo {
+				switch aws.StringValue(i.InstanceType) {
+				case "m6idn.32xlarge":
+					return withDisks("m6idn.32xlarge", &ec2.DiskInfo{Count: aws.Int64(4), SizeInGB: aws.Int64(1900)})
+				case "g4dn.8xlarge":
+					return withDisks("g4dn.8xlarge", &ec2.DiskInfo{Count: aws.Int64(1), SizeInGB: aws.Int64(900)})
+				}
+				return i
+			})
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: infos})
+
+			nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).To(ContainElements("m6idn.32xlarge", "m5.large"))
+			Expect(names).ToNot(ContainElement("g4dn.8xlarge"))
+		})
+		It("should not return instance types with a single instance store disk with the RAID10 instance store policy", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			infos := lo.Map(out.InstanceTypes, func(i *ec2.InstanceTypeInfo, _ int) *ec2.InstanceTypeInfo {
+				if aws.StringValue(i.InstanceType) == "g4dn.8xlarge" {
+					return withDisks("g4dn.8xlarge", &ec2.DiskInfo{Count: aws.Int64(1), SizeInGB: aws.Int64(900)})
+				}
+				return i
+			})
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: infos})
+
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID10)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).To(ContainElements("m6idn.32xlarge", "m5.large"))
+			Expect(names).ToNot(ContainElement("g4dn.8xlarge"))
+		})
+		It("should label nodes with the instance store policy", func() {
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID10)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1beta1.LabelInstanceStorePolicy: "RAID10"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelInstanceStorePolicy, "RAID10"))
+		})
+		It("should not label nodes with the instance store policy when it isn't applied by the AMI family", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1beta1.LabelInstanceStorePolicy: "RAID0"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+	})
 	It("should not set pods to 110 if using ENI-based pod density", func() {
 		instanceInfo, err := awsEnv.InstanceTypesProvider.GetInstanceTypes(ctx)
 		Expect(err).To(BeNil())
@@ -741,6 +1461,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 				fake.DefaultRegion,
 				nodeClass.Spec.BlockDeviceMappings,
 				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -764,6 +1488,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 				fake.DefaultRegion,
 				windowsNodeClass.Spec.BlockDeviceMappings,
 				windowsNodeClass.Spec.InstanceStorePolicy,
+				windowsNodeClass.Spec.InstanceStore,
+				windowsNodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -841,6 +1569,29 @@ var _ = Describe("InstanceTypeProvider", func() {
 				}
 			}
 		})
+		It("should count offerings without a price by capacity type", func() {
+			// Only m5.large has a spot price, and only in test-zone-1a
+			awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []*ec2.SpotPrice{{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("m5.large"),
+					SpotPrice:        aws.String("0.05"),
+					Timestamp:        aws.Time(time.Now().Add(-time.Hour)),
+				}},
+			})
+			Expect(awsEnv.PricingProvider.UpdateSpotPricing(ctx)).To(Succeed())
+			spotBefore := priceMissingCount(corev1beta1.CapacityTypeSpot)
+
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			unpriced := lo.SumBy(instanceTypes, func(it *corecloudprovider.InstanceType) int {
+				return lo.CountBy(it.Offerings, func(of corecloudprovider.Offering) bool {
+					return of.CapacityType == corev1beta1.CapacityTypeSpot && !(it.Name == "m5.large" && of.Zone == "test-zone-1a")
+				})
+			})
+			Expect(unpriced).ToNot(BeZero())
+			Expect(priceMissingCount(corev1beta1.CapacityTypeSpot) - spotBefore).To(BeNumerically("==", unpriced))
+		})
 	})
 	It("should launch instances in local zones", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -855,6 +1606,191 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectScheduled(ctx, env.Client, pod)
 
 	})
+	Context("Tenancy", func() {
+		It("should only return instance types that support dedicated hosts with host tenancy", func() {
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyHost)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).ToNot(ContainElements("t4g.small", "t4g.medium", "t4g.xlarge", "dl1.24xlarge"))
+			Expect(names).To(ContainElements("m5.large", "m5.xlarge"))
+		})
+		It("should not filter instance types by dedicated host support with dedicated tenancy", func() {
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyDedicated)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).To(ContainElements("t4g.small", "dl1.24xlarge", "m5.large"))
+		})
+		It("should return all instance types with default tenancy", func() {
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyDefault)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).To(ContainElements("t4g.small", "dl1.24xlarge", "m5.large"))
+		})
+		DescribeTable("should not return spot offerings without default tenancy",
+			func(tenancy string) {
+				nodeClass.Spec.Tenancy = aws.String(tenancy)
+				instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instanceTypes).ToNot(BeEmpty())
+				for _, it := range instanceTypes {
+					for _, of := range it.Offerings {
+						Expect(of.CapacityType).To(Equal(corev1beta1.CapacityTypeOnDemand))
+					}
+				}
+			},
+			Entry("dedicated", ec2.TenancyDedicated),
+			Entry("host", ec2.TenancyHost),
+		)
+		It("should launch on-demand instances when the NodePool allows spot with host tenancy", func() {
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyHost)
+			nodePool.Spec.Template.Spec.Requirements[0].Values = []string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(corev1beta1.CapacityTypeLabelKey, corev1beta1.CapacityTypeOnDemand))
+		})
+	})
+
+	Context("Outposts", func() {
+		outpostARN := "arn:aws:outposts:us-west-2:123456789012:outpost/op-0123456789abcdef0"
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-region"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(100)},
+				{SubnetId: aws.String("subnet-outpost"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(100), OutpostArn: aws.String(outpostARN)},
+			}})
+			awsEnv.OutpostsAPI.GetOutpostInstanceTypesBehavior.Output.Set(&outposts.GetOutpostInstanceTypesOutput{
+				InstanceTypes: []*outposts.InstanceTypeItem{{InstanceType: aws.String("m5.large")}, {InstanceType: aws.String("m5.xlarge")}},
+			})
+			nodeClass.Spec.OutpostARN = aws.String(outpostARN)
+		})
+		It("should only return the instance types on the outpost, with on-demand offerings in the outpost's zone", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.large", "m5.xlarge"))
+			for _, it := range instanceTypes {
+				for _, of := range it.Offerings {
+					Expect(of.CapacityType).To(Equal(corev1beta1.CapacityTypeOnDemand))
+					Expect(of.Available).To(Equal(of.Zone == "test-zone-1a"))
+				}
+			}
+			Expect(awsEnv.OutpostsAPI.GetOutpostInstanceTypesBehavior.CalledWithInput.Pop().OutpostId).To(Equal(aws.String(outpostARN)))
+		})
+		It("should launch on-demand instances onto the outpost when the NodePool allows spot", func() {
+			nodePool.Spec.Template.Spec.Requirements[0].Values = []string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(corev1beta1.CapacityTypeLabelKey, corev1beta1.CapacityTypeOnDemand))
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-1a"))
+			call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(call.SpotOptions).To(BeNil())
+			for _, ltc := range call.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(aws.StringValue(override.SubnetId)).To(Equal("subnet-outpost"))
+				}
+			}
+		})
+	})
+
+	Context("Wavelength Zones", func() {
+		available := func(it *corecloudprovider.InstanceType, capacityType, zone string) bool {
+			GinkgoHelper()
+			offering, ok := it.Offerings.Get(capacityType, zone)
+			Expect(ok).To(BeTrue())
+			return offering.Available
+		}
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-wavelength"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(100)},
+				{SubnetId: aws.String("subnet-region"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(100)},
+			}})
+			awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
+				{ZoneName: aws.String("test-zone-1a"), ZoneId: aws.String("testzone1a"), ZoneType: aws.String("wavelength-zone"), NetworkBorderGroup: aws.String("test-zone-1-wl1")},
+				{ZoneName: aws.String("test-zone-1b"), ZoneId: aws.String("testzone1b"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+			}})
+		})
+		It("should only offer on-demand capacity in a Wavelength Zone", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			_, ok = it.Offerings.Get(corev1beta1.CapacityTypeSpot, "test-zone-1a")
+			Expect(ok).To(BeFalse())
+			Expect(available(it, corev1beta1.CapacityTypeOnDemand, "test-zone-1a")).To(BeTrue())
+			Expect(available(it, corev1beta1.CapacityTypeSpot, "test-zone-1b")).To(BeTrue())
+		})
+		It("should only offer the instance types that are offered in the Wavelength Zone", func() {
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: []*ec2.InstanceTypeOffering{
+				{InstanceType: aws.String("m5.large"), Location: aws.String("test-zone-1a")},
+				{InstanceType: aws.String("m5.large"), Location: aws.String("test-zone-1b")},
+				{InstanceType: aws.String("m5.xlarge"), Location: aws.String("test-zone-1b")},
+			}})
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(available(it, corev1beta1.CapacityTypeOnDemand, "test-zone-1a")).To(BeFalse())
+			Expect(available(it, corev1beta1.CapacityTypeOnDemand, "test-zone-1b")).To(BeTrue())
+		})
+	})
+
+	Context("Operator Instance Type Lists", func() {
+		listNames := func() []string {
+			GinkgoHelper()
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			return lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+		}
+		It("should exclude instance types matching the denylist", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeDenylist: []string{"m5.*", "*.metal"}}))
+			names := listNames()
+			Expect(names).ToNot(BeEmpty())
+			for _, name := range names {
+				Expect(name).ToNot(HavePrefix("m5."))
+				Expect(name).ToNot(HaveSuffix(".metal"))
+			}
+		})
+		It("should only include instance types matching the allowlist", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeAllowlist: []string{"m5.*", "c6g.large"}}))
+			names := listNames()
+			Expect(names).To(ContainElements("m5.large", "m5.xlarge", "c6g.large"))
+			for _, name := range names {
+				Expect(name == "c6g.large" || strings.HasPrefix(name, "m5.")).To(BeTrue(), name)
+			}
+		})
+		It("should deny instance types that match both the allowlist and the denylist", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				InstanceTypeAllowlist: []string{"m5.*"},
+				InstanceTypeDenylist:  []string{"m5.xlarge"},
+			}))
+			names := listNames()
+			Expect(names).To(ContainElement("m5.large"))
+			Expect(names).ToNot(ContainElement("m5.xlarge"))
+		})
+		It("should not serve cached results after the lists change", func() {
+			Expect(listNames()).To(ContainElement("m5.large"))
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeDenylist: []string{"m5.large"}}))
+			Expect(listNames()).ToNot(ContainElement("m5.large"))
+			ctx = options.ToContext(ctx, test.Options())
+			Expect(listNames()).To(ContainElement("m5.large"))
+		})
+		It("should not launch denied instance types regardless of NodePool requirements", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeDenylist: []string{"m5.large"}}))
+			nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large"}},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+	})
 
 	Context("Overhead", func() {
 		var info *ec2.InstanceTypeInfo
@@ -879,6 +1815,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -906,6 +1846,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -929,6 +1873,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -961,6 +1909,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1000,6 +1952,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1029,6 +1985,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1058,6 +2018,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1087,6 +2051,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1118,6 +2086,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1150,6 +2122,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1179,6 +2155,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1212,6 +2192,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1232,6 +2216,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1266,6 +2254,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1298,6 +2290,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1330,6 +2326,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1353,6 +2353,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1371,6 +2375,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1397,6 +2405,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1422,6 +2434,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1451,6 +2467,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 				fake.DefaultRegion,
 				nodeClass.Spec.BlockDeviceMappings,
 				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1485,6 +2505,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 				fake.DefaultRegion,
 				nodeClass.Spec.BlockDeviceMappings,
 				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1503,6 +2527,98 @@ var _ = Describe("InstanceTypeProvider", func() {
 			maxPods := 0
 			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", maxPods))
 		})
+		It("should use prefixes per ENI in the max-pods calculation when ENI prefix delegation is enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				ENIPrefixDelegation: lo.ToPtr(true),
+			}))
+
+			instanceInfo, err := awsEnv.InstanceTypesProvider.GetInstanceTypes(ctx)
+			Expect(err).To(BeNil())
+			t3Large, ok := lo.Find(instanceInfo, func(info *ec2.InstanceTypeInfo) bool {
+				return *info.InstanceType == "t3.large"
+			})
+			Expect(ok).To(Equal(true))
+			amiFamily := amifamily.GetAMIFamily(lo.ToPtr(v1beta1.AMIFamilyAL2), &amifamily.Options{})
+			it := instancetype.NewInstanceType(ctx,
+				t3Large,
+				fake.DefaultRegion,
+				nodeClass.Spec.BlockDeviceMappings,
+				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
+				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
+				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
+				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
+				nodePool.Spec.Template.Spec.Kubelet.SystemReserved,
+				nodePool.Spec.Template.Spec.Kubelet.EvictionHard,
+				nodePool.Spec.Template.Spec.Kubelet.EvictionSoft,
+				amiFamily,
+				nil,
+			)
+			// t3.large
+			// maxInterfaces = 3
+			// maxIPv4PerInterface = 12
+			// 3 * (12 * 16 - 1) + 2 = 575
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 575))
+			// kube-reserved memory is computed from the ENI-limited pod count: 11 * 575 + 255 = 6580Mi
+			Expect(it.Overhead.KubeReserved.Memory().String()).To(Equal("6580Mi"))
+		})
+		It("should use prefixes per ENI in the max-pods calculation when IPv6 prefixes are assigned", func() {
+			nodeClass.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](1)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			t3Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			// 3 * (12 * 16 - 1) + 2 = 575
+			Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 575))
+			Expect(t3Large.Overhead.KubeReserved.Memory().String()).To(Equal("6580Mi"))
+		})
+		It("should not change the max-pods calculation when IPv6 addresses are assigned", func() {
+			nodeClass.Spec.IPv6 = &v1beta1.IPv6{AddressCount: lo.ToPtr[int64](4)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			t3Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			// 3 * (12 - 1) + 2 = 35
+			Expect(t3Large.Capacity.Pods().Value()).To(BeNumerically("==", 35))
+		})
+		It("should reserve ENIs in the max-pods calculation when ENI prefix delegation is enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				ReservedENIs:        lo.ToPtr(1),
+				ENIPrefixDelegation: lo.ToPtr(true),
+			}))
+
+			instanceInfo, err := awsEnv.InstanceTypesProvider.GetInstanceTypes(ctx)
+			Expect(err).To(BeNil())
+			t3Large, ok := lo.Find(instanceInfo, func(info *ec2.InstanceTypeInfo) bool {
+				return *info.InstanceType == "t3.large"
+			})
+			Expect(ok).To(Equal(true))
+			amiFamily := amifamily.GetAMIFamily(lo.ToPtr(v1beta1.AMIFamilyAL2), &amifamily.Options{})
+			it := instancetype.NewInstanceType(ctx,
+				t3Large,
+				fake.DefaultRegion,
+				nodeClass.Spec.BlockDeviceMappings,
+				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
+				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
+				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
+				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
+				nodePool.Spec.Template.Spec.Kubelet.SystemReserved,
+				nodePool.Spec.Template.Spec.Kubelet.EvictionHard,
+				nodePool.Spec.Template.Spec.Kubelet.EvictionSoft,
+				amiFamily,
+				nil,
+			)
+			// (3 - 1) * (12 * 16 - 1) + 2 = 384
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 384))
+			Expect(it.Overhead.KubeReserved.Memory().String()).To(Equal("4479Mi"))
+		})
 		It("should override pods-per-core value", func() {
 			instanceInfo, err := awsEnv.InstanceTypesProvider.GetInstanceTypes(ctx)
 			Expect(err).To(BeNil())
@@ -1516,6 +2632,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1542,6 +2662,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1568,6 +2692,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 					fake.DefaultRegion,
 					nodeClass.Spec.BlockDeviceMappings,
 					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.InstanceStore,
+					nodeClass.Spec.IPv6,
+					options.FromContext(ctx).ReservedENIs,
+					options.FromContext(ctx).VMMemoryOverheadPercent,
 					nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 					nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 					nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1577,7 +2705,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					amiFamily,
 					nil,
 				)
-				limitedPods := instancetype.ENILimitedPods(ctx, info)
+				limitedPods := instancetype.ENILimitedPods(ctx, info, nil, options.FromContext(ctx).ReservedENIs)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", limitedPods.Value()))
 			}
 		})
@@ -1595,6 +2723,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1613,6 +2745,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 						fake.DefaultRegion,
 						nodeClass.Spec.BlockDeviceMappings,
 						nodeClass.Spec.InstanceStorePolicy,
+						nodeClass.Spec.InstanceStore,
+						nodeClass.Spec.IPv6,
+						options.FromContext(ctx).ReservedENIs,
+						options.FromContext(ctx).VMMemoryOverheadPercent,
 						nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 						nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 						nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -2000,6 +3136,103 @@ var _ = Describe("InstanceTypeProvider", func() {
 			node := ExpectScheduled(ctx, env.Client, pod)
 			Expect(node.Labels).To(HaveKeyWithValue(corev1beta1.NodePoolLabelKey, nodePool.Name))
 		})
+		Context("Capacity Blocks", func() {
+			BeforeEach(func() {
+				nodeClass.Status.CapacityReservations = []v1beta1.CapacityReservation{
+					{
+						ID:               "cr-test2",
+						InstanceType:     "m5.large",
+						AvailabilityZone: "test-zone-1b",
+						ReservationType:  ec2.CapacityReservationTypeCapacityBlock,
+					},
+				}
+			})
+			It("should only offer capacity blocks for the reserved instance type and zone", func() {
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				for _, it := range instanceTypes {
+					offerings := lo.Filter(it.Offerings, func(o corecloudprovider.Offering, _ int) bool {
+						return o.CapacityType == v1beta1.CapacityTypeCapacityBlock
+					})
+					if it.Name != "m5.large" {
+						Expect(offerings).To(BeEmpty())
+						continue
+					}
+					Expect(offerings).To(HaveLen(1))
+					Expect(offerings[0].Zone).To(Equal("test-zone-1b"))
+					Expect(offerings[0].Price).To(BeNumerically("==", 0))
+				}
+			})
+			It("should not offer a capacity block whose reservation is exhausted", func() {
+				awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "ReservationCapacityExceeded", "m5.large", "test-zone-1b", v1beta1.CapacityTypeCapacityBlock, awscache.ReservationScope("cr-test2"))
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+				Expect(ok).To(BeTrue())
+				offering, ok := lo.Find(m5Large.Offerings, func(o corecloudprovider.Offering) bool { return o.CapacityType == v1beta1.CapacityTypeCapacityBlock })
+				Expect(ok).To(BeTrue())
+				Expect(offering.Available).To(BeFalse())
+			})
+			It("should offer a capacity block when the offering has insufficient capacity outside of the reservation", func() {
+				awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1b", v1beta1.CapacityTypeCapacityBlock)
+				awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1b", corev1beta1.CapacityTypeOnDemand)
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+				Expect(ok).To(BeTrue())
+				offering, ok := lo.Find(m5Large.Offerings, func(o corecloudprovider.Offering) bool { return o.CapacityType == v1beta1.CapacityTypeCapacityBlock })
+				Expect(ok).To(BeTrue())
+				Expect(offering.Available).To(BeTrue())
+			})
+			It("should not offer capacity blocks when the nodeclass has none", func() {
+				nodeClass.Status.CapacityReservations = nil
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				for _, it := range instanceTypes {
+					Expect(lo.ContainsBy(it.Offerings, func(o corecloudprovider.Offering) bool {
+						return o.CapacityType == v1beta1.CapacityTypeCapacityBlock
+					})).To(BeFalse())
+				}
+			})
+			It("should launch into the capacity block when the nodepool requires it", func() {
+				nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+					{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{v1beta1.CapacityTypeCapacityBlock}}},
+				}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(node.Labels).To(HaveKeyWithValue(corev1beta1.CapacityTypeLabelKey, v1beta1.CapacityTypeCapacityBlock))
+				Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "m5.large"))
+				Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-1b"))
+
+				Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+				call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+				Expect(aws.StringValue(call.TargetCapacitySpecification.DefaultTargetCapacityType)).To(Equal(v1beta1.CapacityTypeCapacityBlock))
+				Expect(call.OnDemandOptions).To(BeNil())
+				Expect(call.SpotOptions).To(BeNil())
+
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(1))
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(aws.StringValue(ltInput.LaunchTemplateData.InstanceMarketOptions.MarketType)).To(Equal(ec2.MarketTypeCapacityBlock))
+					Expect(aws.StringValue(ltInput.LaunchTemplateData.CapacityReservationSpecification.CapacityReservationTarget.CapacityReservationId)).To(Equal("cr-test2"))
+				})
+			})
+			It("should not launch into the capacity block when the nodepool doesn't allow it", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(node.Labels).To(HaveKeyWithValue(corev1beta1.CapacityTypeLabelKey, corev1beta1.CapacityTypeOnDemand))
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(ltInput.LaunchTemplateData.CapacityReservationSpecification).To(BeNil())
+				})
+			})
+		})
 	})
 	Context("Ephemeral Storage", func() {
 		BeforeEach(func() {
@@ -2094,6 +3327,72 @@ var _ = Describe("InstanceTypeProvider", func() {
 				Expect(*ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.SnapshotId).To(Equal("snap-xxxxxxxx"))
 			})
 		})
+		Context("Root Snapshot Size", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Spec.BlockDeviceMappings = []*v1beta1.BlockDeviceMapping{
+					{
+						DeviceName: aws.String("/dev/xvda"),
+						EBS:        &v1beta1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("50Gi"))},
+					},
+				}
+				awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+					Images: []*ec2.Image{
+						{
+							Name:           aws.String("custom-ami"),
+							ImageId:        aws.String("ami-custom"),
+							CreationDate:   aws.String("2022-08-15T12:00:00Z"),
+							Architecture:   aws.String("x86_64"),
+							RootDeviceName: aws.String("/dev/xvda"),
+							BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+								{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsBlockDevice{VolumeSize: aws.Int64(100), SnapshotId: aws.String("snap-custom")}},
+							},
+						},
+					},
+				})
+				nodeClass.Status.AMIs = []v1beta1.AMI{
+					{
+						ID:               "ami-custom",
+						Requirements:     []corev1beta1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}}}},
+						RootDeviceName:   "/dev/xvda",
+						RootSnapshotSize: lo.ToPtr(resource.MustParse("100Gi")),
+					},
+				}
+			})
+			It("should fail to launch when the root volume is smaller than the AMI's root snapshot", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+			})
+			It("should raise the root volume to the AMI's root snapshot size and report it as ephemeral-storage", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RaiseUndersizedRootVolumes: lo.ToPtr(true)}))
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("100Gi")))
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(ltInput.LaunchTemplateData.BlockDeviceMappings).To(HaveLen(1))
+					Expect(*ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.VolumeSize).To(BeNumerically("==", 100))
+				})
+				// The EC2NodeClass itself is left unchanged
+				Expect(nodeClass.Spec.BlockDeviceMappings[0].EBS.VolumeSize.String()).To(Equal("50Gi"))
+			})
+			It("should schedule pods that only fit on the raised root volume", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RaiseUndersizedRootVolumes: lo.ToPtr(true)}))
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceEphemeralStorage: resource.MustParse("60Gi")},
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+			})
+		})
 	})
 	Context("Metadata Options", func() {
 		It("should default metadata options on generated launch template", func() {
@@ -2129,6 +3428,84 @@ var _ = Describe("InstanceTypeProvider", func() {
 			})
 		})
 	})
+	Context("Stale Instance Type Data", func() {
+		staleServes := func(api string) float64 {
+			GinkgoHelper()
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_instance_type_stale_serves_total", map[string]string{"api": api})
+			if !ok {
+				return 0
+			}
+			return metric.GetCounter().GetValue()
+		}
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+		})
+		It("should serve the last known instance types when DescribeInstanceTypes fails", func() {
+			expected, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			before := staleServes("DescribeInstanceTypes")
+
+			awsEnv.InstanceTypeCache.Flush()
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(
+				ConsistOf(lo.Map(expected, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })))
+			Expect(staleServes("DescribeInstanceTypes")).To(Equal(before + 1))
+
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_instance_type_data_stale", map[string]string{"api": "DescribeInstanceTypes"})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 1))
+		})
+		It("should serve the last known offerings when DescribeInstanceTypeOfferings fails", func() {
+			_, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			before := staleServes("DescribeInstanceTypeOfferings")
+
+			awsEnv.InstanceTypeCache.Flush()
+			// Refresh the instance types so that the next error is returned by DescribeInstanceTypeOfferings
+			_, err = awsEnv.InstanceTypesProvider.GetInstanceTypes(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			Expect(staleServes("DescribeInstanceTypeOfferings")).To(Equal(before + 1))
+		})
+		It("should recover once DescribeInstanceTypes succeeds again", func() {
+			_, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.InstanceTypeCache.Flush()
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			_, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+
+			// The stale data isn't cached, so the next call refreshes from EC2
+			_, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_instance_type_data_stale", map[string]string{"api": "DescribeInstanceTypes"})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 0))
+		})
+		It("should return the error when the last known instance types are older than the max staleness", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				InstanceTypeMaxStaleness: lo.ToPtr(time.Nanosecond),
+			}))
+			_, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(time.Millisecond)
+
+			awsEnv.InstanceTypeCache.Flush()
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			_, err = awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should return the error when instance types have never been retrieved", func() {
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			_, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	Context("Provider Cache", func() {
 		// Keeping the Cache testing in one IT block to validate the combinatorial expansion of instance types generated by different configs
 		It("changes to kubelet configuration fields should result in a different set of instances types", func() {
@@ -2256,6 +3633,48 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Based on the nodeclass configuration, we expect to have 5 unique set of instance types
 			uniqueInstanceTypeList(instanceTypeResult)
 		})
+		It("changes to kubelet configuration fields that don't affect instance types should share a cached set of instance types", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			base := &corev1beta1.KubeletConfiguration{MaxPods: aws.Int32(10), KubeReserved: map[string]string{string(v1.ResourceCPU): "1"}}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, base, nodeClass)
+			Expect(err).To(BeNil())
+			itemCount := awsEnv.InstanceTypeCache.ItemCount()
+
+			for _, change := range []*corev1beta1.KubeletConfiguration{
+				{ClusterDNS: []string{"10.0.0.10"}},
+				{EvictionSoftGracePeriod: map[string]metav1.Duration{"nodefs.available": {Duration: time.Minute}}},
+				{EvictionMaxPodGracePeriod: aws.Int32(30)},
+				{ImageGCHighThresholdPercent: aws.Int32(90), ImageGCLowThresholdPercent: aws.Int32(80)},
+				{CPUCFSQuota: aws.Bool(false)},
+			} {
+				kc := base.DeepCopy()
+				Expect(mergo.Merge(kc, change, mergo.WithOverride)).To(BeNil())
+				cached, err := awsEnv.InstanceTypesProvider.List(ctx, kc, nodeClass)
+				Expect(err).To(BeNil())
+				Expect(cached[0]).To(BeIdenticalTo(instanceTypes[0]))
+			}
+			Expect(awsEnv.InstanceTypeCache.ItemCount()).To(Equal(itemCount))
+		})
+		It("should evict the least recently used set of instance types once the max is exceeded", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeCacheMaxKeys: lo.ToPtr(2)}))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			list := func(maxPods int32) []*corecloudprovider.InstanceType {
+				GinkgoHelper()
+				instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, &corev1beta1.KubeletConfiguration{MaxPods: aws.Int32(maxPods)}, nodeClass)
+				Expect(err).To(BeNil())
+				return instanceTypes
+			}
+			first, second := list(10), list(20)
+			// Using the first set makes the second the least recently used
+			Expect(list(10)[0]).To(BeIdenticalTo(first[0]))
+			list(30)
+
+			Expect(list(10)[0]).To(BeIdenticalTo(first[0]))
+			Expect(list(20)[0]).ToNot(BeIdenticalTo(second[0]))
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_instance_type_cache_keys", map[string]string{})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 2))
+		})
 	})
 })
 
@@ -2303,3 +3722,11 @@ func generateSpotPricing(cp *cloudprovider.CloudProvider, nodePool *corev1beta1.
 	}
 	return rsp
 }
+
+func priceMissingCount(capacityType string) float64 {
+	metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_instance_type_offering_price_missing_total", map[string]string{"capacity_type": capacityType})
+	if !ok {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 )
@@ -45,34 +46,138 @@ const (
 	NodeFSAvailable = "nodefs.available"
 )
 
+// Models of where the ephemeral storage of an instance type is, which its ephemeral-storage overhead is computed against
+const (
+	EphemeralStorageModelEBS           = "ebs"
+	EphemeralStorageModelInstanceStore = "instance-store"
+)
+
+// Estimated percentages of an instance store array that are taken up by filesystem metadata, and aren't available to pods
+const (
+	xfsOverheadPercent  int64 = 1
+	ext4OverheadPercent int64 = 2
+)
+
 var (
+	// instanceTypeScheme captures the category, the memory of high memory instance types such as u-6tb1, and the
+	// generation, which is only digits so that it can be compared with Gt and Lt. mac2-m2pro is category mac, generation 2.
 	instanceTypeScheme = regexp.MustCompile(`(^[a-z]+)(\-[0-9]+tb)?([0-9]+).*\.`)
 )
 
 func NewInstanceType(ctx context.Context, info *ec2.InstanceTypeInfo, region string,
-	blockDeviceMappings []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy, maxPods *int32, podsPerCore *int32,
-	kubeReserved map[string]string, systemReserved map[string]string, evictionHard map[string]string, evictionSoft map[string]string,
+	blockDeviceMappings []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy, instanceStore *v1beta1.InstanceStore, ipv6 *v1beta1.IPv6,
+	reservedENIs int, vmMemoryOverheadPercent float64, maxPods *int32, podsPerCore *int32, kubeReserved map[string]string, systemReserved map[string]string, evictionHard map[string]string, evictionSoft map[string]string,
 	amiFamily amifamily.AMIFamily, offerings cloudprovider.Offerings) *cloudprovider.InstanceType {
 
 	it := &cloudprovider.InstanceType{
 		Name:         aws.StringValue(info.InstanceType),
-		Requirements: computeRequirements(info, offerings, region, amiFamily),
+		Requirements: computeRequirements(ctx, info, offerings, region, amiFamily),
 		Offerings:    offerings,
-		Capacity:     computeCapacity(ctx, info, amiFamily, blockDeviceMappings, instanceStorePolicy, maxPods, podsPerCore),
-		Overhead: &cloudprovider.InstanceTypeOverhead{
-			KubeReserved:      kubeReservedResources(cpu(info), pods(ctx, info, amiFamily, maxPods, podsPerCore), ENILimitedPods(ctx, info), amiFamily, kubeReserved),
-			SystemReserved:    systemReservedResources(systemReserved),
-			EvictionThreshold: evictionThreshold(memory(ctx, info), ephemeralStorage(info, amiFamily, blockDeviceMappings, instanceStorePolicy), amiFamily, evictionHard, evictionSoft),
-		},
+		Capacity:     computeCapacity(ctx, info, amiFamily, blockDeviceMappings, instanceStorePolicy, instanceStore, ipv6, reservedENIs, vmMemoryOverheadPercent, maxPods, podsPerCore),
 	}
+	it.Requirements.Add(instanceStorePolicyRequirement(instanceStorePolicy, amiFamily))
+	reservedEphemeralStorage, ephemeralStorageThresholdPercent := ephemeralStorageOverhead(ctx, EphemeralStorageModel(info, instanceStorePolicy, amiFamily))
+	it.Overhead = &cloudprovider.InstanceTypeOverhead{
+		KubeReserved:      kubeReservedResources(cpu(info), pods(ctx, info, amiFamily, ipv6, reservedENIs, maxPods, podsPerCore), ENILimitedPods(ctx, info, ipv6, reservedENIs), reservedEphemeralStorage, amiFamily, kubeReserved),
+		SystemReserved:    systemReservedResources(systemReserved),
+		EvictionThreshold: evictionThreshold(memory(ctx, info, vmMemoryOverheadPercent), ephemeralStorage(info, amiFamily, blockDeviceMappings, instanceStorePolicy, instanceStore), ephemeralStorageThresholdPercent, amiFamily, evictionHard, evictionSoft),
+	}
+	applyAcceleratorOverlay(it)
 	if it.Requirements.Compatible(scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelOSStable, v1.NodeSelectorOpIn, string(v1.Windows)))) == nil {
 		it.Capacity[v1beta1.ResourcePrivateIPv4Address] = *privateIPv4Address(info)
 	}
 	return it
 }
 
+// AcceleratorInfo is accelerator data that DescribeInstanceTypes doesn't report for an instance type. Names and
+// manufacturers are spelled the way DescribeInstanceTypes would report them.
+type AcceleratorInfo struct {
+	Name         string
+	Manufacturer string
+	// Count is the number of accelerators, which are advertised as aws.amazon.com/neuron
+	Count int64
+	// MemoryMiB is the memory of each accelerator
+	MemoryMiB int64
+}
+
+// applyAcceleratorOverlay fills in the accelerator data from AcceleratorOverlay that wasn't derived from
+// DescribeInstanceTypes. Data derived from DescribeInstanceTypes is never overridden, so an overlay entry stops having
+// an effect once the API starts reporting it.
+func applyAcceleratorOverlay(it *cloudprovider.InstanceType) {
+	overlay, ok := AcceleratorOverlay[it.Name]
+	if !ok {
+		return
+	}
+	for key, value := range map[string]string{
+		v1beta1.LabelInstanceAcceleratorName:         lowerKabobCase(overlay.Name),
+		v1beta1.LabelInstanceAcceleratorManufacturer: lowerKabobCase(overlay.Manufacturer),
+		v1beta1.LabelInstanceAcceleratorCount:        lo.Ternary(overlay.Count > 0, fmt.Sprint(overlay.Count), ""),
+		v1beta1.LabelInstanceAcceleratorMemory:       lo.Ternary(overlay.MemoryMiB > 0, fmt.Sprint(overlay.MemoryMiB), ""),
+	} {
+		if requirement := it.Requirements.Get(key); value != "" && requirement.Operator() == v1.NodeSelectorOpDoesNotExist {
+			requirement.Insert(value)
+		}
+	}
+	if neurons := it.Capacity[v1beta1.ResourceAWSNeuron]; neurons.IsZero() && overlay.Count > 0 {
+		it.Capacity[v1beta1.ResourceAWSNeuron] = *resources.Quantity(fmt.Sprint(overlay.Count))
+	}
+}
+
+// EphemeralStorageModel returns where the ephemeral storage of an instance type is, which is the instance store array
+// when its instance store policy is applied by the AMI family and it has NVMe instance store disks, and EBS otherwise.
+// It's derived from the instance type info rather than its labels, since any of those can be disabled.
+func EphemeralStorageModel(info *ec2.InstanceTypeInfo, instanceStorePolicy *v1beta1.InstanceStorePolicy, amiFamily amifamily.AMIFamily) string {
+	if instanceStorePolicyApplied(instanceStorePolicy, amiFamily) && info.InstanceStorageInfo != nil &&
+		aws.StringValue(info.InstanceStorageInfo.NvmeSupport) != ec2.EphemeralNvmeSupportUnsupported {
+		return EphemeralStorageModelInstanceStore
+	}
+	return EphemeralStorageModelEBS
+}
+
+// ephemeralStorageOverhead returns the default kube-reserved ephemeral-storage and nodefs.available eviction threshold
+// percent of an ephemeral storage model. Instance store arrays are usually much larger than EBS root volumes, so they
+// reserve a larger floor for the kubelet and evict at a smaller percent of their size.
+func ephemeralStorageOverhead(ctx context.Context, model string) (*resource.Quantity, float64) {
+	if model == EphemeralStorageModelInstanceStore {
+		return resources.Quantity(options.FromContext(ctx).InstanceStoreKubeReservedStorage), options.FromContext(ctx).InstanceStoreEvictionPercent
+	}
+	return resources.Quantity("1Gi"), 10
+}
+
+// zoneIDRequirement returns the IDs of the zones that the offerings are available in. Zone IDs come from the subnets
+// that the offerings are launched into, so zones whose subnets don't report an ID are left out.
+func zoneIDRequirement(offerings cloudprovider.Offerings, zoneIDs map[string]string) *scheduling.Requirement {
+	ids := lo.Uniq(lo.FilterMap(offerings.Available(), func(o cloudprovider.Offering, _ int) (string, bool) {
+		id, ok := zoneIDs[o.Zone]
+		return id, ok
+	}))
+	if len(ids) == 0 {
+		return scheduling.NewRequirement(v1beta1.LabelTopologyZoneID, v1.NodeSelectorOpDoesNotExist)
+	}
+	return scheduling.NewRequirement(v1beta1.LabelTopologyZoneID, v1.NodeSelectorOpIn, ids...)
+}
+
+// instanceStorePolicyRequirement returns the instance store policy that the instance store disks are assembled with.
+// The policy is only applied by the AL2 and AL2023 bootstrap scripts.
+func instanceStorePolicyRequirement(instanceStorePolicy *v1beta1.InstanceStorePolicy, amiFamily amifamily.AMIFamily) *scheduling.Requirement {
+	if instanceStorePolicyApplied(instanceStorePolicy, amiFamily) {
+		return scheduling.NewRequirement(v1beta1.LabelInstanceStorePolicy, v1.NodeSelectorOpIn, string(*instanceStorePolicy))
+	}
+	return scheduling.NewRequirement(v1beta1.LabelInstanceStorePolicy, v1.NodeSelectorOpDoesNotExist)
+}
+
+// instanceStorePolicyApplied returns whether the AMI family's bootstrap script assembles the instance store disks with
+// the instance store policy
+func instanceStorePolicyApplied(instanceStorePolicy *v1beta1.InstanceStorePolicy, amiFamily amifamily.AMIFamily) bool {
+	switch amiFamily.(type) {
+	case *amifamily.AL2, *amifamily.AL2023:
+		return instanceStorePolicy != nil
+	}
+	return false
+}
+
 //nolint:gocyclo
-func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Offerings, region string, amiFamily amifamily.AMIFamily) scheduling.Requirements {
+func computeRequirements(ctx context.Context, info *ec2.InstanceTypeInfo, offerings cloudprovider.Offerings, region string, amiFamily amifamily.AMIFamily) scheduling.Requirements {
 	requirements := scheduling.NewRequirements(
 		// Well Known Upstream
 		scheduling.NewRequirement(v1.LabelInstanceTypeStable, v1.NodeSelectorOpIn, aws.StringValue(info.InstanceType)),
@@ -86,13 +191,21 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 		// Well Known to AWS
 		scheduling.NewRequirement(v1beta1.LabelInstanceCPU, v1.NodeSelectorOpIn, fmt.Sprint(aws.Int64Value(info.VCpuInfo.DefaultVCpus))),
 		scheduling.NewRequirement(v1beta1.LabelInstanceCPUManufacturer, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceCPUSustainedClockSpeedMhz, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceSMTSupported, v1.NodeSelectorOpIn, fmt.Sprint(smtSupported(info))),
 		scheduling.NewRequirement(v1beta1.LabelInstanceMemory, v1.NodeSelectorOpIn, fmt.Sprint(aws.Int64Value(info.MemoryInfo.SizeInMiB))),
 		scheduling.NewRequirement(v1beta1.LabelInstanceNetworkBandwidth, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceNetworkCards, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceNetworkCardsBandwidth, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceEFANetworkCards, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceCategory, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceFamily, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceGeneration, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceLocalNVME, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceLocalNVMECount, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceLocalNVMEDiskSize, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceSize, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceBareMetal, v1.NodeSelectorOpIn, fmt.Sprint(bareMetal(info))),
 		scheduling.NewRequirement(v1beta1.LabelInstanceGPUName, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceGPUManufacturer, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpDoesNotExist),
@@ -100,6 +213,7 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 		scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorName, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorManufacturer, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorMemory, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceHypervisor, v1.NodeSelectorOpIn, aws.StringValue(info.Hypervisor)),
 		scheduling.NewRequirement(v1beta1.LabelInstanceEncryptionInTransitSupported, v1.NodeSelectorOpIn, fmt.Sprint(aws.BoolValue(info.NetworkInfo.EncryptionInTransitSupported))),
 	)
@@ -116,11 +230,31 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 	}
 	if info.InstanceStorageInfo != nil && aws.StringValue(info.InstanceStorageInfo.NvmeSupport) != ec2.EphemeralNvmeSupportUnsupported {
 		requirements[v1beta1.LabelInstanceLocalNVME].Insert(fmt.Sprint(aws.Int64Value(info.InstanceStorageInfo.TotalSizeInGB)))
+		// Instance types with instance store have disks of a single size
+		if len(info.InstanceStorageInfo.Disks) > 0 {
+			requirements[v1beta1.LabelInstanceLocalNVMECount].Insert(fmt.Sprint(instanceStoreDiskCount(info)))
+			requirements[v1beta1.LabelInstanceLocalNVMEDiskSize].Insert(fmt.Sprint(aws.Int64Value(info.InstanceStorageInfo.Disks[0].SizeInGB)))
+		}
 	}
 	// Network bandwidth
 	if bandwidth, ok := InstanceTypeBandwidthMegabits[aws.StringValue(info.InstanceType)]; ok {
 		requirements[v1beta1.LabelInstanceNetworkBandwidth].Insert(fmt.Sprint(bandwidth))
 	}
+	// Network cards
+	if info.NetworkInfo != nil && len(info.NetworkInfo.NetworkCards) > 0 {
+		cards := info.NetworkInfo.NetworkCards
+		requirements[v1beta1.LabelInstanceNetworkCards].Insert(fmt.Sprint(len(cards)))
+		// Aggregate baseline bandwidth in megabits, matching the units of the default card's bandwidth label
+		if lo.EveryBy(cards, func(c *ec2.NetworkCardInfo) bool { return c.BaselineBandwidthInGbps != nil }) {
+			requirements[v1beta1.LabelInstanceNetworkCardsBandwidth].Insert(fmt.Sprint(int64(math.Round(lo.SumBy(cards, func(c *ec2.NetworkCardInfo) float64 {
+				return aws.Float64Value(c.BaselineBandwidthInGbps)
+			}) * 1000))))
+		}
+		// EFA interfaces are limited to one per network card
+		if info.NetworkInfo.EfaInfo != nil {
+			requirements[v1beta1.LabelInstanceEFANetworkCards].Insert(fmt.Sprint(lo.Min([]int64{aws.Int64Value(info.NetworkInfo.EfaInfo.MaximumEfaInterfaces), int64(len(cards))})))
+		}
+	}
 	// GPU Labels
 	if info.GpuInfo != nil && len(info.GpuInfo.Gpus) == 1 {
 		gpu := info.GpuInfo.Gpus[0]
@@ -135,26 +269,47 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 		requirements.Get(v1beta1.LabelInstanceAcceleratorName).Insert(lowerKabobCase(aws.StringValue(accelerator.Name)))
 		requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Insert(lowerKabobCase(aws.StringValue(accelerator.Manufacturer)))
 		requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Insert(fmt.Sprint(aws.Int64Value(accelerator.Count)))
+		if accelerator.MemoryInfo != nil {
+			requirements.Get(v1beta1.LabelInstanceAcceleratorMemory).Insert(fmt.Sprint(aws.Int64Value(accelerator.MemoryInfo.SizeInMiB)))
+		}
 	}
 	// Windows Build Version Labels
 	if family, ok := amiFamily.(*amifamily.Windows); ok {
 		requirements.Get(v1.LabelWindowsBuild).Insert(family.Build)
 	}
-	// Trn1 Accelerators
-	// TODO: remove function once DescribeInstanceTypes contains the accelerator data
-	// Values found from: https://aws.amazon.com/ec2/instance-types/trn1/
-	if strings.HasPrefix(*info.InstanceType, "trn1") {
-		requirements.Get(v1beta1.LabelInstanceAcceleratorName).Insert(lowerKabobCase("Inferentia"))
-		requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Insert(lowerKabobCase("AWS"))
-		requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Insert(fmt.Sprint(awsNeurons(info)))
-	}
 	// CPU Manufacturer, valid options: aws, intel, amd
 	if info.ProcessorInfo != nil {
 		requirements.Get(v1beta1.LabelInstanceCPUManufacturer).Insert(lowerKabobCase(aws.StringValue(info.ProcessorInfo.Manufacturer)))
+		// Sustained clock speed in megahertz, so that it can be compared with Gt and Lt
+		if info.ProcessorInfo.SustainedClockSpeedInGhz != nil {
+			requirements.Get(v1beta1.LabelInstanceCPUSustainedClockSpeedMhz).Insert(fmt.Sprint(int64(math.Round(aws.Float64Value(info.ProcessorInfo.SustainedClockSpeedInGhz) * 1000))))
+		}
 	}
 	return requirements
 }
 
+// LabelEmitted returns whether the NodeClaims launched from instance types are labeled with the label. Instance types
+// always carry the requirement, so that NodePools that select on a gated or disabled label still filter by it.
+func LabelEmitted(ctx context.Context, key string) bool {
+	return v1beta1.InstanceTypeLabelEmitted(key, operator.Version, options.FromContext(ctx).EnabledLabels, options.FromContext(ctx).DisabledLabels)
+}
+
+// EmittedLabels returns the registered labels that NodeClaims are labeled with
+func EmittedLabels(ctx context.Context) []string {
+	return v1beta1.EmittedInstanceTypeLabels(operator.Version, options.FromContext(ctx).EnabledLabels, options.FromContext(ctx).DisabledLabels)
+}
+
+// bareMetal returns whether EC2 reports the instance type as bare metal
+func bareMetal(info *ec2.InstanceTypeInfo) bool {
+	return aws.BoolValue(info.BareMetal)
+}
+
+// smtSupported returns whether the threads per core of the instance type can be configured, which allows SMT to be
+// disabled by launching it with one thread per core
+func smtSupported(info *ec2.InstanceTypeInfo) bool {
+	return info.VCpuInfo != nil && len(info.VCpuInfo.ValidThreadsPerCore) > 1
+}
+
 func getOS(info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily) []string {
 	if _, ok := amiFamily.(*amifamily.Windows); ok {
 		if getArchitecture(info) == corev1beta1.ArchitectureAmd64 {
@@ -175,14 +330,14 @@ func getArchitecture(info *ec2.InstanceTypeInfo) string {
 }
 
 func computeCapacity(ctx context.Context, info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily,
-	blockDeviceMapping []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy,
-	maxPods *int32, podsPerCore *int32) v1.ResourceList {
+	blockDeviceMapping []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy, instanceStore *v1beta1.InstanceStore, ipv6 *v1beta1.IPv6,
+	reservedENIs int, vmMemoryOverheadPercent float64, maxPods *int32, podsPerCore *int32) v1.ResourceList {
 
 	resourceList := v1.ResourceList{
 		v1.ResourceCPU:              *cpu(info),
-		v1.ResourceMemory:           *memory(ctx, info),
-		v1.ResourceEphemeralStorage: *ephemeralStorage(info, amiFamily, blockDeviceMapping, instanceStorePolicy),
-		v1.ResourcePods:             *pods(ctx, info, amiFamily, maxPods, podsPerCore),
+		v1.ResourceMemory:           *memory(ctx, info, vmMemoryOverheadPercent),
+		v1.ResourceEphemeralStorage: *ephemeralStorage(info, amiFamily, blockDeviceMapping, instanceStorePolicy, instanceStore),
+		v1.ResourcePods:             *pods(ctx, info, amiFamily, ipv6, reservedENIs, maxPods, podsPerCore),
 		v1beta1.ResourceAWSPodENI:   *awsPodENI(aws.StringValue(info.InstanceType)),
 		v1beta1.ResourceNVIDIAGPU:   *nvidiaGPUs(info),
 		v1beta1.ResourceAMDGPU:      *amdGPUs(info),
@@ -190,6 +345,9 @@ func computeCapacity(ctx context.Context, info *ec2.InstanceTypeInfo, amiFamily
 		v1beta1.ResourceHabanaGaudi: *habanaGaudis(info),
 		v1beta1.ResourceEFA:         *efas(info),
 	}
+	if options.FromContext(ctx).NetworkBandwidthResource {
+		resourceList[v1beta1.ResourceNetworkBandwidth] = *networkBandwidth(info)
+	}
 	return resourceList
 }
 
@@ -197,24 +355,56 @@ func cpu(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(*info.VCpuInfo.DefaultVCpus))
 }
 
-func memory(ctx context.Context, info *ec2.InstanceTypeInfo) *resource.Quantity {
+func memory(ctx context.Context, info *ec2.InstanceTypeInfo, vmMemoryOverheadPercent float64) *resource.Quantity {
 	sizeInMib := *info.MemoryInfo.SizeInMiB
-	// Gravitons have an extra 64 MiB of cma reserved memory that we can't use
-	if len(info.ProcessorInfo.SupportedArchitectures) > 0 && *info.ProcessorInfo.SupportedArchitectures[0] == "arm64" {
-		sizeInMib -= 64
+	// Gravitons have extra cma reserved memory that we can't use. Other arm64 processors don't reserve it.
+	if isGraviton(info) {
+		sizeInMib -= int64(options.FromContext(ctx).GravitonCMAReservedMemoryMiB)
 	}
 	mem := resources.Quantity(fmt.Sprintf("%dMi", sizeInMib))
 	// Account for VM overhead in calculation
-	mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*options.FromContext(ctx).VMMemoryOverheadPercent/1024/1024)))))
+	mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*vmMemoryOverheadPercent/1024/1024)))))
 	return mem
 }
 
+// isGraviton returns whether the instance type has an arm64 processor designed by AWS
+func isGraviton(info *ec2.InstanceTypeInfo) bool {
+	return lo.ContainsBy(info.ProcessorInfo.SupportedArchitectures, func(a *string) bool { return aws.StringValue(a) == ec2.ArchitectureTypeArm64 }) &&
+		strings.EqualFold(aws.StringValue(info.ProcessorInfo.Manufacturer), "aws")
+}
+
+// instanceStoreSize returns the size of the array that the instance store disks are assembled into. Striped disks add up
+// to the total size of the disks, while mirrored disks are only as large as the smallest of them.
+func instanceStoreSize(info *ec2.InstanceTypeInfo, instanceStore *v1beta1.InstanceStore) (*resource.Quantity, bool) {
+	if info.InstanceStorageInfo == nil || info.InstanceStorageInfo.TotalSizeInGB == nil {
+		return nil, false
+	}
+	sizeInGB := aws.Int64Value(info.InstanceStorageInfo.TotalSizeInGB)
+	if instanceStore == nil {
+		return resources.Quantity(fmt.Sprintf("%dG", sizeInGB)), true
+	}
+	if lo.FromPtr(instanceStore.RAIDLevel) == v1beta1.RAIDLevelRAID1 && len(info.InstanceStorageInfo.Disks) > 0 {
+		sizeInGB = lo.Min(lo.Map(info.InstanceStorageInfo.Disks, func(d *ec2.DiskInfo, _ int) int64 { return aws.Int64Value(d.SizeInGB) }))
+	}
+	if lo.FromPtr(instanceStore.ReportUsableCapacity) {
+		overhead := lo.Ternary(lo.FromPtr(instanceStore.Filesystem) == v1beta1.InstanceStoreFilesystemExt4, ext4OverheadPercent, xfsOverheadPercent)
+		return resources.Quantity(fmt.Sprintf("%dM", sizeInGB*1000*(100-overhead)/100)), true
+	}
+	return resources.Quantity(fmt.Sprintf("%dG", sizeInGB)), true
+}
+
 // Setting ephemeral-storage to be either the default value, what is defined in blockDeviceMappings, or the combined size of local store volumes.
-func ephemeralStorage(info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily, blockDeviceMappings []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy) *resource.Quantity {
-	// If local store disks have been configured for node ephemeral-storage, use the total size of the disks.
-	if lo.FromPtr(instanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 {
+func ephemeralStorage(info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily, blockDeviceMappings []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy, instanceStore *v1beta1.InstanceStore) *resource.Quantity {
+	// If local store disks have been configured for node ephemeral-storage, use the size of the array they're assembled into.
+	switch lo.FromPtr(instanceStorePolicy) {
+	case v1beta1.InstanceStorePolicyRAID0:
+		if size, ok := instanceStoreSize(info, instanceStore); ok {
+			return size
+		}
+	case v1beta1.InstanceStorePolicyRAID10:
+		// Every block is mirrored onto two disks, so half of the total size is usable
 		if info.InstanceStorageInfo != nil && info.InstanceStorageInfo.TotalSizeInGB != nil {
-			return resources.Quantity(fmt.Sprintf("%dG", *info.InstanceStorageInfo.TotalSizeInGB))
+			return resources.Quantity(fmt.Sprintf("%dG", aws.Int64Value(info.InstanceStorageInfo.TotalSizeInGB)/2))
 		}
 	}
 	if len(blockDeviceMappings) != 0 {
@@ -280,17 +470,9 @@ func amdGPUs(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(count))
 }
 
-// TODO: remove trn1 hardcode values once DescribeInstanceTypes contains the accelerator data
-// Values found from: https://aws.amazon.com/ec2/instance-types/trn1/
 func awsNeurons(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	count := int64(0)
-	if *info.InstanceType == "trn1.2xlarge" {
-		count = int64(1)
-	} else if *info.InstanceType == "trn1.32xlarge" {
-		count = int64(16)
-	} else if *info.InstanceType == "trn1n.32xlarge" {
-		count = int64(16)
-	} else if info.InferenceAcceleratorInfo != nil {
+	if info.InferenceAcceleratorInfo != nil {
 		for _, accelerator := range info.InferenceAcceleratorInfo.Accelerators {
 			count += *accelerator.Count
 		}
@@ -318,7 +500,14 @@ func efas(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(count))
 }
 
-func ENILimitedPods(ctx context.Context, info *ec2.InstanceTypeInfo) *resource.Quantity {
+// networkBandwidth returns the instance type's network bandwidth in megabits per second
+func networkBandwidth(info *ec2.InstanceTypeInfo) *resource.Quantity {
+	return resources.Quantity(fmt.Sprint(InstanceTypeBandwidthMegabits[aws.StringValue(info.InstanceType)]))
+}
+
+// ENILimitedPods returns the number of pods that the VPC CNI can assign IPs to. The IPv6 prefixes that are delegated to
+// the primary network interface are treated like IPv4 prefix delegation, and reserved ENIs aren't assigned to pods.
+func ENILimitedPods(ctx context.Context, info *ec2.InstanceTypeInfo, ipv6 *v1beta1.IPv6, reservedENIs int) *resource.Quantity {
 	// The number of pods per node is calculated using the formula:
 	// max number of ENIs * (IPv4 Addresses per ENI -1) + 2
 	// https://github.com/awslabs/amazon-eks-ami/blob/master/files/eni-max-pods.txt#L20
@@ -326,11 +515,18 @@ func ENILimitedPods(ctx context.Context, info *ec2.InstanceTypeInfo) *resource.Q
 	// VPC CNI only uses the default network interface
 	// https://github.com/aws/amazon-vpc-cni-k8s/blob/3294231c0dce52cfe473bf6c62f47956a3b333b6/scripts/gen_vpc_ip_limits.go#L162
 	networkInterfaces := *info.NetworkInfo.NetworkCards[*info.NetworkInfo.DefaultNetworkCardIndex].MaximumNetworkInterfaces
-	usableNetworkInterfaces := lo.Max([]int64{(networkInterfaces - int64(options.FromContext(ctx).ReservedENIs)), 0})
+	usableNetworkInterfaces := lo.Max([]int64{(networkInterfaces - int64(reservedENIs)), 0})
 	if usableNetworkInterfaces == 0 {
 		return resource.NewQuantity(0, resource.DecimalSI)
 	}
 	addressesPerInterface := *info.NetworkInfo.Ipv4AddressesPerInterface
+	if options.FromContext(ctx).ENIPrefixDelegation || lo.FromPtr(ipv6).PrefixCount != nil {
+		// With prefix delegation, each of an interface's address slots is assigned a /28 prefix (16 addresses) instead
+		// of a single secondary IP, and the primary IP of the interface remains unavailable to pods
+		// https://github.com/aws/amazon-vpc-cni-k8s/blob/master/docs/prefix-and-ip-target.md
+		ipv4PrefixesPerInterface := addressesPerInterface
+		return resources.Quantity(fmt.Sprint(usableNetworkInterfaces*(ipv4PrefixesPerInterface*16-1) + 2))
+	}
 	return resources.Quantity(fmt.Sprint(usableNetworkInterfaces*(addressesPerInterface-1) + 2))
 }
 
@@ -346,13 +542,13 @@ func systemReservedResources(systemReserved map[string]string) v1.ResourceList {
 	})
 }
 
-func kubeReservedResources(cpus, pods, eniLimitedPods *resource.Quantity, amiFamily amifamily.AMIFamily, kubeReserved map[string]string) v1.ResourceList {
+func kubeReservedResources(cpus, pods, eniLimitedPods, ephemeralStorage *resource.Quantity, amiFamily amifamily.AMIFamily, kubeReserved map[string]string) v1.ResourceList {
 	if amiFamily.FeatureFlags().UsesENILimitedMemoryOverhead {
 		pods = eniLimitedPods
 	}
 	resources := v1.ResourceList{
 		v1.ResourceMemory:           resource.MustParse(fmt.Sprintf("%dMi", (11*pods.Value())+255)),
-		v1.ResourceEphemeralStorage: resource.MustParse("1Gi"), // default kube-reserved ephemeral-storage
+		v1.ResourceEphemeralStorage: *ephemeralStorage, // default kube-reserved ephemeral-storage
 	}
 	// kube-reserved Computed from
 	// https://github.com/bottlerocket-os/bottlerocket/pull/1388/files#diff-bba9e4e3e46203be2b12f22e0d654ebd270f0b478dd34f40c31d7aa695620f2fR611
@@ -381,10 +577,10 @@ func kubeReservedResources(cpus, pods, eniLimitedPods *resource.Quantity, amiFam
 	}))
 }
 
-func evictionThreshold(memory *resource.Quantity, storage *resource.Quantity, amiFamily amifamily.AMIFamily, evictionHard map[string]string, evictionSoft map[string]string) v1.ResourceList {
+func evictionThreshold(memory *resource.Quantity, storage *resource.Quantity, storagePercent float64, amiFamily amifamily.AMIFamily, evictionHard map[string]string, evictionSoft map[string]string) v1.ResourceList {
 	overhead := v1.ResourceList{
 		v1.ResourceMemory:           resource.MustParse("100Mi"),
-		v1.ResourceEphemeralStorage: resource.MustParse(fmt.Sprint(math.Ceil(float64(storage.Value()) / 100 * 10))),
+		v1.ResourceEphemeralStorage: resource.MustParse(fmt.Sprint(math.Ceil(float64(storage.Value()) / 100 * storagePercent))),
 	}
 
 	override := v1.ResourceList{}
@@ -410,13 +606,13 @@ func evictionThreshold(memory *resource.Quantity, storage *resource.Quantity, am
 	return lo.Assign(overhead, override)
 }
 
-func pods(ctx context.Context, info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily, maxPods *int32, podsPerCore *int32) *resource.Quantity {
+func pods(ctx context.Context, info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily, ipv6 *v1beta1.IPv6, reservedENIs int, maxPods *int32, podsPerCore *int32) *resource.Quantity {
 	var count int64
 	switch {
 	case maxPods != nil:
 		count = int64(ptr.Int32Value(maxPods))
 	case amiFamily.FeatureFlags().SupportsENILimitedPodDensity:
-		count = ENILimitedPods(ctx, info).Value()
+		count = ENILimitedPods(ctx, info, ipv6, reservedENIs).Value()
 	default:
 		count = 110
 
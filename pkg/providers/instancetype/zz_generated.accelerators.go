@@ -0,0 +1,26 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+// GENERATED FILE. DO NOT EDIT DIRECTLY.
+// Update hack/code/accelerator_overlay_gen/accelerators.yaml and re-generate to edit
+
+var (
+	AcceleratorOverlay = map[string]AcceleratorInfo{
+		"trn1.2xlarge":   {Name: "Inferentia", Manufacturer: "AWS", Count: 1, MemoryMiB: 32768},
+		"trn1.32xlarge":  {Name: "Inferentia", Manufacturer: "AWS", Count: 16, MemoryMiB: 32768},
+		"trn1n.32xlarge": {Name: "Inferentia", Manufacturer: "AWS", Count: 16, MemoryMiB: 32768},
+	}
+)
@@ -0,0 +1,200 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+)
+
+// Fixture is a canonical EC2NodeClass and NodeClaim whose rendered launch template data is checked against a golden
+// file, so that changes to launch template resolution show which AMI families and variants they affect. Fixtures don't
+// reference any AWS or Kubernetes clients, so forks can check their own cases with VerifyFixture.
+type Fixture struct {
+	// Name is the name of the golden file of the fixture
+	Name          string
+	NodeClass     *v1beta1.EC2NodeClass
+	NodeClaim     *corev1beta1.NodeClaim
+	InstanceTypes []*cloudprovider.InstanceType
+	CapacityType  string
+	// Options are the static launch template parameters. They hold the cluster endpoint, CA bundle, cluster CIDR and
+	// cluster DNS IP that are otherwise discovered from the cluster.
+	Options *amifamily.Options
+	// AMIs are the AMIs that the EC2NodeClass resolves. When unset, the default AMIs of the AMIFamily for
+	// KubernetesVersion are used, identified by their SSM parameter.
+	AMIs              amifamily.AMIs
+	KubernetesVersion string
+}
+
+// RenderedLaunchTemplate is the normalized data of a launch template that a Fixture resolves
+type RenderedLaunchTemplate struct {
+	ImageID            string                 `json:"imageID"`
+	InstanceTypes      []string               `json:"instanceTypes"`
+	LaunchTemplateData map[string]interface{} `json:"launchTemplateData"`
+	// UserData is decoded so that changes to it are readable
+	UserData string `json:"userData"`
+}
+
+// RenderFixture resolves the launch templates of a Fixture and renders their data as YAML. Tags are sorted, unset
+// fields are dropped, and launch templates are ordered by AMI, so that the output only changes with the launch
+// template data. The context must hold the operator options.
+func RenderFixture(ctx context.Context, fixture Fixture) ([]byte, error) {
+	amis := fixture.AMIs
+	if len(amis) == 0 {
+		amis = lo.Map(amifamily.GetAMIFamily(fixture.NodeClass.Spec.AMIFamily, fixture.Options).DefaultAMIs(fixture.KubernetesVersion), func(ami amifamily.DefaultAMIOutput, _ int) amifamily.AMI {
+			return amifamily.AMI{Name: ami.Query, AmiID: ami.Query, Requirements: ami.Requirements}
+		})
+	}
+	resolved, err := amifamily.NewResolver(staticAMIProvider(amis)).Resolve(ctx, fixture.NodeClass, fixture.NodeClaim, fixture.InstanceTypes, fixture.CapacityType, fixture.Options)
+	if err != nil {
+		return nil, fmt.Errorf("resolving launch templates, %w", err)
+	}
+	var rendered []RenderedLaunchTemplate
+	for _, launchTemplate := range resolved {
+		data, err := LaunchTemplateData(launchTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("rendering launch template data, %w", err)
+		}
+		userData, err := base64.StdEncoding.DecodeString(aws.StringValue(data.UserData))
+		if err != nil {
+			return nil, fmt.Errorf("decoding user data, %w", err)
+		}
+		data.UserData = nil
+		for _, tagSpecification := range data.TagSpecifications {
+			sort.Slice(tagSpecification.Tags, func(i, j int) bool {
+				return aws.StringValue(tagSpecification.Tags[i].Key) < aws.StringValue(tagSpecification.Tags[j].Key)
+			})
+		}
+		normalized, err := normalize(data)
+		if err != nil {
+			return nil, err
+		}
+		instanceTypes := lo.Map(launchTemplate.InstanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })
+		sort.Strings(instanceTypes)
+		rendered = append(rendered, RenderedLaunchTemplate{
+			ImageID:            launchTemplate.AMIID,
+			InstanceTypes:      instanceTypes,
+			LaunchTemplateData: normalized,
+			UserData:           string(userData),
+		})
+	}
+	sort.Slice(rendered, func(i, j int) bool {
+		if rendered[i].ImageID != rendered[j].ImageID {
+			return rendered[i].ImageID < rendered[j].ImageID
+		}
+		return strings.Join(rendered[i].InstanceTypes, ",") < strings.Join(rendered[j].InstanceTypes, ",")
+	})
+	return yaml.Marshal(map[string]interface{}{"launchTemplates": rendered})
+}
+
+// VerifyFixture compares the rendered launch template data of a Fixture against its golden file in dir, or rewrites
+// the golden file when update is set
+func VerifyFixture(ctx context.Context, fixture Fixture, dir string, update bool) error {
+	rendered, err := RenderFixture(ctx, fixture)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fixture.Name+".yaml")
+	if update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, rendered, 0644) //nolint:gosec
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading golden file, %w", err)
+	}
+	if line, expected, actual, ok := firstDifference(string(golden), string(rendered)); ok {
+		return fmt.Errorf("launch template data of %q doesn't match %s at line %d, expected %q, got %q", fixture.Name, path, line, expected, actual)
+	}
+	return nil
+}
+
+// normalize converts launch template data into a map without its unset fields
+func normalize(data *ec2.RequestLaunchTemplateData) (map[string]interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return dropUnset(normalized).(map[string]interface{}), nil
+}
+
+func dropUnset(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if field == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = dropUnset(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = dropUnset(v[i])
+		}
+	}
+	return value
+}
+
+// firstDifference returns the first line, counted from one, at which two documents differ
+func firstDifference(expected, actual string) (int, string, string, bool) {
+	expectedLines, actualLines := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		if i >= len(expectedLines) || i >= len(actualLines) || expectedLines[i] != actualLines[i] {
+			return i + 1, lineAt(expectedLines, i), lineAt(actualLines, i), true
+		}
+	}
+	return 0, "", "", false
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+// staticAMIProvider resolves the same AMIs for every EC2NodeClass, so that fixtures don't need SSM or EC2
+type staticAMIProvider amifamily.AMIs
+
+func (s staticAMIProvider) Get(context.Context, *v1beta1.EC2NodeClass, *amifamily.Options) (amifamily.AMIs, error) {
+	return amifamily.AMIs(s), nil
+}
+
+func (s staticAMIProvider) List(context.Context, *v1beta1.EC2NodeClass, *amifamily.Options) (amifamily.AMIs, error) {
+	return amifamily.AMIs(s), nil
+}
@@ -0,0 +1,165 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate_test
+
+import (
+	"context"
+	"flag"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+)
+
+var updateGoldens = flag.Bool("update-goldens", false, "rewrite the launch template golden files in testdata/golden")
+
+// TestGoldens checks the rendered launch template data of the canonical fixtures against testdata/golden. Run
+// `make update-goldens` to rewrite the golden files after an intended change, and review their diff.
+func TestGoldens(t *testing.T) {
+	ctx := coreoptions.ToContext(context.Background(), coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	for _, fixture := range fixtures() {
+		fixture := fixture
+		t.Run(fixture.Name, func(t *testing.T) {
+			if err := launchtemplate.VerifyFixture(ctx, fixture, filepath.Join("testdata", "golden"), *updateGoldens); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func fixtures() []launchtemplate.Fixture {
+	linux := []*cloudprovider.InstanceType{
+		goldenInstanceType("m5.large", corev1beta1.ArchitectureAmd64, v1.Linux, nil),
+		goldenInstanceType("m6g.large", corev1beta1.ArchitectureArm64, v1.Linux, nil),
+		goldenInstanceType("g4dn.xlarge", corev1beta1.ArchitectureAmd64, v1.Linux,
+			scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpIn, "1")),
+	}
+	windows := []*cloudprovider.InstanceType{
+		goldenInstanceType("m5.large", corev1beta1.ArchitectureAmd64, v1.Windows, nil),
+	}
+	return []launchtemplate.Fixture{
+		goldenFixture("al2", &v1beta1.AMIFamilyAL2, linux),
+		goldenFixture("al2-custom-userdata", &v1beta1.AMIFamilyAL2, linux[:1], func(f *launchtemplate.Fixture) {
+			f.NodeClass.Spec.UserData = aws.String("#!/bin/bash\necho \"custom user data\"\n")
+		}),
+		goldenFixture("al2-spot", &v1beta1.AMIFamilyAL2, linux[:1], func(f *launchtemplate.Fixture) {
+			f.CapacityType = corev1beta1.CapacityTypeSpot
+		}),
+		goldenFixture("al2023", &v1beta1.AMIFamilyAL2023, linux),
+		goldenFixture("al2023-custom-userdata", &v1beta1.AMIFamilyAL2023, linux[:1], func(f *launchtemplate.Fixture) {
+			f.NodeClass.Spec.UserData = aws.String("apiVersion: node.eks.aws/v1alpha1\nkind: NodeConfig\nspec:\n  kubelet:\n    config:\n      maxPods: 42\n")
+		}),
+		goldenFixture("al2023-ipv6", &v1beta1.AMIFamilyAL2023, linux[:1], func(f *launchtemplate.Fixture) {
+			f.Options.KubeDNSIP = net.ParseIP("fd01:2345:6789::a")
+			f.Options.IPv6 = &v1beta1.IPv6{PrefixCount: aws.Int64(1)}
+		}),
+		goldenFixture("bottlerocket", &v1beta1.AMIFamilyBottlerocket, linux),
+		goldenFixture("bottlerocket-custom-userdata", &v1beta1.AMIFamilyBottlerocket, linux[:1], func(f *launchtemplate.Fixture) {
+			f.NodeClass.Spec.UserData = aws.String("[settings.kubernetes]\nmax-pods = 42\n\n[settings.ntp]\ntime-servers = [\"169.254.169.123\"]\n")
+		}),
+		goldenFixture("bottlerocket-ipv6", &v1beta1.AMIFamilyBottlerocket, linux[:1], func(f *launchtemplate.Fixture) {
+			f.Options.KubeDNSIP = net.ParseIP("fd01:2345:6789::a")
+			f.Options.IPv6 = &v1beta1.IPv6{PrefixCount: aws.Int64(1)}
+		}),
+		goldenFixture("ubuntu", &v1beta1.AMIFamilyUbuntu, linux[:2]),
+		goldenFixture("windows2022", &v1beta1.AMIFamilyWindows2022, windows),
+		goldenFixture("windows2022-custom-userdata", &v1beta1.AMIFamilyWindows2022, windows, func(f *launchtemplate.Fixture) {
+			f.NodeClass.Spec.UserData = aws.String("Write-Host \"custom user data\"")
+		}),
+		goldenFixture("custom", &v1beta1.AMIFamilyCustom, linux[:1], func(f *launchtemplate.Fixture) {
+			f.AMIs = amifamily.AMIs{{
+				Name:         "custom-ami",
+				AmiID:        "ami-0123456789abcdef0",
+				Requirements: scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64)),
+			}}
+			f.NodeClass.Spec.UserData = aws.String("#!/bin/bash\n/etc/eks/bootstrap.sh golden-cluster\n")
+		}),
+	}
+}
+
+// goldenFixture returns a fixture for an AMIFamily whose cluster and NodeClaim are the same across AMIFamilies, so
+// that goldens only differ by what the AMIFamily renders
+func goldenFixture(name string, amiFamily *string, instanceTypes []*cloudprovider.InstanceType, opts ...func(*launchtemplate.Fixture)) launchtemplate.Fixture {
+	fixture := launchtemplate.Fixture{
+		Name: name,
+		NodeClass: &v1beta1.EC2NodeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "golden"},
+			Spec: v1beta1.EC2NodeClassSpec{
+				AMIFamily: amiFamily,
+				Role:      "golden-role",
+			},
+		},
+		NodeClaim: &corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "golden"},
+			Spec: corev1beta1.NodeClaimSpec{
+				Taints:        []v1.Taint{{Key: "golden.sh/taint", Value: "true", Effect: v1.TaintEffectNoSchedule}},
+				StartupTaints: []v1.Taint{{Key: "golden.sh/startup-taint", Effect: v1.TaintEffectNoExecute}},
+				Kubelet: &corev1beta1.KubeletConfiguration{
+					SystemReserved: map[string]string{string(v1.ResourceMemory): "100Mi"},
+				},
+			},
+		},
+		InstanceTypes: instanceTypes,
+		CapacityType:  corev1beta1.CapacityTypeOnDemand,
+		Options: &amifamily.Options{
+			ClusterName:     "golden-cluster",
+			ClusterEndpoint: "https://golden-cluster.eks.amazonaws.com",
+			ClusterCIDR:     aws.String("10.100.0.0/16"),
+			CABundle:        aws.String("Z29sZGVuLWNhLWJ1bmRsZQo="),
+			KubeDNSIP:       net.ParseIP("10.100.0.10"),
+			InstanceProfile: "golden-instance-profile",
+			SecurityGroups:  []v1beta1.SecurityGroup{{ID: "sg-0123456789abcdef0", Name: "golden"}},
+			Tags:            map[string]string{"karpenter.sh/nodepool": "default", "golden.sh/tag": "value"},
+			Labels:          map[string]string{corev1beta1.NodePoolLabelKey: "default", "golden.sh/label": "value"},
+			NodeClassName:   "golden",
+		},
+		KubernetesVersion: "1.29",
+	}
+	for _, opt := range opts {
+		opt(&fixture)
+	}
+	return fixture
+}
+
+func goldenInstanceType(name string, architecture string, os v1.OSName, requirement *scheduling.Requirement) *cloudprovider.InstanceType {
+	return fake.NewInstanceTypeWithCustomRequirement(fake.InstanceTypeOptions{
+		Name:             name,
+		Architecture:     architecture,
+		OperatingSystems: sets.New(string(os)),
+		Resources: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("2"),
+			v1.ResourceMemory: resource.MustParse("8Gi"),
+			v1.ResourcePods:   resource.MustParse("29"),
+		},
+	}, requirement)
+}
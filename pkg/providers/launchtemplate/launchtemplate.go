@@ -23,6 +23,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"go.uber.org/multierr"
@@ -39,11 +40,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	"knative.dev/pkg/logging"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
@@ -52,13 +55,15 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
-var karpenterManagedTagKey = fmt.Sprintf("%s/cluster", v1beta1.Group)
+// KarpenterManagedTagKey tags the launch templates that Karpenter creates with the name of their cluster
+var KarpenterManagedTagKey = fmt.Sprintf("%s/cluster", v1beta1.Group)
 
 type Provider interface {
 	EnsureAll(context.Context, *v1beta1.EC2NodeClass, *corev1beta1.NodeClaim,
 		[]*cloudprovider.InstanceType, string, map[string]string) ([]*LaunchTemplate, error)
 	DeleteAll(context.Context, *v1beta1.EC2NodeClass) error
 	InvalidateCache(context.Context, string, string)
+	LastUsed(string) (time.Time, bool)
 	ResolveClusterCIDR(context.Context) error
 }
 
@@ -77,10 +82,13 @@ type DefaultProvider struct {
 	subnetProvider        subnet.Provider
 	cache                 *cache.Cache
 	cm                    *pretty.ChangeMonitor
-	KubeDNSIP             net.IP
-	CABundle              *string
-	ClusterEndpoint       string
-	ClusterCIDR           atomic.Pointer[string]
+	// lastUsed is when each launch template was last ensured for a launch, so that launch templates that in-flight
+	// launches may still reference aren't garbage collected
+	lastUsed        map[string]time.Time
+	KubeDNSIP       net.IP
+	CABundle        *string
+	ClusterEndpoint string
+	ClusterCIDR     atomic.Pointer[string]
 }
 
 func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api ec2iface.EC2API, eksapi eksiface.EKSAPI, amiFamily *amifamily.Resolver,
@@ -95,6 +103,7 @@ func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api ec2iface
 		cache:                 cache,
 		CABundle:              caBundle,
 		cm:                    pretty.NewChangeMonitor(),
+		lastUsed:              map[string]time.Time{},
 		KubeDNSIP:             kubeDNSIP,
 		ClusterEndpoint:       clusterEndpoint,
 	}
@@ -117,7 +126,7 @@ func (p *DefaultProvider) EnsureAll(ctx context.Context, nodeClass *v1beta1.EC2N
 	p.Lock()
 	defer p.Unlock()
 
-	options, err := p.createAMIOptions(ctx, nodeClass, lo.Assign(nodeClaim.Labels, map[string]string{corev1beta1.CapacityTypeLabelKey: capacityType}), tags)
+	options, err := p.createAMIOptions(ctx, nodeClass, nodeClaim, lo.Assign(nodeClaim.Labels, map[string]string{corev1beta1.CapacityTypeLabelKey: capacityType}), tags)
 	if err != nil {
 		return nil, err
 	}
@@ -146,13 +155,22 @@ func (p *DefaultProvider) InvalidateCache(ctx context.Context, ltName string, lt
 	p.cache.OnEvicted(nil)
 	logging.FromContext(ctx).Debugf("invalidating launch template in the cache because it no longer exists")
 	p.cache.Delete(ltName)
+	delete(p.lastUsed, ltName)
+}
+
+// LastUsed returns when the launch template was last ensured for a launch by this provider
+func (p *DefaultProvider) LastUsed(ltName string) (time.Time, bool) {
+	p.Lock()
+	defer p.Unlock()
+	lastUsed, ok := p.lastUsed[ltName]
+	return lastUsed, ok
 }
 
 func LaunchTemplateName(options *amifamily.LaunchTemplate) string {
 	return fmt.Sprintf("%s/%d", v1beta1.Group, lo.Must(hashstructure.Hash(options, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})))
 }
 
-func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, labels, tags map[string]string) (*amifamily.Options, error) {
+func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, labels, tags map[string]string) (*amifamily.Options, error) {
 	// Remove any labels passed into userData that are prefixed with "node-restriction.kubernetes.io" or "kops.k8s.io" since the kubelet can't
 	// register the node with any labels from this domain: https://kubernetes.io/docs/reference/access-authn-authz/admission-controllers/#noderestriction
 	for k := range labels {
@@ -173,20 +191,31 @@ func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1bet
 	if len(securityGroups) == 0 {
 		return nil, fmt.Errorf("no security groups exist given constraints")
 	}
+	if len(securityGroups) > securitygroup.MaxSecurityGroups {
+		return nil, fmt.Errorf("%d security groups exist given constraints, more than the %d that can be attached to an instance", len(securityGroups), securitygroup.MaxSecurityGroups)
+	}
+	nodeName, err := renderNodeName(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
 	options := &amifamily.Options{
 		ClusterName:         options.FromContext(ctx).ClusterName,
 		ClusterEndpoint:     p.ClusterEndpoint,
 		ClusterCIDR:         p.ClusterCIDR.Load(),
 		InstanceProfile:     instanceProfile,
 		InstanceStorePolicy: nodeClass.Spec.InstanceStorePolicy,
+		InstanceStore:       nodeClass.Spec.InstanceStore,
 		SecurityGroups: lo.Map(securityGroups, func(s *ec2.SecurityGroup, _ int) v1beta1.SecurityGroup {
 			return v1beta1.SecurityGroup{ID: aws.StringValue(s.GroupId), Name: aws.StringValue(s.GroupName)}
 		}),
-		Tags:          tags,
-		Labels:        labels,
-		CABundle:      p.CABundle,
-		KubeDNSIP:     p.KubeDNSIP,
-		NodeClassName: nodeClass.Name,
+		Tags:               tags,
+		Labels:             labels,
+		CABundle:           p.CABundle,
+		IPv6:               nodeClass.Spec.IPv6,
+		KubeDNSIP:          p.KubeDNSIP,
+		NodeClassName:      nodeClass.Name,
+		NodeNameConvention: options.FromContext(ctx).NodeNameConvention,
+		NodeName:           nodeName,
 	}
 	if nodeClass.Spec.AssociatePublicIPAddress != nil {
 		options.AssociatePublicIPAddress = nodeClass.Spec.AssociatePublicIPAddress
@@ -200,13 +229,45 @@ func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1bet
 		// https://github.com/aws/karpenter-provider-aws/issues/3815
 		options.AssociatePublicIPAddress = aws.Bool(false)
 	}
+	// Instances in a Wavelength Zone are reached through a carrier IP, and can't be assigned a public IP
+	if aws.BoolValue(options.AssociatePublicIPAddress) {
+		wavelength, err := p.inWavelengthZones(ctx, nodeClass, nodeClaim)
+		if err != nil {
+			return nil, err
+		}
+		if wavelength {
+			options.AssociatePublicIPAddress = nil
+			options.AssociateCarrierIPAddress = aws.Bool(true)
+		}
+	}
 	return options, nil
 }
 
+// inWavelengthZones returns true if every subnet that the NodeClaim can be launched into is in a Wavelength Zone
+func (p *DefaultProvider) inWavelengthZones(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim) (bool, error) {
+	subnets, err := p.subnetProvider.List(ctx, nodeClass)
+	if err != nil {
+		return false, err
+	}
+	zoneRequirement := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone)
+	subnets = lo.Filter(subnets, func(s *ec2.Subnet, _ int) bool { return zoneRequirement.Has(aws.StringValue(s.AvailabilityZone)) })
+	if len(subnets) == 0 {
+		return false, nil
+	}
+	zones, err := p.subnetProvider.AvailabilityZones(ctx, subnets)
+	if err != nil {
+		return false, err
+	}
+	return lo.EveryBy(subnets, func(s *ec2.Subnet) bool {
+		return aws.StringValue(lo.FromPtr(zones[aws.StringValue(s.AvailabilityZone)]).ZoneType) == subnet.ZoneTypeWavelengthZone
+	}), nil
+}
+
 func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (*ec2.LaunchTemplate, error) {
 	var launchTemplate *ec2.LaunchTemplate
 	name := LaunchTemplateName(options)
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("launch-template-name", name))
+	p.lastUsed[name] = time.Now()
 	// Read from cache
 	if launchTemplate, ok := p.cache.Get(name); ok {
 		p.cache.SetDefault(name, launchTemplate)
@@ -237,45 +298,17 @@ func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *ami
 }
 
 func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (*ec2.LaunchTemplate, error) {
-	userData, err := options.UserData.Script()
+	launchTemplateData, err := LaunchTemplateData(options)
 	if err != nil {
 		return nil, err
 	}
-	launchTemplateDataTags := []*ec2.LaunchTemplateTagSpecificationRequest{
-		{ResourceType: aws.String(ec2.ResourceTypeNetworkInterface), Tags: utils.MergeTags(options.Tags)},
-	}
-	// Add the spot-instances-request tag if trying to launch spot capacity
-	if options.CapacityType == corev1beta1.CapacityTypeSpot {
-		launchTemplateDataTags = append(launchTemplateDataTags, &ec2.LaunchTemplateTagSpecificationRequest{ResourceType: aws.String(ec2.ResourceTypeSpotInstancesRequest), Tags: utils.MergeTags(options.Tags)})
-	}
-	networkInterfaces := p.generateNetworkInterfaces(options)
 	output, err := p.ec2api.CreateLaunchTemplateWithContext(ctx, &ec2.CreateLaunchTemplateInput{
 		LaunchTemplateName: aws.String(LaunchTemplateName(options)),
-		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
-			BlockDeviceMappings: p.blockDeviceMappings(options.BlockDeviceMappings),
-			IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
-				Name: aws.String(options.InstanceProfile),
-			},
-			Monitoring: &ec2.LaunchTemplatesMonitoringRequest{
-				Enabled: aws.Bool(options.DetailedMonitoring),
-			},
-			// If the network interface is defined, the security groups are defined within it
-			SecurityGroupIds: lo.Ternary(networkInterfaces != nil, nil, lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) })),
-			UserData:         aws.String(userData),
-			ImageId:          aws.String(options.AMIID),
-			MetadataOptions: &ec2.LaunchTemplateInstanceMetadataOptionsRequest{
-				HttpEndpoint:            options.MetadataOptions.HTTPEndpoint,
-				HttpProtocolIpv6:        options.MetadataOptions.HTTPProtocolIPv6,
-				HttpPutResponseHopLimit: options.MetadataOptions.HTTPPutResponseHopLimit,
-				HttpTokens:              options.MetadataOptions.HTTPTokens,
-			},
-			NetworkInterfaces: networkInterfaces,
-			TagSpecifications: launchTemplateDataTags,
-		},
+		LaunchTemplateData: launchTemplateData,
 		TagSpecifications: []*ec2.TagSpecification{
 			{
 				ResourceType: aws.String(ec2.ResourceTypeLaunchTemplate),
-				Tags:         utils.MergeTags(options.Tags, map[string]string{karpenterManagedTagKey: options.ClusterName, v1beta1.LabelNodeClass: options.NodeClassName}),
+				Tags:         utils.MergeTags(options.Tags, map[string]string{KarpenterManagedTagKey: options.ClusterName, v1beta1.LabelNodeClass: options.NodeClassName}),
 			},
 		},
 	})
@@ -286,8 +319,116 @@ func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, options *ami
 	return output.LaunchTemplate, nil
 }
 
+// LaunchTemplateData renders the data of the launch template for a resolved launch template. It only depends on its
+// input, so that rendering is deterministic and doesn't need any AWS clients.
+func LaunchTemplateData(options *amifamily.LaunchTemplate) (*ec2.RequestLaunchTemplateData, error) {
+	userData, err := options.UserData.Script()
+	if err != nil {
+		return nil, err
+	}
+	launchTemplateDataTags := []*ec2.LaunchTemplateTagSpecificationRequest{
+		{ResourceType: aws.String(ec2.ResourceTypeNetworkInterface), Tags: utils.MergeTags(options.Tags)},
+	}
+	// Add the spot-instances-request tag if trying to launch spot capacity
+	if options.CapacityType == corev1beta1.CapacityTypeSpot {
+		launchTemplateDataTags = append(launchTemplateDataTags, &ec2.LaunchTemplateTagSpecificationRequest{ResourceType: aws.String(ec2.ResourceTypeSpotInstancesRequest), Tags: utils.MergeTags(options.Tags)})
+	}
+	networkInterfaces := generateNetworkInterfaces(options)
+	return &ec2.RequestLaunchTemplateData{
+		BlockDeviceMappings: blockDeviceMappings(options.BlockDeviceMappings),
+		IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
+			Name: aws.String(options.InstanceProfile),
+		},
+		Monitoring: &ec2.LaunchTemplatesMonitoringRequest{
+			Enabled: aws.Bool(options.DetailedMonitoring),
+		},
+		// If the network interface is defined, the security groups are defined within it
+		SecurityGroupIds: lo.Ternary(networkInterfaces != nil, nil, lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) })),
+		UserData:         aws.String(userData),
+		ImageId:          aws.String(options.AMIID),
+		MetadataOptions: &ec2.LaunchTemplateInstanceMetadataOptionsRequest{
+			HttpEndpoint:            options.MetadataOptions.HTTPEndpoint,
+			HttpProtocolIpv6:        options.MetadataOptions.HTTPProtocolIPv6,
+			HttpPutResponseHopLimit: options.MetadataOptions.HTTPPutResponseHopLimit,
+			HttpTokens:              options.MetadataOptions.HTTPTokens,
+		},
+		NetworkInterfaces:                networkInterfaces,
+		Placement:                        placement(options),
+		InstanceMarketOptions:            instanceMarketOptions(options),
+		CapacityReservationSpecification: capacityReservationSpecification(options),
+		TagSpecifications:                launchTemplateDataTags,
+		PrivateDnsNameOptions:            privateDNSNameOptions(options.NodeNameConvention),
+	}, nil
+}
+
+// renderNodeName renders the node-name-template for a node with the given labels when nodes are named from the template.
+// The instance ID is left as a reference to a variable that the bootstrap script sets, since it isn't known until launch.
+func renderNodeName(ctx context.Context, labels map[string]string) (string, error) {
+	if options.FromContext(ctx).NodeNameConvention != options.NodeNameConventionTemplate {
+		return "", nil
+	}
+	tmpl, err := template.New("node-name").Parse(options.FromContext(ctx).NodeNameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("parsing node name template, %w", err)
+	}
+	var nodeName strings.Builder
+	if err := tmpl.Execute(&nodeName, options.NodeNameTemplateData{
+		ClusterName: options.FromContext(ctx).ClusterName,
+		NodePool:    labels[corev1beta1.NodePoolLabelKey],
+		InstanceID:  bootstrap.InstanceIDVariable,
+	}); err != nil {
+		return "", fmt.Errorf("rendering node name template, %w", err)
+	}
+	return nodeName.String(), nil
+}
+
+// privateDNSNameOptions launches instances with resource-based hostnames so that nodes are named after their instance ID
+func privateDNSNameOptions(nodeNameConvention string) *ec2.LaunchTemplatePrivateDnsNameOptionsRequest {
+	if nodeNameConvention != options.NodeNameConventionResourceName {
+		return nil
+	}
+	return &ec2.LaunchTemplatePrivateDnsNameOptionsRequest{
+		HostnameType: aws.String(ec2.HostnameTypeResourceName),
+	}
+}
+
+// instanceMarketOptions launches instances into capacity blocks with the capacity-block market type. EC2 Fleet rejects
+// launch templates with spot market options, so spot instances are left to the fleet's spot options.
+func instanceMarketOptions(options *amifamily.LaunchTemplate) *ec2.LaunchTemplateInstanceMarketOptionsRequest {
+	if options.CapacityType != v1beta1.CapacityTypeCapacityBlock {
+		return nil
+	}
+	return &ec2.LaunchTemplateInstanceMarketOptionsRequest{MarketType: aws.String(ec2.MarketTypeCapacityBlock)}
+}
+
+// capacityReservationSpecification targets the capacity block of a capacity-block launch template
+func capacityReservationSpecification(options *amifamily.LaunchTemplate) *ec2.LaunchTemplateCapacityReservationSpecificationRequest {
+	if options.CapacityReservationID == "" {
+		return nil
+	}
+	return &ec2.LaunchTemplateCapacityReservationSpecificationRequest{
+		CapacityReservationTarget: &ec2.CapacityReservationTarget{CapacityReservationId: aws.String(options.CapacityReservationID)},
+	}
+}
+
+func placement(options *amifamily.LaunchTemplate) *ec2.LaunchTemplatePlacementRequest {
+	if options.Placement == nil && options.Tenancy == nil {
+		return nil
+	}
+	placement := &ec2.LaunchTemplatePlacementRequest{Tenancy: options.Tenancy}
+	if options.Placement != nil {
+		placement.GroupName = aws.String(options.Placement.GroupName)
+		placement.PartitionNumber = options.Placement.PartitionNumber
+	}
+	if options.HostPlacement != nil {
+		placement.HostId = options.HostPlacement.HostID
+		placement.HostResourceGroupArn = options.HostPlacement.HostResourceGroupARN
+	}
+	return placement
+}
+
 // generateNetworkInterfaces generates network interfaces for the launch template.
-func (p *DefaultProvider) generateNetworkInterfaces(options *amifamily.LaunchTemplate) []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+func generateNetworkInterfaces(options *amifamily.LaunchTemplate) []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
 	if options.EFACount != 0 {
 		return lo.Times(options.EFACount, func(i int) *ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
 			return &ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
@@ -298,24 +439,31 @@ func (p *DefaultProvider) generateNetworkInterfaces(options *amifamily.LaunchTem
 				Groups:        lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) }),
 				// Instances launched with multiple pre-configured network interfaces cannot set AssociatePublicIPAddress to true. This is an EC2 limitation. However, this does not apply for instances
 				// with a single EFA network interface, and we should support those use cases. Launch failures with multiple enis should be considered user misconfiguration.
-				AssociatePublicIpAddress: options.AssociatePublicIPAddress,
+				AssociatePublicIpAddress:  options.AssociatePublicIPAddress,
+				AssociateCarrierIpAddress: options.AssociateCarrierIPAddress,
+				// IPv6 prefixes and addresses are only assigned to the primary network interface
+				Ipv6PrefixCount:  lo.Ternary(i == 0, lo.FromPtr(options.IPv6).PrefixCount, nil),
+				Ipv6AddressCount: lo.Ternary(i == 0, lo.FromPtr(options.IPv6).AddressCount, nil),
 			}
 		})
 	}
 
-	if options.AssociatePublicIPAddress != nil {
+	if options.AssociatePublicIPAddress != nil || options.AssociateCarrierIPAddress != nil || options.IPv6 != nil {
 		return []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
 			{
-				AssociatePublicIpAddress: options.AssociatePublicIPAddress,
-				DeviceIndex:              aws.Int64(0),
-				Groups:                   lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) }),
+				AssociatePublicIpAddress:  options.AssociatePublicIPAddress,
+				AssociateCarrierIpAddress: options.AssociateCarrierIPAddress,
+				DeviceIndex:               aws.Int64(0),
+				Groups:                    lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) }),
+				Ipv6PrefixCount:           lo.FromPtr(options.IPv6).PrefixCount,
+				Ipv6AddressCount:          lo.FromPtr(options.IPv6).AddressCount,
 			},
 		}
 	}
 	return nil
 }
 
-func (p *DefaultProvider) blockDeviceMappings(blockDeviceMappings []*v1beta1.BlockDeviceMapping) []*ec2.LaunchTemplateBlockDeviceMappingRequest {
+func blockDeviceMappings(blockDeviceMappings []*v1beta1.BlockDeviceMapping) []*ec2.LaunchTemplateBlockDeviceMappingRequest {
 	if len(blockDeviceMappings) == 0 {
 		// The EC2 API fails with empty slices and expects nil.
 		return nil
@@ -332,7 +480,7 @@ func (p *DefaultProvider) blockDeviceMappings(blockDeviceMappings []*v1beta1.Blo
 				Throughput:          blockDeviceMapping.EBS.Throughput,
 				KmsKeyId:            blockDeviceMapping.EBS.KMSKeyID,
 				SnapshotId:          blockDeviceMapping.EBS.SnapshotID,
-				VolumeSize:          p.volumeSize(blockDeviceMapping.EBS.VolumeSize),
+				VolumeSize:          volumeSize(blockDeviceMapping.EBS.VolumeSize),
 			},
 		})
 	}
@@ -340,7 +488,7 @@ func (p *DefaultProvider) blockDeviceMappings(blockDeviceMappings []*v1beta1.Blo
 }
 
 // volumeSize returns a GiB scaled value from a resource quantity or nil if the resource quantity passed in is nil
-func (p *DefaultProvider) volumeSize(quantity *resource.Quantity) *int64 {
+func volumeSize(quantity *resource.Quantity) *int64 {
 	if quantity == nil {
 		return nil
 	}
@@ -352,9 +500,9 @@ func (p *DefaultProvider) volumeSize(quantity *resource.Quantity) *int64 {
 // Any error during hydration will result in a panic
 func (p *DefaultProvider) hydrateCache(ctx context.Context) {
 	clusterName := options.FromContext(ctx).ClusterName
-	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("tag-key", karpenterManagedTagKey, "tag-value", clusterName))
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("tag-key", KarpenterManagedTagKey, "tag-value", clusterName))
 	if err := p.ec2api.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
-		Filters: []*ec2.Filter{{Name: aws.String(fmt.Sprintf("tag:%s", karpenterManagedTagKey)), Values: []*string{aws.String(clusterName)}}},
+		Filters: []*ec2.Filter{{Name: aws.String(fmt.Sprintf("tag:%s", KarpenterManagedTagKey)), Values: []*string{aws.String(clusterName)}}},
 	}, func(output *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
 		for _, lt := range output.LaunchTemplates {
 			p.cache.SetDefault(*lt.LaunchTemplateName, lt)
@@ -379,6 +527,7 @@ func (p *DefaultProvider) cachedEvictedFunc(ctx context.Context) func(string, in
 			logging.FromContext(ctx).With("launch-template", launchTemplate.LaunchTemplateName).Errorf("failed to delete launch template, %v", err)
 			return
 		}
+		delete(p.lastUsed, key)
 		logging.FromContext(ctx).With(
 			"id", aws.StringValue(launchTemplate.LaunchTemplateId),
 			"name", aws.StringValue(launchTemplate.LaunchTemplateName),
@@ -404,7 +553,7 @@ func (p *DefaultProvider) DeleteAll(ctx context.Context, nodeClass *v1beta1.EC2N
 	var ltNames []*string
 	if err := p.ec2api.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
 		Filters: []*ec2.Filter{
-			{Name: aws.String(fmt.Sprintf("tag:%s", karpenterManagedTagKey)), Values: []*string{aws.String(clusterName)}},
+			{Name: aws.String(fmt.Sprintf("tag:%s", KarpenterManagedTagKey)), Values: []*string{aws.String(clusterName)}},
 			{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.LabelNodeClass)), Values: []*string{aws.String(nodeClass.Name)}},
 		},
 	}, func(output *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
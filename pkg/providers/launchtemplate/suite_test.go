@@ -91,7 +91,7 @@ var _ = BeforeSuite(func() {
 
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.SubnetProvider, awsEnv.CostLimitProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster)
 })
@@ -219,6 +219,21 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			Expect(*launchTemplate.LaunchTemplateSpecification.Version).To(Equal("$Latest"))
 		})
 	})
+	It("should record when each launch template was last used for a launch", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		for _, ltConfig := range createFleetInput.LaunchTemplateConfigs {
+			lastUsed, ok := awsEnv.LaunchTemplateProvider.LastUsed(aws.StringValue(ltConfig.LaunchTemplateSpecification.LaunchTemplateName))
+			Expect(ok).To(BeTrue())
+			Expect(lastUsed).To(BeTemporally("~", time.Now(), time.Minute))
+		}
+		_, ok := awsEnv.LaunchTemplateProvider.LastUsed("karpenter.k8s.aws/unused")
+		Expect(ok).To(BeFalse())
+	})
 	It("should fail to provision if the instance profile isn't defined", func() {
 		nodeClass.Status.InstanceProfile = ""
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -329,10 +344,12 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				{ClusterCIDR: lo.ToPtr("test-cidr")},
 				{InstanceProfile: "test-profile"},
 				{InstanceStorePolicy: lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)},
+				{InstanceStore: &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}},
 				{SecurityGroups: []v1beta1.SecurityGroup{{Name: "test-sg"}}},
 				{Tags: map[string]string{"test-key": "test-value"}},
 				{KubeDNSIP: net.ParseIP("192.0.0.2")},
 				{AssociatePublicIPAddress: lo.ToPtr(true)},
+				{IPv6: &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](1)}},
 				{NodeClassName: "test-name"},
 			}
 			launchtemplateResult := []string{}
@@ -340,7 +357,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				lt := &amifamily.LaunchTemplate{Options: option}
 				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
 			}
-			Expect(len(launchtemplateResult)).To(BeNumerically("==", 11))
+			Expect(len(launchtemplateResult)).To(BeNumerically("==", 13))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
 		})
 		It("should not generate different launch template names based on CABundle and Labels", func() {
@@ -593,6 +610,67 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				}))
 			})
 		})
+		It("should apply block device defaults to the AMI family's default block device mappings", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+			nodeClass.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{
+				VolumeType: aws.String("gp3"),
+				IOPS:       aws.Int64(4000),
+				Throughput: aws.Int64(250),
+				KMSKeyID:   aws.String("arn:aws:kms:us-west-2:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.BlockDeviceMappings).To(HaveLen(2))
+				for _, bdm := range ltInput.LaunchTemplateData.BlockDeviceMappings {
+					Expect(aws.Int64Value(bdm.Ebs.Iops)).To(Equal(int64(4000)))
+					Expect(aws.Int64Value(bdm.Ebs.Throughput)).To(Equal(int64(250)))
+					Expect(aws.StringValue(bdm.Ebs.KmsKeyId)).To(Equal("arn:aws:kms:us-west-2:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"))
+				}
+			})
+		})
+		It("should only apply block device defaults to the fields that custom block device mappings omit", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			nodeClass.Spec.BlockDeviceDefaults = &v1beta1.BlockDeviceDefaults{
+				VolumeType: aws.String("gp3"),
+				IOPS:       aws.Int64(4000),
+				Throughput: aws.Int64(250),
+				Encrypted:  aws.Bool(true),
+			}
+			nodeClass.Spec.BlockDeviceMappings = []*v1beta1.BlockDeviceMapping{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					EBS:        &v1beta1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("100Gi")), IOPS: aws.Int64(6000)},
+				},
+				{
+					DeviceName: aws.String("/dev/xvdb"),
+					EBS:        &v1beta1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("100Gi")), VolumeType: aws.String("gp2"), Encrypted: aws.Bool(false)},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs).To(Equal(&ec2.LaunchTemplateEbsBlockDeviceRequest{
+					VolumeSize: aws.Int64(100),
+					VolumeType: aws.String("gp3"),
+					Iops:       aws.Int64(6000),
+					Throughput: aws.Int64(250),
+					Encrypted:  aws.Bool(true),
+				}))
+				// gp2 volumes don't support provisioned IOPS or throughput
+				Expect(ltInput.LaunchTemplateData.BlockDeviceMappings[1].Ebs).To(Equal(&ec2.LaunchTemplateEbsBlockDeviceRequest{
+					VolumeSize: aws.Int64(100),
+					VolumeType: aws.String("gp2"),
+					Encrypted:  aws.Bool(false),
+				}))
+			})
+		})
 		It("should round up for custom block device mappings when specified in gigabytes", func() {
 			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
 			nodeClass.Spec.BlockDeviceMappings = []*v1beta1.BlockDeviceMapping{
@@ -935,6 +1013,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				"",
 				nodeClass.Spec.BlockDeviceMappings,
 				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -988,6 +1070,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				"",
 				nodeClass.Spec.BlockDeviceMappings,
 				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1014,6 +1100,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				"",
 				nodeClass.Spec.BlockDeviceMappings,
 				nodeClass.Spec.InstanceStorePolicy,
+				nodeClass.Spec.InstanceStore,
+				nodeClass.Spec.IPv6,
+				options.FromContext(ctx).ReservedENIs,
+				options.FromContext(ctx).VMMemoryOverheadPercent,
 				nodePool.Spec.Template.Spec.Kubelet.MaxPods,
 				nodePool.Spec.Template.Spec.Kubelet.PodsPerCore,
 				nodePool.Spec.Template.Spec.Kubelet.KubeReserved,
@@ -1043,6 +1133,15 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			ExpectScheduled(ctx, env.Client, pod)
 			ExpectLaunchTemplatesCreatedWithUserDataContaining("--use-max-pods false", "--max-pods=10")
 		})
+		It("should specify --max-pods from the EC2NodeClass's max-pods overrides over the NodePool's maxPods", func() {
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{MaxPods: aws.Int32(10)}
+			nodeClass.Spec.MaxPodsOverrides = map[string]int32{"*": 17}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--use-max-pods false", "--max-pods=17")
+		})
 		It("should specify --system-reserved when overriding system reserved values", func() {
 			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{
 				SystemReserved: map[string]string{
@@ -1286,6 +1385,55 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			ExpectScheduled(ctx, env.Client, pod)
 			ExpectLaunchTemplatesCreatedWithUserDataContaining("--local-disks raid0")
 		})
+		It("should assemble the instance store disks before bootstrapping when they're mirrored on AL2", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--level=1", "mkfs.xfs -f /dev/md/kubernetes")
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--local-disks raid0")
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				Expect(strings.Index(userData, "mdadm --create")).To(BeNumerically("<", strings.Index(userData, "/etc/eks/bootstrap.sh")))
+			}
+		})
+		It("should assemble the instance store disks before bootstrapping with the RAID10 instance store policy on AL2", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID10)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--level=10", "mkfs.xfs -f /dev/md/kubernetes")
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--local-disks raid0")
+		})
+		It("should format the instance store array with ext4 when specified on AL2", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{Filesystem: lo.ToPtr(v1beta1.InstanceStoreFilesystemExt4)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--level=0", "mkfs.ext4 -F -m 0 /dev/md/kubernetes")
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--local-disks raid0")
+		})
+		It("should leave the instance store to bootstrap.sh when it's striped with xfs on AL2", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+			nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{
+				RAIDLevel:  lo.ToPtr(v1beta1.RAIDLevelRAID0),
+				Filesystem: lo.ToPtr(v1beta1.InstanceStoreFilesystemXFS),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--local-disks raid0")
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("mdadm")
+		})
 		Context("Bottlerocket", func() {
 			BeforeEach(func() {
 				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
@@ -1728,6 +1876,24 @@ var _ = Describe("LaunchTemplate Provider", func() {
 					Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(Equal(admv1alpha1.LocalStorageRAID0))
 				}
 			})
+			It("should assemble the instance store disks with a script when they're mirrored", func() {
+				nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)
+				nodeClass.Spec.InstanceStore = &v1beta1.InstanceStore{RAIDLevel: lo.ToPtr(v1beta1.RAIDLevelRAID1)}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+					Expect(len(configs)).To(Equal(1))
+					Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(BeEmpty())
+					archive, err := mime.NewArchive(userData)
+					Expect(err).To(BeNil())
+					script, ok := lo.Find(archive, func(e mime.Entry) bool { return e.ContentType == mime.ContentTypeShellScript })
+					Expect(ok).To(BeTrue())
+					Expect(script.Content).To(ContainSubstring("--level=1"))
+				}
+			})
 			DescribeTable(
 				"should merge custom user data",
 				func(inputFile *string, mergedFile string) {
@@ -1910,6 +2076,17 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				ExpectNotScheduled(ctx, env.Client, pod)
 				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
 			})
+			It("should fail if no amis are owned by an allowed account.", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AllowedAMIOwners: []string{"123456789012"}}))
+				awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+					{Name: aws.String(coretest.RandomName()), ImageId: aws.String("ami-123"), OwnerId: aws.String("111122223333"), Architecture: aws.String("x86_64"), CreationDate: aws.String("2022-01-01T12:00:00Z")}}})
+				nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{ID: "ami-123"}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
+			})
 			It("should fail if no instanceType matches ami requirements.", func() {
 				awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
 					{Name: aws.String(coretest.RandomName()), ImageId: aws.String("ami-123"), Architecture: aws.String("newnew"), CreationDate: aws.String("2022-01-01T12:00:00Z")}}})
@@ -1987,6 +2164,99 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				Entry("AssociatePublicIPAddress is true (EFA)", true, true, true),
 				Entry("AssociatePublicIPAddress is false (EFA)", false, false, true),
 			)
+			Context("Wavelength Zones", func() {
+				BeforeEach(func() {
+					awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
+						{ZoneName: aws.String("test-zone-1a"), ZoneId: aws.String("testzone1a"), ZoneType: aws.String("wavelength-zone"), NetworkBorderGroup: aws.String("test-zone-1-wl1")},
+						{ZoneName: aws.String("test-zone-1b"), ZoneId: aws.String("testzone1b"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+						{ZoneName: aws.String("test-zone-1c"), ZoneId: aws.String("testzone1c"), ZoneType: aws.String("availability-zone"), NetworkBorderGroup: aws.String("test-zone-1")},
+						{ZoneName: aws.String("test-zone-1a-local"), ZoneId: aws.String("testzone1alocal"), ZoneType: aws.String("local-zone"), NetworkBorderGroup: aws.String("test-zone-1-lz1")},
+					}})
+					nodeClass.Spec.AssociatePublicIPAddress = lo.ToPtr(true)
+				})
+				It("should request a carrier IP rather than a public IP when launching into a Wavelength Zone", func() {
+					nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+						NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1a"}},
+					})
+					ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+					pod := coretest.UnschedulablePod()
+					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+					ExpectScheduled(ctx, env.Client, pod)
+					input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+					Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+					Expect(aws.BoolValue(input.LaunchTemplateData.NetworkInterfaces[0].AssociateCarrierIpAddress)).To(BeTrue())
+					Expect(input.LaunchTemplateData.NetworkInterfaces[0].AssociatePublicIpAddress).To(BeNil())
+				})
+				It("should request a public IP when launching outside of Wavelength Zones", func() {
+					nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+						NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1b"}},
+					})
+					ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+					pod := coretest.UnschedulablePod()
+					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+					ExpectScheduled(ctx, env.Client, pod)
+					input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+					Expect(aws.BoolValue(input.LaunchTemplateData.NetworkInterfaces[0].AssociatePublicIpAddress)).To(BeTrue())
+					Expect(input.LaunchTemplateData.NetworkInterfaces[0].AssociateCarrierIpAddress).To(BeNil())
+				})
+			})
+		})
+		Context("IPv6 Prefixes and Addresses", func() {
+			It("should not assign IPv6 prefixes or addresses by default", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				for _, networkInterface := range input.LaunchTemplateData.NetworkInterfaces {
+					Expect(networkInterface.Ipv6PrefixCount).To(BeNil())
+					Expect(networkInterface.Ipv6AddressCount).To(BeNil())
+				}
+			})
+			It("should assign IPv6 prefixes to the primary network interface", func() {
+				nodeClass.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](2)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+				Expect(*input.LaunchTemplateData.NetworkInterfaces[0].DeviceIndex).To(BeNumerically("==", 0))
+				Expect(*input.LaunchTemplateData.NetworkInterfaces[0].Ipv6PrefixCount).To(BeNumerically("==", 2))
+				Expect(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6AddressCount).To(BeNil())
+			})
+			It("should assign IPv6 addresses to the primary network interface", func() {
+				nodeClass.Spec.IPv6 = &v1beta1.IPv6{AddressCount: lo.ToPtr[int64](4)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+				Expect(*input.LaunchTemplateData.NetworkInterfaces[0].Ipv6AddressCount).To(BeNumerically("==", 4))
+				Expect(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6PrefixCount).To(BeNil())
+			})
+			It("should only assign IPv6 prefixes to the primary EFA network interface", func() {
+				nodeClass.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](1)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1beta1.ResourceEFA: resource.MustParse("2")},
+						Limits:   v1.ResourceList{v1beta1.ResourceEFA: resource.MustParse("2")},
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).ToNot(BeEmpty())
+				for _, networkInterface := range input.LaunchTemplateData.NetworkInterfaces {
+					if *networkInterface.DeviceIndex == 0 && *networkInterface.NetworkCardIndex == 0 {
+						Expect(*networkInterface.Ipv6PrefixCount).To(BeNumerically("==", 1))
+					} else {
+						Expect(networkInterface.Ipv6PrefixCount).To(BeNil())
+					}
+				}
+			})
 		})
 		Context("Kubelet Args", func() {
 			It("should specify the --dns-cluster-ip flag when clusterDNSIP is set", func() {
@@ -2037,6 +2307,127 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				Expect(err).To(BeNil())
 				ExpectLaunchTemplatesCreatedWithUserData(fmt.Sprintf(string(content), corev1beta1.NodePoolLabelKey, nodePool.Name))
 			})
+			It("should bootstrap Windows Server 2025 nodes", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyWindows2025
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(nodePool), nodePool)).To(Succeed())
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					NodeSelector: map[string]string{
+						v1.LabelOSStable:     string(v1.Windows),
+						v1.LabelWindowsBuild: v1beta1.Windows2025Build,
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				content, err := os.ReadFile("testdata/windows_userdata_unmerged.golden")
+				Expect(err).To(BeNil())
+				ExpectLaunchTemplatesCreatedWithUserData(fmt.Sprintf(string(content), corev1beta1.NodePoolLabelKey, nodePool.Name))
+			})
+		})
+	})
+	Context("Node Naming", func() {
+		It("should name nodes after their private DNS name by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--hostname-override", "INSTANCE_ID")
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions).To(BeNil())
+			})
+		})
+		It("should launch instances with resource-based hostnames when using the resource-name convention", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeNameConvention: lo.ToPtr("resource-name")}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--hostname-override")
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.PrivateDnsNameOptions.HostnameType)).To(Equal(ec2.HostnameTypeResourceName))
+			})
+		})
+		Context("Template", func() {
+			BeforeEach(func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					NodeNameConvention: lo.ToPtr("template"),
+					NodeNameTemplate:   lo.ToPtr("{{ .ClusterName }}-{{ .NodePool }}-{{ .InstanceID }}"),
+				}))
+			})
+			It("should override the hostname from the bootstrap script (AL2)", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					`INSTANCE_ID=$(curl -s -H "X-aws-ec2-metadata-token: ${TOKEN}" "http://169.254.169.254/latest/meta-data/instance-id")`,
+					fmt.Sprintf(`'"--hostname-override=test-cluster-%s-${INSTANCE_ID}"`, nodePool.Name),
+				)
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions).To(BeNil())
+				})
+			})
+			It("should override the hostname from the bootstrap script (Ubuntu)", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyUbuntu
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					"INSTANCE_ID=$(curl",
+					fmt.Sprintf(`"--hostname-override=test-cluster-%s-${INSTANCE_ID}"`, nodePool.Name),
+				)
+			})
+			It("should override the hostname from a kubelet drop-in (AL2023)", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
+				awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					"INSTANCE_ID=$(curl",
+					"cat <<EOF > /etc/systemd/system/kubelet.service.d/90-karpenter-node-name.conf",
+					fmt.Sprintf(`ExecStart=/usr/bin/kubelet \$NODEADM_KUBELET_ARGS --hostname-override=test-cluster-%s-${INSTANCE_ID}`, nodePool.Name),
+				)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+					Expect(configs).To(HaveLen(1))
+					Expect(configs[0].Spec.Kubelet.Flags).ToNot(ContainElement(ContainSubstring("--hostname-override")))
+				}
+			})
+			It("should render the node name with the NodePool the node is launched for", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					NodeNameConvention: lo.ToPtr("template"),
+					NodeNameTemplate:   lo.ToPtr("{{ .NodePool }}-{{ .InstanceID }}"),
+				}))
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(fmt.Sprintf(`"--hostname-override=%s-${INSTANCE_ID}"`, nodePool.Name))
+			})
+			It("should fail to launch Bottlerocket nodes", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
+			})
+			It("should not change the user data of Custom nodes", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyCustom
+				nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho custom")
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--hostname-override", "INSTANCE_ID")
+			})
 		})
 	})
 	Context("Detailed Monitoring", func() {
@@ -2064,6 +2455,149 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			})
 		})
 	})
+	Context("Placement", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribePlacementGroupsOutput.Set(&ec2.DescribePlacementGroupsOutput{
+				PlacementGroups: []*ec2.PlacementGroup{
+					{GroupName: aws.String("test-cluster-pg"), Strategy: aws.String(ec2.PlacementStrategyCluster)},
+					{GroupName: aws.String("test-partition-pg"), Strategy: aws.String(ec2.PlacementStrategyPartition)},
+				},
+			})
+		})
+		It("should not set placement on the launch template by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.Placement).To(BeNil())
+			})
+		})
+		It("should pass the placement group to the launch template at creation", func() {
+			nodeClass.Spec.Placement = &v1beta1.Placement{GroupName: "test-cluster-pg"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.GroupName)).To(Equal("test-cluster-pg"))
+				Expect(ltInput.LaunchTemplateData.Placement.PartitionNumber).To(BeNil())
+			})
+		})
+		It("should pass the partition number to the launch template at creation", func() {
+			nodeClass.Spec.Placement = &v1beta1.Placement{GroupName: "test-partition-pg", PartitionNumber: aws.Int64(2)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.GroupName)).To(Equal("test-partition-pg"))
+				Expect(aws.Int64Value(ltInput.LaunchTemplateData.Placement.PartitionNumber)).To(BeNumerically("==", 2))
+			})
+		})
+		It("should generate different launch template names based on placement", func() {
+			launchtemplates := []*amifamily.LaunchTemplate{
+				{},
+				{Placement: &v1beta1.Placement{GroupName: "test-cluster-pg"}},
+				{Placement: &v1beta1.Placement{GroupName: "test-partition-pg", PartitionNumber: aws.Int64(1)}},
+				{Placement: &v1beta1.Placement{GroupName: "test-partition-pg", PartitionNumber: aws.Int64(2)}},
+			}
+			launchtemplateResult := lo.Map(launchtemplates, func(lt *amifamily.LaunchTemplate, _ int) string { return launchtemplate.LaunchTemplateName(lt) })
+			Expect(lo.Uniq(launchtemplateResult)).To(HaveLen(4))
+		})
+	})
+	Context("Tenancy", func() {
+		It("should pass dedicated tenancy to the launch template at creation", func() {
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyDedicated)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.Tenancy)).To(Equal(ec2.TenancyDedicated))
+				Expect(ltInput.LaunchTemplateData.Placement.GroupName).To(BeNil())
+				Expect(ltInput.LaunchTemplateData.Placement.HostId).To(BeNil())
+			})
+		})
+		It("should pass the host ID to the launch template at creation", func() {
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyHost)
+			nodeClass.Spec.HostPlacement = &v1beta1.HostPlacement{HostID: aws.String("h-0123456789abcdef0")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.Tenancy)).To(Equal(ec2.TenancyHost))
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.HostId)).To(Equal("h-0123456789abcdef0"))
+				Expect(ltInput.LaunchTemplateData.Placement.HostResourceGroupArn).To(BeNil())
+			})
+		})
+		It("should pass the host resource group to the launch template at creation", func() {
+			arn := "arn:aws:resource-groups:us-west-2:123456789012:group/test-host-group"
+			nodeClass.Spec.Tenancy = aws.String(ec2.TenancyHost)
+			nodeClass.Spec.HostPlacement = &v1beta1.HostPlacement{HostResourceGroupARN: aws.String(arn)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.Tenancy)).To(Equal(ec2.TenancyHost))
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.Placement.HostResourceGroupArn)).To(Equal(arn))
+				Expect(ltInput.LaunchTemplateData.Placement.HostId).To(BeNil())
+			})
+		})
+		It("should generate different launch template names based on tenancy", func() {
+			launchtemplates := []*amifamily.LaunchTemplate{
+				{},
+				{Tenancy: aws.String(ec2.TenancyDedicated)},
+				{Tenancy: aws.String(ec2.TenancyHost)},
+				{Tenancy: aws.String(ec2.TenancyHost), HostPlacement: &v1beta1.HostPlacement{HostID: aws.String("h-0123456789abcdef0")}},
+			}
+			launchtemplateResult := lo.Map(launchtemplates, func(lt *amifamily.LaunchTemplate, _ int) string { return launchtemplate.LaunchTemplateName(lt) })
+			Expect(lo.Uniq(launchtemplateResult)).To(HaveLen(4))
+		})
+	})
+	Context("Instance Market Options", func() {
+		BeforeEach(func() {
+			nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{
+					NodeSelectorRequirement: v1.NodeSelectorRequirement{
+						Key:      corev1beta1.CapacityTypeLabelKey,
+						Operator: v1.NodeSelectorOpIn,
+						Values:   []string{corev1beta1.CapacityTypeSpot},
+					},
+				},
+			}
+		})
+		It("should not set instance market options on spot launch templates", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.InstanceMarketOptions).To(BeNil())
+				Expect(ltInput.LaunchTemplateData.HibernationOptions).To(BeNil())
+			})
+		})
+		It("should not set instance market options on on-demand launch templates", func() {
+			nodePool.Spec.Template.Spec.Requirements[0].Values = []string{corev1beta1.CapacityTypeOnDemand}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.InstanceMarketOptions).To(BeNil())
+			})
+		})
+	})
 })
 
 // ExpectTags verifies that the expected tags are a subset of the tags found
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementgroup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/patrickmn/go-cache"
+	"knative.dev/pkg/logging"
+
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+type Provider interface {
+	Get(context.Context, *v1beta1.EC2NodeClass) (*ec2.PlacementGroup, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	ec2api ec2iface.EC2API
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+}
+
+func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+		cm:     pretty.NewChangeMonitor(),
+		cache:  cache,
+	}
+}
+
+// Get returns the placement group referenced by the nodeclass, or nil if the nodeclass doesn't configure one
+func (p *DefaultProvider) Get(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (*ec2.PlacementGroup, error) {
+	if nodeClass.Spec.Placement == nil {
+		return nil, nil
+	}
+	p.Lock()
+	defer p.Unlock()
+
+	name := nodeClass.Spec.Placement.GroupName
+	if pg, ok := p.cache.Get(name); ok {
+		return pg.(*ec2.PlacementGroup), nil
+	}
+	output, err := p.ec2api.DescribePlacementGroupsWithContext(ctx, &ec2.DescribePlacementGroupsInput{
+		GroupNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing placement group %q, %w", name, err)
+	}
+	if len(output.PlacementGroups) != 1 {
+		return nil, fmt.Errorf("expected to find one placement group %q, but found %d", name, len(output.PlacementGroups))
+	}
+	placementGroup := output.PlacementGroups[0]
+	p.cache.SetDefault(name, placementGroup)
+	if p.cm.HasChanged(fmt.Sprintf("placement-group/%s", nodeClass.Name), placementGroup) {
+		logging.FromContext(ctx).
+			With("placement-group", name, "strategy", aws.StringValue(placementGroup.Strategy)).
+			Debugf("discovered placement group")
+	}
+	return placementGroup, nil
+}
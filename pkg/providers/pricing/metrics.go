@@ -0,0 +1,53 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	pricingSubsystem  = "pricing"
+	workerLabel       = "worker"
+	capacityTypeLabel = "capacity_type"
+)
+
+var (
+	activeWorkers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: pricingSubsystem,
+			Name:      "active_workers",
+			Help:      "Number of pricing updates currently in progress. Labeled by the pricing data being updated.",
+		},
+		[]string{workerLabel},
+	)
+	priceStaleness = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: pricingSubsystem,
+			Name:      "staleness_seconds",
+			Help:      "Time since prices were last updated, as of the most recent pricing update. Labeled by capacity type.",
+		},
+		[]string{capacityTypeLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(activeWorkers, priceStaleness)
+}
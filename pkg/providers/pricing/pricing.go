@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,15 +30,19 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/pricing"
 	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
@@ -49,26 +55,61 @@ type Provider interface {
 	SpotPrice(string, string) (float64, bool)
 	UpdateOnDemandPricing(context.Context) error
 	UpdateSpotPricing(context.Context) error
+	UpdateOverrides(context.Context) error
+	UpdatedAt(string) time.Time
+	Invalidate()
 }
 
+// OnDemandRefreshInterval is how often on-demand prices are updated from the pricing API
+const OnDemandRefreshInterval = 12 * time.Hour
+
 // DefaultProvider provides actual pricing data to the AWS cloud provider to allow it to make more informed decisions
 // regarding which instances to launch.  This is initialized at startup with a periodically updated static price list to
 // support running in locations where pricing data is unavailable.  In those cases the static pricing data provides a
 // relative ordering that is still more accurate than our previous pricing model.  In the event that a pricing update
 // fails, the previous pricing information is retained and used which may be the static initial pricing data if pricing
 // updates never succeed.
+//
+// The provider doesn't schedule its own updates. UpdateOnDemandPricing and UpdateSpotPricing are driven by the pricing
+// controller, and any work they start finishes before they return.
 type DefaultProvider struct {
 	ec2     ec2iface.EC2API
 	pricing pricingiface.PricingAPI
 	region  string
 	cm      *pretty.ChangeMonitor
+	clk     clock.Clock
+
+	// pricingAPIAvailable is false for partitions without an AWS pricing API endpoint, which are only ever priced from
+	// the static price list
+	pricingAPIAvailable bool
+
+	// ctx is canceled when the provider is stopped, aborting any in-flight updates
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	muOnDemand              sync.RWMutex
+	onDemandPrices          map[string]float64
+	onDemandPricesUpdatedAt time.Time
+	// overrides are the on-demand prices from the pricing override file, which take precedence over onDemandPrices
+	overrides map[string]float64
+
+	muSpot              sync.RWMutex
+	spotPrices          map[string]zonal
+	spotPricingUpdated  bool
+	spotPricesUpdatedAt time.Time
+	// spotPricesTimestamp is the time the newest known spot price took effect, which subsequent updates request
+	// prices from
+	spotPricesTimestamp time.Time
+	// spotOverrides are the spot prices by instance type and zone from the pricing override file, which take
+	// precedence over spotPrices
+	spotOverrides map[string]map[string]float64
+}
 
-	muOnDemand     sync.RWMutex
-	onDemandPrices map[string]float64
-
-	muSpot             sync.RWMutex
-	spotPrices         map[string]zonal
-	spotPricingUpdated bool
+// overrideFile is the structured format of the pricing override file. Files that map instance types straight to their
+// on-demand price are also accepted.
+type overrideFile struct {
+	OnDemand map[string]float64            `json:"onDemand,omitempty"`
+	Spot     map[string]map[string]float64 `json:"spot,omitempty"`
 }
 
 // zonalPricing is used to capture the per-zone price
@@ -77,12 +118,19 @@ type DefaultProvider struct {
 // comes up
 type zonal struct {
 	defaultPrice float64 // Used until we get the spot pricing data
-	prices       map[string]float64
+	prices       map[string]spotPrice
+}
+
+// spotPrice is the spot price of an instance type in a zone
+type spotPrice struct {
+	price     float64
+	timestamp time.Time // when EC2 reports that the price took effect
+	observed  time.Time // when DescribeSpotPriceHistory last reported the price
 }
 
 func newZonalPricing(defaultPrice float64) zonal {
 	z := zonal{
-		prices: map[string]float64{},
+		prices: map[string]spotPrice{},
 	}
 	z.defaultPrice = defaultPrice
 	return z
@@ -93,64 +141,124 @@ func NewAPI(sess *session.Session, region string) pricingiface.PricingAPI {
 	if sess == nil {
 		return nil
 	}
-	// pricing API doesn't have an endpoint in all regions
-	pricingAPIRegion := "us-east-1"
-	if strings.HasPrefix(region, "ap-") {
-		pricingAPIRegion = "ap-south-1"
-	} else if strings.HasPrefix(region, "cn-") {
-		pricingAPIRegion = "cn-northwest-1"
-	} else if strings.HasPrefix(region, "eu-") {
-		pricingAPIRegion = "eu-central-1"
+	return pricing.New(sess, &aws.Config{Region: aws.String(APIRegion(region))})
+}
+
+// APIRegion returns the region of the pricing API endpoint that prices a region. The pricing API only has endpoints in
+// a few regions of each partition that has it, so regions are priced by the endpoint in the same partition and, in the
+// aws partition, the nearest geography.
+func APIRegion(region string) string {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok && partition.ID() != endpoints.AwsPartitionID {
+		if service, ok := partition.Services()[pricing.EndpointsID]; ok {
+			if regions := lo.Keys(service.Regions()); len(regions) > 0 {
+				sort.Strings(regions)
+				return regions[0]
+			}
+		}
+	}
+	switch {
+	case strings.HasPrefix(region, "ap-"):
+		return "ap-south-1"
+	case strings.HasPrefix(region, "eu-"):
+		return "eu-central-1"
+	default:
+		return "us-east-1"
+	}
+}
+
+// APIAvailable returns whether the partition of a region has an AWS pricing API endpoint. Regions that aren't
+// in a known partition are assumed to have one.
+func APIAvailable(region string) bool {
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return true
 	}
-	return pricing.New(sess, &aws.Config{Region: aws.String(pricingAPIRegion)})
+	_, ok = partition.Services()[pricing.EndpointsID]
+	return ok
 }
 
-func NewDefaultProvider(_ context.Context, pricing pricingiface.PricingAPI, ec2Api ec2iface.EC2API, region string) *DefaultProvider {
+func NewDefaultProvider(ctx context.Context, pricing pricingiface.PricingAPI, ec2Api ec2iface.EC2API, region string, clk clock.Clock) *DefaultProvider {
 	p := &DefaultProvider{
-		region:  region,
-		ec2:     ec2Api,
-		pricing: pricing,
-		cm:      pretty.NewChangeMonitor(),
+		region:              region,
+		ec2:                 ec2Api,
+		pricing:             pricing,
+		cm:                  pretty.NewChangeMonitor(),
+		clk:                 clk,
+		pricingAPIAvailable: APIAvailable(region),
 	}
+	p.ctx, p.cancel = context.WithCancel(ctx)
 	// sets the pricing data from the static default state for the provider
 	p.Reset()
 
 	return p
 }
 
+// Stop aborts any in-flight pricing updates and causes subsequent updates to fail. The last known prices continue to
+// be served.
+func (p *DefaultProvider) Stop() {
+	p.cancel()
+}
+
+// withLifecycle returns a context that is canceled when either the passed context is canceled or the provider is stopped
+func (p *DefaultProvider) withLifecycle(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if err := p.ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("pricing provider stopped, %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(p.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// track records the worker as active in the active workers metric for the duration of f
+func track(worker string, f func()) {
+	activeWorkers.With(prometheus.Labels{workerLabel: worker}).Inc()
+	defer activeWorkers.With(prometheus.Labels{workerLabel: worker}).Dec()
+	f()
+}
+
 // InstanceTypes returns the list of all instance types for which either a spot or on-demand price is known.
 func (p *DefaultProvider) InstanceTypes() []string {
 	p.muOnDemand.RLock()
 	p.muSpot.RLock()
 	defer p.muOnDemand.RUnlock()
 	defer p.muSpot.RUnlock()
-	return lo.Union(lo.Keys(p.onDemandPrices), lo.Keys(p.spotPrices))
+	return lo.Union(lo.Keys(p.onDemandPrices), lo.Keys(p.overrides), lo.Keys(p.spotPrices), lo.Keys(p.spotOverrides))
 }
 
 // OnDemandPrice returns the last known on-demand price for a given instance type, returning an error if there is no
-// known on-demand pricing for the instance type.
+// known on-demand pricing for the instance type. Prices from the pricing override file are returned as-is.
 func (p *DefaultProvider) OnDemandPrice(instanceType string) (float64, bool) {
 	p.muOnDemand.RLock()
 	defer p.muOnDemand.RUnlock()
+	if price, ok := p.overrides[instanceType]; ok {
+		return price, true
+	}
 	price, ok := p.onDemandPrices[instanceType]
 	if !ok {
 		return 0.0, false
 	}
-	// account for 28% compute savings plan for on-demand instances	
+	// account for 28% compute savings plan for on-demand instances
 	return price * 0.72, true
 }
 
 // SpotPrice returns the last known spot price for a given instance type and zone, returning an error
-// if there is no known spot pricing for that instance type or zone
+// if there is no known spot pricing for that instance type or zone. Prices from the pricing override file are returned
+// as-is.
 func (p *DefaultProvider) SpotPrice(instanceType string, zone string) (float64, bool) {
 	p.muSpot.RLock()
 	defer p.muSpot.RUnlock()
+	if price, ok := p.spotOverrides[instanceType][zone]; ok {
+		return price, true
+	}
 	if val, ok := p.spotPrices[instanceType]; ok {
 		if !p.spotPricingUpdated {
 			return val.defaultPrice, true
 		}
 		if price, ok := p.spotPrices[instanceType].prices[zone]; ok {
-			return price, true
+			return price.price, true
 		}
 		return 0.0, false
 	}
@@ -162,6 +270,11 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	var wg sync.WaitGroup
 	var onDemandPrices, onDemandMetalPrices map[string]float64
 	var onDemandErr, onDemandMetalErr error
+	defer func() {
+		p.muOnDemand.RLock()
+		defer p.muOnDemand.RUnlock()
+		p.recordStaleness(corev1beta1.CapacityTypeOnDemand, p.onDemandPricesUpdatedAt)
+	}()
 
 	// if we are in isolated vpc, skip updating on demand pricing
 	// as pricing api may not be available
@@ -169,49 +282,63 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 		if p.cm.HasChanged("on-demand-prices", nil) {
 			logging.FromContext(ctx).Debug("running in an isolated VPC, on-demand pricing information will not be updated")
 		}
+		p.markOnDemandPricesCurrent()
+		return nil
+	}
+	if !p.pricingAPIAvailable {
+		if p.cm.HasChanged("on-demand-prices", nil) {
+			logging.FromContext(ctx).With("region", p.region).Debug("the AWS pricing API isn't available in this partition, on-demand pricing information will not be updated")
+		}
+		p.markOnDemandPricesCurrent()
 		return nil
 	}
 
-	p.muOnDemand.Lock()
-	defer p.muOnDemand.Unlock()
+	ctx, cancel, err := p.withLifecycle(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		onDemandPrices, onDemandErr = p.fetchOnDemandPricing(ctx,
-			&pricing.Filter{
-				Field: aws.String("tenancy"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String("Shared"),
-			},
-			&pricing.Filter{
-				Field: aws.String("productFamily"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String("Compute Instance"),
-			})
+		track("on-demand", func() {
+			onDemandPrices, onDemandErr = p.fetchOnDemandPricing(ctx,
+				&pricing.Filter{
+					Field: aws.String("tenancy"),
+					Type:  aws.String("TERM_MATCH"),
+					Value: aws.String("Shared"),
+				},
+				&pricing.Filter{
+					Field: aws.String("productFamily"),
+					Type:  aws.String("TERM_MATCH"),
+					Value: aws.String("Compute Instance"),
+				})
+		})
 	}()
 
 	// bare metal on-demand prices
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		onDemandMetalPrices, onDemandMetalErr = p.fetchOnDemandPricing(ctx,
-			&pricing.Filter{
-				Field: aws.String("tenancy"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String("Dedicated"),
-			},
-			&pricing.Filter{
-				Field: aws.String("productFamily"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String("Compute Instance (bare metal)"),
-			})
+		track("on-demand-metal", func() {
+			onDemandMetalPrices, onDemandMetalErr = p.fetchOnDemandPricing(ctx,
+				&pricing.Filter{
+					Field: aws.String("tenancy"),
+					Type:  aws.String("TERM_MATCH"),
+					Value: aws.String("Dedicated"),
+				},
+				&pricing.Filter{
+					Field: aws.String("productFamily"),
+					Type:  aws.String("TERM_MATCH"),
+					Value: aws.String("Compute Instance (bare metal)"),
+				})
+		})
 	}()
 
 	wg.Wait()
 
-	err := multierr.Append(onDemandErr, onDemandMetalErr)
-	if err != nil {
+	if err := multierr.Append(onDemandErr, onDemandMetalErr); err != nil {
 		return fmt.Errorf("retreiving on-demand pricing data, %w", err)
 	}
 
@@ -219,13 +346,83 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 		return fmt.Errorf("no on-demand pricing found")
 	}
 
+	// prices are only locked once they've been fetched so that lookups aren't blocked on the pricing API
+	p.muOnDemand.Lock()
+	defer p.muOnDemand.Unlock()
 	p.onDemandPrices = lo.Assign(onDemandPrices, onDemandMetalPrices)
+	p.onDemandPricesUpdatedAt = p.clk.Now()
 	if p.cm.HasChanged("on-demand-prices", p.onDemandPrices) {
 		logging.FromContext(ctx).With("instance-type-count", len(p.onDemandPrices)).Debugf("updated on-demand pricing")
 	}
 	return nil
 }
 
+// UpdateOverrides re-reads the on-demand and spot prices in the pricing override file, if one is configured. The
+// previous overrides are kept if the file can't be read or parsed.
+func (p *DefaultProvider) UpdateOverrides(ctx context.Context) error {
+	path := options.FromContext(ctx).PricingOverrideFile
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading pricing override file, %w", err)
+	}
+	overrides, err := parseOverrides(data)
+	if err != nil {
+		return fmt.Errorf("parsing pricing override file %q, %w", path, err)
+	}
+
+	p.muOnDemand.Lock()
+	p.overrides = overrides.OnDemand
+	if p.cm.HasChanged("on-demand-price-overrides", p.overrides) {
+		logging.FromContext(ctx).With("instance-type-count", len(p.overrides)).Debugf("updated on-demand pricing overrides")
+	}
+	p.muOnDemand.Unlock()
+
+	p.muSpot.Lock()
+	defer p.muSpot.Unlock()
+	p.spotOverrides = overrides.Spot
+	if p.cm.HasChanged("spot-price-overrides", p.spotOverrides) {
+		logging.FromContext(ctx).With("instance-type-count", len(p.spotOverrides)).Debugf("updated spot pricing overrides")
+	}
+	return nil
+}
+
+// parseOverrides parses the pricing override file, which is either an overrideFile or, as before spot prices could be
+// overridden, a map of instance types to their on-demand price
+func parseOverrides(data []byte) (overrideFile, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return overrideFile{}, err
+	}
+	overrides := overrideFile{}
+	_, onDemand := fields["onDemand"]
+	_, spot := fields["spot"]
+	if onDemand || spot {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&overrides); err != nil {
+			return overrideFile{}, err
+		}
+	} else if err := json.Unmarshal(data, &overrides.OnDemand); err != nil {
+		return overrideFile{}, err
+	}
+	for instanceType, price := range overrides.OnDemand {
+		if price < 0 {
+			return overrideFile{}, fmt.Errorf("price of %q cannot be negative", instanceType)
+		}
+	}
+	for instanceType, zones := range overrides.Spot {
+		for zone, price := range zones {
+			if price < 0 {
+				return overrideFile{}, fmt.Errorf("spot price of %q in %q cannot be negative", instanceType, zone)
+			}
+		}
+	}
+	return overrides, nil
+}
+
 func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFilters ...*pricing.Filter) (map[string]float64, error) {
 	prices := map[string]float64{}
 	filters := append([]*pricing.Filter{
@@ -276,11 +473,11 @@ func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFi
 	return prices, nil
 }
 
-func (p *DefaultProvider) spotPage(ctx context.Context, prices map[string]map[string]float64) func(output *ec2.DescribeSpotPriceHistoryOutput, b bool) bool {
+func (p *DefaultProvider) spotPage(ctx context.Context, prices map[string]map[string]spotPrice) func(output *ec2.DescribeSpotPriceHistoryOutput, b bool) bool {
 	return func(output *ec2.DescribeSpotPriceHistoryOutput, b bool) bool {
 		for _, sph := range output.SpotPriceHistory {
 			spotPriceStr := aws.StringValue(sph.SpotPrice)
-			spotPrice, err := strconv.ParseFloat(spotPriceStr, 64)
+			price, err := strconv.ParseFloat(spotPriceStr, 64)
 			// these errors shouldn't occur, but if pricing API does have an error, we ignore the record
 			if err != nil {
 				logging.FromContext(ctx).Debugf("unable to parse price record %#v", sph)
@@ -293,9 +490,13 @@ func (p *DefaultProvider) spotPage(ctx context.Context, prices map[string]map[st
 			az := aws.StringValue(sph.AvailabilityZone)
 			_, ok := prices[instanceType]
 			if !ok {
-				prices[instanceType] = map[string]float64{}
+				prices[instanceType] = map[string]spotPrice{}
+			}
+			// the history may hold several prices for an offering, of which only the newest is still in effect
+			if existing, ok := prices[instanceType][az]; ok && existing.timestamp.After(*sph.Timestamp) {
+				continue
 			}
-			prices[instanceType][az] = spotPrice
+			prices[instanceType][az] = spotPrice{price: price, timestamp: *sph.Timestamp}
 		}
 		return true
 	}
@@ -354,59 +555,154 @@ func (p *DefaultProvider) onDemandPage(ctx context.Context, prices map[string]fl
 	}
 }
 
+// UpdateSpotPricing requests the spot prices that took effect since the newest known spot price and merges them into
+// the known prices. DescribeSpotPriceHistory also reports the price in effect at the start time, so every offering that
+// still exists is reported on each update and offerings that aren't reported within the spot price TTL are evicted.
 // nolint: gocyclo
 func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
-	prices := map[string]map[string]float64{}
+	prices := map[string]map[string]spotPrice{}
+	defer func() {
+		p.muSpot.RLock()
+		defer p.muSpot.RUnlock()
+		p.recordStaleness(corev1beta1.CapacityTypeSpot, p.spotPricesUpdatedAt)
+	}()
 
-	p.muSpot.Lock()
-	defer p.muSpot.Unlock()
-	err := p.ec2.DescribeSpotPriceHistoryPagesWithContext(
-		ctx,
-		&ec2.DescribeSpotPriceHistoryInput{
-			ProductDescriptions: []*string{
-				aws.String("Linux/UNIX"),
-				aws.String("Linux/UNIX (Amazon VPC)"),
-			},
-			// get the latest spot price for each instance type
-			StartTime: aws.Time(time.Now()),
-		},
-		p.spotPage(ctx, prices),
-	)
+	ctx, cancel, err := p.withLifecycle(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
 
+	p.muSpot.RLock()
+	startTime, initial := p.spotPricesTimestamp, !p.spotPricingUpdated || p.spotPricesTimestamp.IsZero()
+	p.muSpot.RUnlock()
+	if initial {
+		// get the latest spot price for each instance type
+		startTime = p.clk.Now()
+	}
+	track("spot", func() {
+		err = p.ec2.DescribeSpotPriceHistoryPagesWithContext(
+			ctx,
+			&ec2.DescribeSpotPriceHistoryInput{
+				ProductDescriptions: []*string{
+					aws.String("Linux/UNIX"),
+					aws.String("Linux/UNIX (Amazon VPC)"),
+				},
+				StartTime: aws.Time(startTime),
+			},
+			p.spotPage(ctx, prices),
+		)
+	})
 	if err != nil {
 		return fmt.Errorf("retrieving spot pricing data, %w", err)
 	}
-	if len(prices) == 0 {
+	if initial && len(prices) == 0 {
 		return fmt.Errorf("no spot pricing found")
 	}
 
-	totalOfferings := 0
+	p.muSpot.Lock()
+	defer p.muSpot.Unlock()
+
+	now := p.clk.Now()
 	for it, zoneData := range prices {
 		if _, ok := p.spotPrices[it]; !ok {
 			p.spotPrices[it] = newZonalPricing(0)
 		}
 		for zone, price := range zoneData {
+			if existing, ok := p.spotPrices[it].prices[zone]; ok && existing.timestamp.After(price.timestamp) {
+				price = existing
+			}
+			price.observed = now
 			p.spotPrices[it].prices[zone] = price
+			if price.timestamp.After(p.spotPricesTimestamp) {
+				p.spotPricesTimestamp = price.timestamp
+			}
 		}
-		totalOfferings += len(zoneData)
 	}
+	evicted := p.evictSpotPrices(ctx, now)
 
 	p.spotPricingUpdated = true
+	p.spotPricesUpdatedAt = now
 	if p.cm.HasChanged("spot-prices", p.spotPrices) {
 		logging.FromContext(ctx).With(
-			"instance-type-count", len(p.onDemandPrices),
-			"offering-count", totalOfferings).Debugf("updated spot pricing with instance types and offerings")
+			"instance-type-count", len(p.spotPrices),
+			"offering-count", lo.SumBy(lo.Values(p.spotPrices), func(z zonal) int { return len(z.prices) }),
+			"evicted-offering-count", evicted).Debugf("updated spot pricing with instance types and offerings")
 	}
 	return nil
 }
 
+// evictSpotPrices removes the spot prices that DescribeSpotPriceHistory hasn't reported within the spot price TTL,
+// returning the number of offerings evicted
+func (p *DefaultProvider) evictSpotPrices(ctx context.Context, now time.Time) int {
+	ttl := options.FromContext(ctx).SpotPriceTTL
+	if ttl == 0 {
+		return 0
+	}
+	cutoff := now.Add(-ttl)
+	evicted := 0
+	for _, z := range p.spotPrices {
+		for zone, price := range z.prices {
+			if price.observed.Before(cutoff) {
+				delete(z.prices, zone)
+				evicted++
+			}
+		}
+	}
+	return evicted
+}
+
+// Invalidate makes the next spot price update request the current price of every offering, as on startup, rather than
+// only the prices that took effect since the newest known price. The known prices are served until they're replaced,
+// so only the timestamp that updates request prices from is reset.
+func (p *DefaultProvider) Invalidate() {
+	p.muSpot.Lock()
+	defer p.muSpot.Unlock()
+	p.spotPricesTimestamp = time.Time{}
+}
+
+// markOnDemandPricesCurrent records that the static and override on-demand prices were checked. They're never updated
+// from the pricing API, so they're as current as they can be once the override file has been read.
+func (p *DefaultProvider) markOnDemandPricesCurrent() {
+	p.muOnDemand.Lock()
+	defer p.muOnDemand.Unlock()
+	p.onDemandPricesUpdatedAt = p.clk.Now()
+}
+
+// UpdatedAt returns the time that the prices of the capacity type were last updated. On-demand prices that are never
+// updated from the pricing API, in isolated VPCs and partitions without it, count as updated each time they're checked.
+func (p *DefaultProvider) UpdatedAt(capacityType string) time.Time {
+	if capacityType == corev1beta1.CapacityTypeSpot {
+		p.muSpot.RLock()
+		defer p.muSpot.RUnlock()
+		return p.spotPricesUpdatedAt
+	}
+	p.muOnDemand.RLock()
+	defer p.muOnDemand.RUnlock()
+	return p.onDemandPricesUpdatedAt
+}
+
+// recordStaleness sets the price staleness of a capacity type to the time since its prices were last updated
+func (p *DefaultProvider) recordStaleness(capacityType string, updatedAt time.Time) {
+	priceStaleness.With(prometheus.Labels{capacityTypeLabel: capacityType}).Set(p.clk.Since(updatedAt).Seconds())
+}
+
+// LivenessProbe fails if the provider is deadlocked or has no on-demand prices to serve. Partitions without the pricing
+// API are served static prices, so they don't fail it. It also fails once spot prices haven't been updated for longer
+// than spot-price-max-staleness, if set, so that a controller that can't update them is restarted.
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
-	// ensure we don't deadlock and nolint for the empty critical section
 	p.muOnDemand.Lock()
 	p.muSpot.Lock()
-	//nolint: staticcheck
-	p.muOnDemand.Unlock()
-	p.muSpot.Unlock()
+	defer p.muOnDemand.Unlock()
+	defer p.muSpot.Unlock()
+	if len(p.onDemandPrices) == 0 && len(p.overrides) == 0 {
+		return fmt.Errorf("no on-demand prices are known for region %s", p.region)
+	}
+	if maxStaleness := options.FromContext(p.ctx).SpotPriceMaxStaleness; maxStaleness > 0 {
+		if age := p.clk.Since(p.spotPricesUpdatedAt); age > maxStaleness {
+			return fmt.Errorf("spot prices were last updated %s ago, more than spot-price-max-staleness of %s", age.Round(time.Second), maxStaleness)
+		}
+	}
 	return nil
 }
 
@@ -418,16 +714,35 @@ func populateInitialSpotPricing(pricing map[string]float64) map[string]zonal {
 	return m
 }
 
-func (p *DefaultProvider) Reset() {
-	// see if we've got region specific pricing data
-	staticPricing, ok := initialOnDemandPrices[p.region]
-	if !ok {
-		// and if not, fall back to the always available us-east-1
-		staticPricing = initialOnDemandPrices["us-east-1"]
+// staticOnDemandPrices returns the static on-demand prices of a region. Regions without static prices are priced like
+// another region in their partition, since prices differ across partitions far more than across the regions of one, and
+// otherwise like the always available us-east-1.
+func staticOnDemandPrices(region string) map[string]float64 {
+	if prices, ok := initialOnDemandPrices[region]; ok {
+		return prices
 	}
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		regions := lo.Filter(lo.Keys(initialOnDemandPrices), func(r string, _ int) bool {
+			p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), r)
+			return ok && p.ID() == partition.ID()
+		})
+		if len(regions) > 0 {
+			sort.Strings(regions)
+			return initialOnDemandPrices[regions[0]]
+		}
+	}
+	return initialOnDemandPrices["us-east-1"]
+}
 
+func (p *DefaultProvider) Reset() {
+	staticPricing := staticOnDemandPrices(p.region)
 	p.onDemandPrices = staticPricing
+	p.onDemandPricesUpdatedAt = p.clk.Now()
+	p.overrides = nil
 	// default our spot pricing to the same as the on-demand pricing until a price update
 	p.spotPrices = populateInitialSpotPricing(staticPricing)
 	p.spotPricingUpdated = false
+	p.spotPricesUpdatedAt = p.clk.Now()
+	p.spotPricesTimestamp = time.Time{}
+	p.spotOverrides = nil
 }
@@ -32,8 +32,17 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 )
 
+// MaxSecurityGroups is the default quota on the number of security groups that can be attached to a network interface.
+// Launches with more security groups than this are rejected by EC2.
+const MaxSecurityGroups = 5
+
+// NotInSubnetVPCReason is the reason that SecurityGroupsReady is false when a resolved security group isn't in the VPC
+// of the resolved subnets. EC2 rejects launches with these security groups.
+const NotInSubnetVPCReason = "SecurityGroupsNotInSubnetVPC"
+
 type Provider interface {
 	List(context.Context, *v1beta1.EC2NodeClass) ([]*ec2.SecurityGroup, error)
+	Invalidate()
 }
 
 type DefaultProvider struct {
@@ -129,3 +138,10 @@ func getFilterSets(terms []v1beta1.SecurityGroupSelectorTerm) (res [][]*ec2.Filt
 	}
 	return res
 }
+
+// Invalidate drops the cached security groups so that the next List refetches them from EC2
+func (p *DefaultProvider) Invalidate() {
+	p.Lock()
+	defer p.Unlock()
+	p.cache.Flush()
+}
@@ -249,6 +249,30 @@ var _ = Describe("SecurityGroupProvider", func() {
 			},
 		}, securityGroups)
 	})
+	It("should discover security groups by wildcard names", func() {
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{
+			{GroupName: aws.String("eksctl-test-cluster-nodegroup-ng1-SG"), GroupId: aws.String("sg-test1")},
+			{GroupName: aws.String("eksctl-test-cluster-nodegroup-ng2-SG"), GroupId: aws.String("sg-test2")},
+			{GroupName: aws.String("eksctl-test-cluster-cluster-ClusterSharedNodeSecurityGroup"), GroupId: aws.String("sg-test3")},
+		}})
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
+			{
+				Name: "eksctl-test-cluster-nodegroup-*-SG",
+			},
+		}
+		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
+		Expect(err).To(BeNil())
+		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+			{
+				GroupId:   aws.String("sg-test1"),
+				GroupName: aws.String("eksctl-test-cluster-nodegroup-ng1-SG"),
+			},
+			{
+				GroupId:   aws.String("sg-test2"),
+				GroupName: aws.String("eksctl-test-cluster-nodegroup-ng2-SG"),
+			},
+		}, securityGroups)
+	})
 	It("should discover security groups by names intersected with tags", func() {
 		nodeClass.Spec.SecurityGroupSelectorTerms = []v1beta1.SecurityGroupSelectorTerm{
 			{
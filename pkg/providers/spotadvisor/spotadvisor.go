@@ -0,0 +1,159 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotadvisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"knative.dev/pkg/logging"
+
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+)
+
+const (
+	// DataURL is the public feed that backs the EC2 Spot Instance Advisor
+	DataURL = "https://spot-bid-advisor.s3.amazonaws.com/spot-advisor-data.json"
+	// operatingSystem is the operating system whose interruption frequencies are used. Interruption frequencies don't
+	// meaningfully differ between operating systems, and not every instance type is listed for Windows.
+	operatingSystem = "Linux"
+)
+
+// HTTPClient is the subset of *http.Client used to fetch the spot advisor data
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type Provider interface {
+	LivenessProbe(*http.Request) error
+	InterruptionRate(string, string) (float64, bool)
+	UpdateSpotAdvisorData(context.Context) error
+}
+
+// Data is the document served from DataURL. Each instance type in each region and operating system references one of
+// the interruption frequency ranges by its index.
+type Data struct {
+	Ranges      []Range                                 `json:"ranges"`
+	SpotAdvisor map[string]map[string]map[string]Advice `json:"spot_advisor"`
+}
+
+type Range struct {
+	Index int    `json:"index"`
+	Label string `json:"label"`
+	// Max is the upper bound of the range's interruption frequency, in percent
+	Max int `json:"max"`
+}
+
+type Advice struct {
+	Savings    int `json:"s"`
+	RangeIndex int `json:"r"`
+}
+
+// DefaultProvider serves the interruption frequencies of spot instance types from the EC2 Spot Instance Advisor. The
+// provider starts without any data and doesn't schedule its own updates. UpdateSpotAdvisorData is driven by the spot
+// advisor controller, and the last data that was fetched successfully is retained when an update fails.
+type DefaultProvider struct {
+	client HTTPClient
+	url    string
+	cm     *pretty.ChangeMonitor
+
+	mu sync.RWMutex
+	// rates maps region to instance type to interruption frequency, as a fraction between 0 and 1
+	rates map[string]map[string]float64
+}
+
+func NewDefaultProvider(client HTTPClient, url string) *DefaultProvider {
+	return &DefaultProvider{
+		client: client,
+		url:    url,
+		cm:     pretty.NewChangeMonitor(),
+	}
+}
+
+// InterruptionRate returns the upper bound of the interruption frequency range that the spot advisor places the
+// instance type in within the region, as a fraction between 0 and 1. The second return value is false if the data
+// hasn't been fetched or doesn't list the instance type.
+func (p *DefaultProvider) InterruptionRate(instanceType string, region string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	rate, ok := p.rates[region][instanceType]
+	return rate, ok
+}
+
+func (p *DefaultProvider) UpdateSpotAdvisorData(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return fmt.Errorf("creating spot advisor request, %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching spot advisor data, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching spot advisor data, unexpected status %s", resp.Status)
+	}
+	data := Data{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return fmt.Errorf("decoding spot advisor data, %w", err)
+	}
+	rangeMax := map[int]float64{}
+	for _, r := range data.Ranges {
+		rangeMax[r.Index] = float64(r.Max) / 100
+	}
+	rates := map[string]map[string]float64{}
+	entryCount := 0
+	for region, operatingSystems := range data.SpotAdvisor {
+		for instanceType, advice := range operatingSystems[operatingSystem] {
+			rate, ok := rangeMax[advice.RangeIndex]
+			if !ok {
+				continue
+			}
+			if _, ok := rates[region]; !ok {
+				rates[region] = map[string]float64{}
+			}
+			rates[region][instanceType] = rate
+			entryCount++
+		}
+	}
+	if len(rates) == 0 {
+		return fmt.Errorf("no interruption frequencies found in spot advisor data")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rates = rates
+	if p.cm.HasChanged("spot-advisor-data", p.rates) {
+		logging.FromContext(ctx).With("region-count", len(rates), "entry-count", entryCount).Debugf("updated spot advisor interruption frequencies")
+	}
+	return nil
+}
+
+func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
+	// ensure we don't deadlock and nolint for the empty critical section
+	p.mu.Lock()
+	//nolint: staticcheck
+	p.mu.Unlock()
+	return nil
+}
+
+// Reset clears the interruption frequencies so that the provider behaves as if the data was never fetched
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rates = nil
+}
@@ -18,24 +18,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
 
+// maxBatchSize is the most messages that SQS returns from a single receive or deletes in a single batch
+const maxBatchSize = 10
+
 type Provider interface {
 	Name() string
 	GetSQSMessages(context.Context) ([]*sqs.Message, error)
 	SendMessage(context.Context, interface{}) (string, error)
-	DeleteSQSMessage(context.Context, *sqs.Message) error
+	DeleteSQSMessages(context.Context, []*sqs.Message) (int, error)
+	GetSQSQueueDepth(context.Context) (int, error)
 }
 
 type DefaultProvider struct {
 	client sqsiface.SQSAPI
 
-	queueURL string
+	// queueName is set when the provider resolves the queue URL itself
+	queueName string
+	mu        sync.RWMutex
+	queueURL  string
 }
 
 func NewDefaultProvider(client sqsiface.SQSAPI, queueURL string) (*DefaultProvider, error) {
@@ -45,23 +58,58 @@ func NewDefaultProvider(client sqsiface.SQSAPI, queueURL string) (*DefaultProvid
 	}, nil
 }
 
+// NewDefaultProviderForQueue returns a provider for the named queue. The queue URL is resolved on first use, and
+// resolution is retried on each call until it succeeds, so that the queue doesn't need to be reachable on startup.
+func NewDefaultProviderForQueue(client sqsiface.SQSAPI, queueName string) *DefaultProvider {
+	return &DefaultProvider{
+		client:    client,
+		queueName: queueName,
+	}
+}
+
 func (p *DefaultProvider) Name() string {
+	if p.queueName != "" {
+		return p.queueName
+	}
 	ss := strings.Split(p.queueURL, "/")
 	return ss[len(ss)-1]
 }
 
+// url returns the queue URL, resolving it from the queue name if it isn't known yet
+func (p *DefaultProvider) url(ctx context.Context) (string, error) {
+	p.mu.RLock()
+	url := p.queueURL
+	p.mu.RUnlock()
+	if url != "" {
+		return url, nil
+	}
+	out, err := p.client.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(p.queueName)})
+	if err != nil {
+		return "", fmt.Errorf("resolving sqs queue url, %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queueURL = aws.StringValue(out.QueueUrl)
+	return p.queueURL, nil
+}
+
 func (p *DefaultProvider) GetSQSMessages(ctx context.Context) ([]*sqs.Message, error) {
+	url, err := p.url(ctx)
+	if err != nil {
+		return nil, err
+	}
 	input := &sqs.ReceiveMessageInput{
-		MaxNumberOfMessages: aws.Int64(10),
+		MaxNumberOfMessages: aws.Int64(maxBatchSize),
 		VisibilityTimeout:   aws.Int64(20), // Seconds
-		WaitTimeSeconds:     aws.Int64(20), // Seconds, maximum for long polling
+		WaitTimeSeconds:     aws.Int64(int64(options.FromContext(ctx).InterruptionQueueWaitTime.Seconds())),
 		AttributeNames: []*string{
 			aws.String(sqs.MessageSystemAttributeNameSentTimestamp),
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
 		},
 		MessageAttributeNames: []*string{
 			aws.String(sqs.QueueAttributeNameAll),
 		},
-		QueueUrl: aws.String(p.queueURL),
+		QueueUrl: aws.String(url),
 	}
 
 	result, err := p.client.ReceiveMessageWithContext(ctx, input)
@@ -77,9 +125,13 @@ func (p *DefaultProvider) SendMessage(ctx context.Context, body interface{}) (st
 	if err != nil {
 		return "", fmt.Errorf("marshaling the passed body as json, %w", err)
 	}
+	url, err := p.url(ctx)
+	if err != nil {
+		return "", err
+	}
 	input := &sqs.SendMessageInput{
 		MessageBody: aws.String(string(raw)),
-		QueueUrl:    aws.String(p.queueURL),
+		QueueUrl:    aws.String(url),
 	}
 	result, err := p.client.SendMessageWithContext(ctx, input)
 	if err != nil {
@@ -88,14 +140,56 @@ func (p *DefaultProvider) SendMessage(ctx context.Context, body interface{}) (st
 	return aws.StringValue(result.MessageId), nil
 }
 
-func (p *DefaultProvider) DeleteSQSMessage(ctx context.Context, msg *sqs.Message) error {
-	input := &sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(p.queueURL),
-		ReceiptHandle: msg.ReceiptHandle,
+// DeleteSQSMessages removes the passed messages from the queue in batches. It returns the number of messages that
+// were deleted along with an error for each message that wasn't, so that the failures are received again once their
+// visibility timeout expires.
+func (p *DefaultProvider) DeleteSQSMessages(ctx context.Context, msgs []*sqs.Message) (int, error) {
+	url, err := p.url(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	var errs error
+	for _, chunk := range lo.Chunk(msgs, maxBatchSize) {
+		input := &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(url),
+			Entries: lo.Map(chunk, func(msg *sqs.Message, i int) *sqs.DeleteMessageBatchRequestEntry {
+				return &sqs.DeleteMessageBatchRequestEntry{
+					Id:            aws.String(strconv.Itoa(i)),
+					ReceiptHandle: msg.ReceiptHandle,
+				}
+			}),
+		}
+		out, err := p.client.DeleteMessageBatchWithContext(ctx, input)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("deleting messages from sqs queue, %w", err))
+			continue
+		}
+		deleted += len(out.Successful)
+		for _, failed := range out.Failed {
+			errs = multierr.Append(errs, fmt.Errorf("deleting message from sqs queue, %s: %s", aws.StringValue(failed.Code), aws.StringValue(failed.Message)))
+		}
 	}
+	return deleted, errs
+}
 
-	if _, err := p.client.DeleteMessageWithContext(ctx, input); err != nil {
-		return fmt.Errorf("deleting messages from sqs queue, %w", err)
+// GetSQSQueueDepth returns the approximate number of messages on the queue that are available to be received
+func (p *DefaultProvider) GetSQSQueueDepth(ctx context.Context) (int, error) {
+	url, err := p.url(ctx)
+	if err != nil {
+		return 0, err
+	}
+	input := &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(url),
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages)},
+	}
+	out, err := p.client.GetQueueAttributesWithContext(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("getting sqs queue attributes, %w", err)
+	}
+	depth, err := strconv.Atoi(aws.StringValue(out.Attributes[sqs.QueueAttributeNameApproximateNumberOfMessages]))
+	if err != nil {
+		return 0, fmt.Errorf("parsing the approximate number of messages, %w", err)
 	}
-	return nil
+	return depth, nil
 }
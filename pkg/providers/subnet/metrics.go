@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subnet
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	subnetSubsystem = "subnets"
+	subnetIDLabel   = "subnet_id"
+	zoneLabel       = "zone"
+)
+
+var (
+	inflightAvailableIPs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: subnetSubsystem,
+			Name:      "inflight_available_ip_addresses",
+			Help:      "Available IP addresses of a subnet after deducting the IPs of launches that EC2 hasn't reported yet. Only subnets that have inflight launches are tracked.",
+		},
+		[]string{
+			subnetIDLabel,
+			zoneLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(inflightAvailableIPs)
+}
@@ -17,6 +17,7 @@ package subnet
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"sort"
 	"sync"
@@ -35,12 +36,17 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
+// ZoneTypeWavelengthZone is the type of the zones that are embedded in a telecommunications carrier's network
+const ZoneTypeWavelengthZone = "wavelength-zone"
+
 type Provider interface {
 	LivenessProbe(*http.Request) error
 	List(context.Context, *v1beta1.EC2NodeClass) ([]*ec2.Subnet, error)
 	CheckAnyPublicIPAssociations(context.Context, *v1beta1.EC2NodeClass) (bool, error)
+	AvailabilityZones(context.Context, []*ec2.Subnet) (map[string]*ec2.AvailabilityZone, error)
 	ZonalSubnetsForLaunch(context.Context, *v1beta1.EC2NodeClass, []*cloudprovider.InstanceType, string) (map[string]*ec2.Subnet, error)
 	UpdateInflightIPs(*ec2.CreateFleetInput, *ec2.CreateFleetOutput, []*cloudprovider.InstanceType, []*ec2.Subnet, string)
+	Invalidate()
 }
 
 type DefaultProvider struct {
@@ -49,6 +55,10 @@ type DefaultProvider struct {
 	cache       *cache.Cache
 	cm          *pretty.ChangeMonitor
 	inflightIPs map[string]int64
+	// inflightBaselines is the EC2 available IP count that each inflightIPs entry was derived from
+	inflightBaselines map[string]int64
+	// zones are the availability zones, local zones and wavelength zones that subnets have been seen in, by name
+	zones map[string]*ec2.AvailabilityZone
 }
 
 func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
@@ -59,7 +69,9 @@ func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProv
 		// Subnets are sorted on AvailableIpAddressCount, descending order
 		cache: cache,
 		// inflightIPs is used to track IPs from known launched instances
-		inflightIPs: map[string]int64{},
+		inflightIPs:       map[string]int64{},
+		inflightBaselines: map[string]int64{},
+		zones:             map[string]*ec2.AvailabilityZone{},
 	}
 }
 
@@ -75,7 +87,7 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 		return nil, err
 	}
 	if subnets, ok := p.cache.Get(fmt.Sprint(hash)); ok {
-		return subnets.([]*ec2.Subnet), nil
+		return onOutpost(subnets.([]*ec2.Subnet), nodeClass.Spec.OutpostARN), nil
 	}
 
 	// Ensure that all the subnets that are returned here are unique
@@ -87,7 +99,7 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 		}
 		for i := range output.Subnets {
 			subnets[lo.FromPtr(output.Subnets[i].SubnetId)] = output.Subnets[i]
-			delete(p.inflightIPs, lo.FromPtr(output.Subnets[i].SubnetId)) // remove any previously tracked IP addresses since we just refreshed from EC2
+			p.refreshInflightIPs(output.Subnets[i])
 		}
 	}
 	p.cache.SetDefault(fmt.Sprint(hash), lo.Values(subnets))
@@ -98,7 +110,15 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 			})).
 			Debugf("discovered subnets")
 	}
-	return lo.Values(subnets), nil
+	return onOutpost(lo.Values(subnets), nodeClass.Spec.OutpostARN), nil
+}
+
+// onOutpost returns the subnets on the Outpost, or all the subnets if no Outpost is set
+func onOutpost(subnets []*ec2.Subnet, outpostARN *string) []*ec2.Subnet {
+	if outpostARN == nil {
+		return subnets
+	}
+	return lo.Filter(subnets, func(s *ec2.Subnet, _ int) bool { return aws.StringValue(s.OutpostArn) == aws.StringValue(outpostARN) })
 }
 
 // CheckAnyPublicIPAssociations returns a bool indicating whether all referenced subnets assign public IPv4 addresses to EC2 instances created therein
@@ -113,7 +133,27 @@ func (p *DefaultProvider) CheckAnyPublicIPAssociations(ctx context.Context, node
 	return ok, nil
 }
 
-// ZonalSubnetsForLaunch returns a mapping of zone to the subnet with the most available IP addresses and deducts the passed ips from the available count
+// AvailabilityZones returns the zones that the subnets are in, by name. The zone type and network border group of a zone
+// don't change, so zones are only described again when a subnet is in a zone that hasn't been seen before.
+func (p *DefaultProvider) AvailabilityZones(ctx context.Context, subnets []*ec2.Subnet) (map[string]*ec2.AvailabilityZone, error) {
+	p.Lock()
+	defer p.Unlock()
+	names := lo.Uniq(lo.Map(subnets, func(s *ec2.Subnet, _ int) string { return aws.StringValue(s.AvailabilityZone) }))
+	if lo.SomeBy(names, func(name string) bool { _, ok := p.zones[name]; return !ok }) {
+		output, err := p.ec2api.DescribeAvailabilityZonesWithContext(ctx, &ec2.DescribeAvailabilityZonesInput{AllAvailabilityZones: aws.Bool(true)})
+		if err != nil {
+			return nil, fmt.Errorf("describing availability zones, %w", err)
+		}
+		for _, zone := range output.AvailabilityZones {
+			p.zones[aws.StringValue(zone.ZoneName)] = zone
+		}
+	}
+	return lo.PickByKeys(p.zones, names), nil
+}
+
+// ZonalSubnetsForLaunch returns a mapping of zone to the subnet to launch into and deducts the passed ips from the available count.
+// Inflight IPs are tracked by subnet ID, so they're shared between all EC2NodeClasses that select the same subnet. By default the subnet with the most available IP addresses in each zone is chosen. With the Balanced subnet selection
+// policy, subnets in the same zone are chosen at random, weighted by their available IP addresses.
 func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, instanceTypes []*cloudprovider.InstanceType, capacityType string) (map[string]*ec2.Subnet, error) {
 	subnets, err := p.List(ctx, nodeClass)
 	if err != nil {
@@ -124,34 +164,106 @@ func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *
 	}
 	p.Lock()
 	defer p.Unlock()
-	// sort subnets in ascending order of available IP addresses and populate map with most available subnet per AZ
 	zonalSubnets := map[string]*ec2.Subnet{}
-	sort.Slice(subnets, func(i, j int) bool {
-		iIPs := aws.Int64Value(subnets[i].AvailableIpAddressCount)
-		jIPs := aws.Int64Value(subnets[j].AvailableIpAddressCount)
-		// override ip count from ec2.Subnet if we've tracked launches
-		if ips, ok := p.inflightIPs[*subnets[i].SubnetId]; ok {
-			iIPs = ips
-		}
-		if ips, ok := p.inflightIPs[*subnets[j].SubnetId]; ok {
-			jIPs = ips
+	for zone, zoneSubnets := range p.launchableSubnets(subnets, instanceTypes, capacityType) {
+		if lo.FromPtr(nodeClass.Spec.SubnetSelectionPolicy) == v1beta1.SubnetSelectionPolicyBalanced {
+			zonalSubnets[zone] = p.weightedSubnet(zoneSubnets)
+			continue
 		}
-		return iIPs < jIPs
-	})
-	for _, subnet := range subnets {
-		zonalSubnets[*subnet.AvailabilityZone] = subnet
+		// populate map with the most available subnet in the zone, breaking ties by subnet ID
+		zonalSubnets[zone] = lo.MaxBy(zoneSubnets, func(a, b *ec2.Subnet) bool {
+			if p.availableIPs(a) == p.availableIPs(b) {
+				return aws.StringValue(a.SubnetId) < aws.StringValue(b.SubnetId)
+			}
+			return p.availableIPs(a) > p.availableIPs(b)
+		})
 	}
 	for _, subnet := range zonalSubnets {
 		predictedIPsUsed := p.minPods(instanceTypes, *subnet.AvailabilityZone, capacityType)
-		prevIPs := *subnet.AvailableIpAddressCount
-		if trackedIPs, ok := p.inflightIPs[*subnet.SubnetId]; ok {
-			prevIPs = trackedIPs
+		if _, ok := p.inflightIPs[*subnet.SubnetId]; !ok {
+			p.inflightBaselines[*subnet.SubnetId] = aws.Int64Value(subnet.AvailableIpAddressCount)
 		}
-		p.inflightIPs[*subnet.SubnetId] = prevIPs - predictedIPsUsed
+		p.setInflightIPs(subnet, p.availableIPs(subnet)-predictedIPsUsed)
 	}
 	return zonalSubnets, nil
 }
 
+// launchableSubnets groups the subnets by zone, leaving out the subnets that don't have enough available IPs for the
+// launch while another subnet in the zone does. Zones without any such subnet are left out while another zone that the
+// instance types are offered in has one, so that concurrent launches sharing a subnet are steered elsewhere once their
+// inflight IPs have exhausted it.
+func (p *DefaultProvider) launchableSubnets(subnets []*ec2.Subnet, instanceTypes []*cloudprovider.InstanceType, capacityType string) map[string][]*ec2.Subnet {
+	zonal := map[string][]*ec2.Subnet{}
+	exhausted := map[string][]*ec2.Subnet{}
+	offeredZoneHasCapacity := false
+	for zone, zoneSubnets := range lo.GroupBy(subnets, func(s *ec2.Subnet) string { return aws.StringValue(s.AvailabilityZone) }) {
+		predictedIPsUsed := p.minPods(instanceTypes, zone, capacityType)
+		withCapacity := lo.Filter(zoneSubnets, func(s *ec2.Subnet, _ int) bool { return p.availableIPs(s) >= predictedIPsUsed })
+		if len(withCapacity) == 0 {
+			exhausted[zone] = zoneSubnets
+			continue
+		}
+		zonal[zone] = withCapacity
+		// Zones that none of the instance types are offered in can't take the launch in place of an exhausted zone
+		offeredZoneHasCapacity = offeredZoneHasCapacity || predictedIPsUsed > 0
+	}
+	if !offeredZoneHasCapacity {
+		return lo.Assign(zonal, exhausted)
+	}
+	return zonal
+}
+
+// refreshInflightIPs stops tracking the inflight IPs of a subnet since we just refreshed it from EC2. If EC2 still reports
+// the available IP count that the tracking started from, the deducted launches haven't been reflected yet, which happens
+// when another EC2NodeClass that selects the same subnet refreshes right after a launch. The tracking is then kept until
+// the next refresh so that it can't outlive instances that were terminated before EC2 reported them.
+func (p *DefaultProvider) refreshInflightIPs(subnet *ec2.Subnet) {
+	if baseline, ok := p.inflightBaselines[*subnet.SubnetId]; ok && baseline == aws.Int64Value(subnet.AvailableIpAddressCount) {
+		delete(p.inflightBaselines, *subnet.SubnetId)
+		return
+	}
+	delete(p.inflightIPs, *subnet.SubnetId)
+	delete(p.inflightBaselines, *subnet.SubnetId)
+	inflightAvailableIPs.DeletePartialMatch(map[string]string{subnetIDLabel: *subnet.SubnetId})
+}
+
+func (p *DefaultProvider) setInflightIPs(subnet *ec2.Subnet, ips int64) {
+	p.inflightIPs[*subnet.SubnetId] = ips
+	inflightAvailableIPs.With(map[string]string{
+		subnetIDLabel: *subnet.SubnetId,
+		zoneLabel:     aws.StringValue(subnet.AvailabilityZone),
+	}).Set(float64(ips))
+}
+
+// availableIPs returns the subnet's available IP address count, overridden by the inflight count if we've tracked launches
+func (p *DefaultProvider) availableIPs(subnet *ec2.Subnet) int64 {
+	if ips, ok := p.inflightIPs[*subnet.SubnetId]; ok {
+		return ips
+	}
+	return aws.Int64Value(subnet.AvailableIpAddressCount)
+}
+
+// weightedSubnet picks one of the subnets at random with a probability proportional to its available IP addresses.
+// If none of the subnets have IP addresses available, the first subnet by ID is chosen.
+func (p *DefaultProvider) weightedSubnet(subnets []*ec2.Subnet) *ec2.Subnet {
+	sort.Slice(subnets, func(i, j int) bool {
+		return aws.StringValue(subnets[i].SubnetId) < aws.StringValue(subnets[j].SubnetId)
+	})
+	weights := lo.Map(subnets, func(s *ec2.Subnet, _ int) int64 { return lo.Max([]int64{p.availableIPs(s), 0}) })
+	total := lo.Sum(weights)
+	if total == 0 {
+		return subnets[0]
+	}
+	n := rand.Int63n(total) //nolint:gosec
+	for i, weight := range weights {
+		if n < weight {
+			return subnets[i]
+		}
+		n -= weight
+	}
+	return subnets[len(subnets)-1]
+}
+
 // UpdateInflightIPs is used to refresh the in-memory IP usage by adding back unused IPs after a CreateFleet response is returned
 func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInput, createFleetOutput *ec2.CreateFleetOutput, instanceTypes []*cloudprovider.InstanceType,
 	subnets []*ec2.Subnet, capacityType string) {
@@ -205,12 +317,28 @@ func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInp
 			// other IPs deducted were opportunistic and need to be readded since Fleet didn't pick those subnets to launch into
 			if ips, ok := p.inflightIPs[*originalSubnet.SubnetId]; ok {
 				minPods := p.minPods(instanceTypes, *originalSubnet.AvailabilityZone, capacityType)
-				p.inflightIPs[*originalSubnet.SubnetId] = ips + minPods
+				p.setInflightIPs(originalSubnet, ips+minPods)
 			}
 		}
 	}
 }
 
+// Invalidate drops the cached subnets so that the next List refetches them from EC2
+func (p *DefaultProvider) Invalidate() {
+	p.Lock()
+	defer p.Unlock()
+	p.cache.Flush()
+}
+
+// Reset stops tracking inflight IPs for all subnets and forgets the zones that they're in
+func (p *DefaultProvider) Reset() {
+	p.Lock()
+	defer p.Unlock()
+	p.inflightIPs = map[string]int64{}
+	p.inflightBaselines = map[string]int64{}
+	p.zones = map[string]*ec2.AvailabilityZone{}
+}
+
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	p.Lock()
 	//nolint: staticcheck
@@ -16,17 +16,23 @@ package subnet_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/operator/scheme"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
@@ -216,6 +222,28 @@ var _ = Describe("SubnetProvider", func() {
 				},
 			}, subnets)
 		})
+		It("should only discover subnets on the outpost when one is set", func() {
+			outpostARN := "arn:aws:outposts:us-west-2:123456789012:outpost/op-0123456789abcdef0"
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: lo.ToPtr("subnet-test1"), AvailabilityZone: lo.ToPtr("test-zone-1a"), AvailableIpAddressCount: lo.ToPtr[int64](100)},
+				{SubnetId: lo.ToPtr("subnet-test2"), AvailabilityZone: lo.ToPtr("test-zone-1a"), AvailableIpAddressCount: lo.ToPtr[int64](100), OutpostArn: lo.ToPtr(outpostARN)},
+			}})
+			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"*": "*"}}}
+			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(subnets).To(HaveLen(2))
+
+			nodeClass.Spec.OutpostARN = lo.ToPtr(outpostARN)
+			subnets, err = awsEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			ExpectConsistsOfSubnets([]*ec2.Subnet{
+				{
+					SubnetId:                lo.ToPtr("subnet-test2"),
+					AvailabilityZone:        lo.ToPtr("test-zone-1a"),
+					AvailableIpAddressCount: lo.ToPtr[int64](100),
+				},
+			}, subnets)
+		})
 	})
 	Context("CheckAnyPublicIPAssociations", func() {
 		It("should note that no subnets assign a public IPv4 address to EC2 instances on launch", func() {
@@ -240,6 +268,151 @@ var _ = Describe("SubnetProvider", func() {
 			Expect(onlyPrivate).To(BeTrue())
 		})
 	})
+	Context("ZonalSubnetsForLaunch", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-small"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(100)},
+				{SubnetId: aws.String("subnet-large"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(300)},
+				{SubnetId: aws.String("subnet-other-zone"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(100)},
+			}})
+		})
+		launchCounts := func(launches int) map[string]int {
+			GinkgoHelper()
+			counts := map[string]int{}
+			for i := 0; i < launches; i++ {
+				zonalSubnets, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, nil, corev1beta1.CapacityTypeOnDemand)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(zonalSubnets).To(HaveLen(2))
+				Expect(aws.StringValue(zonalSubnets["test-zone-1b"].SubnetId)).To(Equal("subnet-other-zone"))
+				counts[aws.StringValue(zonalSubnets["test-zone-1a"].SubnetId)]++
+			}
+			return counts
+		}
+		It("should always choose the subnet with the most available IPs by default", func() {
+			Expect(launchCounts(50)).To(Equal(map[string]int{"subnet-large": 50}))
+		})
+		It("should always choose the subnet with the most available IPs with the MostAvailableIPs policy", func() {
+			nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyMostAvailableIPs)
+			Expect(launchCounts(50)).To(Equal(map[string]int{"subnet-large": 50}))
+		})
+		It("should spread launches across same-zone subnets in proportion to available IPs with the Balanced policy", func() {
+			nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyBalanced)
+			counts := launchCounts(400)
+			Expect(counts["subnet-small"]).To(BeNumerically(">", 0))
+			Expect(counts["subnet-large"]).To(BeNumerically(">", counts["subnet-small"]))
+		})
+		It("should not choose subnets without available IPs with the Balanced policy", func() {
+			nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyBalanced)
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-small"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(0)},
+				{SubnetId: aws.String("subnet-large"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(300)},
+				{SubnetId: aws.String("subnet-other-zone"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(100)},
+			}})
+			Expect(launchCounts(50)).To(Equal(map[string]int{"subnet-large": 50}))
+		})
+	})
+	Context("Shared Subnets", func() {
+		var nodeClass2 *v1beta1.EC2NodeClass
+		var instanceTypes []*cloudprovider.InstanceType
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-shared"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(10),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("subnet-shared")}}},
+				{SubnetId: aws.String("subnet-other-zone"), AvailabilityZone: aws.String("test-zone-1b"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("subnet-other-zone")}}},
+			}})
+			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "subnet-shared"}}, {Tags: map[string]string{"Name": "subnet-other-zone"}}}
+			nodeClass2 = test.EC2NodeClass(v1beta1.EC2NodeClass{
+				Spec: v1beta1.EC2NodeClassSpec{
+					SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{{ID: "subnet-shared"}, {ID: "subnet-other-zone"}},
+				},
+			})
+			instanceTypes = []*cloudprovider.InstanceType{fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "test-instance-type",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: corev1beta1.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1, Available: true},
+					{CapacityType: corev1beta1.CapacityTypeOnDemand, Zone: "test-zone-1b", Price: 1, Available: true},
+				},
+				Resources: v1.ResourceList{v1.ResourcePods: resource.MustParse("8")},
+			})}
+		})
+		It("should steer one of two concurrent launches away from a subnet that can only fit one", func() {
+			results := make([]map[string]*ec2.Subnet, 2)
+			wg := sync.WaitGroup{}
+			for i, nc := range []*v1beta1.EC2NodeClass{nodeClass, nodeClass2} {
+				wg.Add(1)
+				go func(i int, nc *v1beta1.EC2NodeClass) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					zonalSubnets, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nc, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+					Expect(err).ToNot(HaveOccurred())
+					results[i] = zonalSubnets
+				}(i, nc)
+			}
+			wg.Wait()
+			Expect(lo.CountBy(results, func(zonalSubnets map[string]*ec2.Subnet) bool {
+				_, ok := zonalSubnets["test-zone-1a"]
+				return ok
+			})).To(Equal(1))
+			for _, zonalSubnets := range results {
+				Expect(aws.StringValue(zonalSubnets["test-zone-1b"].SubnetId)).To(Equal("subnet-other-zone"))
+			}
+		})
+		It("should keep inflight IPs when another EC2NodeClass refreshes the subnet before EC2 reports the launch", func() {
+			zonalSubnets, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zonalSubnets).To(HaveKey("test-zone-1a"))
+
+			// nodeClass2 uses different selectors, so it refreshes the shared subnet from EC2 rather than the cache
+			zonalSubnets, err = awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass2, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zonalSubnets).ToNot(HaveKey("test-zone-1a"))
+			Expect(aws.StringValue(zonalSubnets["test-zone-1b"].SubnetId)).To(Equal("subnet-other-zone"))
+		})
+		It("should launch into an exhausted subnet when no other zone has capacity", func() {
+			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "subnet-shared"}}}
+			for i := 0; i < 2; i++ {
+				zonalSubnets, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(aws.StringValue(zonalSubnets["test-zone-1a"].SubnetId)).To(Equal("subnet-shared"))
+			}
+		})
+		It("should launch into exhausted subnets when the other zones don't offer the instance types", func() {
+			instanceTypes = []*cloudprovider.InstanceType{fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "test-instance-type",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: corev1beta1.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1, Available: true},
+				},
+				Resources: v1.ResourceList{v1.ResourcePods: resource.MustParse("20")},
+			})}
+			zonalSubnets, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(zonalSubnets["test-zone-1a"].SubnetId)).To(Equal("subnet-shared"))
+		})
+		It("should give back inflight IPs for subnets that fleet didn't launch into", func() {
+			zonalSubnets, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.SubnetProvider.UpdateInflightIPs(&ec2.CreateFleetInput{LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{{
+				Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{{SubnetId: aws.String("subnet-shared")}, {SubnetId: aws.String("subnet-other-zone")}},
+			}}}, &ec2.CreateFleetOutput{Instances: []*ec2.CreateFleetInstance{{
+				LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{Overrides: &ec2.FleetLaunchTemplateOverrides{SubnetId: aws.String("subnet-other-zone")}},
+			}}}, instanceTypes, lo.Values(zonalSubnets), corev1beta1.CapacityTypeOnDemand)
+
+			zonalSubnets, err = awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass2, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(zonalSubnets["test-zone-1a"].SubnetId)).To(Equal("subnet-shared"))
+		})
+		It("should expose the inflight available IPs of each subnet", func() {
+			_, err := awsEnv.SubnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, corev1beta1.CapacityTypeOnDemand)
+			Expect(err).ToNot(HaveOccurred())
+			metric, ok := FindMetricWithLabelValues("karpenter_subnets_inflight_available_ip_addresses", map[string]string{"subnet_id": "subnet-shared", "zone": "test-zone-1a"})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 2))
+			metric, ok = FindMetricWithLabelValues("karpenter_subnets_inflight_available_ip_addresses", map[string]string{"subnet_id": "subnet-other-zone", "zone": "test-zone-1b"})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 92))
+		})
+	})
 	Context("Provider Cache", func() {
 		It("should resolve subnets from cache that are filtered by id", func() {
 			expectedSubnets := awsEnv.EC2API.DescribeSubnetsOutput.Clone().Subnets
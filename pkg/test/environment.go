@@ -21,6 +21,7 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/ptr"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
@@ -30,12 +31,16 @@ import (
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/costlimit"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/placementgroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/spotadvisor"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
@@ -51,11 +56,15 @@ func init() {
 
 type Environment struct {
 	// API
-	EC2API     *fake.EC2API
-	EKSAPI     *fake.EKSAPI
-	SSMAPI     *fake.SSMAPI
-	IAMAPI     *fake.IAMAPI
-	PricingAPI *fake.PricingAPI
+	EC2API         *fake.EC2API
+	EKSAPI         *fake.EKSAPI
+	SSMAPI         *fake.SSMAPI
+	IAMAPI         *fake.IAMAPI
+	OutpostsAPI    *fake.OutpostsAPI
+	PricingAPI     *fake.PricingAPI
+	SpotAdvisorAPI *fake.SpotAdvisorAPI
+
+	EventRecorder *coretest.EventRecorder
 
 	// Cache
 	EC2Cache                  *cache.Cache
@@ -66,18 +75,28 @@ type Environment struct {
 	SubnetCache               *cache.Cache
 	SecurityGroupCache        *cache.Cache
 	InstanceProfileCache      *cache.Cache
+	PlacementGroupCache       *cache.Cache
+	CapacityReservationCache  *cache.Cache
+	InflightLaunchCache       *cache.Cache
+	LaunchAttemptCache        *cache.Cache
+	SpotFallbackZoneCache     *cache.Cache
+	InstanceDescriptionCache  *cache.Cache
 
 	// Providers
-	InstanceTypesProvider   *instancetype.DefaultProvider
-	InstanceProvider        *instance.DefaultProvider
-	SubnetProvider          *subnet.DefaultProvider
-	SecurityGroupProvider   *securitygroup.DefaultProvider
-	InstanceProfileProvider *instanceprofile.DefaultProvider
-	PricingProvider         *pricing.DefaultProvider
-	AMIProvider             *amifamily.DefaultProvider
-	AMIResolver             *amifamily.Resolver
-	VersionProvider         *version.DefaultProvider
-	LaunchTemplateProvider  *launchtemplate.DefaultProvider
+	InstanceTypesProvider       *instancetype.DefaultProvider
+	InstanceProvider            *instance.DefaultProvider
+	SubnetProvider              *subnet.DefaultProvider
+	SecurityGroupProvider       *securitygroup.DefaultProvider
+	InstanceProfileProvider     *instanceprofile.DefaultProvider
+	PricingProvider             *pricing.DefaultProvider
+	SpotAdvisorProvider         *spotadvisor.DefaultProvider
+	CostLimitProvider           *costlimit.DefaultProvider
+	AMIProvider                 *amifamily.DefaultProvider
+	AMIResolver                 *amifamily.Resolver
+	VersionProvider             *version.DefaultProvider
+	LaunchTemplateProvider      *launchtemplate.DefaultProvider
+	PlacementGroupProvider      *placementgroup.DefaultProvider
+	CapacityReservationProvider *capacityreservation.DefaultProvider
 }
 
 func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment {
@@ -86,6 +105,7 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	eksapi := fake.NewEKSAPI()
 	ssmapi := fake.NewSSMAPI()
 	iamapi := fake.NewIAMAPI()
+	outpostsapi := fake.NewOutpostsAPI()
 
 	// cache
 	ec2Cache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
@@ -96,17 +116,29 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	subnetCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	securityGroupCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	instanceProfileCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	placementGroupCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	capacityReservationCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	inflightLaunchCache := cache.New(awscache.InflightLaunchTTL, awscache.DefaultCleanupInterval)
+	launchAttemptCache := cache.New(awscache.LaunchAttemptTTL, awscache.DefaultCleanupInterval)
+	spotFallbackZoneCache := cache.New(awscache.SpotFallbackZoneTTL, awscache.DefaultCleanupInterval)
+	instanceDescriptionCache := cache.New(awscache.InstanceDescriptionTTL, awscache.DefaultCleanupInterval)
 	fakePricingAPI := &fake.PricingAPI{}
+	fakeSpotAdvisorAPI := &fake.SpotAdvisorAPI{}
+	eventRecorder := coretest.NewEventRecorder()
 
 	// Providers
-	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2api, fake.DefaultRegion)
+	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2api, fake.DefaultRegion, clock.RealClock{})
+	spotAdvisorProvider := spotadvisor.NewDefaultProvider(fakeSpotAdvisorAPI, spotadvisor.DataURL)
+	costLimitProvider := costlimit.NewDefaultProvider(pricingProvider, clock.RealClock{})
 	subnetProvider := subnet.NewDefaultProvider(ec2api, subnetCache)
 	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, securityGroupCache)
+	placementGroupProvider := placementgroup.NewDefaultProvider(ec2api, placementGroupCache)
+	capacityReservationProvider := capacityreservation.NewDefaultProvider(ec2api, capacityReservationCache)
 	versionProvider := version.NewDefaultProvider(env.KubernetesInterface, kubernetesVersionCache)
 	instanceProfileProvider := instanceprofile.NewDefaultProvider(fake.DefaultRegion, iamapi, instanceProfileCache)
 	amiProvider := amifamily.NewDefaultProvider(versionProvider, ssmapi, ec2api, ec2Cache)
 	amiResolver := amifamily.NewResolver(amiProvider)
-	instanceTypesProvider := instancetype.NewDefaultProvider(fake.DefaultRegion, instanceTypeCache, ec2api, subnetProvider, unavailableOfferingsCache, pricingProvider)
+	instanceTypesProvider := instancetype.NewDefaultProvider(fake.DefaultRegion, instanceTypeCache, ec2api, outpostsapi, subnetProvider, unavailableOfferingsCache, pricingProvider)
 	launchTemplateProvider :=
 		launchtemplate.NewDefaultProvider(
 			ctx,
@@ -123,20 +155,34 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		)
 	instanceProvider :=
 		instance.NewDefaultProvider(ctx,
-			"",
+			fake.DefaultRegion,
 			ec2api,
 			unavailableOfferingsCache,
 			instanceTypesProvider,
 			subnetProvider,
 			launchTemplateProvider,
+			placementGroupProvider,
+			spotAdvisorProvider,
+			costLimitProvider,
+			inflightLaunchCache,
+			launchAttemptCache,
+			spotFallbackZoneCache,
+			instanceDescriptionCache,
+			clock.RealClock{},
+			eventRecorder,
+			nil,
 		)
 
 	return &Environment{
-		EC2API:     ec2api,
-		EKSAPI:     eksapi,
-		SSMAPI:     ssmapi,
-		IAMAPI:     iamapi,
-		PricingAPI: fakePricingAPI,
+		EC2API:         ec2api,
+		EKSAPI:         eksapi,
+		SSMAPI:         ssmapi,
+		IAMAPI:         iamapi,
+		OutpostsAPI:    outpostsapi,
+		PricingAPI:     fakePricingAPI,
+		SpotAdvisorAPI: fakeSpotAdvisorAPI,
+
+		EventRecorder: eventRecorder,
 
 		EC2Cache:                  ec2Cache,
 		KubernetesVersionCache:    kubernetesVersionCache,
@@ -145,18 +191,28 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		SubnetCache:               subnetCache,
 		SecurityGroupCache:        securityGroupCache,
 		InstanceProfileCache:      instanceProfileCache,
+		PlacementGroupCache:       placementGroupCache,
+		CapacityReservationCache:  capacityReservationCache,
+		InflightLaunchCache:       inflightLaunchCache,
+		LaunchAttemptCache:        launchAttemptCache,
+		SpotFallbackZoneCache:     spotFallbackZoneCache,
+		InstanceDescriptionCache:  instanceDescriptionCache,
 		UnavailableOfferingsCache: unavailableOfferingsCache,
 
-		InstanceTypesProvider:   instanceTypesProvider,
-		InstanceProvider:        instanceProvider,
-		SubnetProvider:          subnetProvider,
-		SecurityGroupProvider:   securityGroupProvider,
-		LaunchTemplateProvider:  launchTemplateProvider,
-		InstanceProfileProvider: instanceProfileProvider,
-		PricingProvider:         pricingProvider,
-		AMIProvider:             amiProvider,
-		AMIResolver:             amiResolver,
-		VersionProvider:         versionProvider,
+		InstanceTypesProvider:       instanceTypesProvider,
+		InstanceProvider:            instanceProvider,
+		SubnetProvider:              subnetProvider,
+		SecurityGroupProvider:       securityGroupProvider,
+		LaunchTemplateProvider:      launchTemplateProvider,
+		PlacementGroupProvider:      placementGroupProvider,
+		CapacityReservationProvider: capacityReservationProvider,
+		InstanceProfileProvider:     instanceProfileProvider,
+		PricingProvider:             pricingProvider,
+		SpotAdvisorProvider:         spotAdvisorProvider,
+		CostLimitProvider:           costLimitProvider,
+		AMIProvider:                 amiProvider,
+		AMIResolver:                 amiResolver,
+		VersionProvider:             versionProvider,
 	}
 }
 
@@ -165,8 +221,15 @@ func (env *Environment) Reset() {
 	env.EKSAPI.Reset()
 	env.SSMAPI.Reset()
 	env.IAMAPI.Reset()
+	env.OutpostsAPI.Reset()
 	env.PricingAPI.Reset()
 	env.PricingProvider.Reset()
+	env.SpotAdvisorAPI.Reset()
+	env.SpotAdvisorProvider.Reset()
+	env.CostLimitProvider.Reset()
+	env.InstanceTypesProvider.Reset()
+	env.SubnetProvider.Reset()
+	env.EventRecorder.Reset()
 
 	env.EC2Cache.Flush()
 	env.KubernetesVersionCache.Flush()
@@ -176,6 +239,12 @@ func (env *Environment) Reset() {
 	env.SubnetCache.Flush()
 	env.SecurityGroupCache.Flush()
 	env.InstanceProfileCache.Flush()
+	env.PlacementGroupCache.Flush()
+	env.CapacityReservationCache.Flush()
+	env.InflightLaunchCache.Flush()
+	env.LaunchAttemptCache.Flush()
+	env.SpotFallbackZoneCache.Flush()
+	env.InstanceDescriptionCache.Flush()
 
 	mfs, err := crmetrics.Registry.Gather()
 	if err != nil {
@@ -21,19 +21,73 @@ import (
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
 
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
 
 type OptionsFields struct {
-	AssumeRoleARN           *string
-	AssumeRoleDuration      *time.Duration
-	ClusterCABundle         *string
-	ClusterName             *string
-	ClusterEndpoint         *string
-	IsolatedVPC             *bool
-	VMMemoryOverheadPercent *float64
-	InterruptionQueue       *string
-	ReservedENIs            *int
+	AssumeRoleARN                      *string
+	AssumeRoleDuration                 *time.Duration
+	ClusterCABundle                    *string
+	ClusterName                        *string
+	ClusterEndpoint                    *string
+	ClusterAutoscalerCompatibility     *bool
+	IsolatedVPC                        *bool
+	PricingOverrideFile                *string
+	SpotPriceTTL                       *time.Duration
+	SpotPriceRefreshInterval           *time.Duration
+	SpotPriceMaxStaleness              *time.Duration
+	VMMemoryOverheadPercent            *float64
+	GravitonCMAReservedMemoryMiB       *int
+	InstanceStoreKubeReservedStorage   *string
+	InstanceStoreEvictionPercent       *float64
+	InterruptionQueue                  *string
+	ReservedENIs                       *int
+	ENIPrefixDelegation                *bool
+	SnapshotGC                         *bool
+	SnapshotGCRetention                *time.Duration
+	SnapshotGCDryRun                   *bool
+	OnDemandAllocationStrategy         *string
+	TaintTags                          *bool
+	InstanceTypeAllowlist              []string
+	InstanceTypeDenylist               []string
+	MinLaunchInstanceTypes             *int
+	RaiseUndersizedRootVolumes         *bool
+	NetworkBandwidthResource           *bool
+	NodeClassStatusAWSQPS              *float64
+	NodeClassStatusAWSBurst            *int
+	SubnetFreeIPThreshold              *int
+	NodeNameConvention                 *string
+	NodeNameTemplate                   *string
+	SubnetClusterTagging               *bool
+	SubnetClusterTaggingDryRun         *bool
+	InstanceTypeMaxStaleness           *time.Duration
+	InstanceTypeCacheMaxKeys           *int
+	AMIDefaultOwners                   []string
+	AllowedAMIOwners                   []string
+	EnabledLabels                      []string
+	DisabledLabels                     []string
+	AMIDeprecationWindow               *time.Duration
+	RebalanceRecommendations           *bool
+	InterruptionRebalanceAction        *string
+	InterruptionQueueWaitTime          *time.Duration
+	InterruptionQueueParallelism       *int
+	InterruptionQueueMaxParseAttempts  *int
+	InterruptionQueueRequired          *bool
+	SpotInterruptionPenalty            *float64
+	SpotUnfulfillableCapacityTTL       *time.Duration
+	OnDemandInsufficientCapacityTTL    *time.Duration
+	ReservationCapacityExceededTTL     *time.Duration
+	RequirePrivateDNSName              *bool
+	ExtraNodeLabels                    map[string]string
+	ForceInstanceProfileRevalidation   *bool
+	InstanceProfilePath                *string
+	InstanceProfilePermissionsBoundary *string
+	SharedInstanceProfiles             *bool
+	LaunchTemplateGCGracePeriod        *time.Duration
+	HourlyCostLimitFailOpen            *bool
+	AWSOperationTimeouts               map[string]time.Duration
+	CloudWatchMetricsNamespace         *string
 }
 
 func Options(overrides ...OptionsFields) *options.Options {
@@ -44,14 +98,72 @@ func Options(overrides ...OptionsFields) *options.Options {
 		}
 	}
 	return &options.Options{
-		AssumeRoleARN:           lo.FromPtrOr(opts.AssumeRoleARN, ""),
-		AssumeRoleDuration:      lo.FromPtrOr(opts.AssumeRoleDuration, 15*time.Minute),
-		ClusterCABundle:         lo.FromPtrOr(opts.ClusterCABundle, ""),
-		ClusterName:             lo.FromPtrOr(opts.ClusterName, "test-cluster"),
-		ClusterEndpoint:         lo.FromPtrOr(opts.ClusterEndpoint, "https://test-cluster"),
-		IsolatedVPC:             lo.FromPtrOr(opts.IsolatedVPC, false),
-		VMMemoryOverheadPercent: lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
-		InterruptionQueue:       lo.FromPtrOr(opts.InterruptionQueue, ""),
-		ReservedENIs:            lo.FromPtrOr(opts.ReservedENIs, 0),
+		AssumeRoleARN:                      lo.FromPtrOr(opts.AssumeRoleARN, ""),
+		AssumeRoleDuration:                 lo.FromPtrOr(opts.AssumeRoleDuration, 15*time.Minute),
+		ClusterCABundle:                    lo.FromPtrOr(opts.ClusterCABundle, ""),
+		ClusterName:                        lo.FromPtrOr(opts.ClusterName, "test-cluster"),
+		ClusterEndpoint:                    lo.FromPtrOr(opts.ClusterEndpoint, "https://test-cluster"),
+		ClusterAutoscalerCompatibility:     lo.FromPtrOr(opts.ClusterAutoscalerCompatibility, false),
+		IsolatedVPC:                        lo.FromPtrOr(opts.IsolatedVPC, false),
+		PricingOverrideFile:                lo.FromPtrOr(opts.PricingOverrideFile, ""),
+		SpotPriceTTL:                       lo.FromPtrOr(opts.SpotPriceTTL, 24*time.Hour),
+		SpotPriceRefreshInterval:           lo.FromPtrOr(opts.SpotPriceRefreshInterval, 12*time.Hour),
+		SpotPriceMaxStaleness:              lo.FromPtrOr(opts.SpotPriceMaxStaleness, 0),
+		VMMemoryOverheadPercent:            lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
+		GravitonCMAReservedMemoryMiB:       lo.FromPtrOr(opts.GravitonCMAReservedMemoryMiB, 64),
+		InstanceStoreKubeReservedStorage:   lo.FromPtrOr(opts.InstanceStoreKubeReservedStorage, "5Gi"),
+		InstanceStoreEvictionPercent:       lo.FromPtrOr(opts.InstanceStoreEvictionPercent, 5),
+		InterruptionQueue:                  lo.FromPtrOr(opts.InterruptionQueue, ""),
+		ReservedENIs:                       lo.FromPtrOr(opts.ReservedENIs, 0),
+		ENIPrefixDelegation:                lo.FromPtrOr(opts.ENIPrefixDelegation, false),
+		SnapshotGC:                         lo.FromPtrOr(opts.SnapshotGC, false),
+		SnapshotGCRetention:                lo.FromPtrOr(opts.SnapshotGCRetention, 7*24*time.Hour),
+		SnapshotGCDryRun:                   lo.FromPtrOr(opts.SnapshotGCDryRun, false),
+		OnDemandAllocationStrategy:         lo.FromPtrOr(opts.OnDemandAllocationStrategy, "lowest-price"),
+		TaintTags:                          lo.FromPtrOr(opts.TaintTags, false),
+		InstanceTypeAllowlist:              opts.InstanceTypeAllowlist,
+		InstanceTypeDenylist:               opts.InstanceTypeDenylist,
+		MinLaunchInstanceTypes:             lo.FromPtrOr(opts.MinLaunchInstanceTypes, 0),
+		RaiseUndersizedRootVolumes:         lo.FromPtrOr(opts.RaiseUndersizedRootVolumes, false),
+		NetworkBandwidthResource:           lo.FromPtrOr(opts.NetworkBandwidthResource, false),
+		NodeClassStatusAWSQPS:              lo.FromPtrOr(opts.NodeClassStatusAWSQPS, 20),
+		NodeClassStatusAWSBurst:            lo.FromPtrOr(opts.NodeClassStatusAWSBurst, 100),
+		SubnetFreeIPThreshold:              lo.FromPtrOr(opts.SubnetFreeIPThreshold, 0),
+		NodeNameConvention:                 lo.FromPtrOr(opts.NodeNameConvention, "private-dns"),
+		NodeNameTemplate:                   lo.FromPtrOr(opts.NodeNameTemplate, "{{ .ClusterName }}-{{ .NodePool }}-{{ .InstanceID }}"),
+		SubnetClusterTagging:               lo.FromPtrOr(opts.SubnetClusterTagging, false),
+		SubnetClusterTaggingDryRun:         lo.FromPtrOr(opts.SubnetClusterTaggingDryRun, false),
+		InstanceTypeMaxStaleness:           lo.FromPtrOr(opts.InstanceTypeMaxStaleness, 6*time.Hour),
+		InstanceTypeCacheMaxKeys:           lo.FromPtrOr(opts.InstanceTypeCacheMaxKeys, 20),
+		AMIDefaultOwners:                   lo.Ternary(opts.AMIDefaultOwners != nil, opts.AMIDefaultOwners, []string{"self", "amazon"}),
+		AllowedAMIOwners:                   opts.AllowedAMIOwners,
+		EnabledLabels:                      lo.Ternary(opts.EnabledLabels != nil, opts.EnabledLabels, gatedLabels()),
+		DisabledLabels:                     opts.DisabledLabels,
+		AMIDeprecationWindow:               lo.FromPtrOr(opts.AMIDeprecationWindow, 14*24*time.Hour),
+		RebalanceRecommendations:           lo.FromPtrOr(opts.RebalanceRecommendations, false),
+		InterruptionRebalanceAction:        lo.FromPtrOr(opts.InterruptionRebalanceAction, "Ignore"),
+		InterruptionQueueWaitTime:          lo.FromPtrOr(opts.InterruptionQueueWaitTime, 20*time.Second),
+		InterruptionQueueParallelism:       lo.FromPtrOr(opts.InterruptionQueueParallelism, 10),
+		InterruptionQueueMaxParseAttempts:  lo.FromPtrOr(opts.InterruptionQueueMaxParseAttempts, 3),
+		InterruptionQueueRequired:          lo.FromPtrOr(opts.InterruptionQueueRequired, false),
+		SpotInterruptionPenalty:            lo.FromPtrOr(opts.SpotInterruptionPenalty, 0),
+		SpotUnfulfillableCapacityTTL:       lo.FromPtrOr(opts.SpotUnfulfillableCapacityTTL, 3*time.Minute),
+		OnDemandInsufficientCapacityTTL:    lo.FromPtrOr(opts.OnDemandInsufficientCapacityTTL, 15*time.Minute),
+		ReservationCapacityExceededTTL:     lo.FromPtrOr(opts.ReservationCapacityExceededTTL, time.Minute),
+		RequirePrivateDNSName:              lo.FromPtrOr(opts.RequirePrivateDNSName, true),
+		ExtraNodeLabels:                    opts.ExtraNodeLabels,
+		ForceInstanceProfileRevalidation:   lo.FromPtrOr(opts.ForceInstanceProfileRevalidation, false),
+		InstanceProfilePath:                lo.FromPtrOr(opts.InstanceProfilePath, "/"),
+		InstanceProfilePermissionsBoundary: lo.FromPtrOr(opts.InstanceProfilePermissionsBoundary, ""),
+		SharedInstanceProfiles:             lo.FromPtrOr(opts.SharedInstanceProfiles, false),
+		LaunchTemplateGCGracePeriod:        lo.FromPtrOr(opts.LaunchTemplateGCGracePeriod, time.Hour),
+		HourlyCostLimitFailOpen:            lo.FromPtrOr(opts.HourlyCostLimitFailOpen, true),
+		AWSOperationTimeouts:               opts.AWSOperationTimeouts,
+		CloudWatchMetricsNamespace:         lo.FromPtrOr(opts.CloudWatchMetricsNamespace, ""),
 	}
 }
+
+// gatedLabels are the registered labels that are gated, which tests enable by default so that they cover every label
+func gatedLabels() []string {
+	return lo.FilterMap(v1beta1.InstanceTypeLabels, func(l v1beta1.InstanceTypeLabel, _ int) (string, bool) { return l.Key, l.Gated })
+}
@@ -22,6 +22,10 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 )
 
 var (
@@ -43,6 +47,18 @@ func ParseInstanceID(providerID string) (string, error) {
 	return "", fmt.Errorf("parsing instance id %s", providerID)
 }
 
+// NodeClassName returns the name of the EC2NodeClass that the NodeClaim was launched from. NodeClaims launched before
+// the EC2NodeClass was recorded were launched from the one that they reference.
+func NodeClassName(nodeClaim *corev1beta1.NodeClaim) string {
+	if name, ok := nodeClaim.Annotations[v1beta1.AnnotationLaunchedNodeClass]; ok {
+		return name
+	}
+	if nodeClaim.Spec.NodeClassRef == nil {
+		return ""
+	}
+	return nodeClaim.Spec.NodeClassRef.Name
+}
+
 // MergeTags takes a variadic list of maps and merges them together into a list of
 // EC2 tags to be passed into EC2 API calls
 func MergeTags(tags ...map[string]string) []*ec2.Tag {
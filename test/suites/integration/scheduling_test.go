@@ -16,6 +16,7 @@ package integration_test
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
@@ -41,6 +42,10 @@ var _ = Describe("Scheduling", Ordered, ContinueOnFailure, func() {
 	var selectors sets.Set[string]
 
 	BeforeEach(func() {
+		// Gated labels are only emitted once they're enabled, so enable all of them to test every well-known label
+		env.ExpectSettingsOverridden(v1.EnvVar{Name: "ENABLED_LABELS", Value: strings.Join(lo.FilterMap(v1beta1.InstanceTypeLabels, func(l v1beta1.InstanceTypeLabel, _ int) (string, bool) {
+			return l.Key, l.Gated
+		}), ",")})
 		// Make the NodePool requirements fully flexible, so we can match well-known label keys
 		nodePool = test.ReplaceRequirements(nodePool,
 			corev1beta1.NodeSelectorRequirementWithMinValues{
@@ -84,15 +89,20 @@ var _ = Describe("Scheduling", Ordered, ContinueOnFailure, func() {
 				corev1beta1.NodePoolLabelKey: nodePool.Name,
 				v1.LabelInstanceTypeStable:   "c5.large",
 				// Well Known to AWS
-				v1beta1.LabelInstanceHypervisor:       "nitro",
-				v1beta1.LabelInstanceCategory:         "c",
-				v1beta1.LabelInstanceGeneration:       "5",
-				v1beta1.LabelInstanceFamily:           "c5",
-				v1beta1.LabelInstanceSize:             "large",
-				v1beta1.LabelInstanceCPU:              "2",
-				v1beta1.LabelInstanceCPUManufacturer:  "intel",
-				v1beta1.LabelInstanceMemory:           "4096",
-				v1beta1.LabelInstanceNetworkBandwidth: "750",
+				v1beta1.LabelInstanceHypervisor:                "nitro",
+				v1beta1.LabelInstanceCategory:                  "c",
+				v1beta1.LabelInstanceGeneration:                "5",
+				v1beta1.LabelInstanceFamily:                    "c5",
+				v1beta1.LabelInstanceSize:                      "large",
+				v1beta1.LabelInstanceCPU:                       "2",
+				v1beta1.LabelInstanceCPUManufacturer:           "intel",
+				v1beta1.LabelInstanceCPUSustainedClockSpeedMhz: "3400",
+				v1beta1.LabelInstanceSMTSupported:              "true",
+				v1beta1.LabelInstanceBareMetal:                 "false",
+				v1beta1.LabelInstanceMemory:                    "4096",
+				v1beta1.LabelInstanceNetworkBandwidth:          "750",
+				v1beta1.LabelInstanceNetworkCards:              "1",
+				v1beta1.LabelInstanceNetworkCardsBandwidth:     "750",
 			}
 			selectors.Insert(lo.Keys(nodeSelector)...) // Add node selector keys to selectors used in testing to ensure we test all labels
 			requirements := lo.MapToSlice(nodeSelector, func(key string, value string) v1.NodeSelectorRequirement {
@@ -108,22 +118,17 @@ var _ = Describe("Scheduling", Ordered, ContinueOnFailure, func() {
 			env.ExpectCreatedNodeCount("==", 1)
 		})
 		It("should support well-known labels for local NVME storage", func() {
-			selectors.Insert(v1beta1.LabelInstanceLocalNVME) // Add node selector keys to selectors used in testing to ensure we test all labels
+			selectors.Insert(v1beta1.LabelInstanceLocalNVME, v1beta1.LabelInstanceLocalNVMECount, v1beta1.LabelInstanceLocalNVMEDiskSize) // Add node selector keys to selectors used in testing to ensure we test all labels
+			requirements := lo.Map([]string{v1beta1.LabelInstanceLocalNVME, v1beta1.LabelInstanceLocalNVMECount, v1beta1.LabelInstanceLocalNVMEDiskSize}, func(key string, _ int) v1.NodeSelectorRequirement {
+				return v1.NodeSelectorRequirement{
+					Key:      key,
+					Operator: v1.NodeSelectorOpGt,
+					Values:   []string{"0"},
+				}
+			})
 			deployment := test.Deployment(test.DeploymentOptions{Replicas: 1, PodOptions: test.PodOptions{
-				NodePreferences: []v1.NodeSelectorRequirement{
-					{
-						Key:      v1beta1.LabelInstanceLocalNVME,
-						Operator: v1.NodeSelectorOpGt,
-						Values:   []string{"0"},
-					},
-				},
-				NodeRequirements: []v1.NodeSelectorRequirement{
-					{
-						Key:      v1beta1.LabelInstanceLocalNVME,
-						Operator: v1.NodeSelectorOpGt,
-						Values:   []string{"0"},
-					},
-				},
+				NodePreferences:  requirements,
+				NodeRequirements: requirements,
 			}})
 			env.ExpectCreated(nodeClass, nodePool, deployment)
 			env.EventuallyExpectHealthyPodCount(labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels), int(*deployment.Spec.Replicas))
@@ -151,6 +156,21 @@ var _ = Describe("Scheduling", Ordered, ContinueOnFailure, func() {
 			env.EventuallyExpectHealthyPodCount(labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels), int(*deployment.Spec.Replicas))
 			env.ExpectCreatedNodeCount("==", 1)
 		})
+		It("should support well-known labels for EFA network cards", func() {
+			selectors.Insert(v1beta1.LabelInstanceEFANetworkCards) // Add node selector keys to selectors used in testing to ensure we test all labels
+			deployment := test.Deployment(test.DeploymentOptions{Replicas: 1, PodOptions: test.PodOptions{
+				NodeRequirements: []v1.NodeSelectorRequirement{
+					{
+						Key:      v1beta1.LabelInstanceEFANetworkCards,
+						Operator: v1.NodeSelectorOpGt,
+						Values:   []string{"0"},
+					},
+				},
+			}})
+			env.ExpectCreated(nodeClass, nodePool, deployment)
+			env.EventuallyExpectHealthyPodCount(labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels), int(*deployment.Spec.Replicas))
+			env.ExpectCreatedNodeCount("==", 1)
+		})
 		It("should support well-known deprecated labels", func() {
 			nodeSelector := map[string]string{
 				// Deprecated Labels
@@ -237,6 +257,28 @@ var _ = Describe("Scheduling", Ordered, ContinueOnFailure, func() {
 			env.EventuallyExpectHealthyPodCount(labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels), int(*deployment.Spec.Replicas))
 			env.ExpectCreatedNodeCount("==", 1)
 		})
+		It("should support well-known labels for accelerator memory", func() {
+			selectors.Insert(v1beta1.LabelInstanceAcceleratorMemory) // Add node selector keys to selectors used in testing to ensure we test all labels
+			deployment := test.Deployment(test.DeploymentOptions{Replicas: 1, PodOptions: test.PodOptions{
+				NodePreferences: []v1.NodeSelectorRequirement{
+					{
+						Key:      v1beta1.LabelInstanceAcceleratorMemory,
+						Operator: v1.NodeSelectorOpGt,
+						Values:   []string{"0"},
+					},
+				},
+				NodeRequirements: []v1.NodeSelectorRequirement{
+					{
+						Key:      v1beta1.LabelInstanceAcceleratorMemory,
+						Operator: v1.NodeSelectorOpGt,
+						Values:   []string{"0"},
+					},
+				},
+			}})
+			env.ExpectCreated(nodeClass, nodePool, deployment)
+			env.EventuallyExpectHealthyPodCount(labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels), int(*deployment.Spec.Replicas))
+			env.ExpectCreatedNodeCount("==", 1)
+		})
 		It("should support well-known labels for windows-build version", func() {
 			env.ExpectWindowsIPAMEnabled()
 			DeferCleanup(func() {
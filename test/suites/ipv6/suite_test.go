@@ -18,6 +18,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 
@@ -94,4 +95,17 @@ var _ = Describe("IPv6", func() {
 		})
 		Expect(internalIPv6Addrs).To(HaveLen(1))
 	})
+	It("should provision an IPv6 node with the requested number of IPv6 prefixes", func() {
+		nodeClass.Spec.IPv6 = &v1beta1.IPv6{PrefixCount: lo.ToPtr[int64](2)}
+		pod := coretest.Pod()
+		env.ExpectCreated(pod, nodeClass, nodePool)
+		env.EventuallyExpectHealthy(pod)
+		env.ExpectCreatedNodeCount("==", 1)
+		instance := env.GetInstance(pod.Spec.NodeName)
+		primary, ok := lo.Find(instance.NetworkInterfaces, func(ni *ec2.InstanceNetworkInterface) bool {
+			return lo.FromPtr(ni.Attachment.DeviceIndex) == 0
+		})
+		Expect(ok).To(BeTrue())
+		Expect(primary.Ipv6Prefixes).To(HaveLen(2))
+	})
 })